@@ -1,78 +1,313 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"net/rpc"
 	"os"
 	"os/signal"
-	"sync"
+	"runtime"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/godbus/dbus"
 
 	"github.com/plasma-umass/systemgo/config"
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/pid1"
 	"github.com/plasma-umass/systemgo/system"
 	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/systemd1"
+	"github.com/plasma-umass/systemgo/tmpfiles"
+	"github.com/plasma-umass/systemgo/unit"
 )
 
-// Initializes the system, sets the default paths, as specified in configuration and attempts to start the default target, falls back to "rescue.target", if it fails
+// Initializes the system, sets the default paths, as specified in configuration and attempts to start the default target, falls back to "rescue.target" and "emergency.target", if it fails.
+// With --user the manager of the user running it is run instead.
+// In a container the command specified by the arguments is run as the payload, the exit of which stops the container
 func main() {
+	user := flag.Bool("user", false, "serve as the manager of the user running it")
+	container := flag.Bool("container", false, "serve as the init process of a container")
+	flag.Parse()
+
+	if *user {
+		// The units of the user are managed, none of which requires root
+		config.SetUser()
+	}
+	if *container {
+		config.Container = true
+	}
+	config.ApplyCmdline()
+
+	if config.Container {
+		sys.SetContainer()
+	}
+
+	if os.Getpid() == 1 && !config.Container {
+		// The API filesystems, the hostname and the locale are set up before anything else runs
+		pid1.Setup()
+	}
+
+	if os.Getpid() == 1 && !config.Container && config.RuntimeWatchdog > 0 {
+		// A hung manager gets the machine reset
+		if w, err := pid1.OpenWatchdog(config.WatchdogDevice, config.RuntimeWatchdog); err != nil {
+			log.Errorf("Error opening watchdog %s: %s", config.WatchdogDevice, err)
+		} else {
+			log.Infof("Watchdog %s armed with timeout %s", config.WatchdogDevice, config.RuntimeWatchdog)
+			sys.SetWatchdog(w, config.RuntimeWatchdog, config.RebootWatchdog)
+		}
+	}
+
+	kmsg := journal.NewKMsg()
+	if os.Getpid() == 1 && !config.Container {
+		// The messages of the manager are available in the kernel log, even if the boot fails before the journal is stored
+		log.AddHook(kmsg)
+	}
+
+	// The journal is opened before any unit gets loaded
+	if config.Journal != "" {
+		if j, err := journal.Open(config.Journal); err != nil {
+			log.Errorf("Error opening journal in %s, keeping it in memory: %s", config.Journal, err)
+		} else {
+			if err = j.SetLimits(config.JournalMaxUse, config.JournalMaxFileSize); err != nil {
+				log.Errorf("Error vacuuming journal in %s: %s", config.Journal, err)
+			}
+			sys.Journal = j
+		}
+	}
+	if config.ForwardToSyslog {
+		sys.Journal.ForwardTo(journal.NewSyslog("", ""))
+	}
+	if config.ForwardToKMsg {
+		sys.Journal.ForwardTo(kmsg)
+	}
+	if config.ForwardTo != "" {
+		if s, err := journal.Dial(config.ForwardTo); err != nil {
+			log.Errorf("Error forwarding journal to %s: %s", config.ForwardTo, err)
+		} else {
+			sys.Journal.ForwardTo(s)
+		}
+	}
+
+	// The messages of the manager are recorded along with the ones about the units
+	log.AddHook(sys.Journal.Hook(""))
+
+	// The services started inherit the path to the socket
+	if config.NotifySocket != "" {
+		if err := sys.ListenNotify(config.NotifySocket); err != nil {
+			log.Errorf("Error listening for notifications on %s: %s", config.NotifySocket, err)
+		}
+	}
+
+	// The environment is set before any process, the generators included, is started
+	if err := sys.SetEnvironment(config.Environment...); err != nil {
+		log.Errorf("Error setting environment %q: %s", config.Environment, err)
+	}
+	sys.LoadEnvironment(config.EnvironmentPaths...)
+
 	go Serve()
+	if config.DBus {
+		go ServeBus()
+	}
+	if config.API != "" {
+		go ServeAPI()
+	}
+	if config.Metrics != "" {
+		go ServeMetrics()
+	}
+
+	if config.Container {
+		// Reap orphans, stop the container on SIGTERM and forward the other signals to the payload
+		go pid1.RunContainer(sys)
+	} else if os.Getpid() == 1 {
+		// Reap orphans and serve the signals as the init process
+		go pid1.Run(sys)
+	} else {
+		// Deliver the exits of the processes started to the units
+		go pid1.Watch(sys)
+	}
+	for !pid1.Watching() {
+		// The processes started before the children are reaped would be waited for by the units instead
+		runtime.Gosched()
+	}
 
 	// Initialize system
 	log.Info("Systemgo starting...")
 
 	sys.SetPaths(config.Paths...)
+	sys.SetPresetPaths(config.PresetPaths...)
+	sys.SetInhibitDelayMax(config.InhibitDelayMax)
+	setNetworkChecks()
+	if os.Getpid() == 1 {
+		// Only the init process records the boots and logins of the machine
+		sys.SetUtmp(config.Utmp, config.Wtmp)
+	}
+	sys.SetGeneratorPaths(config.GeneratorDir, config.GeneratorPaths...)
+	sys.RunGenerators()
+	if config.AutoReload {
+		if err := sys.WatchPaths(); err != nil {
+			log.Errorf("Error watching unit paths: %s", err)
+		}
+	}
 
-	// Start the default target
-	if err := sys.Start(config.Target); err != nil {
-		log.Errorf("Error starting default target %s: %s", config.Target, err)
-		if err = sys.Start(config.RESCUE_TARGET); err != nil {
-			log.Errorf("Error starting rescue target %s: %s", config.RESCUE_TARGET, err)
+	// The daemons started rely on their volatile directories, e.g. in /run, being created
+	createTmpfiles()
+	if path := os.Getenv(system.STATE_ENV); path != "" {
+		// Re-executed, the units are running already
+		os.Unsetenv(system.STATE_ENV)
+		if err := restore(path); err != nil {
+			log.Errorf("Error restoring state from %s: %s", path, err)
+		}
+	} else {
+		// Start the default target
+		target := sys.BootTarget(config.Target)
+		log.Infof("Starting %s", target)
+		if err := sys.Start(target); err != nil {
+			log.Errorf("Error starting default target %s: %s", target, err)
+			if err = sys.Isolate(config.RESCUE_TARGET); err != nil {
+				log.Errorf("Error starting rescue target %s: %s", config.RESCUE_TARGET, err)
+				if err = sys.Isolate(system.EMERGENCY_TARGET); err != nil {
+					log.Errorf("Error starting emergency target %s: %s", system.EMERGENCY_TARGET, err)
+				}
+			}
+		}
+		go sys.FinishBoot()
+
+		if args := flag.Args(); len(args) > 0 {
+			if !config.Container {
+				log.Errorf("Command %q ignored, it is only run in a container", args)
+			} else if err := sys.StartPayload(args); err != nil {
+				log.Errorf("Error starting %q: %s", args, err)
+				sys.Poweroff()
+			}
 		}
 	}
 
+	go collectGarbage()
+	if config.TmpfilesClean > 0 {
+		go cleanTmpfiles()
+	}
+
 	if log.GetLevel() == log.DebugLevel {
 		go printUnits()
 	}
 
+	if os.Getpid() == 1 || config.Container {
+		// The init process never exits, it gets shut down by the requests served
+		select {}
+	}
+
 	exit := make(chan os.Signal)
 	signal.Notify(exit, os.Interrupt, os.Kill)
 	<-exit
 
 	log.Infoln("Shutting down...")
-	if err := sys.Isolate("shutdown.target"); err != nil {
+	if err := sys.Poweroff(); err != nil {
 		log.Fatalf("Error shutting down: %s", err)
 	}
+}
 
-	wg := &sync.WaitGroup{}
-	for _, u := range sys.Units() {
-		if u.IsActive() {
-			log.Infof("Waiting for %s to stop", u.Name())
-			wg.Add(1)
+// Instance of a system
+var sys = system.New()
 
-			go func(u *system.Unit) {
-				defer wg.Done()
+// Listen for systemctl requests
+func Serve() {
+	if config.Port != 0 {
+		go ServeHTTP()
+	}
 
-				var t time.Duration
-				for range time.Tick(time.Second) {
-					t += time.Second
-					if !u.IsActive() || t == time.Minute {
-						return
-					}
-				}
-			}(u)
+	for {
+		if err := listenControl(config.Socket); err != nil {
+			log.Errorf("Error listening on %s: %s", config.Socket, err)
 		}
+		log.Infof("Retrying in %v seconds", config.Retry)
+		time.Sleep(config.Retry)
 	}
 }
 
-// Instance of a system
-var sys = system.New()
+// Serve the management API requests
+func ServeAPI() {
+	for {
+		if err := listenAPI(config.API); err != nil {
+			log.Errorf("Error serving the API on %s: %s", config.API, err)
+		}
+		log.Infof("Retrying in %v seconds", config.Retry)
+		time.Sleep(config.Retry)
+	}
+}
 
-// Listen for systemctl requests
-func Serve() {
+// Handle the management API requests using HTTP on addr
+func listenAPI(addr string) (err error) {
+	auth, err := authorizer()
+	if err != nil {
+		return
+	}
+	return systemctl.NewAPI(sys, auth).Listen(addr)
+}
+
+// Serve the Prometheus metrics of the system
+func ServeMetrics() {
+	for {
+		if err := systemctl.NewExporter(sys).Listen(config.Metrics); err != nil {
+			log.Errorf("Error serving the metrics on %s: %s", config.Metrics, err)
+		}
+		log.Infof("Retrying in %v seconds", config.Retry)
+		time.Sleep(config.Retry)
+	}
+}
+
+// Expose the system on the D-Bus system bus, once it is available
+func ServeBus() {
+	auth, err := authorizer()
+	if err != nil {
+		log.Errorf("Error serving on the system bus: %s", err)
+		return
+	}
+	manager := systemd1.New(sys, auth)
+
+	for {
+		if err := serveBus(manager); err != nil {
+			log.Debugf("Error serving on the system bus: %s", err)
+		}
+		time.Sleep(config.Retry)
+	}
+}
+
+// Serve the requests received on the system bus using manager, until the connection is closed
+func serveBus(manager *systemd1.Manager) (err error) {
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err = conn.Auth(nil); err != nil {
+		return
+	}
+	if err = conn.Hello(); err != nil {
+		return
+	}
+	return manager.Serve(conn)
+}
+
+// Handle systemctl requests using the control socket at path
+func listenControl(path string) (err error) {
+	auth, err := authorizer()
+	if err != nil {
+		return
+	}
+
+	control, err := systemctl.NewControl(systemctl.NewServer(sys), auth)
+	if err != nil {
+		return
+	}
+	return control.Listen(path)
+}
+
+// Listen for systemctl requests using HTTP
+func ServeHTTP() {
 	for {
 		if err := listenHTTP(config.Port.String()); err != nil {
 			log.Errorf("Error listening on %v: %s", config.Port, err)
@@ -99,6 +334,88 @@ func listenHTTP(addr string) (err error) {
 	return http.Serve(l, nil)
 }
 
+// Returns the authorizer of the clients mutating the state or reading the journal, only root and the user running
+// the manager are allowed to mutate the state of the manager of the user
+func authorizer() (a *systemctl.Authorizer, err error) {
+	if config.User {
+		return systemctl.NewUserAuthorizer(os.Getuid()), nil
+	}
+	if a, err = systemctl.NewAuthorizer(config.Group); err != nil {
+		return
+	}
+	if config.JournalGroup != "" {
+		err = a.SetJournalGroup(config.JournalGroup)
+	}
+	return
+}
+
+// Restore the state serialized before re-execution from file at path
+func restore(path string) (err error) {
+	defer os.Remove(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	return sys.Deserialize(f)
+}
+
+// setNetworkChecks makes network-online.target wait for the network checks configured, the invalid ones are ignored
+func setNetworkChecks() {
+	var checks []system.NetworkCheck
+	for _, spec := range config.NetworkOnline {
+		check, err := system.ParseNetworkCheck(spec)
+		if err != nil {
+			log.WithField("check", spec).Errorf("Error parsing network check: %s", err)
+			continue
+		}
+		checks = append(checks, check)
+	}
+	sys.SetNetworkChecks(config.NetworkOnlineTimeout, checks...)
+}
+
+// Unload unused units periodically
+func collectGarbage() {
+	for range time.Tick(config.GC) {
+		if names := sys.GC(); len(names) > 0 {
+			log.Debugf("Unloaded %v", names)
+		}
+	}
+}
+
+// createTmpfiles creates the volatile files and directories configured and cleans up the ones outdated
+func createTmpfiles() {
+	entries := tmpfiles.Load(config.TmpfilesPaths...)
+	if err := tmpfiles.Create(entries); err != nil {
+		logTmpfilesErr("Error creating volatile files", err)
+	}
+	if err := tmpfiles.Clean(entries, time.Now()); err != nil {
+		logTmpfilesErr("Error cleaning volatile files", err)
+	}
+}
+
+// cleanTmpfiles cleans up the volatile files outdated each config.TmpfilesClean.
+// The configuration is loaded anew, so that the files changed since apply
+func cleanTmpfiles() {
+	for now := range time.Tick(config.TmpfilesClean) {
+		if err := tmpfiles.Clean(tmpfiles.Load(config.TmpfilesPaths...), now); err != nil {
+			logTmpfilesErr("Error cleaning volatile files", err)
+		}
+	}
+}
+
+func logTmpfilesErr(msg string, err error) {
+	merr, ok := err.(unit.MultiError)
+	if !ok {
+		merr = unit.MultiError{err}
+	}
+	for _, err := range merr {
+		log.Errorf("%s: %s", msg, err)
+	}
+}
+
 func printUnits() {
 	for range time.Tick(5 * time.Second) {
 		for _, u := range sys.Units() {
@@ -0,0 +1,44 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// The sysv-generator wraps the SysV init scripts found in /etc/init.d as services.
+// It is run by the manager as a generator with the normal, early and late output directories as arguments,
+// the services are written to the late one, so that the native units take precedence.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/plasma-umass/systemgo/config"
+	"github.com/plasma-umass/systemgo/sysv"
+)
+
+func main() {
+	if len(os.Args) != 4 {
+		fmt.Fprintf(os.Stderr, "Usage: %s NORMAL EARLY LATE\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if err := sysv.Generate(os.Args[3], config.Paths...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating units: %s\n", err)
+		os.Exit(1)
+	}
+}
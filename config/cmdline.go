@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Prefix of the kernel command line parameters configuring the manager
+const CMDLINE_PREFIX = "systemgo."
+
+// cmdlineApplies reports whether the kernel command line configures the manager:
+// the init process of the machine is configured, the ones of the containers and of the users are not
+func cmdlineApplies() bool {
+	return !User && !Container && os.Getpid() == 1
+}
+
+// ApplyCmdline applies the kernel command line to the configuration, if it configures the manager.
+// It is to be called once the manager configured is known, i.e. after SetUser and Container are set as specified
+func ApplyCmdline() {
+	if !cmdlineApplies() {
+		return
+	}
+
+	// Appliance images configure their init on the kernel command line
+	applyCmdline(unit.KernelCommandLine())
+	setLogLevel()
+}
+
+// applyCmdline applies the options found among the kernel command line words to the configuration:
+//
+//	debug, systemgo.debug[=BOOL]   shows the debugging statements
+//	systemgo.log_level=LEVEL       sets the level of the messages of the manager logged
+//	systemgo.setenv=VAR=VALUE      sets the variable in the environment passed to the processes started
+//
+// The unit started at boot, specified by systemgo.unit= or a runlevel, is found by system.Daemon.BootTarget
+func applyCmdline(words []string) {
+	for _, word := range words {
+		if word == "debug" {
+			Debug = true
+			continue
+		}
+		if !strings.HasPrefix(word, CMDLINE_PREFIX) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(word, CMDLINE_PREFIX), "=", 2)
+		key, value := parts[0], ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		e := log.WithField("param", word)
+		switch key {
+		case "debug":
+			if value == "" {
+				Debug = true
+			} else if v, err := unit.ParseBool(value); err != nil {
+				e.Errorf("Invalid boolean, ignoring")
+			} else {
+				Debug = v
+			}
+		case "log_level":
+			if _, err := log.ParseLevel(value); err != nil {
+				e.Errorf("Invalid log level, ignoring")
+			} else {
+				LogLevel = value
+			}
+		case "setenv":
+			if !strings.Contains(value, "=") {
+				e.Errorf("Invalid assignment, ignoring")
+			} else {
+				Environment = append(Environment, value)
+			}
+		case "unit":
+		default:
+			e.Warnf("Unknown kernel command line parameter, ignoring")
+		}
+	}
+}
@@ -6,14 +6,20 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/pid1"
 	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/tmpfiles"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/plasma-umass/systemgo/utmp"
 	"github.com/spf13/viper"
 )
 
 const (
-	DEFAULT_PORT   = 8008
-	DEFAULT_TARGET = "default.target"
-	RESCUE_TARGET  = "rescue.target"
+	DEFAULT_PORT   = 0
+	DEFAULT_SOCKET = "/run/systemgo/private"
+	DEFAULT_TARGET = system.DEFAULT_TARGET
+	RESCUE_TARGET  = system.RESCUE_TARGET
 )
 
 var (
@@ -23,14 +29,98 @@ var (
 	// Paths to search for unit files
 	Paths []string
 
-	// Port for system daemon to listen on
+	// Paths to search for preset files
+	PresetPaths []string
+
+	// Paths to search for generators and the directory to create their output directories in
+	GeneratorPaths []string
+	GeneratorDir   string
+
+	// Variables set in the environment passed to the processes started, as DefaultEnvironment= of systemd,
+	// and the paths to search for environment.d(5) fragments, which are applied afterwards
+	Environment      []string
+	EnvironmentPaths []string
+
+	// Paths to search for tmpfiles.d(5) configuration files and the period between cleanups of the directories
+	// configured, disabled if 0
+	TmpfilesPaths []string
+	TmpfilesClean time.Duration
+
+	// Control socket for system daemon to listen on
+	Socket string
+
+	// Group, the members of which are allowed to mutate the state of the system using the control socket.
+	// Only root is, if empty
+	Group string
+
+	// Group, the members of which are allowed to read the journal using the control socket besides the ones
+	// allowed to mutate the state. None is, if empty
+	JournalGroup string
+
+	// Unix socket path or TCP address to serve the HTTP/JSON management API on, disabled if empty.
+	// Only the clients authorized on a Unix socket may mutate the state of the system
+	API string
+
+	// Unix socket path or TCP address to serve the Prometheus metrics on at /metrics, disabled if empty
+	Metrics string
+
+	// Directory to store the journal in, the journal is kept in memory only if empty
+	Journal string
+
+	// Limits of the size of all the files of the journal and of a single file, in bytes
+	JournalMaxUse, JournalMaxFileSize uint64
+
+	// Whether to forward the records of the journal to the local syslog daemon
+	ForwardToSyslog bool
+
+	// Whether to forward the records of the journal to the kernel log buffer
+	ForwardToKMsg bool
+
+	// Target to forward the records of the journal to(e.g. "udp://loghost" or "json://loghost:5170"), disabled if empty
+	ForwardTo string
+
+	// Socket for the services to send sd_notify(3) messages to, disabled if empty
+	NotifySocket string
+
+	// Whether to expose the system on the D-Bus system bus as org.freedesktop.systemd1
+	DBus bool
+
+	// Port for system daemon to listen on using HTTP, disabled if 0.
+	// No authorization is performed on requests received on the port
 	Port port
 
 	// Retry specifies the period(in seconds) to wait before
-	// restarting the http service if it fails
+	// restarting the control or http service if it fails
 	Retry time.Duration
 
-	// Wheter to show debugging statements
+	// GC specifies the period(in seconds) between unloading of unused units
+	GC time.Duration
+
+	// Hardware watchdog device pinged by the init process and its timeouts while running(disabled if 0)
+	// and while rebooting(disarmed on shutdown if 0), as RuntimeWatchdogSec= and RebootWatchdogSec= of systemd
+	WatchdogDevice                  string
+	RuntimeWatchdog, RebootWatchdog time.Duration
+
+	// Whether to watch the unit paths and reload the units, which definitions change on disk
+	AutoReload bool
+
+	// Longest time the delay inhibitors postpone shutdown by, as InhibitDelayMaxSec= of logind
+	InhibitDelayMax time.Duration
+
+	// Checks network-online.target waits for to pass(e.g. "route" or "interface:eth0"), but no longer than the timeout
+	NetworkOnline        []string
+	NetworkOnlineTimeout time.Duration
+
+	// Files the boot, runlevel and login records are written to by the init process(not written if empty)
+	Utmp, Wtmp string
+
+	// Whether to serve as the init process of a container, detected if "auto" is configured
+	Container bool
+
+	// Level of the messages of the manager logged(e.g. "info" or "warning")
+	LogLevel string
+
+	// Wheter to show debugging statements, overrides LogLevel
 	Debug bool
 )
 
@@ -40,11 +130,103 @@ func (p port) String() string {
 	return fmt.Sprintf(":%v", int(p))
 }
 
+// size returns the size in bytes configured by key(e.g. "64M"), def if it is not configured or invalid
+func size(key string, def uint64) uint64 {
+	s := viper.GetString(key)
+	if s == "" {
+		return def
+	}
+
+	v, err := unit.ParseSize(s)
+	if err != nil || v == 0 {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"value": s,
+		}).Errorf("Invalid size, using default")
+		return def
+	}
+	return v
+}
+
+// timespan returns the time span configured by key(e.g. "10min"), def if it is not configured or invalid
+func timespan(key string, def time.Duration) time.Duration {
+	s := viper.GetString(key)
+	if s == "" {
+		return def
+	}
+
+	v, err := unit.ParseTimespan(s)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"key":   key,
+			"value": s,
+		}).Errorf("Invalid time span, using default")
+		return def
+	}
+	return v
+}
+
+// container returns whether to serve as the init process of a container as configured by s.
+// If s is "auto", the init process running in a container does
+func container(s string) bool {
+	if s == "auto" {
+		_, container := unit.Virtualization()
+		return container && os.Getpid() == 1
+	}
+
+	v, err := unit.ParseBool(s)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"key":   "container",
+			"value": s,
+		}).Errorf("Invalid boolean, detecting")
+		return container("auto")
+	}
+	return v
+}
+
 func init() {
+	load()
+}
+
+// load reads the configuration of the system manager or the one of the user running it, if User is set
+func load() {
+	viper.SetDefault("socket", DEFAULT_SOCKET)
+	viper.SetDefault("group", "")
+	viper.SetDefault("journal_group", "")
+	viper.SetDefault("api", "")
+	viper.SetDefault("metrics", "")
+	viper.SetDefault("journal", journal.DEFAULT_DIR)
+	viper.SetDefault("journal_max_use", "")
+	viper.SetDefault("journal_max_file_size", "")
+	viper.SetDefault("forward_to_syslog", false)
+	viper.SetDefault("forward_to_kmsg", false)
+	viper.SetDefault("forward_to", "")
+	viper.SetDefault("notify_socket", system.DEFAULT_NOTIFY_SOCKET)
+	viper.SetDefault("dbus", true)
 	viper.SetDefault("port", DEFAULT_PORT)
 	viper.SetDefault("target", DEFAULT_TARGET)
 	viper.SetDefault("paths", system.DEFAULT_PATHS)
+	viper.SetDefault("presets", system.DEFAULT_PRESET_PATHS)
+	viper.SetDefault("generators", system.DEFAULT_GENERATOR_PATHS)
+	viper.SetDefault("generator_dir", system.DEFAULT_GENERATOR_DIR)
+	viper.SetDefault("environment", []string{})
+	viper.SetDefault("environment_paths", system.DEFAULT_ENVIRONMENT_PATHS)
+	viper.SetDefault("tmpfiles", tmpfiles.DEFAULT_PATHS)
+	viper.SetDefault("tmpfiles_clean", 24*60*60)
 	viper.SetDefault("retry", 1)
+	viper.SetDefault("gc", 60)
+	viper.SetDefault("watchdog_device", pid1.DEFAULT_WATCHDOG_DEVICE)
+	viper.SetDefault("runtime_watchdog", "0")
+	viper.SetDefault("reboot_watchdog", "10min")
+	viper.SetDefault("auto_reload", false)
+	viper.SetDefault("inhibit_delay_max", "5s")
+	viper.SetDefault("network_online", []string{})
+	viper.SetDefault("network_online_timeout", "2min")
+	viper.SetDefault("utmp", utmp.DEFAULT_UTMP_PATH)
+	viper.SetDefault("wtmp", utmp.DEFAULT_WTMP_PATH)
+	viper.SetDefault("container", "auto")
+	viper.SetDefault("log_level", log.InfoLevel.String())
 	viper.SetDefault("debug", false)
 
 	viper.SetEnvPrefix("systemgo")
@@ -53,11 +235,15 @@ func init() {
 	viper.SetConfigName("systemgo")
 	viper.SetConfigType("yaml")
 
-	viper.AddConfigPath(".")
-	if os.Getenv("XDG_CONFIG_HOME") != "" {
-		viper.AddConfigPath("$XDG_CONFIG_HOME/systemgo")
+	if User {
+		setUserDefaults()
+	} else {
+		viper.AddConfigPath(".")
+		if os.Getenv("XDG_CONFIG_HOME") != "" {
+			viper.AddConfigPath("$XDG_CONFIG_HOME/systemgo")
+		}
+		viper.AddConfigPath("/etc/systemgo")
 	}
-	viper.AddConfigPath("/etc/systemgo")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if os.IsNotExist(err) {
@@ -76,10 +262,52 @@ func init() {
 
 	Target = viper.GetString("target")
 	Paths = viper.GetStringSlice("paths")
+	PresetPaths = viper.GetStringSlice("presets")
+	GeneratorPaths = viper.GetStringSlice("generators")
+	GeneratorDir = viper.GetString("generator_dir")
+	Environment = viper.GetStringSlice("environment")
+	EnvironmentPaths = viper.GetStringSlice("environment_paths")
+	TmpfilesPaths = viper.GetStringSlice("tmpfiles")
+	TmpfilesClean = viper.GetDuration("tmpfiles_clean") * time.Second
+	Socket = viper.GetString("socket")
+	Group = viper.GetString("group")
+	JournalGroup = viper.GetString("journal_group")
+	API = viper.GetString("api")
+	Metrics = viper.GetString("metrics")
+	Journal = viper.GetString("journal")
+	JournalMaxUse = size("journal_max_use", journal.DEFAULT_MAX_USE)
+	JournalMaxFileSize = size("journal_max_file_size", journal.DEFAULT_MAX_FILE_SIZE)
+	ForwardToSyslog = viper.GetBool("forward_to_syslog")
+	ForwardToKMsg = viper.GetBool("forward_to_kmsg")
+	ForwardTo = viper.GetString("forward_to")
+	NotifySocket = viper.GetString("notify_socket")
+	DBus = viper.GetBool("dbus")
 	Port = port(viper.GetInt("port"))
 	Retry = viper.GetDuration("retry") * time.Second
+	GC = viper.GetDuration("gc") * time.Second
+	WatchdogDevice = viper.GetString("watchdog_device")
+	RuntimeWatchdog = timespan("runtime_watchdog", 0)
+	RebootWatchdog = timespan("reboot_watchdog", 10*time.Minute)
+	AutoReload = viper.GetBool("auto_reload")
+	InhibitDelayMax = timespan("inhibit_delay_max", system.DEFAULT_INHIBIT_DELAY_MAX)
+	NetworkOnline = viper.GetStringSlice("network_online")
+	NetworkOnlineTimeout = timespan("network_online_timeout", system.DEFAULT_NETWORK_ONLINE_TIMEOUT)
+	Utmp = viper.GetString("utmp")
+	Wtmp = viper.GetString("wtmp")
+	Container = container(viper.GetString("container"))
+	LogLevel = viper.GetString("log_level")
 	Debug = viper.GetBool("debug")
 
+	setLogLevel()
+}
+
+// setLogLevel sets the level of the messages logged as configured
+func setLogLevel() {
+	if level, err := log.ParseLevel(LogLevel); err != nil {
+		log.WithField("value", LogLevel).Errorf("Invalid log level, using default")
+	} else {
+		log.SetLevel(level)
+	}
 	if Debug {
 		log.SetLevel(log.DebugLevel)
 	}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/spf13/viper"
+)
+
+// Whether the manager of the user running it is configured instead of the system manager, see SetUser
+var User bool
+
+// SetUser configures the manager of the user running it, as systemd --user is, and the clients of it.
+// The units are searched for in $XDG_CONFIG_HOME/systemgo/user, /etc/systemgo/user, $XDG_RUNTIME_DIR/systemgo/user and
+// /usr/lib/systemgo/user, the sockets are created in $XDG_RUNTIME_DIR/systemgo. The configuration is read from
+// $XDG_CONFIG_HOME/systemgo/user.yaml, the ones of the system manager do not apply
+func SetUser() {
+	User = true
+	viper.Reset()
+	load()
+}
+
+// setUserDefaults sets the defaults of the user manager, none of which requires root
+func setUserDefaults() {
+	runtime, config, data := userDirs()
+
+	viper.SetDefault("socket", filepath.Join(runtime, "systemgo", "private"))
+	viper.SetDefault("notify_socket", filepath.Join(runtime, "systemgo", "notify"))
+	viper.SetDefault("journal", filepath.Join(data, "systemgo", "journal"))
+	viper.SetDefault("paths", []string{
+		filepath.Join(config, "systemgo", "user"),
+		"/etc/systemgo/user",
+		filepath.Join(runtime, "systemgo", "user"),
+		"/usr/lib/systemgo/user",
+	})
+	viper.SetDefault("presets", []string{
+		filepath.Join(config, "systemgo", "user-preset"),
+		"/etc/systemgo/user-preset",
+		"/usr/lib/systemgo/user-preset",
+	})
+	viper.SetDefault("generators", []string{
+		filepath.Join(config, "systemgo", "user-generators"),
+		"/etc/systemgo/user-generators",
+		"/usr/lib/systemgo/user-generators",
+	})
+	viper.SetDefault("generator_dir", filepath.Join(runtime, "systemgo"))
+	viper.SetDefault("environment_paths", append([]string{filepath.Join(config, "environment.d")}, system.DEFAULT_ENVIRONMENT_PATHS...))
+	viper.SetDefault("tmpfiles", []string{
+		filepath.Join(config, "user-tmpfiles.d"),
+		filepath.Join(runtime, "user-tmpfiles.d"),
+		filepath.Join(data, "user-tmpfiles.d"),
+		"/etc/xdg/user-tmpfiles.d",
+		"/usr/share/user-tmpfiles.d",
+	})
+	viper.SetDefault("dbus", false)
+	viper.SetDefault("utmp", "")
+	viper.SetDefault("wtmp", "")
+	viper.SetDefault("container", "no")
+
+	viper.SetConfigName("user")
+	viper.AddConfigPath(filepath.Join(config, "systemgo"))
+}
+
+// userDirs returns the runtime, configuration and data directories of the user running the process,
+// as specified by the XDG Base Directory Specification
+func userDirs() (runtime, config, data string) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		if u, err := user.Current(); err == nil {
+			home = u.HomeDir
+		}
+	}
+
+	if runtime = os.Getenv("XDG_RUNTIME_DIR"); runtime == "" {
+		runtime = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	if config = os.Getenv("XDG_CONFIG_HOME"); config == "" {
+		config = filepath.Join(home, ".config")
+	}
+	if data = os.Getenv("XDG_DATA_HOME"); data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field is a field of a record named as the systemd journal does
+type Field struct {
+	Name  string
+	Value string
+}
+
+// JournalFields returns the fields of rec named as the systemd journal does, the trusted fields first.
+// The fields of the message logged by the manager(e.g. "exit_code") are upper-cased(e.g. "EXIT_CODE")
+func (rec Record) JournalFields() (fields []Field) {
+	tag := strings.TrimSuffix(rec.Unit, ".service")
+	if tag == "" {
+		tag = MANAGER_TAG
+	}
+
+	fields = []Field{
+		{"__REALTIME_TIMESTAMP", strconv.FormatInt(rec.Time.UnixNano()/1000, 10)},
+		{"_BOOT_ID", rec.Boot},
+	}
+	if rec.Unit != "" {
+		fields = append(fields, Field{"_SYSTEMD_UNIT", rec.Unit})
+	}
+	fields = append(fields,
+		Field{"PRIORITY", strconv.Itoa(int(rec.Priority))},
+		Field{"SYSLOG_FACILITY", strconv.Itoa(SYSLOG_FACILITY)},
+		Field{"SYSLOG_IDENTIFIER", tag},
+		Field{"MESSAGE", rec.Message},
+	)
+
+	// Fields colliding with the ones above are dropped
+	values := map[string]string{}
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+
+	var names []string
+	for key, v := range rec.Fields {
+		name := fieldName(key)
+		if _, ok := values[name]; !ok && name != "" {
+			names = append(names, name)
+			values[name] = v
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fields = append(fields, Field{name, values[name]})
+	}
+	return
+}
+
+// fieldName returns key as a valid name of a field supplied by a client of the systemd journal:
+// upper-case letters, digits and underscores, not starting with an underscore or a digit
+func fieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return strings.TrimLeft(string(name), "_0123456789")
+}
+
+// JournalJSON returns rec as formatted by journalctl -o json, the names of the fields mapped to their values
+func (rec Record) JournalJSON() map[string]string {
+	fields := rec.JournalFields()
+	m := make(map[string]string, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f.Value
+	}
+	return m
+}
+
+// WriteExport writes records to w in the Journal Export Format, as consumed by systemd-journal-remote(8)
+func WriteExport(w io.Writer, records ...Record) error {
+	bw := bufio.NewWriter(w)
+	for _, rec := range records {
+		for _, f := range rec.JournalFields() {
+			if !strings.ContainsRune(f.Value, '\n') {
+				bw.WriteString(f.Name + "=" + f.Value + "\n")
+				continue
+			}
+
+			// Values containing newlines are serialized in binary form, prefixed by their size
+			size := make([]byte, 8)
+			binary.LittleEndian.PutUint64(size, uint64(len(f.Value)))
+			bw.WriteString(f.Name + "\n")
+			bw.Write(size)
+			bw.WriteString(f.Value + "\n")
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,53 @@
+package journal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalJSON(t *testing.T) {
+	rec := Record{
+		Time:     time.Unix(1500000000, 123456789),
+		Boot:     "b00t",
+		Unit:     "a.service",
+		Priority: Err,
+		Message:  "Failed with result 'exit-code'",
+		Fields:   map[string]string{"exit_code": "1", "job-id": "3", "_private": "x", "message": "dropped"},
+	}
+
+	assert.Equal(t, map[string]string{
+		"__REALTIME_TIMESTAMP": "1500000000123456",
+		"_BOOT_ID":             "b00t",
+		"_SYSTEMD_UNIT":        "a.service",
+		"PRIORITY":             "3",
+		"SYSLOG_FACILITY":      "3",
+		"SYSLOG_IDENTIFIER":    "a",
+		"MESSAGE":              "Failed with result 'exit-code'",
+		"EXIT_CODE":            "1",
+		"JOB_ID":               "3",
+		"PRIVATE":              "x",
+	}, rec.JournalJSON())
+
+	manager := Record{Message: "Systemgo starting..."}.JournalJSON()
+	assert.Equal(t, MANAGER_TAG, manager["SYSLOG_IDENTIFIER"])
+	assert.NotContains(t, manager, "_SYSTEMD_UNIT")
+}
+
+func TestWriteExport(t *testing.T) {
+	records := []Record{
+		{Time: time.Unix(1, 0), Boot: "b00t", Unit: "a.service", Priority: Info, Message: "one"},
+		{Time: time.Unix(2, 0), Boot: "b00t", Priority: Warning, Message: "two\nlines"},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteExport(buf, records...), "WriteExport")
+	assert.Equal(t, "__REALTIME_TIMESTAMP=1000000\n_BOOT_ID=b00t\n_SYSTEMD_UNIT=a.service\n"+
+		"PRIORITY=6\nSYSLOG_FACILITY=3\nSYSLOG_IDENTIFIER=a\nMESSAGE=one\n\n"+
+		"__REALTIME_TIMESTAMP=2000000\n_BOOT_ID=b00t\n"+
+		"PRIORITY=4\nSYSLOG_FACILITY=3\nSYSLOG_IDENTIFIER=systemgo\nMESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n\n",
+		buf.String())
+}
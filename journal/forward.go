@@ -0,0 +1,130 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Number of the records queued for forwarding to a sink, the records appended are dropped while the queue is full
+const FORWARD_QUEUE = 1024
+
+// Port syslog is forwarded to, unless specified
+const SYSLOG_PORT = "514"
+
+var ErrBadTarget = errors.New(`Forwarding target should be "udp://host[:port]", "tcp://host[:port]" or "json://host:port"`)
+
+// Sink is a destination the records of a journal are forwarded to
+type Sink interface {
+	Forward(rec Record) error
+}
+
+// ForwardTo forwards the records appended to j afterwards to s.
+// Forwarding never blocks appending, the records are dropped while s is not keeping up
+func (j *Journal) ForwardTo(s Sink) {
+	fw := &forwarder{sink: s, records: make(chan Record, FORWARD_QUEUE)}
+	go fw.run()
+
+	j.mutex.Lock()
+	j.forwarders = append(j.forwarders, fw)
+	j.mutex.Unlock()
+}
+
+// Dial returns the sink specified by target: syslog over UDP or TCP("udp://host[:port]" or "tcp://host[:port]")
+// or JSON lines over TCP("json://host:port"). The connection is established once a record is forwarded
+func Dial(target string) (s Sink, err error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, ErrBadTarget
+	}
+
+	host := u.Host
+	if u.Port() == "" && u.Scheme != "json" {
+		host = net.JoinHostPort(u.Hostname(), SYSLOG_PORT)
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		return NewSyslog(u.Scheme, host), nil
+	case "json":
+		return NewShipper(host), nil
+	}
+	return nil, ErrBadTarget
+}
+
+// forwarder forwards the records queued to a sink
+type forwarder struct {
+	sink    Sink
+	records chan Record
+
+	// Number of the records dropped since the last one queued, the mutex of the journal must be locked to access it
+	dropped int
+}
+
+// forward queues rec, it is dropped if the queue is full.
+// It returns true, if rec is the first one dropped since the last one queued
+func (fw *forwarder) forward(rec Record) bool {
+	select {
+	case fw.records <- rec:
+		fw.dropped = 0
+		return false
+	default:
+		fw.dropped++
+		return fw.dropped == 1
+	}
+}
+
+func (fw *forwarder) run() {
+	for rec := range fw.records {
+		if err := fw.sink.Forward(rec); err != nil {
+			log.WithField("unit", rec.Unit).Debugf("Error forwarding journal record: %s", err)
+		}
+	}
+}
+
+// conn is a connection to a remote end records are forwarded to, which gets reestablished if it fails
+type conn struct {
+	dial func() (net.Conn, error)
+	net.Conn
+}
+
+// write writes b, reconnecting once if the connection is not established or fails
+func (c *conn) write(b []byte) (err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.Conn == nil {
+			if c.Conn, err = c.dial(); err != nil {
+				return
+			}
+		}
+		if _, err = c.Conn.Write(b); err == nil {
+			return nil
+		}
+		c.Conn.Close()
+		c.Conn = nil
+	}
+	return
+}
+
+// Shipper forwards the records as JSON lines over TCP
+type Shipper struct {
+	conn
+}
+
+// NewShipper returns a sink forwarding the records as JSON lines to the TCP address addr
+func NewShipper(addr string) *Shipper {
+	return &Shipper{conn{dial: func() (net.Conn, error) {
+		return net.Dial("tcp", addr)
+	}}}
+}
+
+// Forward writes rec as a JSON line
+func (sh *Shipper) Forward(rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return sh.write(append(b, '\n'))
+}
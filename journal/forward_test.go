@@ -0,0 +1,80 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDial(t *testing.T) {
+	for target, expected := range map[string]Sink{
+		"udp://loghost":       &Syslog{},
+		"tcp://loghost:1514":  &Syslog{},
+		"json://loghost:5170": &Shipper{},
+	} {
+		s, err := Dial(target)
+		if assert.NoError(t, err, target) {
+			assert.IsType(t, expected, s, target)
+		}
+	}
+
+	for _, target := range []string{"", "loghost", "http://loghost", "udp://"} {
+		_, err := Dial(target)
+		assert.Equal(t, ErrBadTarget, err, target)
+	}
+}
+
+func TestForwardSyslog(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "net.ListenPacket")
+	defer pc.Close()
+
+	sl := NewSyslog("udp", pc.LocalAddr().String())
+	sl.hostname = "host"
+
+	j := New()
+	j.ForwardTo(sl)
+
+	at := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, j.Append(Record{Time: at, Unit: "a.service", Priority: Err, Message: "failed"}), "j.Append")
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err, "pc.ReadFrom")
+	assert.Equal(t, "<27>Mar  4 05:06:07 host a: failed", string(b[:n]))
+}
+
+func TestForwardShipper(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "net.Listen")
+	defer l.Close()
+
+	j := New()
+	j.ForwardTo(NewShipper(l.Addr().String()))
+
+	for _, msg := range []string{"first", "second"} {
+		require.NoError(t, j.Append(Record{Unit: "a.service", Priority: Info, Message: msg}), "j.Append")
+	}
+
+	c, err := l.Accept()
+	require.NoError(t, err, "l.Accept")
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	scanner := bufio.NewScanner(c)
+	for _, msg := range []string{"first", "second"} {
+		require.True(t, scanner.Scan(), "scanner.Scan")
+
+		var rec Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), "json.Unmarshal")
+		assert.Equal(t, msg, rec.Message)
+		assert.Equal(t, "a.service", rec.Unit)
+		assert.Equal(t, j.Boot(), rec.Boot)
+	}
+}
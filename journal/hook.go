@@ -0,0 +1,59 @@
+package journal
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Priorities of the messages logged
+var levelPriorities = map[log.Level]Priority{
+	log.PanicLevel: Emerg,
+	log.FatalLevel: Crit,
+	log.ErrorLevel: Err,
+	log.WarnLevel:  Warning,
+	log.InfoLevel:  Info,
+	log.DebugLevel: Debug,
+}
+
+// hook appends the messages logged as records of a unit
+type hook struct {
+	journal *Journal
+	unit    string
+}
+
+// Hook returns a hook of a logger, which appends the messages logged as records of unit to j.
+// The fields of the messages(e.g. the job and its result) are recorded as well,
+// the messages of the manager itself are recorded with unit empty
+func (j *Journal) Hook(unit string) log.Hook {
+	return &hook{journal: j, unit: unit}
+}
+
+// Levels returns the levels of the messages recorded, debugging messages are not
+func (h *hook) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel, log.InfoLevel}
+}
+
+func (h *hook) Fire(entry *log.Entry) error {
+	rec := Record{Time: entry.Time, Unit: h.unit, Priority: levelPriorities[entry.Level], Message: entry.Message}
+	if len(entry.Data) > 0 {
+		rec.Fields = make(map[string]string, len(entry.Data))
+		for key, v := range entry.Data {
+			rec.Fields[key] = fmt.Sprint(v)
+		}
+	}
+
+	// The hook must never fail logging, the record is kept in memory regardless
+	h.journal.Append(rec)
+	return nil
+}
+
+// entryFields returns the fields of entry as "key=value" ordered by key
+func entryFields(entry *log.Entry) (fields []string) {
+	for key, v := range entry.Data {
+		fields = append(fields, fmt.Sprintf("%s=%v", key, v))
+	}
+	sort.Strings(fields)
+	return
+}
@@ -0,0 +1,455 @@
+// Package journal stores the records of the output of the units in memory and on disk
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Directory the journal is stored in by default
+const DEFAULT_DIR = "/var/log/systemgo"
+
+// Name of the file in the directory of the journal the records are appended to
+const JOURNAL_FILE = "system.journal"
+
+// Pattern of the names of the files rotated, named after the time of rotation
+const ARCHIVE_FILE = "system@%016x.journal"
+
+// Limits of the size of all the files of the journal and of a single file by default
+const (
+	DEFAULT_MAX_USE       = 128 << 20
+	DEFAULT_MAX_FILE_SIZE = DEFAULT_MAX_USE / 8
+)
+
+// Maximum number of records kept in memory
+const MAX_RECORDS = 10000
+
+// Path to the boot ID provided by the kernel
+var BOOT_ID_PATH = "/proc/sys/kernel/random/boot_id"
+
+var ErrBadPriority = errors.New(`Priority should be one of "emerg", "alert", "crit", "err", "warning", "notice", "info", "debug" or 0-7`)
+var ErrNoBoot = errors.New("No such boot")
+var ErrNotStored = errors.New("Record not stored")
+
+// Record is an entry of the journal, e.g. a line of output of a unit
+type Record struct {
+	Time     time.Time `json:"time"`
+	Boot     string    `json:"boot"`
+	Unit     string    `json:"unit"`
+	Priority Priority  `json:"priority"`
+	Message  string    `json:"message"`
+
+	// Fields of the message logged by the manager, e.g. the job the record is about
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Journal stores the records appended, the latest MAX_RECORDS of them in memory.
+// The records stored on disk are indexed, so that they can be looked up without reading the files
+type Journal struct {
+	boot string
+
+	// Latest records in order of appending, the last one having the sequence number next-1
+	records []Record
+	next    uint64
+
+	// Index of the records, the first entry having the sequence number base
+	entries []entry
+	base    uint64
+
+	// Sequence numbers of the records of each unit
+	units map[string][]uint64
+
+	// Boots in order of the records, the current one being the last
+	boots []boot
+
+	// Directory the files are in and the files the records are stored in as JSON lines, the last one being appended to.
+	// The journal is kept in memory only, if there are none
+	dir   string
+	files []*file
+
+	// Limits of the size of all the files and of a single one
+	maxUse, maxFileSize uint64
+
+	// Forwarders of the records appended
+	forwarders []*forwarder
+
+	mutex sync.Mutex
+
+	// Rate limits of the output of the units
+	limits     map[string]*rateLimit
+	limitMutex sync.Mutex
+}
+
+// entry is the index entry of a record
+type entry struct {
+	time     time.Time
+	priority Priority
+
+	// Location of the record, file is nil if the record is not stored
+	file   *file
+	offset int64
+	size   int
+}
+
+// file is a file of the journal
+type file struct {
+	*os.File
+	size int64
+
+	// Path to the file, which changes once it gets archived
+	path string
+
+	// Sequence number of the first record stored in the file
+	first uint64
+}
+
+// boot is the range of sequence numbers of the records appended in a boot
+type boot struct {
+	id          string
+	first, last uint64
+}
+
+// New returns a journal kept in memory only
+func New() *Journal {
+	return &Journal{boot: bootID(), units: map[string][]uint64{}, limits: map[string]*rateLimit{}}
+}
+
+// Open returns a journal storing the records in dir, which gets created if it does not exist.
+// The records stored there already get indexed. The files are limited to DEFAULT_MAX_USE and DEFAULT_MAX_FILE_SIZE,
+// unless SetLimits is called. No files are removed before SetLimits is called or the active file is rotated,
+// so that the ones kept by larger limits configured are not lost on opening
+func Open(dir string) (j *Journal, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	archived, err := filepath.Glob(filepath.Join(dir, strings.Replace(ARCHIVE_FILE, "%016x", "*", 1)))
+	if err != nil {
+		return
+	}
+	sort.Strings(archived)
+
+	j = New()
+	j.dir, j.maxUse, j.maxFileSize = dir, DEFAULT_MAX_USE, DEFAULT_MAX_FILE_SIZE
+	for _, path := range archived {
+		f, err := os.Open(path)
+		if err != nil {
+			j.Close()
+			return nil, err
+		}
+		if err = j.index(f, false); err != nil {
+			f.Close()
+			j.Close()
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, JOURNAL_FILE), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		j.Close()
+		return nil, err
+	}
+	if err = j.index(f, true); err != nil {
+		f.Close()
+		j.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// index indexes the records stored in f and adds it to the files of j. Lines, which can not be parsed, are skipped.
+// An incomplete last line is terminated, if f is appended to
+func (j *Journal) index(f *os.File, active bool) (err error) {
+	fl := &file{File: f, path: f.Name(), first: j.next}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 && active {
+			// Incomplete record of a journal not closed properly, the next one starts on a new line
+			_, err = f.Write([]byte{'\n'})
+			line = append(line, '\n')
+		}
+		if err == io.EOF {
+			fl.size += int64(len(line))
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var rec Record
+		if json.Unmarshal(line, &rec) == nil {
+			j.add(rec, fl, fl.size, len(line))
+		}
+		fl.size += int64(len(line))
+	}
+
+	j.files = append(j.files, fl)
+	return nil
+}
+
+// SetLimits limits the size of all the files of j to maxUse and the size of a single file to maxFileSize.
+// The oldest files get removed, once the limit is exceeded
+func (j *Journal) SetLimits(maxUse, maxFileSize uint64) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.maxUse, j.maxFileSize = maxUse, maxFileSize
+	return j.vacuum()
+}
+
+// bootID returns the ID of the current boot, a random one if the kernel does not provide it
+func bootID() string {
+	if b, err := ioutil.ReadFile(BOOT_ID_PATH); err == nil {
+		if id := strings.Replace(strings.TrimSpace(string(b)), "-", "", -1); id != "" {
+			return id
+		}
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Boot returns the ID of the boot the records are appended in
+func (j *Journal) Boot() string {
+	return j.boot
+}
+
+// Append appends rec to j, setting its boot ID and its time, unless specified.
+// The record is kept in memory, even if it can not be stored
+func (j *Journal) Append(rec Record) (err error) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Boot = j.boot
+
+	j.mutex.Lock()
+
+	var b []byte
+	var fl *file
+	if len(j.files) > 0 {
+		if b, err = json.Marshal(rec); err == nil {
+			b = append(b, '\n')
+			fl, err = j.write(b)
+		}
+	}
+	if fl != nil {
+		j.add(rec, fl, fl.size-int64(len(b)), len(b))
+	} else {
+		j.add(rec, nil, 0, 0)
+	}
+
+	// Trimmed only once twice as many are kept, so that appending takes constant time on average
+	if len(j.records) >= 2*MAX_RECORDS {
+		j.records = append(j.records[:0], j.records[len(j.records)-MAX_RECORDS:]...)
+	}
+	j.records = append(j.records, rec)
+
+	dropping := false
+	for _, fw := range j.forwarders {
+		dropping = fw.forward(rec) || dropping
+	}
+
+	if len(j.files) == 0 && len(j.entries) >= 2*MAX_RECORDS {
+		// The records, which are not kept in memory, can not be looked up
+		j.trim(len(j.entries) - MAX_RECORDS)
+	}
+	j.mutex.Unlock()
+
+	// Logged once unlocked, the message may be recorded in j
+	if dropping {
+		log.Warnf("Forwarding of journal records falling behind, dropping records")
+	}
+	return
+}
+
+// write appends b to the file appended to, which gets rotated first, if it would exceed the limit of the size of a file.
+// It returns the file b is written to, nil if it is not written. j.mutex must be locked
+func (j *Journal) write(b []byte) (fl *file, err error) {
+	fl = j.files[len(j.files)-1]
+	if fl.size > 0 && uint64(fl.size)+uint64(len(b)) > j.maxFileSize {
+		if err = j.rotate(); err != nil {
+			return nil, err
+		}
+		fl = j.files[len(j.files)-1]
+	}
+
+	if _, err = fl.Write(b); err != nil {
+		return nil, err
+	}
+	fl.size += int64(len(b))
+
+	return fl, j.vacuum()
+}
+
+// rotate archives the file appended to and starts a new one, j.mutex must be locked
+func (j *Journal) rotate() (err error) {
+	path := filepath.Join(j.dir, JOURNAL_FILE)
+	archive := filepath.Join(j.dir, fmt.Sprintf(ARCHIVE_FILE, time.Now().UnixNano()))
+
+	// The file archived is kept open to read the records stored
+	if err = os.Rename(path, archive); err != nil {
+		return
+	}
+	j.files[len(j.files)-1].path = archive
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return
+	}
+	j.files = append(j.files, &file{File: f, path: path, first: j.next})
+	return nil
+}
+
+// vacuum removes the oldest files archived, until the size of all the files does not exceed the limit.
+// The file appended to is never removed, j.mutex must be locked
+func (j *Journal) vacuum() (err error) {
+	use := j.use()
+	for len(j.files) > 1 && use > j.maxUse {
+		fl := j.files[0]
+		if err = os.Remove(fl.path); err != nil {
+			return
+		}
+		fl.Close()
+
+		j.files = append(j.files[:0], j.files[1:]...)
+		use -= uint64(fl.size)
+
+		// The records stored in the file removed can not be looked up anymore
+		if first := j.files[0].first; first > j.base {
+			j.trim(int(first - j.base))
+		}
+	}
+	return nil
+}
+
+// use returns the size of all the files, j.mutex must be locked
+func (j *Journal) use() (use uint64) {
+	for _, fl := range j.files {
+		use += uint64(fl.size)
+	}
+	return
+}
+
+// add indexes rec stored in fl at offset in size bytes, j.mutex must be locked
+func (j *Journal) add(rec Record, fl *file, offset int64, size int) {
+	seq := j.base + uint64(len(j.entries))
+	j.entries = append(j.entries, entry{time: rec.Time, priority: rec.Priority, file: fl, offset: offset, size: size})
+	j.units[rec.Unit] = append(j.units[rec.Unit], seq)
+
+	if n := len(j.boots); n == 0 || j.boots[n-1].id != rec.Boot {
+		j.boots = append(j.boots, boot{id: rec.Boot, first: seq})
+	}
+	j.boots[len(j.boots)-1].last = seq
+
+	j.next = seq + 1
+}
+
+// trim removes the first n entries from the index, j.mutex must be locked
+func (j *Journal) trim(n int) {
+	j.entries = append(j.entries[:0], j.entries[n:]...)
+	j.base += uint64(n)
+
+	for name, seqs := range j.units {
+		i := 0
+		for i < len(seqs) && seqs[i] < j.base {
+			i++
+		}
+		if i == len(seqs) {
+			delete(j.units, name)
+		} else {
+			j.units[name] = append(seqs[:0], seqs[i:]...)
+		}
+	}
+
+	boots := j.boots[:0]
+	for _, b := range j.boots {
+		if b.last >= j.base {
+			if b.first < j.base {
+				b.first = j.base
+			}
+			boots = append(boots, b)
+		}
+	}
+	j.boots = boots
+}
+
+// Usage is the disk usage of a journal
+type Usage struct {
+	// Number of the files and their size
+	Files int
+	Bytes uint64
+
+	// Limits of the size of all the files and of a single one
+	MaxUse, MaxFileSize uint64
+}
+
+// Usage returns the disk usage of j
+func (j *Journal) Usage() Usage {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return Usage{Files: len(j.files), Bytes: j.use(), MaxUse: j.maxUse, MaxFileSize: j.maxFileSize}
+}
+
+// Records returns the latest MAX_RECORDS records in order of appending
+func (j *Journal) Records() (records []Record) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	records = j.records
+	if len(records) > MAX_RECORDS {
+		records = records[len(records)-MAX_RECORDS:]
+	}
+	return append([]Record{}, records...)
+}
+
+// record returns the record with the sequence number seq, j.mutex must be locked
+func (j *Journal) record(seq uint64) (rec Record, err error) {
+	if first := j.next - uint64(len(j.records)); seq >= first {
+		return j.records[seq-first], nil
+	}
+
+	e := j.entries[seq-j.base]
+	if e.file == nil || e.file.File == nil {
+		return rec, ErrNotStored
+	}
+
+	b := make([]byte, e.size)
+	if _, err = e.file.ReadAt(b, e.offset); err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &rec)
+	return
+}
+
+// Close closes the files the records are stored in, the records are only kept in memory afterwards
+func (j *Journal) Close() (err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for _, fl := range j.files {
+		if e := fl.Close(); e != nil {
+			err = e
+		}
+		fl.File = nil
+	}
+	j.files = nil
+	return
+}
@@ -0,0 +1,178 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	for s, expected := range map[string]Priority{
+		"emerg": Emerg, "ERR": Err, "warning": Warning, "debug": Debug, "3": Err, "7": Debug,
+	} {
+		p, err := ParsePriority(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, p, s)
+		}
+	}
+
+	for _, s := range []string{"", "8", "-1", "loud"} {
+		_, err := ParsePriority(s)
+		assert.Equal(t, ErrBadPriority, err, s)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	j := New()
+	w := j.Writer("a.service", Info)
+
+	fmt.Fprint(w, "first\r\n<3>second\nthi")
+	fmt.Fprint(w, "rd\n<9>fourth\n")
+	fmt.Fprint(w, "incomplete")
+
+	records := j.Records()
+	require.Len(t, records, 4)
+	for i, expected := range []struct {
+		priority Priority
+		message  string
+	}{
+		{Info, "first"},
+		{Err, "second"},
+		{Info, "third"},
+		{Info, "<9>fourth"},
+	} {
+		assert.Equal(t, "a.service", records[i].Unit)
+		assert.Equal(t, j.Boot(), records[i].Boot)
+		assert.False(t, records[i].Time.IsZero(), "time set")
+		assert.Equal(t, expected.priority, records[i].Priority, expected.message)
+		assert.Equal(t, expected.message, records[i].Message)
+	}
+
+	fmt.Fprint(w, strings.Repeat("x", LINE_MAX+10))
+	records = j.Records()
+	require.Len(t, records, 5, "line longer than LINE_MAX split")
+	assert.Equal(t, "incomplete"+strings.Repeat("x", LINE_MAX-len("incomplete")), records[4].Message)
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	j, err := Open(filepath.Join(dir, "log"))
+	require.NoError(t, err, "Open")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, j.Append(Record{Unit: "a.service", Priority: Notice, Message: fmt.Sprint(i)}), "j.Append")
+	}
+	require.NoError(t, j.Close(), "j.Close")
+
+	f, err := os.Open(filepath.Join(dir, "log", JOURNAL_FILE))
+	require.NoError(t, err, "os.Open")
+	defer f.Close()
+
+	var stored []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), "json.Unmarshal")
+		stored = append(stored, rec)
+	}
+	if assert.Len(t, stored, 3) {
+		assert.Equal(t, "2", stored[2].Message)
+		assert.Equal(t, Notice, stored[2].Priority)
+		assert.Equal(t, j.Boot(), stored[2].Boot)
+	}
+}
+
+func TestRecordsLimit(t *testing.T) {
+	j := New()
+	for i := 0; i < 2*MAX_RECORDS+5; i++ {
+		j.Append(Record{Message: fmt.Sprint(i)})
+	}
+
+	records := j.Records()
+	require.Len(t, records, MAX_RECORDS)
+	assert.Equal(t, fmt.Sprint(2*MAX_RECORDS+4), records[MAX_RECORDS-1].Message, "latest records kept")
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir)
+	require.NoError(t, err, "Open")
+
+	// Records of the same size
+	start := time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	b, err := json.Marshal(Record{Time: start, Boot: j.Boot(), Unit: "a.service", Message: "000"})
+	require.NoError(t, err, "json.Marshal")
+	size := uint64(len(b) + 1)
+
+	// 4 records per file, 3 files at most
+	require.NoError(t, j.SetLimits(12*size, 4*size), "j.SetLimits")
+	for i := 0; i < 30; i++ {
+		rec := Record{Time: start.Add(time.Duration(i) * time.Second), Unit: "a.service", Message: fmt.Sprintf("%03d", i)}
+		require.NoError(t, j.Append(rec), "j.Append")
+	}
+
+	usage := j.Usage()
+	assert.Equal(t, 3, usage.Files)
+	assert.True(t, usage.Bytes <= 12*size, "usage %d within limit %d", usage.Bytes, 12*size)
+	assert.Equal(t, Usage{Files: 3, Bytes: usage.Bytes, MaxUse: 12 * size, MaxFileSize: 4 * size}, usage)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.journal"))
+	require.NoError(t, err, "filepath.Glob")
+	assert.Len(t, files, 3, "oldest files removed")
+
+	res, err := j.Query(Query{})
+	require.NoError(t, err, "j.Query")
+	expected := []string{"020", "021", "022", "023", "024", "025", "026", "027", "028", "029"}
+	assert.Equal(t, expected, messages(res.Records), "records of the files removed can not be looked up")
+	require.NoError(t, j.Close(), "j.Close")
+
+	j, err = Open(dir)
+	require.NoError(t, err, "Open")
+	defer j.Close()
+
+	res, err = j.Query(Query{Units: []string{"a.service"}})
+	require.NoError(t, err, "j.Query")
+	assert.Equal(t, expected, messages(res.Records), "files archived indexed in order")
+}
+
+func TestRateLimit(t *testing.T) {
+	j := New()
+	j.SetRateLimit("a.service", 100*time.Millisecond, 2)
+	w := j.Writer("a.service", Info)
+
+	fmt.Fprint(w, "1\n2\n3\n4\n")
+	assert.Equal(t, []string{"1", "2"}, messages(j.Records()), "records exceeding the burst dropped")
+
+	time.Sleep(150 * time.Millisecond)
+	fmt.Fprint(w, "5\n")
+
+	records := j.Records()
+	require.Len(t, records, 4)
+	assert.Equal(t, "Suppressed 2 messages from a.service", records[2].Message)
+	assert.Equal(t, Warning, records[2].Priority)
+	assert.Equal(t, "5", records[3].Message)
+
+	j.SetRateLimit("b.service", 0, 0)
+	w = j.Writer("b.service", Info)
+	for i := 0; i < 10; i++ {
+		fmt.Fprintln(w, i)
+	}
+	res, err := j.Query(Query{Units: []string{"b.service"}})
+	require.NoError(t, err, "j.Query")
+	assert.Len(t, res.Records, 10, "output not limited")
+}
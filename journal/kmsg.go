@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Path to the device of the kernel log buffer
+var KMSG_PATH = "/dev/kmsg"
+
+// Tag of the messages of the manager itself
+const MANAGER_TAG = "systemgo"
+
+// KMsg writes messages to the kernel log buffer, which is available before any filesystem is mounted or
+// the journal is opened. It is a sink forwarding records and a hook of the logger logging the messages of the manager
+type KMsg struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewKMsg returns a writer of messages to KMSG_PATH, which gets opened once a message is written
+func NewKMsg() *KMsg {
+	return &KMsg{}
+}
+
+// Forward writes rec tagged with the name of its unit
+func (k *KMsg) Forward(rec Record) error {
+	tag := strings.TrimSuffix(rec.Unit, ".service")
+	if tag == "" {
+		tag = MANAGER_TAG
+	}
+	return k.write(rec.Priority, tag, rec.Message)
+}
+
+// Levels returns the levels of the messages of the manager written, debugging messages are not.
+// The kernel rate limits the messages written by userspace
+func (k *KMsg) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel, log.InfoLevel}
+}
+
+// Fire writes the message of the manager logged as entry. The message is dropped if KMSG_PATH can not be opened,
+// e.g. before /dev is mounted
+func (k *KMsg) Fire(entry *log.Entry) error {
+	msg := entry.Message
+	for _, field := range entryFields(entry) {
+		msg += " " + field
+	}
+
+	k.write(levelPriorities[entry.Level], fmt.Sprintf("%s[%d]", MANAGER_TAG, os.Getpid()), msg)
+	return nil
+}
+
+// write writes msg with priority p tagged with tag.
+// Each write is a record of the kernel log, hence the newlines in msg are replaced by spaces
+func (k *KMsg) write(p Priority, tag, msg string) (err error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.file == nil {
+		if k.file, err = os.OpenFile(KMSG_PATH, os.O_WRONLY, 0); err != nil {
+			return
+		}
+	}
+
+	msg = strings.Replace(msg, "\n", " ", -1)
+	if _, err = fmt.Fprintf(k.file, "<%d>%s: %s\n", SYSLOG_FACILITY*8+int(p), tag, msg); err != nil {
+		k.file.Close()
+		k.file = nil
+	}
+	return
+}
@@ -0,0 +1,37 @@
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMsg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-kmsg-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { KMSG_PATH = path }(KMSG_PATH)
+	KMSG_PATH = filepath.Join(dir, "kmsg")
+
+	k := NewKMsg()
+	assert.Error(t, k.Forward(Record{Unit: "a.service", Message: "dropped"}), "kmsg does not exist")
+
+	require.NoError(t, ioutil.WriteFile(KMSG_PATH, nil, 0600), "ioutil.WriteFile")
+	require.NoError(t, k.Forward(Record{Unit: "a.service", Priority: Warning, Message: "multi\nline"}), "k.Forward")
+	require.NoError(t, k.Fire(&log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "Error starting unit",
+		Data:    log.Fields{"unit": "b.service", "err": "failed"},
+	}), "k.Fire")
+
+	b, err := ioutil.ReadFile(KMSG_PATH)
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, fmt.Sprintf("<28>a: multi line\n<27>systemgo[%d]: Error starting unit err=failed unit=b.service\n", os.Getpid()), string(b))
+}
@@ -0,0 +1,52 @@
+package journal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Priority of a record, as defined by syslog(3)
+type Priority int
+
+const (
+	Emerg Priority = iota
+	Alert
+	Crit
+	Err
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func (p Priority) String() string {
+	if p < Emerg || p > Debug {
+		return strconv.Itoa(int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority parses the priority specified by name(e.g. "err") or by number(e.g. "3")
+func ParsePriority(s string) (p Priority, err error) {
+	for i, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return Priority(i), nil
+		}
+	}
+
+	if i, err := strconv.Atoi(s); err == nil && i >= int(Emerg) && i <= int(Debug) {
+		return Priority(i), nil
+	}
+	return 0, ErrBadPriority
+}
+
+// splitPriority splits the "<N>" prefix specifying the priority of line as sd-daemon(3) does off line.
+// The priority specified is returned, def if none is
+func splitPriority(line string, def Priority) (p Priority, msg string) {
+	if len(line) >= 3 && line[0] == '<' && line[2] == '>' && line[1] >= '0' && line[1] <= '7' {
+		return Priority(line[1] - '0'), line[3:]
+	}
+	return def, line
+}
@@ -0,0 +1,154 @@
+package journal
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Query specifies the records looked up in a journal, the zero value matches all of them
+type Query struct {
+	// Units the records are of, any if empty
+	Units []string
+
+	// Records appended at or after Since and before Until, unless zero
+	Since, Until time.Time
+
+	// Records at least as important as Priority, unless nil
+	Priority *Priority
+
+	// Boot the records are appended in, specified by ID or by offset: "0" is the current boot, "-1" the previous one,
+	// "1" the first one recorded. Records of any boot match, if empty
+	Boot string
+
+	// Only the last Lines records matching are returned, unless 0
+	Lines int
+
+	// Records appended before the cursor returned by a previous query are skipped, so that the journal can be followed
+	Cursor uint64
+}
+
+// Result is the result of a query
+type Result struct {
+	// Records matching in order of appending
+	Records []Record
+
+	// Cursor to continue the query with, when following the journal
+	Cursor uint64
+}
+
+// Query returns the records in j matching q.
+// The records are looked up in the index, only the ones matching are read
+func (j *Journal) Query(q Query) (res Result, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	res.Cursor = j.next
+
+	lo, hi, err := j.bootRange(q.Boot)
+	if err != nil {
+		return
+	}
+	if q.Cursor > lo {
+		lo = q.Cursor
+	}
+	if lo >= hi {
+		return
+	}
+
+	// Candidates are checked from the last one, so that only the ones returned get read
+	n, seq := int(hi-lo), func(i int) uint64 { return lo + uint64(i) }
+	if len(q.Units) > 0 {
+		seqs := j.unitSeqs(q.Units, lo, hi)
+		n, seq = len(seqs), func(i int) uint64 { return seqs[i] }
+	}
+
+	var matching []uint64
+	for i := n - 1; i >= 0 && (q.Lines <= 0 || len(matching) < q.Lines); i-- {
+		if q.matches(j.entries[seq(i)-j.base]) {
+			matching = append(matching, seq(i))
+		}
+	}
+
+	res.Records = make([]Record, 0, len(matching))
+	for i := len(matching) - 1; i >= 0; i-- {
+		rec, err := j.record(matching[i])
+		if err != nil {
+			return res, err
+		}
+		res.Records = append(res.Records, rec)
+	}
+	return
+}
+
+// bootRange returns the range of sequence numbers of the records of the boot specified as in Query, j.mutex must be locked
+func (j *Journal) bootRange(id string) (lo, hi uint64, err error) {
+	if id == "" {
+		return j.base, j.next, nil
+	}
+
+	if n, err := strconv.Atoi(id); err == nil {
+		// The current boot is the last one, even if nothing was recorded in it yet
+		boots := len(j.boots)
+		if boots == 0 || j.boots[boots-1].id != j.boot {
+			boots++
+		}
+
+		i := n - 1
+		if n <= 0 {
+			i = boots - 1 + n
+		}
+		switch {
+		case i < 0 || i >= boots:
+			return 0, 0, ErrNoBoot
+		case i == len(j.boots):
+			return j.next, j.next, nil
+		}
+		return j.boots[i].first, j.boots[i].last + 1, nil
+	}
+
+	for _, b := range j.boots {
+		if b.id == id {
+			return b.first, b.last + 1, nil
+		}
+	}
+	if id == j.boot {
+		return j.next, j.next, nil
+	}
+	return 0, 0, ErrNoBoot
+}
+
+// matches reports whether the record of e matches the priority and the time range of q.
+// The clock may be set back between the records appended, hence their times are not assumed to be in order
+func (q Query) matches(e entry) bool {
+	switch {
+	case q.Priority != nil && e.priority > *q.Priority:
+		return false
+	case !q.Since.IsZero() && e.time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// unitSeqs returns the sequence numbers of the records of units in [lo, hi) in order, j.mutex must be locked
+func (j *Journal) unitSeqs(units []string, lo, hi uint64) (seqs []uint64) {
+	seen := map[string]bool{}
+	for _, name := range units {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		all := j.units[name]
+		from := sort.Search(len(all), func(i int) bool { return all[i] >= lo })
+		to := sort.Search(len(all), func(i int) bool { return all[i] >= hi })
+		seqs = append(seqs, all[from:to]...)
+	}
+
+	if len(seen) > 1 {
+		sort.Slice(seqs, func(a, b int) bool { return seqs[a] < seqs[b] })
+	}
+	return
+}
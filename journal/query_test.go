@@ -0,0 +1,133 @@
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// messages returns the messages of records
+func messages(records []Record) (msgs []string) {
+	for _, rec := range records {
+		msgs = append(msgs, rec.Message)
+	}
+	return
+}
+
+func TestQuery(t *testing.T) {
+	j := New()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, rec := range []Record{
+		{Unit: "a.service", Priority: Info},
+		{Unit: "b.service", Priority: Err},
+		{Unit: "a.service", Priority: Warning},
+		{Unit: "c.service", Priority: Debug},
+		{Unit: "b.service", Priority: Info},
+	} {
+		rec.Time, rec.Message = start.Add(time.Duration(i)*time.Minute), fmt.Sprint(i)
+		require.NoError(t, j.Append(rec), "j.Append")
+	}
+
+	warning := Warning
+	for _, c := range []struct {
+		query    Query
+		expected []string
+	}{
+		{Query{}, []string{"0", "1", "2", "3", "4"}},
+		{Query{Units: []string{"a.service"}}, []string{"0", "2"}},
+		{Query{Units: []string{"b.service", "a.service", "b.service"}}, []string{"0", "1", "2", "4"}},
+		{Query{Units: []string{"d.service"}}, nil},
+		{Query{Priority: &warning}, []string{"1", "2"}},
+		{Query{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []string{"1", "2"}},
+		{Query{Lines: 2}, []string{"3", "4"}},
+		{Query{Units: []string{"a.service", "b.service"}, Lines: 3, Priority: &warning}, []string{"1", "2"}},
+		{Query{Cursor: 3}, []string{"3", "4"}},
+		{Query{Boot: "0"}, []string{"0", "1", "2", "3", "4"}},
+		{Query{Boot: j.Boot(), Units: []string{"c.service"}}, []string{"3"}},
+	} {
+		res, err := j.Query(c.query)
+		if assert.NoError(t, err, "%+v", c.query) {
+			assert.Equal(t, c.expected, messages(res.Records), "%+v", c.query)
+			assert.Equal(t, uint64(5), res.Cursor, "%+v", c.query)
+		}
+	}
+
+	// The clock was set back
+	require.NoError(t, j.Append(Record{Unit: "a.service", Time: start, Message: "5"}), "j.Append")
+	res, err := j.Query(Query{Since: start.Add(3 * time.Minute)})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"3", "4"}, messages(res.Records))
+	}
+	res, err = j.Query(Query{Until: start.Add(time.Minute)})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"0", "5"}, messages(res.Records))
+	}
+
+	for _, boot := range []string{"-1", "2", "unknown"} {
+		_, err := j.Query(Query{Boot: boot})
+		assert.Equal(t, ErrNoBoot, err, boot)
+	}
+}
+
+func TestQueryBoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-query-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	// A random ID is used for each journal
+	defer func(path string) { BOOT_ID_PATH = path }(BOOT_ID_PATH)
+	BOOT_ID_PATH = filepath.Join(dir, "boot_id")
+
+	var boots []string
+	for i := 0; i < 2; i++ {
+		j, err := Open(dir)
+		require.NoError(t, err, "Open")
+		boots = append(boots, j.Boot())
+
+		for k := 0; k < 3; k++ {
+			require.NoError(t, j.Append(Record{Unit: "a.service", Message: fmt.Sprint(i, k)}), "j.Append")
+		}
+		require.NoError(t, j.Close(), "j.Close")
+	}
+
+	// Incomplete record of a journal not closed properly
+	f, err := os.OpenFile(filepath.Join(dir, JOURNAL_FILE), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err, "os.OpenFile")
+	_, err = f.WriteString(`{"unit":"a.ser`)
+	require.NoError(t, err, "f.WriteString")
+	require.NoError(t, f.Close(), "f.Close")
+
+	j, err := Open(dir)
+	require.NoError(t, err, "Open")
+	defer j.Close()
+	require.NoError(t, j.Append(Record{Unit: "a.service", Message: "current"}), "j.Append")
+
+	for boot, expected := range map[string][]string{
+		"":       {"0 0", "0 1", "0 2", "1 0", "1 1", "1 2", "current"},
+		"0":      {"current"},
+		"-1":     {"1 0", "1 1", "1 2"},
+		"-2":     {"0 0", "0 1", "0 2"},
+		"1":      {"0 0", "0 1", "0 2"},
+		boots[1]: {"1 0", "1 1", "1 2"},
+	} {
+		res, err := j.Query(Query{Boot: boot, Units: []string{"a.service"}})
+		if assert.NoError(t, err, boot) {
+			assert.Equal(t, expected, messages(res.Records), boot)
+		}
+	}
+
+	res, err := j.Query(Query{Lines: 2})
+	require.NoError(t, err, "j.Query")
+	assert.Equal(t, []string{"1 2", "current"}, messages(res.Records), "records read from the file")
+
+	require.NoError(t, j.Append(Record{Unit: "b.service", Message: "followed"}), "j.Append")
+	res, err = j.Query(Query{Cursor: res.Cursor})
+	require.NoError(t, err, "j.Query")
+	assert.Equal(t, []string{"followed"}, messages(res.Records), "records appended after the cursor")
+}
@@ -0,0 +1,84 @@
+package journal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rate limit of the output of a unit by default, as LogRateLimitIntervalSec= and LogRateLimitBurst= of systemd
+const (
+	DEFAULT_RATE_LIMIT_INTERVAL = 30 * time.Second
+	DEFAULT_RATE_LIMIT_BURST    = 10000
+)
+
+// rateLimit limits the number of the records of a unit appended within an interval
+type rateLimit struct {
+	interval time.Duration
+	burst    int
+
+	// Start of the current interval, the number of the records appended and suppressed within it
+	begin             time.Time
+	count, suppressed int
+}
+
+// SetRateLimit limits the output of unit written to j to burst records within interval.
+// The records exceeding the limit are dropped and a record reporting the number of the ones suppressed is appended,
+// once the interval is over. The output is not limited, if either is 0
+func (j *Journal) SetRateLimit(unit string, interval time.Duration, burst int) {
+	j.limitMutex.Lock()
+	defer j.limitMutex.Unlock()
+
+	if rl, ok := j.limits[unit]; ok {
+		rl.interval, rl.burst = interval, burst
+		return
+	}
+	j.limits[unit] = &rateLimit{interval: interval, burst: burst}
+}
+
+// appendLimited appends rec, unless the rate limit of its unit is exceeded
+func (j *Journal) appendLimited(rec Record) error {
+	now := time.Now()
+
+	allowed, suppressed := j.limit(rec.Unit, now)
+	if suppressed > 0 {
+		j.Append(Record{
+			Time:     now,
+			Unit:     rec.Unit,
+			Priority: Warning,
+			Message:  fmt.Sprintf("Suppressed %d messages from %s", suppressed, rec.Unit),
+		})
+	}
+
+	if !allowed {
+		return nil
+	}
+	return j.Append(rec)
+}
+
+// limit records a record of unit at now. It reports whether the record is within the rate limit
+// and returns the number of the records suppressed within the interval, which is over
+func (j *Journal) limit(unit string, now time.Time) (allowed bool, suppressed int) {
+	j.limitMutex.Lock()
+	defer j.limitMutex.Unlock()
+
+	rl, ok := j.limits[unit]
+	if !ok {
+		rl = &rateLimit{interval: DEFAULT_RATE_LIMIT_INTERVAL, burst: DEFAULT_RATE_LIMIT_BURST}
+		j.limits[unit] = rl
+	}
+	if rl.interval <= 0 || rl.burst <= 0 {
+		return true, 0
+	}
+
+	if now.Sub(rl.begin) >= rl.interval {
+		suppressed = rl.suppressed
+		rl.begin, rl.count, rl.suppressed = now, 0, 0
+	}
+
+	if rl.count >= rl.burst {
+		rl.suppressed++
+		return false, suppressed
+	}
+	rl.count++
+	return true, suppressed
+}
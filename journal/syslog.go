@@ -0,0 +1,87 @@
+package journal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Path to the socket of the local syslog daemon
+var SYSLOG_PATH = "/dev/log"
+
+// Facility of the records forwarded to syslog, LOG_DAEMON as defined by syslog(3)
+const SYSLOG_FACILITY = 3
+
+// Format of the timestamps of the messages forwarded to syslog, as defined by RFC 3164
+const syslogTimeFormat = "Jan _2 15:04:05"
+
+// Syslog forwards the records to syslog as RFC 3164 messages tagged with the name of the unit
+type Syslog struct {
+	conn
+
+	// Hostname sent to remote hosts, empty when forwarding to the local daemon
+	hostname string
+
+	// Whether the messages are framed by newlines
+	stream bool
+}
+
+// NewSyslog returns a sink forwarding the records to syslog at addr reached using network("udp", "tcp", "unix"
+// or "unixgram"). The records are forwarded to the local daemon listening on SYSLOG_PATH, if network is empty
+func NewSyslog(network, addr string) *Syslog {
+	sl := &Syslog{stream: network == "tcp" || network == "unix"}
+
+	switch network {
+	case "":
+		sl.dial = func() (c net.Conn, err error) {
+			if c, err = net.Dial("unixgram", SYSLOG_PATH); err == nil {
+				sl.stream = false
+				return
+			}
+			if c, err = net.Dial("unix", SYSLOG_PATH); err == nil {
+				sl.stream = true
+			}
+			return
+		}
+	case "udp", "tcp":
+		sl.hostname, _ = os.Hostname()
+		fallthrough
+	default:
+		sl.dial = func() (net.Conn, error) {
+			return net.Dial(network, addr)
+		}
+	}
+	return sl
+}
+
+// Forward writes rec as a syslog message
+func (sl *Syslog) Forward(rec Record) (err error) {
+	// Connected first, the framing depends on the kind of the socket of the local daemon
+	if sl.Conn == nil {
+		if sl.Conn, err = sl.dial(); err != nil {
+			return
+		}
+	}
+	return sl.write([]byte(sl.format(rec)))
+}
+
+// format returns rec as a syslog message
+func (sl *Syslog) format(rec Record) string {
+	host := ""
+	if sl.hostname != "" {
+		host = sl.hostname + " "
+	}
+
+	tag := strings.TrimSuffix(rec.Unit, ".service")
+	if tag == "" {
+		tag = "systemgo"
+	}
+
+	msg := fmt.Sprintf("<%d>%s %s%s: %s", SYSLOG_FACILITY*8+int(rec.Priority), rec.Time.Format(syslogTimeFormat),
+		host, tag, rec.Message)
+	if sl.stream {
+		msg += "\n"
+	}
+	return msg
+}
@@ -0,0 +1,58 @@
+package journal
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Maximum length of a line, longer lines are split into multiple records
+const LINE_MAX = 2048
+
+// writer appends a record per line written to the journal
+type writer struct {
+	journal  *Journal
+	unit     string
+	priority Priority
+
+	// Incomplete line written
+	partial []byte
+	mutex   sync.Mutex
+}
+
+// Writer returns a writer, which appends a record of unit per line written to j.
+// The priority of a line can be specified by a "<N>" prefix as sd-daemon(3) defines, priority is used otherwise.
+// Lines get recorded once complete or LINE_MAX bytes long, unless the rate limit set by SetRateLimit is exceeded
+func (j *Journal) Writer(unit string, priority Priority) io.Writer {
+	return &writer{journal: j, unit: unit, priority: priority}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.partial = append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			if len(w.partial) < LINE_MAX {
+				return len(b), nil
+			}
+			i = LINE_MAX
+		}
+
+		line := string(bytes.TrimSuffix(w.partial[:i], []byte("\r")))
+		if i < len(w.partial) && w.partial[i] == '\n' {
+			i++
+		}
+		w.partial = w.partial[i:]
+
+		// The output of the unit must never block or fail, the record is kept in memory regardless
+		p, msg := splitPriority(line, w.priority)
+		if err := w.journal.appendLimited(Record{Unit: w.unit, Priority: p, Message: msg}); err != nil {
+			log.WithField("unit", w.unit).Debugf("Error storing journal record: %s", err)
+		}
+	}
+}
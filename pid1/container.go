@@ -0,0 +1,63 @@
+package pid1
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ContainerHandler serves the requests the init process of a container receives
+type ContainerHandler interface {
+	// Poweroff is requested by SIGTERM and SIGINT, which the container managers stop the containers with
+	Poweroff() error
+
+	// Forward is called with the signals received, which are meant for the payload of the container
+	Forward(sig syscall.Signal)
+
+	Reaper
+}
+
+// forwarded are the signals the init process of a container forwards to the payload
+var forwarded = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGWINCH,
+}
+
+// RunContainer installs the signal handlers of the init process of a container and serves the signals received using h.
+// Unlike Run, ctrl-alt-del is not handled and SIGTERM stops the container instead of re-executing the manager.
+// RunContainer never returns
+func RunContainer(h ContainerHandler) {
+	signal.Ignore(syscall.SIGPIPE, syscall.SIGALRM, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU)
+
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs, append(forwarded, syscall.SIGTERM, syscall.SIGINT)...)
+
+	go Watch(h)
+
+	stopping := false
+	for sig := range sigs {
+		log.WithField("signal", sig).Debugf("pid1.RunContainer")
+
+		switch sig {
+		case syscall.SIGTERM, syscall.SIGINT:
+			if stopping {
+				continue
+			}
+			stopping = true
+
+			go func() {
+				if err := h.Poweroff(); err != nil {
+					log.Errorf("Error stopping the container: %s", err)
+				}
+			}()
+
+		default:
+			h.Forward(sig.(syscall.Signal))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package pid1
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// ExitError is returned by WaitCommand, if the process reaped by Reap did not exit successfully
+type ExitError struct {
+	Exit
+}
+
+func (err *ExitError) Error() string {
+	if err.Status.Signaled() {
+		return fmt.Sprintf("signal: %s", err.Status.Signal())
+	}
+	return fmt.Sprintf("exit status %d", err.Status.ExitStatus())
+}
+
+// RunCommand starts cmd and waits for it to exit, as cmd.Run does, see WaitCommand.
+// The commands run by the manager must be run using RunCommand or CombinedOutput, as the children
+// reaped by Watch can not be waited for by cmd.Run
+func RunCommand(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	_, err := WaitCommand(cmd)
+	return err
+}
+
+// CombinedOutput runs cmd using RunCommand and returns its standard output and standard error combined,
+// as cmd.CombinedOutput does
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return nil, errors.New("exec: Stdout or Stderr already set")
+	}
+
+	var b bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &b, &b
+	err := RunCommand(cmd)
+	return b.Bytes(), err
+}
+
+// WaitCommand waits for cmd started to exit, as cmd.Wait does. If the process has been reaped by Reap already,
+// its exit is claimed using Wait and returned, an ExitError is returned, if it did not exit successfully
+func WaitCommand(cmd *exec.Cmd) (exit *Exit, err error) {
+	if err = cmd.Wait(); !errors.Is(err, syscall.ECHILD) {
+		return nil, err
+	}
+
+	status, err := Wait(cmd.Process.Pid)
+	if err != nil {
+		return nil, err
+	}
+	exit = &Exit{PID: cmd.Process.Pid, Status: status}
+	if status.Signaled() || status.ExitStatus() != 0 {
+		return exit, &ExitError{*exit}
+	}
+	return exit, nil
+}
@@ -0,0 +1,60 @@
+package pid1
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitCommand(t *testing.T) {
+	for code, expected := range map[int]string{0: "", 3: "exit status 3"} {
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code))
+		require.NoError(t, cmd.Start(), "cmd.Start")
+
+		// The process gets reaped by the init process first
+		reapAll(t, cmd.Process.Pid)
+
+		exit, err := WaitCommand(cmd)
+		if expected == "" {
+			assert.NoError(t, err, "WaitCommand")
+		} else {
+			assert.EqualError(t, err, expected, "WaitCommand")
+		}
+		if assert.NotNil(t, exit, "exit claimed") {
+			assert.Equal(t, code, exit.Status.ExitStatus())
+		}
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	defer func() {
+		// The children of the tests run next are not reaped behind their backs
+		close(done)
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+
+		// Reap races cmd.Wait, as Watch does
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				Reap()
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		out, err := CombinedOutput(exec.Command("sh", "-c", "echo out; echo err >&2; exit 2"))
+		assert.Equal(t, "out\nerr\n", string(out), "output")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "exit status 2")
+		}
+	}
+}
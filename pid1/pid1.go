@@ -0,0 +1,108 @@
+// Package pid1 implements the duties of the init process:
+// reaping of orphaned processes and handling of the signals sent to it
+package pid1
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Handler serves the requests the init process receives
+type Handler interface {
+	// DaemonReexec is requested by SIGTERM
+	DaemonReexec() error
+
+	// CtrlAltDel is requested by SIGINT, which the kernel sends on ctrl-alt-del
+	CtrlAltDel() error
+
+	Reaper
+}
+
+// Reaper is implemented by any value that has a Reaped method
+type Reaper interface {
+	// Reaped is called with the wait status of every process reaped
+	Reaped(pid int, status syscall.WaitStatus)
+}
+
+// ignored are the signals, which would kill an ordinary process or stop it
+var ignored = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGPIPE,
+	syscall.SIGALRM,
+	syscall.SIGTSTP,
+	syscall.SIGTTIN,
+	syscall.SIGTTOU,
+}
+
+// Run installs the signal handlers of the init process and serves the signals received using h.
+// Run never returns
+func Run(h Handler) {
+	signal.Ignore(ignored...)
+
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+
+	if err := enableCtrlAltDel(); err != nil {
+		log.Errorf("Error enabling ctrl-alt-del handling: %s", err)
+	}
+
+	go Watch(h)
+
+	for sig := range sigs {
+		log.WithField("signal", sig).Debugf("pid1.Run")
+
+		switch sig {
+		case syscall.SIGTERM:
+			go func() {
+				if err := h.DaemonReexec(); err != nil {
+					log.Errorf("Error re-executing: %s", err)
+				}
+			}()
+
+		case syscall.SIGINT:
+			go func() {
+				if err := h.CtrlAltDel(); err != nil {
+					log.Errorf("Error handling ctrl-alt-del: %s", err)
+				}
+			}()
+		}
+	}
+}
+
+// Watch reaps the children of the calling process on SIGCHLD and passes their statuses to r.
+// Once Watch is called, the children are not expected to be waited for by their owners, see Watching.
+// Watch never returns
+func Watch(r Reaper) {
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs, syscall.SIGCHLD)
+
+	atomic.StoreInt32(&watching, 1)
+
+	// Children may have exited before the handler was installed
+	reap(r)
+
+	for range sigs {
+		reap(r)
+	}
+}
+
+// Watching returns whether the children of the calling process are reaped by Watch
+func Watching() bool {
+	return atomic.LoadInt32(&watching) == 1
+}
+
+var watching int32
+
+// reap reaps the children exited and passes their statuses to r
+func reap(r Reaper) {
+	for _, exit := range Reap() {
+		r.Reaped(exit.PID, exit.Status)
+	}
+}
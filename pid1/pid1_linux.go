@@ -0,0 +1,8 @@
+package pid1
+
+import "syscall"
+
+// enableCtrlAltDel makes the kernel send SIGINT to the init process on ctrl-alt-del instead of rebooting
+func enableCtrlAltDel() error {
+	return syscall.Reboot(syscall.LINUX_REBOOT_CMD_CAD_OFF)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package pid1
+
+// enableCtrlAltDel is a no-op, ctrl-alt-del is only handled on Linux
+func enableCtrlAltDel() error {
+	return nil
+}
@@ -0,0 +1,121 @@
+package pid1
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Statuses of processes reaped, which are not claimed by Wait, are dropped after RETENTION
+const RETENTION = time.Minute
+
+// Exit is the wait status of a process reaped
+type Exit struct {
+	PID    int
+	Status syscall.WaitStatus
+}
+
+type reaped struct {
+	status syscall.WaitStatus
+	since  time.Time
+}
+
+var (
+	mutex sync.Mutex
+
+	// Processes reaped, which are not waited for yet (pid -> status)
+	exited = map[int]reaped{}
+
+	// Processes waited for, which are not reaped yet (pid -> channel the status gets sent to)
+	waiters = map[int]chan syscall.WaitStatus{}
+)
+
+// ErrNoSuchChild is returned by Wait, if the process is not a child of the calling process
+// and its status is not held, e.g. it was dropped after RETENTION
+var ErrNoSuchChild = errors.New("No such child process")
+
+// Reap reaps all the children of the calling process, which have exited, and returns their statuses.
+// The processes, which are waited for or are going to be waited for by Wait, get their statuses delivered
+func Reap() (exits []Exit) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			break
+		}
+
+		deliver(pid, status)
+		exits = append(exits, Exit{pid, status})
+	}
+
+	for pid, r := range exited {
+		if time.Since(r.since) > RETENTION {
+			delete(exited, pid)
+		}
+	}
+	return
+}
+
+// deliver passes status of process pid to the one waiting for it or stores it until it is claimed by Wait.
+// The mutex must be locked
+func deliver(pid int, status syscall.WaitStatus) {
+	if ch, ok := waiters[pid]; ok {
+		delete(waiters, pid)
+		ch <- status
+		return
+	}
+	exited[pid] = reaped{status, time.Now()}
+}
+
+// Claim returns the wait status of process pid, if it has been reaped by Reap already.
+// The status is not returned by Wait or Claim afterwards
+func Claim(pid int) (status syscall.WaitStatus, ok bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	r, ok := exited[pid]
+	delete(exited, pid)
+	return r.status, ok
+}
+
+// Wait waits for the process pid to be reaped by Reap and returns its wait status.
+// It is meant to be used by the owners of processes, which could not wait for them, because
+// they had been reaped by the init process already. If the process has exited, but its status
+// is not held, ErrNoSuchChild is returned instead of waiting forever
+func Wait(pid int) (syscall.WaitStatus, error) {
+	mutex.Lock()
+	if r, ok := exited[pid]; ok {
+		delete(exited, pid)
+		mutex.Unlock()
+		return r.status, nil
+	}
+
+	// Reap holds the mutex from reaping a process until delivering its status,
+	// so the process is either still a child or its status is gone
+	var status syscall.WaitStatus
+	reaped, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+	for err == syscall.EINTR {
+		reaped, err = syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+	}
+	switch {
+	case err == syscall.ECHILD:
+		mutex.Unlock()
+		return 0, ErrNoSuchChild
+	case err == nil && reaped == pid:
+		mutex.Unlock()
+		return status, nil
+	}
+
+	ch := make(chan syscall.WaitStatus, 1)
+	waiters[pid] = ch
+	mutex.Unlock()
+
+	return <-ch, nil
+}
@@ -0,0 +1,102 @@
+package pid1
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reapAll reaps the children exited until the one with pid specified is reaped
+func reapAll(t *testing.T, pid int) (exits []Exit) {
+	for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		for _, exit := range Reap() {
+			exits = append(exits, exit)
+			if exit.PID == pid {
+				return
+			}
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("process %d was not reaped", pid)
+		default:
+		}
+	}
+}
+
+func TestReap(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 3")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+
+	exits := reapAll(t, cmd.Process.Pid)
+	exit := exits[len(exits)-1]
+	assert.True(t, exit.Status.Exited(), "exited")
+	assert.Equal(t, 3, exit.Status.ExitStatus(), "exit status")
+
+	status, err := Wait(cmd.Process.Pid)
+	assert.NoError(t, err, "Wait")
+	assert.Equal(t, exit.Status, status, "status claimed after reaping")
+}
+
+func TestWait(t *testing.T) {
+	cmd := exec.Command("sleep", "0.1")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+
+	waited := make(chan Exit)
+	go func() {
+		status, _ := Wait(cmd.Process.Pid)
+		waited <- Exit{cmd.Process.Pid, status}
+	}()
+
+	reapAll(t, cmd.Process.Pid)
+	select {
+	case exit := <-waited:
+		assert.Equal(t, 0, exit.Status.ExitStatus(), "status delivered to waiter")
+	case <-time.After(5 * time.Second):
+		t.Fatal("status was not delivered")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 2")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+
+	_, ok := Claim(cmd.Process.Pid)
+	assert.False(t, ok, "claimed before reaping")
+
+	reapAll(t, cmd.Process.Pid)
+	status, ok := Claim(cmd.Process.Pid)
+	assert.True(t, ok, "claimed after reaping")
+	assert.Equal(t, 2, status.ExitStatus(), "exit status")
+
+	_, ok = Claim(cmd.Process.Pid)
+	assert.False(t, ok, "claimed twice")
+}
+
+func TestWaitExpired(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 1")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+
+	reapAll(t, cmd.Process.Pid)
+	mutex.Lock()
+	r := exited[cmd.Process.Pid]
+	r.since = r.since.Add(-2 * RETENTION)
+	exited[cmd.Process.Pid] = r
+	mutex.Unlock()
+	Reap()
+
+	done := make(chan error)
+	go func() {
+		_, err := Wait(cmd.Process.Pid)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrNoSuchChild, err, "waiting for a status dropped")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait blocked")
+	}
+}
@@ -0,0 +1,229 @@
+package pid1
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Paths to the files configuring the host, which are applied at boot
+var (
+	HostnamePath = "/etc/hostname"
+	LocalePath   = "/etc/locale.conf"
+	VConsolePath = "/etc/vconsole.conf"
+
+	// The machine ID generated is stored here, if /etc is not writable yet, and bind mounted onto unit.MachineIDPath
+	RuntimeMachineIDPath = "/run/machine-id"
+)
+
+// Hostname set, if none is configured
+const DEFAULT_HOSTNAME = "localhost"
+
+// Variables of the locale read from LocalePath and set in the environment of the manager
+var localeVars = []string{
+	"LANG", "LANGUAGE", "LC_CTYPE", "LC_NUMERIC", "LC_TIME", "LC_COLLATE", "LC_MONETARY", "LC_MESSAGES",
+	"LC_PAPER", "LC_NAME", "LC_ADDRESS", "LC_TELEPHONE", "LC_MEASUREMENT", "LC_IDENTIFICATION",
+}
+
+// Setup prepares the machine before the first unit is started, as the init process does:
+// mounts the API filesystems(/proc, /sys, /dev and /run), sets the hostname configured, initializes the machine ID,
+// if it is missing, sets the locale configured in the environment of the manager and the keymap of the console.
+// The failures are logged, the boot proceeds regardless
+func Setup() {
+	for _, err := range mountAPI() {
+		log.Errorf("Error mounting API filesystem: %s", err)
+	}
+
+	if err := setupHostname(); err != nil {
+		log.Errorf("Error setting hostname: %s", err)
+	}
+	if unit.MachineID() == "" {
+		// Recorded before the ID is initialized, so that ConditionFirstBoot= holds for the whole boot
+		if err := markFirstBoot(); err != nil {
+			log.Errorf("Error marking first boot: %s", err)
+		}
+	}
+	if err := setupMachineID(); err != nil {
+		log.Errorf("Error initializing machine ID: %s", err)
+	}
+	if err := setupLocale(); err != nil {
+		log.Errorf("Error setting locale: %s", err)
+	}
+	if err := setupVConsole(); err != nil {
+		log.Errorf("Error setting up console: %s", err)
+	}
+}
+
+// setupHostname sets the hostname found in HostnamePath.
+// DEFAULT_HOSTNAME is set, if none is configured and the kernel has none set either
+func setupHostname() error {
+	name := readHostname(HostnamePath)
+	if name == "" {
+		if current := unit.Hostname(); current != "" && current != "(none)" {
+			return nil
+		}
+		name = DEFAULT_HOSTNAME
+	}
+
+	log.Infof("Setting hostname to %s", name)
+	return sethostname(name)
+}
+
+// readHostname returns the hostname found in the file at path, the first line, which is not a comment
+func readHostname(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// setupMachineID generates a random machine ID and stores it in unit.MachineIDPath, unless it is set already.
+// If the file is not writable, the ID is stored in RuntimeMachineIDPath and bind mounted onto it,
+// so that it is kept until the ID can be committed
+func setupMachineID() (err error) {
+	if unit.MachineID() != "" {
+		return nil
+	}
+
+	id, err := newMachineID()
+	if err != nil {
+		return
+	}
+	log.Infof("Initializing machine ID %s", id)
+
+	if err = ioutil.WriteFile(unit.MachineIDPath, []byte(id+"\n"), 0444); err == nil {
+		return nil
+	}
+	if _, serr := os.Stat(unit.MachineIDPath); serr != nil {
+		// There is nothing to mount the ID onto
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(RuntimeMachineIDPath), 0755); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(RuntimeMachineIDPath, []byte(id+"\n"), 0444); err != nil {
+		return
+	}
+	return bindMount(RuntimeMachineIDPath, unit.MachineIDPath)
+}
+
+// markFirstBoot creates unit.FirstBootPath, which lives in /run and is gone on the next boot
+func markFirstBoot() error {
+	log.Infof("Machine ID not initialized, booting for the first time")
+	if err := os.MkdirAll(filepath.Dir(unit.FirstBootPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(unit.FirstBootPath, nil, 0444)
+}
+
+// newMachineID returns a random machine ID, 32 lowercase hexadecimal characters as required by machine-id(5)
+func newMachineID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	// Formatted as a version 4 UUID
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return hex.EncodeToString(b), nil
+}
+
+// setupLocale sets the variables of the locale found in LocalePath in the environment of the manager,
+// which the processes started inherit
+func setupLocale() error {
+	vars, err := readAssignments(LocalePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, name := range localeVars {
+		if value, ok := vars[name]; ok && value != "" {
+			if err = os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setupVConsole loads the keymap and the font found in VConsolePath on the console
+// using loadkeys(1) and setfont(8), if present
+func setupVConsole() error {
+	vars, err := readAssignments(VConsolePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, c := range []struct {
+		value string
+		cmd   string
+		args  []string
+	}{
+		{vars["KEYMAP"], "loadkeys", []string{"-q", "-C", "/dev/console", vars["KEYMAP"]}},
+		{vars["FONT"], "setfont", []string{"-C", "/dev/console", vars["FONT"]}},
+	} {
+		if c.value == "" {
+			continue
+		}
+
+		path, err := exec.LookPath(c.cmd)
+		if err != nil {
+			log.Warnf("%s not found, %s not loaded", c.cmd, c.value)
+			continue
+		}
+		if out, err := CombinedOutput(exec.Command(path, c.args...)); err != nil {
+			log.Errorf("Error loading %s: %s: %s", c.value, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// readAssignments returns the variables assigned in the shell-like file at path(e.g. `LANG="en_US.UTF-8"`)
+func readAssignments(path string) (vars map[string]string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	vars = map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		vars[strings.TrimSpace(kv[0])] = strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+	}
+	return vars, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package pid1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// apiMount is an API filesystem mounted at boot
+type apiMount struct {
+	source, target, fstype string
+	flags                  uintptr
+	data                   string
+}
+
+// apiMounts are the API filesystems mounted at boot in order, unless mounted already
+var apiMounts = []apiMount{
+	{"proc", "/proc", "proc", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+	{"sysfs", "/sys", "sysfs", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+	{"devtmpfs", "/dev", "devtmpfs", syscall.MS_NOSUID, "mode=755"},
+	{"devpts", "/dev/pts", "devpts", syscall.MS_NOSUID | syscall.MS_NOEXEC, "gid=5,mode=620,ptmxmode=000"},
+	{"tmpfs", "/dev/shm", "tmpfs", syscall.MS_NOSUID | syscall.MS_NODEV, "mode=1777"},
+	{"tmpfs", "/run", "tmpfs", syscall.MS_NOSUID | syscall.MS_NODEV, "mode=755"},
+	{"cgroup2", "/sys/fs/cgroup", "cgroup2", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+}
+
+// mountAPI mounts the API filesystems, which are not mounted yet
+func mountAPI() (errs []error) {
+	for _, m := range apiMounts {
+		if mounted(m.target) {
+			continue
+		}
+		if err := os.MkdirAll(m.target, 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := syscall.Mount(m.source, m.target, m.fstype, m.flags, m.data); err != nil {
+			errs = append(errs, fmt.Errorf("%s on %s: %s", m.fstype, m.target, err))
+		}
+	}
+	return
+}
+
+// mounted reports whether a filesystem is mounted at path, i.e. path resides on another device than its parent
+func mounted(path string) bool {
+	var st, parent syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return false
+	}
+	if err := syscall.Stat(filepath.Dir(path), &parent); err != nil {
+		return false
+	}
+	return st.Dev != parent.Dev || st.Ino == parent.Ino
+}
+
+// sethostname sets the hostname of the kernel
+func sethostname(name string) error {
+	return syscall.Sethostname([]byte(name))
+}
+
+// bindMount mounts the file or directory source onto target
+func bindMount(source, target string) error {
+	return syscall.Mount(source, target, "", syscall.MS_BIND, "")
+}
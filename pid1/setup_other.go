@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package pid1
+
+import "github.com/plasma-umass/systemgo/unit"
+
+// mountAPI is a no-op, the API filesystems are only mounted on Linux
+func mountAPI() []error {
+	return nil
+}
+
+// sethostname is only supported on Linux
+func sethostname(name string) error {
+	return unit.ErrNotSupported
+}
+
+// bindMount is only supported on Linux
+func bindMount(source, target string) error {
+	return unit.ErrNotSupported
+}
@@ -0,0 +1,58 @@
+package pid1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	path, err := ioutil.TempDir("", "setup-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	hostname := filepath.Join(path, "hostname")
+	require.NoError(t, ioutil.WriteFile(hostname, []byte("# comment\n\n  foo  \nbar\n"), 0644))
+	assert.Equal(t, "foo", readHostname(hostname), "readHostname")
+	assert.Empty(t, readHostname(filepath.Join(path, "missing")), "readHostname of missing file")
+
+	defer func(path string) { unit.MachineIDPath = path }(unit.MachineIDPath)
+	unit.MachineIDPath = filepath.Join(path, "machine-id")
+	require.NoError(t, ioutil.WriteFile(unit.MachineIDPath, []byte("uninitialized\n"), 0644))
+	assert.Empty(t, unit.MachineID(), "uninitialized machine ID")
+
+	defer func(path string) { unit.FirstBootPath = path }(unit.FirstBootPath)
+	unit.FirstBootPath = filepath.Join(path, "systemgo", "first-boot")
+	assert.False(t, unit.FirstBoot(), "first boot before marked")
+	require.NoError(t, markFirstBoot(), "markFirstBoot")
+	assert.True(t, unit.FirstBoot(), "first boot after marked")
+
+	require.NoError(t, setupMachineID(), "setupMachineID")
+	id := unit.MachineID()
+	assert.Regexp(t, regexp.MustCompile("^[0-9a-f]{32}$"), id, "machine ID generated")
+
+	require.NoError(t, setupMachineID(), "setupMachineID with ID set")
+	assert.Equal(t, id, unit.MachineID(), "machine ID changed")
+
+	defer func(path string) { LocalePath = path }(LocalePath)
+	LocalePath = filepath.Join(path, "locale.conf")
+	require.NoError(t, ioutil.WriteFile(LocalePath, []byte("LANG=\"C.UTF-8\"\nFOO=bar\n"), 0644))
+
+	defer func(lang string, ok bool) {
+		if ok {
+			os.Setenv("LANG", lang)
+		} else {
+			os.Unsetenv("LANG")
+		}
+	}(os.LookupEnv("LANG"))
+
+	require.NoError(t, setupLocale(), "setupLocale")
+	assert.Equal(t, "C.UTF-8", os.Getenv("LANG"))
+	assert.Empty(t, os.Getenv("FOO"), "variable not belonging to the locale set")
+}
@@ -0,0 +1,44 @@
+package pid1
+
+import (
+	"os"
+	"time"
+)
+
+// Hardware watchdog device opened by default
+const DEFAULT_WATCHDOG_DEVICE = "/dev/watchdog"
+
+// Watchdog is a hardware watchdog device, which resets the machine, unless it is pinged within its timeout
+type Watchdog struct {
+	file *os.File
+}
+
+// OpenWatchdog opens the watchdog device at path, which arms it, and sets its timeout.
+// The timeout is rounded up to seconds, as the devices count in seconds
+func OpenWatchdog(path string, timeout time.Duration) (w *Watchdog, err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+
+	w = &Watchdog{file: f}
+	if err = w.SetTimeout(timeout); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close disarms the watchdog using the magic close character and closes the device
+func (w *Watchdog) Close() error {
+	w.file.Write([]byte("V"))
+	return w.file.Close()
+}
+
+// seconds returns d in seconds rounded up, at least 1
+func seconds(d time.Duration) int {
+	if s := int((d + time.Second - 1) / time.Second); s > 0 {
+		return s
+	}
+	return 1
+}
@@ -0,0 +1,32 @@
+package pid1
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// watchdog(4) ioctl(2) requests
+const (
+	wdiocKeepalive  = 0x80045705
+	wdiocSetTimeout = 0xc0045706
+)
+
+// Ping resets the countdown of the watchdog
+func (w *Watchdog) Ping() error {
+	var v int32
+	return w.ioctl(wdiocKeepalive, &v)
+}
+
+// SetTimeout sets the time the watchdog waits to be pinged, before resetting the machine, and resets the countdown
+func (w *Watchdog) SetTimeout(d time.Duration) error {
+	v := int32(seconds(d))
+	return w.ioctl(wdiocSetTimeout, &v)
+}
+
+func (w *Watchdog) ioctl(req uintptr, v *int32) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, w.file.Fd(), req, uintptr(unsafe.Pointer(v))); errno != 0 {
+		return errno
+	}
+	return nil
+}
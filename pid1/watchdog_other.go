@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package pid1
+
+import (
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Ping is only supported on Linux
+func (w *Watchdog) Ping() error {
+	return unit.ErrNotSupported
+}
+
+// SetTimeout is only supported on Linux
+func (w *Watchdog) SetTimeout(d time.Duration) error {
+	return unit.ErrNotSupported
+}
@@ -0,0 +1,290 @@
+// Package quadlet converts the declarative container definitions(.container files), as Podman Quadlet reads them,
+// to services running the containers using podman or docker
+package quadlet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Directories the container definitions are found in, a definition shadows the ones with the same name in the following directories
+var DIRS = []string{
+	"/etc/containers/systemd",
+	"/run/containers/systemd",
+	"/usr/share/containers/systemd",
+}
+
+// Container engines the containers are run with, the first one found in PATH is used
+var ENGINES = []string{"podman", "docker"}
+
+// Suffix of the container definitions
+const SUFFIX = ".container"
+
+var ErrNoEngine = errors.New("No container engine found")
+var ErrNoImage = errors.New("Image= not set")
+
+// Container is a container definition
+type Container struct {
+	// Name of the definition file, e.g. "web.container"
+	Name string
+
+	// Path to the definition file
+	Path string
+
+	// Image run, e.g. "docker.io/library/nginx"
+	Image string
+
+	// Name of the container, "systemd-" followed by the name of the definition without the suffix by default
+	ContainerName string
+
+	// Ports published(e.g. "8080:80"), volumes mounted(e.g. "/srv/www:/usr/share/nginx/html:ro")
+	// and environment variables set in the container(e.g. "FOO=bar")
+	PublishPort, Volume, Environment []string
+
+	// Network the container is connected to, e.g. "host"
+	Network string
+
+	// Command run in the container instead of the default one of the image and arguments passed to the engine
+	Exec, PodmanArgs string
+
+	// Options of the other sections, which are copied to the service
+	options unit.Options
+}
+
+// Service returns the name of the service running c
+func (c Container) Service() string {
+	return strings.TrimSuffix(c.Name, SUFFIX) + ".service"
+}
+
+// Parse parses the container definition found at path
+func Parse(path string) (c Container, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	opts, err := unit.Deserialize(f)
+	if err != nil {
+		return
+	}
+
+	c = Container{Name: filepath.Base(path), Path: path}
+	merr := unit.MultiError{}
+	for _, opt := range opts {
+		if opt.Section != "Container" {
+			c.options = append(c.options, opt)
+			continue
+		}
+
+		switch opt.Name {
+		case "Image":
+			c.Image = opt.Value
+		case "ContainerName":
+			c.ContainerName = opt.Value
+		case "PublishPort":
+			c.PublishPort = append(c.PublishPort, strings.Fields(opt.Value)...)
+		case "Volume":
+			c.Volume = append(c.Volume, strings.Fields(opt.Value)...)
+		case "Environment":
+			c.Environment = append(c.Environment, strings.Fields(opt.Value)...)
+		case "Network":
+			c.Network = opt.Value
+		case "Exec":
+			c.Exec = opt.Value
+		case "PodmanArgs":
+			c.PodmanArgs = strings.TrimSpace(c.PodmanArgs + " " + opt.Value)
+		default:
+			merr = append(merr, opt.Err(unit.ErrUnknownDirective))
+		}
+	}
+	if c.Image == "" {
+		merr = append(merr, unit.ParseErr("Image", ErrNoImage))
+	}
+	if len(merr) > 0 {
+		return c, merr
+	}
+	return c, nil
+}
+
+// Unit returns the definition of the service running c using the engine at path, e.g. "/usr/bin/podman".
+// The container is removed, once the service stops, and replaced, if it exists, once it starts
+func (c Container) Unit(engine string) string {
+	name := c.ContainerName
+	if name == "" {
+		name = "systemd-%N"
+	}
+	podman := filepath.Base(engine) == "podman"
+
+	run := []string{engine, "run", "--name=" + name, "--rm"}
+	if podman {
+		// conmon reports the container started and the cgroup delegated to the service is split between it and the container
+		run = append(run, "--replace", "--cgroups=split", "--sdnotify=conmon")
+	}
+	for _, port := range c.PublishPort {
+		run = append(run, "--publish", port)
+	}
+	for _, volume := range c.Volume {
+		run = append(run, "--volume", volume)
+	}
+	for _, env := range c.Environment {
+		run = append(run, "--env", env)
+	}
+	if c.Network != "" {
+		run = append(run, "--network", c.Network)
+	}
+	if c.PodmanArgs != "" {
+		run = append(run, c.PodmanArgs)
+	}
+	run = append(run, c.Image)
+	if c.Exec != "" {
+		run = append(run, c.Exec)
+	}
+
+	rm := []string{engine, "rm", "-f", name}
+	if podman {
+		rm = []string{engine, "rm", "-f", "-i", name}
+	}
+
+	sections := map[string][]string{}
+	for _, opt := range c.options {
+		sections[opt.Section] = append(sections[opt.Section], opt.Name+"="+opt.Value)
+	}
+
+	// The images get pulled and the ports published, once the network is up
+	sections["Unit"] = append([]string{
+		"Wants=network-online.target",
+		"After=network-online.target",
+	}, sections["Unit"]...)
+
+	// The directives generated come first, so that the ones of the definition override them
+	sections["Service"] = append([]string{
+		"Environment=PODMAN_SYSTEMD_UNIT=%n",
+		"Delegate=yes",
+		"ExecStart=" + strings.Join(run, " "),
+		"ExecStop=" + strings.Join(rm, " "),
+	}, sections["Service"]...)
+
+	names := make([]string, 0, len(sections))
+	for section := range sections {
+		if section != "Unit" && section != "Service" && section != "Install" {
+			names = append(names, section)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{"Unit", "Service"}, names...)
+	if _, ok := sections["Install"]; ok {
+		names = append(names, "Install")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Automatically generated by quadlet-generator from %s\n", c.Path)
+	for _, section := range names {
+		fmt.Fprintf(&b, "\n[%s]\n", section)
+		for _, line := range sections[section] {
+			fmt.Fprintln(&b, line)
+		}
+	}
+	return b.String()
+}
+
+// install returns the names of the units, which want or require c, as specified in the [Install] section
+func (c Container) install() (wantedBy, requiredBy []string) {
+	for _, opt := range c.options {
+		if opt.Section != "Install" {
+			continue
+		}
+		switch opt.Name {
+		case "WantedBy":
+			wantedBy = append(wantedBy, strings.Fields(opt.Value)...)
+		case "RequiredBy":
+			requiredBy = append(requiredBy, strings.Fields(opt.Value)...)
+		}
+	}
+	return
+}
+
+// Containers returns the container definitions found in dirs, the ones shadowed excluded.
+// The definitions, which could not be parsed, are reported by the MultiError returned along with the others
+func Containers(dirs ...string) (containers []Container, err error) {
+	found := map[string]bool{}
+	merr := unit.MultiError{}
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+SUFFIX))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if found[name] {
+				continue
+			}
+			found[name] = true
+
+			c, err := Parse(path)
+			if err != nil {
+				merr = append(merr, fmt.Errorf("%s: %s", path, err))
+				continue
+			}
+			containers = append(containers, c)
+		}
+	}
+	if len(merr) > 0 {
+		return containers, merr
+	}
+	return containers, nil
+}
+
+// Engine returns the path to the first of ENGINES found in PATH
+func Engine() (string, error) {
+	for _, name := range ENGINES {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNoEngine
+}
+
+// Generate writes the services running the containers defined in DIRS to dir along with the links
+// making the units specified in [Install] want or require them
+func Generate(dir string) (err error) {
+	containers, perr := Containers(DIRS...)
+	if len(containers) == 0 {
+		return perr
+	}
+
+	engine, err := Engine()
+	if err != nil {
+		return
+	}
+
+	for _, c := range containers {
+		path := filepath.Join(dir, c.Service())
+		if err = ioutil.WriteFile(path, []byte(c.Unit(engine)), 0644); err != nil {
+			return
+		}
+
+		wantedBy, requiredBy := c.install()
+		for suffix, names := range map[string][]string{".wants": wantedBy, ".requires": requiredBy} {
+			for _, name := range names {
+				links := filepath.Join(dir, name+suffix)
+				if err = os.MkdirAll(links, 0755); err != nil {
+					return
+				}
+				if err = os.Symlink(path, filepath.Join(links, c.Service())); err != nil && !os.IsExist(err) {
+					return
+				}
+			}
+		}
+	}
+	return perr
+}
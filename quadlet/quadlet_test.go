@@ -0,0 +1,93 @@
+package quadlet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const container = `[Unit]
+Description=Web server
+
+[Container]
+Image=docker.io/library/nginx
+PublishPort=8080:80
+Volume=/srv/www:/usr/share/nginx/html:ro
+Environment=FOO=bar
+
+[Service]
+Restart=always
+
+[Install]
+WantedBy=multi-user.target
+`
+
+func TestParse(t *testing.T) {
+	path, err := ioutil.TempDir("", "quadlet-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "web.container"), []byte(container), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "bad.container"), []byte("[Container]\nFoo=bar\n"), 0644))
+
+	c, err := Parse(filepath.Join(path, "web.container"))
+	require.NoError(t, err, "Parse")
+	assert.Equal(t, "web.service", c.Service())
+	assert.Equal(t, "docker.io/library/nginx", c.Image)
+	assert.Equal(t, []string{"8080:80"}, c.PublishPort)
+
+	def := c.Unit("/usr/bin/podman")
+	assert.Contains(t, def, "ExecStart=/usr/bin/podman run --name=systemd-%N --rm --replace --cgroups=split --sdnotify=conmon "+
+		"--publish 8080:80 --volume /srv/www:/usr/share/nginx/html:ro --env FOO=bar docker.io/library/nginx\n")
+	assert.Contains(t, def, "ExecStop=/usr/bin/podman rm -f -i systemd-%N\n")
+	assert.Contains(t, def, "Description=Web server\n")
+	assert.Contains(t, def, "[Install]\nWantedBy=multi-user.target\n")
+	assert.NotContains(t, def, "[Container]")
+
+	sv := &service.Unit{}
+	require.NoError(t, sv.Define(strings.NewReader(def)), "sv.Define")
+	assert.True(t, sv.Resources().Delegate, "Delegate")
+
+	assert.NotContains(t, c.Unit("/usr/bin/docker"), "--sdnotify")
+
+	_, err = Parse(filepath.Join(path, "bad.container"))
+	assert.Error(t, err, "unknown directive and no image")
+}
+
+func TestGenerate(t *testing.T) {
+	path, err := ioutil.TempDir("", "quadlet-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	etc, usr, bin, out := filepath.Join(path, "etc"), filepath.Join(path, "usr"), filepath.Join(path, "bin"), filepath.Join(path, "out")
+	for _, dir := range []string{etc, usr, bin, out} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	defer func(dirs, engines []string) { DIRS, ENGINES = dirs, engines }(DIRS, ENGINES)
+	DIRS, ENGINES = []string{etc, usr}, []string{filepath.Join(bin, "podman")}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(etc, "web.container"), []byte(container), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(usr, "web.container"), []byte("[Container]\nImage=shadowed\n"), 0644))
+
+	assert.Equal(t, ErrNoEngine, Generate(out), "no engine")
+
+	require.NoError(t, ioutil.WriteFile(ENGINES[0], []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, Generate(out), "Generate")
+
+	b, err := ioutil.ReadFile(filepath.Join(out, "web.service"))
+	require.NoError(t, err, "web.service not generated")
+	assert.Contains(t, string(b), "docker.io/library/nginx")
+	assert.NotContains(t, string(b), "shadowed")
+
+	target, err := os.Readlink(filepath.Join(out, "multi-user.target.wants", "web.service"))
+	if assert.NoError(t, err, "web.service not enabled") {
+		assert.Equal(t, filepath.Join(out, "web.service"), target)
+	}
+}
@@ -0,0 +1,238 @@
+// Package shutdown implements the final phase of the shutdown following the stop of the units, as systemd-shutdown does:
+// the processes left are killed, swap is turned off, loop devices are detached, the filesystems are unmounted
+// and the machine is powered off, rebooted or halted
+package shutdown
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Path to the proc filesystem
+var PROC_PATH = "/proc"
+
+// Time the processes left get to exit after SIGTERM, before they are killed, and after SIGKILL, before they are given up on
+var KILL_TIMEOUT = 5 * time.Second
+
+// Number of times unmounting, turning off swap and detaching loop devices is attempted, as long as any progress is made
+var MAX_PASSES = 6
+
+var ErrUnknownAction = errors.New("Unknown action")
+
+// apiMounts are the filesystems kept mounted until the very end
+var apiMounts = map[string]bool{
+	"/":        true,
+	"/proc":    true,
+	"/sys":     true,
+	"/dev":     true,
+	"/dev/pts": true,
+	"/run":     true,
+}
+
+// Process is a process left running
+type Process struct {
+	PID     int
+	Command string
+}
+
+// Remaining returns the processes running other than the calling one, kernel threads and zombies excluded
+func Remaining() (procs []Process) {
+	dirs, err := ioutil.ReadDir(PROC_PATH)
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil || pid == self {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(PROC_PATH, dir.Name(), "stat"))
+		if err != nil {
+			// Exited already
+			continue
+		}
+
+		// The name is parenthesized and may contain spaces and parentheses itself
+		open, end := bytes.IndexByte(b, '('), bytes.LastIndexByte(b, ')')
+		if open < 0 || end < open {
+			continue
+		}
+		fields := strings.Fields(string(b[end+1:]))
+		if len(fields) < 2 || fields[0] == "Z" {
+			continue
+		}
+		if ppid, _ := strconv.Atoi(fields[1]); pid == 2 || ppid == 2 {
+			// kthreadd and its children
+			continue
+		}
+
+		procs = append(procs, Process{PID: pid, Command: string(b[open+1 : end])})
+	}
+	return procs
+}
+
+// waitForExit waits until no processes are left or timeout passes and returns the ones left
+func waitForExit(timeout time.Duration) (left []Process) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if left = Remaining(); len(left) == 0 || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// logStragglers logs the processes left using logf
+func logStragglers(procs []Process, logf func(string, ...interface{}), msg string) {
+	for _, p := range procs {
+		logf("Process %d (%s) %s", p.PID, p.Command, msg)
+	}
+}
+
+// Mount is a filesystem mounted, as listed in /proc/self/mountinfo
+type Mount struct {
+	// ID of the mount and of the one it is mounted on
+	ID, Parent int
+
+	// Mount point, type and source of the filesystem
+	Point, Type, Source string
+}
+
+// ParseMountInfo returns the mounts found in r, formatted as /proc/self/mountinfo, in order of mounting
+func ParseMountInfo(r io.Reader) (mounts []Mount, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		m := Mount{Point: unescape(fields[4])}
+		if m.ID, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, err
+		}
+		if m.Parent, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, err
+		}
+
+		// The optional fields are terminated by a single hyphen, which the type and the source follow
+		for i := 5; i+2 < len(fields); i++ {
+			if fields[i] == "-" {
+				m.Type, m.Source = fields[i+1], unescape(fields[i+2])
+				break
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// UnmountOrder returns mounts in reverse dependency order: every mount precedes the one it is mounted on
+// and the ones mounted on the same mount are ordered the most recent first
+func UnmountOrder(mounts []Mount) []Mount {
+	ids := map[int]bool{}
+	children := map[int][]Mount{}
+	for _, m := range mounts {
+		ids[m.ID] = true
+	}
+
+	var roots []Mount
+	for _, m := range mounts {
+		if m.Parent == m.ID || !ids[m.Parent] {
+			roots = append(roots, m)
+			continue
+		}
+		children[m.Parent] = append(children[m.Parent], m)
+	}
+
+	ordered := make([]Mount, 0, len(mounts))
+	visited := map[int]bool{}
+	var visit func(m Mount)
+	visit = func(m Mount) {
+		if visited[m.ID] {
+			return
+		}
+		visited[m.ID] = true
+
+		for i := len(children[m.ID]) - 1; i >= 0; i-- {
+			visit(children[m.ID][i])
+		}
+		ordered = append(ordered, m)
+	}
+	for i := len(roots) - 1; i >= 0; i-- {
+		visit(roots[i])
+	}
+	return ordered
+}
+
+// ParseSwaps returns the swap devices and files found in r, formatted as /proc/swaps
+func ParseSwaps(r io.Reader) (paths []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for header := true; scanner.Scan(); header = false {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 && !header {
+			paths = append(paths, unescape(fields[0]))
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// unescape replaces the octal escapes of whitespace and backslashes found in path
+func unescape(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b = append(b, byte(c))
+				i += 3
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// readMounts returns the mounts of the calling process in reverse dependency order
+func readMounts() ([]Mount, error) {
+	f, err := os.Open(filepath.Join(PROC_PATH, "self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mounts, err := ParseMountInfo(f)
+	return UnmountOrder(mounts), err
+}
+
+// readSwaps returns the swap devices and files in use
+func readSwaps() ([]string, error) {
+	f, err := os.Open(filepath.Join(PROC_PATH, "swaps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseSwaps(f)
+}
+
+// logErr logs err using log.Errorf, if final is set, and log.Debugf otherwise
+func logErr(final bool, format string, args ...interface{}) {
+	if final {
+		log.Errorf(format, args...)
+	} else {
+		log.Debugf(format, args...)
+	}
+}
@@ -0,0 +1,156 @@
+package shutdown
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// LOOP_CLR_FD ioctl(2) request, which detaches the backing file of a loop device
+const loopClrFd = 0x4C01
+
+// rebootCmds maps shutdown actions to reboot(2) commands
+var rebootCmds = map[string]int{
+	"reboot":   syscall.LINUX_REBOOT_CMD_RESTART,
+	"poweroff": syscall.LINUX_REBOOT_CMD_POWER_OFF,
+	"halt":     syscall.LINUX_REBOOT_CMD_HALT,
+}
+
+// Finalize kills the processes left, turns off swap, detaches loop devices, unmounts the filesystems,
+// syncs and invokes reboot(2) with the command corresponding to action("poweroff", "reboot" or "halt")
+func Finalize(action string) (err error) {
+	cmd, ok := rebootCmds[action]
+	if !ok {
+		return ErrUnknownAction
+	}
+
+	Kill()
+	Detach()
+
+	log.Infof("Syncing filesystems")
+	syscall.Sync()
+
+	log.Infof("Running %s", action)
+	return syscall.Reboot(cmd)
+}
+
+// Kill sends SIGTERM to all the processes left, but the calling one, and SIGKILL to the ones still running
+// after KILL_TIMEOUT. The processes, which did not exit even then, are logged and returned
+func Kill() (left []Process) {
+	log.Infof("Sending SIGTERM to remaining processes")
+	syscall.Kill(-1, syscall.SIGTERM)
+	// Stopped processes would never handle SIGTERM
+	syscall.Kill(-1, syscall.SIGCONT)
+
+	if left = waitForExit(KILL_TIMEOUT); len(left) == 0 {
+		return nil
+	}
+	logStragglers(left, log.Warnf, "did not exit, sending SIGKILL")
+
+	log.Infof("Sending SIGKILL to remaining processes")
+	syscall.Kill(-1, syscall.SIGKILL)
+
+	left = waitForExit(KILL_TIMEOUT)
+	logStragglers(left, log.Errorf, "still running after SIGKILL")
+	return left
+}
+
+// Detach turns off swap, detaches loop devices and unmounts the filesystems, which are not API filesystems.
+// The steps are repeated up to MAX_PASSES times, as long as any progress is made, since e.g. a filesystem gets
+// busy no more once the swap file or the loop device backed by it is detached. The mounts still busy are
+// detached lazily(MNT_DETACH) and the root is remounted read-only
+func Detach() {
+	for pass := 1; pass <= MAX_PASSES; pass++ {
+		progress := swapOff(false)
+		progress = detachLoops(false) || progress
+		progress = unmountAll(0, false) || progress
+		if !progress {
+			break
+		}
+	}
+	swapOff(true)
+	detachLoops(true)
+	unmountAll(syscall.MNT_DETACH, true)
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		log.Errorf("Error remounting / read-only: %s", err)
+	}
+}
+
+// swapOff turns off the swap devices and files in use and reports whether any was turned off.
+// Errors are only logged as such, if final is set
+func swapOff(final bool) (progress bool) {
+	paths, err := readSwaps()
+	if err != nil {
+		logErr(final, "Error reading swaps: %s", err)
+	}
+
+	for _, path := range paths {
+		p, err := syscall.BytePtrFromString(path)
+		if err != nil {
+			continue
+		}
+		if _, _, errno := syscall.Syscall(syscall.SYS_SWAPOFF, uintptr(unsafe.Pointer(p)), 0, 0); errno != 0 {
+			logErr(final, "Error turning off swap %s: %s", path, errno)
+			continue
+		}
+		log.Infof("Turned off swap %s", path)
+		progress = true
+	}
+	return
+}
+
+// detachLoops detaches the backing files of the loop devices in use and reports whether any was detached.
+// Errors are only logged as such, if final is set
+func detachLoops(final bool) (progress bool) {
+	backing, err := filepath.Glob("/sys/block/loop*/loop/backing_file")
+	if err != nil {
+		return false
+	}
+
+	for _, path := range backing {
+		dev := filepath.Join("/dev", filepath.Base(filepath.Dir(filepath.Dir(path))))
+
+		f, err := os.OpenFile(dev, os.O_RDONLY, 0)
+		if err != nil {
+			logErr(final, "Error opening %s: %s", dev, err)
+			continue
+		}
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), loopClrFd, 0)
+		f.Close()
+		if errno != 0 {
+			logErr(final, "Error detaching %s: %s", dev, errno)
+			continue
+		}
+		log.Infof("Detached %s", dev)
+		progress = true
+	}
+	return
+}
+
+// unmountAll unmounts the filesystems, but the API ones, in reverse dependency order using flags
+// and reports whether any was unmounted. Errors are only logged as such, if final is set
+func unmountAll(flags int, final bool) (progress bool) {
+	mounts, err := readMounts()
+	if err != nil {
+		logErr(final, "Error reading mounts: %s", err)
+	}
+
+	for _, m := range mounts {
+		if apiMounts[m.Point] {
+			continue
+		}
+
+		if err := syscall.Unmount(m.Point, flags); err != nil {
+			logErr(final, "Error unmounting %s: %s", m.Point, err)
+			continue
+		}
+		log.Infof("Unmounted %s", m.Point)
+		progress = true
+	}
+	return
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package shutdown
+
+import "github.com/plasma-umass/systemgo/unit"
+
+// Finalize is only supported on Linux
+func Finalize(action string) error {
+	return unit.ErrNotSupported
+}
+
+// Kill is only supported on Linux
+func Kill() []Process {
+	return Remaining()
+}
+
+// Detach is a no-op, the filesystems are only unmounted on Linux
+func Detach() {}
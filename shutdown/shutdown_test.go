@@ -0,0 +1,63 @@
+package shutdown
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmountOrder(t *testing.T) {
+	mounts, err := ParseMountInfo(strings.NewReader(`22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:4 / /proc rw,nosuid - proc proc rw
+24 22 8:17 / /mnt/with\040space rw shared:2 master:1 - ext4 /dev/sdb1 rw
+25 22 8:18 / /srv rw - ext4 /dev/sdb2 rw
+26 24 8:19 / /mnt/with\040space/nested rw - ext4 /dev/sdb3 rw
+27 25 0:40 / /srv/tmp rw - tmpfs tmpfs rw
+`))
+	require.NoError(t, err)
+	if assert.Len(t, mounts, 6) {
+		assert.Equal(t, Mount{ID: 24, Parent: 22, Point: "/mnt/with space", Type: "ext4", Source: "/dev/sdb1"}, mounts[2])
+	}
+
+	points := []string{}
+	for _, m := range UnmountOrder(mounts) {
+		points = append(points, m.Point)
+	}
+	assert.Equal(t, []string{"/srv/tmp", "/srv", "/mnt/with space/nested", "/mnt/with space", "/proc", "/"}, points)
+}
+
+func TestParseSwaps(t *testing.T) {
+	paths, err := ParseSwaps(strings.NewReader(`Filename				Type		Size		Used		Priority
+/dev/sda2                               partition	2097148		0		-2
+/swap\040file                           file		1048572		0		-3
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/sda2", "/swap file"}, paths)
+}
+
+func TestRemaining(t *testing.T) {
+	path, err := ioutil.TempDir("", "shutdown-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(orig string) { PROC_PATH = orig }(PROC_PATH)
+	PROC_PATH = path
+
+	for pid, stat := range map[string]string{
+		"2":    "2 (kthreadd) S 0 0 0",
+		"10":   "10 (kworker/0:1) I 2 0 0",
+		"100":  "100 (sleep (1)) S 1 100 100",
+		"101":  "101 (defunct) Z 1 101 101",
+		"self": "1 (init) S 0 1 1",
+	} {
+		require.NoError(t, os.Mkdir(filepath.Join(path, pid), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, pid, "stat"), []byte(stat), 0644))
+	}
+
+	assert.Equal(t, []Process{{PID: 100, Command: "sleep (1)"}}, Remaining())
+}
@@ -0,0 +1,108 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// aliasOf returns the name of the unit name is an alias of, as the symlink named after name found in the unit paths
+// points to its definition(e.g. "dbus-org.example.Foo.service" linked to "foo.service").
+// An instance of an aliased template is an alias of the instance of the template linked to
+func (sys *Daemon) aliasOf(name string) (target string, ok bool) {
+	paths := sys.definitionPaths(name)
+	if filepath.IsAbs(name) || len(paths) == 0 {
+		return "", false
+	}
+	path := paths[0]
+
+	link, err := os.Readlink(path)
+	if err != nil {
+		return "", false
+	}
+	if _, err = sys.evalSymlinks(path); err != nil || isMasked(path) {
+		// Dangling, looping and masking links are no aliases
+		return "", false
+	}
+
+	found, linked := filepath.Base(path), filepath.Base(link)
+	switch {
+	case linked == found, filepath.Ext(linked) != filepath.Ext(found), !Supported(linked):
+		// The definition is merely linked into the unit path
+		return "", false
+	case unit.IsTemplate(found) != unit.IsTemplate(linked):
+		// An instance enabled by linking it to its template
+		return "", false
+	case unit.IsTemplate(linked):
+		return unit.InstanceName(linked, unit.InstanceOf(name)), true
+	}
+	return linked, true
+}
+
+// loadAliased loads the unit name or, if name is an alias, the unit it is an alias of. The load mutex of sys must be locked
+func (sys *Daemon) loadAliased(name string) (u *Unit, err error) {
+	target, ok := sys.aliasOf(name)
+	if !ok {
+		return sys.load(name)
+	}
+
+	if u, err = sys.Unit(target); err != nil || !u.IsLoaded() {
+		if u, err = sys.load(target); err != nil {
+			return
+		}
+	}
+	sys.addAliases(u, name)
+	return u, nil
+}
+
+// addAliases makes names refer to u, unless they refer to other units loaded already
+func (sys *Daemon) addAliases(u *Unit, names ...string) {
+	for _, name := range names {
+		name = unit.Normalize(name)
+		if other, err := sys.Unit(name); err == nil && other != u && other.IsLoaded() {
+			u.Log.Warnf("Alias %s refers to %s already", name, other.Name())
+			continue
+		}
+
+		sys.unitsMutex.Lock()
+		sys.units[name] = u
+		sys.unitsMutex.Unlock()
+	}
+}
+
+// removeAliases makes the names of u other than its own name refer to no unit, e.g. before the definition gets reloaded
+func (sys *Daemon) removeAliases(u *Unit) {
+	sys.unitsMutex.Lock()
+	defer sys.unitsMutex.Unlock()
+
+	for name, other := range sys.units {
+		if other == u && name != u.name && name != strings.TrimSuffix(u.name, ".service") {
+			delete(sys.units, name)
+		}
+	}
+}
+
+// Names returns the name of u followed by its aliases
+func (u *Unit) Names() []string {
+	if u.System == nil {
+		return []string{u.Name()}
+	}
+	return u.System.namesOf(u)
+}
+
+// namesOf returns the name of u followed by the aliases referring to it sorted
+func (sys *Daemon) namesOf(u *Unit) (names []string) {
+	sys.unitsMutex.RLock()
+	for name, other := range sys.units {
+		if other == u && name != u.name && !filepath.IsAbs(name) && Supported(name) {
+			names = append(names, name)
+		}
+	}
+	sys.unitsMutex.RUnlock()
+
+	sort.Strings(names)
+	return append([]string{u.Name()}, names...)
+}
@@ -0,0 +1,104 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliases(t *testing.T) {
+	path, err := ioutil.TempDir("", "alias-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "foo.service"), []byte(`[Service]
+ExecStart=/bin/true
+[Install]
+Alias=baz.service`), 0666), "ioutil.WriteFile")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "tpl@.service"), []byte(`[Service]
+ExecStart=/bin/echo %i`), 0666), "ioutil.WriteFile")
+	require.NoError(t, os.Symlink("foo.service", filepath.Join(path, "bar.service")), "os.Symlink")
+	require.NoError(t, os.Symlink("tpl@.service", filepath.Join(path, "alt@.service")), "os.Symlink")
+	require.NoError(t, os.Symlink("tpl@.service", filepath.Join(path, "tpl@one.service")), "os.Symlink")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	u, err := sys.Get("bar")
+	require.NoError(t, err, "sys.Get(bar)")
+	assert.Equal(t, "foo.service", u.Name())
+
+	for _, name := range []string{"foo", "foo.service", " foo.Service ", "bar.service", "baz.service"} {
+		other, err := sys.Get(name)
+		if assert.NoError(t, err, "sys.Get(%q)", name) {
+			assert.True(t, u == other, "%q refers to another unit", name)
+		}
+	}
+	assert.Equal(t, []string{"foo.service", "bar.service", "baz.service"}, u.Names())
+
+	inst, err := sys.Get("alt@two")
+	require.NoError(t, err, "sys.Get(alt@two)")
+	assert.Equal(t, "tpl@two.service", inst.Name())
+
+	inst, err = sys.Get("tpl@one.service")
+	require.NoError(t, err, "sys.Get(tpl@one.service)")
+	assert.Equal(t, "tpl@one.service", inst.Name(), "instance linked to its template is no alias")
+
+	require.NoError(t, os.Remove(filepath.Join(path, "bar.service")))
+	sys.DaemonReload()
+	_, err = sys.Unit("bar.service")
+	assert.Equal(t, ErrNotFound, err, "alias removed still refers to the unit")
+	assert.Equal(t, []string{"foo.service", "baz.service"}, u.Names())
+}
+
+func TestAliasesInRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "alias-root-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(root)
+
+	etc, lib := filepath.Join(root, "etc", "systemgo", "system"), filepath.Join(root, "usr", "lib", "systemgo", "system")
+	for _, dir := range []string{etc, lib} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "baz.service"), []byte(`[Service]
+ExecStart=/bin/true
+[Install]
+Alias=qux.service
+WantedBy=multi-user.target`), 0666), "ioutil.WriteFile")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "multi-user.target"), []byte(`[Unit]
+Description=Multi-User System`), 0666), "ioutil.WriteFile")
+
+	newDaemon := func() *Daemon {
+		sys := New()
+		sys.SetRoot(root)
+		sys.SetPaths(etc, lib)
+		return sys
+	}
+
+	require.NoError(t, newDaemon().Enable("baz.service"), "Enable")
+	target, err := os.Readlink(filepath.Join(etc, "qux.service"))
+	require.NoError(t, err, "alias not created")
+	assert.Equal(t, "/usr/lib/systemgo/system/baz.service", target, "link points outside of the image")
+
+	// The links point to the paths inside the image, which do not exist on the host
+	sys := newDaemon()
+	u, err := sys.Get("qux.service")
+	require.NoError(t, err, "sys.Get(qux.service)")
+	assert.Equal(t, "baz.service", u.Name())
+	assert.Equal(t, unit.Enabled, u.Enabled())
+
+	targ, err := sys.Get("multi-user.target")
+	require.NoError(t, err, "sys.Get(multi-user.target)")
+	assert.Contains(t, targ.Wants(), filepath.Join(lib, "baz.service"), "wanted unit resolved inside the image")
+
+	require.NoError(t, newDaemon().Disable("qux.service"), "Disable by alias")
+	_, err = os.Lstat(filepath.Join(etc, "qux.service"))
+	assert.True(t, os.IsNotExist(err), "alias removed")
+	_, err = os.Lstat(filepath.Join(etc, "multi-user.target.wants", "baz.service"))
+	assert.True(t, os.IsNotExist(err), "link removed")
+}
@@ -0,0 +1,164 @@
+package system
+
+import (
+	"sort"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// UnitTime holds the times a unit got activating and active on its last start,
+// relative to the time the system started
+type UnitTime struct {
+	Unit string
+
+	Activating time.Duration
+	Activated  time.Duration
+}
+
+// Time returns the time the unit took to activate
+func (ut UnitTime) Time() time.Duration {
+	return ut.Activated - ut.Activating
+}
+
+// unitTime returns the times of the last start of u and whether u has been activated at all
+func (sys *Daemon) unitTime(u *Unit) (ut UnitTime, ok bool) {
+	activating, activated := u.startTimes()
+	if activated.IsZero() {
+		return ut, false
+	}
+	return UnitTime{
+		Unit:       u.Name(),
+		Activating: activating.Sub(sys.since),
+		Activated:  activated.Sub(sys.since),
+	}, true
+}
+
+// BootTime returns the time userspace initialization took, i.e. until the jobs queued at boot finished.
+// ErrBooting is returned, if they have not finished yet
+func (sys *Daemon) BootTime() (d time.Duration, err error) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if sys.booted.IsZero() {
+		return 0, ErrBooting
+	}
+	return sys.booted.Sub(sys.since), nil
+}
+
+// Blame returns the times of the units activated, sorted by the time they took to activate, slowest first
+func (sys *Daemon) Blame() (times []UnitTime) {
+	times = []UnitTime{}
+	for _, u := range sys.Units() {
+		if ut, ok := sys.unitTime(u); ok {
+			times = append(times, ut)
+		}
+	}
+
+	sort.SliceStable(times, func(i, j int) bool {
+		if times[i].Time() == times[j].Time() {
+			return times[i].Unit < times[j].Unit
+		}
+		return times[i].Time() > times[j].Time()
+	})
+	return
+}
+
+// CriticalChain returns the chain of units, which delayed the activation of unit name the most.
+// The unit itself comes first, followed by the unit it is ordered after, which got active last before it got activating, and so on.
+// If name is empty, the unit started at boot is used
+func (sys *Daemon) CriticalChain(name string) (chain []UnitTime, err error) {
+	if name == "" {
+		name = sys.BootTarget(DEFAULT_TARGET)
+	}
+
+	u, err := sys.Unit(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ut, ok := sys.unitTime(u)
+	if !ok {
+		return nil, ErrNotActive
+	}
+
+	seen := map[*Unit]bool{}
+	for {
+		seen[u] = true
+		chain = append(chain, ut)
+
+		var next *Unit
+		var nextTime UnitTime
+		for _, dep := range u.After() {
+			dep, err := sys.Unit(dep)
+			if err != nil || seen[dep] {
+				continue
+			}
+
+			dt, ok := sys.unitTime(dep)
+			if !ok || dt.Activated > ut.Activating || (next != nil && dt.Activated <= nextTime.Activated) {
+				continue
+			}
+			next, nextTime = dep, dt
+		}
+
+		if next == nil {
+			return chain, nil
+		}
+		u, ut = next, nextTime
+	}
+}
+
+// SecurityReport holds the results of the security audit of a unit
+type SecurityReport struct {
+	Unit string
+
+	// Exposure of the unit from 0.0(perfectly sandboxed) to 10.0(fully exposed) and its rating, e.g. "MEDIUM"
+	Exposure float64
+	Rating   string
+
+	Checks []unit.SecurityCheck
+}
+
+// Security returns the security audits of the units names, as systemd-analyze security does, or of all
+// the units loaded supporting one(i.e. services), if none are specified. ErrNoAudit is returned for the other units
+func (sys *Daemon) Security(names ...string) (reports []SecurityReport, err error) {
+	var units []*Unit
+	if len(names) == 0 {
+		for _, u := range sys.Units() {
+			if _, ok := u.Interface.(unit.Auditor); ok && u.IsLoaded() {
+				units = append(units, u)
+			}
+		}
+	}
+	for _, name := range names {
+		u, err := sys.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := u.Interface.(unit.Auditor); !ok {
+			return nil, unit.ParseErr(u.Name(), ErrNoAudit)
+		}
+		units = append(units, u)
+	}
+
+	reports = make([]SecurityReport, 0, len(units))
+	for _, u := range units {
+		u.definition.RLock()
+		checks := u.Interface.(unit.Auditor).Security()
+		u.definition.RUnlock()
+
+		exposure := unit.Exposure(checks)
+		reports = append(reports, SecurityReport{
+			Unit:     u.Name(),
+			Exposure: exposure,
+			Rating:   unit.Rating(exposure),
+			Checks:   checks,
+		})
+	}
+
+	if len(names) == 0 {
+		sort.Slice(reports, func(i, j int) bool { return reports[i].Unit < reports[j].Unit })
+	}
+	return reports, nil
+}
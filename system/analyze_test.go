@@ -0,0 +1,126 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	path, err := ioutil.TempDir("", "analyze-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/sleep 0.2
+RemainAfterExit=yes`,
+		"b.service": `[Unit]
+DefaultDependencies=no
+Requires=a.service
+After=a.service
+[Service]
+Type=oneshot
+ExecStart=/bin/true
+RemainAfterExit=yes`,
+		"c.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/true
+RemainAfterExit=yes`,
+		"t.target": `[Unit]
+DefaultDependencies=no
+Requires=b.service c.service
+After=b.service c.service`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	_, err = sys.BootTime()
+	assert.Equal(t, ErrBooting, err, "sys.BootTime while booting")
+
+	require.NoError(t, sys.Start("t.target"), "sys.Start")
+	sys.FinishBoot()
+
+	d, err := sys.BootTime()
+	require.NoError(t, err, "sys.BootTime")
+	assert.True(t, d > 0, "boot time")
+
+	blame := sys.Blame()
+	if assert.Len(t, blame, 4) {
+		assert.Equal(t, "a.service", blame[0].Unit, "slowest unit first")
+	}
+
+	chain, err := sys.CriticalChain("t.target")
+	require.NoError(t, err, "sys.CriticalChain")
+
+	names := make([]string, 0, len(chain))
+	for _, ut := range chain {
+		names = append(names, ut.Unit)
+	}
+	assert.Equal(t, []string{"t.target", "b.service", "a.service"}, names)
+
+	_, err = sys.CriticalChain("d.service")
+	assert.Error(t, err, "unit not loaded")
+}
+
+func TestSecurity(t *testing.T) {
+	path, err := ioutil.TempDir("", "security-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"root.service": `[Service]
+ExecStart=/bin/true
+User=root
+Delegate=yes`,
+		"nobody.service": `[Service]
+ExecStart=/bin/true
+User=nobody
+MemoryMax=64M
+TasksMax=16`,
+		"t.target": ``,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	_, err = sys.Security("t.target")
+	assert.Error(t, err, "targets are not audited")
+
+	reports, err := sys.Security("root.service", "nobody.service")
+	require.NoError(t, err, "sys.Security")
+	require.Len(t, reports, 2)
+
+	root, nobody := reports[0], reports[1]
+	assert.Equal(t, "root.service", root.Unit)
+	assert.Equal(t, "UNSAFE", root.Rating, "exposure %.1f", root.Exposure)
+	assert.True(t, nobody.Exposure < root.Exposure, "unprivileged service less exposed")
+	assert.Equal(t, "OK", nobody.Rating, "exposure %.1f", nobody.Exposure)
+
+	for _, c := range root.Checks {
+		if c.Directive == "User=" {
+			assert.False(t, c.Passed, "root.service runs as root")
+			assert.NotEmpty(t, c.Recommendation)
+		}
+	}
+
+	reports, err = sys.Security()
+	require.NoError(t, err, "sys.Security")
+	if assert.Len(t, reports, 2, "only services listed") {
+		assert.Equal(t, "nobody.service", reports[0].Unit)
+	}
+}
@@ -0,0 +1,229 @@
+package system
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+const (
+	// Unit started at boot, usually a link to the unit to start
+	DEFAULT_TARGET = "default.target"
+
+	// Unit started at boot, if DEFAULT_TARGET is not found
+	FALLBACK_TARGET = "multi-user.target"
+
+	// Kernel command line parameter overriding the unit started at boot
+	CMDLINE_UNIT = "systemgo.unit="
+
+	// Units isolated, when the system can not boot normally
+	RESCUE_TARGET    = "rescue.target"
+	EMERGENCY_TARGET = "emergency.target"
+)
+
+// cmdlineWords maps kernel command line words to the units started at boot instead of the default one,
+// the runlevels included, as SysV init accepts them
+var cmdlineWords = map[string]string{
+	"emergency": EMERGENCY_TARGET,
+	"-b":        EMERGENCY_TARGET,
+	"rescue":    RESCUE_TARGET,
+	"single":    RESCUE_TARGET,
+	"s":         RESCUE_TARGET,
+	"S":         RESCUE_TARGET,
+	"-s":        RESCUE_TARGET,
+	"1":         RESCUE_TARGET,
+	"2":         "multi-user.target",
+	"3":         "multi-user.target",
+	"4":         "multi-user.target",
+	"5":         "graphical.target",
+}
+
+// Path to the kernel command line
+var KERNEL_CMDLINE = "/proc/cmdline"
+
+// builtin maps names of the units always available to their definitions.
+// A unit file found with the same name shadows the built-in definition
+var builtin = map[string]string{
+	"sysinit.target": `[Unit]
+Description=System Initialization
+OnFailure=emergency.target
+OnFailureJobMode=isolate`,
+
+	"basic.target": `[Unit]
+Description=Basic System
+Requires=sysinit.target
+After=sysinit.target`,
+
+	"multi-user.target": `[Unit]
+Description=Multi-User System
+Requires=basic.target
+After=basic.target`,
+
+	"rescue.target": `[Unit]
+Description=Rescue Mode
+Requires=sysinit.target rescue.service
+After=sysinit.target rescue.service`,
+
+	"rescue.service": `[Unit]
+Description=Rescue Shell
+DefaultDependencies=no
+After=sysinit.target
+Conflicts=shutdown.target
+Before=shutdown.target
+[Service]
+ExecStart=/bin/sh
+StandardInput=tty`,
+
+	"emergency.target": `[Unit]
+Description=Emergency Mode
+Requires=emergency.service
+After=emergency.service`,
+
+	"network.target": `[Unit]
+Description=Network`,
+
+	NETWORK_ONLINE_TARGET: `[Unit]
+Description=Network is Online
+Wants=network.target
+After=network.target`,
+
+	"shutdown.target": `[Unit]
+Description=Shutdown
+DefaultDependencies=no`,
+
+	"reboot.target": `[Unit]
+Description=Reboot
+DefaultDependencies=no
+Requires=shutdown.target
+After=shutdown.target`,
+
+	"poweroff.target": `[Unit]
+Description=Power-Off
+DefaultDependencies=no
+Requires=shutdown.target
+After=shutdown.target`,
+
+	"halt.target": `[Unit]
+Description=Halt
+DefaultDependencies=no
+Requires=shutdown.target
+After=shutdown.target`,
+
+	"emergency.service": `[Unit]
+Description=Emergency Shell
+DefaultDependencies=no
+Conflicts=shutdown.target
+Before=shutdown.target
+[Service]
+ExecStart=/bin/sh
+StandardInput=tty`,
+}
+
+// isBuiltin returns whether u is defined by a built-in definition
+func (u *Unit) isBuiltin() bool {
+	_, ok := builtin[u.Name()]
+	return ok && u.path == ""
+}
+
+// loadBuiltin defines the unit name using the built-in definition def
+func (sys *Daemon) loadBuiltin(name, def string) (u *Unit, err error) {
+	if u, err = sys.Unit(name); err != nil {
+		u = sys.newUnit(name, sys.newInterface(name))
+	}
+
+	u.definition.Lock()
+	defer u.definition.Unlock()
+
+	u.path = ""
+
+	if err = u.Interface.Define(strings.NewReader(def)); err != nil {
+		u.setLoad(unit.Error)
+		return u, err
+	}
+
+	u.setLoad(unit.Loaded)
+	return u, nil
+}
+
+// BootTarget returns the name of the unit to start at boot.
+// The unit specified on the kernel command line by systemgo.unit=, one of "emergency", "rescue" and "single" words or a runlevel
+// takes precedence over the target specified, unless sys serves as the init process of a container.
+// If the unit is a link, the name of the unit linked to is returned. If DEFAULT_TARGET is not found, FALLBACK_TARGET is used
+func (sys *Daemon) BootTarget(target string) (name string) {
+	name = target
+	if !sys.Container() {
+		// The kernel command line of a container is the one of the host
+		if cmdline, err := cmdlineUnit(KERNEL_CMDLINE); err != nil {
+			log.Debugf("Error reading kernel command line: %s", err)
+		} else if cmdline != "" {
+			name = cmdline
+		}
+	}
+
+	for _, path := range sys.searchPaths(name) {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 && !isMasked(path) {
+			if dest, err := os.Readlink(path); err == nil {
+				return filepath.Base(dest)
+			}
+		}
+		return name
+	}
+
+	if name == DEFAULT_TARGET {
+		return FALLBACK_TARGET
+	}
+	return name
+}
+
+// cmdlineUnit returns the unit specified on the kernel command line read from path.
+// If specified multiple times, the last one is used
+func cmdlineUnit(path string) (name string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return parseCmdline(f)
+}
+
+// parseCmdline returns the unit specified in the kernel command line read from r
+func parseCmdline(r io.Reader) (name string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		param := scanner.Text()
+		if target, ok := cmdlineWords[param]; ok {
+			name = target
+		} else if strings.HasPrefix(param, CMDLINE_UNIT) {
+			name = strings.TrimPrefix(param, CMDLINE_UNIT)
+		}
+	}
+	return name, scanner.Err()
+}
+
+// FinishBoot waits for the jobs queued at boot to finish, records the time and publishes BootFinished
+func (sys *Daemon) FinishBoot() {
+	for !sys.waitForQueue(time.Minute) {
+		log.Debugf("Jobs queued at boot still running")
+	}
+
+	sys.mutex.Lock()
+	sys.booted = time.Now()
+	sys.mutex.Unlock()
+
+	sys.recordBoot()
+
+	sys.publish(Event{Type: BootFinished})
+}
@@ -0,0 +1,147 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCmdline(t *testing.T) {
+	for cmdline, name := range map[string]string{
+		"":                              "",
+		"ro quiet":                      "",
+		"ro emergency":                  "emergency.target",
+		"single systemgo.unit=a.target": "a.target",
+		"systemgo.unit=a.target rescue": "rescue.target",
+		"root=/dev/sda1 systemgo.unit=rescue.target quiet": "rescue.target",
+		"systemgo.unit=a.target systemgo.unit=b.target\n":  "b.target",
+		"ro 3":   "multi-user.target",
+		"ro S":   "rescue.target",
+		"ro -b":  "emergency.target",
+		"ro 5 1": "rescue.target",
+	} {
+		found, err := parseCmdline(strings.NewReader(cmdline))
+		require.NoError(t, err, cmdline)
+		assert.Equal(t, name, found, cmdline)
+	}
+}
+
+func TestBootTarget(t *testing.T) {
+	path, err := ioutil.TempDir("", "boot-target-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	cmdline := filepath.Join(path, "cmdline")
+	defer func(path string) {
+		KERNEL_CMDLINE = path
+	}(KERNEL_CMDLINE)
+	KERNEL_CMDLINE = cmdline
+
+	sys := New()
+	sys.SetPaths(path)
+
+	assert.Equal(t, FALLBACK_TARGET, sys.BootTarget(DEFAULT_TARGET), "default.target not found")
+	assert.Equal(t, "foo.target", sys.BootTarget("foo.target"), "target not found")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "graphical.target"), []byte(`[Unit]
+Requires=multi-user.target`), 0666), "ioutil.WriteFile")
+	require.NoError(t, os.Symlink(filepath.Join(path, "graphical.target"), filepath.Join(path, DEFAULT_TARGET)), "os.Symlink")
+	assert.Equal(t, "graphical.target", sys.BootTarget(DEFAULT_TARGET), "default.target link")
+
+	require.NoError(t, ioutil.WriteFile(cmdline, []byte("quiet systemgo.unit=rescue.target\n"), 0666), "ioutil.WriteFile")
+	assert.Equal(t, "rescue.target", sys.BootTarget(DEFAULT_TARGET), "kernel command line")
+
+	sys.SetContainer()
+	assert.Equal(t, "graphical.target", sys.BootTarget(DEFAULT_TARGET), "kernel command line of the host ignored in a container")
+}
+
+func TestBuiltin(t *testing.T) {
+	path, err := ioutil.TempDir("", "builtin-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "sysinit.target"), []byte(`[Unit]
+Description=Shadowed`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("multi-user.target"), "sys.Start")
+	waitForJobs(t, sys, "multi-user.target", "basic.target", "sysinit.target")
+
+	for _, name := range []string{"multi-user.target", "basic.target", "sysinit.target"} {
+		u, err := sys.Unit(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, unit.Loaded, u.Loaded(), name)
+		assert.True(t, u.IsActive(), name)
+	}
+
+	basic, err := sys.Unit("basic.target")
+	require.NoError(t, err)
+	assert.Equal(t, "Basic System", basic.Description())
+	assert.Contains(t, basic.After(), "sysinit.target")
+
+	sysinit, err := sys.Unit("sysinit.target")
+	require.NoError(t, err)
+	assert.Equal(t, "Shadowed", sysinit.Description(), "unit file shadows the built-in definition")
+}
+
+func TestEmergency(t *testing.T) {
+	path, err := ioutil.TempDir("", "emergency-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"broken.service": `[Unit]
+DefaultDependencies=no
+Before=sysinit.target
+[Service]
+Type=oneshot
+ExecStart=/bin/false`,
+		"other.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+		"emergency.service": `[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	requires := filepath.Join(path, "sysinit.target.requires")
+	require.NoError(t, os.Mkdir(requires, 0755), "os.Mkdir")
+	require.NoError(t, os.Symlink(filepath.Join(path, "broken.service"), filepath.Join(requires, "broken.service")), "os.Symlink")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("other.service", "multi-user.target"), "sys.Start")
+
+	emergency, err := sys.Get("emergency.service")
+	require.NoError(t, err, "sys.Get")
+	for emergency.lastJob() == nil {
+		time.Sleep(100 * time.Millisecond)
+	}
+	emergency.lastJob().Wait()
+	assert.True(t, emergency.IsActive(), "emergency.service started")
+
+	other, err := sys.Unit("other.service")
+	require.NoError(t, err)
+	for timeout := time.After(5 * time.Second); !other.IsDead(); time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("other.service was not stopped by isolation")
+		default:
+		}
+	}
+
+	require.NoError(t, sys.Stop("emergency.service"), "sys.Stop")
+	waitForJobs(t, sys, "emergency.service")
+}
@@ -0,0 +1,123 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Interval the main process of a service waiting for its bus name is checked at
+var BUS_NAME_CHECK_INTERVAL = 100 * time.Millisecond
+
+// busNames holds the names owned on the message bus, as reported by the connection to it
+type busNames struct {
+	owned map[string]bool
+
+	// Closed and replaced, whenever the names owned change
+	changed chan struct{}
+
+	mutex sync.Mutex
+}
+
+// SetBusNames replaces the names owned on the message bus with names, e.g. once connected to the bus or, with none, once disconnected
+func (sys *Daemon) SetBusNames(names ...string) {
+	sys.bus.mutex.Lock()
+	defer sys.bus.mutex.Unlock()
+
+	sys.bus.owned = map[string]bool{}
+	for _, name := range names {
+		sys.bus.owned[name] = true
+	}
+	sys.bus.notify()
+}
+
+// BusNameOwnerChanged records, whether name is owned on the message bus. The services of Type=dbus,
+// which are active, get stopped once their bus name is released
+func (sys *Daemon) BusNameOwnerChanged(name string, owned bool) {
+	sys.bus.mutex.Lock()
+	if sys.bus.owned == nil {
+		sys.bus.owned = map[string]bool{}
+	}
+	was := sys.bus.owned[name]
+	if owned {
+		sys.bus.owned[name] = true
+	} else {
+		delete(sys.bus.owned, name)
+	}
+	sys.bus.notify()
+	sys.bus.mutex.Unlock()
+
+	if owned || !was {
+		return
+	}
+
+	for _, u := range sys.Units() {
+		namer, ok := u.Interface.(unit.BusNamer)
+		if !ok || namer.BusName() != name || u.runningJob() != nil || u.Interface.Active() != unit.Active {
+			continue
+		}
+
+		u.Log.Printf("Bus name %s lost, stopping", name)
+		go func(u *Unit) {
+			if err := sys.Stop(u.Name()); err != nil {
+				u.Log.Errorf("Error stopping: %s", err)
+			}
+		}(u)
+	}
+}
+
+// notify wakes up the ones waiting for the names to change. The mutex of names must be locked
+func (names *busNames) notify() {
+	if names.changed != nil {
+		close(names.changed)
+	}
+	names.changed = make(chan struct{})
+}
+
+// isOwned reports whether name is owned on the bus along with the channel closed once that may change
+func (names *busNames) isOwned(name string) (owned bool, changed <-chan struct{}) {
+	names.mutex.Lock()
+	defer names.mutex.Unlock()
+
+	if names.changed == nil {
+		names.changed = make(chan struct{})
+	}
+	return names.owned[name], names.changed
+}
+
+// waitForBusName waits until the bus name of u, if it has one, is acquired. If it is not until ctx is done
+// or the main process of u exits before, the processes of u are stopped and an error is returned
+func (u *Unit) waitForBusName(ctx context.Context) (err error) {
+	namer, ok := u.Interface.(unit.BusNamer)
+	if !ok || namer.BusName() == "" || u.System == nil {
+		return nil
+	}
+	name := namer.BusName()
+
+	ticker := time.NewTicker(BUS_NAME_CHECK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		owned, changed := u.System.bus.isOwned(name)
+		if owned {
+			u.Log.Debugf("Bus name %s acquired", name)
+			return nil
+		}
+		if u.Interface.Active() != unit.Active {
+			u.Log.Errorf("Main process exited before acquiring bus name %s", name)
+			return ErrNoBusName
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-ctx.Done():
+			u.Log.Errorf("Bus name %s not acquired in time, stopping", name)
+			if stopper, ok := u.Interface.(unit.Stopper); ok {
+				stopper.Stop()
+			}
+			return ctx.Err()
+		}
+	}
+}
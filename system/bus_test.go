@@ -0,0 +1,91 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusName(t *testing.T) {
+	path, err := ioutil.TempDir("", "bus-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"foo.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=dbus
+BusName=org.example.Foo
+ExecStart=/bin/sleep 1000`,
+		"slow.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=dbus
+BusName=org.example.Slow
+ExecStart=/bin/sleep 1000
+TimeoutStartSec=200ms`,
+		"exits.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=dbus
+BusName=org.example.Exits
+ExecStart=/bin/true`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+	sys.SetBusNames("org.freedesktop.DBus")
+
+	require.NoError(t, sys.Start("foo.service"), "sys.Start")
+	foo, err := sys.Unit("foo.service")
+	require.NoError(t, err)
+	for foo.lastJob() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, unit.Activating, foo.Active(), "active before the name is acquired")
+
+	sys.BusNameOwnerChanged("org.example.Foo", true)
+	foo.lastJob().Wait()
+	assert.True(t, foo.lastJob().Success(), "foo.service job")
+	assert.Equal(t, unit.Active, foo.Active())
+
+	sys.BusNameOwnerChanged("org.example.Foo", false)
+	for timeout := time.After(5 * time.Second); !foo.IsDead(); time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("foo.service not stopped once the name was lost")
+		default:
+		}
+	}
+	_, failed := sys.failure(foo)
+	assert.False(t, failed, "stopped successfully")
+
+	for name, reason := range map[string]error{
+		"slow.service":  ErrStartTimeout,
+		"exits.service": ErrNoBusName,
+	} {
+		require.NoError(t, sys.Start(name), "sys.Start")
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+		for u.lastJob() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		u.lastJob().Wait()
+
+		assert.Equal(t, unit.Failed, u.Active(), name)
+		if f, ok := sys.failure(u); assert.True(t, ok, name) {
+			assert.Equal(t, reason.Error(), f.Reason, name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Cgroup(v2) under which the cgroups of the units with resource controls get created
+var CGROUP_PATH = "/sys/fs/cgroup/systemgo"
+
+// Period of CPU quotas in microseconds
+const CPU_PERIOD = 100000
+
+// Controllers enabled for the cgroups of the units
+const cgroupControllers = "+cpu +memory +pids"
+
+// cgroupPath returns the path to the cgroup of u, which is nested in the cgroup of its slice, if specified
+func (u *Unit) cgroupPath() string {
+	if slicer, ok := u.Interface.(unit.Slicer); ok && slicer.Slice() != "" {
+		return filepath.Join(CGROUP_PATH, slicer.Slice(), u.Name())
+	}
+	return filepath.Join(CGROUP_PATH, u.Name())
+}
+
+// cgroupOf returns the path to the cgroup(v2) the process pid is in, empty if it is unknown
+func cgroupOf(pid int) string {
+	b, err := ioutil.ReadFile(filepath.Join(PROC_PATH, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return filepath.Join(filepath.Dir(CGROUP_PATH), strings.TrimPrefix(line, "0::"))
+		}
+	}
+	return ""
+}
+
+// applyResources writes the resource controls of u to its cgroup and moves the main process of u
+// along with the processes it groups into it.
+// The cgroup is only created, once u has a resource control, delegation or a slice specified
+func (u *Unit) applyResources() (err error) {
+	var res unit.Resources
+	if rc, ok := u.Interface.(unit.ResourceController); ok {
+		res = rc.Resources()
+	}
+
+	var slice string
+	if slicer, ok := u.Interface.(unit.Slicer); ok {
+		slice = slicer.Slice()
+	}
+
+	path := u.cgroupPath()
+	if _, err = os.Stat(path); os.IsNotExist(err) && res == (unit.Resources{}) && slice == "" {
+		return nil
+	}
+
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return
+	}
+
+	parents := []string{filepath.Dir(CGROUP_PATH), CGROUP_PATH}
+	if slice != "" {
+		parents = append(parents, filepath.Dir(path))
+	}
+
+	// Controllers may have been enabled already
+	for _, parent := range parents {
+		if err := enableControllers(parent); err != nil {
+			log.Debugf("Error enabling controllers in %s: %s", parent, err)
+		}
+	}
+
+	cpu := "max"
+	if res.CPUQuota > 0 {
+		cpu = strconv.Itoa(int(math.Ceil(res.CPUQuota * CPU_PERIOD)))
+	}
+
+	for file, value := range map[string]string{
+		"cpu.max":    fmt.Sprintf("%s %d", cpu, CPU_PERIOD),
+		"memory.max": cgroupLimit(res.MemoryMax),
+		"pids.max":   cgroupLimit(res.TasksMax),
+	} {
+		if err = ioutil.WriteFile(filepath.Join(path, file), []byte(value), 0644); err != nil {
+			return
+		}
+	}
+
+	var pids []int
+	if attacher, ok := u.Interface.(unit.Attacher); ok && attacher.MainPID() > 0 {
+		pids = append(pids, attacher.MainPID())
+	}
+	if grouper, ok := u.Interface.(unit.Grouper); ok {
+		pids = append(pids, grouper.Processes()...)
+	}
+
+	// A single process is moved per write
+	for _, pid := range pids {
+		if err = ioutil.WriteFile(filepath.Join(path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// enableControllers enables the controllers for the children of cgroup at path, if it is a cgroup
+func enableControllers(path string) (err error) {
+	f, err := os.OpenFile(filepath.Join(path, "cgroup.subtree_control"), os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(cgroupControllers)
+	return
+}
+
+// cgroupLimit returns the limit v as written to cgroup files
+func cgroupLimit(v uint64) string {
+	if v == 0 || v == unit.Unlimited {
+		return "max"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// cgroupUsage returns the memory(in bytes) and the CPU time used by the processes in the cgroup of u,
+// as reported by memory.current and cpu.stat. ok is false, if u has no cgroup
+func (u *Unit) cgroupUsage() (memory uint64, cpu time.Duration, ok bool) {
+	path := u.cgroupPath()
+	b, err := ioutil.ReadFile(filepath.Join(path, "memory.current"))
+	if err != nil {
+		return 0, 0, false
+	}
+	memory, _ = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+
+	if b, err = ioutil.ReadFile(filepath.Join(path, "cpu.stat")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "usage_usec" {
+				usec, _ := strconv.ParseUint(fields[1], 10, 64)
+				cpu = time.Duration(usec) * time.Microsecond
+			}
+		}
+	}
+	return memory, cpu, true
+}
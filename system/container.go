@@ -0,0 +1,149 @@
+package system
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Unit the command passed to the init process of a container is run as
+const CONTAINER_PAYLOAD = "container-payload.service"
+
+// Time to wait for the processes left running in a container to exit after SIGTERM, before they get killed
+var CONTAINER_KILL_DELAY = time.Second
+
+// container is the state of the manager running as the init process of a container
+type container struct {
+	enabled bool
+
+	// Name of the unit, the exit of which stops the container, empty if none
+	payload string
+
+	// Exit status of the manager, the one of the payload once it has exited
+	status int
+}
+
+// SetContainer makes sys serve as the init process of a container, which the machine is left alone by:
+// the kernel command line is not consulted, the filesystems are not unmounted and the manager exits on shutdown
+// instead of powering the machine off
+func (sys *Daemon) SetContainer() {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.container.enabled = true
+}
+
+// StartPayload runs the command args as CONTAINER_PAYLOAD, the exit of which stops the container.
+// The manager exits with the exit status of the payload and the output of the payload is written to the
+// standard output and error of the manager, so that it gets collected by the container manager
+func (sys *Daemon) StartPayload(args []string) (err error) {
+	if len(args) == 0 {
+		return ErrNotFound
+	}
+
+	sys.mutex.Lock()
+	sys.container.payload = CONTAINER_PAYLOAD
+	sys.mutex.Unlock()
+
+	defer func() {
+		if err != nil {
+			// The container fails, as a shell failing to run the command does
+			sys.mutex.Lock()
+			sys.container.status = 127
+			sys.mutex.Unlock()
+		}
+	}()
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return
+	}
+
+	return sys.StartTransient(ReplaceMode, TransientUnit{Name: CONTAINER_PAYLOAD, Properties: []unit.Property{
+		{Name: "Description", Value: strings.Join(args, " ")},
+		{Name: "ExecStart", Value: strings.Join(append([]string{path}, args[1:]...), " ")},
+		{Name: "StandardOutput", Value: "file:/dev/stdout"},
+		{Name: "StandardError", Value: "file:/dev/stderr"},
+	}})
+}
+
+// Container reports whether sys serves as the init process of a container
+func (sys *Daemon) Container() bool {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	return sys.container.enabled
+}
+
+// Forward sends sig to the main process of the payload of the container,
+// to the main processes of all the units if there is none
+func (sys *Daemon) Forward(sig syscall.Signal) {
+	sys.mutex.Lock()
+	payload := sys.container.payload
+	sys.mutex.Unlock()
+
+	units := sys.Units()
+	if payload != "" {
+		u, err := sys.Unit(payload)
+		if err != nil {
+			log.Debugf("Signal %s not forwarded: %s", sig, err)
+			return
+		}
+		units = []*Unit{u}
+	}
+
+	for _, u := range units {
+		if attacher, ok := u.Interface.(unit.Attacher); ok && attacher.MainPID() > 0 {
+			u.Log.Printf("Forwarding signal %s to process %d", sig, attacher.MainPID())
+			syscall.Kill(attacher.MainPID(), sig)
+		}
+	}
+}
+
+// payloadExited records the exit status of u, if it is the payload of the container, and stops the container
+func (sys *Daemon) payloadExited(u *Unit, status syscall.WaitStatus) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if !sys.container.enabled || sys.container.payload != u.Name() {
+		return
+	}
+
+	// Killed processes exit with 128+signal, as the shells report
+	sys.container.status = status.ExitStatus()
+	if status.Signaled() {
+		sys.container.status = 128 + int(status.Signal())
+	}
+
+	log.Infof("%s exited, status=%d, stopping the container", u.Name(), sys.container.status)
+	go func() {
+		if err := sys.Poweroff(); err != nil {
+			log.Errorf("Error stopping the container: %s", err)
+		}
+	}()
+}
+
+// exitContainer kills the processes left running and exits the manager with the exit status of the container
+func (sys *Daemon) exitContainer() {
+	if os.Getpid() == 1 {
+		// All the processes of the container are in the PID namespace of the init process
+		log.Infof("Sending SIGTERM to remaining processes")
+		syscall.Kill(-1, syscall.SIGTERM)
+		time.Sleep(CONTAINER_KILL_DELAY)
+
+		log.Infof("Sending SIGKILL to remaining processes")
+		syscall.Kill(-1, syscall.SIGKILL)
+	}
+
+	sys.mutex.Lock()
+	status := sys.container.status
+	sys.mutex.Unlock()
+
+	log.Infof("Exiting with status %d", status)
+	os.Exit(status)
+}
@@ -0,0 +1,38 @@
+package system
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerPayload(t *testing.T) {
+	sys := New()
+	sys.SetContainer()
+	assert.True(t, sys.Container(), "sys.Container")
+
+	assert.Error(t, sys.StartPayload([]string{"/nonexistent/payload"}), "payload not found")
+	assert.Equal(t, 127, sys.container.status, "exit status of a payload not found")
+
+	require.NoError(t, sys.StartPayload([]string{"sleep", "1000"}), "sys.StartPayload")
+	waitForJobs(t, sys, CONTAINER_PAYLOAD)
+
+	u, err := sys.Unit(CONTAINER_PAYLOAD)
+	require.NoError(t, err)
+	require.True(t, u.IsActive(), "payload active")
+	pid := u.Status().MainPID
+
+	sys.Forward(syscall.SIGKILL)
+
+	timeout := time.After(5 * time.Second)
+	for u.IsActive() {
+		select {
+		case <-timeout:
+			t.Fatalf("Signal not forwarded to process %d", pid)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
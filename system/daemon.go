@@ -3,24 +3,35 @@ package system
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/plasma-umass/systemgo/journal"
 	"github.com/plasma-umass/systemgo/unit"
+	"github.com/plasma-umass/systemgo/unit/mount"
+	"github.com/plasma-umass/systemgo/unit/scope"
 	"github.com/plasma-umass/systemgo/unit/service"
+	"github.com/plasma-umass/systemgo/unit/socket"
+	"github.com/plasma-umass/systemgo/unit/timer"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/fsnotify/fsnotify"
 )
 
-// Default paths to search for unit paths - Daemon uses those, if none are specified
-var DEFAULT_PATHS = []string{"/etc/systemd/system/", "/run/systemd/system", "/lib/systemd/system"}
+// Default paths to search for unit paths in order of precedence - Daemon uses those, if none are specified.
+// A definition found in a path with higher precedence completely shadows the ones found in paths searched later
+var DEFAULT_PATHS = []string{"/etc/systemgo/system", "/run/systemgo/system", "/usr/lib/systemgo/system"}
 
 var supported = map[string]bool{
 	".service": true,
 	".target":  true,
-	".mount":   false,
-	".socket":  false,
+	".timer":   true,
+	".scope":   true,
+	".mount":   true,
+	".socket":  true,
 }
 
 // SupportedSuffix returns a bool indicating if suffix represents a unit type,
@@ -40,18 +51,86 @@ type Daemon struct {
 	// System log
 	Log *Log
 
+	// Journal the output of the units gets recorded in
+	Journal *journal.Journal
+
 	// Map of created units (name -> *Unit)
-	units map[string]*Unit
+	units      map[string]*Unit
+	unitsMutex sync.RWMutex
+
+	// Serializes loading the units
+	loadMutex sync.Mutex
 
 	// Paths, where the unit file specifications get searched for
 	paths []string
 
+	// Guards paths, presetPaths and the directories of generators, which are read while the mutex is locked
+	pathsMutex sync.RWMutex
+
+	// Paths, where the preset files get searched for
+	presetPaths []string
+
+	// Presets read from presetPaths, which are read anew on reload
+	presets presetCache
+
+	// Directory the image operated on is mounted at, empty if the running system is managed
+	root string
+
 	// System state
 	state State
 
 	// System starting time
 	since time.Time
 
+	// Time the jobs queued at boot finished, zero while booting
+	booted time.Time
+
+	// Jobs committed and not finished yet (unit -> job)
+	queue      map[*Unit]*job
+	queueMutex sync.Mutex
+
+	// ID of the job committed last
+	lastJobID uint64
+
+	// Subscriptions to the events published
+	events bus
+
+	// Units failed, which have not been reset
+	failed failures
+
+	// State of the manager serving as the init process of a container
+	container container
+
+	// Generators run at boot and on reload
+	generators generators
+
+	// Serializes running the generators, which is done without the mutex locked
+	generatorsMutex sync.Mutex
+
+	// Hardware watchdog pinged while running
+	watchdog watchdog
+
+	// Jobs finished since the manager started
+	jobCounts jobCounts
+
+	// Locks blocking or delaying shutdown
+	inhibitors inhibitors
+
+	// Checks network-online.target waits for to pass
+	network networkOnline
+
+	// Files the login records get written to
+	utmp utmpFiles
+
+	// Names owned on the message bus, which services of Type=dbus wait for
+	bus busNames
+
+	// Paths to the definitions found in the unit paths by name
+	index unitIndex
+
+	// Watches the unit paths for changes, nil unless WatchPaths was called
+	watcher *fsnotify.Watcher
+
 	mutex sync.Mutex
 }
 
@@ -59,24 +138,40 @@ type Daemon struct {
 func New() (sys *Daemon) {
 	return &Daemon{
 		units: make(map[string]*Unit),
-
-		since: time.Now(),
-		Log:   NewLog(),
-		paths: DEFAULT_PATHS,
+		queue: make(map[*Unit]*job),
+
+		since:       time.Now(),
+		Log:         NewLog(),
+		Journal:     journal.New(),
+		paths:       DEFAULT_PATHS,
+		presetPaths: DEFAULT_PRESET_PATHS,
+		inhibitors:  inhibitors{delayMax: DEFAULT_INHIBIT_DELAY_MAX},
 	}
 }
 
 // Paths returns paths, which get searched for unit files by sys(first path gets searched first)
 func (sys *Daemon) Paths() (paths []string) {
+	sys.pathsMutex.RLock()
+	defer sys.pathsMutex.RUnlock()
+
 	return sys.paths
 }
 
-// SetPaths sets paths, which get searched for unit files by sys(first path gets searched first)
+// SetPaths sets paths, which get searched for unit files by sys(first path gets searched first).
+// Paths get cleaned and duplicates are ignored
 func (sys *Daemon) SetPaths(paths ...string) {
-	sys.mutex.Lock()
-	defer sys.mutex.Unlock()
+	sys.pathsMutex.Lock()
+	defer sys.pathsMutex.Unlock()
 
-	sys.paths = paths
+	sys.paths = make([]string, 0, len(paths))
+
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if path = filepath.Clean(path); !seen[path] {
+			seen[path] = true
+			sys.paths = append(sys.paths, path)
+		}
+	}
 }
 
 // Since returns time, when sys was created
@@ -85,15 +180,13 @@ func (sys *Daemon) Since() (t time.Time) {
 }
 
 // IsEnabled returns enable state of the unit held in-memory under specified name.
-// If error is returned, it is going to be ErrNotFound
-//
-// TODO
+// If error is returned, it is going to be error from sys.Get(name)
 func (sys *Daemon) IsEnabled(name string) (st unit.Enable, err error) {
-	//var u *Unit
-	//if u, err = sys.Unit(name); err == nil && sys.Enabled[u] {
-	//st = unit.Enabled
-	//}
-	return -1, ErrNotImplemented
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return -1, err
+	}
+	return u.Enabled(), nil
 }
 
 // IsActive returns activation state of the unit held in-memory under specified name.
@@ -107,10 +200,11 @@ func (sys *Daemon) IsActive(name string) (st unit.Activation, err error) {
 }
 
 // StatusOf returns status of the unit held in-memory under specified name.
-// If error is returned, it is going to be ErrNotFound
+// Status is reported for units, which failed to load(e.g. masked ones) as well.
+// If error is returned, it is going to be the error encountered loading the unit
 func (sys *Daemon) StatusOf(name string) (st unit.Status, err error) {
 	var u *Unit
-	if u, err = sys.Get(name); err != nil {
+	if u, err = sys.Get(name); u == nil {
 		return
 	}
 
@@ -119,75 +213,208 @@ func (sys *Daemon) StatusOf(name string) (st unit.Status, err error) {
 
 // Start gets names from internal hashmap, creates a new start transaction and runs it
 func (sys *Daemon) Start(names ...string) (err error) {
-	log.WithField("names", names).Debugf("sys.Start")
+	return sys.StartWith(ReplaceMode, names...)
+}
 
-	var tr *transaction
-	if tr, err = sys.newTransaction(start, names); err != nil {
-		return
-	}
-	return tr.Run()
+// StartWith is like Start, but enqueues the jobs according to mode
+func (sys *Daemon) StartWith(mode JobMode, names ...string) (err error) {
+	log.WithFields(log.Fields{
+		"mode":  mode,
+		"names": names,
+	}).Debugf("sys.Start")
+
+	return sys.run(start, mode, names)
 }
 
 // Stop gets names from internal hashmap, creates a new stop transaction and runs it
 func (sys *Daemon) Stop(names ...string) (err error) {
-	log.WithField("names", names).Debugf("sys.Stop")
+	return sys.StopWith(ReplaceMode, names...)
+}
 
-	var tr *transaction
-	if tr, err = sys.newTransaction(stop, names); err != nil {
-		return
-	}
-	return tr.Run()
+// StopWith is like Stop, but enqueues the jobs according to mode
+func (sys *Daemon) StopWith(mode JobMode, names ...string) (err error) {
+	log.WithFields(log.Fields{
+		"mode":  mode,
+		"names": names,
+	}).Debugf("sys.Stop")
+
+	return sys.run(stop, mode, names)
 }
 
 // Isolate gets names from internal hashmap, creates a new start transaction, adds a stop job
-// for each unit currently active, but not in the transaction already and runs the transaction
+// for each unit currently active, but not in the transaction already and not ignoring isolation
+// and runs the transaction
 func (sys *Daemon) Isolate(names ...string) (err error) {
 	log.WithField("names", names).Debugf("sys.Isolate")
 
-	var tr *transaction
-	if tr, err = sys.newTransaction(start, names); err != nil {
+	return sys.run(start, IsolateMode, names)
+}
+
+// Restart gets names from internal hashmap, creates a new restart transaction and runs it
+func (sys *Daemon) Restart(names ...string) (err error) {
+	return sys.RestartWith(ReplaceMode, names...)
+}
+
+// RestartWith is like Restart, but enqueues the jobs according to mode
+func (sys *Daemon) RestartWith(mode JobMode, names ...string) (err error) {
+	log.WithFields(log.Fields{
+		"mode":  mode,
+		"names": names,
+	}).Debugf("sys.Restart")
+
+	return sys.run(restart, mode, names)
+}
+
+// Reload gets names from internal hashmap, creates a new reload transaction and runs it
+func (sys *Daemon) Reload(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Reload")
+
+	return sys.run(reload, ReplaceMode, names)
+}
+
+// DaemonReload re-reads the definitions of all units loaded from unit files.
+// Units are redefined in place, hence the jobs and processes of running units are preserved
+// and the dependencies get recomputed from the new definitions.
+// Units, which can not be found anymore, keep running, but are marked as not found.
+// The generators are run anew beforehand
+func (sys *Daemon) DaemonReload() {
+	log.Debugf("sys.DaemonReload")
+
+	// The generators may produce other unit files now
+	sys.RunGenerators()
+	sys.presets.reset()
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for _, u := range sys.Units() {
+		sys.reload(u)
+	}
+}
+
+// reload re-reads the definition of u, unless it is supervised directly. The mutex of sys must be locked
+func (sys *Daemon) reload(u *Unit) {
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	if u.path == "" && !u.isBuiltin() {
+		// Supervised directly
 		return
 	}
 
-	for _, u := range sys.Units() {
-		if _, ok := tr.unmerged[u]; ok {
-			continue
-		}
+	// The definition may now be found in a path with a higher precedence and the aliases may have changed
+	sys.removeAliases(u)
 
-		if err = tr.add(stop, u, nil, true, true); err != nil {
-			return
-		}
+	if _, err := sys.load(u.Name()); err == ErrNotFound {
+		u.Log.Errorf("Unit file not found anymore")
+		u.setLoad(unit.NotFound)
 	}
-	return tr.Run()
 }
 
-// Restart gets names from internal hashmap, creates a new restart transaction and runs it
-func (sys *Daemon) Restart(names ...string) (err error) {
-	log.WithField("names", names).Debugf("sys.Restart")
+// run creates a new transaction of jobs of type typ for units specified by names and runs it in mode
+func (sys *Daemon) run(typ jobType, mode JobMode, names []string) (err error) {
+	if mode, err = checkMode(typ, mode); err != nil {
+		return
+	}
 
 	var tr *transaction
-	if tr, err = sys.newTransaction(restart, names); err != nil {
+	if tr, err = sys.newTransaction(typ, mode, names); err != nil {
 		return
 	}
+
+	if mode == IsolateMode {
+		// Stop every other unit running, unless it is explicitly left alone
+		for _, u := range sys.Units() {
+			if _, ok := tr.unmerged[u]; ok || u.IgnoreOnIsolate() {
+				continue
+			}
+
+			// The jobs queued for the unit are canceled once the transaction is committed
+			tr.isolated = append(tr.isolated, u)
+
+			if st := u.Active(); st == unit.Inactive || st == unit.Failed {
+				continue
+			}
+
+			if err = tr.add(stop, u, nil, true, true); err != nil {
+				return
+			}
+		}
+	}
 	return tr.Run()
 }
 
-// Reload gets names from internal hashmap, creates a new reload transaction and runs it
-func (sys *Daemon) Reload(names ...string) (err error) {
-	log.WithField("names", names).Debugf("sys.Reload")
+// cancelQueued cancels the job queued for u, if there is one
+func (sys *Daemon) cancelQueued(u *Unit) {
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
 
-	var tr *transaction
-	if tr, err = sys.newTransaction(reload, names); err != nil {
-		return
+	if j, ok := sys.queue[u]; ok {
+		j.cancel()
 	}
-	return tr.Run()
 }
 
-func (sys *Daemon) newTransaction(typ jobType, names []string) (tr *transaction, err error) {
+// ListJobs returns the descriptions of jobs queued ordered by ID
+func (sys *Daemon) ListJobs() (jobs []JobInfo) {
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
+
+	jobs = make([]JobInfo, 0, len(sys.queue))
+	for _, j := range sys.queue {
+		jobs = append(jobs, j.info())
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].ID < jobs[k].ID
+	})
+	return
+}
+
+// GetJob returns the description of the queued job with the id specified
+func (sys *Daemon) GetJob(id uint64) (info JobInfo, err error) {
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
+
+	for _, j := range sys.queue {
+		if j.id == id {
+			return j.info(), nil
+		}
+	}
+	return info, ErrNoSuchJob
+}
+
+// CancelJob cancels the job with the id specified. A job waiting for its dependencies
+// finishes immediately, a running one gets interrupted
+func (sys *Daemon) CancelJob(id uint64) (err error) {
+	log.WithField("id", id).Debugf("sys.CancelJob")
+
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
+
+	for _, j := range sys.queue {
+		if j.id == id {
+			j.cancel()
+			return nil
+		}
+	}
+	return ErrNoSuchJob
+}
+
+// dequeue removes j from the job queue, if it is still queued
+func (sys *Daemon) dequeue(j *job) {
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
+
+	if sys.queue[j.unit] == j {
+		delete(sys.queue, j.unit)
+	}
+}
+
+func (sys *Daemon) newTransaction(typ jobType, mode JobMode, names []string) (tr *transaction, err error) {
 	sys.mutex.Lock()
 	defer sys.mutex.Unlock()
 
 	tr = newTransaction()
+	tr.mode = mode
 
 	for _, name := range names {
 		var dep *Unit
@@ -228,6 +455,73 @@ func (sys *Daemon) Disable(names ...string) (err error) {
 	})
 }
 
+// Mask masks the units specified by name by linking their definitions in the path
+// with the highest precedence to /dev/null, so that they can not be loaded or started
+func (sys *Daemon) Mask(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Mask")
+
+	for _, name := range names {
+		if err = sys.mask(unit.Normalize(name)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (sys *Daemon) mask(name string) (err error) {
+	dir, err := sys.firstPath()
+	if err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, filepath.Base(name))
+	if isMasked(path) {
+		return nil
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	if err = os.Symlink(os.DevNull, path); err != nil {
+		return
+	}
+
+	if u, err := sys.Unit(name); err == nil {
+		u.setLoad(unit.Masked)
+	}
+	return nil
+}
+
+// Unmask removes the links to /dev/null created by Mask for units specified by name
+func (sys *Daemon) Unmask(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Unmask")
+
+	for _, name := range names {
+		if err = sys.unmask(unit.Normalize(name)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (sys *Daemon) unmask(name string) (err error) {
+	for _, path := range sys.searchPaths(filepath.Base(name)) {
+		if !isMasked(path) {
+			continue
+		}
+
+		if err = os.Remove(path); err != nil {
+			return
+		}
+	}
+
+	if u, err := sys.Unit(name); err == nil && u.IsMasked() {
+		// The definition gets loaded on next access
+		u.setLoad(unit.Stub)
+	}
+	return nil
+}
+
 func (sys *Daemon) getAndExecute(names []string, fn func(*Unit, error) error) (err error) {
 	for _, name := range names {
 		if err = fn(sys.Get(name)); err != nil {
@@ -241,10 +535,12 @@ func (sys *Daemon) getAndExecute(names []string, fn func(*Unit, error) error) (e
 func (sys *Daemon) Units() (units []*Unit) {
 	log.Debugf("sys.Units")
 
+	sys.unitsMutex.RLock()
 	unitSet := map[*Unit]struct{}{}
 	for _, u := range sys.units {
 		unitSet[u] = struct{}{}
 	}
+	sys.unitsMutex.RUnlock()
 
 	units = make([]*Unit, 0, len(unitSet))
 	for u := range unitSet {
@@ -253,28 +549,42 @@ func (sys *Daemon) Units() (units []*Unit) {
 	return
 }
 
-// Unit looks up unit name in the internal hasmap and returns the unit created associated with it
-// or nil and ErrNotFound, if it does not exist
+// Unit looks up unit name, or its normalized form(e.g. "foo.service" for "foo"), in the internal hasmap
+// and returns the unit created associated with it or nil and ErrNotFound, if it does not exist.
+// The aliases of a unit refer to the unit itself
 func (sys *Daemon) Unit(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debug("sys.Unit")
 
+	sys.unitsMutex.RLock()
+	defer sys.unitsMutex.RUnlock()
+
 	var ok bool
 	if u, ok = sys.units[name]; !ok {
-		return nil, ErrNotFound
+		if u, ok = sys.units[unit.Normalize(name)]; !ok {
+			return nil, ErrNotFound
+		}
 	}
 	return
 }
 
 // Get looks up the unit name in the internal hasmap of loaded units and calls
-// sys.Load(name) if it can not be found.
+// sys.Load(name) with name normalized if it can not be found. If name is an alias, the unit it refers to is loaded.
 // If error is returned, it will be error from sys.Load(name)
 func (sys *Daemon) Get(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debug("sys.Get")
 
-	if u, err = sys.Unit(name); err != nil || !u.IsLoaded() {
-		return sys.load(name)
+	if u, err = sys.Unit(name); err == nil && u.IsLoaded() {
+		return
 	}
-	return
+
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	// The unit may have been loaded meanwhile
+	if u, err = sys.Unit(name); err == nil && u.IsLoaded() {
+		return
+	}
+	return sys.loadAliased(unit.Normalize(name))
 }
 
 // Supervise creates a *Unit wrapping v and stores it in internal hashmap.
@@ -292,6 +602,28 @@ func (sys *Daemon) Supervise(name string, v unit.Interface) (u *Unit, err error)
 	return sys.newUnit(name, v), nil
 }
 
+// newInterface returns a new, undefined value of the type of unit name
+func (sys *Daemon) newInterface(name string) unit.Interface {
+	switch filepath.Ext(name) {
+	case ".target":
+		return &Target{System: sys, name: name}
+	case ".service":
+		return &service.Unit{}
+	case ".timer":
+		return timer.New(name, func(name string) error {
+			return sys.Start(name)
+		})
+	case ".scope":
+		return &scope.Unit{}
+	case ".mount":
+		return &mount.Unit{}
+	case ".socket":
+		return socket.New(name, sys.activate, sys.isRunning)
+	default:
+		panic("Trying to load an unsupported unit type")
+	}
+}
+
 func (sys *Daemon) newUnit(name string, v unit.Interface) (u *Unit) {
 	log.WithFields(log.Fields{
 		"name":      name,
@@ -303,34 +635,57 @@ func (sys *Daemon) newUnit(name string, v unit.Interface) (u *Unit) {
 
 	u.System = sys
 
+	if sys.Journal != nil {
+		// The messages logged about the unit are recorded along with its output
+		u.Log.Hooks.Add(sys.Journal.Hook(name))
+
+		if outputter, ok := v.(unit.Outputter); ok {
+			outputter.SetOutput(sys.Journal.Writer(name, journal.Info), sys.Journal.Writer(name, journal.Info))
+		}
+	}
+
+	sys.unitsMutex.Lock()
 	sys.units[name] = u
 	if strings.HasSuffix(name, ".service") {
 		sys.units[strings.TrimSuffix(name, ".service")] = u
 	}
+	sys.unitsMutex.Unlock()
 
 	return
 }
 
 // load searches for name in configured paths, parses it, and either overwrites the definition of already
-// created Unit or creates a new one
+// created Unit or creates a new one. The load mutex of sys must be locked
 func (sys *Daemon) load(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debugln("sys.Load")
 
+	defer func() {
+		if err != nil || u == nil || !u.IsLoaded() {
+			return
+		}
+
+		u.conflicting = u.Conflicts()
+		sys.setLogRateLimit(u)
+		sys.addAliases(u, u.aliases()...)
+		if collector, ok := u.Interface.(unit.Collector); ok {
+			if mode := collector.CollectMode(); mode != "" && mode != CollectInactive && mode != CollectInactiveOrFailed {
+				u.Log.Warnf("Unknown CollectMode=%s, %s assumed", mode, CollectInactive)
+			}
+		}
+		if sys.subscribed() {
+			sys.publish(Event{Type: UnitLoaded, Unit: u.Name(), Active: u.Active()})
+		}
+	}()
+
 	if !Supported(name) {
 		return nil, ErrUnknownType
 	}
 
-	var paths []string
-	if filepath.IsAbs(name) {
-		paths = []string{name}
-	} else {
-		paths = make([]string, len(sys.paths))
-		for i, path := range sys.paths {
-			paths[i] = filepath.Join(path, name)
-		}
+	if unit.IsTemplate(name) {
+		return nil, ErrNoInstance
 	}
 
-	for _, path := range paths {
+	for _, path := range sys.definitionPaths(name) {
 		var file *os.File
 		if file, err = os.Open(path); err != nil {
 			if os.IsNotExist(err) {
@@ -347,21 +702,25 @@ func (sys *Daemon) load(name string) (u *Unit, err error) {
 		// Check if a unit for name had already been created
 		if u, err = sys.Unit(name); err != nil {
 			// If not - create a new one
-			var v unit.Interface
-			switch filepath.Ext(name) {
-			case ".target":
-				v = &Target{System: sys}
-			case ".service":
-				v = &service.Unit{}
-			default:
-				panic("Trying to load an unsupported unit type")
-			}
-
-			u = sys.newUnit(name, v)
+			u = sys.newUnit(name, sys.newInterface(name))
 		}
 
+		u.definition.Lock()
+		defer u.definition.Unlock()
+
 		u.path = path
-		sys.units[path] = u
+		if !unit.IsTemplate(path) {
+			// Template definitions are shared by all instances
+			sys.unitsMutex.Lock()
+			sys.units[path] = u
+			sys.unitsMutex.Unlock()
+		}
+
+		if isMasked(path) {
+			u.setLoad(unit.Masked)
+			file.Close()
+			return u, ErrMasked
+		}
 
 		var info os.FileInfo
 		if info, err = file.Stat(); err == nil && info.IsDir() {
@@ -373,7 +732,29 @@ func (sys *Daemon) load(name string) (u *Unit, err error) {
 			return u, err
 		}
 
-		if err = u.Interface.Define(file); err != nil {
+		u.dropIns = sys.dropInPaths(name)
+		u.loadedFiles = modTimes(append([]string{path}, u.dropIns...))
+
+		var opts unit.Options
+		if opts, err = readDefinition(file, u.dropIns); err == nil {
+			if err = unit.NewSpecifiers(filepath.Base(name), path).ExpandOptions(opts); err == nil {
+				err = u.Interface.Define(opts.Reader())
+			}
+		}
+
+		var warnings []string
+		if w, ok := err.(unit.Warnings); ok {
+			warnings = w.Errors()
+			for _, msg := range warnings {
+				u.Log.Warn(msg)
+			}
+			err = nil
+		}
+		u.mutex.Lock()
+		u.warnings = warnings
+		u.mutex.Unlock()
+
+		if err != nil {
 			if me, ok := err.(unit.MultiError); ok {
 				u.Log.Error("Definition is invalid:")
 				for _, errmsg := range me.Errors() {
@@ -382,18 +763,100 @@ func (sys *Daemon) load(name string) (u *Unit, err error) {
 			} else {
 				u.Log.Errorf("Error parsing definition: %s", err)
 			}
-			u.load = unit.Error
+			u.setLoad(unit.Error)
 			file.Close()
 			return u, err
 		}
 
-		u.load = unit.Loaded
+		u.setLoad(unit.Loaded)
+		u.setRuntimeProperties()
 		return u, file.Close()
 	}
 
+	if def, ok := builtin[name]; ok {
+		return sys.loadBuiltin(name, def)
+	}
 	return nil, ErrNotFound
 }
 
+// firstPath returns the path searched for unit files first, which the files written by sys get created in.
+// ErrNotFound is returned, if there are no paths
+func (sys *Daemon) firstPath() (path string, err error) {
+	paths := sys.Paths()
+	if len(paths) == 0 {
+		return "", ErrNotFound
+	}
+	return paths[0], nil
+}
+
+// searchPaths returns the paths, where the definition of name gets searched for(first path gets searched first)
+func (sys *Daemon) searchPaths(name string) (paths []string) {
+	for _, path := range sys.unitPaths() {
+		paths = append(paths, filepath.Join(path, name))
+	}
+	return
+}
+
+// dropInPaths returns paths to drop-in configuration files of unit name found in '<name>.d' directories
+// in configured paths(and those of the template, if name is an instance) sorted by file name.
+// A drop-in found in a path searched first shadows the ones with the same file name in paths searched later
+func (sys *Daemon) dropInPaths(name string) (paths []string) {
+	name = filepath.Base(name)
+
+	names := []string{name}
+	if unit.IsInstance(name) {
+		names = append(names, unit.TemplateOf(name))
+	}
+
+	found := map[string]string{}
+	for _, path := range sys.unitPaths() {
+		for _, name := range names {
+			dropIns, err := filepath.Glob(filepath.Join(path, name+".d", "*.conf"))
+			if err != nil {
+				continue
+			}
+
+			for _, dropIn := range dropIns {
+				if _, ok := found[filepath.Base(dropIn)]; !ok {
+					found[filepath.Base(dropIn)] = dropIn
+				}
+			}
+		}
+	}
+
+	filenames := make([]string, 0, len(found))
+	for filename := range found {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	paths = make([]string, len(filenames))
+	for i, filename := range filenames {
+		paths[i] = found[filename]
+	}
+	return
+}
+
+// readDefinition returns the options of the definition read from file followed by the
+// options found in drop-in files at paths specified
+func readDefinition(file *os.File, dropIns []string) (opts unit.Options, err error) {
+	if opts, err = unit.Deserialize(file); err != nil {
+		return nil, err
+	}
+
+	var found unit.Options
+	if found, err = unit.ReadOptions(dropIns...); err != nil {
+		return nil, err
+	}
+	return append(opts, found...), nil
+}
+
+// isMasked returns whether the definition found at path is masked, i.e. is a link to /dev/null
+func isMasked(path string) bool {
+	target, err := filepath.EvalSymlinks(path)
+	return err == nil && target == os.DevNull
+}
+
 // pathset returns a slice of paths to definitions of supported unit types found in path specified
 func pathset(path string) (definitions []string, err error) {
 	var file *os.File
@@ -423,3 +886,26 @@ func pathset(path string) (definitions []string, err error) {
 
 	return
 }
+
+// Reaped delivers the exit status of process pid reaped to the unit owning it.
+// The processes not owned by any unit are orphans adopted by the init process
+func (sys *Daemon) Reaped(pid int, status syscall.WaitStatus) {
+	for _, u := range sys.Units() {
+		if exiter, ok := u.Interface.(unit.Exiter); ok && exiter.Owns(pid) {
+			u.Log.Printf("Process %d exited, status=%d", pid, status.ExitStatus())
+			exiter.Exited(pid, status)
+			if !u.IsActive() {
+				sys.payloadExited(u, status)
+			}
+			if _, failed := sys.failure(u); !failed && u.Interface.Active() == unit.Failed {
+				sys.recordFailure(u, exitCode)
+			}
+			sys.recordDead(u, pid)
+			u.entered(u.Active())
+			u.publishActive()
+			u.scheduleRestart()
+			return
+		}
+	}
+	log.Debugf("Reaped process %d, status=%d", pid, status.ExitStatus())
+}
@@ -1,10 +1,13 @@
 package system
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/plasma-umass/systemgo/test/mock_unit"
 	"github.com/plasma-umass/systemgo/unit"
+	"github.com/plasma-umass/systemgo/unit/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,6 +53,9 @@ func TestGet(t *testing.T) {
 
 	m.MockInterface.EXPECT().Define(gomock.Any()).Return(nil).Times(1)
 
+	// Conflicts are recorded on loading
+	m.MockInterface.EXPECT().Conflicts().Return(nil).Times(1)
+
 	u, err := sys.Supervise(name, m)
 	require.NoError(t, err)
 
@@ -61,6 +68,161 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetInstance(t *testing.T) {
+	path, err := ioutil.TempDir("", "instance-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	err = ioutil.WriteFile(filepath.Join(path, "getty@.service"), []byte(`[Service]
+ExecStart=/bin/echo %i`), 0666)
+	require.NoError(t, err, "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	_, err = sys.Get("getty@.service")
+	assert.Equal(t, ErrNoInstance, err, "sys.Get(template)")
+
+	for _, instance := range []string{"tty1", "tty2"} {
+		name := "getty@" + instance + ".service"
+
+		u, err := sys.Get(name)
+		require.NoError(t, err, name)
+
+		assert.Equal(t, name, u.Name(), "u.Name()")
+		assert.Equal(t, filepath.Join(path, "getty@.service"), u.Path(), "u.Path()")
+		if sv, ok := u.Interface.(*service.Unit); assert.True(t, ok, "u.Interface is *service.Unit") {
+			assert.Equal(t, "/bin/echo "+instance, sv.Definition.Service.ExecStart)
+		}
+	}
+
+	first, _ := sys.Unit("getty@tty1.service")
+	second, _ := sys.Unit("getty@tty2.service")
+	assert.NotEqual(t, first, second, "instances share a unit")
+}
+
+func TestGetDropIns(t *testing.T) {
+	vendor, err := ioutil.TempDir("", "dropin-vendor")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(vendor)
+
+	admin, err := ioutil.TempDir("", "dropin-admin")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(admin)
+
+	for path, contents := range map[string]string{
+		filepath.Join(vendor, "foo.service"): `[Unit]
+Description=vendor
+After=a.service
+[Service]
+ExecStart=/bin/true`,
+		filepath.Join(vendor, "foo.service.d", "10-vendor.conf"): `[Unit]
+After=b.service`,
+		filepath.Join(vendor, "foo.service.d", "20-override.conf"): `[Unit]
+Description=shadowed`,
+		filepath.Join(admin, "foo.service.d", "20-override.conf"): `[Unit]
+Description=admin
+After=
+After=c.service`,
+		filepath.Join(admin, "foo.service.d", "30-more.conf"): `[Unit]
+After=d.service`,
+		filepath.Join(admin, "foo.service.d", "ignored.txt"): `[Unit]
+Description=ignored`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "os.MkdirAll")
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	assert.Equal(t, []string{
+		filepath.Join(vendor, "foo.service.d", "10-vendor.conf"),
+		filepath.Join(admin, "foo.service.d", "20-override.conf"),
+		filepath.Join(admin, "foo.service.d", "30-more.conf"),
+	}, u.DropIns(), "u.DropIns()")
+	assert.Equal(t, "admin", u.Description(), "u.Description()")
+	assert.Equal(t, []string{"c.service", "d.service"}, u.Interface.After(), "u.Interface.After()")
+}
+
+func TestGetPrecedence(t *testing.T) {
+	paths := make([]string, 3)
+	for i := range paths {
+		path, err := ioutil.TempDir("", "precedence-test")
+		require.NoError(t, err, "ioutil.TempDir")
+		defer os.RemoveAll(path)
+
+		paths[i] = path
+	}
+
+	for _, path := range paths[1:] {
+		err := ioutil.WriteFile(filepath.Join(path, "foo.service"), []byte(`[Unit]
+Description=`+path+`
+[Service]
+ExecStart=/bin/true`), 0666)
+		require.NoError(t, err, "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(paths[0], paths[1], paths[1]+"/", paths[2])
+	assert.Equal(t, paths, sys.Paths(), "sys.Paths()")
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	assert.Equal(t, filepath.Join(paths[1], "foo.service"), u.Path(), "u.Path()")
+	assert.Equal(t, paths[1], u.Description(), "u.Description()")
+	assert.Equal(t, filepath.Join(paths[1], "foo.service"), u.Status().Load.Path, "u.Status().Load.Path")
+}
+
+func TestMask(t *testing.T) {
+	admin, err := ioutil.TempDir("", "mask-admin")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "mask-vendor")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(vendor)
+
+	err = ioutil.WriteFile(filepath.Join(vendor, "foo.service"), []byte(`[Service]
+ExecStart=/bin/true`), 0666)
+	require.NoError(t, err, "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	require.NoError(t, sys.Mask("foo.service"), "sys.Mask")
+	assert.True(t, u.IsMasked(), "u.IsMasked()")
+
+	target, err := os.Readlink(filepath.Join(admin, "foo.service"))
+	require.NoError(t, err, "os.Readlink")
+	assert.Equal(t, os.DevNull, target, "link target")
+
+	_, err = sys.Get("foo.service")
+	assert.Equal(t, ErrMasked, err, "sys.Get")
+	assert.Equal(t, ErrMasked, sys.Start("foo.service"), "sys.Start")
+
+	st, err := sys.StatusOf("foo.service")
+	if assert.NoError(t, err, "sys.StatusOf") {
+		assert.Equal(t, unit.Masked, st.Load.Loaded, "st.Load.Loaded")
+	}
+	assert.Equal(t, unit.Masked, u.Status().Load.Loaded, "u.Status().Load.Loaded")
+
+	require.NoError(t, sys.Unmask("foo.service"), "sys.Unmask")
+	_, err = os.Lstat(filepath.Join(admin, "foo.service"))
+	assert.True(t, os.IsNotExist(err), "link removed")
+
+	u, err = sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get after unmask")
+	assert.True(t, u.IsLoaded(), "u.IsLoaded()")
+}
+
 func TestSuported(t *testing.T) {
 	for suffix, is := range supported {
 		assert.Equal(t, is, Supported("foo"+suffix))
@@ -147,12 +309,78 @@ func TestStart(t *testing.T) {
 	waitForJobs(t, sys, names...)
 }
 
+func TestParallelStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a, b := newMock(ctrl), newMock(ctrl)
+
+	// b requires a, but is not ordered after it, hence both start at once
+	empty(a, "wants", "before", "conflicts", "after", "requires")
+	empty(b, "wants", "before", "conflicts", "after")
+	b.MockInterface.EXPECT().Requires().Return([]string{"a"}).Times(1)
+
+	bStarted := make(chan struct{})
+	a.MockStarter.EXPECT().Start().Do(func() {
+		select {
+		case <-bStarted:
+		case <-time.After(5 * time.Second):
+			t.Error("b did not start in parallel with a")
+		}
+	}).Return(nil).Times(1)
+	b.MockStarter.EXPECT().Start().Do(func() {
+		close(bStarted)
+	}).Return(nil).Times(1)
+
+	sys := New()
+	for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+		m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Start("b"))
+	waitForJobs(t, sys, "a", "b")
+}
+
+func TestStopOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a, b := newMock(ctrl), newMock(ctrl)
+
+	// a is started after b, so it is stopped before b
+	empty(a, "before")
+	a.MockInterface.EXPECT().After().Return([]string{"b"}).Times(1)
+	empty(b, "before", "after")
+
+	gomock.InOrder(
+		a.MockStopper.EXPECT().Stop().Return(nil).Times(1),
+		b.MockStopper.EXPECT().Stop().Return(nil).Times(1),
+	)
+
+	sys := New()
+	for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+		m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Stop("b", "a"))
+	waitForJobs(t, sys, "a", "b")
+}
+
 func TestStop(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	m := newMock(ctrl)
 	m.MockStopper.EXPECT().Stop().Return(nil).Times(1)
+	empty(m, "after", "before")
 	m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
 
 	sys := New()
@@ -165,6 +393,187 @@ func TestStop(t *testing.T) {
 	waitForJobs(t, sys, "TestStop")
 }
 
+func TestPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a, b := newMock(ctrl), newMock(ctrl)
+
+	empty(a, "wants", "before", "conflicts", "after", "requires")
+	empty(b, "wants", "before", "conflicts")
+	b.MockInterface.EXPECT().After().Return([]string{"a"}).Times(1)
+	b.MockInterface.EXPECT().Requires().Return([]string{"a"}).Times(1)
+
+	// a is already active, so it must not be started again
+	a.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	b.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+	b.MockStarter.EXPECT().Start().Return(nil).Times(1)
+
+	sys := New()
+	for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Start("b"))
+	waitForJobs(t, sys, "b")
+
+	ua, err := sys.Unit("a")
+	require.NoError(t, err)
+	assert.Nil(t, ua.lastJob(), "redundant job committed")
+
+	sys.queueMutex.Lock()
+	assert.Empty(t, sys.queue)
+	sys.queueMutex.Unlock()
+}
+
+func TestOrderingCycle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, required := range []bool{false, true} {
+		a, b := newMock(ctrl), newMock(ctrl)
+
+		if required {
+			a.MockInterface.EXPECT().Requires().Return([]string{"b"}).AnyTimes()
+			emptyOne(a, "wants").AnyTimes()
+		} else {
+			a.MockInterface.EXPECT().Wants().Return([]string{"b"}).AnyTimes()
+			emptyOne(a, "requires").AnyTimes()
+		}
+		a.MockInterface.EXPECT().After().Return([]string{"b"}).AnyTimes()
+		b.MockInterface.EXPECT().After().Return([]string{"a"}).AnyTimes()
+
+		for _, m := range []*mockUnit{a, b} {
+			for _, method := range []string{"before", "conflicts"} {
+				emptyOne(m, method).AnyTimes()
+			}
+			m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+		}
+		emptyOne(b, "wants").AnyTimes()
+		emptyOne(b, "requires").AnyTimes()
+
+		sys := New()
+		for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+			u, err := sys.Supervise(name, m)
+			require.NoError(t, err)
+			u.load = unit.Loaded
+		}
+
+		if required {
+			err := sys.Start("a")
+			if assert.IsType(t, CycleError{}, err) {
+				assert.Len(t, err, 3)
+				assert.Equal(t, err.(CycleError)[0], err.(CycleError)[2])
+			}
+			continue
+		}
+
+		// The job for b is only wanted, so it gets deleted to break the cycle
+		a.MockStarter.EXPECT().Start().Return(nil).Times(1)
+		require.NoError(t, sys.Start("a"))
+		waitForJobs(t, sys, "a")
+
+		ub, err := sys.Unit("b")
+		require.NoError(t, err)
+		assert.Nil(t, ub.lastJob(), "job for b committed")
+	}
+}
+
+func TestJobModes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a, b := newMock(ctrl), newMock(ctrl)
+	for _, m := range []*mockUnit{a, b} {
+		m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+	}
+	for _, method := range []string{"wants", "requires", "conflicts", "after", "before"} {
+		emptyOne(a, method).AnyTimes()
+	}
+
+	sys := New()
+	for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	assert.Equal(t, ErrUnknownJobMode, sys.StartWith("foo", "a"))
+	assert.Equal(t, ErrIsolate, sys.StopWith(IsolateMode, "a"))
+
+	// Dependencies of b are not even looked up
+	b.MockStarter.EXPECT().Start().Return(nil).Times(1)
+	require.NoError(t, sys.StartWith(IgnoreDependencies, "b"))
+	waitForJobs(t, sys, "b")
+
+	ua, err := sys.Unit("a")
+	require.NoError(t, err)
+	assert.Nil(t, ua.lastJob(), "dependency started")
+
+	// A pending stop job can not be replaced by a start job
+	sys.queue[ua] = newJob(stop, ua)
+	assert.Equal(t, ErrJobPending, sys.StartWith(FailMode, "a"))
+	assert.Nil(t, ua.lastJob(), "job committed")
+}
+
+func TestCancelJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	a, b := newMock(ctrl), newMock(ctrl)
+
+	empty(a, "wants", "before", "conflicts", "after", "requires")
+	empty(b, "wants", "before", "conflicts")
+	b.MockInterface.EXPECT().After().Return([]string{"a"}).Times(1)
+	b.MockInterface.EXPECT().Requires().Return([]string{"a"}).Times(1)
+
+	// a keeps starting until released, b is never started
+	release := make(chan struct{})
+	a.MockStarter.EXPECT().Start().Do(func() { <-release }).Return(nil).Times(1)
+
+	sys := New()
+	for name, m := range map[string]*mockUnit{"a": a, "b": b} {
+		m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Start("b"))
+
+	ub, err := sys.Unit("b")
+	require.NoError(t, err)
+	j := ub.lastJob()
+
+	// a is running, so b is waiting for it
+	ua, err := sys.Unit("a")
+	require.NoError(t, err)
+
+	jobs := sys.ListJobs()
+	if assert.Len(t, jobs, 2, "sys.ListJobs") {
+		assert.Equal(t, JobInfo{ID: ua.lastJob().id, Unit: "a", Type: "start", State: "running"}, jobs[0])
+		assert.Equal(t, JobInfo{ID: j.id, Unit: "b", Type: "start", State: "waiting", WaitingFor: []string{"a"}}, jobs[1])
+	}
+
+	info, err := sys.GetJob(j.id)
+	require.NoError(t, err, "sys.GetJob")
+	assert.Equal(t, "b", info.Unit)
+
+	require.NoError(t, sys.CancelJob(j.id), "sys.CancelJob")
+	j.Wait()
+	assert.Equal(t, ErrCanceled, j.err)
+	assert.Equal(t, ErrNoSuchJob, sys.CancelJob(j.id), "cancelling a finished job")
+
+	_, err = sys.GetJob(j.id)
+	assert.Equal(t, ErrNoSuchJob, err, "sys.GetJob of a finished job")
+
+	close(release)
+	waitForJobs(t, sys, "a")
+}
+
 func TestIsolate(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -179,6 +588,8 @@ func TestIsolate(t *testing.T) {
 
 	mocks["a"].MockStopper.EXPECT().Stop().Return(nil).Times(1)
 	mocks["b"].MockStopper.EXPECT().Stop().Return(nil).Times(1)
+	empty(mocks["a"], "after", "before")
+	empty(mocks["b"], "after", "before")
 
 	empty(mocks["c"], "wants", "before", "conflicts", "after", "requires")
 
@@ -191,13 +602,58 @@ func TestIsolate(t *testing.T) {
 		u.load = unit.Loaded
 	}
 
-	require.NoError(t, sys.Isolate("c"), "sys.Isolate")
+	// Neither of these gets stopped
+	ignored := struct {
+		*mock_unit.MockInterface
+		*mock_unit.MockIsolator
+	}{mock_unit.NewMockInterface(ctrl), mock_unit.NewMockIsolator(ctrl)}
+	ignored.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	ignored.MockIsolator.EXPECT().IgnoreOnIsolate().Return(true).AnyTimes()
 
-	names := make([]string, 0, len(mocks))
-	for name := range mocks {
-		names = append(names, name)
+	dead := newMock(ctrl)
+	dead.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+
+	for name, iface := range map[string]unit.Interface{"ignored": ignored, "dead": dead} {
+		u, err := sys.Supervise(name, iface)
+		require.NoError(t, err)
+
+		u.load = unit.Loaded
 	}
+
+	require.NoError(t, sys.Isolate("c"), "sys.Isolate")
 	waitForJobs(t, sys, "a", "b")
+
+	for _, name := range []string{"ignored", "dead"} {
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+		assert.Nil(t, u.lastJob(), name)
+	}
+}
+
+func TestReaped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	owner := struct {
+		*mock_unit.MockInterface
+		*mock_unit.MockExiter
+	}{mock_unit.NewMockInterface(ctrl), mock_unit.NewMockExiter(ctrl)}
+	owner.MockExiter.EXPECT().Owns(gomock.Any()).DoAndReturn(func(pid int) bool { return pid == 42 }).AnyTimes()
+	owner.MockExiter.EXPECT().Exited(42, syscall.WaitStatus(1<<8)).Times(1)
+	owner.MockInterface.EXPECT().Active().Return(unit.Failed).AnyTimes()
+
+	_, err := sys.Supervise("owner", owner)
+	require.NoError(t, err)
+
+	sys.Reaped(42, syscall.WaitStatus(1<<8))
+	sys.Reaped(43, syscall.WaitStatus(0))
+
+	failed := sys.ListFailed()
+	if assert.Len(t, failed, 1, "failure recorded") {
+		assert.Equal(t, "owner", failed[0].Unit)
+	}
 }
 
 func waitForJobs(t *testing.T, sys *Daemon, names ...string) {
@@ -210,15 +666,15 @@ func waitForJobs(t *testing.T, sys *Daemon, names ...string) {
 		go func(name string, u *Unit) {
 			defer wg.Done()
 
-			for u.job == nil {
+			for u.lastJob() == nil {
 				log.Warnf("%s job still nil", name)
 				time.Sleep(100 * time.Millisecond)
 			}
 
 			log.Warnf("Waiting for %s job to finish", name)
-			u.job.Wait()
+			u.lastJob().Wait()
 
-			assert.True(t, u.job.Success())
+			assert.True(t, u.lastJob().Success())
 		}(name, u)
 	}
 	wg.Wait()
@@ -230,11 +686,14 @@ func TestEnable(t *testing.T) {
 
 	sys := New()
 
-	m := mock_unit.NewMockInterface(ctrl)
-	m.EXPECT().WantedBy().Return([]string{"test.target"}).Times(2)
-	m.EXPECT().RequiredBy().Return([]string{"test.target"}).Times(2)
+	etc, err := ioutil.TempDir("", "enable-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(etc)
+	sys.SetPaths(etc)
 
-	var err error
+	m := mock_unit.NewMockInterface(ctrl)
+	m.EXPECT().WantedBy().Return([]string{"test.target"}).Times(4)
+	m.EXPECT().RequiredBy().Return([]string{"test.target"}).Times(4)
 
 	for name, iface := range map[string]unit.Interface{
 		"test.target":  nil,
@@ -259,26 +718,114 @@ func TestEnable(t *testing.T) {
 	require.NoError(t, sys.Enable("test.service"), "sys.Enable")
 
 	for _, suffix := range []string{"wants", "requires"} {
-		path, err := os.Readlink(filepath.Join(sys.units["test.target"].path+"."+suffix, "test.service"))
+		path, err := os.Readlink(filepath.Join(etc, "test.target."+suffix, "test.service"))
 		require.NoError(t, err, "os.Readlink")
 		assert.Equal(t, path, sys.units["test.service"].path, "link path")
 	}
 
-	// TODO implement
-	//st, err := sys.IsEnabled("test.service")
-	//assert.NoError(t, err, "sys.IsEnabled")
-	//assert.Equal(t, unit.Enabled, st, "sys.IsEnabled")
+	st, err := sys.IsEnabled("test.service")
+	assert.NoError(t, err, "sys.IsEnabled")
+	assert.Equal(t, unit.Enabled, st, "sys.IsEnabled")
 
 	require.NoError(t, sys.Disable("test.service"), "sys.Disable")
 	for _, suffix := range []string{"wants", "requires"} {
-		_, err := os.Open(filepath.Join(sys.units["test.target"].path+"."+suffix, "test.service"))
+		_, err := os.Open(filepath.Join(etc, "test.target."+suffix, "test.service"))
 		assert.True(t, os.IsNotExist(err), "os.Open")
 	}
 
-	// TODO implement
-	//st, err = sys.IsEnabled("test.service")
-	//assert.NoError(t, err, "sys.IsEnabled")
-	//assert.Equal(t, unit.Disabled, st, "sys.IsEnabled")
+	st, err = sys.IsEnabled("test.service")
+	assert.NoError(t, err, "sys.IsEnabled")
+	assert.Equal(t, unit.Disabled, st, "sys.IsEnabled")
+}
+
+func TestEnableInstall(t *testing.T) {
+	etc, err := ioutil.TempDir("", "install-etc")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(etc)
+
+	lib, err := ioutil.TempDir("", "install-lib")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(lib)
+
+	for name, contents := range map[string]string{
+		"foo.service": `[Service]
+ExecStart=/bin/true
+[Install]
+WantedBy=multi-user.target
+Alias=bar.service
+Also=baz.service`,
+		"baz.service": `[Service]
+ExecStart=/bin/true
+[Install]
+RequiredBy=multi-user.target`,
+		"static.service": `[Service]
+ExecStart=/bin/true`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(lib, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(etc, lib)
+
+	for name, expected := range map[string]unit.Enable{
+		"foo.service":    unit.Disabled,
+		"baz.service":    unit.Disabled,
+		"static.service": unit.Static,
+	} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, expected, st, name)
+	}
+
+	require.NoError(t, sys.Enable("foo.service"), "sys.Enable")
+
+	for link, target := range map[string]string{
+		filepath.Join(etc, "multi-user.target.wants", "foo.service"):    filepath.Join(lib, "foo.service"),
+		filepath.Join(etc, "bar.service"):                               filepath.Join(lib, "foo.service"),
+		filepath.Join(etc, "multi-user.target.requires", "baz.service"): filepath.Join(lib, "baz.service"),
+	} {
+		path, err := os.Readlink(link)
+		require.NoError(t, err, "os.Readlink")
+		assert.Equal(t, target, path, link)
+	}
+
+	for _, name := range []string{"foo.service", "baz.service"} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, unit.Enabled, st, name)
+	}
+
+	require.NoError(t, sys.Enable("foo.service"), "sys.Enable twice")
+
+	require.NoError(t, sys.Disable("foo.service"), "sys.Disable")
+	for _, name := range []string{"foo.service", "baz.service"} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, unit.Disabled, st, name)
+	}
+}
+
+func TestCreateLink(t *testing.T) {
+	path, err := ioutil.TempDir("", "create-link-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	link := filepath.Join(path, "multi-user.target.wants", "foo.service")
+	require.NoError(t, createLink(link, "/lib/foo.service"), "createLink")
+	require.NoError(t, createLink(link, "/lib/foo.service"), "createLink of existing link")
+
+	// A link left pointing to a definition, which is not the one found first anymore
+	require.NoError(t, createLink(link, "/etc/foo.service"), "createLink of stale link")
+	target, err := os.Readlink(link)
+	require.NoError(t, err, "os.Readlink")
+	assert.Equal(t, "/etc/foo.service", target, "link replaced")
+
+	file := filepath.Join(path, "bar.service")
+	require.NoError(t, ioutil.WriteFile(file, []byte("[Service]"), 0666), "ioutil.WriteFile")
+	assert.Equal(t, ErrNotSymlink, createLink(file, "/lib/bar.service"), "createLink of unit file")
+	contents, err := ioutil.ReadFile(file)
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, "[Service]", string(contents), "unit file left untouched")
 }
 
 func empty(m *mockUnit, methods ...string) {
@@ -307,3 +854,816 @@ func emptyOne(m *mockUnit, method string) (c *gomock.Call) {
 	}
 	return c.Return([]string{})
 }
+
+func TestStartCondition(t *testing.T) {
+	path, err := ioutil.TempDir("", "condition-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	err = ioutil.WriteFile(filepath.Join(path, "foo.service"), []byte(`[Unit]
+ConditionPathExists=`+filepath.Join(path, "missing")+`
+[Service]
+ExecStart=/bin/sleep 1000`), 0666)
+	require.NoError(t, err, "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("foo.service"), "sys.Start")
+	waitForJobs(t, sys, "foo.service")
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	assert.True(t, u.IsDead(), "u.IsDead()")
+	assert.Equal(t, "ConditionPathExists="+filepath.Join(path, "missing"), u.Status().Condition, "u.Status().Condition")
+}
+
+func TestStartAssert(t *testing.T) {
+	path, err := ioutil.TempDir("", "assert-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	err = ioutil.WriteFile(filepath.Join(path, "foo.service"), []byte(`[Unit]
+AssertPathExists=`+filepath.Join(path, "missing")+`
+[Service]
+ExecStart=/bin/sleep 1000`), 0666)
+	require.NoError(t, err, "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("foo.service"), "sys.Start")
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	for u.lastJob() == nil {
+		time.Sleep(100 * time.Millisecond)
+	}
+	u.lastJob().Wait()
+
+	assert.True(t, u.lastJob().Failed(), "u.lastJob().Failed()")
+	assert.Equal(t, ErrAssert, u.lastJob().err, "u.lastJob().err")
+	assert.True(t, u.IsDead(), "u.IsDead()")
+	assert.Equal(t, "AssertPathExists="+filepath.Join(path, "missing"), u.Status().Assert, "u.Status().Assert")
+}
+
+func TestTrigger(t *testing.T) {
+	path, err := ioutil.TempDir("", "trigger-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"fail.service": `[Unit]
+OnFailure=failure.service
+OnSuccess=success.service
+[Service]
+Type=oneshot
+ExecStart=/bin/false`,
+		"succeed.service": `[Unit]
+OnFailure=failure.service
+OnSuccess=success.service
+[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"failure.service": `[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"success.service": `[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	for name, triggered := range map[string]string{
+		"fail.service":    "failure.service",
+		"succeed.service": "success.service",
+	} {
+		sys := New()
+		sys.SetPaths(path)
+
+		require.NoError(t, sys.Start(name), "sys.Start")
+
+		u, err := sys.Get(triggered)
+		require.NoError(t, err, "sys.Get")
+
+		for u.lastJob() == nil {
+			time.Sleep(100 * time.Millisecond)
+		}
+		u.lastJob().Wait()
+		assert.True(t, u.lastJob().Success(), triggered+" job succeeded")
+	}
+}
+
+func TestBindings(t *testing.T) {
+	path, err := ioutil.TempDir("", "bind-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+		"bound.service": `[Unit]
+BindsTo=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"part.service": `[Unit]
+PartOf=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"requisite.service": `[Unit]
+Requisite=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	assert.Equal(t, ErrRequisite, sys.Start("requisite.service"), "sys.Start(requisite.service)")
+
+	require.NoError(t, sys.Start("bound.service", "part.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service", "bound.service", "part.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err, "sys.Unit")
+	assert.True(t, a.IsActive(), "bound.service started a.service")
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+
+	for _, name := range []string{"a.service", "bound.service", "part.service"} {
+		u, _ := sys.Unit(name)
+		for timeout := time.After(5 * time.Second); u.lastJob().typ != stop; time.Sleep(100 * time.Millisecond) {
+			select {
+			case <-timeout:
+				t.Fatalf("%s was not stopped", name)
+			default:
+			}
+		}
+		u.lastJob().Wait()
+		assert.True(t, u.lastJob().Success(), name+" stopped")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	path, err := ioutil.TempDir("", "conflicts-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Unit]
+Conflicts=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	a, err := sys.Get("a.service")
+	require.NoError(t, err)
+	b, err := sys.Get("b.service")
+	require.NoError(t, err)
+
+	waitForDead := func(u *Unit) {
+		for timeout := time.After(5 * time.Second); !u.IsDead(); time.Sleep(10 * time.Millisecond) {
+			select {
+			case <-timeout:
+				t.Fatalf("%s was not stopped", u.Name())
+			default:
+			}
+		}
+	}
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+	require.True(t, a.IsActive(), "a.service started")
+
+	require.NoError(t, sys.Start("b.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service", "b.service")
+	assert.Equal(t, stop, a.lastJob().typ, "a.service conflicting with b.service stopped")
+	assert.True(t, b.lastJob().after.Contains(a.lastJob()), "b.service started after a.service stopped")
+	assert.True(t, b.IsActive(), "b.service started")
+	waitForDead(a)
+
+	// Conflicts are symmetric
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service", "b.service")
+	assert.Equal(t, stop, b.lastJob().typ, "b.service conflicting with a.service stopped")
+	assert.True(t, a.IsActive(), "a.service started")
+	waitForDead(b)
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+	waitForJobs(t, sys, "a.service")
+}
+
+type reloadMock struct {
+	*mock_unit.MockInterface
+	*mock_unit.MockReloader
+	*mock_unit.MockReloadPropagator
+}
+
+func TestDependencyFailure(t *testing.T) {
+	path, err := ioutil.TempDir("", "dep-failure-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"fail.service": `[Service]
+Type=oneshot
+ExecStartPre=/bin/sleep 1
+ExecStart=/bin/false`,
+		"wants.service": `[Unit]
+Wants=fail.service
+After=fail.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"requires.service": `[Unit]
+Requires=fail.service
+After=fail.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"bound.service": `[Unit]
+BindsTo=fail.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("wants.service", "requires.service", "bound.service"), "sys.Start")
+
+	units := map[string]*Unit{}
+	for _, name := range []string{"fail.service", "wants.service", "requires.service", "bound.service"} {
+		units[name], err = sys.Unit(name)
+		require.NoError(t, err, "sys.Unit")
+	}
+	for _, u := range units {
+		u.lastJob().Wait()
+	}
+
+	assert.True(t, units["fail.service"].lastJob().Failed(), "fail.service failed")
+
+	assert.True(t, units["wants.service"].lastJob().Success(), "wants.service is not affected")
+	assert.Contains(t, units["wants.service"].Status().Dependencies,
+		unit.DependencyStatus{Name: "fail.service", Kind: "wanted", Result: "failed"})
+
+	assert.Equal(t, ErrDepFail, units["requires.service"].lastJob().err, "requires.service failed")
+
+	// bound.service starts in parallel with fail.service, but is stopped once it has failed
+	bound := units["bound.service"]
+	for timeout := time.After(5 * time.Second); bound.lastJob().typ != stop; time.Sleep(100 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("bound.service was not stopped")
+		default:
+		}
+	}
+	bound.lastJob().Wait()
+	assert.True(t, bound.lastJob().Success(), "bound.service stopped")
+}
+
+func TestResetFailed(t *testing.T) {
+	path, err := ioutil.TempDir("", "reset-failed-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"fail.service": `[Service]
+Type=oneshot
+ExecStart=/bin/false`,
+		"ok.service": `[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("fail.service", "ok.service"), "sys.Start")
+	waitForJobs(t, sys, "ok.service")
+
+	u, err := sys.Unit("fail.service")
+	require.NoError(t, err)
+	u.lastJob().Wait()
+
+	failed := sys.ListFailed()
+	require.Len(t, failed, 1, "sys.ListFailed")
+	assert.Equal(t, "fail.service", failed[0].Unit)
+	assert.Equal(t, "exit status 1", failed[0].Reason)
+	assert.Equal(t, 1, failed[0].ExitCode)
+	assert.False(t, failed[0].Since.IsZero(), "time recorded")
+
+	assert.Equal(t, unit.Failed, u.Active())
+
+	require.NoError(t, sys.ResetFailed("fail.service"), "sys.ResetFailed")
+	assert.Empty(t, sys.ListFailed(), "failure reset")
+	assert.True(t, u.IsDead(), "inactive once reset")
+}
+
+func TestJobTimeout(t *testing.T) {
+	path, err := ioutil.TempDir("", "job-timeout-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"slow.service": `[Service]
+ExecStartPre=/bin/sleep 2
+ExecStart=/bin/sleep 1000`,
+		"waiting.service": `[Unit]
+Requires=slow.service
+After=slow.service
+JobTimeoutSec=100ms
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"running.service": `[Unit]
+JobRunningTimeoutSec=100ms
+JobTimeoutAction=none
+[Service]
+ExecStartPre=/bin/sleep 10
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	started := time.Now()
+	require.NoError(t, sys.Start("waiting.service", "running.service"), "sys.Start")
+
+	for _, name := range []string{"waiting.service", "running.service"} {
+		u, err := sys.Unit(name)
+		require.NoError(t, err, "sys.Unit")
+
+		u.lastJob().Wait()
+		assert.Equal(t, ErrJobTimeout, u.lastJob().err, name)
+	}
+	assert.True(t, time.Since(started) < 2*time.Second, "jobs did not time out")
+
+	assert.Equal(t, ErrUnknownAction, sys.runAction("foo"))
+	assert.NoError(t, sys.runAction("none"))
+}
+
+func TestStartLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := struct {
+		*mockUnit
+		*mock_unit.MockStartLimiter
+	}{newMock(ctrl), mock_unit.NewMockStartLimiter(ctrl)}
+
+	for _, method := range []string{"wants", "requires", "conflicts", "after", "before"} {
+		emptyOne(m.mockUnit, method).AnyTimes()
+	}
+	m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+	m.MockInterface.EXPECT().Sub().Return("dead").AnyTimes()
+	m.MockStartLimiter.EXPECT().StartLimitIntervalSec().Return(time.Minute).AnyTimes()
+	m.MockStartLimiter.EXPECT().StartLimitBurst().Return(2).AnyTimes()
+
+	sys := New()
+	u, err := sys.Supervise("limited", m)
+	require.NoError(t, err)
+	u.load = unit.Loaded
+
+	start := func() error {
+		require.NoError(t, sys.Start("limited"))
+		u.lastJob().Wait()
+		return u.lastJob().err
+	}
+
+	m.MockStarter.EXPECT().Start().Return(nil).Times(3)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, start(), "start within the limit")
+	}
+
+	assert.Equal(t, ErrStartLimit, start(), "start over the limit")
+	assert.Equal(t, unit.Failed, u.Active())
+	assert.Equal(t, startLimitHit, u.Sub())
+
+	require.NoError(t, sys.ResetFailed("limited"), "sys.ResetFailed")
+	assert.Equal(t, unit.Inactive, u.Active())
+	assert.NoError(t, start(), "start after reset")
+}
+
+func TestRefuseManual(t *testing.T) {
+	path, err := ioutil.TempDir("", "refuse-manual-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"refused.service": `[Unit]
+RefuseManualStart=yes
+RefuseManualStop=yes
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"puller.service": `[Unit]
+Requires=refused.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	assert.Equal(t, ErrRefuseManualStart, sys.ManualStart(ReplaceMode, "refused.service"))
+	assert.Equal(t, ErrRefuseManualStart, sys.ManualRestart(ReplaceMode, "refused.service"))
+
+	// Pulling the unit in as a dependency is allowed
+	require.NoError(t, sys.ManualStart(ReplaceMode, "puller.service"))
+	waitForJobs(t, sys, "puller.service", "refused.service")
+
+	assert.Equal(t, ErrRefuseManualStop, sys.ManualStop(ReplaceMode, "refused.service"))
+	require.NoError(t, sys.Stop("refused.service", "puller.service"))
+	waitForJobs(t, sys, "puller.service", "refused.service")
+}
+
+func TestStopWhenUnneeded(t *testing.T) {
+	path, err := ioutil.TempDir("", "stop-when-unneeded-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"unneeded.service": `[Unit]
+StopWhenUnneeded=yes
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"a.service": `[Unit]
+Requires=unneeded.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Unit]
+Wants=unneeded.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	waitForDead := func(u *Unit, msg string) {
+		for timeout := time.After(5 * time.Second); !u.IsDead(); time.Sleep(10 * time.Millisecond) {
+			select {
+			case <-timeout:
+				t.Fatal(msg)
+			default:
+			}
+		}
+	}
+
+	require.NoError(t, sys.Start("a.service", "b.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service", "b.service", "unneeded.service")
+
+	unneeded, err := sys.Unit("unneeded.service")
+	require.NoError(t, err)
+	assert.True(t, unneeded.IsActive(), "unneeded.service pulled in")
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+	waitForJobs(t, sys, "a.service")
+	assert.True(t, unneeded.IsActive(), "still wanted by b.service")
+
+	require.NoError(t, sys.Stop("b.service"), "sys.Stop")
+	waitForJobs(t, sys, "b.service")
+	waitForDead(unneeded, "unneeded.service was not stopped, once not needed")
+
+	require.NoError(t, sys.Start("unneeded.service"), "sys.Start")
+	waitForDead(unneeded, "unneeded.service started alone was not stopped")
+}
+
+func TestGC(t *testing.T) {
+	path, err := ioutil.TempDir("", "gc-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+Wants=b.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"c.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	_, err = sys.Get("c.service")
+	require.NoError(t, err, "sys.Get")
+
+	require.NoError(t, sys.Start("a.service"))
+	waitForJobs(t, sys, "a.service", "b.service")
+
+	// b.service is inactive, but still referenced by a.service
+	assert.Equal(t, []string{"c.service"}, sys.GC())
+	_, err = sys.Unit("c")
+	assert.Equal(t, ErrNotFound, err, "alias of c.service unloaded")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Stop("a.service"))
+	waitForJobs(t, sys, "a.service")
+	for timeout := time.After(5 * time.Second); !a.IsDead(); time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("a.service did not stop")
+		default:
+		}
+	}
+
+	// shutdown.target got loaded, as a.service conflicts with it
+	unloaded := sys.GC()
+	assert.Len(t, unloaded, 3)
+	assert.Contains(t, unloaded, "a.service")
+	assert.Contains(t, unloaded, "b.service")
+	assert.Contains(t, unloaded, "shutdown.target")
+	assert.Empty(t, sys.Units())
+}
+
+func TestGCFailed(t *testing.T) {
+	path, err := ioutil.TempDir("", "gc-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"kept.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/false`,
+		"collected.service": `[Unit]
+DefaultDependencies=no
+CollectMode=inactive-or-failed
+[Service]
+Type=oneshot
+ExecStart=/bin/false`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	for _, name := range []string{"kept.service", "collected.service"} {
+		require.NoError(t, sys.Start(name), "sys.Start")
+
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+		for u.lastJob() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		u.lastJob().Wait()
+		require.Equal(t, unit.Failed, u.Active(), "%s did not fail", name)
+	}
+
+	assert.Equal(t, []string{"collected.service"}, sys.GC())
+	if failed := sys.ListFailed(); assert.Len(t, failed, 1) {
+		assert.Equal(t, "kept.service", failed[0].Unit, "failure of the unit collected kept")
+	}
+}
+
+func TestDaemonReload(t *testing.T) {
+	path, err := ioutil.TempDir("", "daemon-reload-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+Description=old
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"gone.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"))
+	waitForJobs(t, sys, "a.service")
+
+	_, err = sys.Get("gone.service")
+	require.NoError(t, err, "sys.Get")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+	pid := a.Interface.(*service.Unit).Process.Pid
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+Description=new
+Wants=b.service
+[Service]
+ExecStart=/bin/sleep 1000`), 0666), "ioutil.WriteFile")
+	require.NoError(t, os.Remove(filepath.Join(path, "gone.service")), "os.Remove")
+
+	sys.DaemonReload()
+
+	assert.Equal(t, "new", a.Description(), "definition re-read")
+	assert.Equal(t, []string{"b.service"}, a.Wants(), "dependencies recomputed")
+	assert.True(t, a.IsActive(), "a.service keeps running")
+	assert.Equal(t, pid, a.Interface.(*service.Unit).Process.Pid, "process preserved")
+
+	gone, err := sys.Unit("gone.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.NotFound, gone.Loaded())
+}
+
+func TestDeserialize(t *testing.T) {
+	path, err := ioutil.TempDir("", "deserialize-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for _, name := range []string{"a.service", "b.service"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(`[Service]
+ExecStart=/bin/sleep 1000`), 0666), "ioutil.WriteFile")
+	}
+
+	old := New()
+	old.SetPaths(path)
+
+	require.NoError(t, old.Start("a.service"))
+	waitForJobs(t, old, "a.service")
+
+	a, err := old.Unit("a.service")
+	require.NoError(t, err)
+	pid := a.Interface.(*service.Unit).Process.Pid
+
+	snap := old.Snapshot()
+	assert.Equal(t, []UnitSnapshot{{Name: "a.service", MainPID: pid}}, snap.Units)
+	assert.Empty(t, snap.Jobs)
+	assert.NotZero(t, snap.LastJobID)
+
+	// Pretend a job was still queued
+	snap.Jobs = append(snap.Jobs, JobInfo{ID: snap.LastJobID, Unit: "b.service", Type: "start", State: "waiting"})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, json.NewEncoder(buf).Encode(snap), "json.Encode")
+
+	sys := New()
+	sys.SetPaths(path)
+	require.NoError(t, sys.Deserialize(buf), "sys.Deserialize")
+
+	a, err = sys.Unit("a.service")
+	require.NoError(t, err, "a.service loaded")
+	assert.True(t, a.IsActive(), "a.service attached")
+	assert.Equal(t, pid, a.Interface.(*service.Unit).MainPID(), "process preserved")
+
+	b, err := sys.Unit("b.service")
+	require.NoError(t, err, "b.service loaded")
+	require.NotNil(t, b.lastJob(), "b.service job enqueued")
+	assert.Equal(t, snap.LastJobID+1, b.lastJob().id, "job IDs continued")
+	waitForJobs(t, sys, "b.service")
+	assert.True(t, b.IsActive(), "b.service started")
+
+	require.NoError(t, sys.Stop("a.service", "b.service"))
+	waitForJobs(t, sys, "a.service", "b.service")
+}
+
+func TestReloadPropagation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocks := map[string]*reloadMock{}
+	for _, name := range []string{"certs", "nginx", "apache", "inactive"} {
+		m := &reloadMock{
+			mock_unit.NewMockInterface(ctrl),
+			mock_unit.NewMockReloader(ctrl),
+			mock_unit.NewMockReloadPropagator(ctrl),
+		}
+		for _, method := range []string{"wants", "requires", "conflicts", "after", "before"} {
+			emptyOne(&mockUnit{MockInterface: m.MockInterface}, method).AnyTimes()
+		}
+		mocks[name] = m
+	}
+
+	mocks["certs"].MockReloadPropagator.EXPECT().PropagatesReloadTo().Return([]string{"nginx", "inactive"}).AnyTimes()
+	for _, name := range []string{"nginx", "apache", "inactive"} {
+		mocks[name].MockReloadPropagator.EXPECT().PropagatesReloadTo().Return([]string{}).AnyTimes()
+	}
+	mocks["certs"].MockReloadPropagator.EXPECT().ReloadPropagatedFrom().Return([]string{}).AnyTimes()
+	mocks["nginx"].MockReloadPropagator.EXPECT().ReloadPropagatedFrom().Return([]string{}).AnyTimes()
+	mocks["apache"].MockReloadPropagator.EXPECT().ReloadPropagatedFrom().Return([]string{"certs"}).AnyTimes()
+	mocks["inactive"].MockReloadPropagator.EXPECT().ReloadPropagatedFrom().Return([]string{"certs"}).AnyTimes()
+
+	sys := New()
+	for name, m := range mocks {
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+
+		if name == "inactive" {
+			m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+			continue
+		}
+		m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+		m.MockReloader.EXPECT().Reload().Return(nil).Times(1)
+	}
+
+	require.NoError(t, sys.Reload("certs"), "sys.Reload")
+	waitForJobs(t, sys, "certs", "nginx", "apache")
+}
+
+func TestDefaultDependencies(t *testing.T) {
+	path, err := ioutil.TempDir("", "default-deps-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"basic.target": `[Unit]
+Description=basic`,
+		"shutdown.target": `[Unit]
+Requires=halt.service`,
+		"foo.service": `[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"bar.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"foo.target": `[Unit]
+Description=foo`,
+		"bar.target": `[Unit]
+DefaultDependencies=no`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	foo, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+	assert.Contains(t, foo.After(), "basic.target", "foo.After()")
+	assert.Contains(t, foo.Before(), "shutdown.target", "foo.Before()")
+	assert.Contains(t, foo.Conflicts(), "shutdown.target", "foo.Conflicts()")
+
+	bar, err := sys.Get("bar.service")
+	require.NoError(t, err, "sys.Get")
+	assert.Empty(t, bar.After(), "bar.After()")
+	assert.Empty(t, bar.Before(), "bar.Before()")
+	assert.Empty(t, bar.Conflicts(), "bar.Conflicts()")
+
+	target, err := sys.Get("foo.target")
+	require.NoError(t, err, "sys.Get")
+	assert.Contains(t, target.Before(), "shutdown.target", "foo.target Before()")
+	assert.Contains(t, target.Conflicts(), "shutdown.target", "foo.target Conflicts()")
+
+	target, err = sys.Get("bar.target")
+	require.NoError(t, err, "sys.Get")
+	assert.Empty(t, target.Before(), "bar.target Before()")
+	assert.Empty(t, target.Conflicts(), "bar.target Conflicts()")
+
+	shutdown, err := sys.Get("shutdown.target")
+	require.NoError(t, err, "sys.Get")
+	assert.Empty(t, shutdown.Conflicts(), "shutdown.target conflicting with itself")
+
+	require.NoError(t, sys.Start("foo.service"), "sys.Start")
+	waitForJobs(t, sys, "foo.service")
+}
+
+func TestGetWarnings(t *testing.T) {
+	path, err := ioutil.TempDir("", "warning-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	fpath := filepath.Join(path, "foo.service")
+	err = ioutil.WriteFile(fpath, []byte(`[Service]
+ExecStart=/bin/true
+ExecStrat=/bin/false`), 0666)
+	require.NoError(t, err, "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err, "sys.Get")
+
+	assert.True(t, u.IsLoaded(), "u.IsLoaded()")
+	assert.Equal(t, []string{fpath + ":3: ExecStrat: " + unit.ErrUnknownDirective.Error()}, u.Status().Warnings, "u.Status().Warnings")
+}
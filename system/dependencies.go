@@ -0,0 +1,61 @@
+package system
+
+import (
+	"sort"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Dependency is a node of the dependency tree of a unit
+type Dependency struct {
+	Unit   string
+	Active unit.Activation
+
+	// Dependencies of the unit, empty if the unit is expanded elsewhere in the tree
+	Dependencies []Dependency
+}
+
+// ListDependencies returns the tree of the units required or wanted by the unit name, recursively.
+// If reverse is true, the tree of the units loaded, which require or want it, is returned instead.
+// Each unit is only expanded once
+func (sys *Daemon) ListDependencies(name string, reverse bool) (tree Dependency, err error) {
+	u, err := sys.Get(name)
+	if u == nil {
+		return tree, err
+	}
+
+	expanded := map[*Unit]bool{}
+
+	var walk func(u *Unit) Dependency
+	walk = func(u *Unit) (node Dependency) {
+		node = Dependency{Unit: u.Name(), Active: u.Active()}
+		if expanded[u] {
+			return
+		}
+		expanded[u] = true
+
+		var deps []*Unit
+		if reverse {
+			deps = u.dependents(func(other *Unit) []string {
+				return append(other.Requires(), other.Wants()...)
+			})
+		} else {
+			for _, name := range append(u.Requires(), u.Wants()...) {
+				if dep, _ := sys.Get(name); dep != nil {
+					deps = append(deps, dep)
+				} else {
+					node.Dependencies = append(node.Dependencies, Dependency{Unit: name, Active: unit.Inactive})
+				}
+			}
+		}
+
+		for _, dep := range deps {
+			node.Dependencies = append(node.Dependencies, walk(dep))
+		}
+		sort.Slice(node.Dependencies, func(i, k int) bool {
+			return node.Dependencies[i].Unit < node.Dependencies[k].Unit
+		})
+		return
+	}
+	return walk(u), nil
+}
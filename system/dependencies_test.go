@@ -0,0 +1,54 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDependencies(t *testing.T) {
+	path, err := ioutil.TempDir("", "list-dependencies-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.target": `[Unit]
+Requires=b.target
+Wants=c.target missing.target`,
+		"b.target": `[Unit]`,
+		"c.target": `[Unit]
+Requires=b.target`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	tree, err := sys.ListDependencies("a.target", false)
+	require.NoError(t, err, "sys.ListDependencies")
+	assert.Equal(t, Dependency{Unit: "a.target", Active: unit.Inactive, Dependencies: []Dependency{
+		{Unit: "b.target", Active: unit.Inactive},
+		{Unit: "c.target", Active: unit.Inactive, Dependencies: []Dependency{
+			{Unit: "b.target", Active: unit.Inactive},
+		}},
+		{Unit: "missing.target", Active: unit.Inactive},
+	}}, tree)
+
+	tree, err = sys.ListDependencies("b.target", true)
+	require.NoError(t, err, "sys.ListDependencies")
+	assert.Equal(t, Dependency{Unit: "b.target", Active: unit.Inactive, Dependencies: []Dependency{
+		{Unit: "a.target", Active: unit.Inactive},
+		{Unit: "c.target", Active: unit.Inactive, Dependencies: []Dependency{
+			{Unit: "a.target", Active: unit.Inactive},
+		}},
+	}}, tree)
+
+	_, err = sys.ListDependencies("missing.target", false)
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Colors of the edges of the dependency graph, as used by systemd-analyze dot
+const (
+	DOT_REQUIRES = "black"
+	DOT_WANTS    = "grey66"
+	DOT_AFTER    = "green"
+)
+
+// Dot writes the Requires=, Wants= and After= dependencies of the units loaded to w as a Graphviz dot graph.
+// If names are specified, only the units pulled in or ordered after by them, recursively, are included
+func (sys *Daemon) Dot(w io.Writer, names ...string) (err error) {
+	units := map[string]*Unit{}
+	for _, u := range sys.Units() {
+		if u.IsLoaded() {
+			units[u.Name()] = u
+		}
+	}
+
+	if len(names) > 0 {
+		queue := make([]*Unit, 0, len(names))
+		for _, name := range names {
+			u, err := sys.Unit(name)
+			if err != nil {
+				return err
+			}
+			queue = append(queue, u)
+		}
+
+		closure := map[string]*Unit{}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+
+			if _, ok := closure[u.Name()]; ok {
+				continue
+			}
+			closure[u.Name()] = u
+
+			for _, dep := range append(append(u.Requires(), u.Wants()...), u.After()...) {
+				if dep, err := sys.Unit(dep); err == nil && dep.IsLoaded() {
+					queue = append(queue, dep)
+				}
+			}
+		}
+		units = closure
+	}
+
+	sorted := make([]string, 0, len(units))
+	for name := range units {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	if _, err = fmt.Fprintln(w, "digraph systemgo {"); err != nil {
+		return
+	}
+	for _, name := range sorted {
+		u := units[name]
+		for _, edge := range []struct {
+			deps  []string
+			color string
+		}{
+			{u.Requires(), DOT_REQUIRES},
+			{u.Wants(), DOT_WANTS},
+			{u.After(), DOT_AFTER},
+		} {
+			for _, dep := range edge.deps {
+				if d, err := sys.Unit(dep); err == nil {
+					dep = d.Name()
+				}
+				if _, ok := units[dep]; !ok && edge.color == DOT_AFTER {
+					// Ordering only matters between the units shown
+					continue
+				}
+				if _, err = fmt.Fprintf(w, "\t%q->%q [color=%q];\n", name, dep, edge.color); err != nil {
+					return
+				}
+			}
+		}
+	}
+	_, err = fmt.Fprintln(w, "}")
+	return
+}
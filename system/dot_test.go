@@ -0,0 +1,61 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDot(t *testing.T) {
+	path, err := ioutil.TempDir("", "dot-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+DefaultDependencies=no
+Requires=b.service
+Wants=c.service
+After=b.service`,
+		"b.service": `[Unit]
+DefaultDependencies=no`,
+		"c.service": `[Unit]
+DefaultDependencies=no`,
+		"d.service": `[Unit]
+DefaultDependencies=no
+Requires=c.service`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents+`
+[Service]
+ExecStart=/bin/true`), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+	for _, name := range []string{"a.service", "b.service", "c.service", "d.service"} {
+		_, err := sys.Get(name)
+		require.NoError(t, err, name)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, sys.Dot(&buf), "sys.Dot")
+	assert.Equal(t, `digraph systemgo {
+	"a.service"->"b.service" [color="black"];
+	"a.service"->"c.service" [color="grey66"];
+	"a.service"->"b.service" [color="green"];
+	"d.service"->"c.service" [color="black"];
+}
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, sys.Dot(&buf, "a.service"), "sys.Dot")
+	assert.NotContains(t, buf.String(), "d.service", "d.service not in the closure of a.service")
+	assert.Contains(t, buf.String(), `"a.service"->"c.service"`)
+
+	assert.Equal(t, ErrNotFound, sys.Dot(&buf, "e.service"), "unit not loaded")
+}
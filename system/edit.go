@@ -0,0 +1,137 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Name of the drop-in file written by Edit
+const OVERRIDE_DROP_IN = "override.conf"
+
+// DefinitionFile is a file the definition of a unit is read from
+type DefinitionFile struct {
+	// Path to the file, empty for built-in definitions
+	Path    string
+	Content string
+}
+
+// Cat returns the file defining the unit name followed by its drop-ins in the order they are applied
+func (sys *Daemon) Cat(name string) (files []DefinitionFile, err error) {
+	u, err := sys.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.isBuiltin() {
+		files = append(files, DefinitionFile{Content: builtin[u.Name()]})
+	} else {
+		b, err := ioutil.ReadFile(u.Path())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, DefinitionFile{Path: u.Path(), Content: string(b)})
+	}
+
+	for _, path := range u.DropIns() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, DefinitionFile{Path: path, Content: string(b)})
+	}
+	return
+}
+
+// Override returns the drop-in of the unit name written by Edit, its content is empty if it does not exist yet.
+// The drop-in of an alias or of a name not normalized is the one of the unit it refers to
+func (sys *Daemon) Override(name string) (f DefinitionFile, err error) {
+	dir, err := sys.firstPath()
+	if err != nil {
+		return
+	}
+
+	if u, err := sys.Get(name); err == nil {
+		name = u.Name()
+	}
+
+	f.Path = filepath.Join(dir, filepath.Base(name)+".d", OVERRIDE_DROP_IN)
+
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return f, err
+	}
+	f.Content = string(b)
+	return f, nil
+}
+
+// Edit checks, whether the definition of the unit name with content as the drop-in returned by Override is valid.
+// If it is, content is written to the drop-in and the definitions of the units are reloaded
+func (sys *Daemon) Edit(name, content string) (err error) {
+	log.WithField("name", name).Debugf("sys.Edit")
+
+	u, err := sys.Get(name)
+	if err != nil {
+		return
+	}
+
+	override, err := sys.Override(u.Name())
+	if err != nil {
+		return
+	}
+
+	var opts unit.Options
+	if u.isBuiltin() {
+		opts, err = unit.Deserialize(strings.NewReader(builtin[u.Name()]))
+	} else {
+		var file *os.File
+		if file, err = os.Open(u.Path()); err != nil {
+			return
+		}
+		opts, err = unit.Deserialize(file)
+		file.Close()
+	}
+	if err != nil {
+		return
+	}
+
+	var dropIns []string
+	for _, path := range u.DropIns() {
+		if path != override.Path {
+			dropIns = append(dropIns, path)
+		}
+	}
+
+	var found unit.Options
+	if found, err = unit.ReadOptions(dropIns...); err != nil {
+		return
+	}
+	opts = append(opts, found...)
+
+	if found, err = unit.Deserialize(strings.NewReader(content)); err != nil {
+		return
+	}
+	opts = append(opts, found...)
+
+	if err = unit.NewSpecifiers(u.Name(), u.Path()).ExpandOptions(opts); err != nil {
+		return
+	}
+	if err = sys.newInterface(u.Name()).Define(opts.Reader()); err != nil && !unit.IsWarning(err) {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(override.Path), 0755); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(override.Path, []byte(content), 0644); err != nil {
+		return
+	}
+
+	u.Log.Printf("Definition overridden by %s", override.Path)
+	sys.DaemonReload()
+	return nil
+}
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdit(t *testing.T) {
+	path, err := ioutil.TempDir("", "edit-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	fragment := "[Unit]\nDescription=A\n[Service]\nExecStart=/bin/true\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(fragment), 0666))
+	require.NoError(t, os.Mkdir(filepath.Join(path, "a.service.d"), 0755))
+	dropIn := filepath.Join(path, "a.service.d", "10-env.conf")
+	require.NoError(t, ioutil.WriteFile(dropIn, []byte("[Service]\nEnvironment=FOO=bar\n"), 0666))
+
+	sys := New()
+	sys.SetPaths(path)
+
+	files, err := sys.Cat("a.service")
+	require.NoError(t, err, "sys.Cat")
+	assert.Equal(t, []DefinitionFile{
+		{Path: filepath.Join(path, "a.service"), Content: fragment},
+		{Path: dropIn, Content: "[Service]\nEnvironment=FOO=bar\n"},
+	}, files)
+
+	files, err = sys.Cat("basic.target")
+	if assert.NoError(t, err, "built-in") && assert.Len(t, files, 1) {
+		assert.Empty(t, files[0].Path)
+		assert.Equal(t, builtin["basic.target"], files[0].Content)
+	}
+
+	override, err := sys.Override("a.service")
+	require.NoError(t, err, "sys.Override")
+	assert.Equal(t, filepath.Join(path, "a.service.d", OVERRIDE_DROP_IN), override.Path)
+	assert.Empty(t, override.Content, "not written yet")
+
+	assert.Error(t, sys.Edit("a.service", "[Service]\nType=forking\n"), "unsupported type")
+	_, err = os.Stat(override.Path)
+	assert.True(t, os.IsNotExist(err), "invalid override not written")
+
+	require.NoError(t, sys.Edit("a.service", "[Unit]\nDescription=Overridden\n"), "sys.Edit")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Overridden", a.Description(), "definitions reloaded")
+
+	files, err = sys.Cat("a.service")
+	if assert.NoError(t, err) && assert.Len(t, files, 3) {
+		assert.Equal(t, DefinitionFile{Path: override.Path, Content: "[Unit]\nDescription=Overridden\n"}, files[2])
+	}
+
+	require.NoError(t, sys.Edit("a", "[Unit]\nDescription=Again\n"), "override replaced")
+	assert.Equal(t, "Again", a.Description())
+
+	override, err = sys.Override("a")
+	if assert.NoError(t, err, "name not normalized") {
+		assert.Equal(t, filepath.Join(path, "a.service.d", OVERRIDE_DROP_IN), override.Path)
+		assert.Equal(t, "[Unit]\nDescription=Again\n", override.Content)
+	}
+	_, err = os.Stat(filepath.Join(path, "a.d"))
+	assert.True(t, os.IsNotExist(err), "no drop-in directory of the name edited")
+}
@@ -0,0 +1,154 @@
+package system
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Paths searched for environment.d(5) fragments by default(first path gets searched first)
+var DEFAULT_ENVIRONMENT_PATHS = []string{"/etc/environment.d", "/run/environment.d", "/usr/lib/environment.d"}
+
+// Suffix of the names of the environment.d(5) fragments
+const ENVIRONMENT_SUFFIX = ".conf"
+
+// SetEnvironment sets the variables assigned in vars(e.g. "FOO=bar") in the environment of the manager,
+// which all the processes started afterwards inherit. Nothing is set, if any of the assignments is invalid
+func (sys *Daemon) SetEnvironment(vars ...string) (err error) {
+	for _, v := range vars {
+		if !validAssignment(v) {
+			return ErrBadAssignment
+		}
+	}
+
+	for _, v := range vars {
+		parts := strings.SplitN(v, "=", 2)
+		log.Debugf("Setting %s in the manager environment", parts[0])
+		if err = os.Setenv(parts[0], parts[1]); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// UnsetEnvironment removes the variables named in vars from the environment of the manager.
+// A variable specified along with a value(e.g. "FOO=bar") is removed only, if it is set to the value
+func (sys *Daemon) UnsetEnvironment(vars ...string) (err error) {
+	for _, v := range vars {
+		if name := strings.SplitN(v, "=", 2)[0]; !validName(name) {
+			return ErrBadAssignment
+		}
+	}
+
+	for _, v := range vars {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && os.Getenv(parts[0]) != parts[1] {
+			continue
+		}
+		log.Debugf("Unsetting %s in the manager environment", parts[0])
+		if err = os.Unsetenv(parts[0]); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// Environment returns the environment of the manager, which the processes started inherit, sorted by name
+func (sys *Daemon) Environment() (vars []string) {
+	vars = os.Environ()
+	sort.Strings(vars)
+	return
+}
+
+// LoadEnvironment sets the variables assigned in the environment.d(5) fragments found in paths in the environment
+// of the manager. The fragments are applied in the order of their names, a fragment found in a path searched first
+// shadows the ones with the same name in paths searched later. "$VAR" and "${VAR}" get expanded to the values
+// assigned before, the invalid assignments are logged and skipped
+func (sys *Daemon) LoadEnvironment(paths ...string) {
+	byName := map[string]string{}
+	for _, path := range paths {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			if _, ok := byName[info.Name()]; !ok && strings.HasSuffix(info.Name(), ENVIRONMENT_SUFFIX) {
+				byName[info.Name()] = filepath.Join(path, info.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := byName[name]
+		if isMasked(path) {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			log.Errorf("Error opening %s: %s", path, err)
+			continue
+		}
+
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+				line = parts[0] + "=" + os.ExpandEnv(unquote(parts[1]))
+			}
+			if err := sys.SetEnvironment(line); err != nil {
+				log.Warnf("%s:%d: %s: %s", path, n, line, err)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("Error reading %s: %s", path, err)
+		}
+		f.Close()
+	}
+}
+
+// unquote removes the quotes the value s is enclosed in, if any
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// validAssignment reports whether s is a valid assignment of an environment variable
+func validAssignment(s string) bool {
+	parts := strings.SplitN(s, "=", 2)
+	return len(parts) == 2 && validName(parts[0])
+}
+
+// validName reports whether s is a valid name of an environment variable
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
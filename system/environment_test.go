@@ -0,0 +1,46 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironment(t *testing.T) {
+	sys := New()
+	defer os.Unsetenv("SYSTEMGO_TEST_FOO")
+	defer os.Unsetenv("SYSTEMGO_TEST_BAR")
+
+	assert.Equal(t, ErrBadAssignment, sys.SetEnvironment("SYSTEMGO_TEST_FOO=foo", "1BAD=bar"))
+	assert.Empty(t, os.Getenv("SYSTEMGO_TEST_FOO"), "variable set despite invalid assignment")
+
+	require.NoError(t, sys.SetEnvironment("SYSTEMGO_TEST_FOO=foo", "SYSTEMGO_TEST_BAR=a=b"))
+	assert.Equal(t, "foo", os.Getenv("SYSTEMGO_TEST_FOO"))
+	assert.Equal(t, "a=b", os.Getenv("SYSTEMGO_TEST_BAR"))
+	assert.Contains(t, sys.Environment(), "SYSTEMGO_TEST_FOO=foo")
+
+	require.NoError(t, sys.UnsetEnvironment("SYSTEMGO_TEST_FOO=other", "SYSTEMGO_TEST_BAR"))
+	assert.Equal(t, "foo", os.Getenv("SYSTEMGO_TEST_FOO"), "variable unset despite value not matching")
+	_, ok := os.LookupEnv("SYSTEMGO_TEST_BAR")
+	assert.False(t, ok, "variable not unset")
+
+	path, err := ioutil.TempDir("", "environment-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	etc, lib := filepath.Join(path, "etc"), filepath.Join(path, "lib")
+	for _, dir := range []string{etc, lib} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "10-foo.conf"), []byte("SYSTEMGO_TEST_FOO=shadowed\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(etc, "10-foo.conf"), []byte("# comment\nSYSTEMGO_TEST_FOO=\"lib\"\ninvalid\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "20-bar.conf"), []byte("SYSTEMGO_TEST_BAR=${SYSTEMGO_TEST_FOO}/bar\n"), 0644))
+
+	sys.LoadEnvironment(etc, lib)
+	assert.Equal(t, "lib", os.Getenv("SYSTEMGO_TEST_FOO"))
+	assert.Equal(t, "lib/bar", os.Getenv("SYSTEMGO_TEST_BAR"))
+}
@@ -14,3 +14,32 @@ var ErrNotActive = errors.New("Unit is not active")
 var ErrExists = errors.New("Unit already exists")
 var ErrNotImplemented = errors.New("Not implemented yet")
 var ErrUnmergeable = errors.New("Unmergeable job types")
+var ErrMasked = errors.New("Unit is masked")
+var ErrNoInstance = errors.New("Template unit can not be loaded without an instance name")
+var ErrAssert = errors.New("Assertion failed")
+var ErrUnknownJobMode = errors.New("Unknown job mode")
+var ErrJobPending = errors.New("A job is already pending for the unit")
+var ErrIsolate = errors.New("Isolate mode is only valid for start jobs")
+var ErrRequisite = errors.New("Requisite unit is not active")
+var ErrCanceled = errors.New("Job canceled")
+var ErrNoSuchJob = errors.New("No such job")
+var ErrJobTimeout = errors.New("Job timed out")
+var ErrUnknownAction = errors.New("Unknown action")
+var ErrStartLimit = errors.New("Start request repeated too quickly")
+var ErrRefuseManualStart = errors.New("Operation refused, unit may be requested by dependency only")
+var ErrRefuseManualStop = errors.New("Operation refused, unit may be stopped by dependency only")
+var ErrBooting = errors.New("Bootup is not yet finished")
+var ErrMalformedStat = errors.New("Malformed process stat")
+var ErrUnknownWho = errors.New(`Processes to kill should be one of "main", "control" or "all"`)
+var ErrNoProcess = errors.New("No process to kill")
+var ErrBadAssignment = errors.New("Invalid environment variable assignment")
+var ErrUnknownInhibit = errors.New(`Operations to inhibit should be "shutdown", "sleep" or "idle"`)
+var ErrInhibitMode = errors.New(`Inhibitor mode should be one of "block" or "delay"`)
+var ErrNoSuchInhibitor = errors.New("No such inhibitor")
+var ErrInhibited = errors.New("Operation inhibited by a lock")
+var ErrUnknownNetworkCheck = errors.New(`Network check should be one of "interface", "address", "route" or "command"`)
+var ErrNotOnline = errors.New("Network is not online")
+var ErrNoAudit = errors.New("Security of the unit type is not analyzed")
+var ErrStartTimeout = errors.New("Start operation timed out")
+var ErrNoBusName = errors.New("Main process exited before acquiring the bus name")
+var ErrNotSymlink = errors.New("Is not a symlink")
@@ -0,0 +1,119 @@
+package system
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Number of events buffered for each subscriber, the events published to a subscriber,
+// which does not keep up, are dropped
+const EVENT_BUFFER = 64
+
+// Event is a change of state published by the system
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Name of the unit, empty for BootFinished
+	Unit string
+
+	// Activation state of the unit, once the event occurred
+	Active unit.Activation
+
+	// Job finished and its error, JobFinished only
+	Job *JobInfo
+	Err error
+}
+
+// bus delivers the events published to the subscribers
+type bus struct {
+	subscribers map[chan Event]struct{}
+
+	// Activation states published last (unit -> state)
+	active map[*Unit]unit.Activation
+
+	mutex sync.Mutex
+}
+
+// Subscribe returns a channel, which receives the events published by sys from now on,
+// and a function, which cancels the subscription and closes the channel
+func (sys *Daemon) Subscribe() (events <-chan Event, cancel func()) {
+	sys.events.mutex.Lock()
+	defer sys.events.mutex.Unlock()
+
+	if sys.events.subscribers == nil {
+		sys.events.subscribers = map[chan Event]struct{}{}
+		sys.events.active = map[*Unit]unit.Activation{}
+	}
+
+	ch := make(chan Event, EVENT_BUFFER)
+	sys.events.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			sys.events.mutex.Lock()
+			defer sys.events.mutex.Unlock()
+
+			delete(sys.events.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// subscribed reports whether there are any subscribers to the events of sys
+func (sys *Daemon) subscribed() bool {
+	sys.events.mutex.Lock()
+	defer sys.events.mutex.Unlock()
+	return len(sys.events.subscribers) > 0
+}
+
+// publish sends ev to the subscribers of sys
+func (sys *Daemon) publish(ev Event) {
+	sys.events.mutex.Lock()
+	defer sys.events.mutex.Unlock()
+
+	sys.events.publish(ev)
+}
+
+// publish sends ev to the subscribers, must be called with b.mutex held
+func (b *bus) publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			log.WithField("event", ev.Type).Debugf("Subscriber not keeping up, event dropped")
+		}
+	}
+}
+
+// publishActive publishes UnitActiveChanged, if the activation state of u has changed since published last
+func (u *Unit) publishActive() {
+	sys := u.System
+	if sys == nil || !sys.subscribed() {
+		return
+	}
+
+	active := u.Active()
+
+	sys.events.mutex.Lock()
+	defer sys.events.mutex.Unlock()
+
+	if last, ok := sys.events.active[u]; ok && last == active {
+		return
+	}
+	sys.events.active[u] = active
+
+	sys.events.publish(Event{
+		Type:   UnitActiveChanged,
+		Unit:   u.Name(),
+		Active: active,
+	})
+}
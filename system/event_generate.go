@@ -0,0 +1,18 @@
+package system
+
+type EventType int
+
+//go:generate stringer -type=EventType event_generate.go
+const (
+	// Definition of the unit was loaded
+	UnitLoaded EventType = iota
+
+	// Activation state of the unit changed
+	UnitActiveChanged
+
+	// Job for the unit finished
+	JobFinished
+
+	// Jobs queued at boot finished
+	BootFinished
+)
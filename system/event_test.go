@@ -0,0 +1,72 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	path, err := ioutil.TempDir("", "subscribe-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Service]
+Type=oneshot
+ExecStart=/bin/true
+RemainAfterExit=yes`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	events, cancel := sys.Subscribe()
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	go sys.FinishBoot()
+
+	var received []Event
+	for timeout := time.After(5 * time.Second); len(received) == 0 || received[len(received)-1].Type != BootFinished; {
+		select {
+		case ev := <-events:
+			received = append(received, ev)
+		case <-timeout:
+			t.Fatalf("BootFinished not received, received: %v", received)
+		}
+	}
+
+	var types []EventType
+	var active []unit.Activation
+	for _, ev := range received {
+		if ev.Unit != "a.service" && ev.Type != BootFinished {
+			// Default dependencies of a.service
+			continue
+		}
+
+		types = append(types, ev.Type)
+		if ev.Type == UnitActiveChanged {
+			active = append(active, ev.Active)
+		}
+	}
+	assert.Equal(t, []EventType{UnitLoaded, UnitActiveChanged, JobFinished, UnitActiveChanged, BootFinished}, types)
+	assert.Equal(t, []unit.Activation{unit.Activating, unit.Active}, active)
+
+	for _, ev := range received {
+		if ev.Type == JobFinished && ev.Unit == "a.service" {
+			assert.NoError(t, ev.Err)
+			if assert.NotNil(t, ev.Job) {
+				assert.Equal(t, "start", ev.Job.Type)
+			}
+		}
+	}
+
+	cancel()
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok, "channel closed on cancel")
+}
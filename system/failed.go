@@ -0,0 +1,128 @@
+package system
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+const (
+	// Reason of the failures of units, which did not fail a job
+	exitCode = "exit-code"
+
+	// Sub state of units failed
+	failedSub = "failed"
+)
+
+// Failure describes the failure of a unit
+type Failure struct {
+	Unit string
+
+	// Reason of the failure, i.e. the error the job for the unit failed with or "exit-code"
+	Reason string
+
+	// Exit code of the main process of the unit, -1 if unknown
+	ExitCode int
+
+	// Time the unit failed
+	Since time.Time
+}
+
+// failures holds the failures of units, which have not been reset yet
+type failures struct {
+	units map[*Unit]Failure
+	mutex sync.Mutex
+}
+
+// notFailures are the errors jobs may fail with, which do not leave the unit failed
+var notFailures = map[error]bool{
+	ErrDepFail:           true,
+	ErrDepConflict:       true,
+	ErrCanceled:          true,
+	ErrAssert:            true,
+	ErrMasked:            true,
+	ErrNotLoaded:         true,
+	ErrRefuseManualStart: true,
+	ErrRefuseManualStop:  true,
+}
+
+// recordFailure records the failure of u due to reason
+func (sys *Daemon) recordFailure(u *Unit, reason string) {
+	code := -1
+	if coder, ok := u.Interface.(unit.ExitCoder); ok {
+		code = coder.ExitCode()
+	}
+
+	sys.failed.mutex.Lock()
+	defer sys.failed.mutex.Unlock()
+
+	if sys.failed.units == nil {
+		sys.failed.units = map[*Unit]Failure{}
+	}
+	sys.failed.units[u] = Failure{
+		Unit:     u.Name(),
+		Reason:   reason,
+		ExitCode: code,
+		Since:    time.Now(),
+	}
+	u.Log.WithFields(log.Fields{"result": reason, "exit_code": code}).Errorf("Failed with result '%s'", reason)
+}
+
+// clearFailure forgets the failure of u
+func (sys *Daemon) clearFailure(u *Unit) {
+	sys.failed.mutex.Lock()
+	defer sys.failed.mutex.Unlock()
+
+	delete(sys.failed.units, u)
+}
+
+// failure returns the failure of u, if it has failed and has not been reset since
+func (sys *Daemon) failure(u *Unit) (f Failure, ok bool) {
+	sys.failed.mutex.Lock()
+	defer sys.failed.mutex.Unlock()
+
+	f, ok = sys.failed.units[u]
+	return
+}
+
+// isFailed reports whether u has failed and has not been reset since
+func (u *Unit) isFailed() bool {
+	if u.System == nil {
+		return false
+	}
+	_, ok := u.System.failure(u)
+	return ok
+}
+
+// ListFailed returns the failures of the units, which have not been reset, sorted by unit name
+func (sys *Daemon) ListFailed() (list []Failure) {
+	sys.failed.mutex.Lock()
+	defer sys.failed.mutex.Unlock()
+
+	list = make([]Failure, 0, len(sys.failed.units))
+	for _, f := range sys.failed.units {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Unit < list[j].Unit
+	})
+	return
+}
+
+// checkFailed records the failure of u, if job j for it has failed with err or has left it failed
+func (u *Unit) checkFailed(j *job, err error) {
+	sys := u.System
+	if sys == nil {
+		return
+	}
+
+	switch {
+	case err != nil && !notFailures[err]:
+		sys.recordFailure(u, err.Error())
+	case err == nil && j.typ != stop && u.Interface.Active() == unit.Failed:
+		sys.recordFailure(u, exitCode)
+	}
+}
@@ -0,0 +1,106 @@
+package system
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Collection modes of the units, as CollectMode= of systemd
+const (
+	// Units are unloaded once inactive, failed ones are kept until their failure is reset
+	CollectInactive = "inactive"
+
+	// Units are unloaded once inactive or failed, their failures get reset
+	CollectInactiveOrFailed = "inactive-or-failed"
+)
+
+// GC unloads the units loaded from unit files, built-in or transient definitions, which are inactive(or failed,
+// if CollectMode=inactive-or-failed), have no job queued and are not referenced by any unit kept loaded.
+// Names of the units unloaded are returned. Units supervised directly or masked are never unloaded
+func (sys *Daemon) GC() (names []string) {
+	log.Debugf("sys.GC")
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	sys.queueMutex.Lock()
+	queued := make(map[*Unit]bool, len(sys.queue))
+	for u := range sys.queue {
+		queued[u] = true
+	}
+	sys.queueMutex.Unlock()
+
+	garbage := map[*Unit]bool{}
+	for _, u := range sys.Units() {
+		if (u.path != "" || u.isBuiltin() || u.transient) && !u.IsMasked() && u.isCollectable() && !queued[u] {
+			garbage[u] = true
+		}
+	}
+
+	// Units referenced by the ones kept are kept as well, until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, u := range sys.Units() {
+			if garbage[u] {
+				continue
+			}
+
+			for _, name := range u.references() {
+				if dep, err := sys.Unit(name); err == nil && garbage[dep] {
+					delete(garbage, dep)
+					changed = true
+				}
+			}
+		}
+	}
+
+	sys.unitsMutex.Lock()
+	for name, u := range sys.units {
+		if garbage[u] {
+			delete(sys.units, name)
+		}
+	}
+	sys.unitsMutex.Unlock()
+
+	for u := range garbage {
+		// The failure of a unit collected is not listed anymore
+		sys.clearFailure(u)
+
+		log.Debugf("Unloaded %s", u.Name())
+		names = append(names, u.Name())
+	}
+	return
+}
+
+// isCollectable reports whether u is in a state it gets unloaded in according to its collection mode
+func (u *Unit) isCollectable() bool {
+	switch st := u.Active(); {
+	case st == unit.Inactive:
+		return true
+	case st == unit.Failed:
+		return u.CollectMode() == CollectInactiveOrFailed
+	}
+	return false
+}
+
+// references returns the names of all units u refers to
+func (u *Unit) references() (names []string) {
+	for _, deps := range [][]string{
+		u.Wants(), u.Requires(), u.Conflicts(), u.After(), u.Before(),
+		u.BindsTo(), u.Requisite(), u.PartOf(),
+		u.PropagatesReloadTo(), u.ReloadPropagatedFrom(),
+	} {
+		names = append(names, deps...)
+	}
+
+	if triggerer, ok := u.Interface.(unit.Triggerer); ok {
+		names = append(names, triggerer.OnFailure()...)
+		names = append(names, triggerer.OnSuccess()...)
+	}
+	if activator, ok := u.Interface.(unit.Activator); ok {
+		names = append(names, activator.Triggers())
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/pid1"
+)
+
+// Paths searched for generators, the executables producing unit files at boot and on reload(first path gets searched first)
+var DEFAULT_GENERATOR_PATHS = []string{"/etc/systemgo/system-generators", "/run/systemgo/system-generators", "/usr/lib/systemgo/system-generators"}
+
+// Directory the output directories of the generators are created in
+const DEFAULT_GENERATOR_DIR = "/run/systemgo"
+
+// Time a generator may run for, before it gets killed
+var GENERATOR_TIMEOUT = 5 * time.Second
+
+// generators are the generators configured and the directories the unit files generated are found in
+type generators struct {
+	paths []string
+
+	// Directory the output directories get created in
+	dir string
+
+	// Output directories of the generators run, empty if none was found.
+	// The unit files generated in early shadow all the others, the ones in normal shadow none of the configured paths,
+	// the ones in late are shadowed by all the others
+	normal, early, late string
+}
+
+// SetGeneratorPaths sets paths, which get searched for generators by sys(first path gets searched first)
+// and dir, the output directories of the generators get created in
+func (sys *Daemon) SetGeneratorPaths(dir string, paths ...string) {
+	sys.pathsMutex.Lock()
+	defer sys.pathsMutex.Unlock()
+
+	sys.generators.dir, sys.generators.paths = dir, paths
+}
+
+// RunGenerators runs the generators found, passing them the normal, early and late output directories as systemd does.
+// The unit files the generators write to the directories get loaded along with the ones found in the configured paths.
+// The generators are run anew on each DaemonReload, the mutex of sys is not held while they run
+func (sys *Daemon) RunGenerators() {
+	sys.generatorsMutex.Lock()
+	defer sys.generatorsMutex.Unlock()
+
+	sys.pathsMutex.RLock()
+	g := sys.generators
+	sys.pathsMutex.RUnlock()
+
+	g.run()
+
+	sys.pathsMutex.Lock()
+	sys.generators.normal, sys.generators.early, sys.generators.late = g.normal, g.early, g.late
+	sys.pathsMutex.Unlock()
+}
+
+// run runs the generators found in the paths of g and sets the output directories of g
+func (g *generators) run() {
+	found := findGenerators(g.paths...)
+	if len(found) == 0 {
+		g.normal, g.early, g.late = "", "", ""
+		return
+	}
+
+	normal := filepath.Join(g.dir, "generator")
+	early, late := normal+".early", normal+".late"
+
+	// The output of the generators run previously is discarded
+	for _, dir := range []string{normal, early, late} {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Errorf("Error removing %s: %s", dir, err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Errorf("Error creating %s: %s", dir, err)
+			return
+		}
+	}
+	g.normal, g.early, g.late = normal, early, late
+
+	for _, path := range found {
+		e := log.WithField("generator", path)
+		e.Debugf("g.run")
+
+		ctx, cancel := context.WithTimeout(context.Background(), GENERATOR_TIMEOUT)
+		out, err := pid1.CombinedOutput(exec.CommandContext(ctx, path, normal, early, late))
+		cancel()
+
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			e.Infof("%s", out)
+		}
+		if err != nil {
+			e.Errorf("Generator failed: %s", err)
+		}
+	}
+}
+
+// findGenerators returns the paths to the generators found in paths sorted by name.
+// A generator found in a path searched first shadows the ones with the same name in paths searched later,
+// a generator linked to /dev/null is masked
+func findGenerators(paths ...string) (found []string) {
+	byName := map[string]string{}
+	for _, path := range paths {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			if _, ok := byName[info.Name()]; !ok {
+				byName[info.Name()] = filepath.Join(path, info.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := byName[name]
+		if strings.HasPrefix(name, ".") || isMasked(path) {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		found = append(found, path)
+	}
+	return
+}
+
+// unitPaths returns the paths searched for unit files: the configured ones and the output directories of the generators
+func (sys *Daemon) unitPaths() (paths []string) {
+	sys.pathsMutex.RLock()
+	defer sys.pathsMutex.RUnlock()
+
+	g := sys.generators
+	if g.normal == "" {
+		return sys.paths
+	}
+
+	paths = make([]string, 0, len(sys.paths)+3)
+	paths = append(paths, g.early)
+	paths = append(paths, sys.paths...)
+	return append(paths, g.normal, g.late)
+}
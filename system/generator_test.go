@@ -0,0 +1,70 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerators(t *testing.T) {
+	path, err := ioutil.TempDir("", "generator-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	units, generators, out := filepath.Join(path, "units"), filepath.Join(path, "generators"), filepath.Join(path, "run")
+	for _, dir := range []string{units, generators} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(units, "a.target"), []byte(`[Unit]
+Description=configured`), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(units, "b.target"), []byte(`[Unit]
+Description=configured`), 0666))
+
+	// Writes a.target shadowing the configured one, b.target shadowed by it and c.target
+	require.NoError(t, ioutil.WriteFile(filepath.Join(generators, "gen"), []byte(`#!/bin/sh
+printf '[Unit]\nDescription=early\n' > "$2/a.target"
+printf '[Unit]\nDescription=late\n' > "$3/b.target"
+printf '[Unit]\nDescription=normal\n' > "$1/c.target"
+`), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(generators, "not-executable"), []byte(`#!/bin/sh
+touch "$1/d.target"
+`), 0644))
+	require.NoError(t, os.Symlink(os.DevNull, filepath.Join(generators, "masked")))
+
+	sys := New()
+	sys.SetPaths(units)
+	sys.SetGeneratorPaths(out, generators)
+	sys.RunGenerators()
+
+	for name, description := range map[string]string{
+		"a.target": "early",
+		"b.target": "configured",
+		"c.target": "normal",
+	} {
+		u, err := sys.Get(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, description, u.Description(), name)
+	}
+
+	_, err = sys.Get("d.target")
+	assert.Equal(t, ErrNotFound, err, "generator not executable")
+
+	// The generators are run again on reload, the output of the previous run being discarded
+	require.NoError(t, ioutil.WriteFile(filepath.Join(generators, "gen"), []byte(`#!/bin/sh
+printf '[Unit]\nDescription=reloaded\n' > "$1/c.target"
+`), 0755))
+	sys.DaemonReload()
+
+	u, err := sys.Get("a.target")
+	require.NoError(t, err)
+	assert.Equal(t, "configured", u.Description(), "early output discarded")
+
+	u, err = sys.Get("c.target")
+	require.NoError(t, err)
+	assert.Equal(t, "reloaded", u.Description())
+}
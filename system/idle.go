@@ -0,0 +1,71 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Longest time the start of idle units(e.g. services of Type=idle) is delayed for, as systemd does
+var IDLE_TIMEOUT = 5 * time.Second
+
+// Interval the job queue is checked at by idle units waiting to start
+var IDLE_CHECK_INTERVAL = 50 * time.Millisecond
+
+// isIdle reports whether the start of u is delayed until the other jobs have finished
+func (u *Unit) isIdle() bool {
+	idler, ok := u.Interface.(unit.Idler)
+	return ok && idler.Idle()
+}
+
+// waitForIdle waits until the jobs queued have finished, but the ones for idle units and the ones waiting for those,
+// so that e.g. a getty does not interleave its prompt with the output of the services starting at boot.
+// Waiting ends after IDLE_TIMEOUT or once ctx is done regardless
+func (sys *Daemon) waitForIdle(ctx context.Context, u *Unit) {
+	deadline := time.After(IDLE_TIMEOUT)
+	for sys.busy() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			u.Log.Debugf("Jobs still running after %s, starting anyway", IDLE_TIMEOUT)
+			return
+		case <-time.After(IDLE_CHECK_INTERVAL):
+		}
+	}
+}
+
+// busy reports whether any job is queued, which is not for an idle unit and does not wait for one
+func (sys *Daemon) busy() bool {
+	sys.queueMutex.Lock()
+	defer sys.queueMutex.Unlock()
+
+	seen := map[*job]bool{}
+	for _, j := range sys.queue {
+		if !j.waitsForIdle(seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitsForIdle reports whether j is for an idle unit or is ordered after a job, which is, directly or not.
+// seen caches the results of the jobs checked already
+func (j *job) waitsForIdle(seen map[*job]bool) bool {
+	if waits, ok := seen[j]; ok {
+		return waits
+	}
+	// Ordering cycles are broken by assuming no wait
+	seen[j] = false
+
+	waits := j.unit.isIdle()
+	for dep := range j.after {
+		if waits {
+			break
+		}
+		waits = dep.waitsForIdle(seen)
+	}
+	seen[j] = waits
+	return waits
+}
@@ -0,0 +1,86 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdle(t *testing.T) {
+	path, err := ioutil.TempDir("", "idle-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"slow.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/sleep 0.3`,
+		"stuck.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/sleep 1000`,
+		"getty.service": `[Unit]
+DefaultDependencies=no
+[Service]
+Type=idle
+ExecStart=/bin/sleep 1000`,
+		"after-getty.target": `[Unit]
+DefaultDependencies=no
+Wants=getty.service
+After=getty.service`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	// The jobs waiting for the idle unit do not delay it
+	require.NoError(t, sys.Start("slow.service", "after-getty.target"), "sys.Start")
+	waitForJobs(t, sys, "slow.service", "getty.service", "after-getty.target")
+
+	slow, err := sys.Unit("slow.service")
+	require.NoError(t, err)
+	getty, err := sys.Unit("getty.service")
+	require.NoError(t, err)
+
+	_, slowDone := slow.startTimes()
+	gettyStarting, gettyStarted := getty.startTimes()
+	assert.True(t, gettyStarting.Before(slowDone), "idle unit job runs along")
+	assert.False(t, gettyStarted.Before(slowDone), "idle unit started before the other jobs finished")
+
+	require.NoError(t, sys.Stop("getty.service"), "sys.Stop")
+	for timeout := time.After(5 * time.Second); !getty.IsDead(); time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("getty.service not stopped")
+		default:
+		}
+	}
+
+	defer func(orig time.Duration) { IDLE_TIMEOUT = orig }(IDLE_TIMEOUT)
+	IDLE_TIMEOUT = 200 * time.Millisecond
+
+	stop := getty.lastJob()
+	start := time.Now()
+	require.NoError(t, sys.Start("stuck.service", "getty.service"), "sys.Start")
+	for getty.lastJob() == stop {
+		time.Sleep(10 * time.Millisecond)
+	}
+	getty.lastJob().Wait()
+	assert.True(t, time.Since(start) >= IDLE_TIMEOUT, "idle unit delayed by stuck.service")
+	assert.True(t, getty.IsActive(), "idle unit started after IDLE_TIMEOUT regardless")
+	assert.True(t, sys.busy(), "stuck.service still starting")
+
+	stuck, err := sys.Unit("stuck.service")
+	require.NoError(t, err)
+	sys.cancelQueued(stuck)
+}
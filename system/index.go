@@ -0,0 +1,169 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// unitIndex maps the names of the definitions found in the unit paths to the ones with the highest precedence,
+// so that a definition is found without probing each of the paths and is only parsed, once referenced
+type unitIndex struct {
+	byName map[string]string
+
+	// Unit paths indexed along with their modification times as of indexing.
+	// The index is rebuilt, once the paths are changed or a definition is added to or removed from any of them
+	dirs []indexedDir
+
+	mutex sync.Mutex
+}
+
+// indexedDir is a unit path indexed
+type indexedDir struct {
+	path    string
+	modTime time.Time
+	exists  bool
+}
+
+// Modification times more recent than this as of indexing may not reflect all the changes made while indexing
+// on filesystems with a coarse timestamp granularity, the index is rebuilt on the next lookup then
+const INDEX_GRANULARITY = time.Second
+
+// indexed returns the paths to the definitions found in the unit paths by name, which must not be modified.
+// The index is rebuilt, if it is out of date
+func (sys *Daemon) indexed() map[string]string {
+	idx := &sys.index
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	paths := sys.unitPaths()
+	if idx.byName != nil && idx.upToDate(paths) {
+		return idx.byName
+	}
+
+	idx.byName = map[string]string{}
+	idx.dirs = make([]indexedDir, len(paths))
+	now := time.Now()
+	for i, path := range paths {
+		idx.dirs[i] = statDir(path)
+		if idx.dirs[i].modTime.After(now.Add(-INDEX_GRANULARITY)) {
+			// Changes made right now may go unnoticed
+			idx.dirs[i].modTime = time.Time{}
+		}
+
+		definitions, err := pathset(path)
+		if err != nil {
+			continue
+		}
+		for _, definition := range definitions {
+			if name := filepath.Base(definition); idx.byName[name] == "" {
+				idx.byName[name] = definition
+			}
+		}
+	}
+	return idx.byName
+}
+
+// upToDate reports whether the index was built from paths and none of them changed since
+func (idx *unitIndex) upToDate(paths []string) bool {
+	if len(paths) != len(idx.dirs) {
+		return false
+	}
+	for i, path := range paths {
+		dir, cur := idx.dirs[i], statDir(path)
+		if dir.path != path || dir.exists && dir.modTime.IsZero() || cur.exists != dir.exists || !cur.modTime.Equal(dir.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// statDir returns the state of the unit path
+func statDir(path string) indexedDir {
+	info, err := os.Stat(path)
+	if err != nil {
+		return indexedDir{path: path}
+	}
+	return indexedDir{path: path, modTime: info.ModTime(), exists: true}
+}
+
+// definitionPaths returns the path to the definition of name with the highest precedence, followed by the one
+// of the template, if name is an instance, as found in the index. An absolute name is the path itself
+func (sys *Daemon) definitionPaths(name string) (paths []string) {
+	if filepath.IsAbs(name) {
+		return []string{name}
+	}
+
+	byName := sys.indexed()
+	if path, ok := byName[name]; ok {
+		paths = append(paths, path)
+	}
+	if unit.IsInstance(name) {
+		// Definition specific to the instance takes precedence over the template
+		if path, ok := byName[unit.TemplateOf(name)]; ok {
+			paths = append(paths, path)
+		}
+	}
+	return
+}
+
+// unitFiles returns names of definitions of supported unit types found in the paths of sys, templates excluded
+func (sys *Daemon) unitFiles() (names []string) {
+	for name := range sys.indexed() {
+		if !unit.IsTemplate(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// peek returns the unit name, which definition is parsed without loading the unit, unless it is loaded already,
+// so that listing the unit files does not keep all of them in memory
+func (sys *Daemon) peek(name string) (u *Unit, err error) {
+	if u, err = sys.Unit(name); err == nil && u.IsLoaded() {
+		return u, nil
+	}
+
+	paths := sys.definitionPaths(name)
+	if len(paths) == 0 {
+		return nil, ErrNotFound
+	}
+	path := paths[0]
+
+	u = NewUnit(sys.newInterface(name))
+	u.name, u.path, u.System = name, path, sys
+	if isMasked(path) {
+		u.setLoad(unit.Masked)
+		return u, ErrMasked
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	u.dropIns = sys.dropInPaths(name)
+
+	var opts unit.Options
+	if opts, err = readDefinition(file, u.dropIns); err == nil {
+		if err = unit.NewSpecifiers(filepath.Base(name), path).ExpandOptions(opts); err == nil {
+			err = u.Interface.Define(opts.Reader())
+		}
+	}
+	if _, ok := err.(unit.Warnings); ok {
+		err = nil
+	}
+
+	if err != nil {
+		u.setLoad(unit.Error)
+		return u, err
+	}
+	u.setLoad(unit.Loaded)
+	return u, nil
+}
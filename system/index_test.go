@@ -0,0 +1,61 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	path, err := ioutil.TempDir("", "index-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	etc, lib := filepath.Join(path, "etc"), filepath.Join(path, "lib")
+	for _, dir := range []string{etc, lib} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+	for path, contents := range map[string]string{
+		filepath.Join(etc, "a.target"):   "[Unit]\nDescription=etc",
+		filepath.Join(lib, "a.target"):   "[Unit]\nDescription=lib",
+		filepath.Join(lib, "b@.service"): "[Service]\nExecStart=/bin/true",
+		filepath.Join(lib, "c.target"):   "[Install]\nWantedBy=a.target",
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(etc, lib)
+
+	assert.Equal(t, []string{filepath.Join(etc, "a.target")}, sys.definitionPaths("a.target"))
+	assert.Equal(t, []string{filepath.Join(lib, "b@.service")}, sys.definitionPaths("b@foo.service"))
+	assert.Empty(t, sys.definitionPaths("missing.target"))
+
+	// The unit files are listed without being loaded
+	files := sys.ListUnitFiles()
+	assert.Equal(t, []UnitFile{{"a.target", "static"}, {"c.target", "disabled"}}, files)
+	assert.Empty(t, sys.Units(), "units loaded by ListUnitFiles")
+
+	// The index is rebuilt once the paths change
+	past := time.Now().Add(-time.Hour)
+	for _, dir := range []string{etc, lib} {
+		require.NoError(t, os.Chtimes(dir, past, past))
+	}
+	sys.indexed()
+	require.NoError(t, os.Remove(filepath.Join(etc, "a.target")))
+	assert.Equal(t, []string{filepath.Join(lib, "a.target")}, sys.definitionPaths("a.target"))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(etc, "d.target"), nil, 0644))
+	u, err := sys.Get("d.target")
+	require.NoError(t, err, "sys.Get of unit added")
+	assert.Equal(t, filepath.Join(etc, "d.target"), u.Path())
+
+	sys.SetPaths(lib)
+	_, err = sys.Get("e.target")
+	assert.Equal(t, ErrNotFound, err)
+}
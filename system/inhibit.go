@@ -0,0 +1,186 @@
+package system
+
+import (
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Modes of the inhibitors
+const (
+	// Prevents the operations inhibited, unless they are forced
+	InhibitBlock = "block"
+
+	// Postpones the operations inhibited until the lock is released or the maximum delay passes
+	InhibitDelay = "delay"
+)
+
+// Longest time the delay inhibitors postpone the operations by default, as InhibitDelayMaxSec= of logind
+const DEFAULT_INHIBIT_DELAY_MAX = 5 * time.Second
+
+// Operations, which can be inhibited
+var inhibitable = map[string]bool{
+	"shutdown": true,
+	"sleep":    true,
+	"idle":     true,
+}
+
+// Inhibitor is a lock held by a client, which blocks or delays operations of the manager
+type Inhibitor struct {
+	ID uint64
+
+	// Operations inhibited("shutdown", "sleep" or "idle")
+	What []string
+
+	// Human readable name of the client and the reason it holds the lock for
+	Who, Why string
+
+	// InhibitBlock or InhibitDelay
+	Mode string
+
+	// Process holding the lock, which is released once the process exits. 0, if released by Uninhibit only
+	PID int
+
+	Since time.Time
+}
+
+// inhibitors are the inhibitor locks held
+type inhibitors struct {
+	byID map[uint64]*Inhibitor
+	last uint64
+
+	// Longest time the delay inhibitors postpone the operations by
+	delayMax time.Duration
+}
+
+// Inhibit takes an inhibitor lock of mode on the operations in what, separated by colons(e.g. "shutdown:sleep"),
+// held by the process pid, and returns its ID
+func (sys *Daemon) Inhibit(what, who, why, mode string, pid int) (id uint64, err error) {
+	ops := strings.Split(what, ":")
+	for _, op := range ops {
+		if !inhibitable[op] {
+			return 0, ErrUnknownInhibit
+		}
+	}
+	if mode != InhibitBlock && mode != InhibitDelay {
+		return 0, ErrInhibitMode
+	}
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if sys.inhibitors.byID == nil {
+		sys.inhibitors.byID = map[uint64]*Inhibitor{}
+	}
+	sys.inhibitors.last++
+	id = sys.inhibitors.last
+	sys.inhibitors.byID[id] = &Inhibitor{
+		ID:    id,
+		What:  ops,
+		Who:   who,
+		Why:   why,
+		Mode:  mode,
+		PID:   pid,
+		Since: time.Now(),
+	}
+
+	log.WithFields(log.Fields{
+		"what": what,
+		"who":  who,
+		"why":  why,
+		"mode": mode,
+	}).Infof("Inhibitor %d taken", id)
+	return id, nil
+}
+
+// Uninhibit releases the inhibitor lock id
+func (sys *Daemon) Uninhibit(id uint64) error {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if _, ok := sys.inhibitors.byID[id]; !ok {
+		return ErrNoSuchInhibitor
+	}
+	delete(sys.inhibitors.byID, id)
+	log.Infof("Inhibitor %d released", id)
+	return nil
+}
+
+// ListInhibitors returns the inhibitor locks held ordered by ID
+func (sys *Daemon) ListInhibitors() (list []Inhibitor) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.releaseDeadInhibitors()
+	for _, inh := range sys.inhibitors.byID {
+		list = append(list, *inh)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return
+}
+
+// SetInhibitDelayMax sets the longest time the delay inhibitors postpone the operations by
+func (sys *Daemon) SetInhibitDelayMax(d time.Duration) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.inhibitors.delayMax = d
+}
+
+// inhibited returns the inhibitor locks of mode held on operation op
+func (sys *Daemon) inhibited(op, mode string) (list []Inhibitor) {
+	for _, inh := range sys.ListInhibitors() {
+		if inh.Mode != mode {
+			continue
+		}
+		for _, what := range inh.What {
+			if what == op {
+				list = append(list, inh)
+				break
+			}
+		}
+	}
+	return
+}
+
+// inhibit returns ErrInhibited, if a block inhibitor is held on operation op, and otherwise waits
+// for the delay inhibitors held on it to be released, but no longer than the maximum delay
+func (sys *Daemon) inhibit(op string) error {
+	if blocking := sys.inhibited(op, InhibitBlock); len(blocking) > 0 {
+		log.Warnf("%s inhibited by %s: %s", op, blocking[0].Who, blocking[0].Why)
+		return ErrInhibited
+	}
+
+	sys.mutex.Lock()
+	delayMax := sys.inhibitors.delayMax
+	sys.mutex.Unlock()
+
+	deadline := time.Now().Add(delayMax)
+	for {
+		delaying := sys.inhibited(op, InhibitDelay)
+		if len(delaying) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			log.Warnf("%s delayed by %s for longer than %s, proceeding", op, delaying[0].Who, delayMax)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// releaseDeadInhibitors releases the locks held by the processes, which have exited, the mutex of sys must be locked
+func (sys *Daemon) releaseDeadInhibitors() {
+	for id, inh := range sys.inhibitors.byID {
+		if inh.PID == 0 {
+			continue
+		}
+		if err := syscall.Kill(inh.PID, 0); err == nil || err == syscall.EPERM {
+			continue
+		}
+		log.Infof("Inhibitor %d released, process %d exited", id, inh.PID)
+		delete(sys.inhibitors.byID, id)
+	}
+}
@@ -0,0 +1,47 @@
+package system
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInhibit(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	_, err := sys.Inhibit("shutdown:foo", "test", "testing", InhibitBlock, 0)
+	assert.Equal(t, ErrUnknownInhibit, err)
+	_, err = sys.Inhibit("shutdown", "test", "testing", "foo", 0)
+	assert.Equal(t, ErrInhibitMode, err)
+
+	block, err := sys.Inhibit("shutdown:sleep", "test", "testing", InhibitBlock, os.Getpid())
+	require.NoError(t, err, "sys.Inhibit")
+	assert.Equal(t, ErrInhibited, sys.inhibit("shutdown"))
+	assert.Equal(t, ErrInhibited, sys.Reboot(), "shutdown not blocked")
+
+	require.NoError(t, sys.Uninhibit(block), "sys.Uninhibit")
+	assert.Equal(t, ErrNoSuchInhibitor, sys.Uninhibit(block))
+	assert.NoError(t, sys.inhibit("shutdown"))
+
+	// The lock is released once the delay passes
+	sys.SetInhibitDelayMax(200 * time.Millisecond)
+	_, err = sys.Inhibit("shutdown", "test", "testing", InhibitDelay, 0)
+	require.NoError(t, err, "sys.Inhibit")
+	start := time.Now()
+	assert.NoError(t, sys.inhibit("shutdown"))
+	assert.True(t, time.Since(start) >= 200*time.Millisecond, "shutdown not delayed")
+	assert.Len(t, sys.ListInhibitors(), 1)
+
+	// The lock of a process is released once it exits
+	cmd := exec.Command("/bin/true")
+	require.NoError(t, cmd.Run())
+	_, err = sys.Inhibit("shutdown", "test", "testing", InhibitBlock, cmd.Process.Pid)
+	require.NoError(t, err, "sys.Inhibit")
+	assert.Len(t, sys.ListInhibitors(), 1, "lock of exited process not released")
+	assert.NoError(t, sys.inhibit("sleep"))
+}
@@ -0,0 +1,176 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Enable creates symlinks to u definition in '.wants' and '.requires' directories of units
+// dependant on u and symlinks named by aliases of u in the path of u.System with the highest precedence.
+// Units listed in Also= of u get enabled as well
+func (u *Unit) Enable() (err error) {
+	return u.install(true, map[*Unit]bool{})
+}
+
+// Disable removes symlinks(if they exist) created by Enable
+func (u *Unit) Disable() (err error) {
+	return u.install(false, map[*Unit]bool{})
+}
+
+func (u *Unit) install(enable bool, visited map[*Unit]bool) (err error) {
+	if visited[u] {
+		return nil
+	}
+	visited[u] = true
+
+	if u.IsMasked() {
+		return ErrMasked
+	}
+
+	paths := u.System.Paths()
+	if len(paths) == 0 {
+		return ErrNotFound
+	}
+
+	for _, link := range u.installLinks(paths[0]) {
+		if enable {
+			err = createLink(link, u.System.inRoot(u.Path()))
+		} else {
+			err = removeLink(link)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return u.System.getAndExecute(u.also(), func(dep *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		return dep.install(enable, visited)
+	})
+}
+
+// Enabled returns the enable state of the unit.
+// Unit is considered enabled if any of the symlinks created by Enable exist in any of paths of u.System
+func (u *Unit) Enabled() (st unit.Enable) {
+	links := u.installLinks(u.System.Paths()...)
+	if len(links) == 0 {
+		if len(u.also()) > 0 {
+			return unit.Indirect
+		}
+		return unit.Static
+	}
+
+	for _, link := range links {
+		if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return unit.Enabled
+		}
+	}
+	return unit.Disabled
+}
+
+// UnitFile is a definition found in the paths of the system along with its enable state
+type UnitFile struct {
+	Name string
+
+	// Enable state of the unit, "masked" or "bad", if the definition can not be loaded
+	State string
+}
+
+// ListUnitFiles returns the definitions of supported unit types found in the paths of sys sorted by name
+func (sys *Daemon) ListUnitFiles() (files []UnitFile) {
+	files = []UnitFile{}
+	for _, name := range sys.unitFiles() {
+		f := UnitFile{Name: name}
+
+		switch u, err := sys.peek(name); {
+		case err == nil:
+			f.State = strings.ToLower(u.Enabled().String())
+		case u != nil && u.IsMasked():
+			f.State = "masked"
+		default:
+			f.State = "bad"
+		}
+		files = append(files, f)
+	}
+	return
+}
+
+// installLinks returns paths of symlinks to u in paths specified, which get created by Enable
+func (u *Unit) installLinks(paths ...string) (links []string) {
+	wantedBy, requiredBy, aliases := u.WantedBy(), u.RequiredBy(), u.aliases()
+
+	for _, path := range paths {
+		for _, name := range wantedBy {
+			links = append(links, filepath.Join(path, name+".wants", u.Name()))
+		}
+		for _, name := range requiredBy {
+			links = append(links, filepath.Join(path, name+".requires", u.Name()))
+		}
+		for _, alias := range aliases {
+			links = append(links, filepath.Join(path, alias))
+		}
+	}
+	return
+}
+
+func (u *Unit) aliases() (names []string) {
+	if installer, ok := u.Interface.(unit.Installer); ok {
+		return installer.Alias()
+	}
+	return nil
+}
+
+func (u *Unit) also() (names []string) {
+	if installer, ok := u.Interface.(unit.Installer); ok {
+		return installer.Also()
+	}
+	return nil
+}
+
+// createLink creates a symlink at path pointing to target and all the
+// missing parent directories. Existing symlink pointing to target is left untouched,
+// one pointing elsewhere gets replaced. ErrNotSymlink is returned, if path is not a symlink
+func createLink(path, target string) (err error) {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink == 0:
+		// Not created by Enable
+		return ErrNotSymlink
+	default:
+		if existing, err := os.Readlink(path); err == nil && existing == target {
+			return nil
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	return os.Symlink(target, path)
+}
+
+// removeLink removes the symlink found at path, if it exists
+func removeLink(path string) (err error) {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink == 0:
+		// Not created by Enable
+		return nil
+	}
+	return os.Remove(path)
+}
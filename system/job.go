@@ -1,14 +1,19 @@
 package system
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
 )
 
 const job_type_count = 4
 
 type job struct {
+	id   uint64
 	typ  jobType
 	unit *Unit
 
@@ -16,11 +21,18 @@ type job struct {
 	wantedBy, requiredBy, conflictedBy set
 	after, before                      set
 
-	executed bool
-
+	// Closed once the job finished, err is set before
 	waitch chan struct{}
 	err    error
 
+	// ctx is done, once the job is cancelled
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Timers cancelling the job on timeout
+	timers   []*time.Timer
+	timedOut bool
+
 	mutex sync.Mutex
 }
 
@@ -30,6 +42,8 @@ func newJob(typ jobType, u *Unit) (j *job) {
 		"u":   u,
 	}).Debugf("newJob")
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &job{
 		typ:  typ,
 		unit: u,
@@ -46,6 +60,9 @@ func newJob(typ jobType, u *Unit) (j *job) {
 		before: set{},
 
 		waitch: make(chan struct{}),
+
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
@@ -67,7 +84,12 @@ func (j *job) IsRedundant() bool {
 }
 
 func (j *job) IsRunning() bool {
-	return !j.executed
+	select {
+	case <-j.waitch:
+		return false
+	default:
+		return true
+	}
 }
 
 func (j *job) Success() bool {
@@ -89,6 +111,8 @@ func (j *job) isOrphan() bool {
 
 func (j *job) State() (st jobState) {
 	switch {
+	case j.IsRunning() && len(j.waitingFor()) > 0:
+		return waiting
 	case j.IsRunning():
 		return running
 	case j.err == nil:
@@ -98,6 +122,63 @@ func (j *job) State() (st jobState) {
 	}
 }
 
+// waitingFor returns the jobs ordered before j, which have not finished yet
+func (j *job) waitingFor() (jobs []*job) {
+	for dep := range j.after {
+		if dep.IsRunning() {
+			jobs = append(jobs, dep)
+		}
+	}
+	return
+}
+
+// dependencyResults returns the results of the jobs j requires or wants ordered by unit name
+func (j *job) dependencyResults() (results []unit.DependencyStatus) {
+	for kind, deps := range map[string]set{
+		"required": j.requires,
+		"wanted":   j.wants,
+	} {
+		for dep := range deps {
+			results = append(results, unit.DependencyStatus{
+				Name:   dep.unit.Name(),
+				Kind:   kind,
+				Result: dep.State().String(),
+			})
+		}
+	}
+	sort.Slice(results, func(i, k int) bool {
+		return results[i].Name < results[k].Name
+	})
+	return
+}
+
+// JobInfo describes a job queued
+type JobInfo struct {
+	ID   uint64
+	Unit string
+	Type string
+
+	// State is either "waiting" or "running"
+	State string
+
+	// Units the job is waiting for the jobs of
+	WaitingFor []string
+}
+
+// info returns the description of j
+func (j *job) info() (info JobInfo) {
+	info = JobInfo{
+		ID:    j.id,
+		Unit:  j.unit.Name(),
+		Type:  j.typ.String(),
+		State: j.State().String(),
+	}
+	for _, dep := range j.waitingFor() {
+		info.WaitingFor = append(info.WaitingFor, dep.unit.Name())
+	}
+	return
+}
+
 func (j *job) Run() (err error) {
 	e := log.WithFields(log.Fields{
 		"unit": j.unit.Name(),
@@ -105,47 +186,101 @@ func (j *job) Run() (err error) {
 	})
 	e.Debugf("j.Run()")
 
-	j.unit.job = j
 	defer func() {
+		timedOut := err == ErrCanceled && j.hasTimedOut()
+		if timedOut {
+			err = ErrJobTimeout
+		}
+
 		j.err = err
 		j.finish()
+
+		result := JobResult(err)
+		entry := j.unit.Log.WithFields(log.Fields{"job": j.id, "type": j.typ.String(), "result": result})
+		if err != nil && err != ErrCanceled {
+			entry.Errorf("%s job failed: %s", j.typ, err)
+		} else {
+			entry.Infof("%s job finished: %s", j.typ, result)
+		}
+
+		j.unit.checkFailed(j, err)
+		if sys := j.unit.System; sys != nil {
+			sys.countJob(j.typ.String(), result)
+		}
+
+		if sys := j.unit.System; sys != nil && sys.subscribed() {
+			info := j.info()
+			sys.publish(Event{Type: JobFinished, Unit: j.unit.Name(), Active: j.unit.Active(), Job: &info, Err: err})
+		}
+		j.unit.publishActive()
+
+		j.unit.trigger(j)
+		j.unit.unbind(j)
+		j.unit.stopUnneeded(j)
+
+		if timedOut {
+			j.unit.timeoutAction()
+		}
 	}()
 
-	wg := &sync.WaitGroup{}
-	for dep := range j.requires {
-		wg.Add(1)
-		go func(dep *job) {
-			e := e.WithField("dep", dep.unit.Name())
+	// Wait for the jobs ordered before j to finish, cancellation interrupts waiting
+	for dep := range j.after {
+		e.WithField("dep", dep.unit.Name()).Debug("dep.Wait")
+
+		select {
+		case <-dep.waitch:
+		case <-j.ctx.Done():
+			e.Debug("cancelled")
+			j.unit.Log.WithField("job", j.id).Printf("%s job cancelled", j.typ)
+			return ErrCanceled
+		}
+	}
+
+	_, running, _ := j.unit.jobTimeouts()
+	j.armTimeout(running)
 
-			e.Debug("dep.Wait")
-			dep.Wait()
-			e.Debug("dep.Wait returned")
+	results := j.dependencyResults()
+	j.unit.mutex.Lock()
+	j.unit.dependencies = results
+	j.unit.mutex.Unlock()
 
-			if !dep.Success() {
-				e.Debugf("->!dep.Success: %s", dep.State())
-				j.unit.Log.Errorf("%s failed to %s", dep.unit.Name(), dep.typ)
-				err = ErrDepFail
-			}
-			wg.Done()
-		}(dep)
+	// Requirements decide whether j can run, but only the ones ordered before j
+	// have finished by now, the rest runs in parallel.
+	// Jobs of wanted units do not affect j at all
+	for dep := range j.requires {
+		if j.after.Contains(dep) && !dep.Success() {
+			e.Debugf("->!dep.Success: %s", dep.State())
+			j.unit.Log.WithField("job", j.id).Errorf("%s failed to %s", dep.unit.Name(), dep.typ)
+			err = ErrDepFail
+		}
+	}
+
+	// Conflicting units must have stopped first
+	for dep := range j.conflicts {
+		if j.after.Contains(dep) && !dep.Success() {
+			e.Debugf("->!conflict.Success: %s", dep.State())
+			j.unit.Log.WithField("job", j.id).Errorf("%s failed to %s", dep.unit.Name(), dep.typ)
+			err = ErrDepConflict
+		}
 	}
-	wg.Wait()
 
 	if err != nil {
 		e.Debugf("failed: %s", err)
 		return
 	}
 
+	j.unit.publishActive()
+
 	switch j.typ {
 	case start:
-		return j.unit.start()
+		return j.unit.start(j.ctx)
 	case stop:
-		return j.unit.stop()
+		return j.unit.stop(j.ctx)
 	case restart:
-		if err = j.unit.stop(); err != nil {
+		if err = j.unit.stop(j.ctx); err != nil {
 			return err
 		}
-		return j.unit.start()
+		return j.unit.start(j.ctx)
 	case reload:
 		return j.unit.reload()
 	default:
@@ -153,9 +288,34 @@ func (j *job) Run() (err error) {
 	}
 }
 
+// JobResult returns the result of a job finished with err, as reported by systemd
+func JobResult(err error) string {
+	switch err {
+	case nil:
+		return "done"
+	case ErrCanceled:
+		return "canceled"
+	case ErrJobTimeout:
+		return "timeout"
+	case ErrDepFail, ErrDepConflict:
+		return "dependency"
+	case ErrAssert:
+		return "assert"
+	default:
+		return "failed"
+	}
+}
+
 func (j *job) finish() {
-	j.executed = true
+	if j.unit != nil && j.unit.System != nil {
+		j.unit.System.dequeue(j)
+	}
+
 	close(j.waitch)
+
+	// Release the resources of the context
+	j.disarmTimeouts()
+	j.cancel()
 }
 
 var mergeTable = map[jobType]map[jobType]jobType{
@@ -175,6 +335,9 @@ var mergeTable = map[jobType]map[jobType]jobType{
 		//verify_active: restart,
 		reload: restart,
 	},
+	stop: {
+		stop: stop,
+	},
 }
 
 func (j *job) mergeWith(other *job) (err error) {
@@ -185,19 +348,65 @@ func (j *job) mergeWith(other *job) (err error) {
 
 	j.typ = t
 
-	for jSet, oSet := range map[*set]*set{
-		&j.wantedBy:     &other.wantedBy,
-		&j.requiredBy:   &other.requiredBy,
-		&j.conflictedBy: &other.conflictedBy,
-
-		&j.wants:     &other.wants,
-		&j.requires:  &other.requires,
-		&j.conflicts: &other.conflicts,
+	// Links of other are moved to j, so that the jobs linked refer to j instead
+	for _, link := range []struct {
+		jSet, oSet *set
+		reverse    func(*job) *set
+	}{
+		{&j.wantedBy, &other.wantedBy, func(dep *job) *set { return &dep.wants }},
+		{&j.requiredBy, &other.requiredBy, func(dep *job) *set { return &dep.requires }},
+		{&j.conflictedBy, &other.conflictedBy, func(dep *job) *set { return &dep.conflicts }},
+
+		{&j.wants, &other.wants, func(dep *job) *set { return &dep.wantedBy }},
+		{&j.requires, &other.requires, func(dep *job) *set { return &dep.requiredBy }},
+		{&j.conflicts, &other.conflicts, func(dep *job) *set { return &dep.conflictedBy }},
 	} {
-		for oJob := range *oSet {
-			jSet.Put(oJob)
+		for oJob := range *link.oSet {
+			link.jSet.Put(oJob)
+
+			reverse := link.reverse(oJob)
+			delete(*reverse, other)
+			reverse.Put(j)
 		}
 	}
 
 	return
 }
+
+// mergeInto merges j into pending, the job queued for the unit of j already, which does what j would.
+// The jobs depending on j depend on pending instead and j is not committed, so that pending keeps running.
+// The links to the jobs j depends on are dropped, as pending has been dispatched already
+func (j *job) mergeInto(pending *job) {
+	for _, link := range []struct {
+		jSet    *set
+		reverse func(*job) *set
+	}{
+		{&j.wantedBy, func(dep *job) *set { return &dep.wants }},
+		{&j.requiredBy, func(dep *job) *set { return &dep.requires }},
+		{&j.conflictedBy, func(dep *job) *set { return &dep.conflicts }},
+		{&j.before, func(dep *job) *set { return &dep.after }},
+	} {
+		for dep := range *link.jSet {
+			reverse := link.reverse(dep)
+			delete(*reverse, j)
+			reverse.Put(pending)
+		}
+	}
+
+	for _, link := range []struct {
+		jSet    *set
+		reverse func(*job) *set
+	}{
+		{&j.wants, func(dep *job) *set { return &dep.wantedBy }},
+		{&j.requires, func(dep *job) *set { return &dep.requiredBy }},
+		{&j.conflicts, func(dep *job) *set { return &dep.conflictedBy }},
+		{&j.after, func(dep *job) *set { return &dep.before }},
+	} {
+		for dep := range *link.jSet {
+			delete(*link.reverse(dep), j)
+		}
+	}
+
+	// Release the resources of the context
+	j.cancel()
+}
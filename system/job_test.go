@@ -2,9 +2,15 @@ package system
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJobState(t *testing.T) {
@@ -18,3 +24,201 @@ func TestJobState(t *testing.T) {
 	j.err = errors.New("")
 	assert.Equal(t, failed, j.State())
 }
+
+// Jobs run while the state of the units is queried, meant to be run with -race
+func TestConcurrentJobs(t *testing.T) {
+	path, err := ioutil.TempDir("", "concurrent-jobs-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Unit]
+Requires=a.service
+After=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"c.service": `[Unit]
+Wants=b.service
+[Service]
+Type=oneshot
+ExecStart=/bin/true`,
+		"group.target": `[Unit]
+Wants=a.service b.service c.service`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+	names := []string{"a.service", "b.service", "c.service", "group.target"}
+
+	done := make(chan struct{})
+	readers := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				for _, u := range sys.Units() {
+					u.Status()
+					u.JobID()
+				}
+				sys.ListJobs()
+				sys.Status()
+				sys.Show("b.service")
+			}
+		}()
+	}
+
+	writers := &sync.WaitGroup{}
+	for i, name := range names {
+		writers.Add(1)
+		go func(i int, name string) {
+			defer writers.Done()
+			for k := 0; k < 20; k++ {
+				if (i+k)%2 == 0 {
+					sys.Start(name)
+				} else {
+					sys.Stop(name)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(i, name)
+	}
+	writers.Wait()
+
+	require.NoError(t, sys.Stop(names...), "sys.Stop")
+	for timeout := time.After(10 * time.Second); len(sys.ListJobs()) > 0; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatalf("jobs still queued: %v", sys.ListJobs())
+		default:
+		}
+	}
+	close(done)
+	readers.Wait()
+
+	for _, name := range names {
+		u, err := sys.Unit(name)
+		if assert.NoError(t, err, name) {
+			assert.False(t, u.IsActive(), "%s stopped", name)
+		}
+	}
+}
+
+func TestReplaceJob(t *testing.T) {
+	path, err := ioutil.TempDir("", "replace-job-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+Wants=b.service
+After=b.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Service]
+Type=oneshot
+ExecStart=/bin/sleep 0.5`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err, "sys.Unit")
+	started := a.lastJob()
+	require.NotNil(t, started)
+	assert.Equal(t, waiting, started.State())
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+	stopped := a.lastJob()
+	require.NotNil(t, stopped)
+	assert.NotEqual(t, started, stopped)
+
+	select {
+	case <-started.waitch:
+	case <-time.After(time.Second):
+		t.Fatal("replaced job still running")
+	}
+	assert.Equal(t, ErrCanceled, started.err)
+
+	stopped.Wait()
+	assert.NoError(t, stopped.err)
+
+	b, err := sys.Unit("b.service")
+	require.NoError(t, err, "sys.Unit")
+	b.lastJob().Wait()
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, a.IsActive(), "a.service stopped")
+	assert.Empty(t, sys.ListJobs())
+}
+
+func TestMergeFailModeJob(t *testing.T) {
+	path, err := ioutil.TempDir("", "merge-job-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Unit]
+Wants=b.service
+After=b.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+		"b.service": `[Service]
+Type=oneshot
+ExecStart=/bin/sleep 0.5`,
+		"c.service": `[Unit]
+Requires=a.service
+After=a.service
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+	defer sys.Stop("a.service", "c.service")
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err, "sys.Unit")
+	started := a.lastJob()
+	require.NotNil(t, started)
+	assert.Equal(t, waiting, started.State())
+
+	require.NoError(t, sys.StartWith(FailMode, "a.service"), "sys.StartWith")
+	assert.Equal(t, started, a.lastJob(), "pending job replaced")
+	assert.Len(t, sys.ListJobs(), 2)
+
+	// The start job pending for a.service is kept and c.service waits for it
+	require.NoError(t, sys.StartWith(FailMode, "c.service"), "sys.StartWith")
+	assert.Equal(t, started, a.lastJob(), "pending job replaced")
+
+	c, err := sys.Unit("c.service")
+	require.NoError(t, err, "sys.Unit")
+	merged := c.lastJob()
+	require.NotNil(t, merged)
+	assert.Contains(t, merged.after, started)
+	assert.Equal(t, waiting, merged.State())
+
+	started.Wait()
+	assert.NoError(t, started.err)
+	merged.Wait()
+	assert.NoError(t, merged.err)
+	assert.True(t, a.IsActive(), "a.service started")
+	assert.True(t, c.IsActive(), "c.service started")
+	assert.Empty(t, sys.ListJobs())
+}
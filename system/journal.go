@@ -0,0 +1,66 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Logs returns the records of the journal matching q.
+// The units can be specified by the names of their aliases and in any form unit.Normalize accepts
+func (sys *Daemon) Logs(q journal.Query) (res journal.Result, err error) {
+	log.WithField("query", q).Debugf("sys.Logs")
+
+	if sys.Journal == nil {
+		return res, ErrNotFound
+	}
+
+	units := make([]string, len(q.Units))
+	for i, name := range q.Units {
+		if u, err := sys.Unit(name); err == nil {
+			name = u.Name()
+		} else {
+			name = unit.Normalize(name)
+		}
+		units[i] = name
+	}
+	q.Units = units
+
+	return sys.Journal.Query(q)
+}
+
+// STATUS_LOG_LINES is the number of the latest records of a unit shown in its status
+const STATUS_LOG_LINES = 10
+
+// statusLog returns the latest records of the unit named name in the form they are shown in its status
+func (sys *Daemon) statusLog(name string) (b []byte, err error) {
+	res, err := sys.Logs(journal.Query{Units: []string{name}, Lines: STATUS_LOG_LINES})
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range res.Records {
+		fmt.Fprintf(&buf, "%s %s: %s\n", rec.Time.Format(time.Stamp), rec.Unit, rec.Message)
+	}
+	return buf.Bytes(), nil
+}
+
+// JournalUsage returns the disk usage of the journal
+func (sys *Daemon) JournalUsage() (journal.Usage, error) {
+	if sys.Journal == nil {
+		return journal.Usage{}, ErrNotFound
+	}
+	return sys.Journal.Usage(), nil
+}
+
+// setLogRateLimit limits the output of u recorded in the journal as LogRateLimitIntervalSec= and LogRateLimitBurst= specify
+func (sys *Daemon) setLogRateLimit(u *Unit) {
+	if limiter, ok := u.Interface.(unit.LogRateLimiter); ok && sys.Journal != nil {
+		sys.Journal.SetRateLimit(u.Name(), limiter.LogRateLimitIntervalSec(), limiter.LogRateLimitBurst())
+	}
+}
@@ -0,0 +1,130 @@
+package system
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Processes of a unit Kill sends the signal to
+const (
+	KillMain    = "main"
+	KillControl = "control"
+	KillAll     = "all"
+)
+
+// Kill sends signal to the processes of the unit name selected by who without stopping the unit.
+// KillAll selects the main and control processes, their descendants and the other processes in the cgroup of the unit
+func (sys *Daemon) Kill(name, who string, signal syscall.Signal) (err error) {
+	log.WithFields(log.Fields{
+		"name":   name,
+		"who":    who,
+		"signal": signal,
+	}).Debugf("sys.Kill")
+
+	u, err := sys.Unit(name)
+	if err != nil {
+		return
+	}
+
+	pids, err := u.processesOf(who)
+	if err != nil {
+		return
+	}
+	if len(pids) == 0 {
+		return ErrNoProcess
+	}
+
+	u.Log.Printf("Sending signal %s to %s process(es): %v", signal, who, pids)
+	for _, pid := range pids {
+		if err = syscall.Kill(pid, signal); err != nil && err != syscall.ESRCH {
+			return
+		}
+	}
+	return nil
+}
+
+// processesOf returns the PIDs of the processes of u selected by who
+func (u *Unit) processesOf(who string) (pids []int, err error) {
+	var main, control int
+	if attacher, ok := u.Interface.(unit.Attacher); ok {
+		main = attacher.MainPID()
+	}
+	if controller, ok := u.Interface.(unit.Controller); ok {
+		control = controller.ControlPID()
+	}
+
+	switch who {
+	case KillMain:
+		if main > 0 {
+			pids = append(pids, main)
+		}
+	case KillControl:
+		if control > 0 {
+			pids = append(pids, control)
+		}
+	case KillAll:
+		seen := map[int]bool{}
+		add := func(pid int) {
+			if pid > 0 && !seen[pid] {
+				seen[pid] = true
+				pids = append(pids, pid)
+			}
+		}
+
+		for _, pid := range u.roots() {
+			procs, _, _ := processTree(pid)
+			for _, proc := range procs {
+				add(proc.PID)
+			}
+		}
+		for _, pid := range u.cgroupProcs() {
+			add(pid)
+		}
+	default:
+		return nil, ErrUnknownWho
+	}
+	return pids, nil
+}
+
+// roots returns the PIDs of the main and control processes of u followed by the ones it groups,
+// the processes of u are these and their descendants along with the processes in the cgroup of u
+func (u *Unit) roots() (pids []int) {
+	candidates := []int{}
+	if attacher, ok := u.Interface.(unit.Attacher); ok {
+		candidates = append(candidates, attacher.MainPID())
+	}
+	if controller, ok := u.Interface.(unit.Controller); ok {
+		candidates = append(candidates, controller.ControlPID())
+	}
+	if grouper, ok := u.Interface.(unit.Grouper); ok {
+		candidates = append(candidates, grouper.Processes()...)
+	}
+
+	for _, pid := range candidates {
+		if pid > 0 {
+			pids = append(pids, pid)
+		}
+	}
+	return
+}
+
+// cgroupProcs returns the PIDs of the processes in the cgroup of u, if it has one
+func (u *Unit) cgroupProcs() (pids []int) {
+	b, err := ioutil.ReadFile(filepath.Join(u.cgroupPath(), "cgroup.procs"))
+	if err != nil {
+		return nil
+	}
+
+	for _, field := range strings.Fields(string(b)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKill(t *testing.T) {
+	path, err := ioutil.TempDir("", "kill-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	script := filepath.Join(path, "tree.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte("#!/bin/sh\n/bin/sleep 1000 &\nwait\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=`+script), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+	defer sys.Stop("a.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrNoProcess, sys.Kill("a.service", KillControl, syscall.SIGTERM), "no control process")
+	assert.Equal(t, ErrUnknownWho, sys.Kill("a.service", "everyone", syscall.SIGTERM))
+	assert.Equal(t, ErrNotFound, sys.Kill("missing.service", KillAll, syscall.SIGTERM))
+
+	main, err := a.processesOf(KillMain)
+	require.NoError(t, err)
+	assert.Equal(t, []int{a.Status().MainPID}, main)
+
+	var all []int
+	timeout := time.After(5 * time.Second)
+	for len(all) < 2 {
+		select {
+		case <-timeout:
+			t.Fatalf("Child of the main process not found: %v", all)
+		case <-time.After(20 * time.Millisecond):
+		}
+		all, err = a.processesOf(KillAll)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, main[0], all[0], "main process first")
+
+	require.NoError(t, sys.Kill("a.service", KillAll, syscall.SIGKILL), "sys.Kill")
+	for _, pid := range all {
+		timeout := time.After(5 * time.Second)
+		for syscall.Kill(pid, 0) == nil && !isZombie(pid) {
+			select {
+			case <-timeout:
+				t.Fatalf("Process %d not killed", pid)
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// isZombie reports whether the process pid has exited, but has not been reaped yet
+func isZombie(pid int) bool {
+	b, err := ioutil.ReadFile(filepath.Join(PROC_PATH, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
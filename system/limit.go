@@ -0,0 +1,96 @@
+package system
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// startLimitHit is the sub state of units refused to start due to the start rate limit
+const startLimitHit = "start-limit-hit"
+
+// startLimit returns StartLimitIntervalSec= and StartLimitBurst= as found in definition
+func (u *Unit) startLimit() (interval time.Duration, burst int) {
+	if limiter, ok := u.Interface.(unit.StartLimiter); ok {
+		return limiter.StartLimitIntervalSec(), limiter.StartLimitBurst()
+	}
+	return
+}
+
+// allowStart records a start attempt of u and reports whether it is within the start rate limit.
+// Once the limit is hit, u remains failed until reset
+func (u *Unit) allowStart() bool {
+	interval, burst := u.startLimit()
+	if interval <= 0 || burst <= 0 {
+		return true
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	now := time.Now()
+
+	// Forget the attempts, which happened before the current interval
+	recent := u.starts[:0]
+	for _, t := range u.starts {
+		if now.Sub(t) < interval {
+			recent = append(recent, t)
+		}
+	}
+	u.starts = recent
+
+	if u.limitHit || len(u.starts) >= burst {
+		u.limitHit = true
+		return false
+	}
+
+	u.starts = append(u.starts, now)
+	return true
+}
+
+// isLimitHit reports whether u was refused to start due to the start rate limit
+func (u *Unit) isLimitHit() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.limitHit
+}
+
+// ResetFailed clears the start rate limit counter and the failure of u
+func (u *Unit) ResetFailed() {
+	u.mutex.Lock()
+	u.starts = nil
+	u.limitHit = false
+	u.mutex.Unlock()
+
+	if u.System != nil {
+		u.System.clearFailure(u)
+	}
+
+	if resetter, ok := u.Interface.(unit.FailedResetter); ok && u.Interface.Active() == unit.Failed {
+		resetter.ResetFailed()
+	}
+}
+
+// ResetFailed gets names from internal hashmap and resets the failed state of each unit returned.
+// If no names are specified, the failed state of all units is reset
+func (sys *Daemon) ResetFailed(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.ResetFailed")
+
+	if len(names) == 0 {
+		for _, u := range sys.Units() {
+			u.ResetFailed()
+		}
+		return nil
+	}
+
+	return sys.getAndExecute(names, func(u *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		u.ResetFailed()
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package system
 import (
 	"bytes"
 	"io"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -27,6 +28,9 @@ type Log struct {
 	*log.Logger
 	*bytes.Reader
 	buffer *bytes.Buffer
+
+	// Guards the buffer and the reader, the log is written and read concurrently
+	mutex sync.Mutex
 }
 
 // NewLog returns a new log
@@ -48,14 +52,21 @@ func NewLog() (l *Log) {
 }
 
 func (l *Log) Len() (n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	return l.buffer.Len()
 }
 
 func (l *Log) Cap() (n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	return l.buffer.Cap()
 }
 
 func (l *Log) Read(b []byte) (n int, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
 	if l.Reader == nil {
 		l.Reader = bytes.NewReader(l.buffer.Bytes())
 	}
@@ -69,7 +80,11 @@ func (l *Log) Read(b []byte) (n int, err error) {
 }
 
 func (l *Log) Write(b []byte) (n int, err error) {
-	if l.Len()+len(b) <= l.Cap() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	size, capacity := l.buffer.Len(), l.buffer.Cap()
+	if size+len(b) <= capacity {
 		return l.buffer.Write(b)
 	}
 
@@ -80,12 +95,12 @@ func (l *Log) Write(b []byte) (n int, err error) {
 		}
 	}()
 
-	if len(b) >= l.Cap() {
+	if len(b) >= capacity {
 		l.buffer.Reset()
-		return l.buffer.Write(b[len(b)-l.Cap():])
+		return l.buffer.Write(b[len(b)-capacity:])
 	}
 
-	if _, err = l.buffer.Read(make([]byte, len(b)-l.Cap()+l.Len())); err != nil {
+	if _, err = l.buffer.Read(make([]byte, len(b)-capacity+size)); err != nil {
 		return 0, err
 	}
 
@@ -3,8 +3,11 @@ package system
 import (
 	"bytes"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/plasma-umass/systemgo/journal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,3 +76,69 @@ func TestRead(t *testing.T) {
 
 	assert.Equal(t, b, bTest, "ioutil.ReadAll(l) bytes read")
 }
+
+func TestJournalOutput(t *testing.T) {
+	path, err := ioutil.TempDir("", "journal-output-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	script := filepath.Join(path, "script")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo out
+echo '<4>err' >&2`), 0755), "ioutil.WriteFile")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=`+script), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+
+	messages := map[string]journal.Priority{}
+	var fields map[string]string
+	for _, rec := range sys.Journal.Records() {
+		if assert.Equal(t, "a.service", rec.Unit) {
+			messages[rec.Message] = rec.Priority
+			if rec.Fields != nil {
+				fields = rec.Fields
+			}
+		}
+	}
+	assert.Equal(t, map[string]journal.Priority{
+		"Starting...":              journal.Info,
+		"out":                      journal.Info,
+		"err":                      journal.Warning,
+		"start job finished: done": journal.Info,
+	}, messages, "output recorded with the messages of the manager")
+	assert.Equal(t, "done", fields["result"], "job result recorded")
+	assert.Equal(t, "start", fields["type"], "job type recorded")
+}
+
+func TestStatusLog(t *testing.T) {
+	path, err := ioutil.TempDir("", "status-log-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+Type=oneshot
+ExecStart=/bin/echo output of a`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+
+	u, err := sys.Unit("a.service")
+	require.NoError(t, err, "sys.Unit")
+	shown := string(u.Status().Log)
+	assert.Contains(t, shown, "a.service: output of a", "output shown in the status")
+	assert.Contains(t, shown, "a.service: Starting...", "messages of the manager shown in the status")
+}
@@ -0,0 +1,109 @@
+package system
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Metrics is a snapshot of the counters of the manager exposed for monitoring
+type Metrics struct {
+	// Time the manager started
+	Since time.Time
+
+	// Number of the jobs queued
+	JobsQueued int
+
+	// Number of the jobs finished since the manager started by type and result
+	JobsFinished map[JobOutcome]uint64
+
+	// Units loaded ordered by name
+	Units []UnitMetrics
+}
+
+// JobOutcome is the type of a job finished(e.g. "start") and its result(e.g. "done")
+type JobOutcome struct {
+	Type, Result string
+}
+
+// UnitMetrics are the counters of a unit
+type UnitMetrics struct {
+	Name   string
+	Load   unit.Load
+	Active unit.Activation
+	Sub    string
+
+	// Whether the unit failed and was not reset since
+	Failed bool
+
+	// Number of the automatic restarts of the unit since the manager started
+	Restarts uint64
+
+	// Memory(in bytes) and CPU time used by the processes of the unit, read from its cgroup, if it has one
+	Memory uint64
+	CPU    time.Duration
+}
+
+// jobCounts counts the jobs finished
+type jobCounts struct {
+	finished map[JobOutcome]uint64
+	mutex    sync.Mutex
+}
+
+// Metrics returns a snapshot of the counters of sys
+func (sys *Daemon) Metrics() (m Metrics) {
+	m.Since = sys.Since()
+
+	sys.queueMutex.Lock()
+	m.JobsQueued = len(sys.queue)
+	sys.queueMutex.Unlock()
+
+	sys.jobCounts.mutex.Lock()
+	m.JobsFinished = make(map[JobOutcome]uint64, len(sys.jobCounts.finished))
+	for outcome, n := range sys.jobCounts.finished {
+		m.JobsFinished[outcome] = n
+	}
+	sys.jobCounts.mutex.Unlock()
+
+	for _, u := range sys.Units() {
+		m.Units = append(m.Units, u.metrics())
+	}
+	sort.Slice(m.Units, func(i, j int) bool { return m.Units[i].Name < m.Units[j].Name })
+	return
+}
+
+// countJob counts a job of type typ finished with result
+func (sys *Daemon) countJob(typ, result string) {
+	sys.jobCounts.mutex.Lock()
+	defer sys.jobCounts.mutex.Unlock()
+
+	if sys.jobCounts.finished == nil {
+		sys.jobCounts.finished = map[JobOutcome]uint64{}
+	}
+	sys.jobCounts.finished[JobOutcome{typ, result}]++
+}
+
+// metrics returns the counters of u
+func (u *Unit) metrics() (m UnitMetrics) {
+	m = UnitMetrics{
+		Name:   u.Name(),
+		Load:   u.Loaded(),
+		Active: u.Active(),
+		Sub:    u.Sub(),
+		Failed: u.isFailed(),
+	}
+
+	u.mutex.Lock()
+	m.Restarts = u.restarts
+	u.mutex.Unlock()
+
+	var ok bool
+	if m.Memory, m.CPU, ok = u.cgroupUsage(); !ok {
+		if attacher, isAttacher := u.Interface.(unit.Attacher); isAttacher && attacher.MainPID() > 0 {
+			_, m.Memory, m.CPU = processTree(attacher.MainPID())
+		}
+	}
+	return
+}
@@ -0,0 +1,93 @@
+package system
+
+// JobMode specifies how the jobs of a transaction are enqueued -- https://www.freedesktop.org/software/systemd/man/systemctl.html#--job-mode=
+type JobMode string
+
+const (
+	// ReplaceMode replaces the jobs pending for units in the transaction
+	ReplaceMode JobMode = "replace"
+
+	// FailMode refuses to enqueue the transaction, if it would replace a pending job
+	// with one it can not be merged with
+	FailMode JobMode = "fail"
+
+	// IsolateMode starts the units and stops all the other ones
+	IsolateMode JobMode = "isolate"
+
+	// IgnoreDependencies enqueues only the jobs for the units requested, ordering is not honored
+	IgnoreDependencies JobMode = "ignore-dependencies"
+
+	// IgnoreRequirements enqueues only the jobs for the units requested, ordering is honored
+	IgnoreRequirements JobMode = "ignore-requirements"
+)
+
+// checkMode returns the mode to use for jobs of type typ. An empty mode is equivalent to ReplaceMode
+func checkMode(typ jobType, mode JobMode) (JobMode, error) {
+	switch mode {
+	case "":
+		return ReplaceMode, nil
+	case ReplaceMode, FailMode, IgnoreDependencies, IgnoreRequirements:
+		return mode, nil
+	case IsolateMode:
+		if typ != start {
+			return "", ErrIsolate
+		}
+		return mode, nil
+	default:
+		return "", ErrUnknownJobMode
+	}
+}
+
+// ignoresRequirements reports whether the dependencies of units are not added
+// to transactions run in mode
+func (mode JobMode) ignoresRequirements() bool {
+	return mode == IgnoreDependencies || mode == IgnoreRequirements
+}
+
+// ManualStart is like StartWith, but is meant for requests initiated by the user directly,
+// which are refused for units with RefuseManualStart= set
+func (sys *Daemon) ManualStart(mode JobMode, names ...string) (err error) {
+	if err = sys.refuseManual(start, names); err != nil {
+		return
+	}
+	return sys.StartWith(mode, names...)
+}
+
+// ManualStop is like StopWith, but is meant for requests initiated by the user directly,
+// which are refused for units with RefuseManualStop= set
+func (sys *Daemon) ManualStop(mode JobMode, names ...string) (err error) {
+	if err = sys.refuseManual(stop, names); err != nil {
+		return
+	}
+	return sys.StopWith(mode, names...)
+}
+
+// ManualRestart is like RestartWith, but is meant for requests initiated by the user directly,
+// which are refused for units with RefuseManualStart= set
+func (sys *Daemon) ManualRestart(mode JobMode, names ...string) (err error) {
+	if err = sys.refuseManual(restart, names); err != nil {
+		return
+	}
+	return sys.RestartWith(mode, names...)
+}
+
+// refuseManual returns an error, if any of units specified by names refuses jobs of type typ
+// requested manually. Units pulled in as dependencies are not checked
+func (sys *Daemon) refuseManual(typ jobType, names []string) error {
+	for _, name := range names {
+		u, err := sys.Get(name)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case typ == stop && u.RefuseManualStop():
+			u.Log.Errorf("%s: %s", typ, ErrRefuseManualStop)
+			return ErrRefuseManualStop
+		case typ != stop && u.RefuseManualStart():
+			u.Log.Errorf("%s: %s", typ, ErrRefuseManualStart)
+			return ErrRefuseManualStart
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package system
+
+import (
+	"path/filepath"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// mountDependencies returns the mount units of the paths u accesses and of the ones they are nested in,
+// which are loaded or defined in the unit paths, as RequiresMountsFor= of systemd. The mount unit of u itself is omitted
+func (u *Unit) mountDependencies() (names []string) {
+	requirer, ok := u.Interface.(unit.MountRequirer)
+	if !ok || u.System == nil {
+		return
+	}
+
+	seen := map[string]bool{u.Name(): true}
+	for _, path := range requirer.RequiresMountsFor() {
+		if !filepath.IsAbs(path) {
+			continue
+		}
+
+		for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+			if name := unit.PathUnit(p, ".mount"); !seen[name] {
+				seen[name] = true
+				if u.System.known(name) {
+					names = append(names, name)
+				}
+			}
+			if p == "/" {
+				break
+			}
+		}
+	}
+	return
+}
+
+// known reports whether the unit name is loaded or its definition is found in the unit paths
+func (sys *Daemon) known(name string) bool {
+	if u, err := sys.Unit(name); err == nil && u.IsLoaded() {
+		return true
+	}
+	return len(sys.definitionPaths(name)) > 0
+}
@@ -0,0 +1,55 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountDependencies(t *testing.T) {
+	path, err := ioutil.TempDir("", "mount-dependencies-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"srv.mount": `[Mount]
+What=/dev/sdb1
+Where=/srv`,
+		"srv-data.mount": `[Mount]
+What=/dev/sdb2
+Where=/srv/data`,
+		"opt-tools.mount": `[Mount]
+What=tools
+Where=/opt/tools
+Type=nfs
+Options=ro`,
+		"a.service": `[Unit]
+RequiresMountsFor=/opt/tools/bin
+[Service]
+WorkingDirectory=/srv/data/a
+ExecStart=/usr/bin/a`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	a, err := sys.Get("a.service")
+	require.NoError(t, err, "sys.Get")
+	for _, name := range []string{"srv-data.mount", "srv.mount", "opt-tools.mount"} {
+		assert.Contains(t, a.Requires(), name, "a.service requires")
+		assert.Contains(t, a.After(), name, "a.service is ordered after")
+	}
+	assert.NotContains(t, a.Requires(), "-.mount", "root mount not defined")
+	assert.NotContains(t, a.Requires(), "usr.mount", "/usr mount not defined")
+
+	data, err := sys.Get("srv-data.mount")
+	require.NoError(t, err, "sys.Get")
+	assert.Equal(t, []string{"srv.mount"}, data.mountDependencies(), "nested mount")
+	assert.NotContains(t, data.Requires(), "srv-data.mount", "mount requires itself")
+}
@@ -0,0 +1,215 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/pid1"
+)
+
+// Target reached once the network is online according to the checks set, as network-online.target of systemd
+const NETWORK_ONLINE_TARGET = "network-online.target"
+
+// Longest time network-online.target waits for the network to get online by default
+const DEFAULT_NETWORK_ONLINE_TIMEOUT = 2 * time.Minute
+
+// Interval the network checks are repeated at, until they pass
+var NETWORK_CHECK_INTERVAL = 500 * time.Millisecond
+
+// Routing tables of the kernel, which the default routes are looked up in
+var (
+	PROC_NET_ROUTE      = "/proc/net/route"
+	PROC_NET_IPV6_ROUTE = "/proc/net/ipv6_route"
+)
+
+// NetworkCheck reports whether the network is online in some respect, e.g. an interface is up
+type NetworkCheck func() (online bool, err error)
+
+// networkOnline are the checks, which all have to pass for network-online.target to be reached
+type networkOnline struct {
+	checks  []NetworkCheck
+	timeout time.Duration
+}
+
+// SetNetworkChecks makes network-online.target wait for all of checks to pass, but no longer than timeout.
+// With no checks the target is reached immediately
+func (sys *Daemon) SetNetworkChecks(timeout time.Duration, checks ...NetworkCheck) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.network = networkOnline{checks: checks, timeout: timeout}
+}
+
+// ParseNetworkCheck returns the check specified by spec, one of:
+//
+//	"interface" or "interface:NAME" - an interface other than loopback(or the one named) is up and running
+//	"address" or "address:NAME" - the interface has a global address, e.g. one leased by DHCP
+//	"route" - a default route is present
+//	"command:CMD ARGS..." - the command exits successfully, e.g. "command:/usr/bin/nm-online -q"
+func ParseNetworkCheck(spec string) (check NetworkCheck, err error) {
+	kind, arg := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		kind, arg = spec[:i], spec[i+1:]
+	}
+
+	switch kind {
+	case "interface":
+		return func() (bool, error) { return interfaceUp(arg, false) }, nil
+	case "address":
+		return func() (bool, error) { return interfaceUp(arg, true) }, nil
+	case "route":
+		if arg != "" {
+			break
+		}
+		return defaultRoute, nil
+	case "command":
+		fields := strings.Fields(arg)
+		if len(fields) == 0 {
+			break
+		}
+		return func() (bool, error) {
+			err := pid1.RunCommand(exec.Command(fields[0], fields[1:]...))
+			switch err.(type) {
+			case *exec.ExitError, *pid1.ExitError:
+				return false, nil
+			}
+			if err == pid1.ErrNoSuchChild || errors.Is(err, syscall.ECHILD) {
+				// The exit status of the command reaped by the init process is lost, the check gets repeated
+				return false, fmt.Errorf("%s: exit status lost", fields[0])
+			}
+			return err == nil, err
+		}, nil
+	}
+	return nil, ErrUnknownNetworkCheck
+}
+
+// waitOnline waits for the network checks of sys to pass, but no longer than the timeout set
+func (sys *Daemon) waitOnline(ctx context.Context) error {
+	sys.mutex.Lock()
+	network := sys.network
+	sys.mutex.Unlock()
+
+	if len(network.checks) == 0 {
+		return nil
+	}
+
+	if network.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, network.timeout)
+		defer cancel()
+	}
+
+	for {
+		if network.online() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				log.Warnf("Network is not online after %s", network.timeout)
+				return ErrNotOnline
+			}
+			return ctx.Err()
+		case <-time.After(NETWORK_CHECK_INTERVAL):
+		}
+	}
+}
+
+// online reports whether all the checks pass
+func (network networkOnline) online() bool {
+	for _, check := range network.checks {
+		online, err := check()
+		if err != nil {
+			log.Debugf("Error checking network: %s", err)
+		}
+		if !online {
+			return false
+		}
+	}
+	return true
+}
+
+// interfaceUp reports whether the interface name, any other than loopback if empty, is up and running.
+// If global is set, it also must have a global unicast address
+func interfaceUp(name string, global bool) (bool, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false, err
+	}
+
+	for _, iface := range ifaces {
+		switch {
+		case name != "" && iface.Name != name,
+			name == "" && iface.Flags&net.FlagLoopback != 0,
+			iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagRunning == 0:
+			continue
+		case !global:
+			return true, nil
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return false, err
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.IsGlobalUnicast() {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// defaultRoute reports whether an IPv4 or IPv6 default route via an interface other than loopback is present
+func defaultRoute() (bool, error) {
+	v4, err := hasRoute(PROC_NET_ROUTE, func(fields []string) bool {
+		// Iface Destination Gateway Flags ...
+		if len(fields) < 4 || fields[1] != "00000000" {
+			return false
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		return err == nil && flags&0x1 != 0 // RTF_UP
+	})
+	if v4 || err != nil && !os.IsNotExist(err) {
+		return v4, err
+	}
+
+	v6, err := hasRoute(PROC_NET_IPV6_ROUTE, func(fields []string) bool {
+		// Destination PrefixLength Source SourcePrefixLength NextHop Metric RefCount Use Flags Iface
+		return len(fields) >= 10 && strings.Trim(fields[0], "0") == "" && fields[1] == "00" && fields[9] != "lo"
+	})
+	if os.IsNotExist(err) {
+		// IPv6 is disabled
+		return false, nil
+	}
+	return v6, err
+}
+
+// hasRoute reports whether any of the lines of the routing table at path, split to fields, matches,
+// the routes via loopback excluded
+func hasRoute(path string, matches func(fields []string) bool) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[0] != "lo" && matches(fields) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,109 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworkCheck(t *testing.T) {
+	for _, spec := range []string{"interface", "interface:eth0", "address", "address:eth0", "route", "command:true"} {
+		check, err := ParseNetworkCheck(spec)
+		assert.NoError(t, err, spec)
+		assert.NotNil(t, check, spec)
+	}
+	for _, spec := range []string{"", "dhcp", "route:eth0", "command:"} {
+		_, err := ParseNetworkCheck(spec)
+		assert.Equal(t, ErrUnknownNetworkCheck, err, spec)
+	}
+
+	check, err := ParseNetworkCheck("command:true")
+	require.NoError(t, err)
+	online, err := check()
+	assert.NoError(t, err)
+	assert.True(t, online, "command exiting successfully")
+
+	check, err = ParseNetworkCheck("command:false")
+	require.NoError(t, err)
+	online, err = check()
+	assert.NoError(t, err)
+	assert.False(t, online, "command failing")
+}
+
+func TestDefaultRoute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	defer func(v4, v6 string) { PROC_NET_ROUTE, PROC_NET_IPV6_ROUTE = v4, v6 }(PROC_NET_ROUTE, PROC_NET_IPV6_ROUTE)
+	PROC_NET_ROUTE, PROC_NET_IPV6_ROUTE = filepath.Join(dir, "route"), filepath.Join(dir, "ipv6_route")
+
+	const header = "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\tMTU\tWindow\tIRTT\n"
+	require.NoError(t, ioutil.WriteFile(PROC_NET_ROUTE, []byte(header+
+		"eth0\t0002A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"), 0644))
+	online, err := defaultRoute()
+	assert.NoError(t, err)
+	assert.False(t, online, "local route only")
+
+	require.NoError(t, ioutil.WriteFile(PROC_NET_ROUTE, []byte(header+
+		"eth0\t00000000\t0102A8C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"), 0644))
+	online, err = defaultRoute()
+	assert.NoError(t, err)
+	assert.True(t, online, "IPv4 default route")
+
+	require.NoError(t, ioutil.WriteFile(PROC_NET_ROUTE, []byte(header), 0644))
+	require.NoError(t, ioutil.WriteFile(PROC_NET_IPV6_ROUTE, []byte(
+		"00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0\n"), 0644))
+	online, err = defaultRoute()
+	assert.NoError(t, err)
+	assert.True(t, online, "IPv6 default route")
+}
+
+func TestNetworkOnline(t *testing.T) {
+	defer func(interval time.Duration) { NETWORK_CHECK_INTERVAL = interval }(NETWORK_CHECK_INTERVAL)
+	NETWORK_CHECK_INTERVAL = 10 * time.Millisecond
+
+	var mutex sync.Mutex
+	online := false
+	check := func() (bool, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return online, nil
+	}
+
+	sys := New()
+	sys.SetPaths()
+	sys.SetNetworkChecks(time.Minute, check)
+
+	require.NoError(t, sys.Start(NETWORK_ONLINE_TARGET), "sys.Start")
+	u, err := sys.Unit(NETWORK_ONLINE_TARGET)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, unit.Activating, u.Active(), "target waiting for the network")
+
+	mutex.Lock()
+	online = true
+	mutex.Unlock()
+	waitForJobs(t, sys, NETWORK_ONLINE_TARGET)
+	assert.Equal(t, unit.Active, u.Active(), "target once online")
+
+	// Timed out
+	sys = New()
+	sys.SetPaths()
+	sys.SetNetworkChecks(50*time.Millisecond, func() (bool, error) { return false, nil })
+
+	require.NoError(t, sys.Start(NETWORK_ONLINE_TARGET), "sys.Start")
+	u, err = sys.Unit(NETWORK_ONLINE_TARGET)
+	require.NoError(t, err)
+	u.lastJob().Wait()
+	assert.Equal(t, ErrNotOnline, u.lastJob().err)
+	assert.Equal(t, unit.Failed, u.Active(), "target timed out")
+}
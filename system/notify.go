@@ -0,0 +1,156 @@
+package system
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Path to the socket the services send sd_notify(3) messages to by default
+const DEFAULT_NOTIFY_SOCKET = "/run/systemgo/notify"
+
+// Environment variable passing the path to the notification socket to the services
+const NOTIFY_SOCKET_ENV = "NOTIFY_SOCKET"
+
+// Maximum size of a notification message
+const NOTIFY_BUFFER_SIZE = 4096
+
+// notification is the state of a unit reported by its processes using sd_notify(3)
+type notification struct {
+	// Free-form status text, STATUS=
+	status string
+
+	// Error number of the failure reported, ERRNO=, 0 if none
+	errno int
+
+	// D-Bus error name of the failure reported, BUSERROR=
+	busError string
+}
+
+// ListenNotify listens for sd_notify(3) messages on the Unix datagram socket at path and passes the path to
+// the processes started afterwards, so that the services can report their status. The sender is identified by its
+// credentials, the messages from processes not belonging to any unit are ignored
+func (sys *Daemon) ListenNotify(path string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+
+	// Any process may send a notification, the services may run as other users
+	if err = os.Chmod(path, 0777); err != nil {
+		conn.Close()
+		return
+	}
+	if err = setPassCred(conn); err != nil {
+		conn.Close()
+		return
+	}
+
+	if err = os.Setenv(NOTIFY_SOCKET_ENV, path); err != nil {
+		conn.Close()
+		return
+	}
+
+	go sys.serveNotify(conn)
+	return nil
+}
+
+// serveNotify receives the messages on conn, until it is closed
+func (sys *Daemon) serveNotify(conn *net.UnixConn) {
+	b := make([]byte, NOTIFY_BUFFER_SIZE)
+	oob := make([]byte, credentialsSpace)
+	for {
+		n, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+		if err != nil {
+			log.Errorf("Error receiving notification: %s", err)
+			return
+		}
+
+		pid := senderPID(oob[:oobn])
+		if pid <= 0 {
+			log.Debugf("Notification without credentials ignored")
+			continue
+		}
+		sys.Notify(pid, string(b[:n]))
+	}
+}
+
+// Notify applies the sd_notify(3) message msg sent by the process pid to the unit it belongs to.
+// STATUS=, ERRNO= and BUSERROR= are recorded, the other assignments are ignored
+func (sys *Daemon) Notify(pid int, msg string) {
+	u := sys.unitOf(pid)
+	if u == nil {
+		log.WithField("pid", pid).Debugf("Notification from process not belonging to any unit ignored")
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for _, line := range strings.Split(msg, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch key, value := parts[0], parts[1]; key {
+		case "STATUS":
+			u.notification.status = value
+		case "ERRNO":
+			if errno, err := strconv.Atoi(value); err == nil && errno >= 0 {
+				u.notification.errno = errno
+			}
+		case "BUSERROR":
+			u.notification.busError = value
+		default:
+			log.WithFields(log.Fields{
+				"unit": u.Name(),
+				"key":  key,
+			}).Debugf("Notification assignment ignored")
+		}
+	}
+}
+
+// unitOf returns the unit the process pid belongs to, nil if there is none.
+// Rather than listing the processes of every unit, the ancestors of pid are matched against the main, control
+// and grouped processes of the units, then its cgroup against the ones of the units
+func (sys *Daemon) unitOf(pid int) *Unit {
+	units := sys.Units()
+	roots := make(map[int]*Unit, len(units))
+	for _, u := range units {
+		for _, root := range u.roots() {
+			roots[root] = u
+		}
+	}
+
+	for p := pid; p > 0; {
+		if u, ok := roots[p]; ok {
+			return u
+		}
+		st, err := readStat(p)
+		if err != nil {
+			break
+		}
+		p = st.PPID
+	}
+
+	if cgroup := cgroupOf(pid); cgroup != "" {
+		for _, u := range units {
+			if u.cgroupPath() == cgroup {
+				return u
+			}
+		}
+	}
+	return nil
+}
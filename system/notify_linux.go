@@ -0,0 +1,37 @@
+package system
+
+import (
+	"net"
+	"syscall"
+)
+
+// Size of the control message holding the credentials of the sender of a notification
+var credentialsSpace = syscall.CmsgSpace(syscall.SizeofUcred)
+
+// setPassCred makes the credentials of the senders get received along with the messages on conn
+func setPassCred(conn *net.UnixConn) (err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	if cerr := raw.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	}); cerr != nil {
+		return cerr
+	}
+	return
+}
+
+// senderPID returns the PID of the sender found among the control messages oob, 0 if not found
+func senderPID(oob []byte) int {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0
+	}
+	for _, msg := range msgs {
+		if cred, err := syscall.ParseUnixCredentials(&msg); err == nil {
+			return int(cred.Pid)
+		}
+	}
+	return 0
+}
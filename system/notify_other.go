@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package system
+
+import (
+	"net"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// The credentials of the senders are only received on Linux
+var credentialsSpace = 0
+
+// setPassCred returns unit.ErrNotSupported, the senders of notifications can only be identified on Linux
+func setPassCred(conn *net.UnixConn) error {
+	return unit.ErrNotSupported
+}
+
+// senderPID returns 0, the credentials are only received on Linux
+func senderPID(oob []byte) int {
+	return 0
+}
@@ -0,0 +1,66 @@
+package system
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	path, err := ioutil.TempDir("", "notify-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	sys := New()
+
+	// The test process is the process of the scope, hence the scope must never be stopped
+	require.NoError(t, sys.StartTransient(ReplaceMode, TransientUnit{Name: "n.scope", Properties: []unit.Property{
+		{Name: "DefaultDependencies", Value: "no"},
+		{Name: "PIDs", Value: strconv.Itoa(os.Getpid())},
+	}}), "sys.StartTransient")
+	waitForJobs(t, sys, "n.scope")
+
+	socket := filepath.Join(path, "notify")
+	require.NoError(t, sys.ListenNotify(socket), "sys.ListenNotify")
+	defer os.Unsetenv(NOTIFY_SOCKET_ENV)
+	assert.Equal(t, socket, os.Getenv(NOTIFY_SOCKET_ENV))
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err, "net.DialUnix")
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("READY=1\nSTATUS=Processing requests\nERRNO=2\nBUSERROR=org.freedesktop.DBus.Error.Failed"))
+	require.NoError(t, err, "conn.Write")
+
+	s, err := sys.Unit("n.scope")
+	require.NoError(t, err)
+
+	timeout := time.After(5 * time.Second)
+	for s.Status().StatusText == "" {
+		select {
+		case <-timeout:
+			t.Fatal("Notification not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	st := s.Status()
+	assert.Equal(t, "Processing requests", st.StatusText)
+	assert.Equal(t, int(syscall.ENOENT), st.StatusErrno)
+	assert.Equal(t, "org.freedesktop.DBus.Error.Failed", st.StatusBusError)
+
+	// Fields not sent are kept
+	sys.Notify(os.Getpid(), "STATUS=Idle")
+	st = s.Status()
+	assert.Equal(t, "Idle", st.StatusText)
+	assert.Equal(t, int(syscall.ENOENT), st.StatusErrno)
+}
@@ -0,0 +1,212 @@
+package system
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Default paths to search for preset files in order of precedence
+var DEFAULT_PRESET_PATHS = []string{"/etc/systemgo/system-preset", "/run/systemgo/system-preset", "/usr/lib/systemgo/system-preset"}
+
+// Presets is an ordered list of preset rules -- https://www.freedesktop.org/software/systemd/man/systemd.preset.html
+type Presets []presetRule
+
+type presetRule struct {
+	pattern string
+	enable  bool
+}
+
+// ReadPresets parses '*.preset' files found in paths specified.
+// Files are read in lexical order of their names, a file found in a path searched
+// first shadows the ones with the same name in paths searched later
+func ReadPresets(paths ...string) (presets Presets, err error) {
+	found := map[string]string{}
+	for _, path := range paths {
+		files, err := filepath.Glob(filepath.Join(path, "*.preset"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if _, ok := found[filepath.Base(file)]; !ok {
+				found[filepath.Base(file)] = file
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var rules Presets
+		if rules, err = readPresetFile(found[name]); err != nil {
+			return nil, err
+		}
+		presets = append(presets, rules...)
+	}
+	return
+}
+
+func readPresetFile(path string) (rules Presets, err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+
+		if len(fields) != 2 || fields[0] != "enable" && fields[0] != "disable" {
+			log.WithFields(log.Fields{
+				"file": path,
+				"line": line,
+			}).Warn("Ignoring invalid preset rule")
+			continue
+		}
+
+		rules = append(rules, presetRule{
+			pattern: fields[1],
+			enable:  fields[0] == "enable",
+		})
+	}
+	return rules, scanner.Err()
+}
+
+// Enabled returns whether unit name should be enabled according to presets.
+// The first rule, which pattern matches name, applies. If none match - the unit should be enabled
+func (presets Presets) Enabled(name string) bool {
+	for _, rule := range presets {
+		if ok, _ := filepath.Match(rule.pattern, name); ok {
+			return rule.enable
+		}
+	}
+	return true
+}
+
+// PresetPaths returns paths, which get searched for preset files by sys(first path gets searched first)
+func (sys *Daemon) PresetPaths() (paths []string) {
+	sys.pathsMutex.RLock()
+	defer sys.pathsMutex.RUnlock()
+
+	return sys.presetPaths
+}
+
+// SetPresetPaths sets paths, which get searched for preset files by sys(first path gets searched first)
+func (sys *Daemon) SetPresetPaths(paths ...string) {
+	sys.pathsMutex.Lock()
+	sys.presetPaths = paths
+	sys.pathsMutex.Unlock()
+
+	sys.presets.reset()
+}
+
+// presetCache holds the presets read once, so that the statuses of the units do not read the preset files each time
+type presetCache struct {
+	presets Presets
+	err     error
+	read    bool
+
+	mutex sync.Mutex
+}
+
+// vendorPresets returns the presets found in the preset paths of sys as of the last reload
+func (sys *Daemon) vendorPresets() (presets Presets, err error) {
+	c := &sys.presets
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.read {
+		c.presets, c.err = ReadPresets(sys.PresetPaths()...)
+		c.read = true
+	}
+	return c.presets, c.err
+}
+
+// reset makes the preset files get read anew
+func (c *presetCache) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.presets, c.err, c.read = nil, nil, false
+}
+
+// Preset enables or disables units specified by name according to the preset files
+func (sys *Daemon) Preset(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Preset")
+
+	var presets Presets
+	if presets, err = ReadPresets(sys.PresetPaths()...); err != nil {
+		return
+	}
+
+	return sys.getAndExecute(names, func(u *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		return u.preset(presets)
+	})
+}
+
+// PresetAll enables or disables all units found in the paths of sys according to the preset files.
+// The definitions are parsed without loading the units, masked units and aliases are skipped.
+// Units which fail to load are skipped, the errors encountered get returned as unit.MultiError
+func (sys *Daemon) PresetAll() (err error) {
+	log.Debugf("sys.PresetAll")
+
+	var presets Presets
+	if presets, err = ReadPresets(sys.PresetPaths()...); err != nil {
+		return
+	}
+
+	merr := unit.MultiError{}
+	for _, name := range sys.unitFiles() {
+		if _, ok := sys.aliasOf(name); ok {
+			// Preset along with the unit it is an alias of
+			continue
+		}
+
+		var u *Unit
+		switch u, err = sys.peek(name); err {
+		case nil:
+			err = u.preset(presets)
+		case ErrMasked:
+			continue
+		}
+		if err != nil {
+			merr = append(merr, unit.ParseErr(name, err))
+		}
+	}
+
+	if len(merr) > 0 {
+		return merr
+	}
+	return nil
+}
+
+func (u *Unit) preset(presets Presets) (err error) {
+	switch u.Enabled() {
+	case unit.Static, unit.Indirect:
+		return nil
+	}
+
+	if presets.Enabled(u.Name()) {
+		return u.Enable()
+	}
+	return u.Disable()
+}
@@ -0,0 +1,106 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPresets(t *testing.T) {
+	etc, err := ioutil.TempDir("", "preset-etc")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(etc)
+
+	lib, err := ioutil.TempDir("", "preset-lib")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(lib)
+
+	for path, contents := range map[string]string{
+		filepath.Join(lib, "90-default.preset"): `# comment
+disable *`,
+		filepath.Join(lib, "50-vendor.preset"): `enable shadowed.service`,
+		filepath.Join(etc, "50-vendor.preset"): `; comment
+enable foo.service
+enable getty@*.service
+invalid rule here`,
+		filepath.Join(etc, "10-admin.preset"): `disable getty@tty2.service`,
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	presets, err := ReadPresets(etc, lib)
+	require.NoError(t, err, "ReadPresets")
+
+	for name, enabled := range map[string]bool{
+		"foo.service":        true,
+		"getty@tty1.service": true,
+		"getty@tty2.service": false,
+		"shadowed.service":   false,
+		"bar.service":        false,
+	} {
+		assert.Equal(t, enabled, presets.Enabled(name), name)
+	}
+
+	assert.True(t, Presets{}.Enabled("foo.service"), "no presets")
+}
+
+func TestPresetAll(t *testing.T) {
+	etc, err := ioutil.TempDir("", "preset-all-etc")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(etc)
+
+	lib, err := ioutil.TempDir("", "preset-all-lib")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(lib)
+
+	presets, err := ioutil.TempDir("", "preset-all-presets")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(presets)
+
+	for name := range map[string]bool{"foo.service": true, "bar.service": true, "masked.service": true} {
+		err = ioutil.WriteFile(filepath.Join(lib, name), []byte(`[Service]
+ExecStart=/bin/true
+[Install]
+WantedBy=multi-user.target`), 0666)
+		require.NoError(t, err, "ioutil.WriteFile")
+	}
+	require.NoError(t, os.Symlink("/dev/null", filepath.Join(etc, "masked.service")), "os.Symlink")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(presets, "00-test.preset"), []byte("disable bar.service"), 0666))
+
+	sys := New()
+	sys.SetPaths(etc, lib)
+	sys.SetPresetPaths(presets)
+
+	require.NoError(t, sys.Enable("bar.service"), "sys.Enable")
+	require.NoError(t, sys.PresetAll(), "sys.PresetAll")
+
+	for name, expected := range map[string]unit.Enable{
+		"foo.service": unit.Enabled,
+		"bar.service": unit.Disabled,
+	} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, expected, st, name)
+
+		u, err := sys.Get(name)
+		require.NoError(t, err, "sys.Get")
+		assert.Equal(t, expected, u.Status().Load.Vendor, "vendor preset of "+name)
+	}
+
+	_, err = os.Lstat(filepath.Join(etc, "multi-user.target.wants", "masked.service"))
+	assert.True(t, os.IsNotExist(err), "masked unit enabled")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(presets, "00-test.preset"), []byte("disable foo.service"), 0666))
+	foo, _ := sys.Unit("foo.service")
+	bar, _ := sys.Unit("bar.service")
+	assert.Equal(t, unit.Enabled, foo.Status().Load.Vendor, "presets read until reloaded")
+
+	sys.DaemonReload()
+	assert.Equal(t, unit.Disabled, foo.Status().Load.Vendor, "presets reloaded")
+	assert.Equal(t, unit.Enabled, bar.Status().Load.Vendor, "presets reloaded")
+}
@@ -0,0 +1,162 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Path to the proc filesystem
+var PROC_PATH = "/proc"
+
+// Clock ticks per second, in which the CPU times are reported by the proc filesystem
+const clockTicks = 100
+
+// procStat is the part of /proc/<pid>/stat of interest
+type procStat struct {
+	unit.Process
+
+	// CPU time in clock ticks and resident set size in pages
+	ticks uint64
+	rss   uint64
+}
+
+// readStat reads the stat of the process pid
+func readStat(pid int) (st procStat, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(PROC_PATH, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return
+	}
+
+	// The name is parenthesized and may contain spaces and parentheses itself
+	open, end := bytes.IndexByte(b, '('), bytes.LastIndexByte(b, ')')
+	if open < 0 || end < open {
+		return st, ErrMalformedStat
+	}
+	st.PID = pid
+	st.Command = string(b[open+1 : end])
+
+	// Fields following the name, starting with the state
+	fields := strings.Fields(string(b[end+1:]))
+	if len(fields) < 22 {
+		return st, ErrMalformedStat
+	}
+
+	if st.PPID, err = strconv.Atoi(fields[1]); err != nil {
+		return
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	st.ticks = utime + stime
+	st.rss, _ = strconv.ParseUint(fields[21], 10, 64)
+
+	if cmdline, err := ioutil.ReadFile(filepath.Join(PROC_PATH, strconv.Itoa(pid), "cmdline")); err == nil && len(cmdline) > 0 {
+		st.Command = strings.TrimSpace(string(bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)))
+	}
+	return st, nil
+}
+
+// processTree returns the process pid and its descendants, parents before children,
+// along with the resident memory(in bytes) and CPU time used by them
+func processTree(pid int) (procs []unit.Process, memory uint64, cpu time.Duration) {
+	root, err := readStat(pid)
+	if err != nil {
+		return []unit.Process{{PID: pid}}, 0, 0
+	}
+
+	children := map[int][]procStat{}
+	if dir, err := ioutil.ReadDir(PROC_PATH); err == nil {
+		for _, info := range dir {
+			child, err := strconv.Atoi(info.Name())
+			if err != nil || child == pid {
+				continue
+			}
+
+			st, err := readStat(child)
+			if err != nil {
+				continue
+			}
+			children[st.PPID] = append(children[st.PPID], st)
+		}
+	}
+	return walkTree([]procStat{root}, children)
+}
+
+// cgroupTree returns the processes pids ordered as a forest, parents before children,
+// along with the resident memory(in bytes) and CPU time used by them.
+// The processes, the parents of which are not among pids, are the roots
+func cgroupTree(pids []int) (procs []unit.Process, memory uint64, cpu time.Duration) {
+	stats := map[int]procStat{}
+	for _, pid := range pids {
+		if st, err := readStat(pid); err == nil {
+			stats[pid] = st
+		}
+	}
+
+	var roots []procStat
+	children := map[int][]procStat{}
+	for _, st := range stats {
+		if _, ok := stats[st.PPID]; ok && st.PPID != st.PID {
+			children[st.PPID] = append(children[st.PPID], st)
+		} else {
+			roots = append(roots, st)
+		}
+	}
+	return walkTree(roots, children)
+}
+
+// walkTree returns the processes roots and their descendants found in children by the parent PID,
+// parents before children and siblings ordered by PID, along with the resident memory(in bytes) and CPU time used by them
+func walkTree(roots []procStat, children map[int][]procStat) (procs []unit.Process, memory uint64, cpu time.Duration) {
+	byPID := func(procs []procStat) {
+		sort.Slice(procs, func(i, k int) bool {
+			return procs[i].PID < procs[k].PID
+		})
+	}
+	byPID(roots)
+	for _, procs := range children {
+		byPID(procs)
+	}
+
+	var ticks uint64
+	var walk func(st procStat)
+	walk = func(st procStat) {
+		procs = append(procs, st.Process)
+		memory += st.rss * uint64(os.Getpagesize())
+		ticks += st.ticks
+
+		for _, child := range children[st.PID] {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+
+	return procs, memory, time.Duration(ticks) * time.Second / clockTicks
+}
+
+// processes returns the processes of u, parents before children, along with the resident memory(in bytes)
+// and CPU time used by them. These are the processes in the cgroup of u, if it has one, otherwise
+// the main and control processes given and their descendants
+func (u *Unit) processes(main, control int) (procs []unit.Process, memory uint64, cpu time.Duration) {
+	if pids := u.cgroupProcs(); len(pids) > 0 {
+		return cgroupTree(pids)
+	}
+
+	for _, pid := range []int{main, control} {
+		if pid > 0 {
+			tree, treeMemory, treeCPU := processTree(pid)
+			procs, memory, cpu = append(procs, tree...), memory+treeMemory, cpu+treeCPU
+		}
+	}
+	return
+}
@@ -0,0 +1,95 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTree(t *testing.T) {
+	cmd := exec.Command("/bin/sleep", "1000")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	procs, memory, _ := processTree(os.Getpid())
+	require.NotEmpty(t, procs)
+	assert.Equal(t, os.Getpid(), procs[0].PID, "root first")
+	found := false
+	for _, p := range procs {
+		if p.PID == cmd.Process.Pid {
+			found = true
+			assert.Equal(t, os.Getpid(), p.PPID, "child")
+		}
+	}
+	assert.True(t, found, "child listed")
+	assert.NotZero(t, memory)
+
+	path, err := ioutil.TempDir("", "proc-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(path string) {
+		PROC_PATH = path
+	}(PROC_PATH)
+	PROC_PATH = path
+
+	require.NoError(t, os.Mkdir(filepath.Join(path, "42"), 0755), "os.Mkdir")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "42", "stat"),
+		[]byte("42 (a (b) c) S 1 42 42 0 -1 4194560 100 0 0 0 150 50 0 0 20 0 1 0 100 1000 3 0 0"), 0666), "ioutil.WriteFile")
+
+	procs, memory, cpu := processTree(42)
+	assert.Equal(t, []unit.Process{{PID: 42, PPID: 1, Command: "a (b) c"}}, procs, "name with parentheses")
+	assert.Equal(t, uint64(3*os.Getpagesize()), memory)
+	assert.Equal(t, "2s", cpu.String())
+
+	procs, _, _ = processTree(43)
+	assert.Equal(t, []unit.Process{{PID: 43}}, procs, "process not found")
+}
+
+func TestCgroupProcesses(t *testing.T) {
+	path, err := ioutil.TempDir("", "cgroup-proc-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(proc, cgroup string) {
+		PROC_PATH, CGROUP_PATH = proc, cgroup
+	}(PROC_PATH, CGROUP_PATH)
+	PROC_PATH, CGROUP_PATH = filepath.Join(path, "proc"), filepath.Join(path, "cgroup")
+
+	for pid, stat := range map[string]string{
+		"42": "42 (main) S 1 42 42 0 -1 4194560 100 0 0 0 150 50 0 0 20 0 1 0 100 1000 3 0 0",
+		"43": "43 (child) S 42 42 42 0 -1 4194560 100 0 0 0 50 50 0 0 20 0 1 0 100 1000 1 0 0",
+		"44": "44 (daemon) S 1 44 44 0 -1 4194560 100 0 0 0 0 0 0 0 20 0 1 0 100 1000 1 0 0",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(PROC_PATH, pid), 0755), "os.MkdirAll")
+		require.NoError(t, ioutil.WriteFile(filepath.Join(PROC_PATH, pid, "stat"), []byte(stat), 0666), "ioutil.WriteFile")
+	}
+
+	u := NewUnit(&Target{})
+	u.name = "a.service"
+
+	// Without a cgroup the main process and its descendants are the processes of the unit
+	procs, memory, cpu := u.processes(42, 0)
+	assert.Equal(t, []unit.Process{{PID: 42, PPID: 1, Command: "main"}, {PID: 43, PPID: 42, Command: "child"}}, procs)
+	assert.Equal(t, uint64(4*os.Getpagesize()), memory)
+	assert.Equal(t, "3s", cpu.String())
+
+	require.NoError(t, os.MkdirAll(u.cgroupPath(), 0755), "os.MkdirAll")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(u.cgroupPath(), "cgroup.procs"), []byte("43\n44\n42\n"), 0666), "ioutil.WriteFile")
+
+	// The process reparented to init is in the cgroup still
+	procs, memory, _ = u.processes(42, 0)
+	assert.Equal(t, []unit.Process{
+		{PID: 42, PPID: 1, Command: "main"},
+		{PID: 43, PPID: 42, Command: "child"},
+		{PID: 44, PPID: 1, Command: "daemon"},
+	}, procs)
+	assert.Equal(t, uint64(5*os.Getpagesize()), memory)
+}
@@ -0,0 +1,105 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Prefix of the drop-in files written by SetProperty
+const propertyDropInPrefix = "50-"
+
+// SetProperty changes the properties of the unit name loaded and applies the resource controls to its cgroup immediately.
+// If runtime is true, the properties are kept until the manager exits, otherwise they are written to drop-in files
+// in the path with the highest precedence
+func (sys *Daemon) SetProperty(name string, runtime bool, props ...unit.Property) (err error) {
+	log.WithFields(log.Fields{
+		"name":    name,
+		"runtime": runtime,
+		"props":   props,
+	}).Debugf("sys.SetProperty")
+
+	u, err := sys.Get(name)
+	if err != nil {
+		return
+	}
+	if !u.IsLoaded() {
+		return ErrNotLoaded
+	}
+
+	setter, ok := u.Interface.(unit.PropertySetter)
+	if !ok {
+		return unit.ErrNotSupported
+	}
+
+	u.definition.Lock()
+	for _, prop := range props {
+		if err = setter.SetProperty(prop.Name, prop.Value); err != nil {
+			break
+		}
+	}
+	u.definition.Unlock()
+	if err != nil {
+		return
+	}
+
+	if runtime {
+		u.mutex.Lock()
+		u.properties = append(u.properties, props...)
+		u.mutex.Unlock()
+	} else if err = sys.writeProperties(u, props); err != nil {
+		return
+	}
+
+	u.Log.Printf("Properties set: %v", props)
+	return u.applyResources()
+}
+
+// writeProperties writes each of props into a drop-in file of u in the path with the highest precedence
+func (sys *Daemon) writeProperties(u *Unit, props []unit.Property) (err error) {
+	path, err := sys.firstPath()
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Join(path, u.Name()+".d")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	for _, prop := range props {
+		section := unit.DirectiveSection(u.Interface, prop.Name)
+		if section == "" {
+			return unit.ErrUnknownDirective
+		}
+
+		path := filepath.Join(dir, propertyDropInPrefix+prop.Name+".conf")
+		if err = ioutil.WriteFile(path, []byte(fmt.Sprintf("[%s]\n%s=%s\n", section, prop.Name, prop.Value)), 0644); err != nil {
+			return
+		}
+	}
+
+	u.dropIns = sys.dropInPaths(u.Name())
+	return nil
+}
+
+// setRuntimeProperties sets the properties set at runtime on u again, once its definition has been loaded
+func (u *Unit) setRuntimeProperties() {
+	u.mutex.Lock()
+	props := u.properties
+	u.mutex.Unlock()
+
+	setter, ok := u.Interface.(unit.PropertySetter)
+	if !ok {
+		return
+	}
+	for _, prop := range props {
+		if err := setter.SetProperty(prop.Name, prop.Value); err != nil {
+			u.Log.Errorf("Error setting property %s: %s", prop.Name, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProperty(t *testing.T) {
+	path, err := ioutil.TempDir("", "property-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(orig string) {
+		CGROUP_PATH = orig
+	}(CGROUP_PATH)
+	CGROUP_PATH = filepath.Join(path, "cgroup")
+
+	units := filepath.Join(path, "units")
+	require.NoError(t, os.Mkdir(units, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(units, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 1000`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(units)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+	defer sys.Stop("a.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+
+	_, err = os.Stat(a.cgroupPath())
+	assert.True(t, os.IsNotExist(err), "no cgroup without resource controls")
+
+	require.NoError(t, sys.SetProperty("a.service", true,
+		unit.Property{Name: "CPUQuota", Value: "20%"},
+		unit.Property{Name: "MemoryMax", Value: "64M"},
+	), "sys.SetProperty")
+
+	for file, expected := range map[string]string{
+		"cpu.max":      "20000 100000",
+		"memory.max":   strconv.Itoa(64 << 20),
+		"pids.max":     "max",
+		"cgroup.procs": strconv.Itoa(a.Status().MainPID),
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(a.cgroupPath(), file))
+		if assert.NoError(t, err, file) {
+			assert.Equal(t, expected, string(b), file)
+		}
+	}
+	assert.Empty(t, a.DropIns(), "runtime properties not persisted")
+
+	sys.DaemonReload()
+	assert.Equal(t, unit.Resources{CPUQuota: 0.2, MemoryMax: 64 << 20}, a.Interface.(unit.ResourceController).Resources(),
+		"runtime properties kept on reload")
+
+	require.NoError(t, sys.SetProperty("a.service", false, unit.Property{Name: "Restart", Value: "always"}), "sys.SetProperty")
+	if assert.Len(t, a.DropIns(), 1) {
+		b, err := ioutil.ReadFile(a.DropIns()[0])
+		if assert.NoError(t, err) {
+			assert.Equal(t, "[Service]\nRestart=always\n", string(b))
+		}
+	}
+
+	// The service process killed is restarted
+	pid := a.Status().MainPID
+	require.NoError(t, syscall.Kill(pid, syscall.SIGKILL))
+	sys.Reaped(pid, syscall.WaitStatus(syscall.SIGKILL))
+
+	timeout := time.After(5 * time.Second)
+	for a.Status().MainPID == pid || a.Status().MainPID == 0 {
+		select {
+		case <-timeout:
+			t.Fatal("Service not restarted")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	assert.Error(t, sys.SetProperty("a.service", true, unit.Property{Name: "ExecStart", Value: "/bin/true"}), "not settable")
+	assert.Equal(t, ErrNotFound, sys.SetProperty("missing.service", true))
+}
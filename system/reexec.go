@@ -0,0 +1,262 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// STATE_ENV is the environment variable, which holds the path to the state file on re-execution
+const STATE_ENV = "SYSTEMGO_STATE"
+
+// FDS_ENV is the environment variable, which holds the file descriptors inherited on re-execution
+// along with the units listening on them, e.g. "3=foo.socket,4=foo.socket"
+const FDS_ENV = "SYSTEMGO_FDS"
+
+// Snapshot is the runtime state of the manager preserved across re-execution.
+// The listening sockets of the units are not part of it, they are inherited as described by FDS_ENV
+type Snapshot struct {
+	Since time.Time
+
+	// ID of the job committed last
+	LastJobID uint64
+
+	Units []UnitSnapshot
+	Jobs  []JobInfo
+}
+
+// UnitSnapshot is the runtime state of a unit preserved across re-execution
+type UnitSnapshot struct {
+	Name string
+
+	// PID of the main process of the unit, 0 if there is none
+	MainPID int
+}
+
+// Snapshot returns the runtime state of sys.
+// Only units loaded from unit files can be restored, hence the ones supervised directly are omitted
+func (sys *Daemon) Snapshot() (snap Snapshot) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	snap = Snapshot{
+		Since: sys.since,
+		Units: []UnitSnapshot{},
+		Jobs:  sys.ListJobs(),
+	}
+
+	sys.queueMutex.Lock()
+	snap.LastJobID = sys.lastJobID
+	sys.queueMutex.Unlock()
+
+	for _, u := range sys.Units() {
+		if u.path == "" || u.IsDead() {
+			continue
+		}
+
+		us := UnitSnapshot{Name: u.Name()}
+		if attacher, ok := u.Interface.(unit.Attacher); ok {
+			us.MainPID = attacher.MainPID()
+		}
+		snap.Units = append(snap.Units, us)
+	}
+	return
+}
+
+// Serialize writes the runtime state of sys to w
+func (sys *Daemon) Serialize(w io.Writer) (err error) {
+	return json.NewEncoder(w).Encode(sys.Snapshot())
+}
+
+// Deserialize restores the runtime state of sys read from r.
+// Units are loaded again and attached to their main processes, the units listening on the sockets
+// inherited, as found at FDS_ENV, adopt them and the jobs queued get enqueued anew
+func (sys *Daemon) Deserialize(r io.Reader) (err error) {
+	log.Debugf("sys.Deserialize")
+
+	var snap Snapshot
+	if err = json.NewDecoder(r).Decode(&snap); err != nil {
+		return
+	}
+
+	sys.since = snap.Since
+
+	sys.queueMutex.Lock()
+	sys.lastJobID = snap.LastJobID
+	sys.queueMutex.Unlock()
+
+	for _, us := range snap.Units {
+		u, err := sys.Get(us.Name)
+		if err != nil {
+			log.Errorf("Error loading %s: %s", us.Name, err)
+			continue
+		}
+
+		if us.MainPID == 0 {
+			continue
+		}
+
+		attacher, ok := u.Interface.(unit.Attacher)
+		if !ok {
+			u.Log.Errorf("Unable to attach to process %d", us.MainPID)
+			continue
+		}
+		if err = attacher.Attach(us.MainPID); err != nil {
+			u.Log.Errorf("Error attaching to process %d: %s", us.MainPID, err)
+		}
+	}
+
+	// The sockets get adopted before the jobs, which could open them anew, are enqueued
+	if spec := os.Getenv(FDS_ENV); spec != "" {
+		os.Unsetenv(FDS_ENV)
+		sys.adoptFiles(spec)
+	}
+
+	for _, info := range snap.Jobs {
+		typ, ok := parseJobType(info.Type)
+		if !ok {
+			log.Errorf("Unknown type %s of job %d", info.Type, info.ID)
+			continue
+		}
+
+		if err := sys.run(typ, ReplaceMode, []string{info.Unit}); err != nil {
+			log.Errorf("Error enqueuing %s job for %s: %s", info.Type, info.Unit, err)
+		}
+	}
+	return nil
+}
+
+// DaemonReexec serializes the runtime state of sys to a file and replaces the running process
+// with a new instance of the executable, which is expected to restore the state from the file
+// found at STATE_ENV. DaemonReexec only returns on failure
+func (sys *Daemon) DaemonReexec() (err error) {
+	log.Debugf("sys.DaemonReexec")
+
+	path, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	f, err := ioutil.TempFile("", "systemgo-state")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = sys.Serialize(f); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	env := append(os.Environ(), STATE_ENV+"="+f.Name())
+	spec, passed := sys.passFiles()
+	if spec != "" {
+		env = append(env, FDS_ENV+"="+spec)
+	}
+
+	log.Infof("Re-executing %s", path)
+	err = syscall.Exec(path, os.Args, env)
+	for _, f := range passed {
+		syscall.CloseOnExec(int(f.Fd()))
+	}
+	return err
+}
+
+// passFiles makes the listening sockets of the units of sys inherited on exec and returns the value of FDS_ENV
+// describing them along with the files passed
+func (sys *Daemon) passFiles() (spec string, passed []*os.File) {
+	var pairs []string
+	for _, u := range sys.Units() {
+		listener, ok := u.Interface.(unit.Listener)
+		if !ok || u.path == "" {
+			continue
+		}
+
+		for _, f := range listener.Files() {
+			fd := f.Fd()
+			if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFD, 0); errno != 0 {
+				u.Log.Errorf("Error passing file descriptor %d: %s", fd, errno)
+				continue
+			}
+			pairs = append(pairs, fmt.Sprintf("%d=%s", fd, u.Name()))
+			passed = append(passed, f)
+		}
+	}
+	return strings.Join(pairs, ","), passed
+}
+
+// adoptFiles makes the units listed in spec, the value of FDS_ENV, listen on the file descriptors inherited
+func (sys *Daemon) adoptFiles(spec string) {
+	var names []string
+	files := map[string][]*os.File{}
+	for _, pair := range strings.Split(spec, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i < 0 {
+			log.Errorf("Invalid file descriptor passed: %q", pair)
+			continue
+		}
+		fd, err := strconv.Atoi(pair[:i])
+		if err != nil {
+			log.Errorf("Invalid file descriptor passed: %q", pair)
+			continue
+		}
+		syscall.CloseOnExec(fd)
+
+		name := pair[i+1:]
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+		files[name] = append(files[name], os.NewFile(uintptr(fd), name))
+	}
+
+	for _, name := range names {
+		u, err := sys.Get(name)
+		if err != nil {
+			log.Errorf("Error loading %s: %s", name, err)
+			closeFiles(files[name])
+			continue
+		}
+
+		listener, ok := u.Interface.(unit.Listener)
+		if !ok {
+			u.Log.Errorf("Unable to adopt the sockets passed")
+			closeFiles(files[name])
+			continue
+		}
+		u.Log.Debugf("Adopting %d sockets", len(files[name]))
+		listener.Adopt(files[name])
+	}
+}
+
+// closeFiles closes files
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// parseJobType returns the job type named s
+func parseJobType(s string) (typ jobType, ok bool) {
+	for typ = start; typ <= restart; typ++ {
+		if typ.String() == s {
+			return typ, true
+		}
+	}
+	return
+}
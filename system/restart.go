@@ -0,0 +1,34 @@
+package system
+
+import (
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// scheduleRestart restarts u after RestartSec=, if its main process has exited and is to be restarted according to Restart=
+func (u *Unit) scheduleRestart() {
+	restarter, ok := u.Interface.(unit.AutoRestarter)
+	if !ok || u.System == nil || !restarter.AutoRestart() {
+		return
+	}
+
+	delay := restarter.RestartSec()
+	u.Log.Printf("Scheduled restart in %s", delay)
+
+	time.AfterFunc(delay, func() {
+		// The unit may have been stopped or started meanwhile
+		if !restarter.AutoRestart() || u.jobRunning() {
+			return
+		}
+
+		if err := u.System.RestartWith(ReplaceMode, u.Name()); err != nil {
+			u.Log.Errorf("Error restarting: %s", err)
+			return
+		}
+
+		u.mutex.Lock()
+		u.restarts++
+		u.mutex.Unlock()
+	})
+}
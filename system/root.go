@@ -0,0 +1,77 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Most symlinks followed resolving a path inside the root, as the kernel does
+const MAX_SYMLINKS = 40
+
+// SetRoot makes sys operate on the unit files of the image mounted at root, e.g. while building it.
+// The paths of sys must be set under root, the symlinks created by Enable point to the paths inside the image
+func (sys *Daemon) SetRoot(root string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.root = filepath.Clean(root)
+}
+
+// inRoot returns path as seen inside the root of sys
+func (sys *Daemon) inRoot(path string) string {
+	if sys.root == "" || sys.root == "/" {
+		return path
+	}
+	if rel, err := filepath.Rel(sys.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("/", rel)
+	}
+	return path
+}
+
+// evalSymlinks returns path with the symlinks in it resolved as inside the root of sys,
+// so that the absolute links found in the image point into the image rather than to the host
+func (sys *Daemon) evalSymlinks(path string) (string, error) {
+	rel, err := filepath.Rel(sys.root, path)
+	if sys.root == "" || sys.root == "/" || err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.EvalSymlinks(path)
+	}
+
+	resolved, remaining := "/", strings.Split(rel, "/")
+	for links := 0; len(remaining) > 0; {
+		name := remaining[0]
+		remaining = remaining[1:]
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, name)
+		info, err := os.Lstat(filepath.Join(sys.root, next))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > MAX_SYMLINKS {
+			return "", &os.PathError{Op: "evalsymlinks", Path: path, Err: syscall.ELOOP}
+		}
+		target, err := os.Readlink(filepath.Join(sys.root, next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return filepath.Join(sys.root, resolved), nil
+}
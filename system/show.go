@@ -0,0 +1,110 @@
+package system
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Format of the timestamps among the properties of a unit
+const TIMESTAMP_FORMAT = "Mon 2006-01-02 15:04:05 MST"
+
+// Show returns the properties of the unit name: the runtime properties followed by the directives of its definition
+func (sys *Daemon) Show(name string) (props []unit.Property, err error) {
+	u, err := sys.Get(name)
+	if u == nil {
+		return nil, err
+	}
+	return u.Properties(), nil
+}
+
+// Properties returns the runtime properties of u followed by the directives of its definition.
+// The directives shadowed by the runtime properties are omitted
+func (u *Unit) Properties() (props []unit.Property) {
+	st := u.Status()
+
+	names := u.Names()
+
+	u.definition.RLock()
+	defer u.definition.RUnlock()
+
+	mainPID, exitCode := 0, -1
+	if attacher, ok := u.Interface.(unit.Attacher); ok {
+		mainPID = attacher.MainPID()
+	}
+	if coder, ok := u.Interface.(unit.ExitCoder); ok {
+		exitCode = coder.ExitCode()
+	}
+
+	fileState := ""
+	if st.Load.State >= 0 {
+		fileState = strings.ToLower(st.Load.State.String())
+	}
+
+	result := "success"
+	if u.System != nil {
+		if f, ok := u.System.failure(u); ok {
+			result = f.Reason
+		}
+	}
+
+	u.mutex.Lock()
+	restarts := u.restarts
+	u.mutex.Unlock()
+	times := u.timestamps()
+
+	props = []unit.Property{
+		{Name: "Id", Value: u.Name()},
+		{Name: "Names", Value: strings.Join(names, " ")},
+		{Name: "Description", Value: st.Description},
+		{Name: "LoadState", Value: strings.ToLower(st.Load.Loaded.String())},
+		{Name: "ActiveState", Value: strings.ToLower(st.Activation.State.String())},
+		{Name: "SubState", Value: st.Activation.Sub},
+		{Name: "FragmentPath", Value: st.Load.Path},
+		{Name: "DropInPaths", Value: strings.Join(st.Load.DropIns, " ")},
+		{Name: "UnitFileState", Value: fileState},
+		{Name: "NeedDaemonReload", Value: yesNo(st.Load.NeedDaemonReload)},
+		{Name: "MainPID", Value: strconv.Itoa(mainPID)},
+		{Name: "ControlPID", Value: strconv.Itoa(st.ControlPID)},
+		{Name: "ExecMainStatus", Value: strconv.Itoa(exitCode)},
+		{Name: "Result", Value: result},
+		{Name: "NRestarts", Value: strconv.FormatUint(restarts, 10)},
+		{Name: "StatusText", Value: st.StatusText},
+		{Name: "StatusErrno", Value: strconv.Itoa(st.StatusErrno)},
+		{Name: "StatusBusError", Value: st.StatusBusError},
+		{Name: "InactiveExitTimestamp", Value: timestamp(times.inactiveExit)},
+		{Name: "ActiveEnterTimestamp", Value: timestamp(times.activeEnter)},
+		{Name: "ActiveExitTimestamp", Value: timestamp(times.activeExit)},
+		{Name: "InactiveEnterTimestamp", Value: timestamp(times.inactiveEnter)},
+		{Name: "StateChangeTimestamp", Value: timestamp(times.changed)},
+	}
+
+	shown := map[string]bool{}
+	for _, prop := range props {
+		shown[prop.Name] = true
+	}
+	for _, prop := range unit.DefinitionProperties(u.Interface) {
+		if !shown[prop.Name] {
+			props = append(props, prop)
+		}
+	}
+	return
+}
+
+// timestamp returns t formatted as a property, empty if t is zero
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(TIMESTAMP_FORMAT)
+}
+
+// yesNo returns b formatted as a property
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
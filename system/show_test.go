@@ -0,0 +1,57 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShow(t *testing.T) {
+	path, err := ioutil.TempDir("", "show-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+Description=A
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 1000`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+	defer sys.Stop("a.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+
+	list, err := sys.Show("a.service")
+	require.NoError(t, err, "sys.Show")
+
+	props := map[string]string{}
+	for _, prop := range list {
+		_, dup := props[prop.Name]
+		assert.False(t, dup, prop.Name)
+		props[prop.Name] = prop.Value
+	}
+
+	assert.Equal(t, unit.Property{Name: "Id", Value: "a.service"}, list[0], "runtime properties first")
+	assert.Equal(t, "A", props["Description"])
+	assert.Equal(t, "active", props["ActiveState"])
+	assert.Equal(t, "success", props["Result"])
+	assert.Equal(t, a.Status().MainPID, a.Interface.(unit.Attacher).MainPID())
+	assert.NotEqual(t, "0", props["MainPID"], "main process running")
+	assert.NotEmpty(t, props["ActiveEnterTimestamp"])
+	assert.Equal(t, "/bin/sleep 1000", props["ExecStart"], "directive")
+	assert.Equal(t, "no", props["DefaultDependencies"], "directive")
+
+	_, err = sys.Show("missing.service")
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,115 @@
+package system
+
+import (
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/shutdown"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Unit started on ctrl-alt-del, usually a link to reboot.target
+const CTRL_ALT_DEL_TARGET = "ctrl-alt-del.target"
+
+// Time units get to stop on shutdown, before their processes are killed
+var SHUTDOWN_TIMEOUT = 90 * time.Second
+
+// Poweroff stops all units and powers the machine off
+func (sys *Daemon) Poweroff() error {
+	return sys.shutdown("poweroff", false)
+}
+
+// Reboot stops all units and reboots the machine
+func (sys *Daemon) Reboot() error {
+	return sys.shutdown("reboot", false)
+}
+
+// Halt stops all units and halts the machine
+func (sys *Daemon) Halt() error {
+	return sys.shutdown("halt", false)
+}
+
+// CtrlAltDel handles the ctrl-alt-del key combination by starting ctrl-alt-del.target,
+// if it is found, and rebooting otherwise
+func (sys *Daemon) CtrlAltDel() error {
+	if _, err := sys.Get(CTRL_ALT_DEL_TARGET); err == nil {
+		return sys.Start(CTRL_ALT_DEL_TARGET)
+	}
+	return sys.Reboot()
+}
+
+// shutdown isolates the target of action("poweroff", "reboot" or "halt") and waits for the units to stop,
+// the main processes left running are killed. If force is set, the processes are killed right away and the inhibitors
+// are ignored, otherwise ErrInhibited is returned, if a block inhibitor is held on "shutdown".
+// The final phase of shutdown(see package shutdown) is only entered by the init process, which is not the one of a container
+func (sys *Daemon) shutdown(action string, force bool) (err error) {
+	log.WithFields(log.Fields{
+		"action": action,
+		"force":  force,
+	}).Debugf("sys.shutdown")
+
+	if !force {
+		if err = sys.inhibit("shutdown"); err != nil {
+			return
+		}
+		sys.recordShutdown(action)
+		if err = sys.Isolate(action + ".target"); err != nil {
+			return
+		}
+
+		if !sys.waitForQueue(SHUTDOWN_TIMEOUT) {
+			log.Errorf("Units did not stop within %s", SHUTDOWN_TIMEOUT)
+		}
+	}
+	sys.killStragglers()
+
+	if sys.Container() {
+		// The container exits, the machine is left alone
+		sys.exitContainer()
+	}
+	if os.Getpid() != 1 {
+		// Not the init process, the machine is left alone
+		return nil
+	}
+	sys.armRebootWatchdog(action)
+	return shutdown.Finalize(action)
+}
+
+// waitForQueue waits until no jobs are queued or timeout passes and reports whether the queue got empty
+func (sys *Daemon) waitForQueue(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		sys.queueMutex.Lock()
+		n := len(sys.queue)
+		sys.queueMutex.Unlock()
+
+		if n == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// killStragglers kills the main processes of units still running
+func (sys *Daemon) killStragglers() {
+	for _, u := range sys.Units() {
+		attacher, ok := u.Interface.(unit.Attacher)
+		if !ok {
+			continue
+		}
+
+		pid := attacher.MainPID()
+		if pid == 0 {
+			continue
+		}
+
+		u.Log.Printf("Killing process %d", pid)
+		if proc, err := os.FindProcess(pid); err == nil {
+			proc.Kill()
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	path, err := ioutil.TempDir("", "shutdown-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for name, contents := range map[string]string{
+		"a.service": `[Service]
+ExecStart=/bin/sleep 1000`,
+		"ignored.service": `[Unit]
+IgnoreOnIsolate=yes
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 1000`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.service", "ignored.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service", "ignored.service")
+
+	require.NoError(t, sys.Reboot(), "sys.Reboot")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+	assert.True(t, a.IsDead(), "a.service stopped")
+
+	// Units ignoring isolation get killed
+	ignored, err := sys.Unit("ignored.service")
+	require.NoError(t, err)
+	for timeout := time.After(5 * time.Second); ignored.IsActive(); time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("ignored.service was not killed")
+		default:
+		}
+	}
+
+	sys.queueMutex.Lock()
+	assert.Empty(t, sys.queue, "no jobs left")
+	sys.queueMutex.Unlock()
+}
@@ -0,0 +1,37 @@
+package system
+
+import (
+	"os"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// activate starts the service name activated by a socket passing it the listening sockets files
+// and waits for the start job to finish
+func (sys *Daemon) activate(name string, files []*os.File) (err error) {
+	u, err := sys.Get(name)
+	if err != nil {
+		return
+	}
+	if user, ok := u.Interface.(unit.SocketUser); ok {
+		user.SetSockets(files)
+	}
+
+	if err = sys.Start(name); err != nil {
+		return
+	}
+	if j := u.lastJob(); j != nil {
+		j.Wait()
+		return j.err
+	}
+	return nil
+}
+
+// isRunning reports whether the unit name is loaded and neither inactive nor failed
+func (sys *Daemon) isRunning(name string) bool {
+	u, err := sys.Unit(name)
+	if err != nil {
+		return false
+	}
+	return !isInactiveState(u.Active())
+}
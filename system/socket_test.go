@@ -0,0 +1,145 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketActivation(t *testing.T) {
+	path, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	sockPath, out := filepath.Join(path, "a.sock"), filepath.Join(path, "out")
+
+	// Reads a datagram from the socket passed and exits, as an idle service does
+	script := filepath.Join(path, "a.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`#!/bin/sh
+[ "$LISTEN_FDS" = 1 ] && [ "$LISTEN_PID" = $$ ] || exit 1
+dd bs=64 count=1 <&3 >>`+out+` 2>/dev/null
+`), 0755), "ioutil.WriteFile")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.socket"), []byte(`[Unit]
+DefaultDependencies=no
+[Socket]
+ListenDatagram=`+sockPath), 0666), "ioutil.WriteFile")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=`+script), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	require.NoError(t, sys.Start("a.socket"), "sys.Start")
+	waitForJobs(t, sys, "a.socket")
+
+	sock, err := sys.Unit("a.socket")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, sock.Active())
+	defer sys.Stop("a.socket")
+
+	send := func(msg string) {
+		conn, err := net.Dial("unixgram", sockPath)
+		require.NoError(t, err, "net.Dial")
+		defer conn.Close()
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err, "conn.Write")
+	}
+	waitFor := func(content string) {
+		for timeout := time.After(10 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+			if b, _ := ioutil.ReadFile(out); string(b) == content {
+				return
+			}
+			select {
+			case <-timeout:
+				b, _ := ioutil.ReadFile(out)
+				t.Fatalf("service output is %q, expected %q", b, content)
+			default:
+			}
+		}
+	}
+
+	send("a")
+	waitFor("a")
+
+	sv, err := sys.Unit("a.service")
+	require.NoError(t, err)
+	for timeout := time.After(5 * time.Second); sv.Active() != unit.Inactive; time.Sleep(50 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatalf("service is %s after exiting idle", sv.Active())
+		default:
+		}
+	}
+	assert.Equal(t, unit.Active, sock.Active(), "socket after the service exited idle")
+	assert.False(t, sv.isFailed(), "service failed on a clean exit")
+
+	// The socket retained activates the service again
+	send("b")
+	waitFor("ab")
+	assert.True(t, strings.HasSuffix(sock.Sub(), "ing"), "socket is listening or running")
+}
+
+func TestReexecSockets(t *testing.T) {
+	path, err := ioutil.TempDir("", "socket-reexec-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	sockPath := filepath.Join(path, "a.sock")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.socket"), []byte(`[Unit]
+DefaultDependencies=no
+[Socket]
+ListenStream=`+sockPath), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+	require.NoError(t, sys.Start("a.socket"), "sys.Start")
+	waitForJobs(t, sys, "a.socket")
+
+	spec, passed := sys.passFiles()
+	require.Len(t, passed, 1, "sockets passed")
+	fd := int(passed[0].Fd())
+	assert.Equal(t, fmt.Sprintf("%d=a.socket", fd), spec)
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFD, 0)
+	require.Zero(t, errno, "fcntl")
+	assert.Zero(t, flags&syscall.FD_CLOEXEC, "socket closed on exec")
+
+	// The re-executed manager gets its own descriptor, as the one of sys gets closed
+	inherited, err := syscall.Dup(fd)
+	require.NoError(t, err, "syscall.Dup")
+	snap := &bytes.Buffer{}
+	require.NoError(t, sys.Serialize(snap), "sys.Serialize")
+	require.NoError(t, sys.Stop("a.socket"), "sys.Stop")
+	waitForJobs(t, sys, "a.socket")
+
+	reexecuted := New()
+	reexecuted.SetPaths(path)
+	os.Setenv(FDS_ENV, fmt.Sprintf("%d=a.socket", inherited))
+	require.NoError(t, reexecuted.Deserialize(snap), "Deserialize")
+	assert.Empty(t, os.Getenv(FDS_ENV), "FDS_ENV unset")
+
+	sock, err := reexecuted.Unit("a.socket")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, sock.Active())
+	defer reexecuted.Stop("a.socket")
+	if files := sock.Interface.(unit.Listener).Files(); assert.Len(t, files, 1) {
+		assert.Equal(t, inherited, int(files[0].Fd()), "socket adopted")
+	}
+
+	conn, err := net.Dial("unix", sockPath)
+	require.NoError(t, err, "socket not listening after re-execution")
+	conn.Close()
+}
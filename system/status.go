@@ -45,12 +45,12 @@ func (sys *Daemon) Status() (st Status, err error) {
 	st = Status{Since: sys.since}
 
 	for _, u := range sys.Units() {
-		switch {
-		case u.job == nil:
+		switch j := u.lastJob(); {
+		case j == nil:
 			continue
-		case u.job.IsRunning():
+		case j.IsRunning():
 			st.Jobs++
-		case u.job.Failed():
+		case j.Failed():
 			st.Failed++
 		}
 	}
@@ -1,7 +1,9 @@
 package system
 
 import (
+	"context"
 	"io"
+	"sync"
 
 	"github.com/plasma-umass/systemgo/unit"
 )
@@ -15,18 +17,74 @@ const (
 type Target struct {
 	unit.Definition
 	System *Daemon
+
+	// Name of the unit, used to look up the dependencies found in dependency directories
+	name string
+
+	// Whether the target was started and not stopped since
+	started bool
+	mutex   sync.Mutex
 }
 
 // Define attempts to fill the targ definition by parsing r
 func (targ *Target) Define(r io.Reader) (err error) {
-	return unit.ParseDefinition(r, &targ.Definition)
+	def := unit.Definition{}
+	def.Unit.DefaultDependencies = true
+
+	if err = unit.ParseDefinition(r, &def); err != nil && !unit.IsWarning(err) {
+		return
+	}
+	targ.Definition = def
+	return
+}
+
+// Start marks targ as started, targ is active as long as its requirements are
+func (targ *Target) Start() error {
+	targ.setStarted(true)
+	return nil
+}
+
+// StartContext marks targ as started. network-online.target waits for the network to get online first
+func (targ *Target) StartContext(ctx context.Context) error {
+	if targ.name == NETWORK_ONLINE_TARGET && targ.System != nil {
+		if err := targ.System.waitOnline(ctx); err != nil {
+			return err
+		}
+	}
+	return targ.Start()
+}
+
+// Stop marks targ as stopped
+func (targ *Target) Stop() error {
+	targ.setStarted(false)
+	return nil
+}
+
+// setStarted records whether targ was started
+func (targ *Target) setStarted(started bool) {
+	targ.mutex.Lock()
+	targ.started = started
+	targ.mutex.Unlock()
 }
 
 // Active returns activation status of the unit
 func (targ *Target) Active() unit.Activation {
+	targ.mutex.Lock()
+	started := targ.started
+	targ.mutex.Unlock()
+
+	if !started {
+		return unit.Inactive
+	}
+
 	encountered := map[unit.Activation]bool{}
 
-	for _, name := range targ.Definition.Unit.Requires {
+	requires := targ.Definition.Unit.Requires
+	if u, err := targ.System.Unit(targ.name); targ.name != "" && err == nil {
+		requires = u.Requires()
+	}
+
+	for _, name := range requires {
 		dep, err := targ.System.Unit(name)
 		if err != nil {
 			return unit.Inactive
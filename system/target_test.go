@@ -16,6 +16,8 @@ func TestTargetActive(t *testing.T) {
 
 	sys := New()
 	targ := &Target{System: sys}
+	assert.Equal(t, unit.Inactive, targ.Active(), "not started")
+	targ.Start()
 
 	for name, st := range map[string]unit.Activation{
 		"active":       unit.Active,
@@ -0,0 +1,113 @@
+package system
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// actionTargets maps the actions, which can be run on job timeouts, to the targets isolated to run them
+// -- https://www.freedesktop.org/software/systemd/man/systemd.unit.html#JobTimeoutAction=
+var actionTargets = map[string]string{
+	"reboot":             "reboot.target",
+	"reboot-force":       "reboot.target",
+	"reboot-immediate":   "reboot.target",
+	"poweroff":           "poweroff.target",
+	"poweroff-force":     "poweroff.target",
+	"poweroff-immediate": "poweroff.target",
+	"halt":               "halt.target",
+	"halt-force":         "halt.target",
+	"halt-immediate":     "halt.target",
+	"exit":               "exit.target",
+	"exit-force":         "exit.target",
+}
+
+// runAction isolates the target corresponding to action. An empty action is equivalent to "none".
+// Shutdown actions suffixed with "-force" or "-immediate" kill the units instead of stopping them in order
+func (sys *Daemon) runAction(action string) error {
+	switch action {
+	case "", "none":
+		return nil
+	}
+
+	target, ok := actionTargets[action]
+	if !ok {
+		return ErrUnknownAction
+	}
+
+	switch name := strings.TrimSuffix(strings.TrimSuffix(action, "-force"), "-immediate"); name {
+	case "reboot", "poweroff", "halt":
+		return sys.shutdown(name, name != action)
+	}
+	return sys.Isolate(target)
+}
+
+// jobTimeouts returns JobTimeoutSec=, JobRunningTimeoutSec= and JobTimeoutAction= as found in definition
+func (u *Unit) jobTimeouts() (timeout, running time.Duration, action string) {
+	if timeouter, ok := u.Interface.(unit.JobTimeouter); ok {
+		return timeouter.JobTimeoutSec(), timeouter.JobRunningTimeoutSec(), timeouter.JobTimeoutAction()
+	}
+	return
+}
+
+// startContext returns ctx limited to TimeoutStartSec= of u, if it is set
+func (u *Unit) startContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeouter, ok := u.Interface.(unit.StartTimeouter); ok && timeouter.TimeoutStartSec() > 0 {
+		return context.WithTimeout(ctx, timeouter.TimeoutStartSec())
+	}
+	return ctx, func() {}
+}
+
+// timeoutAction runs JobTimeoutAction= of u
+func (u *Unit) timeoutAction() {
+	_, _, action := u.jobTimeouts()
+	if u.System == nil {
+		return
+	}
+
+	if err := u.System.runAction(action); err != nil {
+		u.Log.Errorf("Error running JobTimeoutAction=%s: %s", action, err)
+	}
+}
+
+// armTimeout cancels j, if it does not finish within d.
+// Non-positive and infinite durations disable the timeout
+func (j *job) armTimeout(d time.Duration) {
+	if d <= 0 || d == unit.Infinity {
+		return
+	}
+
+	timer := time.AfterFunc(d, func() {
+		j.mutex.Lock()
+		j.timedOut = true
+		j.mutex.Unlock()
+
+		j.unit.Log.WithField("job", j.id).Errorf("%s job timed out after %s", j.typ, d)
+		j.cancel()
+	})
+
+	j.mutex.Lock()
+	j.timers = append(j.timers, timer)
+	j.mutex.Unlock()
+}
+
+// disarmTimeouts stops the timers armed for j
+func (j *job) disarmTimeouts() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for _, timer := range j.timers {
+		timer.Stop()
+	}
+	j.timers = nil
+}
+
+// hasTimedOut reports whether j was cancelled due to a timeout
+func (j *job) hasTimedOut() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.timedOut
+}
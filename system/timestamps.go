@@ -0,0 +1,80 @@
+package system
+
+import (
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// timestamps are the times a unit last entered and left the active and inactive states
+type timestamps struct {
+	inactiveExit, activeEnter, activeExit, inactiveEnter time.Time
+
+	// Time of the last transition recorded
+	changed time.Time
+
+	// State the unit was in as of the last transition recorded
+	state unit.Activation
+}
+
+// isActiveState reports whether st counts as active, as the timestamps of systemd do
+func isActiveState(st unit.Activation) bool {
+	return st == unit.Active || st == unit.Reloading
+}
+
+// isInactiveState reports whether st counts as inactive, as the timestamps of systemd do
+func isInactiveState(st unit.Activation) bool {
+	return st == unit.Inactive || st == unit.Failed
+}
+
+// entered records the transition of u to state st, unless u is in st already
+func (u *Unit) entered(st unit.Activation) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	prev := u.times.state
+	if prev == st {
+		return
+	}
+
+	now := time.Now()
+	if isInactiveState(prev) && !isInactiveState(st) {
+		u.times.inactiveExit = now
+	}
+	if !isActiveState(prev) && isActiveState(st) {
+		u.times.activeEnter = now
+	}
+	if isActiveState(prev) && !isActiveState(st) {
+		u.times.activeExit = now
+	}
+	if !isInactiveState(prev) && isInactiveState(st) {
+		u.times.inactiveEnter = now
+	}
+	u.times.state, u.times.changed = st, now
+}
+
+// timestamps returns the times u last entered and left the active and inactive states
+func (u *Unit) timestamps() timestamps {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.times
+}
+
+// since returns the time the unit entered the state st, zero if the transition to st was not recorded
+func (ts timestamps) since(st unit.Activation) time.Time {
+	if st != ts.state {
+		return time.Time{}
+	}
+
+	switch {
+	case isActiveState(st):
+		return ts.activeEnter
+	case isInactiveState(st):
+		return ts.inactiveEnter
+	case st == unit.Activating:
+		return ts.inactiveExit
+	case st == unit.Deactivating:
+		return ts.activeExit
+	}
+	return time.Time{}
+}
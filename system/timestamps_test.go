@@ -0,0 +1,53 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamps(t *testing.T) {
+	path, err := ioutil.TempDir("", "timestamps-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 1000`), 0666), "ioutil.WriteFile")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	before := time.Now()
+	require.NoError(t, sys.Start("a.service"), "sys.Start")
+	waitForJobs(t, sys, "a.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+
+	started := a.timestamps()
+	assert.False(t, started.inactiveExit.Before(before), "InactiveExitTimestamp")
+	assert.False(t, started.activeEnter.Before(started.inactiveExit), "ActiveEnterTimestamp")
+	assert.True(t, started.activeExit.IsZero(), "ActiveExitTimestamp")
+	assert.True(t, started.inactiveEnter.IsZero(), "InactiveEnterTimestamp")
+
+	st := a.Status()
+	assert.Equal(t, unit.Active, st.Activation.State)
+	assert.Equal(t, started.activeEnter, st.Activation.Since, "active since")
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+	waitForJobs(t, sys, "a.service")
+
+	stopped := a.timestamps()
+	assert.Equal(t, started.activeEnter, stopped.activeEnter, "ActiveEnterTimestamp")
+	assert.False(t, stopped.activeExit.Before(stopped.activeEnter), "ActiveExitTimestamp")
+	assert.False(t, stopped.inactiveEnter.Before(stopped.activeExit), "InactiveEnterTimestamp")
+	assert.Equal(t, unit.Inactive, stopped.state)
+}
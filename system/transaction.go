@@ -1,8 +1,7 @@
 package system
 
 import (
-	"errors"
-	"fmt"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -10,6 +9,12 @@ import (
 type transaction struct {
 	unmerged map[*Unit]*prospectiveJobs
 	merged   map[*Unit]*job
+
+	// Mode the transaction is run in, the zero value is equivalent to ReplaceMode
+	mode JobMode
+
+	// Units left out by isolation, the jobs queued for them get canceled once the transaction is committed
+	isolated []*Unit
 }
 
 type prospectiveJobs struct {
@@ -25,6 +30,9 @@ func newTransaction() (tr *transaction) {
 	}
 }
 
+// Run builds the transaction and commits it. The jobs added get merged and ordered,
+// then the ones for units already in the state requested get pruned.
+// Only if all of that succeeds, the remaining jobs are committed to the job queue at once
 func (tr *transaction) Run() (err error) {
 	log.WithField("transaction", tr).Debugf("tr.Run")
 
@@ -37,15 +45,156 @@ func (tr *transaction) Run() (err error) {
 		return
 	}
 
-	for _, j := range ordering {
-		if j.IsRedundant() {
+	tr.prune()
+
+	if tr.mode == FailMode {
+		if err = tr.checkPending(); err != nil {
+			return
+		}
+	}
+
+	tr.commit(ordering)
+	return
+}
+
+// checkPending returns ErrJobPending, if committing the transaction would replace
+// a job pending for a unit with a job, which it can not be merged into
+func (tr *transaction) checkPending() error {
+	for u, j := range tr.merged {
+		if u.System == nil {
 			continue
 		}
 
+		u.System.queueMutex.Lock()
+		pending, ok := u.System.queue[u]
+		u.System.queueMutex.Unlock()
+
+		if ok && !subsumes(pending.typ, j.typ) {
+			u.Log.Errorf("%s job would replace pending %s job: %s", j.typ, pending.typ, ErrJobPending)
+			return ErrJobPending
+		}
+	}
+	return nil
+}
+
+// prune removes the redundant jobs from the transaction, which are the jobs for units
+// already in the state requested. Jobs depending on a redundant job consider it satisfied.
+// In FailMode the jobs a pending job does the work of are kept to be merged into it
+func (tr *transaction) prune() {
+	log.Debug("tr.prune")
+
+	for u, j := range tr.merged {
+		if !j.IsRedundant() {
+			continue
+		}
+		if pending := u.runningJob(); tr.mode == FailMode && pending != nil && subsumes(pending.typ, j.typ) {
+			// Merged into the pending job on commit, so that the jobs depending on j wait for it
+			continue
+		}
+		log.Debugf("%s job for %s is redundant", j.typ, u.Name())
+
+		delete(tr.merged, u)
+
+		for depender := range j.requiredBy {
+			delete(depender.requires, j)
+		}
+		for depender := range j.wantedBy {
+			delete(depender.wants, j)
+		}
+		for depender := range j.conflictedBy {
+			delete(depender.conflicts, j)
+		}
+
+		for dependency := range j.requires {
+			delete(dependency.requiredBy, j)
+		}
+		for dependency := range j.wants {
+			delete(dependency.wantedBy, j)
+		}
+		for dependency := range j.conflicts {
+			delete(dependency.conflictedBy, j)
+		}
+
+		for other := range j.after {
+			delete(other.before, j)
+		}
+		for other := range j.before {
+			delete(other.after, j)
+		}
+
+		j.finish()
+	}
+}
+
+// commit installs the jobs in the job queue of the system in order and dispatches them.
+// Unless the transaction is run in FailMode, a job already queued for a unit gets canceled and replaced
+// in the queue, the job replacing it is ordered after it, so that both do not run at once.
+// In FailMode the job gets merged into the one queued instead, which keeps running.
+// The jobs queued for the units left out by isolation get canceled
+func (tr *transaction) commit(ordering []*job) {
+	log.Debug("tr.commit")
+
+	// Pruned jobs are not committed
+	committed := ordering[:0]
+	for _, j := range ordering {
+		if tr.merged[j.unit] == j {
+			committed = append(committed, j)
+		}
+	}
+	ordering = committed
+
+	var sys *Daemon
+	switch {
+	case len(ordering) > 0:
+		sys = ordering[0].unit.System
+	case len(tr.isolated) > 0:
+		sys = tr.isolated[0].System
+	}
+
+	if sys != nil {
+		sys.queueMutex.Lock()
+		queued := ordering[:0]
+		for _, j := range ordering {
+			pending, ok := sys.queue[j.unit]
+			switch {
+			case !ok || pending == j:
+			case tr.mode == FailMode && subsumes(pending.typ, j.typ):
+				pending.unit.Log.WithField("job", pending.id).Printf("%s job merged into pending %s job", j.typ, pending.typ)
+				j.mergeInto(pending)
+				continue
+			default:
+				pending.unit.Log.WithField("job", pending.id).Printf("%s job replaced by %s job", pending.typ, j.typ)
+				pending.cancel()
+				j.after.Put(pending)
+			}
+
+			sys.lastJobID++
+			j.id = sys.lastJobID
+			sys.queue[j.unit] = j
+			queued = append(queued, j)
+		}
+		ordering = queued
+
+		// Jobs queued for the units isolated would bring them back up
+		for _, u := range tr.isolated {
+			if pending, ok := sys.queue[u]; ok && tr.merged[u] != pending {
+				pending.cancel()
+			}
+		}
+		sys.queueMutex.Unlock()
+	}
+
+	for _, j := range ordering {
+		j.unit.setJob(j)
+
+		timeout, _, _ := j.unit.jobTimeouts()
+		j.armTimeout(timeout)
+	}
+
+	for _, j := range ordering {
 		log.Debugf("dispatching job for %s", j.unit.Name())
 		go j.Run()
 	}
-	return
 }
 
 // recursively adds jobs to transaction
@@ -106,19 +255,29 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 		}
 	}
 
+	if isNew && tr.mode.ignoresRequirements() {
+		return nil
+	}
+
 	if isNew && typ != stop {
+		// Conflicts are symmetric, units conflicting with u get stopped as well
+		conflicting := u.conflictedBy()
 		for _, name := range u.Conflicts() {
 			dep, err := u.System.Get(name)
 			if err != nil {
-				return err
+				// Units, which can not be loaded, are not running either
+				continue
 			}
+			conflicting = append(conflicting, dep)
+		}
 
-			if err = tr.add(stop, dep, j, true, anchor); err != nil {
+		for _, dep := range conflicting {
+			if err = tr.addConflict(dep, j, anchor); err != nil {
 				return err
 			}
 		}
 
-		for _, name := range u.Requires() {
+		for _, name := range append(u.Requires(), u.BindsTo()...) {
 			dep, err := u.System.Get(name)
 			if err != nil {
 				return err
@@ -129,6 +288,19 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 			}
 		}
 
+		// Requisite units are not started, but have to be active already
+		for _, name := range u.Requisite() {
+			dep, err := u.System.Get(name)
+			if err != nil {
+				return err
+			}
+
+			if !dep.IsActive() && !dep.IsReloading() {
+				u.Log.Errorf("%s: %s", name, ErrRequisite)
+				return ErrRequisite
+			}
+		}
+
 		for _, name := range u.Wants() {
 			dep, err := u.System.Get(name)
 			if err != nil {
@@ -139,6 +311,43 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 		}
 	}
 
+	if isNew && typ == reload {
+		// Reloads are only propagated to active units, the jobs are optional and
+		// get dropped, if they can not be merged with other jobs in the transaction
+		for _, dep := range u.reloadPropagated() {
+			if dep.IsActive() {
+				if err = tr.add(reload, dep, j, false, false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if isNew && (typ == stop || typ == restart) {
+		// Stopping or restarting a unit propagates to the units bound to it
+		for _, dep := range u.boundBy() {
+			if err = tr.add(typ, dep, j, false, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addConflict adds a stop job for u conflicting with job parent to the transaction.
+// The stop job is required by parent, which gets ordered after it
+func (tr *transaction) addConflict(u *Unit, parent *job, anchor bool) (err error) {
+	if err = tr.add(stop, u, nil, true, anchor); err != nil {
+		return
+	}
+
+	j := tr.unmerged[u].optional[stop]
+	if anchor {
+		j = tr.unmerged[u].anchored[stop]
+	}
+	parent.conflicts.Put(j)
+	j.conflictedBy.Put(parent)
 	return nil
 }
 
@@ -257,6 +466,13 @@ func (tr *transaction) delete(j *job) {
 				defer tr.delete(dependency)
 			}
 		},
+
+		&j.after: func(other *job) {
+			delete(other.before, j)
+		},
+		&j.before: func(other *job) {
+			delete(other.after, j)
+		},
 	} {
 		for dep := range *deps {
 			f(dep)
@@ -269,59 +485,125 @@ func canMerge(what, with jobType) (ok bool) {
 	return
 }
 
+// subsumes reports whether a job of type what does what a job of type with would,
+// so that the latter can be merged into the former without changing its type
+func subsumes(what, with jobType) bool {
+	merged, ok := mergeTable[what][with]
+	return what == with || ok && merged == what
+}
+
+// order links the merged jobs and returns them in the order they can be run in.
+// Ordering cycles are broken by deleting a job, which is only wanted by others,
+// if there is no such job, a CycleError is returned
 func (tr *transaction) order() (ordering []*job, err error) {
 	log.Debug("tr.order")
 
-	g := newGraph()
+	// Ordering is not honored, if dependencies are ignored
+	if tr.mode != IgnoreDependencies {
+		tr.link()
+	}
+
+	for {
+		g := newGraph()
+		g.ordering = make([]*job, 0, len(tr.merged))
+
+		var cycle []*job
+		for _, j := range tr.merged {
+			if cycle = g.order(j); cycle != nil {
+				break
+			}
+		}
+		if cycle == nil {
+			return g.ordering, nil
+		}
+
+		names := make(CycleError, len(cycle))
+		for i, j := range cycle {
+			names[i] = j.unit.Name()
+		}
+
+		victim := breakable(cycle)
+		if victim == nil {
+			log.Errorf("%s", names)
+			return nil, names
+		}
+
+		log.Warnf("%s", names)
+		victim.unit.Log.WithField("job", victim.id).Warnf("Deleting %s job to break the ordering cycle", victim.typ)
+		tr.delete(victim)
+	}
+}
 
+// link links the merged jobs according to After= and Before= of their units.
+// Jobs are ordered after the stop jobs of the units conflicting with them
+func (tr *transaction) link() {
 	for u, j := range tr.merged {
-		if j.typ == stop {
-			// TODO Introduce stop job ordering(if needed)
-			continue
+		for dep := range j.conflicts {
+			j.after.Put(dep)
+			dep.before.Put(j)
 		}
 
 		log.Debugf("Checking after of %s...", j.unit.Name())
 		for _, depname := range u.After() {
-			var dep *Unit
-			if dep, err = u.System.Unit(depname); err != nil {
+			dep, err := u.System.Unit(depname)
+			if err != nil {
 				continue
 			}
 
-			depJob, ok := tr.merged[dep]
-			if ok {
-				j.after.Put(depJob)
-				depJob.before.Put(j)
+			if depJob, ok := tr.merged[dep]; ok {
+				orderAfter(j, depJob)
 			}
 		}
 
 		log.Debugf("Checking before of %s...", j.unit.Name())
 		for _, depname := range u.Before() {
-			var dep *Unit
-			if dep, err = u.System.Unit(depname); err != nil {
+			dep, err := u.System.Unit(depname)
+			if err != nil {
 				continue
 			}
 
-			depJob, ok := tr.merged[dep]
-			if ok {
-				depJob.after.Put(j)
-				j.before.Put(depJob)
+			if depJob, ok := tr.merged[dep]; ok {
+				orderAfter(depJob, j)
 			}
 		}
 	}
+}
+
+// orderAfter orders job later of the unit ordered after the one of job earlier.
+// Stopping goes in reverse order, so if later is a stop job, it is run first
+func orderAfter(later, earlier *job) {
+	if later.typ == stop {
+		later, earlier = earlier, later
+	}
+	later.after.Put(earlier)
+	earlier.before.Put(later)
+}
 
-	g.ordering = make([]*job, 0, len(tr.merged))
-	for _, j := range tr.merged {
-		if err = g.order(j); err != nil {
-			return nil, fmt.Errorf("Dependency cycle determined:\njob for %s depends on %s", j.unit.Name(), err)
+// breakable returns a job of cycle, which can be deleted to break it,
+// or nil if every job in the cycle is required by the transaction
+func breakable(cycle []*job) *job {
+	for _, j := range cycle {
+		if len(j.requiredBy) == 0 && len(j.conflictedBy) == 0 && len(j.wantedBy) > 0 {
+			return j
 		}
 	}
+	return nil
+}
+
+// CycleError is returned, if jobs of a transaction are ordered in a cycle, which can not be broken.
+// It contains names of the units in the cycle in order, the first and the last name are the same
+type CycleError []string
 
-	return g.ordering, nil
+func (err CycleError) Error() string {
+	return "Ordering cycle found: " + strings.Join(err, " after ")
 }
 
 type graph struct {
 	visited, ordered set
 	ordering         []*job
+
+	// jobs being visited in the order they were reached in
+	path []*job
 }
 
 func newGraph() (g *graph) {
@@ -333,9 +615,9 @@ func newGraph() (g *graph) {
 	}
 }
 
-var errBlank = errors.New("")
-
-func (g *graph) order(j *job) (err error) {
+// order appends j to the ordering after the jobs j is ordered after.
+// If an ordering cycle is reached, the jobs forming it are returned
+func (g *graph) order(j *job) (cycle []*job) {
 	log.WithField("j", j).Debugf("g.order")
 
 	if g.ordered.Contains(j) {
@@ -343,26 +625,28 @@ func (g *graph) order(j *job) (err error) {
 	}
 
 	if g.visited.Contains(j) {
-		return errBlank
+		for i, other := range g.path {
+			if other == j {
+				cycle = make([]*job, 0, len(g.path)-i+1)
+				return append(append(cycle, g.path[i:]...), j)
+			}
+		}
 	}
 
 	g.visited.Put(j)
+	g.path = append(g.path, j)
 
 	for depJob := range j.after {
-		if err = g.order(depJob); err != nil {
-			if err == errBlank {
-				return fmt.Errorf("%s\n", depJob.unit.Name())
-			}
-			return fmt.Errorf("%v\n%s depends on %s", j.unit.Name(), j.unit.Name(), err)
+		if cycle = g.order(depJob); cycle != nil {
+			return
 		}
 	}
 
+	g.path = g.path[:len(g.path)-1]
 	delete(g.visited, j)
 
-	if !g.ordered.Contains(j) {
-		g.ordering = append(g.ordering, j)
-		g.ordered.Put(j)
-	}
+	g.ordering = append(g.ordering, j)
+	g.ordered.Put(j)
 
 	return nil
 }
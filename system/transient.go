@@ -0,0 +1,97 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// TransientUnit is a unit defined at runtime by the properties specified instead of a unit file
+type TransientUnit struct {
+	Name       string
+	Properties []unit.Property
+}
+
+// StartTransient defines the units specified and starts the first one using mode.
+// The others are auxiliary units, e.g. the service started by a transient timer.
+// Transient units are kept loaded until they are inactive and garbage collected, they are not affected by DaemonReload.
+// If a unit with any of the names already exists, ErrExists is returned and no unit gets defined
+func (sys *Daemon) StartTransient(mode JobMode, units ...TransientUnit) (err error) {
+	log.WithField("units", units).Debugf("sys.StartTransient")
+
+	if len(units) == 0 {
+		return ErrNotFound
+	}
+
+	defined := make([]unit.Interface, len(units))
+	for i, tu := range units {
+		if !Supported(tu.Name) || unit.IsTemplate(tu.Name) {
+			return unit.ParseErr(tu.Name, unit.ErrNotSupported)
+		}
+		if _, err = sys.Unit(tu.Name); err == nil {
+			return ErrExists
+		}
+
+		v := sys.newInterface(tu.Name)
+
+		def, err := transientDefinition(v, tu.Properties)
+		if err != nil {
+			return unit.ParseErr(tu.Name, err)
+		}
+		if err = v.Define(bytes.NewReader(def)); err != nil && !unit.IsWarning(err) {
+			return unit.ParseErr(tu.Name, err)
+		}
+		defined[i] = v
+	}
+
+	for i, v := range defined {
+		u, err := sys.Supervise(units[i].Name, v)
+		if err != nil {
+			return err
+		}
+		u.setLoad(unit.Loaded)
+		u.transient = true
+		u.conflicting = u.Conflicts()
+		sys.setLogRateLimit(u)
+
+		u.Log.Printf("Transient unit created: %v", units[i].Properties)
+		if sys.subscribed() {
+			sys.publish(Event{Type: UnitLoaded, Unit: u.Name(), Active: u.Active()})
+		}
+	}
+
+	return sys.StartWith(mode, units[0].Name)
+}
+
+// transientDefinition returns the definition of v, which has the directives of props set,
+// grouped by the sections found in the definition of v
+func transientDefinition(v unit.Interface, props []unit.Property) (def []byte, err error) {
+	var sections []string
+	directives := map[string][]unit.Property{}
+	for _, prop := range props {
+		section := unit.DirectiveSection(v, prop.Name)
+		if section == "" {
+			return nil, unit.ParseErr(prop.Name, unit.ErrUnknownDirective)
+		}
+		if strings.ContainsAny(prop.Value, "\r\n") {
+			return nil, unit.ParseErr(prop.Name, unit.ErrWrongVal)
+		}
+
+		if _, ok := directives[section]; !ok {
+			sections = append(sections, section)
+		}
+		directives[section] = append(directives[section], prop)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, section := range sections {
+		fmt.Fprintf(buf, "[%s]\n", section)
+		for _, prop := range directives[section] {
+			fmt.Fprintf(buf, "%s=%s\n", prop.Name, prop.Value)
+		}
+	}
+	return buf.Bytes(), nil
+}
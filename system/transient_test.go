@@ -0,0 +1,139 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartTransient(t *testing.T) {
+	path, err := ioutil.TempDir("", "transient-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(orig string) {
+		CGROUP_PATH = orig
+	}(CGROUP_PATH)
+	CGROUP_PATH = filepath.Join(path, "cgroup")
+
+	sys := New()
+	sys.SetPaths(path)
+
+	assert.Error(t, sys.StartTransient(ReplaceMode, TransientUnit{Name: "a.service", Properties: []unit.Property{
+		{Name: "ExecStrat", Value: "/bin/sleep 1000"},
+	}}), "unknown directive")
+	assert.Error(t, sys.StartTransient(ReplaceMode, TransientUnit{Name: "a.service"}), "ExecStart= not set")
+	_, err = sys.Unit("a.service")
+	assert.Equal(t, ErrNotFound, err, "no unit defined on error")
+
+	require.NoError(t, sys.StartTransient(ReplaceMode, TransientUnit{Name: "a.service", Properties: []unit.Property{
+		{Name: "Description", Value: "transient"},
+		{Name: "DefaultDependencies", Value: "no"},
+		{Name: "ExecStart", Value: "/bin/sleep 1000"},
+		{Name: "Slice", Value: "test.slice"},
+	}}), "sys.StartTransient")
+	waitForJobs(t, sys, "a.service")
+
+	a, err := sys.Unit("a.service")
+	require.NoError(t, err)
+	assert.True(t, a.IsActive(), "a.IsActive")
+	assert.Equal(t, "transient", a.Description())
+	assert.Equal(t, filepath.Join(CGROUP_PATH, "test.slice", "a.service"), a.cgroupPath())
+
+	b, err := ioutil.ReadFile(filepath.Join(a.cgroupPath(), "cgroup.procs"))
+	if assert.NoError(t, err, "cgroup created in the slice") {
+		assert.Equal(t, strconv.Itoa(a.Status().MainPID), string(b))
+	}
+
+	assert.Equal(t, ErrExists, sys.StartTransient(ReplaceMode, TransientUnit{Name: "a.service", Properties: []unit.Property{
+		{Name: "ExecStart", Value: "/bin/sleep 1000"},
+	}}), "unit exists")
+
+	sys.DaemonReload()
+	_, err = sys.Unit("a.service")
+	assert.NoError(t, err, "transient unit kept on reload")
+
+	require.NoError(t, sys.Stop("a.service"), "sys.Stop")
+	waitForJobs(t, sys, "a.service")
+
+	deadline := time.After(5 * time.Second)
+	for !a.IsDead() {
+		select {
+		case <-deadline:
+			t.Fatal("a.service not stopped")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	assert.Contains(t, sys.GC(), "a.service", "inactive transient unit collected")
+}
+
+func TestTransientTimer(t *testing.T) {
+	sys := New()
+
+	out := filepath.Join(os.TempDir(), "transient-timer-test-"+strconv.Itoa(os.Getpid()))
+	defer os.Remove(out)
+
+	require.NoError(t, sys.StartTransient(ReplaceMode,
+		TransientUnit{Name: "t.timer", Properties: []unit.Property{
+			{Name: "DefaultDependencies", Value: "no"},
+			{Name: "OnCalendar", Value: "*:*:*"},
+			{Name: "Unit", Value: "t-run.service"},
+		}},
+		TransientUnit{Name: "t-run.service", Properties: []unit.Property{
+			{Name: "DefaultDependencies", Value: "no"},
+			{Name: "Type", Value: "oneshot"},
+			{Name: "ExecStart", Value: "/bin/touch " + out},
+		}},
+	), "sys.StartTransient")
+	waitForJobs(t, sys, "t.timer")
+	defer sys.Stop("t.timer")
+
+	timer, err := sys.Unit("t.timer")
+	require.NoError(t, err)
+	assert.True(t, timer.IsActive(), "timer.IsActive")
+
+	assert.NotContains(t, sys.GC(), "t-run.service", "service triggered by an active timer kept")
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, err := os.Stat(out); err == nil {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("service not started by the timer")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestTransientScope(t *testing.T) {
+	cmd := exec.Command("/bin/sleep", "1000")
+	require.NoError(t, cmd.Start(), "cmd.Start")
+	defer cmd.Process.Kill()
+
+	sys := New()
+	require.NoError(t, sys.StartTransient(ReplaceMode, TransientUnit{Name: "s.scope", Properties: []unit.Property{
+		{Name: "DefaultDependencies", Value: "no"},
+		{Name: "PIDs", Value: strconv.Itoa(cmd.Process.Pid)},
+	}}), "sys.StartTransient")
+	waitForJobs(t, sys, "s.scope")
+
+	s, err := sys.Unit("s.scope")
+	require.NoError(t, err)
+	assert.True(t, s.IsActive(), "s.IsActive")
+
+	require.NoError(t, sys.Stop("s.scope"), "sys.Stop")
+	waitForJobs(t, sys, "s.scope")
+
+	cmd.Wait()
+	assert.True(t, s.IsDead(), "scope inactive, once its processes are gone")
+}
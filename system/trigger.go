@@ -0,0 +1,107 @@
+package system
+
+import "github.com/plasma-umass/systemgo/unit"
+
+// unbind stops the units bound to u, which are running, if job j has failed to bring u up
+func (u *Unit) unbind(j *job) {
+	if u.System == nil || j.typ == stop || !j.Failed() {
+		return
+	}
+
+	// Only BindsTo= reacts to failures, PartOf= propagates just stopping and restarting
+	bound := u.dependents(func(other *Unit) []string {
+		return other.BindsTo()
+	})
+
+	var names []string
+	for _, dep := range bound {
+		if dep.IsActive() || dep.IsActivating() || dep.IsReloading() {
+			names = append(names, dep.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	u.Log.Printf("Stopping units bound to it: %v", names)
+	if err := u.System.Stop(names...); err != nil {
+		u.Log.Errorf("Error stopping %v: %s", names, err)
+	}
+}
+
+// stopUnneeded stops the units with StopWhenUnneeded=yes, which are not needed anymore after job j for u has finished
+func (u *Unit) stopUnneeded(j *job) {
+	if u.System == nil {
+		return
+	}
+
+	var names []string
+	for _, dep := range u.System.Units() {
+		dep.definition.RLock()
+		unneeded := dep.StopWhenUnneeded()
+		dep.definition.RUnlock()
+		if !unneeded || !dep.IsActive() || dep.jobRunning() {
+			continue
+		}
+
+		needed := false
+		for _, other := range dep.dependents(func(other *Unit) []string {
+			other.definition.RLock()
+			defer other.definition.RUnlock()
+			return other.needs()
+		}) {
+			if j := other.lastJob(); j != nil && j.typ == stop {
+				// Stopped, but the processes may not have been reaped yet
+				continue
+			}
+			needed = needed || other.IsActive() || other.IsActivating() || other.IsReloading()
+		}
+		if !needed {
+			names = append(names, dep.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	u.Log.Printf("Stopping units not needed anymore: %v", names)
+	if err := u.System.Stop(names...); err != nil {
+		u.Log.Errorf("Error stopping %v: %s", names, err)
+	}
+}
+
+// needs returns the names of the units u keeps running, i.e. the ones it requires, wants or is bound to
+func (u *Unit) needs() []string {
+	names := append(u.Requires(), u.Wants()...)
+	names = append(names, u.BindsTo()...)
+	return append(names, u.Requisite()...)
+}
+
+// trigger enqueues the OnFailure= or OnSuccess= units of u depending on the result of job j
+func (u *Unit) trigger(j *job) {
+	triggerer, ok := u.Interface.(unit.Triggerer)
+	if !ok || u.System == nil {
+		return
+	}
+
+	var names []string
+	var mode JobMode
+	switch {
+	case j.Failed() && j.err != ErrCanceled, u.Active() == unit.Failed:
+		names, mode = triggerer.OnFailure(), JobMode(triggerer.OnFailureJobMode())
+	case u.IsDead() && !u.skipped():
+		// Unit has entered the inactive state successfully
+		names, mode = triggerer.OnSuccess(), JobMode(triggerer.OnSuccessJobMode())
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	u.Log.Printf("Triggering %v", names)
+	if err := u.System.StartWith(mode, names...); err != nil {
+		u.Log.Errorf("Error triggering %v: %s", names, err)
+	}
+}
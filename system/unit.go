@@ -1,11 +1,12 @@
 package system
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
-	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/plasma-umass/systemgo/unit"
@@ -22,13 +23,60 @@ type Unit struct {
 	// Unit log
 	Log *Log
 
-	name string
-	path string
-	load unit.Load
+	name    string
+	path    string
+	dropIns []string
+	load    unit.Load
+
+	// Modification times of the definition file and the drop-ins as of loading them
+	loadedFiles map[string]time.Time
+
+	// Warnings emitted while parsing the definition
+	warnings []string
+
+	// Description of the condition, which prevented the last start of the unit
+	condition string
+
+	// Description of the assertion, which failed the last start of the unit
+	assert string
+
+	// Results of the jobs of dependencies on the last job for the unit
+	dependencies []unit.DependencyStatus
+
+	// Automatic restarts since the manager started
+	restarts uint64
+
+	// Start attempts within the current start rate limit interval
+	starts   []time.Time
+	limitHit bool
+
+	// Units the unit conflicts with as of loading the definition
+	conflicting []string
+
+	// Properties set at runtime, which get applied again on reload
+	properties []unit.Property
+
+	// Whether the unit was created at runtime by StartTransient
+	transient bool
+
+	// Times the unit got activating and active on the last start
+	activating, activated time.Time
+
+	// Times the unit last entered and left the active and inactive states
+	times timestamps
+
+	// State reported by the processes of the unit since the last start
+	notification notification
+
+	// PID of the main process recorded in utmp, 0 if none
+	utmpPID int
 
 	job *job
 
 	mutex sync.Mutex
+
+	// Held for writing while the definition is loaded or changed and for reading while it is reported
+	definition sync.RWMutex
 }
 
 // TODO introduce a better workaround
@@ -50,11 +98,17 @@ func NewUnit(v unit.Interface) (u *Unit) {
 //return u.Name()
 //}
 
-// Path returns path to the defintion unit was loaded from
+// Path returns path to the defintion(fragment) unit was loaded from,
+// that is the one found in the load path with the highest precedence
 func (u *Unit) Path() string {
 	return u.path
 }
 
+// DropIns returns paths to drop-in files, which were merged into the definition of the unit
+func (u *Unit) DropIns() []string {
+	return u.dropIns
+}
+
 // Name returns the name of the unit(filename of the defintion)
 func (u *Unit) Name() string {
 	return u.name
@@ -62,9 +116,18 @@ func (u *Unit) Name() string {
 
 // Loaded returns load state of the unit
 func (u *Unit) Loaded() unit.Load {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
 	return u.load
 }
 
+// setLoad sets the load state of the unit
+func (u *Unit) setLoad(st unit.Load) {
+	u.mutex.Lock()
+	u.load = st
+	u.mutex.Unlock()
+}
+
 func (u *Unit) IsDead() bool {
 	return u.Active() == unit.Inactive
 }
@@ -85,6 +148,10 @@ func (u *Unit) IsLoaded() bool {
 	return u.Loaded() == unit.Loaded
 }
 
+func (u *Unit) IsMasked() bool {
+	return u.Loaded() == unit.Masked
+}
+
 // IsReloader returns whether u.Interface is capable of reloading
 func (u *Unit) IsReloader() (ok bool) {
 	_, ok = u.Interface.(unit.Reloader)
@@ -92,8 +159,8 @@ func (u *Unit) IsReloader() (ok bool) {
 }
 
 func (u *Unit) Active() (st unit.Activation) {
-	if u.jobRunning() {
-		switch u.job.typ {
+	if j := u.runningJob(); j != nil {
+		switch j.typ {
 		case start:
 			return unit.Activating
 		case stop:
@@ -103,12 +170,21 @@ func (u *Unit) Active() (st unit.Activation) {
 		}
 	}
 
+	if u.isLimitHit() || u.isFailed() {
+		return unit.Failed
+	}
+
 	return u.Interface.Active()
 }
 
 func (u *Unit) Sub() string {
-	if u.jobRunning() {
-		switch u.job.typ {
+	if j := u.runningJob(); j != nil {
+		// The step of the job reported by the unit itself, e.g. ExecStartPre= command running
+		if active := u.Interface.Active(); j.typ == start && active == unit.Activating || j.typ == stop && active == unit.Deactivating {
+			return u.Interface.Sub()
+		}
+
+		switch j.typ {
 		case start:
 			return starting
 		case stop:
@@ -118,29 +194,124 @@ func (u *Unit) Sub() string {
 		}
 	}
 
+	if u.isLimitHit() {
+		return startLimitHit
+	}
+
+	if u.isFailed() {
+		return failedSub
+	}
+
 	return u.Interface.Sub()
 }
 
+// JobID returns the ID of the job enqueued for u last, 0 if none was
+func (u *Unit) JobID() uint64 {
+	j := u.lastJob()
+	if j == nil {
+		return 0
+	}
+	return j.id
+}
+
 func (u *Unit) jobRunning() bool {
-	return u.job != nil && u.job.IsRunning()
+	return u.runningJob() != nil
+}
+
+// startTimes returns the times u got activating and active on the last start, the latter is zero, unless it did
+func (u *Unit) startTimes() (activating, activated time.Time) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.activating, u.activated
+}
+
+// skipped reports whether the last start of u was skipped, as a condition was not met
+func (u *Unit) skipped() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.condition != ""
+}
+
+// lastJob returns the job enqueued for u last, nil if none was
+func (u *Unit) lastJob() *job {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.job
+}
+
+// runningJob returns the job enqueued for u last, if it has not finished yet, nil otherwise
+func (u *Unit) runningJob() *job {
+	if j := u.lastJob(); j != nil && j.IsRunning() {
+		return j
+	}
+	return nil
+}
+
+// setJob makes j the job enqueued for u last
+func (u *Unit) setJob(j *job) {
+	u.mutex.Lock()
+	u.job = j
+	u.mutex.Unlock()
 }
 
 // Status returns status of the unit
 func (u *Unit) Status() unit.Status {
+	u.definition.RLock()
 	st := unit.Status{
+		Description: u.Description(),
+
 		Load: unit.LoadStatus{
-			Path:   u.Path(),
-			Loaded: u.Loaded(),
-			State:  -1, // TODO
+			Path:    u.path,
+			DropIns: u.dropIns,
+			Loaded:  u.Loaded(),
+			State:   -1,
 		},
 		Activation: unit.ActivationStatus{
 			State: u.Active(),
 			Sub:   u.Sub(),
 		},
 	}
+	u.definition.RUnlock()
+	st.Activation.Since = u.timestamps().since(st.Activation.State)
+
+	u.mutex.Lock()
+	st.Condition, st.Assert, st.Warnings, st.Dependencies = u.condition, u.assert, u.warnings, u.dependencies
+	u.mutex.Unlock()
+
+	if u.System != nil && u.IsLoaded() {
+		st.Load.State = u.Enabled()
+
+		st.Load.Vendor = unit.Disabled
+		if presets, err := u.System.vendorPresets(); err == nil && presets.Enabled(u.Name()) {
+			st.Load.Vendor = unit.Enabled
+		}
+
+		if st.Load.NeedDaemonReload = u.NeedDaemonReload(); st.Load.NeedDaemonReload {
+			st.Warnings = append(append([]string{}, st.Warnings...),
+				"The unit file or drop-ins changed on disk. Run 'systemctl daemon-reload' to reload units.")
+		}
+	}
+
+	if attacher, ok := u.Interface.(unit.Attacher); ok {
+		st.MainPID = attacher.MainPID()
+	}
+	if controller, ok := u.Interface.(unit.Controller); ok {
+		st.ControlPID = controller.ControlPID()
+	}
+	st.Processes, st.Memory, st.CPU = u.processes(st.MainPID, st.ControlPID)
 
+	u.mutex.Lock()
+	st.StatusText, st.StatusErrno, st.StatusBusError = u.notification.status, u.notification.errno, u.notification.busError
+	u.mutex.Unlock()
+
+	// The journal holds the output of the unit along with the messages of the manager
 	var err error
-	if st.Log, err = ioutil.ReadAll(u.Log); err != nil {
+	if u.System != nil && u.System.Journal != nil {
+		st.Log, err = u.System.statusLog(u.Name())
+	} else {
+		st.Log, err = ioutil.ReadAll(u.Log)
+	}
+	if err != nil {
 		u.Log.Errorf("Error reading log: %s", err)
 	}
 
@@ -154,115 +325,274 @@ func (u *Unit) Status() unit.Status {
 	}
 }
 
-// Requires returns a slice of unit names as found in definition and absolute paths
-// of units symlinked in units '.wants' directory
+// Requires returns a slice of unit names as found in definition, absolute paths
+// of units symlinked in units '.requires' directories and the mount units of the paths u accesses
 func (u *Unit) Requires() (names []string) {
-	names = u.Interface.Requires()
+	names = append(u.Interface.Requires(), u.readDepDirs("requires")...)
+	return append(names, u.mountDependencies()...)
+}
 
-	if paths, err := readDepDir(u.requiresDir()); err == nil {
-		names = append(names, paths...)
+// Wants returns a slice of unit names as found in definition and absolute paths
+// of units symlinked in units '.wants' directories
+func (u *Unit) Wants() (names []string) {
+	return append(u.Interface.Wants(), u.readDepDirs("wants")...)
+}
+
+// IgnoreOnIsolate returns whether u is left running, when another unit is isolated
+func (u *Unit) IgnoreOnIsolate() bool {
+	if isolator, ok := u.Interface.(unit.Isolator); ok {
+		return isolator.IgnoreOnIsolate()
 	}
+	return false
+}
 
-	return
+// StopWhenUnneeded returns whether u gets stopped, once no active unit requires or wants it
+func (u *Unit) StopWhenUnneeded() bool {
+	if unneeder, ok := u.Interface.(unit.Unneeder); ok {
+		return unneeder.StopWhenUnneeded()
+	}
+	return false
 }
 
-// Wants returns a slice of unit names as found in definition and absolute paths
-// of units symlinked in units '.wants' directory
-func (u *Unit) Wants() (names []string) {
-	names = u.Interface.Wants()
+// CollectMode returns whether u gets unloaded once inactive(CollectInactive) or failed as well(CollectInactiveOrFailed)
+func (u *Unit) CollectMode() string {
+	if collector, ok := u.Interface.(unit.Collector); ok && collector.CollectMode() == CollectInactiveOrFailed {
+		return CollectInactiveOrFailed
+	}
+	return CollectInactive
+}
 
-	if paths, err := readDepDir(u.wantsDir()); err == nil {
-		names = append(names, paths...)
+// RefuseManualStart returns whether u may only be started as a dependency
+func (u *Unit) RefuseManualStart() bool {
+	if refuser, ok := u.Interface.(unit.ManualRefuser); ok {
+		return refuser.RefuseManualStart()
 	}
+	return false
+}
 
-	return
+// RefuseManualStop returns whether u may only be stopped as a dependency
+func (u *Unit) RefuseManualStop() bool {
+	if refuser, ok := u.Interface.(unit.ManualRefuser); ok {
+		return refuser.RefuseManualStop()
+	}
+	return false
+}
+
+// BindsTo returns a slice of unit names as found in definition
+func (u *Unit) BindsTo() []string {
+	if binder, ok := u.Interface.(unit.Binder); ok {
+		return binder.BindsTo()
+	}
+	return nil
 }
 
-func (u *Unit) wantsDir() (path string) {
-	return u.depDir("wants")
+// Requisite returns a slice of unit names as found in definition
+func (u *Unit) Requisite() []string {
+	if binder, ok := u.Interface.(unit.Binder); ok {
+		return binder.Requisite()
+	}
+	return nil
 }
 
-func (u *Unit) requiresDir() (path string) {
-	return u.depDir("requires")
+// PartOf returns a slice of unit names as found in definition
+func (u *Unit) PartOf() []string {
+	if binder, ok := u.Interface.(unit.Binder); ok {
+		return binder.PartOf()
+	}
+	return nil
 }
 
-func (u *Unit) depDir(suffix string) (path string) {
-	return u.Path() + "." + suffix
+// PropagatesReloadTo returns a slice of unit names as found in definition
+func (u *Unit) PropagatesReloadTo() []string {
+	if propagator, ok := u.Interface.(unit.ReloadPropagator); ok {
+		return propagator.PropagatesReloadTo()
+	}
+	return nil
 }
 
-// Enable creates symlinks to u definition in dependency directories of each unit dependant on u
-func (u *Unit) Enable() (err error) {
-	err = u.System.getAndExecute(u.RequiredBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
-		}
+// ReloadPropagatedFrom returns a slice of unit names as found in definition
+func (u *Unit) ReloadPropagatedFrom() []string {
+	if propagator, ok := u.Interface.(unit.ReloadPropagator); ok {
+		return propagator.ReloadPropagatedFrom()
+	}
+	return nil
+}
 
-		return dep.addRequiresDep(u)
+// boundBy returns loaded units of u.System, which are bound to u by BindsTo= or PartOf=
+func (u *Unit) boundBy() []*Unit {
+	return u.dependents(func(other *Unit) []string {
+		return append(other.BindsTo(), other.PartOf()...)
 	})
-	if err != nil {
-		return
+}
+
+// reloadPropagated returns units, which reloads of u propagate to.
+// Those are units listed in PropagatesReloadTo= of u and loaded units listing u in ReloadPropagatedFrom=
+func (u *Unit) reloadPropagated() (units []*Unit) {
+	if u.System == nil {
+		return nil
 	}
 
-	return u.System.getAndExecute(u.WantedBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
+	for _, name := range u.PropagatesReloadTo() {
+		if dep, err := u.System.Get(name); err == nil {
+			units = append(units, dep)
 		}
+	}
+	return append(units, u.dependents(func(other *Unit) []string {
+		return other.ReloadPropagatedFrom()
+	})...)
+}
 
-		return dep.addWantsDep(u)
+// conflictedBy returns the units loaded from definitions, which conflict with u
+func (u *Unit) conflictedBy() []*Unit {
+	return u.dependents(func(other *Unit) []string {
+		return other.conflicting
 	})
 }
 
-func (u *Unit) addWantsDep(dep *Unit) (err error) {
-	return linkDep(u.wantsDir(), dep)
+// dependents returns loaded units of u.System, which refer to u in names returned by deps
+func (u *Unit) dependents(deps func(*Unit) []string) (units []*Unit) {
+	if u.System == nil {
+		return nil
+	}
+
+	for _, other := range u.System.Units() {
+		for _, name := range deps(other) {
+			if dep, err := u.System.Unit(name); err == nil && dep == u {
+				units = append(units, other)
+				break
+			}
+		}
+	}
+	return
+}
+
+// After returns a slice of unit names as found in definition and the implicit ordering dependencies of u
+func (u *Unit) After() []string {
+	after := append(u.Interface.After(), u.defaultDependencies().after...)
+	return append(after, u.mountDependencies()...)
 }
 
-func (u *Unit) addRequiresDep(dep *Unit) (err error) {
-	return linkDep(u.requiresDir(), dep)
+// Before returns a slice of unit names as found in definition and the implicit ordering dependencies of u
+func (u *Unit) Before() []string {
+	return append(u.Interface.Before(), u.defaultDependencies().before...)
 }
 
-func linkDep(dir string, dep *Unit) (err error) {
-	if err = os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
+// Conflicts returns a slice of unit names as found in definition and the implicit conflicts of u
+func (u *Unit) Conflicts() []string {
+	return append(u.Interface.Conflicts(), u.defaultDependencies().conflicts...)
+}
 
-	return os.Symlink(dep.Path(), filepath.Join(dir, dep.Name()))
+// implicitDeps holds dependencies units get, unless DefaultDependencies=no is specified
+type implicitDeps struct {
+	after, before, conflicts []string
 }
 
-// Disable removes symlinks(if they exist) created by Enable
-func (u *Unit) Disable() (err error) {
-	err = u.System.getAndExecute(u.RequiredBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
-		}
+// defaultDeps maps unit suffixes to default dependencies of units of that type -- https://www.freedesktop.org/software/systemd/man/systemd.special.html
+var defaultDeps = map[string]implicitDeps{
+	".service": {
+		after:     []string{"basic.target"},
+		before:    []string{"shutdown.target"},
+		conflicts: []string{"shutdown.target"},
+	},
+	".mount": {
+		after:     []string{"local-fs-pre.target"},
+		before:    []string{"local-fs.target", "umount.target"},
+		conflicts: []string{"umount.target"},
+	},
+	".socket": {
+		before:    []string{"sockets.target", "shutdown.target"},
+		conflicts: []string{"shutdown.target"},
+	},
+	".target": {
+		before:    []string{"shutdown.target"},
+		conflicts: []string{"shutdown.target"},
+	},
+}
 
-		return dep.removeRequiresDep(u)
-	})
-	if err != nil {
+// defaultDependencies returns default dependencies of u.
+// Dependencies on u itself are omitted
+func (u *Unit) defaultDependencies() (deps implicitDeps) {
+	defaulter, ok := u.Interface.(unit.Defaulter)
+	if !ok || !defaulter.DefaultDependencies() {
 		return
 	}
 
-	return u.System.getAndExecute(u.WantedBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
+	filter := func(names []string) (filtered []string) {
+		for _, name := range names {
+			if name != u.Name() {
+				filtered = append(filtered, name)
+			}
 		}
+		return
+	}
 
-		return dep.removeWantsDep(u)
-	})
+	implicit := defaultDeps[filepath.Ext(u.Name())]
+	return implicitDeps{
+		after:     filter(append(implicit.after, u.targetDependencies()...)),
+		before:    filter(implicit.before),
+		conflicts: filter(implicit.conflicts),
+	}
 }
 
-func (u *Unit) removeWantsDep(dep *Unit) (err error) {
-	return unlinkDep(u.wantsDir(), dep)
-}
+// targetDependencies returns the units a target u is implicitly ordered after, i.e. the ones it wants or requires.
+// Units with no default dependencies and the ones, which would form an ordering cycle, are omitted
+func (u *Unit) targetDependencies() (names []string) {
+	if _, ok := u.Interface.(*Target); !ok || u.System == nil {
+		return
+	}
+
+	before, after := map[string]bool{}, map[string]bool{}
+	for _, name := range u.Interface.Before() {
+		before[name] = true
+	}
+	for _, name := range u.Interface.After() {
+		after[name] = true
+	}
+
+	for _, name := range append(u.Requires(), u.Wants()...) {
+		dep, err := u.System.Unit(name)
+		if err != nil || !dep.IsLoaded() || before[dep.Name()] || after[name] {
+			continue
+		}
+
+		if defaulter, ok := dep.Interface.(unit.Defaulter); !ok || !defaulter.DefaultDependencies() {
+			continue
+		}
 
-func (u *Unit) removeRequiresDep(dep *Unit) (err error) {
-	return unlinkDep(u.requiresDir(), dep)
+		cycle := false
+		for _, after := range append(dep.Interface.After(), defaultDeps[filepath.Ext(dep.Name())].after...) {
+			cycle = cycle || after == u.Name()
+		}
+		if !cycle {
+			names = append(names, name)
+		}
+	}
+	return
 }
 
-func unlinkDep(dir string, dep *Unit) (err error) {
-	if err = os.Remove(filepath.Join(dir, dep.Name())); err != nil && !os.IsNotExist(err) {
-		return
+// readDepDirs returns absolute paths of units symlinked in dependency directories
+// of u with suffix specified. The directories are looked up next to the
+// definition of u and in each of the paths of u.System
+func (u *Unit) readDepDirs(suffix string) (paths []string) {
+	dirs := []string{u.Path() + "." + suffix}
+	if u.System != nil {
+		for _, path := range u.System.unitPaths() {
+			dirs = append(dirs, filepath.Join(path, u.Name()+"."+suffix))
+		}
 	}
-	return nil
+
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		if dir = filepath.Clean(dir); seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		if found, err := u.readDepDir(dir); err == nil {
+			paths = append(paths, found...)
+		}
+	}
+	return
 }
 
 // Reload creates a new reload transaction and runs it
@@ -297,17 +627,120 @@ func (u *Unit) Start() (err error) {
 	return tr.Run()
 }
 
-func (u *Unit) start() (err error) {
+func (u *Unit) start(ctx context.Context) (err error) {
 	e := log.WithField("unit", u.Name())
 	e.Debugf("u.start")
 
+	if u.IsMasked() {
+		e.Debug("masked")
+		return ErrMasked
+	}
+
 	if !u.IsLoaded() {
 		e.Debug("not loaded")
 		return ErrNotLoaded
 	}
 
+	if conditioner, ok := u.Interface.(unit.Conditioner); ok {
+		met, failed := unit.CheckConditions(conditioner.Conditions())
+		u.mutex.Lock()
+		u.condition = ""
+		if !met {
+			u.condition = failed.String()
+		}
+		u.mutex.Unlock()
+
+		if !met {
+			// Unit is skipped, but the job succeeds
+			e.Debugf("condition %s not met", failed)
+			u.Log.Printf("Condition check resulted in unit being skipped: %s", failed)
+			return nil
+		}
+	}
+
+	if asserter, ok := u.Interface.(unit.Asserter); ok {
+		met, failed := unit.CheckConditions(asserter.Asserts())
+		u.mutex.Lock()
+		u.assert = ""
+		if !met {
+			u.assert = failed.String()
+		}
+		u.mutex.Unlock()
+
+		if !met {
+			e.Debugf("assertion %s failed", failed)
+			u.Log.Errorf("Assertion failed: %s", failed)
+			return ErrAssert
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return ErrCanceled
+	}
+
+	if !u.allowStart() {
+		e.Debug("start limit hit")
+		u.Log.Errorf("Start request repeated too quickly")
+		return ErrStartLimit
+	}
+
 	u.Log.Println("Starting...")
 
+	if u.System != nil {
+		// Starting anew clears the failure
+		u.System.clearFailure(u)
+	}
+	u.mutex.Lock()
+	u.activating, u.activated = time.Now(), time.Time{}
+	u.notification = notification{}
+	u.mutex.Unlock()
+	u.entered(unit.Activating)
+
+	defer func() {
+		if err != nil {
+			if !notFailures[err] {
+				u.entered(unit.Failed)
+			} else {
+				u.entered(u.Interface.Active())
+			}
+			return
+		}
+
+		u.mutex.Lock()
+		u.activated = time.Now()
+		u.mutex.Unlock()
+		u.entered(u.Interface.Active())
+		if err := u.applyResources(); err != nil {
+			u.Log.Errorf("Error applying resource controls: %s", err)
+		}
+		if u.System != nil {
+			u.System.recordInit(u)
+		}
+	}()
+
+	if u.isIdle() && u.System != nil {
+		u.System.waitForIdle(ctx, u)
+	}
+
+	if starter, ok := u.Interface.(unit.ContextStarter); ok {
+		startCtx, cancel := u.startContext(ctx)
+		defer cancel()
+
+		e.Debugf("Interface.StartContext")
+		if err = starter.StartContext(startCtx); err == nil {
+			err = u.waitForBusName(startCtx)
+		}
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			return ErrCanceled
+		case startCtx.Err() != nil:
+			u.Log.Errorf("Start operation timed out")
+			return ErrStartTimeout
+		}
+		return
+	}
+
 	starter, ok := u.Interface.(unit.Starter)
 	if !ok {
 		e.Debugf("Interface is not unit.Starter")
@@ -329,14 +762,38 @@ func (u *Unit) Stop() (err error) {
 	return tr.Run()
 }
 
-func (u *Unit) stop() (err error) {
+func (u *Unit) stop(ctx context.Context) (err error) {
 	log.WithField("u", u).Debugf("u.stop")
 
 	if !u.IsLoaded() {
 		return ErrNotLoaded
 	}
 
+	if err = ctx.Err(); err != nil {
+		return ErrCanceled
+	}
+
 	u.Log.Println("Stopping...")
+	u.entered(unit.Deactivating)
+
+	defer func() {
+		st := u.Interface.Active()
+		switch {
+		case err != nil && !notFailures[err]:
+			st = unit.Failed
+		case err == nil && !isInactiveState(st):
+			// The processes killed may not have been reaped yet
+			st = unit.Inactive
+		}
+		u.entered(st)
+	}()
+
+	if stopper, ok := u.Interface.(unit.ContextStopper); ok {
+		if err = stopper.StopContext(ctx); err != nil && ctx.Err() != nil {
+			return ErrCanceled
+		}
+		return
+	}
 
 	stopper, ok := u.Interface.(unit.Stopper)
 	if !ok {
@@ -346,15 +803,21 @@ func (u *Unit) stop() (err error) {
 	return stopper.Stop()
 }
 
-func readDepDir(dir string) (paths []string, err error) {
+// readDepDir returns the paths the links found in dir point to, resolved inside the root of u.System
+func (u *Unit) readDepDir(dir string) (paths []string, err error) {
 	var links []string
 	if links, err = pathset(dir); err != nil {
 		return
 	}
 
+	eval := filepath.EvalSymlinks
+	if u.System != nil {
+		eval = u.System.evalSymlinks
+	}
+
 	paths = make([]string, 0, len(links))
 	for _, path := range links {
-		if path, err = filepath.EvalSymlinks(path); err != nil {
+		if path, err = eval(path); err != nil {
 			return
 		}
 		paths = append(paths, path)
@@ -0,0 +1,127 @@
+package system
+
+import (
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/plasma-umass/systemgo/utmp"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// runlevels maps the targets to the SysV runlevels recorded in utmp, the first one active is the current runlevel
+var runlevels = []struct {
+	target string
+	level  byte
+}{
+	{"graphical.target", '5'},
+	{"multi-user.target", '3'},
+	{RESCUE_TARGET, '1'},
+}
+
+// utmpFiles are the paths to the utmp and wtmp files the login records get written to
+// and the runlevel last recorded
+type utmpFiles struct {
+	utmpPath, wtmpPath string
+	runlevel           byte
+}
+
+// SetUtmp makes sys write the boot, runlevel and shutdown records and the ones of the processes started
+// with UtmpIdentifier= to the utmp file at utmpPath and append them to the wtmp file at wtmpPath, as systemd-update-utmp does.
+// Either file is not written, if its path is empty
+func (sys *Daemon) SetUtmp(utmpPath, wtmpPath string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	sys.utmp.utmpPath, sys.utmp.wtmpPath = utmpPath, wtmpPath
+}
+
+// writeUtmp writes r to the utmp and wtmp files set
+func (sys *Daemon) writeUtmp(r utmp.Record) {
+	sys.mutex.Lock()
+	files := sys.utmp
+	sys.mutex.Unlock()
+
+	if files.utmpPath == "" && files.wtmpPath == "" {
+		return
+	}
+	if err := utmp.Write(files.utmpPath, files.wtmpPath, r); err != nil {
+		log.Warnf("Error writing utmp record: %s", err)
+	}
+}
+
+// recordRunlevel records the change of the runlevel to level, unless it was recorded already
+func (sys *Daemon) recordRunlevel(level byte) {
+	sys.mutex.Lock()
+	previous := sys.utmp.runlevel
+	sys.utmp.runlevel = level
+	sys.mutex.Unlock()
+
+	if level != 0 && level != previous {
+		sys.writeUtmp(utmp.Runlevel(level, previous))
+	}
+}
+
+// recordBoot records the boot at the time sys started and the runlevel the system booted to
+func (sys *Daemon) recordBoot() {
+	sys.writeUtmp(utmp.Boot(sys.Since()))
+	sys.recordRunlevel(sys.runlevel())
+}
+
+// recordShutdown records the change to the runlevel of action("poweroff", "reboot" or "halt") and the system shutting down
+func (sys *Daemon) recordShutdown(action string) {
+	level := byte('0')
+	if action == "reboot" {
+		level = '6'
+	}
+	sys.recordRunlevel(level)
+	sys.writeUtmp(utmp.Shutdown())
+}
+
+// runlevel returns the runlevel of the targets active, 0 if none
+func (sys *Daemon) runlevel() byte {
+	for _, rl := range runlevels {
+		if u, err := sys.Unit(rl.target); err == nil && u.IsActive() {
+			return rl.level
+		}
+	}
+	return 0
+}
+
+// recordInit records the main process of u started, if it has an utmp identifier
+func (sys *Daemon) recordInit(u *Unit) {
+	recorder, ok := u.Interface.(unit.UtmpRecorder)
+	if !ok {
+		return
+	}
+	attacher, ok := u.Interface.(unit.Attacher)
+	if !ok {
+		return
+	}
+
+	id, line := recorder.Utmp()
+	pid := attacher.MainPID()
+	if id == "" || pid == 0 {
+		return
+	}
+
+	u.mutex.Lock()
+	u.utmpPID = pid
+	u.mutex.Unlock()
+	sys.writeUtmp(utmp.Init(id, line, pid))
+}
+
+// recordDead records the main process pid of u exited, if it was recorded started
+func (sys *Daemon) recordDead(u *Unit, pid int) {
+	u.mutex.Lock()
+	recorded := pid != 0 && u.utmpPID == pid
+	if recorded {
+		u.utmpPID = 0
+	}
+	u.mutex.Unlock()
+
+	if !recorded {
+		return
+	}
+	if recorder, ok := u.Interface.(unit.UtmpRecorder); ok {
+		id, line := recorder.Utmp()
+		sys.writeUtmp(utmp.Dead(id, line, pid))
+	}
+}
@@ -0,0 +1,73 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/plasma-umass/systemgo/utmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtmp(t *testing.T) {
+	path, err := ioutil.TempDir("", "utmp-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "getty.service"), []byte(`[Unit]
+DefaultDependencies=no
+[Service]
+ExecStart=/bin/sleep 10
+UtmpIdentifier=tty9
+TTYPath=/dev/tty9`), 0666), "ioutil.WriteFile")
+
+	utmpPath, wtmpPath := filepath.Join(path, "utmp"), filepath.Join(path, "wtmp")
+
+	sys := New()
+	sys.SetPaths(path)
+	sys.SetUtmp(utmpPath, wtmpPath)
+
+	sys.recordBoot()
+
+	require.NoError(t, sys.Start("getty.service"), "sys.Start")
+	waitForJobs(t, sys, "getty.service")
+
+	u, err := sys.Unit("getty.service")
+	require.NoError(t, err)
+	pid := u.Interface.(unit.Attacher).MainPID()
+	require.NotZero(t, pid, "MainPID")
+
+	require.NoError(t, sys.Stop("getty.service"), "sys.Stop")
+	waitForJobs(t, sys, "getty.service")
+	sys.recordDead(u, pid)
+
+	sys.recordShutdown("reboot")
+
+	records, err := utmp.Read(utmpPath)
+	require.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "reboot", records[0].User)
+		assert.Equal(t, sys.Since().Unix(), records[0].Time.Unix())
+
+		assert.EqualValues(t, utmp.DeadProcess, records[1].Type)
+		assert.Equal(t, "tty9", records[1].ID)
+		assert.Equal(t, "tty9", records[1].Line)
+		assert.EqualValues(t, pid, records[1].PID)
+
+		assert.Equal(t, "shutdown", records[2].User, "shutdown record supersedes runlevel")
+	}
+
+	records, err = utmp.Read(wtmpPath)
+	require.NoError(t, err)
+	types := make([]int16, len(records))
+	for i, r := range records {
+		types[i] = r.Type
+	}
+	assert.Equal(t, []int16{utmp.BootTime, utmp.InitProcess, utmp.DeadProcess, utmp.RunLevel, utmp.RunLevel}, types)
+	if assert.Len(t, records, 5) {
+		assert.EqualValues(t, '6', records[3].PID, "runlevel without previous one")
+	}
+}
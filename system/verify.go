@@ -0,0 +1,101 @@
+package system
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Verify loads the unit files found at paths specified and checks them for problems
+// without starting any units. Units referred to by the files are searched for in the
+// directories of the files first and in the paths of sys afterwards.
+// Problems found are returned keyed by path, the ones without problems are omitted
+func (sys *Daemon) Verify(paths ...string) (problems map[string][]string) {
+	log.WithField("paths", paths).Debugf("sys.Verify")
+
+	problems = map[string][]string{}
+
+	// The paths of the caller are left alone
+	paths = append([]string{}, paths...)
+
+	dirs := []string{}
+	for i, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			paths[i] = abs
+		}
+		dirs = append(dirs, filepath.Dir(paths[i]))
+	}
+	sys.SetPaths(append(dirs, sys.Paths()...)...)
+
+	for _, path := range paths {
+		if msgs := sys.verify(path); len(msgs) > 0 {
+			problems[path] = msgs
+		}
+	}
+	return
+}
+
+// verify returns the problems found in the unit file at path
+func (sys *Daemon) verify(path string) (msgs []string) {
+	// Directory of path is searched first, loading by name makes
+	// the unit resolvable when referred to by other units
+	u, err := sys.Get(filepath.Base(path))
+	if err == nil && u.Path() != path {
+		u, err = sys.Get(path)
+	}
+	if err != nil {
+		return append(msgs, errorMessages(err)...)
+	}
+
+	u.mutex.Lock()
+	loadWarnings := u.warnings
+	u.mutex.Unlock()
+	for _, w := range loadWarnings {
+		msgs = append(msgs, "Warning: "+w)
+	}
+
+	warnings := len(msgs)
+	for directive, names := range map[string][]string{
+		"Requires":  u.Requires(),
+		"BindsTo":   u.BindsTo(),
+		"Requisite": u.Requisite(),
+	} {
+		for _, name := range names {
+			if _, err := sys.Get(name); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s=%s: %s", directive, name, err))
+			}
+		}
+	}
+
+	if len(msgs) > warnings {
+		// Transaction can not be built with dependencies missing
+		return
+	}
+
+	// Build a start transaction to detect ordering cycles, the transaction is never run
+	tr := newTransaction()
+	if err = tr.add(start, u, nil, true, true); err != nil && err != ErrRequisite {
+		return append(msgs, errorMessages(err)...)
+	}
+	if err = tr.merge(); err == nil {
+		_, err = tr.order()
+	}
+	if err != nil {
+		msgs = append(msgs, err.Error())
+	}
+	return
+}
+
+// errorMessages returns the messages of errors contained in err
+func errorMessages(err error) []string {
+	switch err := err.(type) {
+	case unit.MultiError:
+		return err.Errors()
+	case unit.Warnings:
+		return err.Errors()
+	default:
+		return []string{err.Error()}
+	}
+}
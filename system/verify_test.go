@@ -0,0 +1,77 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	path, err := ioutil.TempDir("", "verify-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	files := map[string]string{
+		"good.service": `[Unit]
+Requires=dep.service
+[Service]
+ExecStart=/bin/true`,
+		"dep.service": `[Service]
+ExecStart=/bin/true`,
+		"noexec.service": `[Unit]
+Description=%Q`,
+		"typo.service": `[Unit]
+Requires=missing.service
+[Service]
+ExecStrat=/bin/true
+ExecStart=/bin/true`,
+		"a.service": `[Unit]
+Requires=b.service
+After=b.service
+[Service]
+ExecStart=/bin/true`,
+		"b.service": `[Unit]
+After=a.service
+[Service]
+ExecStart=/bin/true`,
+	}
+	for name, contents := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0666), "ioutil.WriteFile")
+	}
+
+	paths := []string{}
+	for name := range files {
+		paths = append(paths, filepath.Join(path, name))
+	}
+
+	problems := New().Verify(paths...)
+
+	for _, name := range []string{"good.service", "dep.service"} {
+		assert.NotContains(t, problems, filepath.Join(path, name), name)
+	}
+
+	if assert.Contains(t, problems, filepath.Join(path, "noexec.service")) {
+		assert.Len(t, problems[filepath.Join(path, "noexec.service")], 1, "unresolvable specifier")
+	}
+
+	if msgs := problems[filepath.Join(path, "typo.service")]; assert.Len(t, msgs, 2, "typo.service") {
+		assert.Contains(t, msgs, "Warning: "+filepath.Join(path, "typo.service")+":4: ExecStrat: "+unit.ErrUnknownDirective.Error())
+		assert.Contains(t, msgs, "Requires=missing.service: "+ErrNotFound.Error())
+	}
+
+	assert.Contains(t, problems, filepath.Join(path, "a.service"), "ordering cycle")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err, "os.Getwd")
+	rel, err := filepath.Rel(wd, filepath.Join(path, "good.service"))
+	require.NoError(t, err, "filepath.Rel")
+
+	args := []string{rel}
+	assert.Empty(t, New().Verify(args...), "relative path")
+	assert.Equal(t, []string{rel}, args, "paths of the caller left alone")
+}
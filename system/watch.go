@@ -0,0 +1,176 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsnotify/fsnotify"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Time the changes of the unit files are collected for, before the units affected get reloaded
+var AUTO_RELOAD_DELAY = 500 * time.Millisecond
+
+// WatchPaths makes sys watch the unit paths and reload the units, which definition files or drop-ins
+// get added, changed or removed, as daemon-reload would, but without rerunning the generators
+// and touching the units not affected. A watch set previously is stopped
+func (sys *Daemon) WatchPaths() (err error) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if sys.watcher != nil {
+		sys.watcher.Close()
+		sys.watcher = nil
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+
+	for _, path := range sys.unitPaths() {
+		if err := w.Add(path); err != nil {
+			// The path may not exist
+			continue
+		}
+
+		dropIns, _ := filepath.Glob(filepath.Join(path, "*.d"))
+		for _, dir := range dropIns {
+			if err := w.Add(dir); err != nil {
+				log.Warnf("Error watching %s: %s", dir, err)
+			}
+		}
+	}
+
+	sys.watcher = w
+	go sys.watch(w, AUTO_RELOAD_DELAY)
+	return nil
+}
+
+// watch reloads the units affected by the changes reported by w, collected for period, until w is closed
+func (sys *Daemon) watch(w *fsnotify.Watcher, period time.Duration) {
+	changed := map[string]bool{}
+	var delay <-chan time.Time
+
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if ev.Op&fsnotify.Create != 0 && strings.HasSuffix(ev.Name, ".d") {
+				// Drop-in directory created
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := w.Add(ev.Name); err != nil {
+						log.Warnf("Error watching %s: %s", ev.Name, err)
+					}
+				}
+			}
+
+			if name := changedUnit(ev.Name); name != "" {
+				log.Debugf("Definition of %s changed on disk", name)
+				changed[name] = true
+				delay = time.After(period)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("Error watching unit paths: %s", err)
+		case <-delay:
+			sys.reloadChanged(changed)
+			changed = map[string]bool{}
+			delay = nil
+		}
+	}
+}
+
+// reloadChanged reloads the units loaded, which are named in names or are instances of the templates named
+func (sys *Daemon) reloadChanged(names map[string]bool) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for _, u := range sys.Units() {
+		if names[u.Name()] || unit.IsInstance(u.Name()) && names[unit.TemplateOf(u.Name())] {
+			log.Infof("Reloading %s, its definition changed on disk", u.Name())
+			sys.reload(u)
+		}
+	}
+}
+
+// changedUnit returns the name of the unit, which definition is changed by a change of path,
+// either the definition file, a drop-in or a drop-in directory. Empty, if path defines no unit
+func changedUnit(path string) string {
+	if name := filepath.Base(path); Supported(name) {
+		return name
+	} else if name = strings.TrimSuffix(name, ".d"); name != filepath.Base(path) && Supported(name) {
+		return name
+	}
+
+	if dir := filepath.Base(filepath.Dir(path)); strings.HasSuffix(dir, ".d") && strings.HasSuffix(path, ".conf") {
+		if name := strings.TrimSuffix(dir, ".d"); Supported(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// NeedDaemonReload reports whether the definition file or the drop-ins of u were added, changed or removed
+// on disk since they were loaded
+func (u *Unit) NeedDaemonReload() bool {
+	u.definition.RLock()
+	defer u.definition.RUnlock()
+
+	if u.System == nil || u.transient || u.path == "" && !u.isBuiltin() {
+		return false
+	}
+
+	path := ""
+	for _, p := range u.System.definitionPaths(u.Name()) {
+		if _, err := os.Lstat(p); err == nil {
+			path = p
+			break
+		}
+	}
+	if path != u.path {
+		return true
+	}
+	if path == "" {
+		// Built-in, no definition file found
+		return false
+	}
+
+	files := modTimes(append([]string{path}, u.System.dropInPaths(u.Name())...))
+	if len(files) != len(u.loadedFiles) {
+		return true
+	}
+	for p, t := range files {
+		if loaded, ok := u.loadedFiles[p]; !ok || !loaded.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTimes returns the modification times of the files at paths, which exist.
+// The time of masked definitions is zero
+func modTimes(paths []string) (times map[string]time.Time) {
+	times = make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if isMasked(path) {
+			times[path] = time.Time{}
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedUnit(t *testing.T) {
+	for path, name := range map[string]string{
+		"/etc/systemgo/system/foo.service":               "foo.service",
+		"/etc/systemgo/system/foo@.service":              "foo@.service",
+		"/etc/systemgo/system/foo.service.d":             "foo.service",
+		"/etc/systemgo/system/foo.service.d/10-bar.conf": "foo.service",
+		"/etc/systemgo/system/foo.service.d/README":      "",
+		"/etc/systemgo/system/foo.conf":                  "",
+	} {
+		assert.Equal(t, name, changedUnit(path), path)
+	}
+}
+
+func TestWatchPaths(t *testing.T) {
+	path, err := ioutil.TempDir("", "watch-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target"), []byte("[Unit]\nDescription=old"), 0644))
+
+	sys := New()
+	sys.SetPaths(path)
+
+	a, err := sys.Get("a.target")
+	require.NoError(t, err, "sys.Get")
+	assert.False(t, a.NeedDaemonReload(), "reload needed once loaded")
+
+	require.NoError(t, os.Mkdir(filepath.Join(path, "a.target.d"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target.d", "10-new.conf"), []byte("[Unit]\nDescription=new"), 0644))
+	assert.True(t, a.NeedDaemonReload(), "reload not needed with drop-in added")
+	assert.Contains(t, a.Status().Warnings[len(a.Status().Warnings)-1], "daemon-reload")
+
+	sys.DaemonReload()
+	assert.False(t, a.NeedDaemonReload(), "reload needed once reloaded")
+
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(path, "a.target"), future, future))
+	assert.True(t, a.NeedDaemonReload(), "reload not needed with definition changed")
+	sys.DaemonReload()
+
+	defer func(delay time.Duration) { AUTO_RELOAD_DELAY = delay }(AUTO_RELOAD_DELAY)
+	AUTO_RELOAD_DELAY = 10 * time.Millisecond
+	require.NoError(t, sys.WatchPaths(), "sys.WatchPaths")
+	defer sys.watcher.Close()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target.d", "10-new.conf"), []byte("[Unit]\nDescription=watched"), 0644))
+	for timeout := time.After(5 * time.Second); a.Status().Description != "watched"; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("a.target not reloaded")
+		default:
+		}
+	}
+
+	require.NoError(t, os.Remove(filepath.Join(path, "a.target")))
+	for timeout := time.After(5 * time.Second); a.Loaded() != unit.NotFound; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("a.target not marked not found")
+		default:
+		}
+	}
+}
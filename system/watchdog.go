@@ -0,0 +1,111 @@
+package system
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Watchdog is a hardware watchdog, which resets the machine, unless it is pinged within its timeout
+type Watchdog interface {
+	Ping() error
+	SetTimeout(time.Duration) error
+
+	// Close disarms the watchdog
+	Close() error
+}
+
+// watchdog is the hardware watchdog of the manager
+type watchdog struct {
+	device Watchdog
+
+	// Timeouts of the watchdog while running, as RuntimeWatchdogSec= of systemd, and while rebooting,
+	// as RebootWatchdogSec=. The watchdog is disarmed on shutdown, if reboot is 0
+	runtime, reboot time.Duration
+
+	// Closed to stop pinging
+	stop chan struct{}
+}
+
+// SetWatchdog makes sys ping w each half of runtime, the timeout of w, so that a hung manager gets the machine reset.
+// Once the units are stopped on shutdown, the timeout of w is set to reboot and pinging stops, so that the reboot
+// hanging gets it reset as well. A watchdog set previously is disarmed, so is w, if runtime is not positive
+func (sys *Daemon) SetWatchdog(w Watchdog, runtime, reboot time.Duration) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.closeWatchdog()
+	if runtime <= 0 {
+		if err := w.Close(); err != nil {
+			log.Errorf("Error disarming watchdog: %s", err)
+		}
+		return
+	}
+
+	sys.watchdog = watchdog{device: w, runtime: runtime, reboot: reboot, stop: make(chan struct{})}
+	go sys.pingWatchdog(w, runtime/2, sys.watchdog.stop)
+}
+
+// pingWatchdog pings w each interval until stop is closed.
+// The mutex of sys is acquired first, so that the manager deadlocked does not get w pinged
+func (sys *Daemon) pingWatchdog(w Watchdog, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		sys.mutex.Lock()
+		select {
+		case <-stop:
+			sys.mutex.Unlock()
+			return
+		default:
+		}
+		if err := w.Ping(); err != nil {
+			log.Errorf("Error pinging watchdog: %s", err)
+		}
+		sys.mutex.Unlock()
+	}
+}
+
+// armRebootWatchdog stops pinging the watchdog and sets its timeout to the reboot one, if action is "reboot".
+// The watchdog gets disarmed otherwise or if the reboot timeout is 0, the machine powered off or halted is not reset
+func (sys *Daemon) armRebootWatchdog(action string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	wd := &sys.watchdog
+	if wd.device == nil {
+		return
+	}
+	if action != "reboot" || wd.reboot <= 0 {
+		sys.closeWatchdog()
+		return
+	}
+
+	close(wd.stop)
+	log.Infof("Setting watchdog timeout to %s for reboot", wd.reboot)
+	if err := wd.device.SetTimeout(wd.reboot); err != nil {
+		log.Errorf("Error setting watchdog timeout: %s", err)
+	}
+	wd.device = nil
+}
+
+// closeWatchdog stops pinging the watchdog and disarms it, the mutex of sys must be locked
+func (sys *Daemon) closeWatchdog() {
+	wd := &sys.watchdog
+	if wd.device == nil {
+		return
+	}
+
+	close(wd.stop)
+	if err := wd.device.Close(); err != nil {
+		log.Errorf("Error disarming watchdog: %s", err)
+	}
+	wd.device = nil
+}
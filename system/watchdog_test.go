@@ -0,0 +1,77 @@
+package system
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testWatchdog struct {
+	mutex   sync.Mutex
+	pings   int
+	timeout time.Duration
+	closed  bool
+}
+
+func (w *testWatchdog) Ping() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pings++
+	return nil
+}
+
+func (w *testWatchdog) SetTimeout(d time.Duration) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.timeout = d
+	return nil
+}
+
+func (w *testWatchdog) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *testWatchdog) state() (int, time.Duration, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.pings, w.timeout, w.closed
+}
+
+func TestWatchdog(t *testing.T) {
+	sys := New()
+
+	w := &testWatchdog{}
+	sys.SetWatchdog(w, 20*time.Millisecond, time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	pings, _, _ := w.state()
+	assert.True(t, pings > 0, "watchdog not pinged")
+
+	// The manager holding its mutex does not get the watchdog pinged
+	sys.mutex.Lock()
+	pings, _, _ = w.state()
+	time.Sleep(50 * time.Millisecond)
+	after, _, _ := w.state()
+	sys.mutex.Unlock()
+	assert.Equal(t, pings, after, "watchdog pinged by manager hung")
+
+	sys.armRebootWatchdog("reboot")
+	pings, timeout, closed := w.state()
+	assert.Equal(t, time.Minute, timeout, "reboot timeout")
+	assert.False(t, closed, "watchdog disarmed on reboot")
+
+	time.Sleep(50 * time.Millisecond)
+	after, _, _ = w.state()
+	assert.Equal(t, pings, after, "watchdog pinged after reboot armed")
+
+	w = &testWatchdog{}
+	sys.SetWatchdog(w, 20*time.Millisecond, time.Minute)
+	sys.armRebootWatchdog("poweroff")
+	_, _, closed = w.state()
+	assert.True(t, closed, "watchdog not disarmed on poweroff")
+}
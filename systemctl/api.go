@@ -0,0 +1,263 @@
+package systemctl
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// API serves the management API using HTTP with JSON bodies:
+//
+//	GET /units                              statuses of the units loaded
+//	GET /units/{name}                       status of the unit
+//	POST /units/{name}/{start|stop|restart|reload}[?mode=...]  enqueues a job for the unit
+//	GET /jobs                               jobs queued
+//	GET /jobs/{id}                          job queued
+//	DELETE /jobs/{id}                       cancels the job
+//	GET /journal[?unit=...]                 log lines of the units
+//
+// Only the requests received on a Unix socket from the clients authorized may mutate the state of the system
+type API struct {
+	sys  Daemon
+	auth *Authorizer
+}
+
+// NewAPI returns an API serving the requests using sys and authorizing the clients using auth
+func NewAPI(sys Daemon, auth *Authorizer) *API {
+	return &API{sys, auth}
+}
+
+// JobReply is the reply to a request enqueueing a job
+type JobReply struct {
+	Unit string `json:"Unit"`
+
+	// ID of the job enqueued last for the unit
+	Job uint64 `json:"Job"`
+}
+
+// Journal is the log of a unit
+type Journal struct {
+	Unit  string   `json:"Unit"`
+	Lines []string `json:"Lines"`
+}
+
+// apiError is the body of the replies to the requests failed
+type apiError struct {
+	Error string `json:"Error"`
+}
+
+// mutateKey is the key of the value in the context of a request, which is true if its client may mutate the state
+type mutateKey struct{}
+
+// Listen serves requests on addr until an error occurs.
+// addr is either a path to a Unix socket, which is replaced, if it exists, or a TCP address
+func (a *API) Listen(addr string) (err error) {
+	l, err := listenHTTP(addr)
+	if err != nil {
+		return
+	}
+	defer l.Close()
+
+	log.Infof("Serving the API on %s", addr)
+	return (&http.Server{Handler: a, ConnContext: a.connContext}).Serve(l)
+}
+
+// listenHTTP listens on addr, either a path to a Unix socket, which is replaced, if it exists, or a TCP address
+func listenHTTP(addr string) (l net.Listener, err error) {
+	network := "tcp"
+	if filepath.IsAbs(addr) {
+		network = "unix"
+
+		if err = os.MkdirAll(filepath.Dir(addr), 0755); err != nil {
+			return
+		}
+		if err = os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+
+	if l, err = net.Listen(network, addr); err != nil {
+		return
+	}
+
+	if network == "unix" {
+		if err = os.Chmod(addr, 0666); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// connContext records in ctx, whether the client on the other end of conn may mutate the state
+func (a *API) connContext(ctx context.Context, conn net.Conn) context.Context {
+	mutate := false
+	if uconn, ok := conn.(*net.UnixConn); ok {
+		if p, err := peerCred(uconn); err == nil {
+			mutate = a.auth.Authorized(p.uid, p.gid)
+		}
+	}
+	return context.WithValue(ctx, mutateKey{}, mutate)
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch {
+	case path[0] == "units" && len(path) == 1 && r.Method == http.MethodGet:
+		v = a.units()
+	case path[0] == "units" && len(path) == 2 && r.Method == http.MethodGet:
+		v, err = a.sys.StatusOf(path[1])
+	case path[0] == "units" && len(path) == 3 && r.Method == http.MethodPost:
+		if err = a.authorize(r); err == nil {
+			v, err = a.enqueue(path[1], system.JobMode(r.URL.Query().Get("mode")), a.action(path[2]))
+		}
+	case path[0] == "jobs" && len(path) == 1 && r.Method == http.MethodGet:
+		v = a.sys.ListJobs()
+	case path[0] == "jobs" && len(path) == 2 && (r.Method == http.MethodGet || r.Method == http.MethodDelete):
+		var id uint64
+		if id, err = strconv.ParseUint(path[1], 10, 64); err != nil {
+			a.reply(w, http.StatusBadRequest, apiError{err.Error()})
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			v, err = a.sys.GetJob(id)
+		} else if err = a.authorize(r); err == nil {
+			err = a.sys.CancelJob(id)
+		}
+	case path[0] == "journal" && len(path) == 1 && r.Method == http.MethodGet:
+		v, err = a.journal(r.URL.Query().Get("unit"))
+	default:
+		a.reply(w, http.StatusNotFound, apiError{"No such endpoint"})
+		return
+	}
+
+	if err != nil {
+		a.reply(w, errorStatus(err), apiError{err.Error()})
+		return
+	}
+	a.reply(w, http.StatusOK, v)
+}
+
+// authorize returns ErrAccessDenied, unless the client of r may mutate the state
+func (a *API) authorize(r *http.Request) error {
+	if mutate, _ := r.Context().Value(mutateKey{}).(bool); !mutate {
+		log.WithField("url", r.URL).Warn("Refusing request of an unprivileged client")
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// reply writes v encoded as JSON with status code
+func (a *API) reply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Error writing reply: %s", err)
+	}
+}
+
+// errorStatus returns the status code of the replies to the requests failed with err
+func errorStatus(err error) int {
+	switch err {
+	case system.ErrNotFound, system.ErrNoSuchJob:
+		return http.StatusNotFound
+	case ErrAccessDenied:
+		return http.StatusForbidden
+	case system.ErrUnknownJobMode:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// units returns the statuses of the units loaded
+func (a *API) units() map[string]unit.Status {
+	statuses := map[string]unit.Status{}
+	for _, u := range a.sys.Units() {
+		statuses[u.Name()] = u.Status()
+	}
+	return statuses
+}
+
+// action returns the function enqueueing the jobs of the action specified by the endpoint, nil if it is unknown
+func (a *API) action(typ string) func(system.JobMode, ...string) error {
+	switch typ {
+	case "start":
+		return a.sys.ManualStart
+	case "stop":
+		return a.sys.ManualStop
+	case "restart":
+		return a.sys.ManualRestart
+	case "reload":
+		return func(mode system.JobMode, names ...string) error {
+			return a.sys.Reload(names...)
+		}
+	}
+	return nil
+}
+
+// enqueue enqueues a job for the unit name in mode, replace if empty, using fn
+func (a *API) enqueue(name string, mode system.JobMode, fn func(system.JobMode, ...string) error) (reply JobReply, err error) {
+	if fn == nil {
+		return reply, system.ErrNotFound
+	}
+	if err = fn(mode, name); err != nil {
+		return
+	}
+
+	u, err := a.sys.Unit(name)
+	if err != nil {
+		return
+	}
+	return JobReply{name, u.JobID()}, nil
+}
+
+// journal returns the logs of the unit name or of all the units loaded, if empty
+func (a *API) journal(name string) (journals []Journal, err error) {
+	var units []*system.Unit
+	if name == "" {
+		units = a.sys.Units()
+	} else {
+		var u *system.Unit
+		if u, err = a.sys.Unit(name); err != nil {
+			return
+		}
+		units = []*system.Unit{u}
+	}
+
+	sort.Slice(units, func(i, k int) bool {
+		return units[i].Name() < units[k].Name()
+	})
+
+	journals = make([]Journal, 0, len(units))
+	for _, u := range units {
+		b, err := ioutil.ReadAll(u.Log)
+		if err != nil {
+			return nil, err
+		}
+
+		j := Journal{Unit: u.Name(), Lines: []string{}}
+		if s := strings.TrimRight(string(b), "\n"); s != "" {
+			j.Lines = strings.Split(s, "\n")
+		}
+		journals = append(journals, j)
+	}
+	return journals, nil
+}
@@ -0,0 +1,116 @@
+package systemctl
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI(t *testing.T) {
+	path, err := ioutil.TempDir("", "api-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target"), []byte(`[Unit]
+Description=A`), 0666), "ioutil.WriteFile")
+
+	sys := system.New()
+	sys.SetPaths(path)
+
+	auth, err := NewAuthorizer("")
+	require.NoError(t, err, "NewAuthorizer")
+	api := NewAPI(sys, auth)
+
+	socket := filepath.Join(path, "run", "api")
+	go api.Listen(socket)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socket)
+		},
+	}}
+
+	do := func(method, url string, v interface{}) int {
+		req, err := http.NewRequest(method, "http://systemgo"+url, nil)
+		require.NoError(t, err, url)
+
+		for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			resp, err := client.Do(req)
+			if err == nil {
+				defer resp.Body.Close()
+				if v != nil {
+					require.NoError(t, json.NewDecoder(resp.Body).Decode(v), url)
+				}
+				return resp.StatusCode
+			}
+
+			select {
+			case <-timeout:
+				t.Fatalf("%s %s: %s", method, url, err)
+			default:
+			}
+		}
+	}
+
+	var reply JobReply
+	require.Equal(t, http.StatusOK, do("POST", "/units/a.target/start", &reply), "start")
+	assert.Equal(t, "a.target", reply.Unit)
+	assert.NotZero(t, reply.Job)
+
+	for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var st unit.Status
+		require.Equal(t, http.StatusOK, do("GET", "/units/a.target", &st), "status")
+		if st.Activation.State == unit.Active {
+			assert.Equal(t, "A", st.Description)
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("a.target not started")
+		default:
+		}
+	}
+
+	var statuses map[string]unit.Status
+	require.Equal(t, http.StatusOK, do("GET", "/units", &statuses), "units")
+	assert.Contains(t, statuses, "a.target")
+
+	var journals []Journal
+	require.Equal(t, http.StatusOK, do("GET", "/journal?unit=a.target", &journals), "journal")
+	require.Len(t, journals, 1)
+	assert.NotEmpty(t, journals[0].Lines)
+
+	var jobs []system.JobInfo
+	assert.Equal(t, http.StatusOK, do("GET", "/jobs", &jobs), "jobs")
+	assert.Equal(t, http.StatusNotFound, do("GET", "/jobs/42", nil), "no such job")
+	assert.Equal(t, http.StatusBadRequest, do("DELETE", "/jobs/foo", nil), "malformed job ID")
+	assert.Equal(t, http.StatusNotFound, do("GET", "/units/b.target", nil), "no such unit")
+	assert.Equal(t, http.StatusNotFound, do("GET", "/foo", nil), "no such endpoint")
+
+	// Requests received on TCP may not mutate the state
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/units/a.target/stop", "application/json", nil)
+	require.NoError(t, err, "http.Post")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "unprivileged client")
+
+	resp, err = http.Get(server.URL + "/units")
+	require.NoError(t, err, "http.Get")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "read-only request")
+}
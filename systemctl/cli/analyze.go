@@ -0,0 +1,205 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// analyzeCmd represents the analyze command
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze system boot-up performance",
+	Long:  `analyze prints the time userspace initialization took at boot`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.BootTime", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		d, _ := resp.Yield.(time.Duration)
+		if jsonOutput() {
+			printJSON(struct {
+				UserspaceUsec int64 `json:"userspace_usec"`
+			}{usec(d)})
+			return
+		}
+		fmt.Printf("Startup finished in %s (userspace)\n", d)
+	},
+}
+
+// blameCmd represents the analyze blame command
+var blameCmd = &cobra.Command{
+	Use:   "blame",
+	Short: "List units by the time they took to start",
+	Long:  `blame lists the units activated along with the time they took to start, slowest first`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Blame", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		times, _ := resp.Yield.([]system.UnitTime)
+		if jsonOutput() {
+			printUnitTimes(times)
+			return
+		}
+
+		for _, ut := range times {
+			fmt.Printf("%12s %s\n", ut.Time(), ut.Unit)
+		}
+	},
+}
+
+// criticalChainCmd represents the analyze critical-chain command
+var criticalChainCmd = &cobra.Command{
+	Use:   "critical-chain [UNIT]",
+	Short: "Print the tree of the units delaying the start of a unit",
+	Long: `critical-chain prints the chain of units, which delayed the start of the unit specified
+or of the unit started at boot the most. The time the unit got active after boot is printed after "@",
+the time it took to start after "+"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.CriticalChain", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		chain, _ := resp.Yield.([]system.UnitTime)
+		if jsonOutput() {
+			printUnitTimes(chain)
+			return
+		}
+
+		for i, ut := range chain {
+			prefix := ""
+			if i > 0 {
+				prefix = strings.Repeat("  ", i-1) + "└─"
+			}
+
+			if d := ut.Time(); d > 0 {
+				fmt.Printf("%s%s @%s +%s\n", prefix, ut.Unit, ut.Activated, d)
+			} else {
+				fmt.Printf("%s%s @%s\n", prefix, ut.Unit, ut.Activated)
+			}
+		}
+	},
+}
+
+// dotCmd represents the analyze dot command
+var dotCmd = &cobra.Command{
+	Use:   "dot [UNIT...]",
+	Short: "Print the dependency graph of units in dot format",
+	Long: `dot prints the Requires=, Wants= and After= dependencies of the units loaded as a Graphviz dot graph.
+If units are specified, only the ones pulled in or ordered after by them are included.
+Pipe the output to "dot -Tsvg" to render it`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Dot", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		graph, _ := resp.Yield.(string)
+		fmt.Print(graph)
+	},
+}
+
+// securityCmd represents the analyze security command
+var securityCmd = &cobra.Command{
+	Use:   "security [UNIT...]",
+	Short: "Audit the security of services",
+	Long: `security scores the exposure of the services specified to attacks from 0.0(perfectly sandboxed) to 10.0(fully exposed),
+as systemd-analyze security does, and prints the checks failed along with recommendations.
+If no units are specified, the exposure of all the services loaded is listed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Security", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		reports, _ := resp.Yield.([]system.SecurityReport)
+		if jsonOutput() {
+			list := make([]securityJSON, len(reports))
+			for i, r := range reports {
+				list[i] = securityJSON{Unit: r.Unit, Exposure: r.Exposure, Rating: r.Rating, Checks: []securityCheckJSON{}}
+				for _, c := range r.Checks {
+					list[i].Checks = append(list[i].Checks, securityCheckJSON{
+						Directive: c.Directive, Description: c.Description, Recommendation: c.Recommendation, Weight: c.Weight, Passed: c.Passed,
+					})
+				}
+			}
+			printJSON(list)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		if len(args) == 0 {
+			fmt.Fprintln(w, "UNIT\tEXPOSURE\tPREDICATE")
+			for _, r := range reports {
+				fmt.Fprintf(w, "%s\t%.1f\t%s\n", r.Unit, r.Exposure, r.Rating)
+			}
+			if err := w.Flush(); err != nil {
+				log.Error(err)
+			}
+			return
+		}
+
+		for i, r := range reports {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "  NAME\tDESCRIPTION\tRECOMMENDATION")
+			for _, c := range r.Checks {
+				mark := "✗"
+				if c.Passed {
+					mark = "✓"
+				}
+				fmt.Fprintf(w, "%s %s\t%s\t%s\n", mark, c.Directive, c.Description, c.Recommendation)
+			}
+			fmt.Fprintf(w, "\n→ Overall exposure level for %s: %.1f %s\n", r.Unit, r.Exposure, r.Rating)
+		}
+		if err := w.Flush(); err != nil {
+			log.Error(err)
+		}
+	},
+}
+
+// printUnitTimes prints times as JSON
+func printUnitTimes(times []system.UnitTime) {
+	list := make([]unitTimeJSON, len(times))
+	for i, ut := range times {
+		list[i] = toUnitTimeJSON(ut)
+	}
+	printJSON(list)
+}
+
+func init() {
+	analyzeCmd.AddCommand(blameCmd, criticalChainCmd, dotCmd, securityCmd)
+	RootCmd.AddCommand(analyzeCmd)
+}
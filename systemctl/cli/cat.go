@@ -0,0 +1,63 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat UNIT...",
+	Short: "Show files and drop-ins of specified units",
+	Long:  `cat prints the files defining the units specified followed by their drop-ins, each preceded by a comment with its path`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Cat", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		files, _ := resp.Yield.([]system.DefinitionFile)
+		for i, f := range files {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			path := f.Path
+			if path == "" {
+				path = "(built-in)"
+			}
+			fmt.Printf("# %s\n%s\n", path, strings.TrimRight(f.Content, "\n"))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(catCmd)
+}
@@ -0,0 +1,362 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Units completed by __complete-units
+const (
+	completeAll     = "all"
+	completeService = "service"
+	completeFailed  = "failed"
+)
+
+// unitCompletion maps the commands taking unit names to the units completed
+var unitCompletion = map[string]string{
+	"start":             completeAll,
+	"stop":              completeAll,
+	"reload":            completeAll,
+	"restart":           completeService,
+	"status":            completeAll,
+	"is-active":         completeAll,
+	"is-enabled":        completeAll,
+	"is-failed":         completeAll,
+	"show":              completeAll,
+	"cat":               completeAll,
+	"edit":              completeAll,
+	"set-property":      completeAll,
+	"kill":              completeAll,
+	"isolate":           completeAll,
+	"list-dependencies": completeAll,
+	"monitor":           completeAll,
+	"reset-failed":      completeFailed,
+	"enable":            completeAll,
+	"disable":           completeAll,
+	"mask":              completeAll,
+	"unmask":            completeAll,
+	"preset":            completeAll,
+}
+
+// shells maps the shells supported to the functions writing the completion scripts for them
+var shells = map[string]func(io.Writer){
+	"bash": writeBashCompletion,
+	"zsh":  writeZshCompletion,
+	"fish": writeFishCompletion,
+}
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Print a shell completion script",
+	Long: `completion prints the script completing the commands, the flags and the unit names for the shell specified.
+The unit names are completed by querying the manager, only services are completed for restart and only the units failed for reset-failed,
+e.g. "source <(systemctl completion bash)"`,
+	Args:        cobra.ExactArgs(1),
+	ValidArgs:   []string{"bash", "zsh", "fish"},
+	Annotations: map[string]string{offline: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		write, ok := shells[args[0]]
+		if !ok {
+			log.Fatalf("Unsupported shell: %s", args[0])
+		}
+		write(os.Stdout)
+	},
+}
+
+// completeUnitsCmd represents the __complete-units command run by the completion scripts
+var completeUnitsCmd = &cobra.Command{
+	Use:    "__complete-units [all|service|failed]",
+	Short:  "Print the names of the units to complete",
+	Hidden: true,
+	Args:   cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := completeAll
+		if len(args) > 0 {
+			filter = args[0]
+		}
+
+		for _, name := range completeUnits(filter) {
+			fmt.Println(name)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+	RootCmd.AddCommand(completeUnitsCmd)
+}
+
+// completeUnits returns the sorted names of the units known to the manager matching filter.
+// Unless only the units failed are requested, the units having unit files are included, even if not loaded
+func completeUnits(filter string) (names []string) {
+	set := map[string]bool{}
+
+	if filter == completeFailed {
+		var resp systemctl.Response
+		if err := client.Call("Server.ListFailed", []string{}, &resp); err == nil {
+			failed, _ := resp.Yield.([]system.Failure)
+			for _, f := range failed {
+				set[f.Unit] = true
+			}
+		}
+	} else {
+		var resp systemctl.Response
+		if err := client.Call("Server.StatusAll", []string{}, &resp); err == nil {
+			statuses, _ := resp.Yield.(map[string]unit.Status)
+			for name := range statuses {
+				set[name] = true
+			}
+		}
+
+		resp = systemctl.Response{}
+		if err := client.Call("Server.ListUnitFiles", []string{}, &resp); err == nil {
+			files, _ := resp.Yield.([]system.UnitFile)
+			for _, f := range files {
+				set[f.Name] = true
+			}
+		}
+	}
+
+	for name := range set {
+		if filter != completeService || strings.HasSuffix(name, ".service") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// completedCommands returns the commands completed sorted by name
+func completedCommands() (cmds []*cobra.Command) {
+	for _, cmd := range RootCmd.Commands() {
+		if !cmd.Hidden {
+			cmds = append(cmds, cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name() < cmds[j].Name()
+	})
+	return
+}
+
+// flagNames returns the names of flags prefixed by dashes. If value is true, only the flags taking values are returned
+func flagNames(flags *pflag.FlagSet, value bool) (names []string) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Name == "help" || value && f.Value.Type() == "bool" {
+			return
+		}
+		names = append(names, "--"+f.Name)
+		if f.Shorthand != "" {
+			names = append(names, "-"+f.Shorthand)
+		}
+	})
+	return
+}
+
+// globalValueFlags returns the global flags taking values as a shell pattern, e.g. "-o|--output"
+func globalValueFlags() string {
+	return strings.Join(flagNames(RootCmd.PersistentFlags(), true), "|")
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# bash completion for systemctl of systemgo
+
+_systemctl_systemgo() {
+    local cur=${COMP_WORDS[COMP_CWORD]} cmd="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+            %s) ((i++)) ;;
+            -*) ;;
+            *) cmd=${COMP_WORDS[i]}; break ;;
+        esac
+    done
+
+    if [[ -z $cmd ]]; then
+        if [[ $cur == -* ]]; then
+            COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        fi
+        return
+    fi
+
+    local flags="" filter=""
+    case $cmd in
+`, globalValueFlags(), strings.Join(flagNames(RootCmd.PersistentFlags(), false), " "), strings.Join(commandNames(), " "))
+
+	for _, cmd := range completedCommands() {
+		fmt.Fprintf(w, "        %s) flags=%q; filter=%q ;;\n", cmd.Name(),
+			strings.Join(append(flagNames(cmd.LocalFlags(), false), flagNames(RootCmd.PersistentFlags(), false)...), " "),
+			unitCompletion[cmd.Name()])
+	}
+
+	fmt.Fprint(w, `    esac
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [[ -n $filter ]]; then
+        COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" "${COMP_WORDS[@]:1:i-1}" __complete-units "$filter" 2>/dev/null)" -- "$cur"))
+    fi
+}
+
+complete -F _systemctl_systemgo systemctl
+`)
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintf(w, `#compdef systemctl
+# zsh completion for systemctl of systemgo
+
+_systemctl_systemgo() {
+    local -a commands units
+    local i cmd filter
+    for ((i = 2; i < CURRENT; i++)); do
+        case ${words[i]} in
+            %s) ((i++)) ;;
+            -*) ;;
+            *) cmd=${words[i]}; break ;;
+        esac
+    done
+
+    if [[ -z $cmd ]]; then
+        commands=(
+`, globalValueFlags())
+
+	for _, cmd := range completedCommands() {
+		fmt.Fprintf(w, "            %s\n", shellQuote(cmd.Name()+":"+strings.Replace(cmd.Short, ":", `\:`, -1)))
+	}
+
+	fmt.Fprint(w, `        )
+        _describe -t commands 'systemctl command' commands
+        return
+    fi
+
+    case $cmd in
+`)
+	for _, name := range sortedCompletions() {
+		fmt.Fprintf(w, "        %s) filter=%s ;;\n", name, unitCompletion[name])
+	}
+
+	fmt.Fprint(w, `        *) return 1 ;;
+    esac
+
+    units=(${(f)"$(${words[1]} ${words[2,i-1]} __complete-units $filter 2>/dev/null)"})
+    compadd -a units
+}
+
+compdef _systemctl_systemgo systemctl
+`)
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# fish completion for systemctl of systemgo
+
+# Prints the global arguments preceding the command, followed by the command
+function __systemctl_systemgo_split
+    set -l words (commandline -opc)
+    set -e words[1]
+    set -l skip 0
+    for word in $words
+        if test $skip = 1
+            set skip 0
+            echo $word
+            continue
+        end
+        switch $word
+            case %s
+                set skip 1
+                echo $word
+            case '-*'
+                echo $word
+            case '*'
+                echo $word
+                return
+        end
+    end
+end
+
+# Succeeds, if the command being completed is the one specified
+function __systemctl_systemgo_command
+    set -l words (__systemctl_systemgo_split)
+    test (count $words) -gt 0; and test $words[-1] = $argv[1]
+end
+
+# Prints the units to complete for the command being completed
+function __systemctl_systemgo_units
+    set -l words (__systemctl_systemgo_split)
+    set -e words[-1]
+    set -l systemctl (commandline -opc)[1]
+    $systemctl $words __complete-units $argv[1] 2>/dev/null
+end
+
+complete -c systemctl -f
+`, strings.Replace(globalValueFlags(), "|", " ", -1))
+
+	for _, cmd := range completedCommands() {
+		fmt.Fprintf(w, "complete -c systemctl -n 'not __fish_seen_subcommand_from %s' -a %s -d %s\n",
+			strings.Join(commandNames(), " "), cmd.Name(), fishQuote(cmd.Short))
+	}
+	for _, name := range sortedCompletions() {
+		fmt.Fprintf(w, "complete -c systemctl -n '__systemctl_systemgo_command %s' -a '(__systemctl_systemgo_units %s)'\n",
+			name, unitCompletion[name])
+	}
+}
+
+// commandNames returns the names of the commands completed
+func commandNames() (names []string) {
+	for _, cmd := range completedCommands() {
+		names = append(names, cmd.Name())
+	}
+	return
+}
+
+// sortedCompletions returns the names of the commands completing unit names sorted
+func sortedCompletions() (names []string) {
+	for name := range unitCompletion {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// fishQuote returns s single-quoted for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// shellQuote returns s single-quoted for POSIX-like shells
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
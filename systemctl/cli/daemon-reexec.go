@@ -0,0 +1,47 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"io"
+	"net/rpc"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// daemonReexecCmd represents the daemon-reexec command
+var daemonReexecCmd = &cobra.Command{
+	Use:   "daemon-reexec",
+	Short: "Re-execute the manager",
+	Long:  `daemon-reexec serializes the manager state, re-executes the manager binary and restores the state, keeping the units running`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// The connection is closed by the manager being replaced
+		if err := client.Call("Server.DaemonReexec", args, nil); err != nil && err != io.ErrUnexpectedEOF && err != rpc.ErrShutdown {
+			log.Error(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(daemonReexecCmd)
+}
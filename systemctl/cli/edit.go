@@ -0,0 +1,108 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// Editor used, if neither $EDITOR nor $VISUAL is set
+const DEFAULT_EDITOR = "vi"
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit UNIT",
+	Short: "Edit a drop-in overriding the definition of a unit",
+	Long: `edit opens the ` + system.OVERRIDE_DROP_IN + ` drop-in of the unit specified in $EDITOR.
+Once the editor exits, the definition overridden is checked and, if it is valid, the drop-in is saved and the definitions are reloaded`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Override", args[0], &resp); err != nil {
+			log.Fatal(err)
+		}
+		override, _ := resp.Yield.(system.DefinitionFile)
+
+		tmp, err := ioutil.TempFile("", filepath.Base(args[0])+"-*.conf")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+
+		_, err = tmp.WriteString(override.Content)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = runEditor(tmp.Name()); err != nil {
+			log.Fatalf("Editor failed: %s", err)
+		}
+
+		b, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if string(b) == override.Content {
+			fmt.Println("Unchanged")
+			return
+		}
+
+		req := systemctl.EditRequest{Name: args[0], Content: string(b)}
+		if err := client.Call("Server.Edit", req, &resp); err != nil {
+			log.Fatalf("Definition is invalid, %s not saved: %s", override.Path, err)
+		}
+		fmt.Printf("Saved %s\n", override.Path)
+	},
+}
+
+// runEditor opens path in the editor of the user
+func runEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		editor = DEFAULT_EDITOR
+	}
+
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func init() {
+	RootCmd.AddCommand(editCmd)
+}
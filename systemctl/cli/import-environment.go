@@ -0,0 +1,81 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// importEnvironmentCmd represents the import-environment command
+var importEnvironmentCmd = &cobra.Command{
+	Use:   "import-environment [VARIABLE...]",
+	Short: "Import variables of the client environment into the manager environment",
+	Long: `import-environment sets the variables specified, which are set in the environment of systemctl, in the environment
+of the manager, e.g. DISPLAY or SSH_AUTH_SOCK for the services started from a session.
+Shell patterns match the names(e.g. 'XDG_*'), the whole environment is imported, if none are specified`,
+	Run: func(cmd *cobra.Command, args []string) {
+		vars := importedEnvironment(os.Environ(), args)
+		if len(vars) == 0 {
+			return
+		}
+		if err := client.Call("Server.SetEnvironment", vars, nil); err != nil {
+			log.Error(err)
+		}
+	},
+}
+
+// importedEnvironment returns the assignments of env, which names match any of patterns, all of them if none are given.
+// The variables, which names are not valid shell names(e.g. exported shell functions), are skipped
+func importedEnvironment(env, patterns []string) (vars []string) {
+	for _, v := range env {
+		name := strings.SplitN(v, "=", 2)[0]
+		if name == "" || name[0] >= '0' && name[0] <= '9' || strings.IndexFunc(name, invalidNameRune) >= 0 {
+			continue
+		}
+
+		matched := len(patterns) == 0
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			vars = append(vars, v)
+		}
+	}
+	return
+}
+
+// invalidNameRune reports whether r may not be found in a variable name
+func invalidNameRune(r rune) bool {
+	return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+}
+
+func init() {
+	RootCmd.AddCommand(importEnvironmentCmd)
+}
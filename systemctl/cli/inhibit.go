@@ -0,0 +1,146 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the inhibit command
+var (
+	inhibitWhat string
+	inhibitWho  string
+	inhibitWhy  string
+	inhibitMode string
+	inhibitList bool
+)
+
+// inhibitCmd represents the inhibit command
+var inhibitCmd = &cobra.Command{
+	Use:   "inhibit [flags] COMMAND [ARGS...]",
+	Short: "Run a command holding an inhibitor lock",
+	Long: `inhibit takes an inhibitor lock on the operations specified by --what, runs the command and releases the lock, once it exits.
+A block lock makes shutdown requests fail, a delay lock postpones shutdown until released, but no longer than the maximum delay
+configured. The lock is released as well, if systemctl gets killed. With --list the locks held are listed instead`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if inhibitList {
+			listInhibitors()
+			return
+		}
+		if len(args) == 0 {
+			log.Fatal("Command to run not specified")
+		}
+
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		who := inhibitWho
+		if who == "" {
+			who = strings.Join(args, " ")
+		}
+
+		var resp systemctl.Response
+		if err := client.Call("Server.Inhibit", systemctl.InhibitRequest{
+			What: inhibitWhat,
+			Who:  who,
+			Why:  inhibitWhy,
+			Mode: inhibitMode,
+		}, &resp); err != nil {
+			log.Fatal(err)
+		}
+		id, _ := resp.Yield.(uint64)
+
+		// The signals of the terminal are handled by the command
+		signal.Ignore(os.Interrupt, syscall.SIGQUIT)
+
+		c := exec.Command(path, args[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err = c.Run()
+
+		if uerr := client.Call("Server.Uninhibit", id, nil); uerr != nil {
+			log.Error(uerr)
+		}
+
+		if err != nil {
+			if exit, ok := err.(*exec.ExitError); ok {
+				if status, ok := exit.Sys().(syscall.WaitStatus); ok && status.Exited() {
+					os.Exit(status.ExitStatus())
+				}
+			}
+			log.Error(err)
+			os.Exit(exitFailure)
+		}
+	},
+}
+
+// listInhibitors prints the inhibitor locks held
+func listInhibitors() {
+	var resp systemctl.Response
+	if err := client.Call("Server.ListInhibitors", []string{}, &resp); err != nil {
+		log.Fatal(err)
+	}
+
+	inhibitors, _ := resp.Yield.([]system.Inhibitor)
+	if jsonOutput() {
+		list := make([]inhibitorJSON, len(inhibitors))
+		for i, inh := range inhibitors {
+			list[i] = inhibitorJSON{What: strings.Join(inh.What, ":"), Who: inh.Who, Why: inh.Why, Mode: inh.Mode, PID: inh.PID, Since: inh.Since}
+		}
+		printJSON(list)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
+	fmt.Fprintln(w, "who\twhat\twhy\tmode\tpid\t")
+	for _, inh := range inhibitors {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t\n", inh.Who, strings.Join(inh.What, ":"), inh.Why, inh.Mode, inh.PID)
+	}
+	if err := w.Flush(); err != nil {
+		log.Error(err)
+	}
+	fmt.Printf("\n%d inhibitors listed.\n", len(inhibitors))
+}
+
+func init() {
+	RootCmd.AddCommand(inhibitCmd)
+
+	// Flags following the command are the arguments of the command
+	inhibitCmd.Flags().SetInterspersed(false)
+
+	inhibitCmd.Flags().StringVar(&inhibitWhat, "what", "shutdown:sleep:idle", "Operations to inhibit, separated by colons(shutdown, sleep or idle)")
+	inhibitCmd.Flags().StringVar(&inhibitWho, "who", "", "Name of the holder of the lock, the command by default")
+	inhibitCmd.Flags().StringVar(&inhibitWhy, "why", "Unknown reason", "Reason the lock is held for")
+	inhibitCmd.Flags().StringVar(&inhibitMode, "mode", system.InhibitBlock, "Mode of the lock(block or delay)")
+	inhibitCmd.Flags().BoolVar(&inhibitList, "list", false, "List the inhibitor locks held")
+}
@@ -0,0 +1,73 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+)
+
+// isFailedCmd represents the is-failed command
+var isFailedCmd = &cobra.Command{
+	Use:   "is-failed UNIT...",
+	Short: "Check whether units are failed",
+	Long: `is-failed prints the activation states of the units specified.
+Exits with code 0, if at least one of them has failed, with code 1 otherwise`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.IsActive", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		failed := false
+		states, _ := resp.Yield.([]unit.Activation)
+		for _, st := range states {
+			failed = failed || st == unit.Failed
+			if !quiet && !jsonOutput() {
+				fmt.Println(strings.ToLower(st.String()))
+			}
+		}
+
+		if !quiet && jsonOutput() {
+			list := make([]stateJSON, len(states))
+			for i, st := range states {
+				list[i] = stateJSON{Unit: args[i], State: strings.ToLower(st.String())}
+			}
+			printJSON(list)
+		}
+
+		if !failed {
+			os.Exit(exitFailure)
+		}
+	},
+}
+
+func init() {
+	isFailedCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Do not print the states")
+	RootCmd.AddCommand(isFailedCmd)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Processes to send the signal to
+	killWho string
+
+	// Signal to send
+	killSignal string
+)
+
+// signals maps the names of the signals, which may be sent, to the signals
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"ABRT": syscall.SIGABRT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"ALRM": syscall.SIGALRM,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+}
+
+// killCmd represents the kill command
+var killCmd = &cobra.Command{
+	Use:   "kill UNIT...",
+	Short: "Send a signal to processes of a unit",
+	Long: `kill sends the signal specified by --signal to the processes of the units selected by --who without stopping them.
+--who is one of "main", "control" or "all", the latter being the main and control processes along with their descendants`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		signal, err := parseSignal(killSignal)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		req := systemctl.KillRequest{Names: args, Who: killWho, Signal: int(signal)}
+		if err := client.Call("Server.Kill", req, nil); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// parseSignal parses the name(e.g. "HUP" or "SIGHUP") or the number of a signal
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+
+	if signal, ok := signals[strings.TrimPrefix(strings.ToUpper(s), "SIG")]; ok {
+		return signal, nil
+	}
+	return 0, fmt.Errorf("Unknown signal: %s", s)
+}
+
+func init() {
+	RootCmd.AddCommand(killCmd)
+	killCmd.Flags().StringVar(&killWho, "who", system.KillAll, `Processes to send the signal to, one of "main", "control" or "all"`)
+	killCmd.Flags().StringVarP(&killSignal, "signal", "s", "SIGTERM", "Signal to send")
+}
@@ -0,0 +1,117 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+)
+
+// Escape sequences coloring the markers of the units listed
+const (
+	colorActive   = "\x1b[0;1;32m"
+	colorFailed   = "\x1b[0;1;31m"
+	colorInactive = "\x1b[0;1;37m"
+	colorReset    = "\x1b[0m"
+)
+
+// reverseDependencies is true, if the units depending on the unit are to be listed
+var reverseDependencies bool
+
+// listDependenciesCmd represents the list-dependencies command
+var listDependenciesCmd = &cobra.Command{
+	Use:   "list-dependencies [UNIT]",
+	Short: "Recursively show units which are required or wanted by the unit",
+	Long: `list-dependencies prints the tree of the units required or wanted by the unit specified
+or by ` + system.DEFAULT_TARGET + `, if none is. With --reverse the tree of the units requiring or wanting it is printed instead`,
+	Run: func(cmd *cobra.Command, args []string) {
+		req := systemctl.DependencyRequest{Name: system.DEFAULT_TARGET, Reverse: reverseDependencies}
+		if len(args) > 0 {
+			req.Name = args[0]
+		}
+
+		var resp systemctl.Response
+		if err := client.Call("Server.ListDependencies", req, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		tree, _ := resp.Yield.(system.Dependency)
+		if jsonOutput() {
+			printJSON(toDependencyJSON(tree))
+			return
+		}
+		printDependencies(os.Stdout, tree, isTerminal(os.Stdout))
+	},
+}
+
+// printDependencies writes tree to w, coloring the markers of the units, if color is true
+func printDependencies(w io.Writer, tree system.Dependency, color bool) {
+	fmt.Fprintln(w, tree.Unit)
+
+	var walk func(dep system.Dependency, prefix string, last bool)
+	walk = func(dep system.Dependency, prefix string, last bool) {
+		branch, indent := "├─", "│ "
+		if last {
+			branch, indent = "└─", "  "
+		}
+
+		marker := "●"
+		if color {
+			switch dep.Active {
+			case unit.Active, unit.Reloading:
+				marker = colorActive + marker + colorReset
+			case unit.Failed:
+				marker = colorFailed + marker + colorReset
+			default:
+				marker = colorInactive + marker + colorReset
+			}
+		} else if dep.Active != unit.Active && dep.Active != unit.Reloading {
+			marker = "○"
+		}
+		fmt.Fprintf(w, "%s %s%s%s\n", marker, prefix, branch, dep.Unit)
+
+		for i, child := range dep.Dependencies {
+			walk(child, prefix+indent, i == len(dep.Dependencies)-1)
+		}
+	}
+	for i, dep := range tree.Dependencies {
+		walk(dep, "", i == len(tree.Dependencies)-1)
+	}
+}
+
+// isTerminal returns whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	RootCmd.AddCommand(listDependenciesCmd)
+	listDependenciesCmd.Flags().BoolVar(&reverseDependencies, "reverse", false, "Show the units depending on the unit instead")
+}
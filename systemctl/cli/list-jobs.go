@@ -0,0 +1,76 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// listJobsCmd represents the list-jobs command
+var listJobsCmd = &cobra.Command{
+	Use:   "list-jobs",
+	Short: "list jobs",
+	Long:  `list jobs lists all jobs queued along with the units they are waiting for`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.ListJobs", args, &resp); err != nil {
+			log.Error(err)
+		}
+
+		jobs, _ := resp.Yield.([]system.JobInfo)
+		if jsonOutput() {
+			list := make([]jobJSON, len(jobs))
+			for i, j := range jobs {
+				list[i] = jobJSON{Job: j.ID, Unit: j.Unit, Type: j.Type, State: j.State, WaitingFor: append([]string{}, j.WaitingFor...)}
+			}
+			printJSON(list)
+			return
+		}
+
+		if len(jobs) == 0 {
+			fmt.Println("No jobs running.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
+		fmt.Fprintln(w, "job\tunit\ttype\tstate\twaiting for")
+		for _, j := range jobs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n",
+				j.ID, j.Unit, j.Type, j.State, strings.Join(j.WaitingFor, " "))
+		}
+
+		if err := w.Flush(); err != nil {
+			log.Error(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(listJobsCmd)
+}
@@ -23,9 +23,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/system"
 	"github.com/plasma-umass/systemgo/systemctl"
 	"github.com/plasma-umass/systemgo/unit"
 	"github.com/spf13/cobra"
@@ -37,26 +41,94 @@ var listUnitsCmd = &cobra.Command{
 	Short: "list units",
 	Long:  `list units lists all units known to systemgo`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if listFailed {
+			printFailed(args)
+			return
+		}
+
 		var resp systemctl.Response
 		if err := client.Call("Server.StatusAll", args, &resp); err != nil {
 			log.Error(err)
 		}
 
 		if resp.Yield != nil {
-			w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
-			fmt.Fprintln(w, "unit\tload\tactive\tsub")
-			for name, st := range resp.Yield.(map[string]unit.Status) {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
-					name, st.Load.Loaded, st.Activation.State, st.Activation.Sub)
+			statuses := resp.Yield.(map[string]unit.Status)
+
+			if jsonOutput() {
+				units := make([]unitJSON, 0, len(statuses))
+				for _, name := range sortedNames(statuses) {
+					units = append(units, toUnitJSON(name, statuses[name]))
+				}
+				printJSON(units)
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+			fmt.Fprintln(w, "UNIT\tLOAD\tACTIVE\tSUB\tDESCRIPTION")
+			for _, name := range sortedNames(statuses) {
+				st := statuses[name]
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name,
+					strings.ToLower(st.Load.Loaded.String()), strings.ToLower(st.Activation.State.String()),
+					st.Activation.Sub, st.Description)
 			}
 
 			if err := w.Flush(); err != nil {
 				log.Error(err)
 			}
+
+			fmt.Printf("\n%d loaded units listed.\n", len(statuses))
 		}
 	},
 }
 
+// sortedNames returns the names of the units in statuses sorted
+func sortedNames(statuses map[string]unit.Status) (names []string) {
+	names = make([]string, 0, len(statuses))
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// listFailed makes list-units list only the units failed
+var listFailed bool
+
+// printFailed prints the units failed along with the reasons of the failures
+func printFailed(args []string) {
+	var resp systemctl.Response
+	if err := client.Call("Server.ListFailed", args, &resp); err != nil {
+		log.Error(err)
+	}
+
+	failed, _ := resp.Yield.([]system.Failure)
+	if jsonOutput() {
+		units := make([]failureJSON, len(failed))
+		for i, f := range failed {
+			units[i] = failureJSON{Unit: f.Unit, Reason: f.Reason, ExitCode: f.ExitCode, Since: f.Since}
+		}
+		printJSON(units)
+		return
+	}
+
+	if len(failed) == 0 {
+		fmt.Println("No units failed.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
+	fmt.Fprintln(w, "unit\treason\texit code\tsince")
+	for _, f := range failed {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t\n",
+			f.Unit, f.Reason, f.ExitCode, f.Since.Format(time.RFC1123))
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Error(err)
+	}
+}
+
 func init() {
+	listUnitsCmd.Flags().BoolVar(&listFailed, "failed", false, "List only the units failed")
 	RootCmd.AddCommand(listUnitsCmd)
 }
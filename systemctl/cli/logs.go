@@ -0,0 +1,214 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// Format of the times of the records printed
+const recordTimeFormat = "Jan _2 15:04:05"
+
+// Interval the journal is polled in with --follow
+const followInterval = 250 * time.Millisecond
+
+// Number of the records printed before following the journal, unless --lines is specified
+const followLines = 10
+
+var (
+	logsUnits    []string
+	logsSince    string
+	logsUntil    string
+	logsPriority string
+	logsBoot     string
+	logsLines    int
+	logsFollow   bool
+	logsUsage    bool
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the records of the journal",
+	Long: `logs prints the records of the journal, the output of the units, matching the options specified.
+With --follow the records appended afterwards get printed as well, until interrupted.
+With --output=journal-json the records are printed as JSON lines with the fields named as journalctl -o json does,
+with --output=export in the Journal Export Format`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if logsUsage {
+			printUsage()
+			return
+		}
+
+		q, err := logsQuery(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for {
+			var resp systemctl.Response
+			if err := client.Call("Server.Logs", q, &resp); err != nil {
+				log.Fatal(err)
+			}
+
+			res, _ := resp.Yield.(journal.Result)
+			switch {
+			case output == outputExport:
+				if err := journal.WriteExport(os.Stdout, res.Records...); err != nil {
+					log.Fatal(err)
+				}
+			case output == outputJournalJSON:
+				for _, rec := range res.Records {
+					printJSON(rec.JournalJSON())
+				}
+			case jsonOutput():
+				for _, rec := range res.Records {
+					printJSON(toRecordJSON(rec))
+				}
+			default:
+				for _, rec := range res.Records {
+					printRecord(rec)
+				}
+			}
+
+			if !logsFollow {
+				return
+			}
+			q.Cursor, q.Lines = res.Cursor, 0
+			time.Sleep(followInterval)
+		}
+	},
+}
+
+// printRecord prints rec in human-readable form, the messages of the manager are tagged with journal.MANAGER_TAG
+func printRecord(rec journal.Record) {
+	tag := rec.Unit
+	if tag == "" {
+		tag = journal.MANAGER_TAG
+	}
+	fmt.Printf("%s %s: %s\n", rec.Time.Format(recordTimeFormat), tag, rec.Message)
+}
+
+// printUsage prints the disk usage of the journal
+func printUsage() {
+	var resp systemctl.Response
+	if err := client.Call("Server.JournalUsage", []string{}, &resp); err != nil {
+		log.Fatal(err)
+	}
+
+	usage, _ := resp.Yield.(journal.Usage)
+	if jsonOutput() {
+		printJSON(toUsageJSON(usage))
+		return
+	}
+	fmt.Printf("Journal files take up %s in %d files(limit %s, %s per file)\n",
+		formatBytes(usage.Bytes), usage.Files, formatBytes(usage.MaxUse), formatBytes(usage.MaxFileSize))
+}
+
+// logsQuery returns the query specified by the flags of cmd
+func logsQuery(cmd *cobra.Command) (q journal.Query, err error) {
+	q.Units, q.Boot, q.Lines = logsUnits, logsBoot, logsLines
+	if logsFollow && !cmd.Flags().Changed("lines") {
+		q.Lines = followLines
+	}
+
+	if logsPriority != "" {
+		p, err := journal.ParsePriority(logsPriority)
+		if err != nil {
+			return q, err
+		}
+		q.Priority = &p
+	}
+
+	now := time.Now()
+	if logsSince != "" {
+		if q.Since, err = parseTime(logsSince, now); err != nil {
+			return
+		}
+	}
+	if logsUntil != "" {
+		if q.Until, err = parseTime(logsUntil, now); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// parseTime parses the time specified as "2006-01-02 15:04:05", "2006-01-02", "15:04:05", "today", "yesterday",
+// "now" or relative to now, e.g. "-1h" or "1h ago"
+func parseTime(s string, now time.Time) (t time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if strings.HasSuffix(s, " ago") {
+		s = "-" + strings.TrimSpace(strings.TrimSuffix(s, " ago"))
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return t, err
+		}
+		return now.Add(d), nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err = time.ParseInLocation(layout, s, time.Local); err == nil {
+			return
+		}
+	}
+	if t, err = time.ParseInLocation("15:04:05", s, time.Local); err == nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+	}
+	return t, fmt.Errorf("Failed to parse time: %q", s)
+}
+
+func init() {
+	RootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().StringArrayVarP(&logsUnits, "unit", "u", nil, "Print the records of the unit specified, may be repeated")
+	logsCmd.Flags().StringVarP(&logsSince, "since", "S", "", "Print the records appended at or after the time specified")
+	logsCmd.Flags().StringVarP(&logsUntil, "until", "U", "", "Print the records appended before the time specified")
+	logsCmd.Flags().StringVarP(&logsPriority, "priority", "p", "",
+		"Print the records with the priority specified(e.g. err or 3) or higher")
+	logsCmd.Flags().StringVarP(&logsBoot, "boot", "b", "",
+		`Print the records of the boot specified by ID or offset, 0 being the current one and -1 the previous one`)
+	logsCmd.Flags().Lookup("boot").NoOptDefVal = "0"
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 0, "Print the last records only, as many as specified")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Print the records appended, until interrupted")
+	logsCmd.Flags().BoolVar(&logsUsage, "disk-usage", false, "Print the disk usage of the journal")
+}
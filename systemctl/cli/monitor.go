@@ -0,0 +1,145 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/spf13/cobra"
+)
+
+// Format of the times of the events printed
+const eventTimeFormat = "Jan 02 15:04:05.000"
+
+// monitorCmd represents the monitor command
+var monitorCmd = &cobra.Command{
+	Use:   "monitor [UNIT...]",
+	Short: "Print state changes of units as they happen",
+	Long: `monitor prints the changes of the activation states of the units specified(or all units, if none are)
+and the jobs finished for them as they happen, until interrupted`,
+	Run: func(cmd *cobra.Command, args []string) {
+		follow(args)
+	},
+}
+
+// eventJSON is an event printed by monitor and status --follow
+type eventJSON struct {
+	Type    string `json:"type"`
+	Time    string `json:"time"`
+	Unit    string `json:"unit,omitempty"`
+	Active  string `json:"active,omitempty"`
+	Sub     string `json:"sub,omitempty"`
+	Job     uint64 `json:"job,omitempty"`
+	JobType string `json:"job_type,omitempty"`
+	Result  string `json:"result,omitempty"`
+}
+
+// follow prints the events of the units in names, all units if empty, until interrupted
+func follow(names []string) {
+	var resp systemctl.Response
+	if err := client.Call("Server.Subscribe", systemctl.SubscribeRequest{Names: names}, &resp); err != nil {
+		log.Fatal(err)
+	}
+	id, _ := resp.Yield.(uint64)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		client.Call("Server.Unsubscribe", systemctl.SubscriptionRequest{ID: id}, nil)
+		os.Exit(0)
+	}()
+
+	for {
+		var resp systemctl.Response
+		if err := client.Call("Server.Events", systemctl.SubscriptionRequest{ID: id}, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		notifications, _ := resp.Yield.([]systemctl.Notification)
+		for _, n := range notifications {
+			if jsonOutput() {
+				printJSON(toEventJSON(n))
+			} else {
+				printNotification(os.Stdout, n)
+			}
+		}
+	}
+}
+
+// printNotification writes n to w in human-readable form
+func printNotification(w io.Writer, n systemctl.Notification) {
+	fmt.Fprintf(w, "%s ", n.Time.Format(eventTimeFormat))
+
+	switch n.Type {
+	case system.UnitLoaded:
+		fmt.Fprintf(w, "%s: loaded\n", n.Unit)
+	case system.UnitActiveChanged:
+		fmt.Fprintf(w, "%s: %s (%s)\n", n.Unit, strings.ToLower(n.Active.String()), n.Sub)
+	case system.JobFinished:
+		fmt.Fprintf(w, "%s: %s job %d finished: %s\n", n.Unit, n.Job.Type, n.Job.ID, result(n))
+	case system.BootFinished:
+		fmt.Fprintln(w, "Startup finished")
+	default:
+		fmt.Fprintln(w, n.Type)
+	}
+}
+
+// result returns the result of the job finished as reported by n
+func result(n systemctl.Notification) string {
+	if n.Error != "" {
+		return n.Error
+	}
+	return "done"
+}
+
+func toEventJSON(n systemctl.Notification) eventJSON {
+	v := eventJSON{
+		Type: n.Type.String(),
+		Time: n.Time.Format(time.RFC3339Nano),
+		Unit: n.Unit,
+	}
+
+	switch n.Type {
+	case system.UnitLoaded, system.UnitActiveChanged:
+		v.Active, v.Sub = strings.ToLower(n.Active.String()), n.Sub
+	case system.JobFinished:
+		if n.Job != nil {
+			v.Job, v.JobType = n.Job.ID, n.Job.Type
+		}
+		v.Result = result(n)
+	}
+	return v
+}
+
+func init() {
+	RootCmd.AddCommand(monitorCmd)
+}
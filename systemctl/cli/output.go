@@ -0,0 +1,313 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Output modes
+const (
+	outputText       = "text"
+	outputJSON       = "json"
+	outputJSONPretty = "json-pretty"
+
+	// Fields named as journalctl -o json does and the Journal Export Format, printed by logs only
+	outputJournalJSON = "journal-json"
+	outputExport      = "export"
+)
+
+// output is the mode of the output of the listing and status commands
+var output string
+
+// jsonOutput reports whether the output is to be printed as JSON
+func jsonOutput() bool {
+	return output == outputJSON || output == outputJSONPretty
+}
+
+// checkOutput exits, if the output mode specified is unknown
+func checkOutput() {
+	switch output {
+	case outputText, outputJSON, outputJSONPretty, outputJournalJSON, outputExport:
+	default:
+		log.Fatalf("Unknown output mode: %s", output)
+	}
+}
+
+// printJSON prints v as JSON to the standard output, indented with json-pretty
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	if output == outputJSONPretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// The structures printed with --output=json are documented in README.
+// Time spans are in microseconds, the states are lower-case as in the text output
+
+// unitJSON is a unit listed by list-units
+type unitJSON struct {
+	Unit        string `json:"unit"`
+	Load        string `json:"load"`
+	Active      string `json:"active"`
+	Sub         string `json:"sub"`
+	Description string `json:"description"`
+}
+
+// failureJSON is a unit listed by list-units --failed
+type failureJSON struct {
+	Unit     string    `json:"unit"`
+	Reason   string    `json:"reason"`
+	ExitCode int       `json:"exit_code"`
+	Since    time.Time `json:"since"`
+}
+
+// unitFileJSON is a unit file listed by list-unit-files
+type unitFileJSON struct {
+	UnitFile string `json:"unit_file"`
+	State    string `json:"state"`
+}
+
+// jobJSON is a job listed by list-jobs
+type jobJSON struct {
+	Job        uint64   `json:"job"`
+	Unit       string   `json:"unit"`
+	Type       string   `json:"type"`
+	State      string   `json:"state"`
+	WaitingFor []string `json:"waiting_for"`
+}
+
+// inhibitorJSON is an inhibitor lock listed by inhibit --list
+type inhibitorJSON struct {
+	What  string    `json:"what"`
+	Who   string    `json:"who"`
+	Why   string    `json:"why"`
+	Mode  string    `json:"mode"`
+	PID   int       `json:"pid"`
+	Since time.Time `json:"since"`
+}
+
+// dependencyJSON is a node of the tree printed by list-dependencies
+type dependencyJSON struct {
+	Unit         string           `json:"unit"`
+	Active       string           `json:"active"`
+	Dependencies []dependencyJSON `json:"dependencies"`
+}
+
+// stateJSON is a state printed by is-active, is-enabled and is-failed
+type stateJSON struct {
+	Unit  string `json:"unit"`
+	State string `json:"state"`
+}
+
+// unitTimeJSON is a unit listed by analyze blame and analyze critical-chain
+type unitTimeJSON struct {
+	Unit           string `json:"unit"`
+	ActivatingUsec int64  `json:"activating_usec"`
+	ActivatedUsec  int64  `json:"activated_usec"`
+	TimeUsec       int64  `json:"time_usec"`
+}
+
+// securityJSON is the security audit of a unit printed by analyze security
+type securityJSON struct {
+	Unit     string              `json:"unit"`
+	Exposure float64             `json:"exposure"`
+	Rating   string              `json:"rating"`
+	Checks   []securityCheckJSON `json:"checks"`
+}
+
+// securityCheckJSON is the result of a check of a security audit
+type securityCheckJSON struct {
+	Directive      string  `json:"directive"`
+	Description    string  `json:"description"`
+	Recommendation string  `json:"recommendation,omitempty"`
+	Weight         float64 `json:"weight"`
+	Passed         bool    `json:"passed"`
+}
+
+// statusJSON is the status of a unit printed by status
+type statusJSON struct {
+	Unit           string                 `json:"unit"`
+	Description    string                 `json:"description"`
+	Load           string                 `json:"load"`
+	UnitFileState  string                 `json:"unit_file_state"`
+	VendorPreset   string                 `json:"vendor_preset"`
+	FragmentPath   string                 `json:"fragment_path"`
+	DropInPaths    []string               `json:"drop_in_paths"`
+	Active         string                 `json:"active"`
+	Sub            string                 `json:"sub"`
+	StatusText     string                 `json:"status_text,omitempty"`
+	StatusErrno    int                    `json:"status_errno,omitempty"`
+	StatusBusError string                 `json:"status_bus_error,omitempty"`
+	Condition      string                 `json:"condition,omitempty"`
+	Assert         string                 `json:"assert,omitempty"`
+	Warnings       []string               `json:"warnings"`
+	Dependencies   []dependencyResultJSON `json:"dependencies"`
+	MainPID        int                    `json:"main_pid"`
+	Processes      []processJSON          `json:"processes"`
+	MemoryBytes    uint64                 `json:"memory_bytes"`
+	CPUUsec        int64                  `json:"cpu_usec"`
+	Log            []string               `json:"log"`
+}
+
+// dependencyResultJSON is the result of the job of a dependency on the last job of a unit
+type dependencyResultJSON struct {
+	Unit   string `json:"unit"`
+	Kind   string `json:"kind"`
+	Result string `json:"result"`
+}
+
+// processJSON is a process of a unit
+type processJSON struct {
+	PID     int    `json:"pid"`
+	PPID    int    `json:"ppid"`
+	Command string `json:"command"`
+}
+
+// recordJSON is a record of the journal printed by logs
+type recordJSON struct {
+	Time     string            `json:"time"`
+	Boot     string            `json:"boot"`
+	Unit     string            `json:"unit"`
+	Priority string            `json:"priority"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// usageJSON is the disk usage of the journal printed by logs --disk-usage
+type usageJSON struct {
+	Files       int    `json:"files"`
+	Bytes       uint64 `json:"bytes"`
+	MaxUse      uint64 `json:"max_use"`
+	MaxFileSize uint64 `json:"max_file_size"`
+}
+
+func toUnitJSON(name string, st unit.Status) unitJSON {
+	return unitJSON{
+		Unit:        name,
+		Load:        strings.ToLower(st.Load.Loaded.String()),
+		Active:      strings.ToLower(st.Activation.State.String()),
+		Sub:         st.Activation.Sub,
+		Description: st.Description,
+	}
+}
+
+func toDependencyJSON(dep system.Dependency) dependencyJSON {
+	v := dependencyJSON{
+		Unit:         dep.Unit,
+		Active:       strings.ToLower(dep.Active.String()),
+		Dependencies: make([]dependencyJSON, len(dep.Dependencies)),
+	}
+	for i, child := range dep.Dependencies {
+		v.Dependencies[i] = toDependencyJSON(child)
+	}
+	return v
+}
+
+func toUnitTimeJSON(ut system.UnitTime) unitTimeJSON {
+	return unitTimeJSON{
+		Unit:           ut.Unit,
+		ActivatingUsec: usec(ut.Activating),
+		ActivatedUsec:  usec(ut.Activated),
+		TimeUsec:       usec(ut.Time()),
+	}
+}
+
+func toStatusJSON(name string, st unit.Status) statusJSON {
+	v := statusJSON{
+		Unit:           name,
+		Description:    st.Description,
+		Load:           strings.ToLower(st.Load.Loaded.String()),
+		UnitFileState:  enableState(st.Load.State),
+		VendorPreset:   enableState(st.Load.Vendor),
+		FragmentPath:   st.Load.Path,
+		DropInPaths:    append([]string{}, st.Load.DropIns...),
+		Active:         strings.ToLower(st.Activation.State.String()),
+		Sub:            st.Activation.Sub,
+		StatusText:     st.StatusText,
+		StatusErrno:    st.StatusErrno,
+		StatusBusError: st.StatusBusError,
+		Condition:      st.Condition,
+		Assert:         st.Assert,
+		Warnings:       append([]string{}, st.Warnings...),
+		Dependencies:   make([]dependencyResultJSON, len(st.Dependencies)),
+		MainPID:        st.MainPID,
+		Processes:      make([]processJSON, len(st.Processes)),
+		MemoryBytes:    st.Memory,
+		CPUUsec:        usec(st.CPU),
+		Log:            lastLines(st.Log, statusLines),
+	}
+	for i, dep := range st.Dependencies {
+		v.Dependencies[i] = dependencyResultJSON{Unit: dep.Name, Kind: dep.Kind, Result: dep.Result}
+	}
+	for i, p := range st.Processes {
+		v.Processes[i] = processJSON{PID: p.PID, PPID: p.PPID, Command: p.Command}
+	}
+	if v.Log == nil {
+		v.Log = []string{}
+	}
+	return v
+}
+
+func toRecordJSON(rec journal.Record) recordJSON {
+	return recordJSON{
+		Time:     rec.Time.Format(time.RFC3339Nano),
+		Boot:     rec.Boot,
+		Unit:     rec.Unit,
+		Priority: rec.Priority.String(),
+		Message:  rec.Message,
+		Fields:   rec.Fields,
+	}
+}
+
+func toUsageJSON(usage journal.Usage) usageJSON {
+	return usageJSON{
+		Files:       usage.Files,
+		Bytes:       usage.Bytes,
+		MaxUse:      usage.MaxUse,
+		MaxFileSize: usage.MaxFileSize,
+	}
+}
+
+// enableState returns st as printed, empty if unknown
+func enableState(st unit.Enable) string {
+	if st < 0 {
+		return ""
+	}
+	return strings.ToLower(st.String())
+}
+
+// usec returns d in microseconds
+func usec(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
@@ -28,6 +28,8 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/plasma-umass/systemgo/config"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
 	"github.com/spf13/cobra"
 )
 
@@ -35,6 +37,18 @@ var client *rpc.Client
 
 var cfgFile string
 
+// host is the remote host to manage over SSH, "[user@]host[:port]"
+var host string
+
+// jobMode is the mode jobs requested are enqueued in
+var jobMode string
+
+// rootDir is the directory the image operated on instead of the manager is mounted at, see DialRoot
+var rootDir string
+
+// userMode is whether the manager of the user running the client is talked to instead of the system manager
+var userMode bool
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "systemctl",
@@ -52,7 +66,82 @@ func Execute() {
 	}
 }
 
+// offline is the annotation of commands, which do not need a connection to the manager
+const offline = "offline"
+
+// rooted is the annotation of commands, which may operate on the unit files of an image specified by --root
+const rooted = "rooted"
+
 func init() {
+	RootCmd.PersistentFlags().StringVar(&jobMode, "job-mode", string(system.ReplaceMode),
+		"How to deal with already queued jobs(replace, fail, isolate, ignore-dependencies or ignore-requirements)")
+	RootCmd.PersistentFlags().StringVarP(&host, "host", "H", "",
+		"Manage the remote host([user@]host[:port]) over SSH")
+	RootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputText,
+		"Output mode of the listing and status commands(text, json or json-pretty), journal-json or export for logs")
+	RootCmd.PersistentFlags().BoolVar(&userMode, "user", false,
+		"Talk to the manager of the user running the client")
+	RootCmd.PersistentFlags().StringVar(&rootDir, "root", "",
+		"Operate on the unit files of the image mounted at the directory specified without a running manager")
+
+	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if userMode {
+			// The socket and unit paths of the manager of the user are used
+			config.SetUser()
+		}
+		checkOutput()
+		switch {
+		case cmd.Annotations[offline] != "":
+		case rootDir != "":
+			dialRoot(cmd)
+		default:
+			dial()
+		}
+	}
+}
+
+// dialRoot makes the client operate on the unit files of the image mounted at rootDir,
+// if cmd supports it
+func dialRoot(cmd *cobra.Command) {
+	e := log.WithField("root", rootDir)
+	if cmd.Annotations[rooted] == "" {
+		e.Fatalf("%s does not support --root", cmd.Name())
+	}
+	if info, err := os.Stat(rootDir); err != nil || !info.IsDir() {
+		e.Fatalf("Root directory not found")
+	}
+
+	var err error
+	if client, err = systemctl.DialRoot(rootDir, config.Paths, config.PresetPaths); err != nil {
+		e.Fatalf("Error opening root: %s", err)
+	}
+}
+
+// dial connects the client to the manager using the control socket or HTTP, if a port is configured.
+// The manager on the host specified by --host is connected to over SSH
+func dial() {
+	if host != "" {
+		e := log.WithField("host", host)
+		e.Debugf("Dialing...")
+
+		var err error
+		if client, err = systemctl.DialSSH(host); err != nil {
+			e.Fatalf("Dial failed: %s", err)
+		}
+		return
+	}
+
+	if config.Port == 0 {
+		e := log.WithField("socket", config.Socket)
+		e.Debugf("Dialing...")
+
+		var err error
+		if client, err = systemctl.Dial(config.Socket); err != nil {
+			e.Fatalf("Dial failed: %s", err)
+		}
+		return
+	}
+
 	addr := fmt.Sprintf("localhost%s", config.Port)
 
 	e := log.WithField("addr", addr)
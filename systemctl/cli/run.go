@@ -0,0 +1,153 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the run command
+var (
+	runUnit       string
+	runProperties []string
+	runUID        string
+	runSlice      string
+	runCalendar   string
+	runScope      bool
+	runCollect    bool
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [flags] COMMAND [ARGS...]",
+	Short: "Run a command as a transient service or scope",
+	Long: `run creates and starts a transient service running the command specified.
+With --scope the command is executed by systemctl itself as a process of a transient scope instead,
+with --on-calendar a transient timer is started, which starts the service on the calendar events specified.
+Transient units are unloaded, once they are inactive, with --collect also once they failed`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			log.Fatal(err)
+		}
+
+		if runScope && (runCalendar != "" || runUID != "" || host != "") {
+			log.Fatal("--scope can not be combined with --on-calendar, --uid or --host")
+		}
+
+		name := runUnit
+		if name == "" {
+			name = fmt.Sprintf("run-u%x", time.Now().UnixNano())
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".service"), ".scope")
+
+		props := []unit.Property{{Name: "Description", Value: strings.Join(args, " ")}}
+		if runScope {
+			name += ".scope"
+			props = append(props, unit.Property{Name: "PIDs", Value: strconv.Itoa(os.Getpid())})
+		} else {
+			name += ".service"
+			props = append(props, unit.Property{Name: "ExecStart", Value: strings.Join(append([]string{path}, args[1:]...), " ")})
+			if runUID != "" {
+				props = append(props, unit.Property{Name: "User", Value: runUID})
+			}
+		}
+		if runCollect {
+			props = append(props, unit.Property{Name: "CollectMode", Value: system.CollectInactiveOrFailed})
+		}
+		if runSlice != "" {
+			props = append(props, unit.Property{Name: "Slice", Value: runSlice})
+		}
+
+		for _, arg := range runProperties {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				log.Fatalf("Invalid assignment: %s", arg)
+			}
+			props = append(props, unit.Property{Name: parts[0], Value: parts[1]})
+		}
+
+		req := systemctl.TransientRequest{
+			Mode:  system.JobMode(jobMode),
+			Units: []system.TransientUnit{{Name: name, Properties: props}},
+		}
+
+		if runCalendar != "" {
+			timer := strings.TrimSuffix(name, ".service") + ".timer"
+			req.Units = append([]system.TransientUnit{{Name: timer, Properties: []unit.Property{
+				{Name: "Description", Value: strings.Join(args, " ")},
+				{Name: "OnCalendar", Value: runCalendar},
+				{Name: "Unit", Value: name},
+			}}}, req.Units...)
+		}
+
+		var resp systemctl.Response
+		if err := client.Call("Server.StartTransient", req, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		switch {
+		case runScope:
+			fmt.Printf("Running scope as unit: %s\n", name)
+			if err := syscall.Exec(path, args, os.Environ()); err != nil {
+				log.Fatal(err)
+			}
+		case runCalendar != "":
+			fmt.Printf("Running timer as unit: %s\n", req.Units[0].Name)
+			fmt.Printf("Will run service as unit: %s\n", name)
+		default:
+			fmt.Printf("Running as unit: %s\n", name)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(runCmd)
+
+	// Flags following the command are the arguments of the command
+	runCmd.Flags().SetInterspersed(false)
+
+	runCmd.Flags().StringVarP(&runUnit, "unit", "u", "", "Name of the transient unit")
+	runCmd.Flags().StringArrayVarP(&runProperties, "property", "p", nil, "Set a property of the unit(NAME=VALUE)")
+	runCmd.Flags().StringVar(&runUID, "uid", "", "Run the service as the user specified by name or numeric ID")
+	runCmd.Flags().StringVar(&runSlice, "slice", "", "Create the cgroup of the unit in the slice specified")
+	runCmd.Flags().StringVar(&runCalendar, "on-calendar", "", "Start a transient timer starting the service on the calendar events specified")
+	runCmd.Flags().BoolVarP(&runCollect, "collect", "G", false, "Unload the unit also once it failed")
+	runCmd.Flags().BoolVar(&runScope, "scope", false, "Run the command in a transient scope instead of a service")
+}
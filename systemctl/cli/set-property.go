@@ -0,0 +1,64 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+)
+
+// runtimeProperties is true, if the properties set are not to be persisted
+var runtimeProperties bool
+
+// setPropertyCmd represents the set-property command
+var setPropertyCmd = &cobra.Command{
+	Use:   "set-property UNIT PROPERTY=VALUE...",
+	Short: "Sets one or more properties of a unit",
+	Long: `set-property changes CPUQuota=, MemoryMax=, TasksMax= or Restart= of a unit loaded at runtime.
+The resource controls are applied to the cgroup of the unit immediately. The properties are persisted in drop-in files,
+unless --runtime is specified, in which case they are lost once the manager exits`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		req := systemctl.PropertyRequest{Name: args[0], Runtime: runtimeProperties}
+		for _, arg := range args[1:] {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				log.Fatalf("Invalid assignment: %s", arg)
+			}
+			req.Properties = append(req.Properties, unit.Property{Name: parts[0], Value: parts[1]})
+		}
+
+		var resp systemctl.Response
+		if err := client.Call("Server.SetProperty", req, &resp); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(setPropertyCmd)
+	setPropertyCmd.Flags().BoolVar(&runtimeProperties, "runtime", false, "Do not persist the properties")
+}
@@ -0,0 +1,109 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Names of the properties to show, all if empty
+	showProperties []string
+
+	// showJSON is true, if the properties are to be printed as JSON objects
+	showJSON bool
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show UNIT...",
+	Short: "Show properties of one or more units",
+	Long: `show prints the runtime properties and the directives of the definitions of the units specified
+as key=value pairs or, with --json, as a JSON object mapping the names of the units to their properties`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.Show", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		props, _ := resp.Yield.(map[string][]unit.Property)
+
+		if showJSON && !jsonOutput() {
+			// --json prints the properties as --output=json-pretty does
+			output = outputJSONPretty
+		}
+
+		if jsonOutput() {
+			units := map[string]map[string]string{}
+			for name, list := range props {
+				units[name] = map[string]string{}
+				for _, prop := range filterProperties(list) {
+					units[name][prop.Name] = prop.Value
+				}
+			}
+
+			printJSON(units)
+			return
+		}
+
+		for i, name := range args {
+			if i > 0 {
+				fmt.Println()
+			}
+			for _, prop := range filterProperties(props[name]) {
+				fmt.Printf("%s=%s\n", prop.Name, prop.Value)
+			}
+		}
+	},
+}
+
+// filterProperties returns the properties in props requested by --property
+func filterProperties(props []unit.Property) []unit.Property {
+	if len(showProperties) == 0 {
+		return props
+	}
+
+	requested := map[string]bool{}
+	for _, name := range showProperties {
+		requested[name] = true
+	}
+
+	filtered := make([]unit.Property, 0, len(showProperties))
+	for _, prop := range props {
+		if requested[prop.Name] {
+			filtered = append(filtered, prop)
+		}
+	}
+	return filtered
+}
+
+func init() {
+	RootCmd.AddCommand(showCmd)
+	showCmd.Flags().StringSliceVarP(&showProperties, "property", "p", nil, "Show only the properties specified")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "Print the properties as JSON")
+}
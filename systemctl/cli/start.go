@@ -23,6 +23,8 @@ package cli
 import (
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
 	"github.com/spf13/cobra"
 )
 
@@ -32,7 +34,7 @@ var startCmd = &cobra.Command{
 	Short: "Start (activate) one or more units",
 	Long:  `TODO: add description`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := client.Call("Server.Start", args, nil); err != nil {
+		if err := client.Call("Server.Start", systemctl.JobRequest{Names: args, Mode: system.JobMode(jobMode)}, nil); err != nil {
 			log.Error(err)
 		}
 	},
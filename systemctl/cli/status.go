@@ -22,7 +22,14 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/plasma-umass/systemgo/system"
 	"github.com/plasma-umass/systemgo/systemctl"
 	"github.com/plasma-umass/systemgo/unit"
 	"github.com/spf13/cobra"
@@ -34,23 +41,233 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show runtime status of one or more units",
-	Long:  `TODO: add description`,
+	Long: `status prints the load and activation states, the results of the last start, the processes and the last lines of the log of the units specified.
+With --follow the changes of the states are printed afterwards as they happen, until interrupted`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var resp systemctl.Response
 		if err := client.Call("Server.Status", args, &resp); err != nil {
 			log.Error(err)
 		}
 
-		if resp.Yield != nil {
-			for _, st := range resp.Yield.(map[string]unit.Status) {
-				fmt.Println(st)
+		if statuses, ok := resp.Yield.(map[string]unit.Status); ok && jsonOutput() {
+			list := make([]statusJSON, 0, len(statuses))
+			for _, name := range sortedNames(statuses) {
+				list = append(list, toStatusJSON(name, statuses[name]))
 			}
+			printJSON(list)
+		} else if ok {
+			for i, name := range sortedNames(statuses) {
+				if i > 0 {
+					fmt.Println()
+				}
+				printStatus(os.Stdout, name, statuses[name])
+			}
+		}
+
+		if statusFollow {
+			follow(args)
 		}
 	},
 }
 
+// statusFollow is true, if the changes of the states are to be printed as they happen
+var statusFollow bool
+
+// Number of the log lines of a unit printed by status
+var statusLines int
+
+// printStatus writes the status st of the unit name to w in human-readable form
+func printStatus(w io.Writer, name string, st unit.Status) {
+	if st.Description != "" {
+		fmt.Fprintf(w, "● %s - %s\n", name, st.Description)
+	} else {
+		fmt.Fprintf(w, "● %s\n", name)
+	}
+
+	field := func(key, format string, args ...interface{}) {
+		fmt.Fprintf(w, "%11s: %s\n", key, fmt.Sprintf(format, args...))
+	}
+
+	field("Loaded", "%s (%s; %s; vendor preset: %s)", strings.ToLower(st.Load.Loaded.String()),
+		st.Load.Path, strings.ToLower(st.Load.State.String()), strings.ToLower(st.Load.Vendor.String()))
+	if len(st.Load.DropIns) > 0 {
+		field("Drop-In", "%s", strings.Join(st.Load.DropIns, "\n             "))
+	}
+	active := fmt.Sprintf("%s (%s)", strings.ToLower(st.Activation.State.String()), st.Activation.Sub)
+	if since := st.Activation.Since; !since.IsZero() {
+		active += fmt.Sprintf(" since %s; %s ago", since.Format(system.TIMESTAMP_FORMAT), formatAgo(time.Since(since)))
+	}
+	field("Active", "%s", active)
+	if st.StatusText != "" {
+		field("Status", "%q", st.StatusText)
+	}
+	if st.StatusErrno > 0 {
+		field("Error", "%d (%s)", st.StatusErrno, syscall.Errno(st.StatusErrno))
+	}
+	if st.StatusBusError != "" {
+		field("Bus Error", "%s", st.StatusBusError)
+	}
+
+	if st.Condition != "" {
+		field("Condition", "start condition failed: %s", st.Condition)
+	}
+	if st.Assert != "" {
+		field("Assert", "start assertion failed: %s", st.Assert)
+	}
+	for _, warning := range st.Warnings {
+		field("Warning", "%s", warning)
+	}
+	for _, dep := range st.Dependencies {
+		field("Dependency", "%s (%s): %s", dep.Name, dep.Kind, dep.Result)
+	}
+
+	if st.MainPID > 0 {
+		field("Main PID", "%d (%s)", st.MainPID, commandOf(st.Processes, st.MainPID))
+	}
+	if st.ControlPID > 0 {
+		field("Control PID", "%d (%s)", st.ControlPID, commandOf(st.Processes, st.ControlPID))
+	}
+	if len(st.Processes) > 0 {
+		field("Memory", "%s", formatBytes(st.Memory))
+		field("CPU", "%s", st.CPU)
+
+		for i, line := range processTree(st.Processes) {
+			if i == 0 {
+				field("Processes", "%s", line)
+			} else {
+				fmt.Fprintf(w, "%13s%s\n", "", line)
+			}
+		}
+	}
+
+	if lines := lastLines(st.Log, statusLines); len(lines) > 0 {
+		fmt.Fprintln(w)
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// processTree returns the lines of the tree of procs, where parents precede children
+func processTree(procs []unit.Process) (lines []string) {
+	listed := map[int]bool{}
+	for _, p := range procs {
+		listed[p.PID] = true
+	}
+
+	var roots []unit.Process
+	children := map[int][]unit.Process{}
+	for _, p := range procs {
+		if listed[p.PPID] {
+			children[p.PPID] = append(children[p.PPID], p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+
+	var walk func(p unit.Process, prefix string, last bool)
+	walk = func(p unit.Process, prefix string, last bool) {
+		branch, indent := "├─", "│ "
+		if last {
+			branch, indent = "└─", "  "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s%d %s", prefix, branch, p.PID, p.Command))
+
+		for i, child := range children[p.PID] {
+			walk(child, prefix+indent, i == len(children[p.PID])-1)
+		}
+	}
+	for i, root := range roots {
+		walk(root, "", i == len(roots)-1)
+	}
+	return
+}
+
+// commandOf returns the name of the command of the process pid listed in procs, "?" if it is not listed
+func commandOf(procs []unit.Process, pid int) string {
+	for _, p := range procs {
+		if p.PID != pid {
+			continue
+		}
+		if args := strings.Fields(p.Command); len(args) > 0 {
+			return filepath.Base(args[0])
+		}
+	}
+	return "?"
+}
+
+// lastLines returns up to n last lines of log
+func lastLines(log []byte, n int) []string {
+	s := strings.TrimRight(string(log), "\n")
+	if s == "" || n <= 0 {
+		return nil
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// formatBytes returns n in human-readable form
+func formatBytes(n uint64) string {
+	const k = 1024
+	if n < k {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := uint64(k), 0
+	for m := n / k; m >= k; m /= k {
+		div *= k
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatAgo returns d in human-readable form using its two largest units as systemd does, e.g. "3h 12min" or "2 days 1h"
+func formatAgo(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{24 * time.Hour, " days"},
+		{time.Hour, "h"},
+		{time.Minute, "min"},
+		{time.Second, "s"},
+	}
+
+	var parts []string
+	for i, u := range units {
+		n := d / u.d
+		if n == 0 {
+			continue
+		}
+
+		name := u.name
+		if n == 1 && name == " days" {
+			name = " day"
+		}
+		parts = append(parts, fmt.Sprintf("%d%s", n, name))
+
+		if i+1 < len(units) {
+			if next := (d % u.d) / units[i+1].d; next > 0 {
+				parts = append(parts, fmt.Sprintf("%d%s", next, units[i+1].name))
+			}
+		}
+		break
+	}
+	return strings.Join(parts, " ")
+}
+
 func init() {
 	RootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().IntVarP(&statusLines, "lines", "n", 10, "Number of log lines to show")
+	statusCmd.Flags().BoolVarP(&statusFollow, "follow", "f", false, "Print the changes of the states as they happen")
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,257 @@
+package systemctl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"reflect"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Maximum length of a frame received on the control socket
+const MAX_FRAME = 16 << 20
+
+var ErrFrameTooLarge = errors.New("Frame too large")
+var ErrUnknownYield = errors.New("Unknown type yielded")
+
+// yields maps the names of the types yielded in responses to the types
+var yields = map[string]reflect.Type{}
+
+// register registers the type of v to be yielded in responses
+func register(v interface{}) {
+	gob.Register(v)
+
+	t := reflect.TypeOf(v)
+	yields[t.String()] = t
+}
+
+// yield is the JSON encoding of a Response, specifying the type yielded
+type yield struct {
+	Type  string          `json:"Type,omitempty"`
+	Yield json.RawMessage `json:"Yield,omitempty"`
+}
+
+func (resp Response) MarshalJSON() ([]byte, error) {
+	if resp.Yield == nil {
+		return json.Marshal(yield{})
+	}
+
+	b, err := json.Marshal(resp.Yield)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(yield{reflect.TypeOf(resp.Yield).String(), b})
+}
+
+func (resp *Response) UnmarshalJSON(b []byte) (err error) {
+	var y yield
+	if err = json.Unmarshal(b, &y); err != nil {
+		return
+	}
+
+	if y.Type == "" {
+		resp.Yield = nil
+		return nil
+	}
+
+	t, ok := yields[y.Type]
+	if !ok {
+		return fmt.Errorf("%s: %s", ErrUnknownYield, y.Type)
+	}
+
+	v := reflect.New(t)
+	if err = json.Unmarshal(y.Yield, v.Interface()); err != nil {
+		return
+	}
+	resp.Yield = v.Elem().Interface()
+	return nil
+}
+
+// request is a frame sent by the client
+type request struct {
+	Method string          `json:"Method"`
+	Seq    uint64          `json:"Seq"`
+	Params json.RawMessage `json:"Params,omitempty"`
+}
+
+// response is a frame sent by the server
+type response struct {
+	Seq    uint64          `json:"Seq"`
+	Error  string          `json:"Error,omitempty"`
+	Result json.RawMessage `json:"Result,omitempty"`
+}
+
+// conn reads and writes frames, each being a JSON object prefixed by its length as a big-endian uint32
+type conn struct {
+	rwc io.ReadWriteCloser
+	r   *bufio.Reader
+
+	mutex sync.Mutex
+}
+
+func newConn(rwc io.ReadWriteCloser) *conn {
+	return &conn{rwc: rwc, r: bufio.NewReader(rwc)}
+}
+
+// read decodes the next frame into v
+func (c *conn) read(v interface{}) (err error) {
+	var n uint32
+	if err = binary.Read(c.r, binary.BigEndian, &n); err != nil {
+		return
+	}
+	if n > MAX_FRAME {
+		return ErrFrameTooLarge
+	}
+
+	b := make([]byte, n)
+	if _, err = io.ReadFull(c.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	return json.Unmarshal(b, v)
+}
+
+// write encodes v as a frame
+func (c *conn) write(v interface{}) (err error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if len(b) > MAX_FRAME {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(frame, uint32(len(b)))
+	copy(frame[4:], b)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, err = c.rwc.Write(frame)
+	return
+}
+
+func (c *conn) Close() error {
+	return c.rwc.Close()
+}
+
+// serverCodec serves requests of a single client.
+// Requests for the methods, which the access of the client does not allow, are refused
+type serverCodec struct {
+	*conn
+	req request
+
+	// Process on the other end of the connection
+	peer *peer
+
+	access access
+}
+
+func newServerCodec(rwc io.ReadWriteCloser, p *peer, a access) *serverCodec {
+	return &serverCodec{conn: newConn(rwc), peer: p, access: a}
+}
+
+// fromPeer is implemented by the requests, which are told the peer they are received from
+type fromPeer interface {
+	setPeer(p *peer)
+}
+
+// caller records the peer a request is received from, it is nil if the request was not received on a connection
+type caller struct {
+	peer *peer
+}
+
+func (c *caller) setPeer(p *peer) {
+	c.peer = p
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) (err error) {
+	for {
+		c.req = request{}
+		if err = c.read(&c.req); err != nil {
+			return
+		}
+
+		if c.access.allows(c.req.Method) {
+			r.ServiceMethod = c.req.Method
+			r.Seq = c.req.Seq
+			return nil
+		}
+
+		log.WithField("method", c.req.Method).Warn("Refusing request of an unprivileged client")
+		if err = c.write(response{Seq: c.req.Seq, Error: ErrAccessDenied.Error()}); err != nil {
+			return
+		}
+	}
+}
+
+func (c *serverCodec) ReadRequestBody(v interface{}) (err error) {
+	if v == nil {
+		return nil
+	}
+	if len(c.req.Params) > 0 {
+		if err = json.Unmarshal(c.req.Params, v); err != nil {
+			return
+		}
+	}
+	if r, ok := v.(fromPeer); ok {
+		r.setPeer(c.peer)
+	}
+	return nil
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, v interface{}) (err error) {
+	resp := response{Seq: r.Seq, Error: r.Error}
+	if r.Error == "" {
+		if resp.Result, err = json.Marshal(v); err != nil {
+			resp = response{Seq: r.Seq, Error: err.Error()}
+		}
+	}
+	return c.write(resp)
+}
+
+// clientCodec sends requests to the server on the other end of a control socket connection
+type clientCodec struct {
+	*conn
+	resp response
+}
+
+func newClientCodec(rwc io.ReadWriteCloser) *clientCodec {
+	return &clientCodec{conn: newConn(rwc)}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, v interface{}) (err error) {
+	params, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	return c.write(request{Method: r.ServiceMethod, Seq: r.Seq, Params: params})
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) (err error) {
+	c.resp = response{}
+	if err = c.read(&c.resp); err != nil {
+		return
+	}
+
+	r.Seq = c.resp.Seq
+	r.Error = c.resp.Error
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(v interface{}) error {
+	if v == nil || len(c.resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(c.resp.Result, v)
+}
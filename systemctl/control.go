@@ -0,0 +1,257 @@
+package systemctl
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var ErrAccessDenied = errors.New("Access denied")
+var ErrUnknownPeer = errors.New("Peer process unknown")
+
+// readOnly is the set of the methods, which do not mutate the state of the system,
+// hence may be called by any client
+var readOnly = map[string]bool{
+	"Server.ListJobs":         true,
+	"Server.GetJob":           true,
+	"Server.ListFailed":       true,
+	"Server.ListInhibitors":   true,
+	"Server.ListUnitFiles":    true,
+	"Server.IsActive":         true,
+	"Server.IsEnabled":        true,
+	"Server.Subscribe":        true,
+	"Server.Unsubscribe":      true,
+	"Server.Events":           true,
+	"Server.BootTime":         true,
+	"Server.Blame":            true,
+	"Server.CriticalChain":    true,
+	"Server.Dot":              true,
+	"Server.Security":         true,
+	"Server.ListDependencies": true,
+	"Server.Show":             true,
+	"Server.Cat":              true,
+	"Server.Override":         true,
+	"Server.Status":           true,
+	"Server.StatusAll":        true,
+	"Server.JournalUsage":     true,
+}
+
+// journalReads is the set of the methods reading the records of the journal, which may contain sensitive data,
+// hence may only be called by the clients allowed to read the journal
+var journalReads = map[string]bool{
+	"Server.Logs": true,
+}
+
+// access is the level of access of a client to the control socket
+type access int
+
+const (
+	// Only the read-only methods may be called
+	readAccess access = iota
+
+	// The journal may be read as well
+	journalAccess
+
+	// Any method may be called
+	mutateAccess
+)
+
+// allows reports whether the method may be called with the level of access
+func (a access) allows(method string) bool {
+	switch {
+	case a >= mutateAccess, readOnly[method]:
+		return true
+	case journalReads[method]:
+		return a >= journalAccess
+	}
+	return false
+}
+
+// Authorizer decides, whether a user may mutate the state of the system or read the journal.
+// Only root and the members of the group specified or the user specified are allowed to
+type Authorizer struct {
+	// Group allowed to mutate the state, -1 if only root is
+	gid int
+
+	// User allowed to mutate the state, -1 if only root is
+	uid int
+
+	// Group allowed to read the journal besides the users allowed to mutate the state, -1 if none is
+	journalGid int
+}
+
+// NewAuthorizer returns an Authorizer allowing the members of group, specified by name or ID, to mutate the state.
+// Only root is allowed to, if group is empty
+func NewAuthorizer(group string) (a *Authorizer, err error) {
+	a = &Authorizer{gid: -1, uid: -1, journalGid: -1}
+	if group != "" {
+		if a.gid, err = lookupGroup(group); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// NewUserAuthorizer returns an Authorizer allowing the user with uid and root to mutate the state,
+// as the manager of the user does
+func NewUserAuthorizer(uid int) *Authorizer {
+	return &Authorizer{gid: -1, uid: uid, journalGid: -1}
+}
+
+// SetJournalGroup allows the members of group, specified by name or ID, to read the journal
+func (a *Authorizer) SetJournalGroup(group string) (err error) {
+	gid, err := lookupGroup(group)
+	if err != nil {
+		return
+	}
+	a.journalGid = gid
+	return nil
+}
+
+// lookupGroup returns the ID of the group specified by name or ID
+func lookupGroup(group string) (gid int, err error) {
+	if gid, err = strconv.Atoi(group); err == nil {
+		return
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// Control serves the requests received on the control socket.
+// Only the clients authorized may call the methods mutating the state of the system
+type Control struct {
+	*Authorizer
+
+	rpc *rpc.Server
+}
+
+// NewControl returns a Control serving the requests using sv, authorizing the clients using a
+func NewControl(sv *Server, a *Authorizer) (c *Control, err error) {
+	c = &Control{Authorizer: a, rpc: rpc.NewServer()}
+	if err = c.rpc.RegisterName("Server", sv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Listen serves requests on the socket created at path until an error occurs.
+// A socket left by a previous instance is replaced
+func (c *Control) Listen(path string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return
+	}
+	defer l.Close()
+
+	// Anyone may query the state, the peer credentials are checked per request
+	if err = os.Chmod(path, 0666); err != nil {
+		return
+	}
+
+	log.Infof("Listening on %s", path)
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return err
+		}
+		go c.Serve(conn)
+	}
+}
+
+// peer is the process on the other end of a connection to the control socket
+type peer struct {
+	uid, gid, pid int
+}
+
+// Serve serves the requests received on conn, the peer of which is authorized using its credentials
+func (c *Control) Serve(conn *net.UnixConn) {
+	p, err := peerCred(conn)
+	if err != nil {
+		log.Warnf("Error getting peer credentials: %s", err)
+		p = &peer{uid: -1, gid: -1}
+	}
+
+	a := readAccess
+	switch {
+	case err != nil:
+	case c.Authorized(p.uid, p.gid):
+		a = mutateAccess
+	case c.ReadsJournal(p.uid, p.gid):
+		a = journalAccess
+	}
+	log.WithFields(log.Fields{
+		"uid":     p.uid,
+		"gid":     p.gid,
+		"pid":     p.pid,
+		"mutate":  a >= mutateAccess,
+		"journal": a >= journalAccess,
+	}).Debugf("Client connected")
+
+	c.rpc.ServeCodec(newServerCodec(conn, p, a))
+}
+
+// Authorized returns whether the user uid with primary group gid may mutate the state.
+// gid is -1, if not known
+func (a *Authorizer) Authorized(uid, gid int) bool {
+	if uid == 0 || uid == a.uid {
+		return true
+	}
+	return member(uid, gid, a.gid)
+}
+
+// ReadsJournal returns whether the user uid with primary group gid may read the journal.
+// gid is -1, if not known
+func (a *Authorizer) ReadsJournal(uid, gid int) bool {
+	return a.Authorized(uid, gid) || member(uid, gid, a.journalGid)
+}
+
+// member returns whether the user uid with primary group gid is a member of the group, which is -1 if none
+func member(uid, gid, group int) bool {
+	if group < 0 {
+		return false
+	}
+	if gid == group {
+		return true
+	}
+
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return false
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, g := range groups {
+		if g == strconv.Itoa(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dial connects to the control socket at path
+func Dial(path string) (client *rpc.Client, err error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClientWithCodec(newClientCodec(conn)), nil
+}
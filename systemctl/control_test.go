@@ -0,0 +1,130 @@
+package systemctl
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControl(t *testing.T) {
+	path, err := ioutil.TempDir("", "control-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target"), []byte(`[Unit]
+Description=A`), 0666), "ioutil.WriteFile")
+
+	sys := system.New()
+	sys.SetPaths(path)
+
+	auth, err := NewAuthorizer("")
+	require.NoError(t, err, "NewAuthorizer")
+
+	control, err := NewControl(NewServer(sys), auth)
+	require.NoError(t, err, "NewControl")
+
+	socket := filepath.Join(path, "run", "private")
+	go control.Listen(socket)
+
+	var client *rpc.Client
+	for timeout := time.After(5 * time.Second); client == nil; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("control socket not listening")
+		default:
+		}
+		client, _ = Dial(socket)
+	}
+	defer client.Close()
+
+	resp := &Response{}
+	require.NoError(t, client.Call("Server.Start", JobRequest{Names: []string{"a.target"}, Mode: system.ReplaceMode}, resp), "Server.Start")
+
+	for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		require.NoError(t, client.Call("Server.IsActive", []string{"a.target", "b.target"}, resp), "Server.IsActive")
+		if states := resp.Yield.([]unit.Activation); states[0] == unit.Active {
+			assert.Equal(t, unit.Inactive, states[1])
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("a.target not started")
+		default:
+		}
+	}
+
+	require.NoError(t, client.Call("Server.Status", []string{"a.target"}, resp), "Server.Status")
+	assert.Equal(t, "A", resp.Yield.(map[string]unit.Status)["a.target"].Description)
+
+	require.NoError(t, client.Call("Server.IsEnabled", []string{"a.target", "b.target"}, resp), "Server.IsEnabled")
+	assert.Equal(t, []string{"static", "not-found"}, resp.Yield)
+
+	require.NoError(t, client.Call("Server.SetEnvironment", []string{"SYSTEMGO_TEST_FOO=foo"}, resp), "Server.SetEnvironment")
+	defer os.Unsetenv("SYSTEMGO_TEST_FOO")
+	require.NoError(t, client.Call("Server.Environment", []string{}, resp), "Server.Environment")
+	assert.Contains(t, resp.Yield, "SYSTEMGO_TEST_FOO=foo")
+
+	require.NoError(t, client.Call("Server.Inhibit", InhibitRequest{What: "shutdown", Who: "test", Why: "testing", Mode: system.InhibitBlock}, resp), "Server.Inhibit")
+	id := resp.Yield.(uint64)
+	require.NoError(t, client.Call("Server.ListInhibitors", []string{}, resp), "Server.ListInhibitors")
+	if assert.Len(t, resp.Yield, 1) {
+		assert.Equal(t, os.Getpid(), resp.Yield.([]system.Inhibitor)[0].PID, "holder is the peer process")
+	}
+	require.NoError(t, client.Call("Server.Uninhibit", id, resp), "Server.Uninhibit")
+
+	err = client.Call("Server.Frobnicate", []string{}, resp)
+	assert.Error(t, err, "unknown method")
+}
+
+func TestAccessDenied(t *testing.T) {
+	sys := system.New()
+
+	auth, err := NewAuthorizer("")
+	require.NoError(t, err, "NewAuthorizer")
+
+	control, err := NewControl(NewServer(sys), auth)
+	require.NoError(t, err, "NewControl")
+
+	server, conn := net.Pipe()
+	go control.rpc.ServeCodec(newServerCodec(server, &peer{uid: 1000, gid: 1000, pid: os.Getpid()}, readAccess))
+
+	client := rpc.NewClientWithCodec(newClientCodec(conn))
+	defer client.Close()
+
+	resp := &Response{}
+	assert.EqualError(t, client.Call("Server.Stop", JobRequest{Names: []string{"a.target"}}, resp), ErrAccessDenied.Error(), "mutating request")
+	assert.NoError(t, client.Call("Server.ListJobs", []string{}, resp), "read-only request")
+	assert.Empty(t, resp.Yield)
+	assert.EqualError(t, client.Call("Server.Logs", journal.Query{}, resp), ErrAccessDenied.Error(), "journal read")
+
+	assert.True(t, journalAccess.allows("Server.Logs"), "journal read")
+	assert.False(t, journalAccess.allows("Server.Stop"), "mutating request with journal access")
+
+	assert.False(t, control.Authorized(1000, 1000), "unprivileged user")
+	assert.True(t, control.Authorized(0, 0), "root")
+
+	control.gid = 1000
+	assert.True(t, control.Authorized(1000, 1000), "member of the group")
+
+	assert.False(t, control.ReadsJournal(1001, 1001), "unprivileged user")
+	assert.True(t, control.ReadsJournal(1000, 1000), "user allowed to mutate")
+	require.NoError(t, control.SetJournalGroup("1001"), "SetJournalGroup")
+	assert.True(t, control.ReadsJournal(1001, 1001), "member of the journal group")
+	assert.False(t, control.Authorized(1001, 1001), "member of the journal group")
+
+	user := NewUserAuthorizer(1000)
+	assert.True(t, user.Authorized(1000, 1000), "user running the manager")
+	assert.True(t, user.Authorized(0, 0), "root")
+	assert.False(t, user.Authorized(1001, 1000), "other user")
+}
@@ -1,20 +1,70 @@
 package systemctl
 
 import (
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/plasma-umass/systemgo/journal"
 	"github.com/plasma-umass/systemgo/system"
 	"github.com/plasma-umass/systemgo/unit"
 )
 
 type Daemon interface {
 	Start(...string) error
+	StartWith(system.JobMode, ...string) error
+	ManualStart(system.JobMode, ...string) error
 	Stop(...string) error
+	StopWith(system.JobMode, ...string) error
+	ManualStop(system.JobMode, ...string) error
 	Isolate(...string) error
 	Restart(...string) error
+	RestartWith(system.JobMode, ...string) error
+	ManualRestart(system.JobMode, ...string) error
 	Reload(...string) error
+	CancelJob(uint64) error
+	ResetFailed(...string) error
+	ListFailed() []system.Failure
+	DaemonReload()
+	DaemonReexec() error
+	SetEnvironment(...string) error
+	UnsetEnvironment(...string) error
+	Environment() []string
+	Poweroff() error
+	Reboot() error
+	Halt() error
+	Inhibit(string, string, string, string, int) (uint64, error)
+	Uninhibit(uint64) error
+	ListInhibitors() []system.Inhibitor
+	ListJobs() []system.JobInfo
+	GetJob(uint64) (system.JobInfo, error)
 	Enable(...string) error
 	Disable(...string) error
+	Mask(...string) error
+	Unmask(...string) error
+	Preset(...string) error
+	PresetAll() error
+	ListUnitFiles() []system.UnitFile
+	BootTime() (time.Duration, error)
+	Blame() []system.UnitTime
+	CriticalChain(string) ([]system.UnitTime, error)
+	Dot(io.Writer, ...string) error
+	Security(...string) ([]system.SecurityReport, error)
+	ListDependencies(string, bool) (system.Dependency, error)
+	Show(string) ([]unit.Property, error)
+	SetProperty(string, bool, ...unit.Property) error
+	Kill(string, string, syscall.Signal) error
+	Cat(string) ([]system.DefinitionFile, error)
+	Override(string) (system.DefinitionFile, error)
+	Edit(string, string) error
+	StartTransient(system.JobMode, ...system.TransientUnit) error
+	Logs(journal.Query) (journal.Result, error)
+	JournalUsage() (journal.Usage, error)
+	Metrics() system.Metrics
 
 	Units() []*system.Unit
+	Unit(string) (*system.Unit, error)
+	Subscribe() (<-chan system.Event, func())
 	Status() (system.Status, error)
 	StatusOf(string) (unit.Status, error)
 	IsEnabled(string) (unit.Enable, error)
@@ -0,0 +1,135 @@
+package systemctl
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Activation states the per-unit state metric is exposed for, as the systemd collector of node_exporter does
+var metricStates = []unit.Activation{unit.Active, unit.Activating, unit.Deactivating, unit.Inactive, unit.Failed, unit.Reloading}
+
+// Exporter serves the metrics of the manager in the Prometheus text exposition format on GET /metrics
+type Exporter struct {
+	sys Daemon
+}
+
+// NewExporter returns an Exporter serving the metrics of sys
+func NewExporter(sys Daemon) *Exporter {
+	return &Exporter{sys}
+}
+
+// Listen serves the metrics on addr until an error occurs.
+// addr is either a path to a Unix socket, which is replaced, if it exists, or a TCP address
+func (e *Exporter) Listen(addr string) (err error) {
+	l, err := listenHTTP(addr)
+	if err != nil {
+		return
+	}
+	defer l.Close()
+
+	log.Infof("Serving the metrics on %s", addr)
+	return (&http.Server{Handler: e}).Serve(l)
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	bw := bufio.NewWriter(w)
+	e.write(bw)
+	if err := bw.Flush(); err != nil {
+		log.Errorf("Error writing metrics: %s", err)
+	}
+}
+
+// write writes the metrics to w
+func (e *Exporter) write(w *bufio.Writer) {
+	m := e.sys.Metrics()
+
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("systemgo_start_time_seconds", "gauge", "Time the manager started since the epoch.")
+	fmt.Fprintf(w, "systemgo_start_time_seconds %d\n", m.Since.Unix())
+
+	byState := map[string]int{}
+	failed := 0
+	for _, u := range m.Units {
+		byState[strings.ToLower(u.Active.String())]++
+		if u.Failed {
+			failed++
+		}
+	}
+	metric("systemgo_units", "gauge", "Number of the units loaded by active state.")
+	for _, st := range metricStates {
+		name := strings.ToLower(st.String())
+		fmt.Fprintf(w, "systemgo_units{state=%q} %d\n", name, byState[name])
+	}
+	metric("systemgo_units_failed", "gauge", "Number of the units failed, which were not reset.")
+	fmt.Fprintf(w, "systemgo_units_failed %d\n", failed)
+
+	metric("systemgo_jobs_queued", "gauge", "Number of the jobs queued.")
+	fmt.Fprintf(w, "systemgo_jobs_queued %d\n", m.JobsQueued)
+
+	outcomes := make([]string, 0, len(m.JobsFinished))
+	counts := map[string]uint64{}
+	for outcome, n := range m.JobsFinished {
+		key := fmt.Sprintf("type=%s,result=%s", label(outcome.Type), label(outcome.Result))
+		outcomes = append(outcomes, key)
+		counts[key] = n
+	}
+	sort.Strings(outcomes)
+	metric("systemgo_jobs_finished_total", "counter", "Number of the jobs finished by type and result.")
+	for _, key := range outcomes {
+		fmt.Fprintf(w, "systemgo_jobs_finished_total{%s} %d\n", key, counts[key])
+	}
+
+	metric("systemgo_unit_state", "gauge", "Whether the unit is in the active state labelled.")
+	for _, u := range m.Units {
+		for _, st := range metricStates {
+			v := 0
+			if u.Active == st {
+				v = 1
+			}
+			fmt.Fprintf(w, "systemgo_unit_state{unit=%s,state=%q} %d\n", label(u.Name), strings.ToLower(st.String()), v)
+		}
+	}
+
+	metric("systemgo_unit_restarts_total", "counter", "Number of the automatic restarts of the unit.")
+	for _, u := range m.Units {
+		fmt.Fprintf(w, "systemgo_unit_restarts_total{unit=%s} %d\n", label(u.Name), u.Restarts)
+	}
+
+	metric("systemgo_unit_memory_bytes", "gauge", "Memory used by the processes of the unit.")
+	for _, u := range m.Units {
+		if u.Memory > 0 {
+			fmt.Fprintf(w, "systemgo_unit_memory_bytes{unit=%s} %d\n", label(u.Name), u.Memory)
+		}
+	}
+
+	metric("systemgo_unit_cpu_seconds_total", "counter", "CPU time used by the processes of the unit.")
+	for _, u := range m.Units {
+		if u.CPU > 0 {
+			fmt.Fprintf(w, "systemgo_unit_cpu_seconds_total{unit=%s} %g\n", label(u.Name), u.CPU.Seconds())
+		}
+	}
+}
+
+// label returns s quoted as a label value of the exposition format
+func label(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
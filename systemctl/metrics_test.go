@@ -0,0 +1,53 @@
+package systemctl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	path, err := ioutil.TempDir("", "metrics-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target"), []byte("[Unit]\nDescription=A"), 0666))
+
+	sys := system.New()
+	sys.SetPaths(path)
+	require.NoError(t, sys.Start("a.target"), "sys.Start")
+	for timeout := time.After(5 * time.Second); sys.Metrics().JobsFinished[system.JobOutcome{Type: "start", Result: "done"}] == 0; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("a.target not started")
+		default:
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewExporter(sys).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE systemgo_units gauge\n")
+	assert.Contains(t, body, `systemgo_units{state="active"} 1`)
+	assert.Contains(t, body, "systemgo_units_failed 0\n")
+	assert.Contains(t, body, `systemgo_jobs_finished_total{type="start",result="done"} 1`)
+	assert.Contains(t, body, `systemgo_unit_state{unit="a.target",state="active"} 1`)
+	assert.Contains(t, body, `systemgo_unit_state{unit="a.target",state="failed"} 0`)
+	assert.Contains(t, body, `systemgo_unit_restarts_total{unit="a.target"} 0`)
+
+	rec = httptest.NewRecorder()
+	NewExporter(sys).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, `"a\"b\\c\n"`, label("a\"b\\c\n"))
+}
@@ -0,0 +1,203 @@
+package systemctl
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Time Server.Events waits for an event, before replying with none
+const EVENTS_TIMEOUT = 30 * time.Second
+
+// Time a subscription is kept without being polled by Server.Events
+const SUBSCRIPTION_TIMEOUT = 2 * EVENTS_TIMEOUT
+
+var ErrNoSubscription = errors.New("No such subscription")
+
+// Notification is an event published by the system as delivered to the clients
+type Notification struct {
+	Type system.EventType
+	Time time.Time
+
+	// Name of the unit and its states, once the event occurred. Empty for BootFinished
+	Unit   string
+	Active unit.Activation
+	Sub    string
+
+	// Job finished and the error it failed with, JobFinished only
+	Job   *system.JobInfo
+	Error string
+}
+
+// subscription buffers the events published for a client
+type subscription struct {
+	events <-chan system.Event
+	cancel func()
+
+	// Units the client is interested in, all if empty
+	units map[string]bool
+
+	// Client the subscription belongs to
+	owner *peer
+
+	// Cancels the subscription, unless polled in time
+	expiry *time.Timer
+}
+
+// subscriptions are the subscriptions of the clients of a Server
+type subscriptions struct {
+	byID map[uint64]*subscription
+	last uint64
+
+	mutex sync.Mutex
+}
+
+// SubscribeRequest requests a subscription to the events of the units in Names, all units if empty
+type SubscribeRequest struct {
+	Names []string
+
+	caller
+}
+
+// SubscriptionRequest specifies the subscription ID of the client
+type SubscriptionRequest struct {
+	ID uint64
+
+	caller
+}
+
+// Subscribe subscribes the client to the events of the units requested.
+// The ID of the subscription, which is to be polled by Events on the same connection, is yielded
+func (sv *Server) Subscribe(req SubscribeRequest, resp *Response) (err error) {
+	events, cancel := sv.sys.Subscribe()
+
+	sub := &subscription{
+		events: events,
+		cancel: cancel,
+		units:  map[string]bool{},
+		owner:  req.peer,
+	}
+	for _, name := range req.Names {
+		sub.units[name] = true
+	}
+
+	sv.subscriptions.mutex.Lock()
+	defer sv.subscriptions.mutex.Unlock()
+
+	if sv.subscriptions.byID == nil {
+		sv.subscriptions.byID = map[uint64]*subscription{}
+	}
+	sv.subscriptions.last++
+	id := sv.subscriptions.last
+	sv.subscriptions.byID[id] = sub
+
+	sub.expiry = time.AfterFunc(SUBSCRIPTION_TIMEOUT, func() {
+		sv.unsubscribe(id, sub.owner)
+	})
+
+	*resp = *newResponse()
+	resp.Yield = id
+	return nil
+}
+
+// Unsubscribe cancels the subscription of the client
+func (sv *Server) Unsubscribe(req SubscriptionRequest, resp *Response) (err error) {
+	if !sv.unsubscribe(req.ID, req.peer) {
+		return ErrNoSubscription
+	}
+	return nil
+}
+
+// unsubscribe cancels the subscription id of owner and reports whether it existed
+func (sv *Server) unsubscribe(id uint64, owner *peer) bool {
+	sv.subscriptions.mutex.Lock()
+	defer sv.subscriptions.mutex.Unlock()
+
+	sub, ok := sv.subscriptions.byID[id]
+	if !ok || sub.owner != owner {
+		return false
+	}
+
+	delete(sv.subscriptions.byID, id)
+	sub.expiry.Stop()
+	sub.cancel()
+	return true
+}
+
+// Events yields the notifications of the events received by the subscription of the client since polled last.
+// If there are none, it waits for EVENTS_TIMEOUT for one to be published
+func (sv *Server) Events(req SubscriptionRequest, resp *Response) (err error) {
+	sv.subscriptions.mutex.Lock()
+	sub, ok := sv.subscriptions.byID[req.ID]
+	if ok = ok && sub.owner == req.peer; ok {
+		sub.expiry.Reset(SUBSCRIPTION_TIMEOUT)
+	}
+	sv.subscriptions.mutex.Unlock()
+
+	if !ok {
+		return ErrNoSubscription
+	}
+
+	notifications := []Notification{}
+
+	timeout := time.NewTimer(EVENTS_TIMEOUT)
+	defer timeout.Stop()
+
+wait:
+	for len(notifications) == 0 {
+		select {
+		case ev, ok := <-sub.events:
+			if !ok {
+				break wait
+			}
+			if sub.wants(ev) {
+				notifications = append(notifications, sv.notification(ev))
+			}
+		case <-timeout.C:
+			break wait
+		}
+
+		// Receive the rest of the events buffered without waiting
+		for drained := false; !drained; {
+			select {
+			case ev, ok := <-sub.events:
+				if ok && sub.wants(ev) {
+					notifications = append(notifications, sv.notification(ev))
+				}
+				drained = !ok
+			default:
+				drained = true
+			}
+		}
+	}
+
+	*resp = *newResponse()
+	resp.Yield = notifications
+	return nil
+}
+
+// wants reports whether the client is interested in ev
+func (sub *subscription) wants(ev system.Event) bool {
+	return len(sub.units) == 0 || ev.Unit == "" || sub.units[ev.Unit]
+}
+
+// notification returns ev as delivered to the clients
+func (sv *Server) notification(ev system.Event) (n Notification) {
+	n = Notification{
+		Type:   ev.Type,
+		Time:   ev.Time,
+		Unit:   ev.Unit,
+		Active: ev.Active,
+		Job:    ev.Job,
+	}
+	if ev.Err != nil {
+		n.Error = ev.Err.Error()
+	}
+	if u, err := sv.sys.Unit(ev.Unit); err == nil {
+		n.Sub = u.Sub()
+	}
+	return
+}
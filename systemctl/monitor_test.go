@@ -0,0 +1,70 @@
+package systemctl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	path, err := ioutil.TempDir("", "monitor-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	for _, name := range []string{"a.target", "b.target"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(`[Unit]
+DefaultDependencies=no`), 0666), "ioutil.WriteFile")
+	}
+
+	sys := system.New()
+	sys.SetPaths(path)
+	sv := NewServer(sys)
+
+	resp := &Response{}
+	require.NoError(t, sv.Subscribe(SubscribeRequest{Names: []string{"a.target"}}, resp), "sv.Subscribe")
+	id := resp.Yield.(uint64)
+
+	require.NoError(t, sys.Start("b.target", "a.target"), "sys.Start")
+
+	var notifications []Notification
+	timeout := time.After(5 * time.Second)
+	for !activated(notifications) {
+		select {
+		case <-timeout:
+			t.Fatalf("a.target activation not received: %v", notifications)
+		default:
+		}
+
+		require.NoError(t, sv.Events(SubscriptionRequest{ID: id}, resp), "sv.Events")
+		notifications = append(notifications, resp.Yield.([]Notification)...)
+	}
+
+	for _, n := range notifications {
+		assert.Equal(t, "a.target", n.Unit, "events of the units subscribed only")
+	}
+
+	other := SubscriptionRequest{ID: id, caller: caller{&peer{uid: 1000, gid: 1000}}}
+	assert.Equal(t, ErrNoSubscription, sv.Events(other, resp), "subscription of another client")
+	assert.Equal(t, ErrNoSubscription, sv.Unsubscribe(other, resp), "subscription of another client")
+
+	require.NoError(t, sv.Unsubscribe(SubscriptionRequest{ID: id}, resp), "sv.Unsubscribe")
+	assert.Equal(t, ErrNoSubscription, sv.Events(SubscriptionRequest{ID: id}, resp))
+	assert.Equal(t, ErrNoSubscription, sv.Unsubscribe(SubscriptionRequest{ID: id}, resp))
+}
+
+// activated reports whether notifications contain the activation of a unit
+func activated(notifications []Notification) bool {
+	for _, n := range notifications {
+		if n.Type == system.UnitActiveChanged && n.Active == unit.Active {
+			return n.Sub != ""
+		}
+	}
+	return false
+}
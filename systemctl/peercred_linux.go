@@ -0,0 +1,25 @@
+package systemctl
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCred returns the credentials of the process on the other end of conn
+func peerCred(conn *net.UnixConn) (p *peer, err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *syscall.Ucred
+	if cerr := raw.Control(func(fd uintptr) {
+		cred, err = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &peer{uid: int(cred.Uid), gid: int(cred.Gid), pid: int(cred.Pid)}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package systemctl
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCred fails, the peer credentials are only available on Linux, hence the clients may only query the state
+func peerCred(conn *net.UnixConn) (p *peer, err error) {
+	return nil, errors.New("Peer credentials are not supported")
+}
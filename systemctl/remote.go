@@ -0,0 +1,88 @@
+package systemctl
+
+import (
+	"io"
+	"net"
+	"net/rpc"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Command used to reach remote hosts, OpenSSH client compatible
+var SSH_COMMAND = "ssh"
+
+// Command run on the remote host, which connects its standard input and output to the control socket there
+var BRIDGE_COMMAND = []string{"systemctl", "stdio-bridge"}
+
+// DialSSH connects to the control socket on host, specified as "[user@]host[:port]", tunnelling the control
+// protocol over SSH. The requests are authorized on the remote host as the ones of the user logged in
+func DialSSH(host string) (client *rpc.Client, err error) {
+	cmd := exec.Command(SSH_COMMAND, append(sshArgs(host), BRIDGE_COMMAND...)...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	log.WithField("cmd", cmd.Args).Debugf("Starting tunnel")
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	return rpc.NewClientWithCodec(newClientCodec(&pipeConn{stdout, stdin, cmd})), nil
+}
+
+// sshArgs returns the arguments of SSH_COMMAND connecting to host
+func sshArgs(host string) (args []string) {
+	// No X11 forwarding and no terminal, the frames are binary
+	args = []string{"-x", "-T"}
+
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		if _, err := strconv.ParseUint(host[i+1:], 10, 16); err == nil {
+			args = append(args, "-p", host[i+1:])
+			host = host[:i]
+		}
+	}
+	return append(args, "--", host)
+}
+
+// pipeConn is the connection to the remote end of a tunnel started as cmd
+type pipeConn struct {
+	io.ReadCloser
+	io.WriteCloser
+
+	cmd *exec.Cmd
+}
+
+// Close closes the pipes and waits for the tunnel to exit
+func (c *pipeConn) Close() error {
+	c.WriteCloser.Close()
+	c.ReadCloser.Close()
+	return c.cmd.Wait()
+}
+
+// Bridge connects r and w to the control socket at path, until either end of the connection is closed
+func Bridge(path string, r io.Reader, w io.Writer) (err error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	done := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(conn, r)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(w, conn)
+		done <- err
+	}()
+	return <-done
+}
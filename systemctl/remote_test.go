@@ -0,0 +1,63 @@
+package systemctl
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHArgs(t *testing.T) {
+	for host, expected := range map[string][]string{
+		"device":             {"-x", "-T", "--", "device"},
+		"root@device":        {"-x", "-T", "--", "root@device"},
+		"root@10.0.0.2:2222": {"-x", "-T", "-p", "2222", "--", "root@10.0.0.2"},
+		"device:notaport":    {"-x", "-T", "--", "device:notaport"},
+	} {
+		assert.Equal(t, expected, sshArgs(host), host)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	path, err := ioutil.TempDir("", "bridge-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	auth, err := NewAuthorizer("")
+	require.NoError(t, err, "NewAuthorizer")
+
+	control, err := NewControl(NewServer(system.New()), auth)
+	require.NoError(t, err, "NewControl")
+
+	socket := filepath.Join(path, "private")
+	go control.Listen(socket)
+
+	for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatal("control socket not listening")
+		default:
+		}
+	}
+
+	local, remote := net.Pipe()
+	go Bridge(socket, remote, remote)
+
+	client := rpc.NewClientWithCodec(newClientCodec(local))
+	defer client.Close()
+
+	resp := &Response{}
+	require.NoError(t, client.Call("Server.IsActive", []string{"a.target"}, resp), "Server.IsActive over the bridge")
+	assert.Equal(t, []unit.Activation{unit.Inactive}, resp.Yield)
+}
@@ -0,0 +1,41 @@
+package systemctl
+
+import (
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+
+	"github.com/plasma-umass/systemgo/system"
+)
+
+// DialRoot returns a client operating on the unit files of the image mounted at root without a running manager,
+// as image building pipelines need. The unit files and the presets are searched for in paths and presetPaths
+// under root, the requests are served in process
+func DialRoot(root string, paths, presetPaths []string) (client *rpc.Client, err error) {
+	sys := system.New()
+	sys.SetRoot(root)
+	sys.SetPaths(rooted(root, paths)...)
+	sys.SetPresetPaths(rooted(root, presetPaths)...)
+
+	c, err := NewControl(NewServer(sys), nil)
+	if err != nil {
+		return
+	}
+
+	// The client is the process itself
+	p := &peer{uid: os.Getuid(), gid: os.Getgid(), pid: os.Getpid()}
+
+	server, conn := net.Pipe()
+	go c.rpc.ServeCodec(newServerCodec(server, p, mutateAccess))
+	return rpc.NewClientWithCodec(newClientCodec(conn)), nil
+}
+
+// rooted returns paths joined to root
+func rooted(root string, paths []string) (joined []string) {
+	joined = make([]string, len(paths))
+	for i, path := range paths {
+		joined[i] = filepath.Join(root, path)
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package systemctl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "root-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(root)
+
+	lib := filepath.Join(root, "usr", "lib", "systemgo", "system")
+	require.NoError(t, os.MkdirAll(lib, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "foo.service"), []byte(`[Service]
+ExecStart=/bin/foo
+
+[Install]
+WantedBy=multi-user.target`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lib, "baz.service"), []byte(`[Service]
+ExecStart=/bin/baz
+
+[Install]
+Alias=qux.service`), 0644))
+
+	client, err := DialRoot(root, []string{"/etc/systemgo/system", "/usr/lib/systemgo/system"}, nil)
+	require.NoError(t, err, "DialRoot")
+	defer client.Close()
+
+	require.NoError(t, client.Call("Server.Enable", []string{"foo.service"}, nil), "Enable")
+
+	target, err := os.Readlink(filepath.Join(root, "etc", "systemgo", "system", "multi-user.target.wants", "foo.service"))
+	require.NoError(t, err, "foo.service not enabled")
+	assert.Equal(t, "/usr/lib/systemgo/system/foo.service", target, "link points outside of the image")
+
+	var resp Response
+	require.NoError(t, client.Call("Server.IsEnabled", []string{"foo.service"}, &resp), "IsEnabled")
+	assert.Equal(t, []string{"enabled"}, resp.Yield)
+
+	require.NoError(t, client.Call("Server.Enable", []string{"baz.service"}, nil), "Enable")
+
+	// The alias links to the path inside the image, as seen by the next invocation
+	client, err = DialRoot(root, []string{"/etc/systemgo/system", "/usr/lib/systemgo/system"}, nil)
+	require.NoError(t, err, "DialRoot")
+	defer client.Close()
+
+	resp = Response{}
+	require.NoError(t, client.Call("Server.IsEnabled", []string{"qux.service"}, &resp), "IsEnabled by alias")
+	assert.Equal(t, []string{"enabled"}, resp.Yield)
+	require.NoError(t, client.Call("Server.Disable", []string{"qux.service"}, nil), "Disable by alias")
+	_, err = os.Lstat(filepath.Join(root, "etc", "systemgo", "system", "qux.service"))
+	assert.True(t, os.IsNotExist(err), "alias not removed")
+
+	require.NoError(t, client.Call("Server.Mask", []string{"bar.service"}, nil), "Mask")
+	target, err = os.Readlink(filepath.Join(root, "etc", "systemgo", "system", "bar.service"))
+	require.NoError(t, err, "bar.service not masked")
+	assert.Equal(t, os.DevNull, target)
+}
@@ -1,9 +1,14 @@
 package systemctl
 
 import (
-	"encoding/gob"
+	"bytes"
 	"fmt"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/plasma-umass/systemgo/journal"
+	"github.com/plasma-umass/systemgo/system"
 	"github.com/plasma-umass/systemgo/unit"
 )
 
@@ -12,7 +17,27 @@ type Response struct {
 }
 
 func init() {
-	gob.Register(map[string]unit.Status{})
+	register(map[string]unit.Status{})
+	register([]system.JobInfo{})
+	register(system.JobInfo{})
+	register([]system.UnitTime{})
+	register([]system.Failure{})
+	register([]system.UnitFile{})
+	register([]unit.Activation{})
+	register([]string{})
+	register(uint64(0))
+	register([]system.DefinitionFile{})
+	register(system.DefinitionFile{})
+	register([]Notification{})
+	register(time.Duration(0))
+	register(system.Dependency{})
+	register(map[string][]unit.Property{})
+	register(map[string]fmt.Stringer{})
+	register("")
+	register(journal.Result{})
+	register(journal.Usage{})
+	register([]system.Inhibitor{})
+	register([]system.SecurityReport{})
 }
 
 func newResponse() (resp *Response) {
@@ -22,33 +47,137 @@ func newResponse() (resp *Response) {
 }
 
 func NewServer(sys Daemon) (sv *Server) {
-	return &Server{sys}
+	return &Server{sys: sys}
 }
 
 type Server struct {
 	sys Daemon
+
+	subscriptions subscriptions
+}
+
+// JobRequest requests jobs for units specified by Names to be enqueued in Mode
+type JobRequest struct {
+	Names []string
+	Mode  system.JobMode
 }
 
-func (sv *Server) Start(names []string, resp *Response) (err error) {
-	return sv.sys.Start(names...)
+func (sv *Server) Start(req JobRequest, resp *Response) (err error) {
+	return sv.sys.ManualStart(req.Mode, req.Names...)
 }
 
-func (sv *Server) Stop(names []string, resp *Response) (err error) {
-	return sv.sys.Stop(names...)
+func (sv *Server) Stop(req JobRequest, resp *Response) (err error) {
+	return sv.sys.ManualStop(req.Mode, req.Names...)
 }
 
-func (sv *Server) Restart(names []string, resp *Response) (err error) {
-	return sv.sys.Restart(names...)
+func (sv *Server) Restart(req JobRequest, resp *Response) (err error) {
+	return sv.sys.ManualRestart(req.Mode, req.Names...)
 }
 
 func (sv *Server) Isolate(names []string, resp *Response) (err error) {
-	return sv.sys.Isolate(names...)
+	return sv.sys.ManualStart(system.IsolateMode, names...)
 }
 
 func (sv *Server) Reload(names []string, resp *Response) (err error) {
 	return sv.sys.Reload(names...)
 }
 
+func (sv *Server) ListJobs(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.ListJobs()
+	return
+}
+
+func (sv *Server) GetJob(id uint64, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.GetJob(id)
+	return
+}
+
+func (sv *Server) CancelJob(ids []uint64, resp *Response) (err error) {
+	for _, id := range ids {
+		if err = sv.sys.CancelJob(id); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (sv *Server) ResetFailed(names []string, resp *Response) (err error) {
+	return sv.sys.ResetFailed(names...)
+}
+
+func (sv *Server) ListFailed(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.ListFailed()
+	return
+}
+
+func (sv *Server) DaemonReload(names []string, resp *Response) (err error) {
+	sv.sys.DaemonReload()
+	return nil
+}
+
+func (sv *Server) SetEnvironment(vars []string, resp *Response) (err error) {
+	return sv.sys.SetEnvironment(vars...)
+}
+
+func (sv *Server) UnsetEnvironment(vars []string, resp *Response) (err error) {
+	return sv.sys.UnsetEnvironment(vars...)
+}
+
+func (sv *Server) Environment(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.Environment()
+	return
+}
+
+// DaemonReexec replaces the daemon process, hence no reply is sent on success
+func (sv *Server) DaemonReexec(names []string, resp *Response) (err error) {
+	return sv.sys.DaemonReexec()
+}
+
+func (sv *Server) Poweroff(names []string, resp *Response) (err error) {
+	return sv.sys.Poweroff()
+}
+
+func (sv *Server) Reboot(names []string, resp *Response) (err error) {
+	return sv.sys.Reboot()
+}
+
+func (sv *Server) Halt(names []string, resp *Response) (err error) {
+	return sv.sys.Halt()
+}
+
+// InhibitRequest requests an inhibitor lock of Mode on the operations What, separated by colons.
+// The lock is held by the client process, as identified by the credentials of its connection
+type InhibitRequest struct {
+	What, Who, Why, Mode string
+
+	caller
+}
+
+// Inhibit yields the ID of the inhibitor lock taken
+func (sv *Server) Inhibit(req InhibitRequest, resp *Response) (err error) {
+	if req.peer == nil || req.peer.pid <= 0 {
+		return ErrUnknownPeer
+	}
+
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.Inhibit(req.What, req.Who, req.Why, req.Mode, req.peer.pid)
+	return
+}
+
+func (sv *Server) Uninhibit(id uint64, resp *Response) (err error) {
+	return sv.sys.Uninhibit(id)
+}
+
+func (sv *Server) ListInhibitors(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.ListInhibitors()
+	return
+}
+
 func (sv *Server) Enable(names []string, resp *Response) (err error) {
 	return sv.sys.Enable(names...)
 }
@@ -57,6 +186,223 @@ func (sv *Server) Disable(names []string, resp *Response) (err error) {
 	return sv.sys.Disable(names...)
 }
 
+func (sv *Server) Mask(names []string, resp *Response) (err error) {
+	return sv.sys.Mask(names...)
+}
+
+func (sv *Server) Unmask(names []string, resp *Response) (err error) {
+	return sv.sys.Unmask(names...)
+}
+
+func (sv *Server) Preset(names []string, resp *Response) (err error) {
+	return sv.sys.Preset(names...)
+}
+
+func (sv *Server) PresetAll(names []string, resp *Response) (err error) {
+	return sv.sys.PresetAll()
+}
+
+func (sv *Server) ListUnitFiles(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.ListUnitFiles()
+	return
+}
+
+// IsActive yields the activation states of the units in names in order.
+// The units, which can not be loaded, are reported inactive
+func (sv *Server) IsActive(names []string, resp *Response) (err error) {
+	states := make([]unit.Activation, len(names))
+	for i, name := range names {
+		if states[i], err = sv.sys.IsActive(name); err != nil {
+			states[i] = unit.Inactive
+		}
+	}
+
+	*resp = *newResponse()
+	resp.Yield = states
+	return nil
+}
+
+// IsEnabled yields the enable states of the units in names in order.
+// The units, which can not be loaded, are reported "masked" or "not-found"
+func (sv *Server) IsEnabled(names []string, resp *Response) (err error) {
+	states := make([]string, len(names))
+	for i, name := range names {
+		st, err := sv.sys.IsEnabled(name)
+		switch err {
+		case nil:
+			states[i] = strings.ToLower(st.String())
+		case system.ErrMasked:
+			states[i] = "masked"
+		case system.ErrNotFound:
+			states[i] = "not-found"
+		default:
+			states[i] = "bad"
+		}
+	}
+
+	*resp = *newResponse()
+	resp.Yield = states
+	return nil
+}
+
+func (sv *Server) BootTime(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.BootTime()
+	return
+}
+
+func (sv *Server) Blame(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield = sv.sys.Blame()
+	return
+}
+
+// CriticalChain yields the critical chain of the first unit in names or of the unit started at boot, if none is specified
+func (sv *Server) CriticalChain(names []string, resp *Response) (err error) {
+	name := ""
+	if len(names) > 0 {
+		name = names[0]
+	}
+
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.CriticalChain(name)
+	return
+}
+
+// Dot yields the dependency graph of the units in names or of all the units loaded, if none are specified
+func (sv *Server) Dot(names []string, resp *Response) (err error) {
+	var buf bytes.Buffer
+	if err = sv.sys.Dot(&buf, names...); err != nil {
+		return
+	}
+
+	*resp = *newResponse()
+	resp.Yield = buf.String()
+	return
+}
+
+// Security yields the security audits of the units in names or of all the services loaded, if none are specified
+func (sv *Server) Security(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.Security(names...)
+	return
+}
+
+// DependencyRequest requests the dependency tree of the unit Name, reversed if Reverse is true
+type DependencyRequest struct {
+	Name    string
+	Reverse bool
+}
+
+func (sv *Server) ListDependencies(req DependencyRequest, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.ListDependencies(req.Name, req.Reverse)
+	return
+}
+
+// Show yields the properties of the units in names
+func (sv *Server) Show(names []string, resp *Response) (err error) {
+	props := map[string][]unit.Property{}
+	for _, name := range names {
+		if props[name], err = sv.sys.Show(name); err != nil {
+			return
+		}
+	}
+
+	*resp = *newResponse()
+	resp.Yield = props
+	return
+}
+
+// PropertyRequest requests setting Properties of the unit Name, only until the manager exits if Runtime is true
+type PropertyRequest struct {
+	Name       string
+	Runtime    bool
+	Properties []unit.Property
+}
+
+func (sv *Server) SetProperty(req PropertyRequest, resp *Response) (err error) {
+	*resp = *newResponse()
+	return sv.sys.SetProperty(req.Name, req.Runtime, req.Properties...)
+}
+
+// TransientRequest requests defining Units and starting the first one using Mode
+type TransientRequest struct {
+	Mode  system.JobMode
+	Units []system.TransientUnit
+}
+
+func (sv *Server) StartTransient(req TransientRequest, resp *Response) (err error) {
+	*resp = *newResponse()
+	return sv.sys.StartTransient(req.Mode, req.Units...)
+}
+
+// KillRequest requests sending Signal to the processes of the units in Names selected by Who
+type KillRequest struct {
+	Names  []string
+	Who    string
+	Signal int
+}
+
+func (sv *Server) Kill(req KillRequest, resp *Response) (err error) {
+	*resp = *newResponse()
+	for _, name := range req.Names {
+		if err = sv.sys.Kill(name, req.Who, syscall.Signal(req.Signal)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Cat yields the files defining the units in names followed by their drop-ins
+func (sv *Server) Cat(names []string, resp *Response) (err error) {
+	var files []system.DefinitionFile
+	for _, name := range names {
+		var found []system.DefinitionFile
+		if found, err = sv.sys.Cat(name); err != nil {
+			return
+		}
+		files = append(files, found...)
+	}
+
+	*resp = *newResponse()
+	resp.Yield = files
+	return
+}
+
+// Override yields the drop-in of the unit name written by Edit
+func (sv *Server) Override(name string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.Override(name)
+	return
+}
+
+// EditRequest requests Content to be written to the drop-in overriding the definition of the unit Name
+type EditRequest struct {
+	Name    string
+	Content string
+}
+
+func (sv *Server) Edit(req EditRequest, resp *Response) (err error) {
+	*resp = *newResponse()
+	return sv.sys.Edit(req.Name, req.Content)
+}
+
+// Logs yields the records of the journal matching q
+func (sv *Server) Logs(q journal.Query, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.Logs(q)
+	return
+}
+
+// JournalUsage yields the disk usage of the journal
+func (sv *Server) JournalUsage(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+	resp.Yield, err = sv.sys.JournalUsage()
+	return
+}
+
 func (sv *Server) Status(names []string, resp *Response) (err error) {
 	*resp = *newResponse()
 
@@ -0,0 +1,212 @@
+package systemd1
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/plasma-umass/systemgo/system"
+)
+
+// UnitStatus describes a unit listed by ListUnits
+type UnitStatus struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+
+	// Unit followed, always empty
+	Followed string
+
+	Path dbus.ObjectPath
+
+	// Job queued for the unit, 0, "" and "/" respectively, if none
+	JobID   uint32
+	JobType string
+	JobPath dbus.ObjectPath
+}
+
+// JobStatus describes a job listed by ListJobs
+type JobStatus struct {
+	ID    uint32
+	Unit  string
+	Type  string
+	State string
+
+	Path     dbus.ObjectPath
+	UnitPath dbus.ObjectPath
+}
+
+// UnitFileStatus describes a unit file listed by ListUnitFiles
+type UnitFileStatus struct {
+	Path  string
+	State string
+}
+
+// GetUnit returns the path of the object of the unit name, which must be loaded
+func (m *Manager) GetUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	if _, err := m.sys.Unit(name); err != nil {
+		return "/", failed(err)
+	}
+	return UnitPath(name), nil
+}
+
+// LoadUnit loads the unit name, if not loaded yet, and returns the path of its object
+func (m *Manager) LoadUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	if _, err := m.sys.Get(name); err != nil {
+		return "/", failed(err)
+	}
+	return UnitPath(name), nil
+}
+
+func (m *Manager) StartUnit(sender dbus.Sender, name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.enqueue(sender, name, system.JobMode(mode), m.sys.ManualStart)
+}
+
+func (m *Manager) StopUnit(sender dbus.Sender, name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.enqueue(sender, name, system.JobMode(mode), m.sys.ManualStop)
+}
+
+func (m *Manager) RestartUnit(sender dbus.Sender, name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.enqueue(sender, name, system.JobMode(mode), m.sys.ManualRestart)
+}
+
+func (m *Manager) ReloadUnit(sender dbus.Sender, name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.enqueue(sender, name, system.JobMode(mode), func(mode system.JobMode, names ...string) error {
+		return m.sys.Reload(names...)
+	})
+}
+
+// enqueue enqueues a job for the unit name in mode using fn on behalf of sender and returns the path of its object
+func (m *Manager) enqueue(sender dbus.Sender, name string, mode system.JobMode, fn func(system.JobMode, ...string) error) (dbus.ObjectPath, *dbus.Error) {
+	if err := m.authorize(sender); err != nil {
+		return "/", err
+	}
+
+	if err := fn(mode, name); err != nil {
+		return "/", failed(err)
+	}
+
+	u, err := m.sys.Unit(name)
+	if err != nil {
+		return "/", failed(err)
+	}
+	return JobPath(u.JobID()), nil
+}
+
+func (m *Manager) CancelJob(sender dbus.Sender, id uint32) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	return failed(m.sys.CancelJob(uint64(id)))
+}
+
+// ResetFailed resets the failed state of all the units
+func (m *Manager) ResetFailed(sender dbus.Sender) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	return failed(m.sys.ResetFailed())
+}
+
+func (m *Manager) ResetFailedUnit(sender dbus.Sender, name string) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	return failed(m.sys.ResetFailed(name))
+}
+
+// Reload reloads the definitions of all the units
+func (m *Manager) Reload(sender dbus.Sender) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	m.sys.DaemonReload()
+	return nil
+}
+
+// SetEnvironment sets the variables assigned in names in the environment passed to the processes started
+func (m *Manager) SetEnvironment(sender dbus.Sender, names []string) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	return failed(m.sys.SetEnvironment(names...))
+}
+
+func (m *Manager) UnsetEnvironment(sender dbus.Sender, names []string) *dbus.Error {
+	if err := m.authorize(sender); err != nil {
+		return err
+	}
+	return failed(m.sys.UnsetEnvironment(names...))
+}
+
+func (m *Manager) ListUnits() ([]UnitStatus, *dbus.Error) {
+	jobs := map[string]system.JobInfo{}
+	for _, j := range m.sys.ListJobs() {
+		jobs[j.Unit] = j
+	}
+
+	units := m.sys.Units()
+	list := make([]UnitStatus, 0, len(units))
+	for _, u := range units {
+		st := u.Status()
+		us := UnitStatus{
+			Name:        u.Name(),
+			Description: st.Description,
+			LoadState:   loadState(st.Load.Loaded),
+			ActiveState: activeState(st.Activation.State),
+			SubState:    st.Activation.Sub,
+			Path:        UnitPath(u.Name()),
+			JobPath:     "/",
+		}
+		if j, ok := jobs[u.Name()]; ok {
+			us.JobID = uint32(j.ID)
+			us.JobType = j.Type
+			us.JobPath = JobPath(j.ID)
+		}
+		list = append(list, us)
+	}
+	return list, nil
+}
+
+func (m *Manager) ListJobs() ([]JobStatus, *dbus.Error) {
+	jobs := m.sys.ListJobs()
+
+	list := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, JobStatus{
+			ID:       uint32(j.ID),
+			Unit:     j.Unit,
+			Type:     j.Type,
+			State:    j.State,
+			Path:     JobPath(j.ID),
+			UnitPath: UnitPath(j.Unit),
+		})
+	}
+	return list, nil
+}
+
+func (m *Manager) ListUnitFiles() ([]UnitFileStatus, *dbus.Error) {
+	files := m.sys.ListUnitFiles()
+
+	list := make([]UnitFileStatus, 0, len(files))
+	for _, f := range files {
+		list = append(list, UnitFileStatus{f.Name, f.State})
+	}
+	return list, nil
+}
+
+// Subscribe enables the emission of the signals, until all the subscribers unsubscribe
+func (m *Manager) Subscribe(sender dbus.Sender) *dbus.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.subscribers[sender] = struct{}{}
+	return nil
+}
+
+func (m *Manager) Unsubscribe(sender dbus.Sender) *dbus.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.subscribers, sender)
+	return nil
+}
@@ -0,0 +1,94 @@
+package systemd1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	path, err := ioutil.TempDir("", "systemd1-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "a.target"), []byte(`[Unit]
+Description=A
+DefaultDependencies=no`), 0666), "ioutil.WriteFile")
+
+	sys := system.New()
+	sys.SetPaths(path)
+
+	auth, err := systemctl.NewAuthorizer("")
+	require.NoError(t, err, "systemctl.NewAuthorizer")
+
+	m := New(sys, auth)
+	m.uid = func(sender dbus.Sender) (int, error) {
+		if sender == "root" {
+			return 0, nil
+		}
+		return 1000, nil
+	}
+
+	_, dbusErr := m.GetUnit("a.target")
+	require.NotNil(t, dbusErr, "unit not loaded")
+	assert.Equal(t, errNoSuchUnit, dbusErr.Name)
+
+	_, dbusErr = m.StartUnit("user", "a.target", "replace")
+	require.NotNil(t, dbusErr, "unprivileged client")
+	assert.Equal(t, errAccessDenied, dbusErr.Name)
+
+	job, dbusErr := m.StartUnit("root", "a.target", "replace")
+	require.Nil(t, dbusErr, "StartUnit")
+	assert.Regexp(t, "^"+JOB_PATH+"/[0-9]+$", string(job))
+
+	upath, dbusErr := m.GetUnit("a.target")
+	require.Nil(t, dbusErr, "GetUnit")
+	assert.Equal(t, UnitPath("a.target"), upath)
+
+	for timeout := time.After(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		props, dbusErr := m.unitProperties("a.target")
+		require.Nil(t, dbusErr, "unitProperties")
+		if props["ActiveState"].Value() == "active" {
+			assert.Equal(t, "A", props["Description"].Value())
+			assert.Equal(t, "loaded", props["LoadState"].Value())
+			assert.Equal(t, "(uo)", props["Job"].Signature().String())
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("a.target not started")
+		default:
+		}
+	}
+
+	units, dbusErr := m.ListUnits()
+	require.Nil(t, dbusErr, "ListUnits")
+	require.Len(t, units, 1)
+	assert.Equal(t, UnitStatus{
+		Name:        "a.target",
+		Description: "A",
+		LoadState:   "loaded",
+		ActiveState: "active",
+		SubState:    units[0].SubState,
+		Path:        UnitPath("a.target"),
+		JobPath:     "/",
+	}, units[0])
+
+	_, dbusErr = m.LoadUnit("b.target")
+	require.NotNil(t, dbusErr, "unit file not found")
+
+	assert.False(t, m.subscribed())
+	require.Nil(t, m.Subscribe("root"))
+	assert.True(t, m.subscribed())
+	require.Nil(t, m.Unsubscribe("root"))
+	assert.False(t, m.subscribed())
+}
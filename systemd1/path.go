@@ -0,0 +1,73 @@
+package systemd1
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+// UnitPath returns the path of the object representing the unit name
+func UnitPath(name string) dbus.ObjectPath {
+	return dbus.ObjectPath(UNIT_PATH + "/" + escape(name))
+}
+
+// JobPath returns the path of the object representing the job id
+func JobPath(id uint64) dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/%d", JOB_PATH, id))
+}
+
+// unitName returns the name of the unit represented by the object at path
+func unitName(path dbus.ObjectPath) (name string, ok bool) {
+	if !strings.HasPrefix(string(path), UNIT_PATH+"/") {
+		return "", false
+	}
+	return unescape(strings.TrimPrefix(string(path), UNIT_PATH+"/"))
+}
+
+// escape escapes s to be used as an object path element the way systemd does:
+// the characters other than ASCII letters and digits (a leading digit incl.) are replaced by '_' followed by their hex code
+func escape(s string) string {
+	if s == "" {
+		return "_"
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// unescape reverses escape
+func unescape(s string) (string, bool) {
+	if s == "_" {
+		return "", true
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+2 >= len(s) {
+			return "", false
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), true
+}
@@ -0,0 +1,30 @@
+package systemd1
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitPath(t *testing.T) {
+	for name, path := range map[string]dbus.ObjectPath{
+		"foo.service":        "/org/freedesktop/systemd1/unit/foo_2eservice",
+		"getty@tty1.service": "/org/freedesktop/systemd1/unit/getty_40tty1_2eservice",
+		"1-a.target":         "/org/freedesktop/systemd1/unit/_31_2da_2etarget",
+	} {
+		assert.Equal(t, path, UnitPath(name), name)
+		assert.True(t, path.IsValid(), name)
+
+		found, ok := unitName(path)
+		assert.True(t, ok, name)
+		assert.Equal(t, name, found)
+	}
+
+	for _, path := range []dbus.ObjectPath{"/org/freedesktop/systemd1", "/org/freedesktop/systemd1/unit/foo_2", "/org/freedesktop/systemd1/unit/foo_zz"} {
+		_, ok := unitName(path)
+		assert.False(t, ok, path)
+	}
+
+	assert.Equal(t, dbus.ObjectPath("/org/freedesktop/systemd1/job/42"), JobPath(42))
+}
@@ -0,0 +1,272 @@
+// Package systemd1 exposes the system on D-Bus using the org.freedesktop.systemd1 API,
+// so that the tools driving systemd can drive systemgo
+package systemd1
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/plasma-umass/systemgo/system"
+	"github.com/plasma-umass/systemgo/systemctl"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+const (
+	BUS_NAME    = "org.freedesktop.systemd1"
+	OBJECT_PATH = "/org/freedesktop/systemd1"
+
+	MANAGER_INTERFACE        = "org.freedesktop.systemd1.Manager"
+	UNIT_INTERFACE           = "org.freedesktop.systemd1.Unit"
+	PROPERTIES_INTERFACE     = "org.freedesktop.DBus.Properties"
+	INTROSPECTABLE_INTERFACE = "org.freedesktop.DBus.Introspectable"
+
+	UNIT_PATH = OBJECT_PATH + "/unit"
+	JOB_PATH  = OBJECT_PATH + "/job"
+
+	// Version reported by the manager
+	VERSION = "systemgo"
+)
+
+var ErrNameTaken = errors.New("Bus name is already taken")
+var ErrClosed = errors.New("Bus connection closed")
+
+// Names of the errors replied
+const (
+	errNoSuchUnit    = "org.freedesktop.systemd1.NoSuchUnit"
+	errNoSuchJob     = "org.freedesktop.systemd1.NoSuchJob"
+	errAccessDenied  = "org.freedesktop.DBus.Error.AccessDenied"
+	errFailed        = "org.freedesktop.DBus.Error.Failed"
+	errUnknownProp   = "org.freedesktop.DBus.Error.UnknownProperty"
+	errUnknownObject = "org.freedesktop.DBus.Error.UnknownObject"
+)
+
+// Signal of the bus reporting the owner of a name changed
+const (
+	nameOwnerChanged = "org.freedesktop.DBus.NameOwnerChanged"
+	nameOwnerMatch   = "type='signal',sender='org.freedesktop.DBus',interface='org.freedesktop.DBus',member='NameOwnerChanged'"
+)
+
+// Daemon is the system exposed
+type Daemon interface {
+	systemctl.Daemon
+
+	Get(string) (*system.Unit, error)
+
+	// The names owned on the bus are reported, so that services of Type=dbus get supervised
+	SetBusNames(...string)
+	BusNameOwnerChanged(string, bool)
+}
+
+// Authorizer decides, whether a user may mutate the state of the system
+type Authorizer interface {
+	Authorized(uid, gid int) bool
+}
+
+// Manager serves the org.freedesktop.systemd1.Manager interface and the objects of the units
+type Manager struct {
+	sys  Daemon
+	auth Authorizer
+
+	// uid returns the user ID of the sender of a request
+	uid func(dbus.Sender) (int, error)
+
+	// Senders subscribed to the signals
+	subscribers map[dbus.Sender]struct{}
+	mutex       sync.Mutex
+}
+
+// New returns a Manager exposing sys, allowing the users authorized by auth to mutate the state
+func New(sys Daemon, auth Authorizer) (m *Manager) {
+	return &Manager{
+		sys:         sys,
+		auth:        auth,
+		subscribers: map[dbus.Sender]struct{}{},
+	}
+}
+
+// Serve exports the objects on conn, acquires BUS_NAME and emits the signals until conn is closed
+func (m *Manager) Serve(conn *dbus.Conn) (err error) {
+	m.uid = func(sender dbus.Sender) (uid int, err error) {
+		var id uint32
+		err = conn.BusObject().Call("org.freedesktop.DBus.GetConnectionUnixUser", 0, string(sender)).Store(&id)
+		return int(id), err
+	}
+
+	// The objects of the units are served by the handlers exported on the subtree of UNIT_PATH
+	for _, obj := range []struct {
+		v       interface{}
+		iface   string
+		subtree bool
+	}{
+		{m, MANAGER_INTERFACE, false},
+		{properties{m}, PROPERTIES_INTERFACE, false},
+		{introspectable(OBJECT_PATH, MANAGER_INTERFACE, m), INTROSPECTABLE_INTERFACE, false},
+		{unitObject{m}, UNIT_INTERFACE, true},
+		{properties{m}, PROPERTIES_INTERFACE, true},
+		{introspectable(UNIT_PATH, UNIT_INTERFACE, unitObject{m}), INTROSPECTABLE_INTERFACE, true},
+	} {
+		path := dbus.ObjectPath(OBJECT_PATH)
+		export := conn.Export
+		if obj.subtree {
+			path, export = UNIT_PATH, conn.ExportSubtree
+		}
+
+		if err = export(obj.v, path, obj.iface); err != nil {
+			return
+		}
+	}
+
+	reply, err := conn.RequestName(BUS_NAME, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return ErrNameTaken
+	}
+	log.Infof("Acquired %s on the system bus", BUS_NAME)
+
+	events, cancel := m.sys.Subscribe()
+	defer cancel()
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	if err = m.watchNames(conn); err != nil {
+		return
+	}
+	defer m.sys.SetBusNames()
+
+	for {
+		select {
+		case ev := <-events:
+			if m.subscribed() {
+				m.emit(conn, ev)
+			}
+		case sig, ok := <-signals:
+			if !ok {
+				return ErrClosed
+			}
+			if sig.Name != nameOwnerChanged || len(sig.Body) != 3 {
+				continue
+			}
+			name, _ := sig.Body[0].(string)
+			owner, _ := sig.Body[2].(string)
+			if name != "" && !strings.HasPrefix(name, ":") {
+				m.sys.BusNameOwnerChanged(name, owner != "")
+			}
+		}
+	}
+}
+
+// watchNames subscribes to the changes of the owners of names on the bus conn is connected to
+// and reports the well-known names owned already to the system
+func (m *Manager) watchNames(conn *dbus.Conn) (err error) {
+	if err = conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, nameOwnerMatch).Err; err != nil {
+		return
+	}
+
+	var names, wellKnown []string
+	if err = conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, ":") {
+			wellKnown = append(wellKnown, name)
+		}
+	}
+	m.sys.SetBusNames(wellKnown...)
+	return nil
+}
+
+// introspectable returns the introspection data of the object at path implementing iface using v
+func introspectable(path, iface string, v interface{}) introspect.Introspectable {
+	return introspect.NewIntrospectable(&introspect.Node{
+		Name: path,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: iface, Methods: introspect.Methods(v)},
+			{Name: PROPERTIES_INTERFACE, Methods: introspect.Methods(properties{})},
+		},
+	})
+}
+
+// emit emits the signals corresponding to ev on conn
+func (m *Manager) emit(conn *dbus.Conn, ev system.Event) {
+	var err error
+	switch ev.Type {
+	case system.UnitLoaded:
+		err = conn.Emit(OBJECT_PATH, MANAGER_INTERFACE+".UnitNew", ev.Unit, UnitPath(ev.Unit))
+	case system.UnitActiveChanged:
+		err = conn.Emit(UnitPath(ev.Unit), PROPERTIES_INTERFACE+".PropertiesChanged", UNIT_INTERFACE, map[string]dbus.Variant{
+			"ActiveState": dbus.MakeVariant(activeState(ev.Active)),
+		}, []string{"SubState"})
+	case system.JobFinished:
+		err = conn.Emit(OBJECT_PATH, MANAGER_INTERFACE+".JobRemoved",
+			uint32(ev.Job.ID), JobPath(ev.Job.ID), ev.Unit, system.JobResult(ev.Err))
+	case system.BootFinished:
+		err = conn.Emit(OBJECT_PATH, MANAGER_INTERFACE+".StartupFinished")
+	}
+	if err != nil {
+		log.WithField("event", ev.Type).Errorf("Error emitting signal: %s", err)
+	}
+}
+
+// activeState returns st as reported by systemd
+func activeState(st unit.Activation) string {
+	return strings.ToLower(st.String())
+}
+
+// subscribed returns whether any client is subscribed to the signals
+func (m *Manager) subscribed() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.subscribers) > 0
+}
+
+// authorize returns an error, unless sender may mutate the state of the system
+func (m *Manager) authorize(sender dbus.Sender) *dbus.Error {
+	if m.uid == nil {
+		return dbus.NewError(errAccessDenied, []interface{}{systemctl.ErrAccessDenied.Error()})
+	}
+
+	uid, err := m.uid(sender)
+	if err != nil {
+		return dbus.NewError(errAccessDenied, []interface{}{err.Error()})
+	}
+
+	gid := -1
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		if gid, err = strconv.Atoi(u.Gid); err != nil {
+			gid = -1
+		}
+	}
+
+	if !m.auth.Authorized(uid, gid) {
+		log.WithFields(log.Fields{
+			"sender": sender,
+			"uid":    uid,
+		}).Warn("Refusing request of an unprivileged client")
+		return dbus.NewError(errAccessDenied, []interface{}{systemctl.ErrAccessDenied.Error()})
+	}
+	return nil
+}
+
+// failed returns err replied
+func failed(err error) *dbus.Error {
+	switch err {
+	case nil:
+		return nil
+	case system.ErrNotFound:
+		return dbus.NewError(errNoSuchUnit, []interface{}{err.Error()})
+	case system.ErrNoSuchJob:
+		return dbus.NewError(errNoSuchJob, []interface{}{err.Error()})
+	default:
+		return dbus.NewError(errFailed, []interface{}{err.Error()})
+	}
+}
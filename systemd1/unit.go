@@ -0,0 +1,163 @@
+package systemd1
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// unitObject serves the org.freedesktop.systemd1.Unit interface of the objects of the units
+type unitObject struct {
+	m *Manager
+}
+
+// name returns the name of the unit, the object of which msg is sent to
+func (o unitObject) name(msg dbus.Message) (string, *dbus.Error) {
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	name, ok := unitName(path)
+	if !ok {
+		return "", dbus.NewError(errUnknownObject, []interface{}{string(path)})
+	}
+	return name, nil
+}
+
+func (o unitObject) Start(sender dbus.Sender, msg dbus.Message, mode string) (dbus.ObjectPath, *dbus.Error) {
+	name, err := o.name(msg)
+	if err != nil {
+		return "/", err
+	}
+	return o.m.StartUnit(sender, name, mode)
+}
+
+func (o unitObject) Stop(sender dbus.Sender, msg dbus.Message, mode string) (dbus.ObjectPath, *dbus.Error) {
+	name, err := o.name(msg)
+	if err != nil {
+		return "/", err
+	}
+	return o.m.StopUnit(sender, name, mode)
+}
+
+func (o unitObject) Restart(sender dbus.Sender, msg dbus.Message, mode string) (dbus.ObjectPath, *dbus.Error) {
+	name, err := o.name(msg)
+	if err != nil {
+		return "/", err
+	}
+	return o.m.RestartUnit(sender, name, mode)
+}
+
+func (o unitObject) Reload(sender dbus.Sender, msg dbus.Message, mode string) (dbus.ObjectPath, *dbus.Error) {
+	name, err := o.name(msg)
+	if err != nil {
+		return "/", err
+	}
+	return o.m.ReloadUnit(sender, name, mode)
+}
+
+func (o unitObject) ResetFailed(sender dbus.Sender, msg dbus.Message) *dbus.Error {
+	name, err := o.name(msg)
+	if err != nil {
+		return err
+	}
+	return o.m.ResetFailedUnit(sender, name)
+}
+
+// properties serves the org.freedesktop.DBus.Properties interface of the manager and the objects of the units
+type properties struct {
+	m *Manager
+}
+
+func (p properties) Get(msg dbus.Message, iface, name string) (dbus.Variant, *dbus.Error) {
+	props, err := p.GetAll(msg, iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+
+	v, ok := props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError(errUnknownProp, []interface{}{name})
+	}
+	return v, nil
+}
+
+func (p properties) GetAll(msg dbus.Message, iface string) (map[string]dbus.Variant, *dbus.Error) {
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	if path == OBJECT_PATH {
+		if iface != MANAGER_INTERFACE && iface != "" {
+			return nil, dbus.NewError(errUnknownProp, []interface{}{iface})
+		}
+		return p.m.properties(), nil
+	}
+
+	name, ok := unitName(path)
+	if !ok {
+		return nil, dbus.NewError(errUnknownObject, []interface{}{string(path)})
+	}
+	if iface != UNIT_INTERFACE && iface != "" {
+		return nil, dbus.NewError(errUnknownProp, []interface{}{iface})
+	}
+	return p.m.unitProperties(name)
+}
+
+func (p properties) Set(msg dbus.Message, iface, name string, v dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{name})
+}
+
+// properties returns the properties of the manager
+func (m *Manager) properties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Version": dbus.MakeVariant(VERSION),
+		"NNames":  dbus.MakeVariant(uint32(len(m.sys.Units()))),
+		"NJobs":   dbus.MakeVariant(uint32(len(m.sys.ListJobs()))),
+
+		"Environment": dbus.MakeVariant(m.sys.Environment()),
+	}
+}
+
+// unitProperties returns the properties of the unit name
+func (m *Manager) unitProperties(name string) (map[string]dbus.Variant, *dbus.Error) {
+	st, err := m.sys.StatusOf(name)
+	if err != nil {
+		return nil, failed(err)
+	}
+
+	// name may be an alias
+	id, names := name, []string{name}
+	if u, err := m.sys.Get(name); err == nil {
+		id, names = u.Name(), u.Names()
+	}
+
+	job := struct {
+		ID   uint32
+		Path dbus.ObjectPath
+	}{0, "/"}
+	for _, j := range m.sys.ListJobs() {
+		if j.Unit == name {
+			job.ID, job.Path = uint32(j.ID), JobPath(j.ID)
+		}
+	}
+
+	return map[string]dbus.Variant{
+		"Id":            dbus.MakeVariant(id),
+		"Names":         dbus.MakeVariant(names),
+		"Following":     dbus.MakeVariant(""),
+		"Description":   dbus.MakeVariant(st.Description),
+		"LoadState":     dbus.MakeVariant(loadState(st.Load.Loaded)),
+		"ActiveState":   dbus.MakeVariant(activeState(st.Activation.State)),
+		"SubState":      dbus.MakeVariant(st.Activation.Sub),
+		"FragmentPath":  dbus.MakeVariant(st.Load.Path),
+		"DropInPaths":   dbus.MakeVariant(append([]string{}, st.Load.DropIns...)),
+		"UnitFileState": dbus.MakeVariant(strings.ToLower(st.Load.State.String())),
+		"Job":           dbus.MakeVariant(job),
+
+		"NeedDaemonReload": dbus.MakeVariant(st.Load.NeedDaemonReload),
+	}, nil
+}
+
+// loadState returns st as reported by systemd
+func loadState(st unit.Load) string {
+	if st == unit.NotFound {
+		return "not-found"
+	}
+	return strings.ToLower(st.String())
+}
@@ -0,0 +1,345 @@
+// Package sysv wraps the SysV init scripts as service units, the dependencies of which are read from the LSB headers
+package sysv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Directory the init scripts are found in
+var INIT_DIR = "/etc/init.d"
+
+// Directory the rcN.d directories, which the init scripts are linked to for each runlevel, are found in
+var RC_DIR = "/etc"
+
+// Targets the runlevels the init scripts get started in correspond to
+var RUNLEVEL_TARGETS = map[int]string{
+	2: "multi-user.target",
+	3: "multi-user.target",
+	4: "multi-user.target",
+	5: "graphical.target",
+}
+
+// Units the LSB facilities correspond to. The facilities mapped to an empty string are ignored
+var FACILITIES = map[string]string{
+	"$local_fs":  "local-fs.target",
+	"$network":   "network.target",
+	"$named":     "nss-lookup.target",
+	"$portmap":   "rpcbind.target",
+	"$remote_fs": "remote-fs.target",
+	"$time":      "time-sync.target",
+	"$syslog":    "",
+	"$all":       "",
+}
+
+// Names of the files in INIT_DIR, which are not init scripts
+var ignored = map[string]bool{
+	"README":    true,
+	"skeleton":  true,
+	"rc":        true,
+	"rcS":       true,
+	"rc.local":  true,
+	"functions": true,
+	"halt":      true,
+	"reboot":    true,
+	"single":    true,
+}
+
+// Lines the LSB header is enclosed in
+const (
+	HEADER_BEGIN = "### BEGIN INIT INFO"
+	HEADER_END   = "### END INIT INFO"
+)
+
+var ErrNoHeader = errors.New("No LSB header found")
+
+// Header is the LSB header of an init script
+type Header struct {
+	Provides                    []string
+	RequiredStart, RequiredStop []string
+	ShouldStart, ShouldStop     []string
+	StartBefore, StopAfter      []string
+	DefaultStart, DefaultStop   []string
+	ShortDescription            string
+	Description                 string
+}
+
+// ParseHeader parses the LSB header found in r
+func ParseHeader(r io.Reader) (h Header, err error) {
+	found := false
+	inside := false
+	last := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == HEADER_BEGIN:
+			found, inside = true, true
+			continue
+		case line == HEADER_END:
+			if inside {
+				return h, nil
+			}
+			continue
+		case !inside || !strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "#")
+
+		// Continuation lines of Description: start with a tab or at least two spaces
+		if last == "Description" && (strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "  ")) {
+			h.Description += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			last = ""
+			continue
+		}
+
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch last = key; key {
+		case "Provides":
+			h.Provides = strings.Fields(value)
+		case "Required-Start":
+			h.RequiredStart = strings.Fields(value)
+		case "Required-Stop":
+			h.RequiredStop = strings.Fields(value)
+		case "Should-Start":
+			h.ShouldStart = strings.Fields(value)
+		case "Should-Stop":
+			h.ShouldStop = strings.Fields(value)
+		case "X-Start-Before":
+			h.StartBefore = strings.Fields(value)
+		case "X-Stop-After":
+			h.StopAfter = strings.Fields(value)
+		case "Default-Start":
+			h.DefaultStart = strings.Fields(value)
+		case "Default-Stop":
+			h.DefaultStop = strings.Fields(value)
+		case "Short-Description":
+			h.ShortDescription = value
+		case "Description":
+			h.Description = value
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	if !found {
+		return h, ErrNoHeader
+	}
+	// The header is not terminated, the fields found are returned
+	return h, nil
+}
+
+// After returns the units, the service wrapping the script with header h is ordered after
+func (h Header) After() []string {
+	return units(append(append([]string{}, h.RequiredStart...), h.ShouldStart...)...)
+}
+
+// Before returns the units, the service wrapping the script with header h is ordered before
+func (h Header) Before() []string {
+	return units(append(append([]string{}, h.StartBefore...), h.StopAfter...)...)
+}
+
+// units returns the names of the units the facilities and the names of the scripts in names correspond to
+func units(names ...string) (found []string) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		u, ok := FACILITIES[name]
+		switch {
+		case ok && u == "":
+			continue
+		case !ok && strings.HasPrefix(name, "$"):
+			// Facilities unknown
+			continue
+		case !ok:
+			u = name + ".service"
+		}
+
+		if !seen[u] {
+			seen[u] = true
+			found = append(found, u)
+		}
+	}
+	return
+}
+
+// Script is an init script found
+type Script struct {
+	// Name of the script, the service wrapping it is named after
+	Name string
+
+	// Path to the script
+	Path string
+
+	Header Header
+
+	// Runlevels the script is started in, as linked to in the rcN.d directories
+	Runlevels []int
+}
+
+// Service returns the name of the service wrapping s
+func (s Script) Service() string {
+	return s.Name + ".service"
+}
+
+// Unit returns the definition of the service wrapping s.
+// Forking services are emulated by a oneshot service, which is left active once the script has exited
+func (s Script) Unit() string {
+	description := s.Header.ShortDescription
+	if description == "" {
+		description = s.Name
+	}
+
+	lines := []string{
+		"# Automatically generated by the SysV compatibility layer of systemgo",
+		"[Unit]",
+		"Description=LSB: " + description,
+		"Documentation=file:" + s.Path,
+	}
+	if after := s.Header.After(); len(after) > 0 {
+		lines = append(lines, "After="+strings.Join(after, " "))
+	}
+	if before := s.Header.Before(); len(before) > 0 {
+		lines = append(lines, "Before="+strings.Join(before, " "))
+	}
+
+	lines = append(lines,
+		"",
+		"[Service]",
+		"Type=oneshot",
+		"RemainAfterExit=yes",
+		"ExecStart="+s.Path+" start",
+		"ExecStop="+s.Path+" stop",
+		"ExecReload="+s.Path+" reload",
+		"Environment=SYSTEMCTL_SKIP_REDIRECT=1",
+	)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Scripts returns the init scripts found in dir sorted by name.
+// The scripts without an LSB header are wrapped without dependencies
+func Scripts(dir string) (scripts []Script, err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if ignored[name] || strings.HasPrefix(name, ".") || isBackup(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		h, err := ParseHeader(f)
+		f.Close()
+		if err != nil && err != ErrNoHeader {
+			return nil, err
+		}
+
+		scripts = append(scripts, Script{Name: name, Path: path, Header: h, Runlevels: runlevels(name)})
+	}
+	return
+}
+
+// isBackup reports whether name is the one of a file left behind by a package manager or an editor
+func isBackup(name string) bool {
+	for _, suffix := range []string{"~", ".dpkg-old", ".dpkg-new", ".dpkg-dist", ".dpkg-bak", ".rpmsave", ".rpmnew", ".swp"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runlevels returns the runlevels, the script called name is linked to with a start link(e.g. /etc/rc2.d/S01name)
+func runlevels(name string) (found []int) {
+	for level := 0; level <= 6; level++ {
+		matches, _ := filepath.Glob(filepath.Join(RC_DIR, fmt.Sprintf("rc%d.d", level), "S[0-9][0-9]"+name))
+		if len(matches) > 0 {
+			found = append(found, level)
+		}
+	}
+	return
+}
+
+// Generate writes the services wrapping the init scripts found in INIT_DIR to dir and enables them for the targets
+// their runlevels correspond to. The scripts, for which a unit file is found in any of paths, are skipped, as
+// the native units take precedence
+func Generate(dir string, paths ...string) (err error) {
+	scripts, err := Scripts(INIT_DIR)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return
+	}
+
+	for _, s := range scripts {
+		if native(s.Service(), paths...) {
+			continue
+		}
+
+		path := filepath.Join(dir, s.Service())
+		if err = ioutil.WriteFile(path, []byte(s.Unit()), 0644); err != nil {
+			return
+		}
+
+		targets := map[string]bool{}
+		for _, level := range s.Runlevels {
+			if target, ok := RUNLEVEL_TARGETS[level]; ok {
+				targets[target] = true
+			}
+		}
+
+		names := make([]string, 0, len(targets))
+		for target := range targets {
+			names = append(names, target)
+		}
+		sort.Strings(names)
+
+		for _, target := range names {
+			wants := filepath.Join(dir, target+".wants")
+			if err = os.MkdirAll(wants, 0755); err != nil {
+				return
+			}
+			if err = os.Symlink(path, filepath.Join(wants, s.Service())); err != nil && !os.IsExist(err) {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+// native reports whether a unit file called name is found in any of paths
+func native(name string, paths ...string) bool {
+	for _, path := range paths {
+		if _, err := os.Lstat(filepath.Join(path, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
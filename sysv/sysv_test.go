@@ -0,0 +1,85 @@
+package sysv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const script = `#!/bin/sh
+### BEGIN INIT INFO
+# Provides:          foo
+# Required-Start:    $local_fs $network $syslog bar
+# Required-Stop:     $local_fs
+# Should-Start:      $time
+# X-Start-Before:    baz
+# Default-Start:     2 3 4 5
+# Default-Stop:      0 1 6
+# Short-Description: Foo daemon
+# Description:       Foo daemon, which
+#                    does foo
+### END INIT INFO
+echo "$1"
+`
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(strings.NewReader(script))
+	require.NoError(t, err, "ParseHeader")
+
+	assert.Equal(t, []string{"foo"}, h.Provides, "h.Provides")
+	assert.Equal(t, []string{"2", "3", "4", "5"}, h.DefaultStart, "h.DefaultStart")
+	assert.Equal(t, "Foo daemon", h.ShortDescription, "h.ShortDescription")
+	assert.Equal(t, "Foo daemon, which does foo", h.Description, "h.Description")
+	assert.Equal(t, []string{"local-fs.target", "network.target", "bar.service", "time-sync.target"}, h.After(), "h.After")
+	assert.Equal(t, []string{"baz.service"}, h.Before(), "h.Before")
+
+	_, err = ParseHeader(strings.NewReader("#!/bin/sh\necho\n"))
+	assert.Equal(t, ErrNoHeader, err, "ParseHeader without header")
+}
+
+func TestGenerate(t *testing.T) {
+	path, err := ioutil.TempDir("", "sysv-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	defer func(init, rc string) { INIT_DIR, RC_DIR = init, rc }(INIT_DIR, RC_DIR)
+	INIT_DIR, RC_DIR = filepath.Join(path, "init.d"), path
+
+	native, out := filepath.Join(path, "units"), filepath.Join(path, "out")
+	for _, dir := range []string{INIT_DIR, filepath.Join(path, "rc3.d"), native, out} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	for _, name := range []string{"foo", "native"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(INIT_DIR, name), []byte(script), 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(INIT_DIR, "README"), []byte("readme"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(INIT_DIR, "data"), []byte(script), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(native, "native.service"), nil, 0644))
+	require.NoError(t, os.Symlink("../init.d/foo", filepath.Join(path, "rc3.d", "S01foo")))
+
+	require.NoError(t, Generate(out, native), "Generate")
+
+	b, err := ioutil.ReadFile(filepath.Join(out, "foo.service"))
+	require.NoError(t, err, "foo.service not generated")
+	assert.Contains(t, string(b), "Description=LSB: Foo daemon\n")
+	assert.Contains(t, string(b), "After=local-fs.target network.target bar.service time-sync.target\n")
+	assert.Contains(t, string(b), "ExecStart="+filepath.Join(INIT_DIR, "foo")+" start\n")
+
+	target, err := os.Readlink(filepath.Join(out, "multi-user.target.wants", "foo.service"))
+	if assert.NoError(t, err, "foo.service not enabled") {
+		assert.Equal(t, filepath.Join(out, "foo.service"), target)
+	}
+	_, err = os.Lstat(filepath.Join(out, "graphical.target.wants"))
+	assert.True(t, os.IsNotExist(err), "graphical.target.wants created")
+
+	for _, name := range []string{"native.service", "README.service", "data.service"} {
+		_, err = os.Lstat(filepath.Join(out, name))
+		assert.True(t, os.IsNotExist(err), name+" generated")
+	}
+}
@@ -0,0 +1,16 @@
+package tmpfiles
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the time the file described by info was last accessed at
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Atim.Unix()), true
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package tmpfiles
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime is not implemented, only the modification time is considered outside of Linux
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
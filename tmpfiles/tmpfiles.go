@@ -0,0 +1,419 @@
+// Package tmpfiles creates and cleans the volatile files and directories configured in tmpfiles.d(5) files
+package tmpfiles
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Paths searched for configuration files by default(first path gets searched first)
+var DEFAULT_PATHS = []string{"/etc/tmpfiles.d", "/run/tmpfiles.d", "/usr/lib/tmpfiles.d"}
+
+// Suffix of the names of the configuration files
+const SUFFIX = ".conf"
+
+// Modes of the directories and files created, unless configured
+const (
+	DEFAULT_DIR_MODE  = 0755
+	DEFAULT_FILE_MODE = 0644
+)
+
+var ErrBadLine = errors.New("Line should consist of type, path and optional mode, user, group, age and argument")
+var ErrBadMode = errors.New("Invalid mode")
+var ErrUnknownUser = errors.New("Unknown user")
+var ErrUnknownGroup = errors.New("Unknown group")
+
+// Entry is a line of a configuration file
+type Entry struct {
+	// Type of the entry: 'd' creates a directory, 'f' a file, 'L' a symlink and 'z' adjusts the mode and the ownership
+	Type byte
+
+	// Whether the type is followed by '+': f+ truncates the file, L+ replaces the existing path
+	Plus bool
+
+	// Path to create or adjust, a glob pattern for 'z'
+	Path string
+
+	// Mode to set, 0 if not configured
+	Mode os.FileMode
+
+	// Owner to set, -1 if not configured
+	UID, GID int
+
+	// Age of the files in the directory, after which they get removed on cleanup, 0 if never
+	Age time.Duration
+
+	// Content written to the file created, target of the symlink
+	Argument string
+}
+
+// Parse parses the configuration found in r
+func Parse(r io.Reader) (entries []Entry, err error) {
+	var merr unit.MultiError
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := parseLine(line)
+		if err != nil {
+			perr, ok := err.(unit.ParseError)
+			if !ok {
+				perr = unit.ParseErr(line, err)
+			}
+			perr.Line = n
+			merr = append(merr, perr)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	if len(merr) > 0 {
+		return entries, merr
+	}
+	return entries, nil
+}
+
+// parseLine parses a line of a configuration
+func parseLine(line string) (e Entry, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return e, ErrBadLine
+	}
+
+	// The argument is the rest of the line and may contain whitespace
+	if len(fields) > 6 {
+		rest := line
+		for i := 0; i < 6; i++ {
+			rest = strings.TrimSpace(rest)
+			rest = rest[strings.IndexAny(rest, " \t"):]
+		}
+		fields = append(fields[:6], strings.TrimSpace(rest))
+	}
+	for len(fields) < 7 {
+		fields = append(fields, "-")
+	}
+
+	typ := strings.TrimSuffix(fields[0], "+")
+	switch typ {
+	case "d", "f", "L", "z":
+	default:
+		return e, unit.ParseErr(fields[0], unit.ErrNotSupported)
+	}
+	e.Type, e.Plus = typ[0], strings.HasSuffix(fields[0], "+")
+
+	if e.Path = fields[1]; !filepath.IsAbs(e.Path) {
+		return e, unit.ParseErr(e.Path, unit.ErrPathNotAbs)
+	}
+	e.Path = filepath.Clean(e.Path)
+
+	if mode := strings.TrimPrefix(fields[2], "~"); mode != "-" {
+		v, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || v > 07777 {
+			return e, unit.ParseErr(fields[2], ErrBadMode)
+		}
+		e.Mode = os.FileMode(v)&os.ModePerm | specialBits(v)
+	}
+
+	if e.UID, err = lookupID(fields[3], lookupUser); err != nil {
+		return e, unit.ParseErr(fields[3], err)
+	}
+	if e.GID, err = lookupID(fields[4], lookupGroup); err != nil {
+		return e, unit.ParseErr(fields[4], err)
+	}
+
+	if age := fields[5]; age != "-" {
+		if e.Age, err = unit.ParseTimespan(age); err != nil {
+			return e, unit.ParseErr(age, err)
+		}
+	}
+
+	if arg := fields[6]; arg != "-" {
+		e.Argument = arg
+	}
+	if e.Type == 'L' && e.Argument == "" {
+		return e, unit.ParseErr(e.Path, unit.ErrNotSet)
+	}
+	return e, nil
+}
+
+// specialBits returns the setuid, setgid and sticky bits of the octal mode v as os.FileMode bits
+func specialBits(v uint64) (mode os.FileMode) {
+	if v&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if v&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if v&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return
+}
+
+// lookupID returns the numeric ID s specifies, looking the name up using lookup, -1 if s is "-"
+func lookupID(s string, lookup func(string) (int, error)) (int, error) {
+	if s == "-" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(s); err == nil && id >= 0 {
+		return id, nil
+	}
+	return lookup(s)
+}
+
+func lookupUser(name string) (int, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return -1, ErrUnknownUser
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+func lookupGroup(name string) (int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return -1, ErrUnknownGroup
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// Load returns the entries of the configuration files found in paths, ordered by the names of the files.
+// A file found in a path searched first shadows the ones with the same name in paths searched later,
+// a file linked to /dev/null is masked. The lines, which fail to parse, are logged and skipped
+func Load(paths ...string) (entries []Entry) {
+	byName := map[string]string{}
+	for _, path := range paths {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			if _, ok := byName[info.Name()]; !ok && strings.HasSuffix(info.Name(), SUFFIX) {
+				byName[info.Name()] = filepath.Join(path, info.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := byName[name]
+		if target, err := filepath.EvalSymlinks(path); err == nil && target == os.DevNull {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			log.Errorf("Error opening %s: %s", path, err)
+			continue
+		}
+		parsed, err := Parse(f)
+		f.Close()
+
+		if merr, ok := err.(unit.MultiError); ok {
+			for _, err := range merr {
+				if perr, ok := err.(unit.ParseError); ok {
+					perr.File = path
+					err = perr
+				}
+				log.Warnf("Line ignored: %s", err)
+			}
+		} else if err != nil {
+			log.Errorf("Error reading %s: %s", path, err)
+		}
+		entries = append(entries, parsed...)
+	}
+	return
+}
+
+// Create creates the paths of entries in order, as systemd-tmpfiles --create does.
+// The existing files and directories are left in place, but get their mode and ownership adjusted
+func Create(entries []Entry) (err error) {
+	var merr unit.MultiError
+	for _, e := range entries {
+		if err := e.create(); err != nil {
+			merr = append(merr, err)
+		}
+	}
+	if len(merr) > 0 {
+		return merr
+	}
+	return nil
+}
+
+// create creates or adjusts the path of e
+func (e Entry) create() (err error) {
+	switch e.Type {
+	case 'd':
+		mode := e.Mode
+		if mode == 0 {
+			mode = DEFAULT_DIR_MODE
+		}
+		if err = os.MkdirAll(e.Path, mode); err != nil {
+			return
+		}
+		return e.adjust(e.Path)
+
+	case 'f':
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if e.Plus {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		mode := e.Mode
+		if mode == 0 {
+			mode = DEFAULT_FILE_MODE
+		}
+
+		f, err := os.OpenFile(e.Path, flags, mode)
+		switch {
+		case os.IsExist(err):
+			// The content of the file existing is kept
+			return e.adjust(e.Path)
+		case err != nil:
+			return err
+		}
+		if e.Argument != "" {
+			_, err = f.WriteString(e.Argument)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		return e.adjust(e.Path)
+
+	case 'L':
+		if e.Plus {
+			if target, err := os.Readlink(e.Path); err == nil && target == e.Argument {
+				return nil
+			}
+			if err = os.RemoveAll(e.Path); err != nil {
+				return
+			}
+		}
+		if err = os.Symlink(e.Argument, e.Path); err != nil && !os.IsExist(err) {
+			return
+		}
+		return nil
+
+	case 'z':
+		matches, err := filepath.Glob(e.Path)
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if err = e.adjust(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return unit.ErrNotSupported
+}
+
+// adjust sets the mode and the ownership of path configured by e
+func (e Entry) adjust(path string) (err error) {
+	if e.Mode != 0 {
+		if err = os.Chmod(path, e.Mode); err != nil {
+			return
+		}
+	}
+	if e.UID >= 0 || e.GID >= 0 {
+		return os.Lchown(path, e.UID, e.GID)
+	}
+	return nil
+}
+
+// Clean removes the files, which have neither been modified nor accessed for longer than the age configured, from the
+// directories of entries, as systemd-tmpfiles --clean does. The directories emptied get removed, once they are old
+// enough as well, the directories of entries are kept
+func Clean(entries []Entry, now time.Time) (err error) {
+	var merr unit.MultiError
+	for _, e := range entries {
+		if e.Type != 'd' || e.Age <= 0 {
+			continue
+		}
+		if err := clean(e.Path, now.Add(-e.Age)); err != nil && !os.IsNotExist(err) {
+			merr = append(merr, err)
+		}
+	}
+	if len(merr) > 0 {
+		return merr
+	}
+	return nil
+}
+
+// clean removes the contents of dir not used since before
+func clean(dir string, before time.Time) (err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		if info.IsDir() {
+			if err = clean(path, before); err != nil {
+				return
+			}
+			if empty, _ := isEmpty(path); !empty {
+				continue
+			}
+		}
+		if used(info).After(before) {
+			continue
+		}
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether the directory at path is empty
+func isEmpty(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if _, err = f.Readdirnames(1); err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// used returns the time the file described by info was last modified or accessed at
+func used(info os.FileInfo) time.Time {
+	t := info.ModTime()
+	if atime, ok := accessTime(info); ok && atime.After(t) {
+		return atime
+	}
+	return t
+}
@@ -0,0 +1,89 @@
+package tmpfiles
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(`# comment
+d /run/foo 0750 0 0 10d -
+f+ /run/foo/bar 0600 - - - hello world
+L /run/baz - - - - /run/foo
+z /run/foo/* ~0644
+x /run/unsupported
+d relative
+`))
+	if assert.Error(t, err) {
+		assert.Len(t, err.(unit.MultiError), 2, "errors")
+	}
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, Entry{Type: 'd', Path: "/run/foo", Mode: 0750, UID: 0, GID: 0, Age: 10 * 24 * time.Hour}, entries[0])
+	assert.Equal(t, Entry{Type: 'f', Plus: true, Path: "/run/foo/bar", Mode: 0600, UID: -1, GID: -1, Argument: "hello world"}, entries[1])
+	assert.Equal(t, Entry{Type: 'L', Path: "/run/baz", UID: -1, GID: -1, Argument: "/run/foo"}, entries[2])
+	assert.Equal(t, Entry{Type: 'z', Path: "/run/foo/*", Mode: 0644, UID: -1, GID: -1}, entries[3])
+}
+
+func TestCreateClean(t *testing.T) {
+	path, err := ioutil.TempDir("", "tmpfiles-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	conf, root := filepath.Join(path, "tmpfiles.d"), filepath.Join(path, "root")
+	require.NoError(t, os.Mkdir(conf, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(conf, "foo.conf"), []byte(`
+d `+root+`/dir 0700 - - 1h
+f `+root+`/dir/file - - - - content
+L `+root+`/link - - - - dir
+z `+root+`/dir/file 0600
+`), 0644))
+	require.NoError(t, os.Symlink(os.DevNull, filepath.Join(conf, "masked.conf")))
+
+	entries := Load(conf)
+	require.Len(t, entries, 4)
+	require.NoError(t, Create(entries), "Create")
+
+	info, err := os.Stat(filepath.Join(root, "dir"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	b, err := ioutil.ReadFile(filepath.Join(root, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	info, err = os.Stat(filepath.Join(root, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	target, err := os.Readlink(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "dir", target)
+
+	// The content of the files existing is kept
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "dir", "file"), []byte("changed"), 0600))
+	require.NoError(t, Create(entries), "Create again")
+	b, err = ioutil.ReadFile(filepath.Join(root, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(b))
+
+	old := filepath.Join(root, "dir", "old")
+	require.NoError(t, ioutil.WriteFile(old, nil, 0644))
+	past := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(old, past, past))
+
+	require.NoError(t, Clean(entries, time.Now()), "Clean")
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err), "old file not removed")
+	_, err = os.Stat(filepath.Join(root, "dir", "file"))
+	assert.NoError(t, err, "recent file removed")
+}
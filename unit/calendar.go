@@ -0,0 +1,275 @@
+package unit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of days Next looks ahead for an event
+const calendarHorizon = 5 * 366
+
+// calendarShorthands maps the shorthands of calendar events to the expressions they stand for
+var calendarShorthands = map[string]string{
+	"minutely":     "*-*-* *:*:00",
+	"hourly":       "*-*-* *:00:00",
+	"daily":        "*-*-* 00:00:00",
+	"weekly":       "Mon *-*-* 00:00:00",
+	"monthly":      "*-*-01 00:00:00",
+	"yearly":       "*-01-01 00:00:00",
+	"annually":     "*-01-01 00:00:00",
+	"quarterly":    "*-01,04,07,10-01 00:00:00",
+	"semiannually": "*-01,07-01 00:00:00",
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Calendar is a calendar event expression as found in OnCalendar=, e.g. "daily" or "Mon..Fri *-*-* 09:00".
+// It has the form "[weekdays] [year-]month-day [hour:minute[:second]]", each component being "*",
+// a value, a range "a..b", a repetition "a/step" or a comma-separated list of those
+type Calendar struct {
+	expr string
+
+	weekdays             map[time.Weekday]bool
+	year, month, day     calendarField
+	hour, minute, second calendarField
+}
+
+// calendarField matches the values of a component of a calendar event, nil matches any value
+type calendarField []calendarRange
+
+type calendarRange struct {
+	from, to, step int
+}
+
+func (f calendarField) matches(v int) bool {
+	if f == nil {
+		return true
+	}
+	for _, r := range f {
+		if v >= r.from && v <= r.to && (v-r.from)%r.step == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCalendar parses the calendar event expression s
+func ParseCalendar(s string) (c Calendar, err error) {
+	c.expr = strings.TrimSpace(s)
+
+	expr := c.expr
+	if full, ok := calendarShorthands[strings.ToLower(expr)]; ok {
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return c, ErrBadCalendar
+	}
+
+	if first := fields[0]; !strings.ContainsAny(first[:1], "0123456789*") {
+		if c.weekdays, err = parseWeekdays(first); err != nil {
+			return
+		}
+		fields = fields[1:]
+	}
+
+	date, clock := "*-*-*", "00:00:00"
+	switch len(fields) {
+	case 0:
+	case 1:
+		if strings.Contains(fields[0], ":") {
+			clock = fields[0]
+		} else {
+			date = fields[0]
+		}
+	case 2:
+		date, clock = fields[0], fields[1]
+	default:
+		return c, ErrBadCalendar
+	}
+
+	if err = c.parseDate(date); err != nil {
+		return
+	}
+	return c, c.parseClock(clock)
+}
+
+// parseDate parses the "[year-]month-day" component of c
+func (c *Calendar) parseDate(s string) (err error) {
+	parts := strings.Split(s, "-")
+	switch len(parts) {
+	case 2:
+		parts = append([]string{"*"}, parts...)
+	case 3:
+	default:
+		return ErrBadCalendar
+	}
+
+	if c.year, err = parseCalendarField(parts[0], 1970, 9999); err != nil {
+		return
+	}
+	if c.month, err = parseCalendarField(parts[1], 1, 12); err != nil {
+		return
+	}
+	c.day, err = parseCalendarField(parts[2], 1, 31)
+	return
+}
+
+// parseClock parses the "hour:minute[:second]" component of c
+func (c *Calendar) parseClock(s string) (err error) {
+	parts := strings.Split(s, ":")
+	switch len(parts) {
+	case 2:
+		parts = append(parts, "00")
+	case 3:
+	default:
+		return ErrBadCalendar
+	}
+
+	if c.hour, err = parseCalendarField(parts[0], 0, 23); err != nil {
+		return
+	}
+	if c.minute, err = parseCalendarField(parts[1], 0, 59); err != nil {
+		return
+	}
+	c.second, err = parseCalendarField(parts[2], 0, 59)
+	return
+}
+
+// parseCalendarField parses a component of a calendar event, which takes values from min to max
+func parseCalendarField(s string, min, max int) (f calendarField, err error) {
+	if s == "*" {
+		return nil, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		r := calendarRange{from: min, to: max, step: 1}
+
+		if i := strings.Index(item, "/"); i >= 0 {
+			if r.step, err = strconv.Atoi(item[i+1:]); err != nil || r.step <= 0 {
+				return nil, ErrBadCalendar
+			}
+			item = item[:i]
+			if item != "*" {
+				if r.from, err = calendarValue(item, min, max); err != nil {
+					return
+				}
+			}
+		} else if i := strings.Index(item, ".."); i >= 0 {
+			if r.from, err = calendarValue(item[:i], min, max); err != nil {
+				return
+			}
+			if r.to, err = calendarValue(item[i+2:], r.from, max); err != nil {
+				return
+			}
+		} else {
+			if r.from, err = calendarValue(item, min, max); err != nil {
+				return
+			}
+			r.to = r.from
+		}
+		f = append(f, r)
+	}
+	return f, nil
+}
+
+// calendarValue parses a value of a component of a calendar event, which takes values from min to max
+func calendarValue(s string, min, max int) (v int, err error) {
+	if v, err = strconv.Atoi(s); err != nil || v < min || v > max {
+		return 0, ErrBadCalendar
+	}
+	return v, nil
+}
+
+// parseWeekdays parses the weekdays component of a calendar event, e.g. "Mon..Fri" or "Sat,Sun"
+func parseWeekdays(s string) (days map[time.Weekday]bool, err error) {
+	days = map[time.Weekday]bool{}
+	for _, item := range strings.Split(s, ",") {
+		bounds := strings.SplitN(item, "..", 2)
+
+		from, err := weekday(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = weekday(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// weekday parses the English name of a weekday, either full or abbreviated
+func weekday(s string) (time.Weekday, error) {
+	if len(s) >= 3 {
+		if d, ok := weekdays[strings.ToLower(s[:3])]; ok {
+			return d, nil
+		}
+	}
+	return 0, ErrBadCalendar
+}
+
+// String returns the expression c was parsed from
+func (c Calendar) String() string {
+	return c.expr
+}
+
+// Next returns the first time after t matching c in the location of t.
+// The zero time is returned, if c does not match any time within the next few years
+func (c Calendar) Next(t time.Time) time.Time {
+	start := t.Truncate(time.Second).Add(time.Second)
+	loc := start.Location()
+
+	midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < calendarHorizon; i++ {
+		day := midnight.AddDate(0, 0, i)
+		if !c.matchesDate(day) {
+			continue
+		}
+
+		for h := 0; h < 24; h++ {
+			if !c.hour.matches(h) {
+				continue
+			}
+			for m := 0; m < 60; m++ {
+				if !c.minute.matches(m) {
+					continue
+				}
+				for s := 0; s < 60; s++ {
+					if !c.second.matches(s) {
+						continue
+					}
+					if next := time.Date(day.Year(), day.Month(), day.Day(), h, m, s, 0, loc); !next.Before(start) {
+						return next
+					}
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDate returns whether the date of t matches c
+func (c Calendar) matchesDate(t time.Time) bool {
+	return (c.weekdays == nil || c.weekdays[t.Weekday()]) &&
+		c.year.matches(t.Year()) && c.month.matches(int(t.Month())) && c.day.matches(t.Day())
+}
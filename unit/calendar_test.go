@@ -0,0 +1,43 @@
+package unit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2017, time.March, 15, 10, 30, 15, 0, time.UTC)
+
+	for expr, expected := range map[string]time.Time{
+		"minutely":                time.Date(2017, time.March, 15, 10, 31, 0, 0, time.UTC),
+		"hourly":                  time.Date(2017, time.March, 15, 11, 0, 0, 0, time.UTC),
+		"daily":                   time.Date(2017, time.March, 16, 0, 0, 0, 0, time.UTC),
+		"weekly":                  time.Date(2017, time.March, 20, 0, 0, 0, 0, time.UTC),
+		"monthly":                 time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC),
+		"yearly":                  time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"*-*-* 10:30:15":          time.Date(2017, time.March, 16, 10, 30, 15, 0, time.UTC),
+		"*:0/20":                  time.Date(2017, time.March, 15, 10, 40, 0, 0, time.UTC),
+		"Mon..Fri 09:00":          time.Date(2017, time.March, 16, 9, 0, 0, 0, time.UTC),
+		"Sat,Sun *-*-* 12:00":     time.Date(2017, time.March, 18, 12, 0, 0, 0, time.UTC),
+		"2017-03-15":              {},
+		"*-02-29 00:00":           time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"*-*-1,15 10..12:00":      time.Date(2017, time.March, 15, 11, 0, 0, 0, time.UTC),
+		"Fri..Mon *-*-* 00:00:00": time.Date(2017, time.March, 17, 0, 0, 0, 0, time.UTC),
+		"2016-*-* 00:00:00":       {},
+	} {
+		c, err := unit.ParseCalendar(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, expected, c.Next(now), expr)
+			assert.Equal(t, expr, c.String())
+		}
+	}
+
+	for _, expr := range []string{"", "sometimes", "*-13-01", "25:00", "*:*/0", "Mon..Xyz", "1 2 3 4"} {
+		_, err := unit.ParseCalendar(expr)
+		assert.Equal(t, unit.ErrBadCalendar, err, expr)
+	}
+}
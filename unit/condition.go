@@ -0,0 +1,258 @@
+package unit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+// Path to the file listing the mount points of the host
+var MountInfoPath = "/proc/self/mountinfo"
+
+// Conditions holds the Condition*= directives of a unit definition -- https://www.freedesktop.org/software/systemd/man/systemd.unit.html#Conditions%20and%20Asserts
+type Conditions struct {
+	ConditionPathExists         []string
+	ConditionPathExistsGlob     []string
+	ConditionPathIsDirectory    []string
+	ConditionPathIsSymbolicLink []string
+	ConditionPathIsMountPoint   []string
+	ConditionPathIsReadWrite    []string
+	ConditionDirectoryNotEmpty  []string
+	ConditionFileNotEmpty       []string
+	ConditionFileIsExecutable   []string
+	ConditionKernelCommandLine  []string
+	ConditionVirtualization     []string
+	ConditionArchitecture       []string
+	ConditionHost               []string
+	ConditionFirstBoot          []string
+}
+
+// Asserts holds the Assert*= directives of a unit definition. Assertions check the same properties
+// as conditions do, but an unmet assertion fails the start of the unit
+type Asserts struct {
+	AssertPathExists         []string
+	AssertPathExistsGlob     []string
+	AssertPathIsDirectory    []string
+	AssertPathIsSymbolicLink []string
+	AssertPathIsMountPoint   []string
+	AssertPathIsReadWrite    []string
+	AssertDirectoryNotEmpty  []string
+	AssertFileNotEmpty       []string
+	AssertFileIsExecutable   []string
+	AssertKernelCommandLine  []string
+	AssertVirtualization     []string
+	AssertArchitecture       []string
+	AssertHost               []string
+	AssertFirstBoot          []string
+}
+
+// Condition is a single condition(or assertion) check of a unit definition
+type Condition struct {
+	// Directive the condition was defined by(e.g. "ConditionPathExists" or "AssertPathExists")
+	Directive string
+
+	// Parameter of the check with prefixes removed
+	Parameter string
+
+	// Whether the condition is a triggering one("|" prefix).
+	// If triggering conditions are specified, at least one of them has to be met
+	Trigger bool
+
+	// Whether the result of the check is negated("!" prefix)
+	Negate bool
+}
+
+// NewCondition returns a condition defined by directive with value specified
+func NewCondition(directive, value string) (c Condition) {
+	c.Directive = directive
+
+	if strings.HasPrefix(value, "|") {
+		c.Trigger = true
+		value = value[1:]
+	}
+	if strings.HasPrefix(value, "!") {
+		c.Negate = true
+		value = value[1:]
+	}
+
+	c.Parameter = value
+	return
+}
+
+func (c Condition) String() (out string) {
+	out = c.Directive + "="
+	if c.Trigger {
+		out += "|"
+	}
+	if c.Negate {
+		out += "!"
+	}
+	return out + c.Parameter
+}
+
+// Met returns whether the condition is met
+func (c Condition) Met() bool {
+	// Strip "Condition" and "Assert" prefixes
+	check := strings.TrimPrefix(strings.TrimPrefix(c.Directive, "Condition"), "Assert")
+
+	test, ok := conditionChecks[check]
+	if !ok {
+		return false
+	}
+	return test(c.Parameter) != c.Negate
+}
+
+// CheckConditions checks conditions specified.
+// All non-triggering conditions and at least one of triggering ones(if any) have to be met.
+// If that is not the case, the condition responsible is returned as failed
+func CheckConditions(conds []Condition) (ok bool, failed Condition) {
+	var triggers []Condition
+	for _, c := range conds {
+		if c.Trigger {
+			triggers = append(triggers, c)
+		} else if !c.Met() {
+			return false, c
+		}
+	}
+
+	for _, c := range triggers {
+		if c.Met() {
+			return true, Condition{}
+		}
+	}
+
+	if len(triggers) > 0 {
+		// None of the triggering conditions was met
+		return false, triggers[len(triggers)-1]
+	}
+	return true, Condition{}
+}
+
+// Conditions returns the conditions as found in Definition
+func (def Definition) Conditions() []Condition {
+	return conditionsOf(def.Unit.Conditions)
+}
+
+// Asserts returns the assertions as found in Definition
+func (def Definition) Asserts() []Condition {
+	return conditionsOf(def.Unit.Asserts)
+}
+
+// conditionsOf returns conditions defined by each of the []string fields of v
+func conditionsOf(v interface{}) (conds []Condition) {
+	val := reflect.ValueOf(v)
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		if values, ok := val.Field(i).Interface().([]string); ok {
+			for _, value := range values {
+				conds = append(conds, NewCondition(typ.Field(i).Name, value))
+			}
+		}
+	}
+	return
+}
+
+// conditionChecks maps condition types to the functions performing the check
+var conditionChecks = map[string]func(string) bool{
+	"PathExists": func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	},
+	"PathExistsGlob": func(pattern string) bool {
+		matches, err := filepath.Glob(pattern)
+		return err == nil && len(matches) > 0
+	},
+	"PathIsDirectory": func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.IsDir()
+	},
+	"PathIsSymbolicLink": func(path string) bool {
+		info, err := os.Lstat(path)
+		return err == nil && info.Mode()&os.ModeSymlink != 0
+	},
+	"PathIsMountPoint": IsMountPoint,
+	"PathIsReadWrite": func(path string) bool {
+		// W_OK
+		return syscall.Access(path, 2) == nil
+	},
+	"DirectoryNotEmpty": func(path string) bool {
+		names, err := ioutil.ReadDir(path)
+		return err == nil && len(names) > 0
+	},
+	"FileNotEmpty": func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode().IsRegular() && info.Size() > 0
+	},
+	"FileIsExecutable": func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0
+	},
+	"KernelCommandLine": func(param string) bool {
+		for _, word := range KernelCommandLine() {
+			// Either the whole word or the key of an assignment has to match
+			if word == param || !strings.Contains(param, "=") && strings.SplitN(word, "=", 2)[0] == param {
+				return true
+			}
+		}
+		return false
+	},
+	"Virtualization": func(param string) bool {
+		id, container := Virtualization()
+
+		if b, err := ParseBool(param); err == nil {
+			return (id != "") == b
+		}
+
+		switch param {
+		case "vm":
+			return id != "" && !container
+		case "container":
+			return container
+		default:
+			return id == param
+		}
+	},
+	"Architecture": func(param string) bool {
+		return param == "native" || param == Architecture()
+	},
+	"Host": func(param string) bool {
+		if ok, _ := filepath.Match(param, Hostname()); ok {
+			return true
+		}
+		return param == MachineID()
+	},
+	"FirstBoot": func(param string) bool {
+		b, err := ParseBool(param)
+		return err == nil && b == FirstBoot()
+	},
+}
+
+// IsMountPoint returns whether path is a mount point
+func IsMountPoint(path string) bool {
+	b, err := ioutil.ReadFile(MountInfoPath)
+	if err != nil {
+		return false
+	}
+
+	path = filepath.Clean(path)
+	for _, line := range strings.Split(string(b), "\n") {
+		// Mount point is the fifth field
+		if fields := strings.Fields(line); len(fields) > 4 && unescapeMountPath(fields[4]) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeMountPath undoes the octal escaping of whitespace in mount paths
+func unescapeMountPath(path string) string {
+	for _, c := range " \t\n\\" {
+		path = strings.Replace(path, fmt.Sprintf("\\%03o", c), string(c), -1)
+	}
+	return path
+}
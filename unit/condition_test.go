@@ -0,0 +1,153 @@
+package unit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCondition(t *testing.T) {
+	for value, expected := range map[string]unit.Condition{
+		"/foo":   {"ConditionPathExists", "/foo", false, false},
+		"!/foo":  {"ConditionPathExists", "/foo", false, true},
+		"|/foo":  {"ConditionPathExists", "/foo", true, false},
+		"|!/foo": {"ConditionPathExists", "/foo", true, true},
+	} {
+		c := unit.NewCondition("ConditionPathExists", value)
+		assert.Equal(t, expected, c, value)
+		assert.Equal(t, "ConditionPathExists="+value, c.String(), value)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0644), "ioutil.WriteFile")
+
+	exe := filepath.Join(dir, "exe")
+	require.NoError(t, ioutil.WriteFile(exe, []byte("#!/bin/sh"), 0755), "ioutil.WriteFile")
+
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Symlink(exe, link), "os.Symlink")
+
+	missing := filepath.Join(dir, "missing")
+
+	for directive, cases := range map[string]map[string]bool{
+		"ConditionPathExists": {
+			exe:           true,
+			missing:       false,
+			"!" + exe:     false,
+			"!" + missing: true,
+		},
+		"ConditionPathExistsGlob": {
+			filepath.Join(dir, "e*"): true,
+			filepath.Join(dir, "m*"): false,
+		},
+		"ConditionPathIsDirectory": {
+			dir: true,
+			exe: false,
+		},
+		"ConditionPathIsSymbolicLink": {
+			link: true,
+			exe:  false,
+		},
+		"ConditionDirectoryNotEmpty": {
+			dir:     true,
+			missing: false,
+		},
+		"ConditionFileNotEmpty": {
+			exe:   true,
+			empty: false,
+		},
+		"ConditionFileIsExecutable": {
+			exe:   true,
+			empty: false,
+		},
+		"ConditionArchitecture": {
+			"native":            true,
+			unit.Architecture(): true,
+			"foo":               false,
+		},
+		"ConditionHost": {
+			unit.Hostname(): true,
+			"!foo-host":     true,
+		},
+		"ConditionFirstBoot": {
+			"yes":  unit.FirstBoot(),
+			"no":   !unit.FirstBoot(),
+			"!yes": !unit.FirstBoot(),
+			"foo":  false,
+		},
+	} {
+		for value, expected := range cases {
+			c := unit.NewCondition(directive, value)
+			assert.Equal(t, expected, c.Met(), c.String())
+		}
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	exists := "ConditionPathExists=" + dir
+	missing := "ConditionPathExists=" + filepath.Join(dir, "missing")
+
+	for _, c := range []struct {
+		conditions []string
+		met        bool
+		failed     string
+	}{
+		{nil, true, ""},
+		{[]string{exists}, true, ""},
+		{[]string{exists, missing}, false, missing},
+		{[]string{exists, "ConditionPathExists=|" + dir, "ConditionPathExists=|" + filepath.Join(dir, "missing")}, true, ""},
+		{[]string{"ConditionPathExists=|" + filepath.Join(dir, "missing")}, false, "ConditionPathExists=|" + filepath.Join(dir, "missing")},
+	} {
+		conds := make([]unit.Condition, len(c.conditions))
+		for i, cond := range c.conditions {
+			kv := strings.SplitN(cond, "=", 2)
+			conds[i] = unit.NewCondition(kv[0], kv[1])
+		}
+
+		met, failed := unit.CheckConditions(conds)
+		if assert.Equal(t, c.met, met, "%v", c.conditions) && !met {
+			assert.Equal(t, c.failed, failed.String(), "%v", c.conditions)
+		}
+	}
+}
+
+func TestDefinitionConditions(t *testing.T) {
+	def := unit.Definition{}
+	require.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+ConditionPathExists=/foo
+ConditionPathExists=|!/bar
+ConditionHost=foo`), &def), "unit.ParseDefinition")
+
+	assert.Equal(t, []unit.Condition{
+		{"ConditionPathExists", "/foo", false, false},
+		{"ConditionPathExists", "/bar", true, true},
+		{"ConditionHost", "foo", false, false},
+	}, def.Conditions())
+}
+
+func TestDefinitionAsserts(t *testing.T) {
+	def := unit.Definition{}
+	require.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+AssertPathExists=!/foo
+ConditionPathExists=/bar`), &def), "unit.ParseDefinition")
+
+	assert.Equal(t, []unit.Condition{
+		{"AssertPathExists", "/foo", false, true},
+	}, def.Asserts())
+}
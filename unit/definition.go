@@ -1,13 +1,11 @@
 package unit
 
 import (
-	"errors"
 	"io"
 	"reflect"
 	"strconv"
 	"strings"
-
-	"github.com/coreos/go-systemd/unit"
+	"time"
 )
 
 // Definition of a unit matching the fields found in unit-file
@@ -16,9 +14,33 @@ type Definition struct {
 		Description                               string
 		Documentation                             string
 		Wants, Requires, Conflicts, Before, After []string
+		BindsTo, Requisite, PartOf                []string
+
+		PropagatesReloadTo, ReloadPropagatedFrom []string
+
+		DefaultDependencies bool
+		IgnoreOnIsolate     bool
+		StopWhenUnneeded    bool
+		CollectMode         string
+
+		RefuseManualStart, RefuseManualStop bool
+
+		JobTimeoutSec, JobRunningTimeoutSec time.Duration
+		JobTimeoutAction                    string
+
+		StartLimitIntervalSec time.Duration
+		StartLimitBurst       int
+
+		OnFailure, OnSuccess               []string
+		OnFailureJobMode, OnSuccessJobMode string
+
+		RequiresMountsFor []string
+
+		Conditions
+		Asserts
 	}
 	Install struct {
-		WantedBy, RequiredBy []string
+		WantedBy, RequiredBy, Alias, Also []string
 	}
 }
 
@@ -47,6 +69,31 @@ func (def Definition) Conflicts() []string {
 	return def.Unit.Conflicts
 }
 
+// BindsTo returns a slice of unit names as found in Definition
+func (def Definition) BindsTo() []string {
+	return def.Unit.BindsTo
+}
+
+// Requisite returns a slice of unit names as found in Definition
+func (def Definition) Requisite() []string {
+	return def.Unit.Requisite
+}
+
+// PartOf returns a slice of unit names as found in Definition
+func (def Definition) PartOf() []string {
+	return def.Unit.PartOf
+}
+
+// PropagatesReloadTo returns a slice of unit names as found in Definition
+func (def Definition) PropagatesReloadTo() []string {
+	return def.Unit.PropagatesReloadTo
+}
+
+// ReloadPropagatedFrom returns a slice of unit names as found in Definition
+func (def Definition) ReloadPropagatedFrom() []string {
+	return def.Unit.ReloadPropagatedFrom
+}
+
 // After returns a slice of unit names as found in Definition
 func (def Definition) After() []string {
 	return def.Unit.After
@@ -57,6 +104,86 @@ func (def Definition) Before() []string {
 	return def.Unit.Before
 }
 
+// DefaultDependencies returns a bool as found in Definition
+func (def Definition) DefaultDependencies() bool {
+	return def.Unit.DefaultDependencies
+}
+
+// IgnoreOnIsolate returns a bool as found in Definition
+func (def Definition) IgnoreOnIsolate() bool {
+	return def.Unit.IgnoreOnIsolate
+}
+
+// StopWhenUnneeded returns a bool as found in Definition
+func (def Definition) StopWhenUnneeded() bool {
+	return def.Unit.StopWhenUnneeded
+}
+
+// CollectMode returns a string as found in Definition
+func (def Definition) CollectMode() string {
+	return def.Unit.CollectMode
+}
+
+// RefuseManualStart returns a bool as found in Definition
+func (def Definition) RefuseManualStart() bool {
+	return def.Unit.RefuseManualStart
+}
+
+// RefuseManualStop returns a bool as found in Definition
+func (def Definition) RefuseManualStop() bool {
+	return def.Unit.RefuseManualStop
+}
+
+// JobTimeoutSec returns a time.Duration as found in Definition
+func (def Definition) JobTimeoutSec() time.Duration {
+	return def.Unit.JobTimeoutSec
+}
+
+// JobRunningTimeoutSec returns a time.Duration as found in Definition
+func (def Definition) JobRunningTimeoutSec() time.Duration {
+	return def.Unit.JobRunningTimeoutSec
+}
+
+// JobTimeoutAction returns a string as found in Definition
+func (def Definition) JobTimeoutAction() string {
+	return def.Unit.JobTimeoutAction
+}
+
+// StartLimitIntervalSec returns a time.Duration as found in Definition
+func (def Definition) StartLimitIntervalSec() time.Duration {
+	return def.Unit.StartLimitIntervalSec
+}
+
+// StartLimitBurst returns an int as found in Definition
+func (def Definition) StartLimitBurst() int {
+	return def.Unit.StartLimitBurst
+}
+
+// OnFailure returns a slice of unit names as found in Definition
+func (def Definition) OnFailure() []string {
+	return def.Unit.OnFailure
+}
+
+// OnFailureJobMode returns a string as found in Definition
+func (def Definition) OnFailureJobMode() string {
+	return def.Unit.OnFailureJobMode
+}
+
+// OnSuccess returns a slice of unit names as found in Definition
+func (def Definition) OnSuccess() []string {
+	return def.Unit.OnSuccess
+}
+
+// OnSuccessJobMode returns a string as found in Definition
+func (def Definition) OnSuccessJobMode() string {
+	return def.Unit.OnSuccessJobMode
+}
+
+// RequiresMountsFor returns a slice of absolute paths as found in Definition
+func (def Definition) RequiresMountsFor() []string {
+	return def.Unit.RequiresMountsFor
+}
+
 // RequiredBy returns a slice of unit names as found in Definition
 func (def Definition) RequiredBy() []string {
 	return def.Install.RequiredBy
@@ -67,7 +194,19 @@ func (def Definition) WantedBy() []string {
 	return def.Install.WantedBy
 }
 
-// ParseDefinition parses the data in Systemd unit-file format and stores the result in value pointed by Definition
+// Alias returns a slice of unit names as found in Definition
+func (def Definition) Alias() []string {
+	return def.Install.Alias
+}
+
+// Also returns a slice of unit names as found in Definition
+func (def Definition) Also() []string {
+	return def.Install.Also
+}
+
+// ParseDefinition parses the data in Systemd unit-file format and stores the result in value pointed by Definition.
+// Every problem encountered is reported by a ParseError carrying the position of the option in the MultiError returned.
+// Options, which do not match any field, are ignored. If those are the only problems found, Warnings are returned
 func ParseDefinition(r io.Reader, v interface{}) (err error) {
 	// Access the underlying value of the pointer
 	def := reflect.ValueOf(v).Elem()
@@ -76,54 +215,126 @@ func ParseDefinition(r io.Reader, v interface{}) (err error) {
 		return ErrWrongVal
 	}
 
+	merr := MultiError{}
+
 	// Deserialized options
-	var opts []*unit.UnitOption
-	if opts, err = unit.Deserialize(r); err != nil {
-		return
+	var opts Options
+	if or, ok := r.(*optionsReader); ok {
+		opts = or.opts
+	} else if opts, err = Deserialize(r); err != nil {
+		me, ok := err.(MultiError)
+		if !ok {
+			return
+		}
+		merr = append(merr, me...)
 	}
 
 	// Loop over deserialized options trying to match them to the ones as found in Definition
+	warnings := Warnings{}
 	for _, opt := range opts {
-		if v := def.FieldByName(opt.Section); v.IsValid() && v.CanSet() {
-			if v := v.FieldByName(opt.Name); v.IsValid() && v.CanSet() {
-				// reflect.Kind of field in Definition
-				switch v.Kind() {
-
-				case reflect.String:
-					v.SetString(opt.Value)
-
-				case reflect.Bool:
-					if opt.Value == "yes" {
-						v.SetBool(true)
-					} else if opt.Value != "no" {
-						return ParseErr(opt.Name, errors.New(`Value should be "yes" or "no"`))
-					}
-
-				case reflect.Slice:
-					if _, ok := v.Interface().([]string); ok { // []string
-						v.Set(reflect.ValueOf(strings.Fields(opt.Value)))
-
-					} else if _, ok := v.Interface().([]int); ok { // []int
-						ints := []int{}
-						for _, val := range strings.Fields(opt.Value) {
-							if converted, err := strconv.Atoi(val); err == nil {
-								ints = append(ints, converted)
-							} else {
-								return ParseErr(opt.Name, err)
-							}
-						}
-						v.Set(reflect.ValueOf(ints))
-					}
-
-				default:
-					return ParseErr(opt.Name, ErrUnknownType)
+		switch err = setOption(def, opt); err {
+		case nil:
+		case ErrUnknownDirective:
+			warnings = append(warnings, opt.Err(err))
+		default:
+			merr = append(merr, opt.Err(err))
+		}
+	}
+
+	switch {
+	case len(merr) > 0:
+		for _, w := range warnings {
+			merr = append(merr, w)
+		}
+		return merr
+	case len(warnings) > 0:
+		return warnings
+	default:
+		return nil
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setOption sets the field of def matching opt to the value of opt
+func setOption(def reflect.Value, opt *Option) error {
+	// Section headers are matched regardless of case
+	v := def.FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, opt.Section)
+	})
+	if !v.IsValid() || !v.CanSet() {
+		return ErrUnknownDirective
+	}
+
+	if v = v.FieldByName(opt.Name); !v.IsValid() || !v.CanSet() {
+		return ErrUnknownDirective
+	}
+
+	// reflect.Kind of field in Definition
+	switch v.Kind() {
+
+	case reflect.String:
+		v.SetString(opt.Value)
+
+	case reflect.Bool:
+		b, err := ParseBool(opt.Value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int:
+		i, err := strconv.Atoi(strings.TrimSpace(opt.Value))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+
+	case reflect.Int64:
+		if v.Type() != durationType {
+			return ErrUnknownType
+		}
+
+		d, err := ParseTimespan(opt.Value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+
+	case reflect.Uint64:
+		size, err := ParseSize(opt.Value)
+		if err != nil {
+			return err
+		}
+		v.SetUint(size)
+
+	case reflect.Slice:
+		// Values of a repeated option get appended, an empty assignment resets the list
+		if strings.TrimSpace(opt.Value) == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		if strs, ok := v.Interface().([]string); ok { // []string
+			values, err := SplitQuoted(opt.Value)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(append(strs, values...)))
+
+		} else if ints, ok := v.Interface().([]int); ok { // []int
+			for _, val := range strings.Fields(opt.Value) {
+				converted, err := strconv.Atoi(val)
+				if err != nil {
+					return err
 				}
-			} else {
-				return ParseErr(opt.Name, ErrNotExist)
+				ints = append(ints, converted)
 			}
-		} else {
-			return ParseErr(opt.Name, ErrNotExist)
+			v.Set(reflect.ValueOf(ints))
 		}
+
+	default:
+		return ErrUnknownType
 	}
-	return
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/plasma-umass/systemgo/unit"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,8 @@ import (
 var DEFAULT_INTS = []int{1, 2, 3}
 
 const DEFAULT_BOOL = true
+const DEFAULT_DURATION = 90 * time.Second
+const DEFAULT_INT = 42
 const DEFAULT_UNIT = `[Unit]
 Description=Description
 Documentation=Documentation
@@ -22,10 +25,37 @@ Requires=Requires
 Conflicts=Conflicts
 Before=Before
 After=After
+BindsTo=BindsTo
+Requisite=Requisite
+PartOf=PartOf
+PropagatesReloadTo=PropagatesReloadTo
+ReloadPropagatedFrom=ReloadPropagatedFrom
+
+DefaultDependencies=yes
+IgnoreOnIsolate=yes
+StopWhenUnneeded=yes
+RefuseManualStart=yes
+RefuseManualStop=yes
+CollectMode=CollectMode
+
+JobTimeoutSec=90s
+JobRunningTimeoutSec=1min 30s
+JobTimeoutAction=JobTimeoutAction
+
+StartLimitIntervalSec=90s
+StartLimitBurst=42
+
+OnFailure=OnFailure
+OnFailureJobMode=OnFailureJobMode
+OnSuccess=OnSuccess
+OnSuccessJobMode=OnSuccessJobMode
+RequiresMountsFor=RequiresMountsFor
 
 [Install]
 WantedBy=WantedBy
-RequiredBy=RequiredBy`
+RequiredBy=RequiredBy
+Alias=Alias
+Also=Also`
 
 func TestParseDefinition(t *testing.T) {
 	cases := []struct {
@@ -119,6 +149,18 @@ Bool=foo`),
 						m := methodByName(defVal, option.Name).(func() bool)
 						assert.Equal(t, m(), DEFAULT_BOOL, "bool getter")
 					}
+				case reflect.Int:
+					assert.Equal(t, option.Int(), int64(DEFAULT_INT), "int")
+
+					m := methodByName(defVal, option.Name).(func() int)
+					assert.Equal(t, m(), DEFAULT_INT, "int getter")
+				case reflect.Int64:
+					if d, ok := interfaceOf(option.Value).(time.Duration); ok {
+						assert.Equal(t, d, DEFAULT_DURATION, "time.Duration")
+
+						m := methodByName(defVal, option.Name).(func() time.Duration)
+						assert.Equal(t, m(), DEFAULT_DURATION, "time.Duration getter")
+					}
 				case reflect.Slice:
 					if slice, ok := interfaceOf(option.Value).([]string); ok {
 						expect := []string{option.Name}
@@ -149,3 +191,37 @@ func interfaceOf(val reflect.Value) interface{} {
 func methodByName(val reflect.Value, name string) interface{} {
 	return interfaceOf(val.MethodByName(name))
 }
+
+func TestParseDefinitionOverride(t *testing.T) {
+	def := &struct {
+		unit.Definition
+		Test struct {
+			Ints []int
+			Bool bool
+		}
+	}{}
+
+	err := unit.ParseDefinition(strings.NewReader(DEFAULT_UNIT+`
+[Unit]
+After=Other
+[Test]
+Ints=1 2
+Bool=yes
+
+[Unit]
+Wants=
+Wants=Overridden
+Description=Overridden
+[Test]
+Ints=3
+Bool=no`), def)
+	if !assert.NoError(t, err, "ParseDefinition") {
+		return
+	}
+
+	assert.Equal(t, "Overridden", def.Description(), "string")
+	assert.Equal(t, []string{"Overridden"}, def.Wants(), "reset []string")
+	assert.Equal(t, []string{"After", "Other"}, def.After(), "appended []string")
+	assert.Equal(t, DEFAULT_INTS, def.Test.Ints, "appended []int")
+	assert.False(t, def.Test.Bool, "bool")
+}
@@ -13,10 +13,26 @@ var ErrPathNotAbs = errors.New("Path specified is not absolute")
 var ErrNotParsed = errors.New("Unit definition is not parsed properly")
 var ErrWrongVal = errors.New("Wrong value received")
 var ErrNotStarted = errors.New("Unit not started")
+var ErrUnknownSpecifier = errors.New("Unknown specifier")
+var ErrNoSection = errors.New("Assignment outside of a section")
+var ErrNoAssignment = errors.New("Line is not an assignment")
+var ErrBadSection = errors.New("Invalid section header")
+var ErrUnknownDirective = errors.New("Unknown directive, ignoring")
+var ErrBadBool = errors.New(`Value should be one of "yes", "no", "on", "off", "true", "false", "1" or "0"`)
+var ErrBadTimespan = errors.New("Invalid time span")
+var ErrBadSize = errors.New("Invalid size")
+var ErrBadQuota = errors.New("Invalid CPU quota, should be a percentage")
+var ErrBadQuoting = errors.New("Unbalanced quotes or trailing backslash")
+var ErrBadCalendar = errors.New("Invalid calendar event expression")
 
+// ParseError describes a problem found in a unit definition
 type ParseError struct {
 	Source string
 	Err    error
+
+	// Position of the problem, if known
+	File string
+	Line int
 }
 
 func ParseErr(source string, err error) ParseError {
@@ -27,7 +43,16 @@ func ParseErr(source string, err error) ParseError {
 }
 
 func (err ParseError) Error() string {
-	return fmt.Sprintf("%s: %s", err.Source, err.Err)
+	msg := fmt.Sprintf("%s: %s", err.Source, err.Err)
+
+	switch {
+	case err.File != "" && err.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", err.File, err.Line, msg)
+	case err.Line > 0:
+		return fmt.Sprintf("line %d: %s", err.Line, msg)
+	default:
+		return msg
+	}
 }
 
 type MultiError []error
@@ -46,3 +71,28 @@ func (m MultiError) Error() string {
 	}
 	return fmt.Sprintf("%d errors encountered, first: %s", len(m), m[0])
 }
+
+// Warnings is a list of non-fatal problems found in a unit definition,
+// e.g. unknown directives, which get ignored
+type Warnings []ParseError
+
+func (w Warnings) Errors() (errs []string) {
+	errs = make([]string, len(w))
+	for i, err := range w {
+		errs[i] = err.Error()
+	}
+	return
+}
+
+func (w Warnings) Error() string {
+	if len(w) == 0 {
+		return "No warnings"
+	}
+	return fmt.Sprintf("%d warnings encountered, first: %s", len(w), w[0])
+}
+
+// IsWarning returns whether err only consists of Warnings
+func IsWarning(err error) bool {
+	_, ok := err.(Warnings)
+	return ok
+}
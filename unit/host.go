@@ -0,0 +1,148 @@
+package unit
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Paths to files describing the host
+var (
+	// Machine ID, as read by MachineID
+	MachineIDPath = "/etc/machine-id"
+
+	// ID of the current boot, as read by BootID
+	BootIDPath = "/proc/sys/kernel/random/boot_id"
+
+	// Release of the running kernel, as read by KernelRelease
+	KernelReleasePath = "/proc/sys/kernel/osrelease"
+
+	// Kernel command line, as read by KernelCommandLine
+	KernelCmdlinePath = "/proc/cmdline"
+
+	// os-release files in order of precedence, the first one found is read by OSRelease
+	OSReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+	// Created at boot, if the machine ID was not initialized, see FirstBoot
+	FirstBootPath = "/run/systemgo/first-boot"
+)
+
+// Directories of the system manager, which the %t, %S, %C, %L, %E, %T and %V specifiers expand to respectively
+var (
+	RuntimeDir = "/run"
+	StateDir   = "/var/lib"
+	CacheDir   = "/var/cache"
+	LogsDir    = "/var/log"
+	ConfigDir  = "/etc"
+	TempDir    = "/tmp"
+	VarTempDir = "/var/tmp"
+)
+
+// architectures maps values of runtime.GOARCH to architecture names used by Systemd
+var architectures = map[string]string{
+	"386":      "x86",
+	"amd64":    "x86-64",
+	"arm":      "arm",
+	"arm64":    "arm64",
+	"mips":     "mips",
+	"mipsle":   "mips-le",
+	"mips64":   "mips64",
+	"mips64le": "mips64-le",
+	"ppc64":    "ppc64",
+	"ppc64le":  "ppc64-le",
+	"riscv64":  "riscv64",
+	"s390x":    "s390x",
+}
+
+// Architecture returns the architecture of the host as named by Systemd(e.g. "x86-64"), which
+// ConditionArchitecture= and the %a specifier use. runtime.GOARCH is returned for the ones Systemd has no name for
+func Architecture() string {
+	if arch, ok := architectures[runtime.GOARCH]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+// Hostname returns the hostname of the host or an empty string, if it can not be determined
+func Hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// MachineID returns the machine ID of the host or an empty string, if it is not set
+func MachineID() string {
+	if id := readLine(MachineIDPath); id != "uninitialized" {
+		return id
+	}
+	return ""
+}
+
+// FirstBoot reports whether the host is booted for the first time, that is, the machine ID
+// was missing, empty or "uninitialized" when the init process started
+func FirstBoot() bool {
+	_, err := os.Stat(FirstBootPath)
+	return err == nil
+}
+
+// BootID returns the ID of the current boot with dashes removed
+// or an empty string, if it can not be determined
+func BootID() string {
+	return strings.Replace(readLine(BootIDPath), "-", "", -1)
+}
+
+// KernelRelease returns the release of the running kernel(as reported by "uname -r")
+// or an empty string, if it can not be determined
+func KernelRelease() string {
+	return readLine(KernelReleasePath)
+}
+
+// KernelCommandLine returns the words of the kernel command line the host was booted with,
+// nil if it can not be read. Quoted words containing spaces are not supported
+func KernelCommandLine() []string {
+	b, err := ioutil.ReadFile(KernelCmdlinePath)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(b))
+}
+
+// OSRelease returns the key-value pairs found in the first os-release file of the host found in OSReleasePaths
+// with the quotes around the values removed. The map returned is empty, if there is none
+func OSRelease() (release map[string]string) {
+	release = map[string]string{}
+
+	for _, path := range OSReleasePaths {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			release[kv[0]] = strings.Trim(kv[1], `"'`)
+		}
+		return
+	}
+	return
+}
+
+// readLine returns the first line of file found at path with surrounding whitespace removed
+func readLine(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+}
@@ -1,6 +1,12 @@
 package unit
 
-import "io"
+import (
+	"context"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
 
 type Interface interface {
 	Definer
@@ -12,6 +18,8 @@ type Interface interface {
 	Dependency
 }
 
+// Definer is implemented by any value that has a Define method.
+// Define may return Warnings, in which case the definition is usable nonetheless
 type Definer interface {
 	Define(io.Reader) error
 }
@@ -38,6 +46,18 @@ type Stopper interface {
 	Stop() error
 }
 
+// ContextStarter is implemented by any value that has a StartContext method.
+// Starting is interrupted, once the context is done
+type ContextStarter interface {
+	StartContext(context.Context) error
+}
+
+// ContextStopper is implemented by any value that has a StopContext method.
+// Stopping is interrupted, once the context is done
+type ContextStopper interface {
+	StopContext(context.Context) error
+}
+
 // Reloader is implemented by any value capable of reloading itself(or its definition)
 type Reloader interface {
 	Reload() error
@@ -55,3 +75,213 @@ type Dependency interface {
 	After() []string
 	Before() []string
 }
+
+// Defaulter is implemented by any value that has a DefaultDependencies method
+type Defaulter interface {
+	DefaultDependencies() bool
+}
+
+// MountRequirer is implemented by any value that has a RequiresMountsFor method.
+// RequiresMountsFor returns the absolute paths the value accesses, the mount units of which it requires and is ordered after
+type MountRequirer interface {
+	RequiresMountsFor() []string
+}
+
+// Isolator is implemented by any value that has an IgnoreOnIsolate method
+type Isolator interface {
+	IgnoreOnIsolate() bool
+}
+
+// Unneeder is implemented by any value that has a StopWhenUnneeded method
+type Unneeder interface {
+	StopWhenUnneeded() bool
+}
+
+// Collector is implemented by any value that has a CollectMode method.
+// CollectMode returns whether the value gets unloaded once inactive("inactive") or failed as well("inactive-or-failed")
+type Collector interface {
+	CollectMode() string
+}
+
+// ManualRefuser is implemented by any value that has RefuseManualStart and RefuseManualStop methods
+type ManualRefuser interface {
+	RefuseManualStart() bool
+	RefuseManualStop() bool
+}
+
+// JobTimeouter is implemented by any value that has JobTimeoutSec, JobRunningTimeoutSec and JobTimeoutAction methods
+type JobTimeouter interface {
+	JobTimeoutSec() time.Duration
+	JobRunningTimeoutSec() time.Duration
+	JobTimeoutAction() string
+}
+
+// StartLimiter is implemented by any value that has StartLimitIntervalSec and StartLimitBurst methods
+type StartLimiter interface {
+	StartLimitIntervalSec() time.Duration
+	StartLimitBurst() int
+}
+
+// LogRateLimiter is implemented by any value that has LogRateLimitIntervalSec and LogRateLimitBurst methods.
+// The output of the processes the value starts is limited to LogRateLimitBurst lines within LogRateLimitIntervalSec
+type LogRateLimiter interface {
+	LogRateLimitIntervalSec() time.Duration
+	LogRateLimitBurst() int
+}
+
+// Attacher is implemented by any value that has MainPID and Attach methods.
+// Attach makes the value supervise an already running process, e.g. one started before re-execution
+type Attacher interface {
+	MainPID() int
+	Attach(pid int) error
+}
+
+// Controller is implemented by any value that has a ControlPID method.
+// ControlPID returns the PID of the control process running(e.g. ExecStartPre= command) or 0, if there is none
+type Controller interface {
+	ControlPID() int
+}
+
+// Exiter is implemented by any value that has Owns and Exited methods.
+// Exited is called by the manager with the wait status of every process reaped, which the value Owns
+type Exiter interface {
+	Owns(pid int) bool
+	Exited(pid int, status syscall.WaitStatus)
+}
+
+// ExitCoder is implemented by any value that has an ExitCode method.
+// ExitCode returns the exit code of the main process, -1 if it has not exited normally
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// AutoRestarter is implemented by any value that has AutoRestart and RestartSec methods.
+// AutoRestart reports whether the main process, which has exited, is to be restarted after RestartSec
+type AutoRestarter interface {
+	AutoRestart() bool
+	RestartSec() time.Duration
+}
+
+// StartTimeouter is implemented by any value that has a TimeoutStartSec method.
+// TimeoutStartSec returns the time the value gets to start, 0 if unlimited
+type StartTimeouter interface {
+	TimeoutStartSec() time.Duration
+}
+
+// Idler is implemented by any value that has an Idle method.
+// Idle returns whether the start of the value is delayed until the other jobs have finished
+type Idler interface {
+	Idle() bool
+}
+
+// BusNamer is implemented by any value that has a BusName method.
+// BusName returns the name on the message bus the value is active once it has been acquired, "" if none
+type BusNamer interface {
+	BusName() string
+}
+
+// ResourceController is implemented by any value that has a Resources method
+type ResourceController interface {
+	Resources() Resources
+}
+
+// Outputter is implemented by any value that has a SetOutput method.
+// SetOutput makes the standard output and error of the processes the value starts get written to the writers specified
+type Outputter interface {
+	SetOutput(stdout, stderr io.Writer)
+}
+
+// Slicer is implemented by any value that has a Slice method.
+// Slice returns the name of the slice(e.g. "system.slice") the cgroup of the value is created in, if any
+type Slicer interface {
+	Slice() string
+}
+
+// Grouper is implemented by any value that has a Processes method.
+// Processes returns the PIDs of the running processes grouped, which were not started by the value, e.g. those of a scope
+type Grouper interface {
+	Processes() []int
+}
+
+// Activator is implemented by any value that has a Triggers method.
+// Triggers returns the name of the unit the value starts, e.g. the one started by a timer elapsing
+type Activator interface {
+	Triggers() string
+}
+
+// PropertySetter is implemented by any value that has a SetProperty method.
+// SetProperty changes a directive of the definition at runtime
+type PropertySetter interface {
+	SetProperty(name, value string) error
+}
+
+// Auditor is implemented by any value that has a Security method.
+// Security returns the results of the security checks of the value
+type Auditor interface {
+	Security() []SecurityCheck
+}
+
+// FailedResetter is implemented by any value that has a ResetFailed method.
+// ResetFailed makes a failed value inactive
+type FailedResetter interface {
+	ResetFailed()
+}
+
+// Binder is implemented by any value that has BindsTo, Requisite and PartOf methods
+type Binder interface {
+	BindsTo() []string
+	Requisite() []string
+	PartOf() []string
+}
+
+// ReloadPropagator is implemented by any value that has PropagatesReloadTo and ReloadPropagatedFrom methods
+type ReloadPropagator interface {
+	PropagatesReloadTo() []string
+	ReloadPropagatedFrom() []string
+}
+
+// Triggerer is implemented by any value that has OnFailure and OnSuccess methods
+type Triggerer interface {
+	OnFailure() []string
+	OnFailureJobMode() string
+
+	OnSuccess() []string
+	OnSuccessJobMode() string
+}
+
+// Installer is implemented by any value that has Alias and Also methods
+type Installer interface {
+	Alias() []string
+	Also() []string
+}
+
+// Conditioner is implemented by any value that has a Conditions method
+type Conditioner interface {
+	Conditions() []Condition
+}
+
+// Asserter is implemented by any value that has an Asserts method
+type Asserter interface {
+	Asserts() []Condition
+}
+
+// SocketUser is implemented by any value that has a SetSockets method.
+// SetSockets makes the value pass the listening sockets to the processes it starts from now on, as socket activation does
+type SocketUser interface {
+	SetSockets(files []*os.File)
+}
+
+// Listener is implemented by any value that has Files and Adopt methods.
+// Files returns the listening sockets opened, nil if there are none. Adopt makes the value listen on files
+// opened already instead of opening the sockets anew, e.g. the ones passed across re-execution
+type Listener interface {
+	Files() []*os.File
+	Adopt(files []*os.File)
+}
+
+// UtmpRecorder is implemented by any value that has a Utmp method.
+// Utmp returns the identifier(e.g. "tty1") the main process is recorded in utmp with, empty if it is not recorded,
+// and the terminal it runs on
+type UtmpRecorder interface {
+	Utmp() (id, line string)
+}
@@ -0,0 +1,134 @@
+// Package mount defines a mount unit type, which mounts a file system at a path
+package mount
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasma-umass/systemgo/pid1"
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+const (
+	dead    = "dead"
+	mounted = "mounted"
+)
+
+// Commands run to mount and unmount the file systems
+var (
+	MOUNT_COMMAND  = "mount"
+	UMOUNT_COMMAND = "umount"
+)
+
+// Mount unit
+type Unit struct {
+	Definition
+}
+
+// Mount unit definition
+type Definition struct {
+	unit.Definition
+	Mount struct {
+		// File system mounted, e.g. a device node, and the absolute path it is mounted at
+		What, Where string
+
+		// File system type and mount options as passed to mount(8), optional
+		Type, Options string
+	}
+}
+
+// Define attempts to fill the m definition by parsing r
+func (m *Unit) Define(r io.Reader) (err error) {
+	def := Definition{}
+	def.Unit.DefaultDependencies = true
+
+	var warnings error
+	if err = unit.ParseDefinition(r, &def); unit.IsWarning(err) {
+		warnings = err
+	} else if err != nil {
+		return
+	}
+
+	switch {
+	case def.Mount.What == "":
+		return unit.ParseErr("What", unit.ErrNotSet)
+	case def.Mount.Where == "":
+		return unit.ParseErr("Where", unit.ErrNotSet)
+	case !filepath.IsAbs(def.Mount.Where):
+		return unit.ParseErr("Where", unit.ParseErr(def.Mount.Where, unit.ErrWrongVal))
+	}
+
+	m.Definition = def
+	return warnings
+}
+
+// StartContext mounts the file system, unless it is mounted already
+func (m *Unit) StartContext(ctx context.Context) error {
+	if m.Active() == unit.Active {
+		return nil
+	}
+
+	args := []string{m.Definition.Mount.What, m.Definition.Mount.Where}
+	if opts := m.Definition.Mount.Options; opts != "" {
+		args = append([]string{"-o", opts}, args...)
+	}
+	if typ := m.Definition.Mount.Type; typ != "" {
+		args = append([]string{"-t", typ}, args...)
+	}
+	return run(ctx, MOUNT_COMMAND, args...)
+}
+
+// StopContext unmounts the file system, unless it is not mounted
+func (m *Unit) StopContext(ctx context.Context) error {
+	if m.Active() != unit.Active {
+		return nil
+	}
+	return run(ctx, UMOUNT_COMMAND, m.Definition.Mount.Where)
+}
+
+// run runs the command name with args killing it, if ctx is done before it has finished.
+// The output of the command is included in the error returned
+func run(ctx context.Context, name string, args ...string) error {
+	out, err := pid1.CombinedOutput(exec.CommandContext(ctx, name, args...))
+	if err != nil {
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			return fmt.Errorf("%s: %s", err, out)
+		}
+	}
+	return err
+}
+
+// RequiresMountsFor returns the paths as found in the definition of m followed by the parent of the mount point,
+// so that the file systems get mounted at the paths they are nested in first
+func (m *Unit) RequiresMountsFor() (paths []string) {
+	paths = append(paths, m.Definition.Unit.RequiresMountsFor...)
+	if where := filepath.Clean(m.Definition.Mount.Where); where != "/" {
+		paths = append(paths, filepath.Dir(where))
+	}
+	if what := m.Definition.Mount.What; filepath.IsAbs(what) && !strings.HasPrefix(what, "/dev/") {
+		// Bind mount source
+		paths = append(paths, what)
+	}
+	return
+}
+
+// Active returns activation status of the unit, m is active as long as the file system is mounted
+func (m *Unit) Active() unit.Activation {
+	if m.Definition.Mount.Where != "" && unit.IsMountPoint(m.Definition.Mount.Where) {
+		return unit.Active
+	}
+	return unit.Inactive
+}
+
+// Sub reports the sub status of a mount
+func (m *Unit) Sub() string {
+	if m.Active() == unit.Active {
+		return mounted
+	}
+	return dead
+}
@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Suffixes of the unit types known to systemd, whether supported or not
+var types = map[string]bool{
+	".service":   true,
+	".socket":    true,
+	".target":    true,
+	".device":    true,
+	".mount":     true,
+	".automount": true,
+	".swap":      true,
+	".timer":     true,
+	".path":      true,
+	".slice":     true,
+	".scope":     true,
+}
+
+// Normalize returns the canonical form of the unit name as specified by a caller, as systemctl mangles the names:
+// the space around it is trimmed, the type suffix is matched regardless of case and ".service" is appended, if name
+// has no suffix of a known type(e.g. "foo.service" for "foo" and "Foo.service" for "Foo.SERVICE", "getty@tty1.service" for "getty@tty1").
+// Paths are returned unchanged
+func Normalize(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if types[strings.ToLower(ext)] {
+		return strings.TrimSuffix(name, ext) + strings.ToLower(ext)
+	}
+	return name + ".service"
+}
+
+// InstanceName returns the name of the instance of template(e.g. "getty@.service") named instance(e.g. "tty1")
+func InstanceName(template, instance string) string {
+	prefix, _, _ := splitInstance(template)
+	return strings.TrimSuffix(template, filepath.Base(template)) + prefix + "@" + instance + filepath.Ext(template)
+}
+
+// IsTemplate returns whether name is a name of a template unit(e.g. "getty@.service")
+func IsTemplate(name string) bool {
+	prefix, instance, ok := splitInstance(name)
+	return ok && prefix != "" && instance == ""
+}
+
+// IsInstance returns whether name is a name of an instance of a template unit(e.g. "getty@tty1.service")
+func IsInstance(name string) bool {
+	prefix, instance, ok := splitInstance(name)
+	return ok && prefix != "" && instance != ""
+}
+
+// TemplateOf returns the name of the template, which name is an instance of.
+// If name is not an instance, it is returned unchanged
+func TemplateOf(name string) string {
+	prefix, instance, ok := splitInstance(name)
+	if !ok || prefix == "" || instance == "" {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Base(name)) + prefix + "@" + filepath.Ext(name)
+}
+
+// InstanceOf returns the instance string of name(e.g. "tty1" for "getty@tty1.service").
+// If name is not an instance, an empty string is returned
+func InstanceOf(name string) (instance string) {
+	_, instance, _ = splitInstance(name)
+	return
+}
+
+// CheckSlice returns an error, unless name is empty or a name of a slice(e.g. "batch.slice")
+func CheckSlice(name string) error {
+	if name != "" && (filepath.Ext(name) != ".slice" || strings.ContainsRune(name, '/') || name == ".slice") {
+		return ParseErr(name, ErrWrongVal)
+	}
+	return nil
+}
+
+// PathUnit returns the name of the unit of the type suffix for path, which is escaped as systemd-escape --path does
+// (e.g. "srv-my\x2ddata.mount" for "/srv/my-data" and ".mount", "-.mount" for "/")
+func PathUnit(path, suffix string) string {
+	path = strings.Trim(filepath.Clean(path), "/")
+	if path == "" {
+		return "-" + suffix
+	}
+
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '/':
+			escaped.WriteByte('-')
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == ':', c == '_', c == '.' && i > 0:
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "\\x%02x", c)
+		}
+	}
+	return escaped.String() + suffix
+}
+
+// splitInstance splits the base of name(without the suffix) on the '@' character
+func splitInstance(name string) (prefix, instance string, ok bool) {
+	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	i := strings.IndexByte(base, '@')
+	if i < 0 {
+		return base, "", false
+	}
+	return base[:i], base[i+1:], true
+}
@@ -0,0 +1,65 @@
+package unit_test
+
+import (
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceNames(t *testing.T) {
+	cases := []struct {
+		name               string
+		template, instance bool
+		templateOf         string
+		instanceOf         string
+	}{
+		{"foo.service", false, false, "foo.service", ""},
+		{"getty@.service", true, false, "getty@.service", ""},
+		{"getty@tty1.service", false, true, "getty@.service", "tty1"},
+		{"/etc/systemd/system/getty@tty1.service", false, true, "/etc/systemd/system/getty@.service", "tty1"},
+		{"@.service", false, false, "@.service", ""},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.template, unit.IsTemplate(c.name), "IsTemplate(%s)", c.name)
+		assert.Equal(t, c.instance, unit.IsInstance(c.name), "IsInstance(%s)", c.name)
+		assert.Equal(t, c.templateOf, unit.TemplateOf(c.name), "TemplateOf(%s)", c.name)
+		assert.Equal(t, c.instanceOf, unit.InstanceOf(c.name), "InstanceOf(%s)", c.name)
+	}
+
+	assert.Equal(t, "getty@tty1.service", unit.InstanceName("getty@.service", "tty1"))
+	assert.Equal(t, "/etc/systemd/system/getty@tty1.service", unit.InstanceName("/etc/systemd/system/getty@.service", "tty1"))
+}
+
+func TestNormalize(t *testing.T) {
+	for name, normalized := range map[string]string{
+		"foo.service":        "foo.service",
+		"foo":                "foo.service",
+		" foo ":              "foo.service",
+		"Foo.SERVICE":        "Foo.service",
+		"multi-user.Target":  "multi-user.target",
+		"getty@tty1":         "getty@tty1.service",
+		"getty@.service":     "getty@.service",
+		"org.example.Daemon": "org.example.Daemon.service",
+		"home.mount":         "home.mount",
+		"/etc/foo.service":   "/etc/foo.service",
+		"":                   "",
+	} {
+		assert.Equal(t, normalized, unit.Normalize(name), "Normalize(%q)", name)
+	}
+}
+
+func TestPathUnit(t *testing.T) {
+	for path, name := range map[string]string{
+		"/":              "-.mount",
+		"/srv/data":      "srv-data.mount",
+		"/srv/data/":     "srv-data.mount",
+		"//srv//my-data": `srv-my\x2ddata.mount`,
+		"/home/.cache":   "home-.cache.mount",
+		"/.hidden":       `\x2ehidden.mount`,
+		"/mnt/a b":       `mnt-a\x20b.mount`,
+	} {
+		assert.Equal(t, name, unit.PathUnit(path, ".mount"), "PathUnit(%s)", path)
+	}
+}
@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Option is a single assignment found in a unit file along with its position
+type Option struct {
+	Section, Name, Value string
+
+	// File and line the option was found at
+	File string
+	Line int
+}
+
+// Options is a list of options in the order they were found in
+type Options []*Option
+
+// Deserialize parses the unit file read from r. If r has a Name method(like *os.File does),
+// the name is used as the file name of the options returned.
+// Every malformed line is reported by a ParseError in the MultiError returned
+func Deserialize(r io.Reader) (opts Options, err error) {
+	var file string
+	if named, ok := r.(interface {
+		Name() string
+	}); ok {
+		file = named.Name()
+	}
+
+	var section string
+	merr := MultiError{}
+
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineno == 1 {
+			// Files edited on some systems start with a byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+			continue
+
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				merr = append(merr, ParseError{Source: line, Err: ErrBadSection, File: file, Line: lineno})
+				continue
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			merr = append(merr, ParseError{Source: line, Err: ErrNoAssignment, File: file, Line: lineno})
+			continue
+		}
+
+		opt := &Option{
+			Section: section,
+			Name:    strings.TrimSpace(kv[0]),
+			Value:   strings.TrimSpace(kv[1]),
+			File:    file,
+			Line:    lineno,
+		}
+
+		// Trailing backslash continues the value on the next line,
+		// comment lines in between are skipped
+		for strings.HasSuffix(opt.Value, `\`) {
+			opt.Value = strings.TrimSpace(strings.TrimSuffix(opt.Value, `\`))
+			if !scanner.Scan() {
+				break
+			}
+			lineno++
+
+			next := strings.TrimSpace(scanner.Text())
+			switch {
+			case next == "":
+			case next[0] == '#', next[0] == ';':
+				opt.Value += `\`
+			case opt.Value == "":
+				opt.Value = next
+			default:
+				opt.Value += " " + next
+			}
+		}
+
+		if section == "" {
+			merr = append(merr, opt.Err(ErrNoSection))
+			continue
+		}
+		opts = append(opts, opt)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(merr) > 0 {
+		return opts, merr
+	}
+	return opts, nil
+}
+
+// ReadOptions deserializes unit files found at paths specified in order
+func ReadOptions(paths ...string) (opts Options, err error) {
+	merr := MultiError{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := Deserialize(file)
+		file.Close()
+
+		opts = append(opts, found...)
+		if me, ok := err.(MultiError); ok {
+			merr = append(merr, me...)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(merr) > 0 {
+		return opts, merr
+	}
+	return opts, nil
+}
+
+// Err returns err wrapped in a ParseError referring to the position of opt
+func (opt *Option) Err(err error) ParseError {
+	return ParseError{
+		Source: opt.Name,
+		Err:    err,
+		File:   opt.File,
+		Line:   opt.Line,
+	}
+}
+
+// Reader returns a reader of opts serialized in unit-file format.
+// ParseDefinition uses the options directly, if passed such a reader,
+// which preserves the positions of options for error reporting
+func (opts Options) Reader() io.Reader {
+	return &optionsReader{opts: opts}
+}
+
+// optionsReader serializes the options lazily on first read
+type optionsReader struct {
+	opts Options
+	r    *bytes.Reader
+}
+
+func (or *optionsReader) Read(b []byte) (n int, err error) {
+	if or.r == nil {
+		buf := &bytes.Buffer{}
+
+		var section string
+		for _, opt := range or.opts {
+			if opt.Section != section || buf.Len() == 0 {
+				section = opt.Section
+				fmt.Fprintf(buf, "[%s]\n", section)
+			}
+			fmt.Fprintf(buf, "%s=%s\n", opt.Name, opt.Value)
+		}
+		or.r = bytes.NewReader(buf.Bytes())
+	}
+	return or.r.Read(b)
+}
@@ -0,0 +1,100 @@
+package unit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeserialize(t *testing.T) {
+	opts, err := unit.Deserialize(strings.NewReader(`# comment
+[Unit]
+Description=foo
+
+; another comment
+After=a.service \
+	b.service
+[Service]
+ExecStart=/bin/true`))
+	require.NoError(t, err, "unit.Deserialize")
+
+	assert.Equal(t, unit.Options{
+		{Section: "Unit", Name: "Description", Value: "foo", Line: 3},
+		{Section: "Unit", Name: "After", Value: "a.service b.service", Line: 6},
+		{Section: "Service", Name: "ExecStart", Value: "/bin/true", Line: 9},
+	}, opts)
+
+	_, err = unit.Deserialize(strings.NewReader(`Description=foo
+[Unit
+[Unit]
+garbage`))
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") {
+		assert.Equal(t, []string{
+			"line 1: Description: " + unit.ErrNoSection.Error(),
+			"line 2: [Unit: " + unit.ErrBadSection.Error(),
+			"line 4: garbage: " + unit.ErrNoAssignment.Error(),
+		}, me.Errors())
+	}
+}
+
+func TestParseDefinitionPositions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "option-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.service")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[Unit]
+Description=foo
+Wrong=field
+[Wrong]
+Section=wrong`), 0666), "ioutil.WriteFile")
+
+	opts, err := unit.ReadOptions(path)
+	require.NoError(t, err, "unit.ReadOptions")
+
+	err = unit.ParseDefinition(opts.Reader(), &unit.Definition{})
+	if w, ok := err.(unit.Warnings); assert.True(t, ok, "error is Warnings") {
+		assert.Equal(t, []string{
+			path + ":3: Wrong: " + unit.ErrUnknownDirective.Error(),
+			path + ":5: Section: " + unit.ErrUnknownDirective.Error(),
+		}, w.Errors())
+	}
+}
+
+func TestDeserializeSyntax(t *testing.T) {
+	opts, err := unit.Deserialize(strings.NewReader("\ufeff[ Unit ]\r\n" + `Description=multi \
+# comment inside of a continuation
+; another one
+   line \
+   description
+Documentation=\
+
+[SERVICE]
+ExecStart=/bin/echo \
+	foo
+`))
+	require.NoError(t, err, "unit.Deserialize")
+
+	assert.Equal(t, unit.Options{
+		{Section: "Unit", Name: "Description", Value: "multi line description", Line: 2},
+		{Section: "Unit", Name: "Documentation", Value: "", Line: 7},
+		{Section: "SERVICE", Name: "ExecStart", Value: "/bin/echo foo", Line: 10},
+	}, opts)
+
+	def := &struct {
+		unit.Definition
+		Service struct {
+			ExecStart string
+		}
+	}{}
+	require.NoError(t, unit.ParseDefinition(opts.Reader(), def), "unit.ParseDefinition")
+
+	assert.Equal(t, "multi line description", def.Description(), "def.Description()")
+	assert.Equal(t, "/bin/echo foo", def.Service.ExecStart, "def.Service.ExecStart")
+}
@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Property is a directive of the definition or a runtime property of a unit
+type Property struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// DefinitionProperties returns the directives of the Definition field of the struct v points to, in the order of the fields.
+// Nil is returned, if v has no such field
+func DefinitionProperties(v interface{}) (props []Property) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	def := rv.FieldByName("Definition")
+	if !def.IsValid() || def.Kind() != reflect.Struct {
+		return nil
+	}
+	return sectionsOf(def)
+}
+
+// sectionsOf returns the directives of the sections of def, the fields of embedded definitions incl.
+func sectionsOf(def reflect.Value) (props []Property) {
+	for i := 0; i < def.NumField(); i++ {
+		field, v := def.Type().Field(i), def.Field(i)
+		if field.PkgPath != "" || v.Kind() != reflect.Struct {
+			continue
+		}
+
+		if field.Anonymous {
+			props = append(props, sectionsOf(v)...)
+		} else {
+			props = append(props, directivesOf(v)...)
+		}
+	}
+	return
+}
+
+// directivesOf returns the directives of section, the fields of embedded structs incl.
+func directivesOf(section reflect.Value) (props []Property) {
+	for i := 0; i < section.NumField(); i++ {
+		field, v := section.Type().Field(i), section.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if field.Anonymous && v.Kind() == reflect.Struct {
+			props = append(props, directivesOf(v)...)
+			continue
+		}
+		props = append(props, Property{field.Name, formatValue(v)})
+	}
+	return
+}
+
+// formatValue returns v formatted the way it is specified in a definition
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return v.Interface().(fmt.Stringer).String()
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			return "yes"
+		}
+		return "no"
+	case v.Kind() == reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(values, " ")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// DirectiveSection returns the section of the directive name in the Definition field of the struct v points to.
+// An empty string is returned, if there is no such directive
+func DirectiveSection(v interface{}, name string) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+
+	def := rv.FieldByName("Definition")
+	if !def.IsValid() || def.Kind() != reflect.Struct {
+		return ""
+	}
+	return sectionOf(def, name)
+}
+
+// SetDirective sets the directive name in the definition pointed to by v to value parsed as ParseDefinition would parse it.
+// The section of the directive is returned
+func SetDirective(v interface{}, name, value string) (section string, err error) {
+	def := reflect.Indirect(reflect.ValueOf(v))
+	if !def.IsValid() || !def.CanSet() || def.Kind() != reflect.Struct {
+		return "", ErrWrongVal
+	}
+
+	if section = sectionOf(def, name); section == "" {
+		return "", ErrUnknownDirective
+	}
+	return section, setOption(def, &Option{Section: section, Name: name, Value: value})
+}
+
+// sectionOf returns the name of the section of def, embedded definitions incl., which has the directive name
+func sectionOf(def reflect.Value, name string) string {
+	for i := 0; i < def.NumField(); i++ {
+		field, v := def.Type().Field(i), def.Field(i)
+		if field.PkgPath != "" || v.Kind() != reflect.Struct {
+			continue
+		}
+
+		if field.Anonymous {
+			if section := sectionOf(v, name); section != "" {
+				return section
+			}
+		} else if _, ok := v.Type().FieldByName(name); ok {
+			return field.Name
+		}
+	}
+	return ""
+}
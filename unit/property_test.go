@@ -0,0 +1,79 @@
+package unit_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionProperties(t *testing.T) {
+	var v struct {
+		Definition struct {
+			unit.Definition
+			Service struct {
+				ExecStart string
+			}
+		}
+	}
+
+	require.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+Description=Foo
+Requires=a.service b.service
+IgnoreOnIsolate=yes
+JobTimeoutSec=90
+ConditionPathExists=/foo
+[Service]
+ExecStart=/bin/foo --bar`), &v.Definition), "unit.ParseDefinition")
+
+	props := map[string]string{}
+	for _, prop := range unit.DefinitionProperties(&v) {
+		props[prop.Name] = prop.Value
+	}
+
+	assert.Equal(t, "Foo", props["Description"])
+	assert.Equal(t, "a.service b.service", props["Requires"])
+	assert.Equal(t, "yes", props["IgnoreOnIsolate"])
+	assert.Equal(t, "no", props["RefuseManualStart"])
+	assert.Equal(t, (90 * time.Second).String(), props["JobTimeoutSec"])
+	assert.Equal(t, "/foo", props["ConditionPathExists"])
+	assert.Equal(t, "/bin/foo --bar", props["ExecStart"])
+	assert.Contains(t, props, "WantedBy")
+
+	assert.Nil(t, unit.DefinitionProperties(struct{}{}), "no definition")
+
+	assert.Equal(t, "Service", unit.DirectiveSection(&v, "ExecStart"))
+	assert.Equal(t, "Unit", unit.DirectiveSection(&v, "Requires"))
+	assert.Empty(t, unit.DirectiveSection(&v, "Foo"))
+}
+
+func TestSetDirective(t *testing.T) {
+	var def struct {
+		unit.Definition
+		Service struct {
+			ExecStart string
+			MemoryMax uint64
+		}
+	}
+
+	section, err := unit.SetDirective(&def, "MemoryMax", "512M")
+	if assert.NoError(t, err, "MemoryMax") {
+		assert.Equal(t, "Service", section)
+		assert.Equal(t, uint64(512<<20), def.Service.MemoryMax)
+	}
+
+	section, err = unit.SetDirective(&def, "Description", "Foo")
+	if assert.NoError(t, err, "Description") {
+		assert.Equal(t, "Unit", section, "embedded definition")
+		assert.Equal(t, "Foo", def.Unit.Description)
+	}
+
+	_, err = unit.SetDirective(&def, "MemoryMax", "lots")
+	assert.Equal(t, unit.ErrBadSize, err)
+
+	_, err = unit.SetDirective(&def, "Foo", "bar")
+	assert.Equal(t, unit.ErrUnknownDirective, err)
+}
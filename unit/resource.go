@@ -0,0 +1,14 @@
+package unit
+
+// Resources are the resource controls applied to the processes of a unit, zero values impose no limit
+type Resources struct {
+	// Share of the time of a single CPU, e.g. 0.2 for "CPUQuota=20%"
+	CPUQuota float64
+
+	// Maximum memory usage in bytes and maximum number of tasks, Unlimited if "infinity"
+	MemoryMax uint64
+	TasksMax  uint64
+
+	// Whether the unit gets a cgroup of its own, which its processes manage the subtree of, e.g. a container engine does
+	Delegate bool
+}
@@ -0,0 +1,119 @@
+// Package scope defines a scope unit type, which groups processes started elsewhere
+package scope
+
+import (
+	"io"
+	"sync"
+	"syscall"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+const (
+	dead    = "dead"
+	running = "running"
+)
+
+// Scope unit
+type Unit struct {
+	Definition
+
+	// Whether the scope was started and not stopped since
+	started bool
+	mutex   sync.Mutex
+}
+
+// Scope unit definition
+type Definition struct {
+	unit.Definition
+	Scope struct {
+		// Processes grouped, which must be running, once the scope is started
+		PIDs  []int
+		Slice string
+	}
+}
+
+// Define attempts to fill the sc definition by parsing r
+func (sc *Unit) Define(r io.Reader) (err error) {
+	def := Definition{}
+	def.Unit.DefaultDependencies = true
+
+	var warnings error
+	if err = unit.ParseDefinition(r, &def); unit.IsWarning(err) {
+		warnings = err
+	} else if err != nil {
+		return
+	}
+
+	if len(def.Scope.PIDs) == 0 {
+		return unit.ParseErr("PIDs", unit.ErrNotSet)
+	}
+	if err = unit.CheckSlice(def.Scope.Slice); err != nil {
+		return unit.ParseErr("Slice", err)
+	}
+
+	sc.Definition = def
+	return warnings
+}
+
+// Start fails, unless any of the processes of sc is running
+func (sc *Unit) Start() error {
+	if len(sc.Processes()) == 0 {
+		return unit.ParseErr("PIDs", unit.ErrNotExist)
+	}
+	sc.setStarted(true)
+	return nil
+}
+
+// Stop terminates the processes of sc
+func (sc *Unit) Stop() (err error) {
+	sc.setStarted(false)
+	for _, pid := range sc.Processes() {
+		if e := syscall.Kill(pid, syscall.SIGTERM); e != nil && e != syscall.ESRCH {
+			err = e
+		}
+	}
+	return
+}
+
+// setStarted records whether sc was started
+func (sc *Unit) setStarted(started bool) {
+	sc.mutex.Lock()
+	sc.started = started
+	sc.mutex.Unlock()
+}
+
+// Processes returns the PIDs of the processes of sc, which are still running
+func (sc *Unit) Processes() (pids []int) {
+	for _, pid := range sc.Definition.Scope.PIDs {
+		if err := syscall.Kill(pid, 0); err == nil || err == syscall.EPERM {
+			pids = append(pids, pid)
+		}
+	}
+	return
+}
+
+// Slice returns the name of the slice the cgroup of sc is created in
+func (sc *Unit) Slice() string {
+	return sc.Definition.Scope.Slice
+}
+
+// Active returns activation status of the unit, sc is active as long as any of its processes is running
+func (sc *Unit) Active() unit.Activation {
+	sc.mutex.Lock()
+	started := sc.started
+	sc.mutex.Unlock()
+
+	if started && len(sc.Processes()) > 0 {
+		return unit.Active
+	}
+	return unit.Inactive
+}
+
+// Sub reports the sub status of a scope
+func (sc *Unit) Sub() string {
+	if sc.Active() == unit.Active {
+		return running
+	}
+	return dead
+}
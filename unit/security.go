@@ -0,0 +1,57 @@
+package unit
+
+// SecurityCheck is the result of a check of the security audit of a unit, as systemd-analyze security performs
+type SecurityCheck struct {
+	// Directive the check is about, e.g. "User="
+	Directive string
+
+	// Description of what was found, e.g. "Service runs as root user"
+	Description string
+
+	// Recommendation on how to pass the check, empty if it has passed
+	Recommendation string
+
+	// Weight of the check in the exposure of the unit
+	Weight float64
+
+	Passed bool
+}
+
+// Ratings of the exposure levels, the level of each rating is the lowest exposure rated as such
+var ratings = []struct {
+	level  float64
+	rating string
+}{
+	{10, "DANGEROUS"},
+	{9, "UNSAFE"},
+	{7.5, "EXPOSED"},
+	{5, "MEDIUM"},
+	{1, "OK"},
+	{0.1, "SAFE"},
+	{0, "PERFECT"},
+}
+
+// Exposure returns the share of the weight of checks, which have not passed, scaled to 0.0-10.0
+func Exposure(checks []SecurityCheck) float64 {
+	var total, failed float64
+	for _, c := range checks {
+		total += c.Weight
+		if !c.Passed {
+			failed += c.Weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return failed / total * 10
+}
+
+// Rating returns the rating of exposure, e.g. "MEDIUM" for 5.2
+func Rating(exposure float64) string {
+	for _, r := range ratings {
+		if exposure >= r.level {
+			return r.rating
+		}
+	}
+	return "PERFECT"
+}
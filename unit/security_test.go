@@ -0,0 +1,29 @@
+package unit_test
+
+import (
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExposure(t *testing.T) {
+	checks := []unit.SecurityCheck{
+		{Directive: "User=", Weight: 3},
+		{Directive: "PrivateTmp=", Weight: 1, Passed: true},
+	}
+	assert.Equal(t, 7.5, unit.Exposure(checks))
+	assert.Equal(t, 0.0, unit.Exposure(nil))
+
+	for exposure, rating := range map[float64]string{
+		10:   "DANGEROUS",
+		9.6:  "UNSAFE",
+		7.5:  "EXPOSED",
+		5.2:  "MEDIUM",
+		1:    "OK",
+		0.05: "PERFECT",
+		0:    "PERFECT",
+	} {
+		assert.Equal(t, rating, unit.Rating(exposure), "exposure %.2f", exposure)
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Flags the file specified by StandardOutput= or StandardError= gets opened with, by the prefix of the value.
+// file: writes from the beginning of the file without truncating it
+var outputFlags = map[string]int{
+	"file":     os.O_WRONLY | os.O_CREATE,
+	"append":   os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	"truncate": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+}
+
+// Mode of the output files created
+const OUTPUT_FILE_MODE = 0644
+
+// parseOutput returns the path to the file specified by s, as found in StandardOutput= or StandardError=
+// (e.g. "append:/var/log/foo.log"), along with the flags to open it with. path is empty, if s specifies no file
+func parseOutput(s string) (path string, flags int, err error) {
+	switch s {
+	case "", "inherit", "journal", "null":
+		return "", 0, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	flags, ok := outputFlags[parts[0]]
+	if !ok || len(parts) != 2 {
+		return "", 0, unit.ParseErr(s, unit.ErrNotSupported)
+	}
+	if path = parts[1]; !filepath.IsAbs(path) {
+		return "", 0, unit.ParseErr(path, unit.ErrPathNotAbs)
+	}
+	return filepath.Clean(path), flags, nil
+}
+
+// setOutput connects the standard output and error of sv.Cmd as specified by StandardOutput= and StandardError=.
+// The files are opened anew on each start, so that the ones removed(e.g. by log rotation) get created again.
+// The files opened are returned to be closed, once the process has started
+func (sv *Unit) setOutput() (files []*os.File, err error) {
+	connect := func(value string, inherited, journal io.Writer) (w io.Writer, err error) {
+		switch value {
+		case "", "inherit":
+			return inherited, nil
+		case "journal":
+			return journal, nil
+		case "null":
+			return nil, nil
+		}
+
+		path, flags, err := parseOutput(value)
+		if err != nil {
+			return
+		}
+		f, err := os.OpenFile(path, flags, OUTPUT_FILE_MODE)
+		if err != nil {
+			return
+		}
+		files = append(files, f)
+		return f, nil
+	}
+
+	stdout, err := connect(sv.Definition.Service.StandardOutput, sv.Cmd.Stdout, sv.stdout)
+	if err != nil {
+		return
+	}
+
+	// The standard error follows the output, unless either is left connected to the journal
+	inherited := sv.Cmd.Stderr
+	switch sv.Definition.Service.StandardOutput {
+	case "", "inherit", "journal":
+	default:
+		inherited = stdout
+	}
+
+	stderr, err := connect(sv.Definition.Service.StandardError, inherited, sv.stderr)
+	if err != nil {
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, err
+	}
+
+	sv.Cmd.Stdout, sv.Cmd.Stderr = stdout, stderr
+	return
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"os"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// Security returns the results of the security checks of the definition of sv.
+// The sandboxing directives of systemd(PrivateTmp=, ProtectSystem=, NoNewPrivileges=) are not supported,
+// hence the checks about them only pass, where running as an unprivileged user provides the same protection
+func (sv *Unit) Security() []unit.SecurityCheck {
+	def := sv.Definition.Service
+
+	// The processes run as the user running the manager, unless User= is set
+	uid := os.Getuid()
+	if def.User != "" {
+		if cred, err := credential(def.User); err == nil {
+			uid = int(cred.Uid)
+		}
+	}
+	root := uid == 0
+	res := sv.Resources()
+
+	return []unit.SecurityCheck{
+		check("User=", !root, 2,
+			"Service runs as root user",
+			"Service runs as an unprivileged user",
+			"Set User= to a dedicated unprivileged user"),
+		check("CapabilityBoundingSet=", !root, 1.5,
+			"Service has all capabilities of root",
+			"Service has no capabilities, as it runs as an unprivileged user",
+			"Set User=, capabilities can not be restricted otherwise"),
+		check("ProtectSystem=", !root, 1.5,
+			"Service may modify /usr, /boot and /etc",
+			"Service may only modify the files its user owns",
+			"Set User=, ProtectSystem= is not supported"),
+		check("PrivateTmp=", false, 1,
+			"Service shares /tmp with the other processes",
+			"",
+			"Run the service in a container(see quadlet-generator), PrivateTmp= is not supported"),
+		check("NoNewPrivileges=", false, 1,
+			"Service may acquire privileges executing set-user-ID programs",
+			"",
+			"Run the service in a container(see quadlet-generator), NoNewPrivileges= is not supported"),
+		check("Delegate=", !res.Delegate, 0.5,
+			"Service manages the cgroup subtree delegated to it",
+			"Service does not manage cgroups",
+			"Unset Delegate=, unless the service runs containers"),
+		check("MemoryMax=", res.MemoryMax != 0 && res.MemoryMax != unit.Unlimited, 0.5,
+			"Service may use all memory",
+			"Memory usage of the service is limited",
+			"Set MemoryMax="),
+		check("TasksMax=", res.TasksMax != 0 && res.TasksMax != unit.Unlimited, 0.5,
+			"Service may fork without limit",
+			"Number of tasks of the service is limited",
+			"Set TasksMax="),
+		check("StandardInput=", def.StandardInput != "tty", 0.5,
+			"Service has access to the terminal of TTYPath=",
+			"Service has no access to terminals",
+			"Unset StandardInput=tty, unless the service is interactive"),
+	}
+}
+
+// check returns the result of the check of directive weighing weight, described by failed or passed
+func check(directive string, ok bool, weight float64, failed, passed, recommendation string) unit.SecurityCheck {
+	if ok {
+		return unit.SecurityCheck{Directive: directive, Description: passed, Weight: weight, Passed: true}
+	}
+	return unit.SecurityCheck{Directive: directive, Description: failed, Recommendation: recommendation, Weight: weight}
+}
@@ -2,10 +2,20 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"io"
+	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/plasma-umass/systemgo/pid1"
 	"github.com/plasma-umass/systemgo/unit"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,6 +23,33 @@ import (
 
 const DEFAULT_TYPE = "simple"
 
+// Default terminal the service gets connected to with StandardInput=tty
+const DEFAULT_TTY_PATH = "/dev/console"
+
+// Default start rate limit -- at most DEFAULT_START_LIMIT_BURST starts within DEFAULT_START_LIMIT_INTERVAL
+const (
+	DEFAULT_START_LIMIT_INTERVAL = 10 * time.Second
+	DEFAULT_START_LIMIT_BURST    = 5
+)
+
+// Default log rate limit -- at most DEFAULT_LOG_RATE_LIMIT_BURST lines of output within DEFAULT_LOG_RATE_LIMIT_INTERVAL
+const (
+	DEFAULT_LOG_RATE_LIMIT_INTERVAL = 30 * time.Second
+	DEFAULT_LOG_RATE_LIMIT_BURST    = 10000
+)
+
+// Default time services get to start, as TimeoutStartSec= of systemd. Oneshot services get unlimited time by default
+const DEFAULT_TIMEOUT_START_SEC = 90 * time.Second
+
+// Time to wait for the output of a process exited to be copied, processes left running may keep the pipes open
+const OUTPUT_WAIT_DELAY = 500 * time.Millisecond
+
+// Defaults of Restart= and RestartSec=
+const (
+	DEFAULT_RESTART     = "no"
+	DEFAULT_RESTART_SEC = 100 * time.Millisecond
+)
+
 const (
 	dead         = "dead"
 	startPre     = "startPre"
@@ -32,19 +69,60 @@ const (
 	autoRestart  = "autoRestart"
 )
 
+// restartPolicies are the values of Restart= supported
+var restartPolicies = map[string]bool{
+	"no":          true,
+	"always":      true,
+	"on-success":  true,
+	"on-failure":  true,
+	"on-abnormal": true,
+	"on-abort":    true,
+}
+
+// settable are the directives, which may be changed at runtime by SetProperty
+var settable = map[string]bool{
+	"CPUQuota":  true,
+	"MemoryMax": true,
+	"TasksMax":  true,
+	"Restart":   true,
+}
+
 var supported = map[string]bool{
 	"oneshot": true,
 	"simple":  true,
 	"forking": false,
-	"dbus":    false,
+	"dbus":    true,
 	"notify":  false,
-	"idle":    false,
+	"idle":    true,
 }
 
 // Service unit
 type Unit struct {
 	Definition
 	*exec.Cmd
+
+	// State of the service process started as Cmd, once it has been waited for
+	exitState *os.ProcessState
+
+	// Whether the service process was killed by Stop
+	killed bool
+
+	// Exit of the service process, if it was reaped by the init process
+	reaped *pid1.Exit
+
+	// Control process running, i.e. ExecStartPre=, ExecStartPost= or ExecStop= command,
+	// and the sub status of the service while it runs
+	control    *os.Process
+	controlSub string
+
+	// Writers the standard output and error of the processes get written to, discarded if nil
+	stdout, stderr io.Writer
+
+	// Listening sockets passed to the service process, as socket activation does
+	sockets []*os.File
+
+	// Guards the processes of the service, which are started, waited for and queried concurrently
+	mutex sync.Mutex
 }
 
 // Service unit definition
@@ -52,12 +130,32 @@ type Definition struct {
 	unit.Definition
 	Service struct {
 		Type                            string
+		BusName                         string
+		ExecStartPre, ExecStartPost     string
 		ExecStart, ExecStop, ExecReload string
-		//Restart                         string
-		//RestartSec                      int
+		//PIDFile                         string
 		RemainAfterExit  bool
 		WorkingDirectory string
-		//PIDFile          string
+		Environment      []string
+		StandardInput    string
+		StandardOutput   string
+		StandardError    string
+		TTYPath          string
+		UtmpIdentifier   string
+
+		Restart         string
+		RestartSec      time.Duration
+		TimeoutStartSec time.Duration
+
+		CPUQuota            string
+		MemoryMax, TasksMax uint64
+		Delegate            bool
+
+		User  string
+		Slice string
+
+		LogRateLimitIntervalSec time.Duration
+		LogRateLimitBurst       int
 	}
 }
 
@@ -75,92 +173,702 @@ func (sv *Unit) Define(r io.Reader /*, errch chan<- error*/) (err error) {
 
 	def := Definition{}
 	def.Service.Type = DEFAULT_TYPE
+	def.Service.TTYPath = DEFAULT_TTY_PATH
+	def.Service.Restart = DEFAULT_RESTART
+	def.Service.RestartSec = DEFAULT_RESTART_SEC
+	def.Service.TimeoutStartSec = -1
+	def.Unit.DefaultDependencies = true
+	def.Unit.StartLimitIntervalSec = DEFAULT_START_LIMIT_INTERVAL
+	def.Unit.StartLimitBurst = DEFAULT_START_LIMIT_BURST
+	def.Service.LogRateLimitIntervalSec = DEFAULT_LOG_RATE_LIMIT_INTERVAL
+	def.Service.LogRateLimitBurst = DEFAULT_LOG_RATE_LIMIT_BURST
+
+	var warnings error
+	if err = unit.ParseDefinition(r, &def); unit.IsWarning(err) {
+		warnings = err
+	} else if err != nil {
+		return
+	}
 
-	if err = unit.ParseDefinition(r, &def); err != nil {
+	if err = def.check(); err != nil {
 		return
 	}
 
+	if def.Service.TimeoutStartSec < 0 {
+		def.Service.TimeoutStartSec = DEFAULT_TIMEOUT_START_SEC
+		if def.Service.Type == "oneshot" {
+			def.Service.TimeoutStartSec = unit.Infinity
+		}
+	}
+	sv.Definition = def
+
+	// A process already started is kept, the command defined is used on the next start
+	sv.mutex.Lock()
+	if sv.Cmd == nil || sv.Cmd.Process == nil {
+		sv.Cmd, sv.exitState = sv.command(), nil
+	}
+	sv.mutex.Unlock()
+
+	return warnings
+}
+
+// check returns the errors found in def
+func (def Definition) check() error {
 	merr := unit.MultiError{}
 
-	// Check definition for errors
 	switch {
 	case def.Service.ExecStart == "":
 		merr = append(merr, unit.ParseErr("ExecStart", unit.ErrNotSet))
 
 	case !Supported(def.Service.Type):
 		merr = append(merr, unit.ParseErr("Type", unit.ParseErr(def.Service.Type, unit.ErrNotSupported)))
+
+	case def.Service.StandardInput != "" && def.Service.StandardInput != "null" && def.Service.StandardInput != "tty":
+		merr = append(merr, unit.ParseErr("StandardInput", unit.ParseErr(def.Service.StandardInput, unit.ErrNotSupported)))
+
+	case def.Service.Type == "dbus" && def.Service.BusName == "":
+		merr = append(merr, unit.ParseErr("BusName", unit.ErrNotSet))
+
+	case !restartPolicies[def.Service.Restart]:
+		merr = append(merr, unit.ParseErr("Restart", unit.ParseErr(def.Service.Restart, unit.ErrNotSupported)))
+	}
+
+	if _, _, err := parseOutput(def.Service.StandardOutput); err != nil {
+		merr = append(merr, unit.ParseErr("StandardOutput", err))
+	}
+	if _, _, err := parseOutput(def.Service.StandardError); err != nil {
+		merr = append(merr, unit.ParseErr("StandardError", err))
+	}
+
+	if _, err := unit.ParseCPUQuota(def.Service.CPUQuota); err != nil {
+		merr = append(merr, unit.ParseErr("CPUQuota", err))
+	}
+
+	if def.Service.User != "" {
+		if _, err := credential(def.Service.User); err != nil {
+			merr = append(merr, unit.ParseErr("User", unit.ParseErr(def.Service.User, err)))
+		}
+	}
+
+	if err := unit.CheckSlice(def.Service.Slice); err != nil {
+		merr = append(merr, unit.ParseErr("Slice", err))
 	}
 
 	if len(merr) > 0 {
 		return merr
 	}
+	return nil
+}
 
-	sv.Definition = def
+// SetProperty changes the directive name of the definition of sv to value.
+// Only CPUQuota=, MemoryMax=, TasksMax= and Restart= may be changed
+func (sv *Unit) SetProperty(name, value string) (err error) {
+	if !settable[name] {
+		return unit.ParseErr(name, unit.ErrNotSupported)
+	}
 
-	cmd := strings.Fields(def.Service.ExecStart)
-	sv.Cmd = exec.Command(cmd[0], cmd[1:]...)
-	sv.Cmd.Dir = sv.Definition.Service.WorkingDirectory
+	def := sv.Definition
+	if _, err = unit.SetDirective(&def, name, value); err != nil {
+		return unit.ParseErr(name, err)
+	}
+	if err = def.check(); err != nil {
+		return
+	}
 
+	sv.Definition = def
 	return nil
 }
 
+// Resources returns the resource controls specified in the definition of sv
+func (sv *Unit) Resources() unit.Resources {
+	// CPUQuota= is checked, when defined
+	quota, _ := unit.ParseCPUQuota(sv.Definition.Service.CPUQuota)
+	return unit.Resources{
+		CPUQuota:  quota,
+		MemoryMax: sv.Definition.Service.MemoryMax,
+		TasksMax:  sv.Definition.Service.TasksMax,
+		Delegate:  sv.Definition.Service.Delegate,
+	}
+}
+
+// TimeoutStartSec returns the time the service gets to start, 0 if unlimited
+func (sv *Unit) TimeoutStartSec() time.Duration {
+	if d := sv.Definition.Service.TimeoutStartSec; d != unit.Infinity {
+		return d
+	}
+	return 0
+}
+
+// Idle returns whether the service of Type=idle is started only once the other jobs have finished
+func (sv *Unit) Idle() bool {
+	return sv.Definition.Service.Type == "idle"
+}
+
+// BusName returns the name on the message bus a service of Type=dbus is active once it has acquired, "" for the other types
+func (sv *Unit) BusName() string {
+	if sv.Definition.Service.Type != "dbus" {
+		return ""
+	}
+	return sv.Definition.Service.BusName
+}
+
+// LogRateLimitIntervalSec returns the interval the output of the service is rate limited in
+func (sv *Unit) LogRateLimitIntervalSec() time.Duration {
+	return sv.Definition.Service.LogRateLimitIntervalSec
+}
+
+// LogRateLimitBurst returns the number of the lines of output of the service recorded within the rate limit interval
+func (sv *Unit) LogRateLimitBurst() int {
+	return sv.Definition.Service.LogRateLimitBurst
+}
+
+// RestartSec returns the time to sleep before restarting the service process
+func (sv *Unit) RestartSec() time.Duration {
+	return sv.Definition.Service.RestartSec
+}
+
+// AutoRestart reports whether the service process, which has exited, is to be restarted according to Restart=.
+// The service is never restarted, if it was stopped by Stop
+func (sv *Unit) AutoRestart() bool {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.killed || sv.Cmd == nil || sv.Cmd.Process == nil {
+		return false
+	}
+
+	var status syscall.WaitStatus
+	switch {
+	case sv.reaped != nil && sv.reaped.PID == sv.Cmd.Process.Pid:
+		status = sv.reaped.Status
+	case sv.exitState != nil:
+		status, _ = sv.exitState.Sys().(syscall.WaitStatus)
+	default:
+		// Still running
+		return false
+	}
+
+	clean := status.Exited() && status.ExitStatus() == 0
+	switch sv.Definition.Service.Restart {
+	case "always":
+		return true
+	case "on-success":
+		return clean
+	case "on-failure":
+		return !clean
+	case "on-abnormal", "on-abort":
+		return status.Signaled()
+	default:
+		return false
+	}
+}
+
+// command returns the command to execute as specified in service definition
+func (sv *Unit) command() (cmd *exec.Cmd) {
+	fields := strings.Fields(sv.Definition.Service.ExecStart)
+	if len(sv.sockets) > 0 {
+		// LISTEN_PID must be the PID of the service process, which is only known once it has started
+		fields = append([]string{"/bin/sh", "-c", `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`}, fields...)
+	}
+	cmd = exec.Command(fields[0], fields[1:]...)
+	cmd.Dir = sv.Definition.Service.WorkingDirectory
+	if len(sv.Definition.Service.Environment) > 0 {
+		cmd.Env = append(os.Environ(), sv.Definition.Service.Environment...)
+	}
+	if len(sv.sockets) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		// The sockets are passed starting with file descriptor 3, as sd_listen_fds(3) expects
+		cmd.Env = append(cmd.Env, "LISTEN_FDS="+strconv.Itoa(len(sv.sockets)))
+		cmd.ExtraFiles = sv.sockets
+	}
+	cmd.Stdout, cmd.Stderr = sv.stdout, sv.stderr
+	cmd.WaitDelay = OUTPUT_WAIT_DELAY
+	if sv.Definition.Service.User != "" {
+		// User= is checked, when defined
+		cred, _ := credential(sv.Definition.Service.User)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+	return
+}
+
+// credential returns the credential of the user specified by name or numeric ID, as found in User=
+func credential(name string) (cred *syscall.Credential, err error) {
+	u, err := user.Lookup(name)
+	if _, ok := err.(user.UnknownUserError); ok {
+		u, err = user.LookupId(name)
+	}
+	if err != nil {
+		return
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return
+	}
+	cred = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				cred.Groups = append(cred.Groups, uint32(g))
+			}
+		}
+	}
+	return cred, nil
+}
+
+// SetOutput makes the standard output and error of the processes of sv started from now on get written to stdout and stderr
+func (sv *Unit) SetOutput(stdout, stderr io.Writer) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.stdout, sv.stderr = stdout, stderr
+	if sv.Cmd != nil && sv.Cmd.Process == nil {
+		sv.Cmd.Stdout, sv.Cmd.Stderr = stdout, stderr
+	}
+}
+
+// SetSockets makes the listening sockets files get passed to the service processes started from now on
+func (sv *Unit) SetSockets(files []*os.File) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.sockets = files
+	if sv.Cmd != nil && sv.Cmd.Process == nil {
+		sv.Cmd = sv.command()
+	}
+}
+
+// RequiresMountsFor returns the paths as found in the definition of sv followed by the working directory
+// and the absolute paths of the commands run
+func (sv *Unit) RequiresMountsFor() (paths []string) {
+	paths = append(paths, sv.Definition.Unit.RequiresMountsFor...)
+	if dir := sv.Definition.Service.WorkingDirectory; filepath.IsAbs(dir) {
+		paths = append(paths, dir)
+	}
+	for _, cmd := range []string{sv.Definition.Service.ExecStartPre, sv.Definition.Service.ExecStart, sv.Definition.Service.ExecStartPost, sv.Definition.Service.ExecStop} {
+		if fields := strings.Fields(cmd); len(fields) > 0 && filepath.IsAbs(fields[0]) {
+			paths = append(paths, fields[0])
+		}
+	}
+	return
+}
+
+// Utmp returns the identifier the main process of sv is recorded in utmp with and the terminal it runs on
+func (sv *Unit) Utmp() (id, line string) {
+	return sv.Definition.Service.UtmpIdentifier, sv.Definition.Service.TTYPath
+}
+
+// Slice returns the name of the slice the cgroup of sv is created in
+func (sv *Unit) Slice() string {
+	return sv.Definition.Service.Slice
+}
+
 // Start executes the command specified in service definition
 func (sv *Unit) Start() (err error) {
+	return sv.StartContext(context.Background())
+}
+
+// StartContext executes the command specified in service definition.
+// If ctx is done before the service has started, the commands being run are killed
+func (sv *Unit) StartContext(ctx context.Context) (err error) {
 	e := log.WithField("ExecStart", sv.Definition.Service.ExecStart)
 
 	e.Debug("sv.Start")
 
+	cmd := sv.nextCommand()
+
+	if fields := strings.Fields(sv.Definition.Service.ExecStartPre); len(fields) > 0 {
+		pre := exec.Command(fields[0], fields[1:]...)
+		pre.Dir, pre.Env = cmd.Dir, cmd.Env
+		if _, err = sv.runControl(ctx, pre, startPre); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			e.WithField("err", err).Debug("ExecStartPre failed")
+			return
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	if sv.Definition.Service.StandardInput == "tty" {
+		// The service process inherits the terminal, hence it can be closed once started
+		var tty *os.File
+		if tty, err = os.OpenFile(sv.Definition.Service.TTYPath, os.O_RDWR, 0); err != nil {
+			return
+		}
+		defer tty.Close()
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	}
+
+	files, err := sv.setOutput()
+	if err != nil {
+		return
+	}
+	defer func() {
+		// The process inherits the files, hence they can be closed once started
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
 	switch sv.Definition.Service.Type {
-	case "simple":
-		if err = sv.Cmd.Start(); err == nil {
-			go sv.Cmd.Wait()
+	case "simple", "dbus", "idle":
+		sv.mutex.Lock()
+		if err = cmd.Start(); err == nil {
+			sv.watch(cmd)
 		}
+		sv.mutex.Unlock()
 	case "oneshot":
-		err = sv.Cmd.Run()
+		sv.mutex.Lock()
+		err = cmd.Start()
+		sv.mutex.Unlock()
+		if err != nil {
+			break
+		}
+
+		var exit *pid1.Exit
+		exit, err = waitContext(ctx, cmd)
+
+		sv.mutex.Lock()
+		if exit != nil {
+			sv.reaped = exit
+		}
+		sv.exitState = cmd.ProcessState
+		sv.mutex.Unlock()
 	default:
 		panic("Unknown service type")
 	}
 
+	if err == nil {
+		err = sv.startPost(ctx, cmd)
+	}
+
 	e.WithField("err", err).Debug("started")
 	return
 }
 
+// startPost runs ExecStartPost= command, once the service process started as cmd has started or, if sv is oneshot,
+// finished. If the command fails, the service process is killed
+func (sv *Unit) startPost(ctx context.Context, cmd *exec.Cmd) (err error) {
+	fields := strings.Fields(sv.Definition.Service.ExecStartPost)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	post := exec.Command(fields[0], fields[1:]...)
+	post.Dir, post.Env = cmd.Dir, cmd.Env
+	if _, err = sv.runControl(ctx, post, startPost); err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	log.WithField("err", err).Debug("ExecStartPost failed")
+
+	sv.mutex.Lock()
+	if sv.mainSub() == running {
+		sv.killed = true
+		cmd.Process.Kill()
+	}
+	sv.mutex.Unlock()
+	return
+}
+
+// nextCommand returns the command the service process is to be started as, Cmd is replaced first, if it was started
+func (sv *Unit) nextCommand() *exec.Cmd {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.Cmd.Process != nil {
+		// Commands can not be reused, once started
+		sv.Cmd, sv.exitState = sv.command(), nil
+	}
+	sv.killed = false
+	return sv.Cmd
+}
+
+// MainPID returns the PID of the service process running or 0, if there is none
+func (sv *Unit) MainPID() int {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.mainSub() != running {
+		return 0
+	}
+	return sv.Cmd.Process.Pid
+}
+
+// Attach makes sv supervise the running process with the pid specified,
+// which must be a child of the calling process
+func (sv *Unit) Attach(pid int) (err error) {
+	log.WithField("pid", pid).Debug("sv.Attach")
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return
+	}
+	if err = proc.Signal(syscall.Signal(0)); err != nil {
+		return
+	}
+
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	cmd := sv.command()
+	cmd.Process = proc
+	sv.Cmd, sv.exitState, sv.killed = cmd, nil, false
+
+	sv.watch(cmd)
+	return
+}
+
+// watch makes sv record the exit of the service process started as cmd, the mutex of sv must be locked.
+// If the children are reaped by pid1.Watch, the exit gets delivered by Exited,
+// otherwise the process is waited for
+func (sv *Unit) watch(cmd *exec.Cmd) {
+	pid := cmd.Process.Pid
+	if pid1.Watching() {
+		// The process may have been reaped before its PID was known
+		if status, ok := pid1.Claim(pid); ok {
+			sv.reaped = &pid1.Exit{PID: pid, Status: status}
+		}
+		return
+	}
+
+	go func() {
+		exit, _ := pid1.WaitCommand(cmd)
+
+		sv.mutex.Lock()
+		defer sv.mutex.Unlock()
+		if exit != nil {
+			sv.reaped = exit
+		}
+		if cmd == sv.Cmd {
+			sv.exitState = cmd.ProcessState
+		}
+	}()
+}
+
+// ControlPID returns the PID of the control process running or 0, if there is none
+func (sv *Unit) ControlPID() int {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if control := sv.control; control != nil {
+		return control.Pid
+	}
+	return 0
+}
+
+// Owns returns whether pid is the PID of the service process or of the control process running
+func (sv *Unit) Owns(pid int) bool {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if control := sv.control; control != nil && control.Pid == pid {
+		return true
+	}
+	return sv.Cmd != nil && sv.Cmd.Process != nil && sv.Cmd.Process.Pid == pid
+}
+
+// Exited records the exit of the service process reaped by the manager.
+// The exits of control processes are reported by the commands run
+func (sv *Unit) Exited(pid int, status syscall.WaitStatus) {
+	log.WithFields(log.Fields{
+		"pid":    pid,
+		"status": status,
+	}).Debug("sv.Exited")
+
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.Cmd != nil && sv.Cmd.Process != nil && sv.Cmd.Process.Pid == pid {
+		sv.reaped = &pid1.Exit{PID: pid, Status: status}
+	}
+}
+
 // Stop stops execution of the command specified in service definition
 func (sv *Unit) Stop() (err error) {
+	return sv.StopContext(context.Background())
+}
+
+// StopContext stops execution of the command specified in service definition.
+// The control process running, e.g. ExecStartPre= command hanging, is killed first.
+// If ctx is done before ExecStop= command has finished, it gets killed
+func (sv *Unit) StopContext(ctx context.Context) (err error) {
+	sv.mutex.Lock()
+	control, started := sv.control, sv.mainSub() == running
+	sv.mutex.Unlock()
+	if control != nil {
+		log.WithField("pid", control.Pid).Debug("Killing the control process")
+		if err = control.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return
+		}
+		if !started {
+			// The service process was not started, e.g. ExecStartPre= command was running
+			return nil
+		}
+	}
+
 	if cmd := strings.Fields(sv.Definition.Service.ExecStop); len(cmd) > 0 {
-		return exec.Command(cmd[0], cmd[1:]...).Run()
+		_, err = sv.runControl(ctx, exec.Command(cmd[0], cmd[1:]...), stop)
+		return
 	}
-	if sv.Cmd.Process != nil {
-		return sv.Cmd.Process.Kill()
+
+	sv.mutex.Lock()
+	proc := sv.Cmd.Process
+	if proc != nil {
+		sv.killed = true
+	}
+	sv.mutex.Unlock()
+
+	if proc != nil {
+		if err = proc.Kill(); errors.Is(err, os.ErrProcessDone) {
+			// Stopped already
+			return nil
+		}
+		return err
 	}
 	return nil
 }
 
+// runControl runs cmd as the control process of sv killing it, if ctx is done before it has finished.
+// sv reports sub status sub while cmd runs. The exit of cmd is returned, if it was reaped by the init process
+func (sv *Unit) runControl(ctx context.Context, cmd *exec.Cmd, sub string) (exit *pid1.Exit, err error) {
+	sv.mutex.Lock()
+	cmd.Stdout, cmd.Stderr, cmd.WaitDelay = sv.stdout, sv.stderr, OUTPUT_WAIT_DELAY
+	if err = cmd.Start(); err != nil {
+		sv.mutex.Unlock()
+		return
+	}
+	sv.control, sv.controlSub = cmd.Process, sub
+	sv.mutex.Unlock()
+
+	defer func() {
+		sv.mutex.Lock()
+		sv.control, sv.controlSub = nil, ""
+		sv.mutex.Unlock()
+	}()
+	return waitContext(ctx, cmd)
+}
+
+// waitContext waits for cmd started to exit killing it, if ctx is done before it has finished
+func waitContext(ctx context.Context, cmd *exec.Cmd) (exit *pid1.Exit, err error) {
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		exit, err = pid1.WaitCommand(cmd)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		return
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-done
+		return exit, ctx.Err()
+	}
+}
+
 // Sub reports the sub status of a service
 func (sv *Unit) Sub() string {
 	log.WithField("sv", sv).Debugf("sv.Sub")
 
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	return sv.sub()
+}
+
+// sub reports the sub status of a service, the mutex of sv must be locked
+func (sv *Unit) sub() string {
+	if sv.control != nil {
+		// ExecStartPre=, ExecStartPost= or ExecStop= command running
+		return sv.controlSub
+	}
+	return sv.mainSub()
+}
+
+// mainSub reports the sub status of the service process, the mutex of sv must be locked
+func (sv *Unit) mainSub() string {
 	switch {
-	case sv.Cmd.Process == nil:
+	case sv.Cmd == nil || sv.Cmd.Process == nil:
 		// Service has not been started yet
 		return dead
 
-	case sv.Cmd.ProcessState == nil:
+	case sv.reaped != nil && sv.reaped.PID == sv.Cmd.Process.Pid:
+		// Service process was reaped by the init process
+		switch {
+		case sv.killed:
+			return dead
+		case sv.reaped.Status.Exited() && sv.reaped.Status.ExitStatus() == 0:
+			return sv.exitedSub()
+		}
+		return failed
+
+	case sv.exitState == nil:
 		// Wait has not returned yet
 		return running
 
-	case sv.ProcessState.Exited(), sv.ProcessState.Success():
-		if sv.Definition.Service.RemainAfterExit {
-			return exited
-		}
+	case sv.killed:
+		// Service process was stopped deliberately
 		return dead
 
+	case sv.exitState.Exited(), sv.exitState.Success():
+		return sv.exitedSub()
+
 	default:
 		// Service process has finished, but did not return a 0 exit code
 		return failed
 	}
 }
 
+// ExitCode returns the exit code of the service process, -1 if it has not exited normally
+func (sv *Unit) ExitCode() int {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	switch {
+	case sv.Cmd == nil || sv.Cmd.Process == nil:
+		return -1
+	case sv.reaped != nil && sv.reaped.PID == sv.Cmd.Process.Pid:
+		if sv.reaped.Status.Exited() {
+			return sv.reaped.Status.ExitStatus()
+		}
+	case sv.exitState != nil:
+		return sv.exitState.ExitCode()
+	}
+	return -1
+}
+
+// ResetFailed makes a failed service inactive by discarding the service process exited
+func (sv *Unit) ResetFailed() {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.sub() == failed {
+		sv.Cmd, sv.exitState, sv.reaped = sv.command(), nil, nil
+	}
+}
+
+// exitedSub returns the sub status of a service, which process has exited normally
+func (sv *Unit) exitedSub() string {
+	if sv.Definition.Service.RemainAfterExit {
+		return exited
+	}
+	return dead
+}
+
 // Active reports activation status of a service
 func (sv *Unit) Active() unit.Activation {
 	log.WithField("sv", sv).Debugf("sv.Active")
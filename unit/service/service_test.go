@@ -1,13 +1,20 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/plasma-umass/systemgo/unit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefine(t *testing.T) {
@@ -27,6 +34,56 @@ ExecStart=/bin/echo test`)), "sv.Define")
 			}
 		}
 	}
+	sv = Unit{}
+	err = sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+ExecStrat=/bin/echo typo`))
+	assert.True(t, unit.IsWarning(err), "sv.Define with unknown directive returns warnings")
+	assert.Equal(t, "/bin/echo test", sv.Definition.Service.ExecStart, "sv.Definition.Service.ExecStart")
+}
+
+func TestStartTTY(t *testing.T) {
+	tty, err := ioutil.TempFile("", "tty-test")
+	require.NoError(t, err, "ioutil.TempFile")
+	tty.Close()
+	defer os.Remove(tty.Name())
+
+	sv := Unit{}
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=oneshot
+ExecStart=/bin/echo test
+StandardInput=tty
+TTYPath=`+tty.Name())), "sv.Define")
+
+	assert.NoError(t, sv.Start(), "sv.Start")
+
+	b, err := ioutil.ReadFile(tty.Name())
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, "test\n", string(b), "output written to the terminal")
+
+	sv = Unit{}
+	assert.Error(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+StandardInput=socket`)), "unsupported StandardInput=")
+}
+
+func TestExited(t *testing.T) {
+	sv := Unit{}
+	sv.Definition.Service.Type = "simple"
+	sv.Cmd = exec.Command("sleep", "1000")
+	require.NoError(t, sv.Start(), "sv.Start")
+	defer sv.Cmd.Process.Kill()
+
+	pid := sv.Cmd.Process.Pid
+	assert.True(t, sv.Owns(pid), "main process")
+	assert.False(t, sv.Owns(pid+1), "other process")
+	assert.Equal(t, running, sv.Sub())
+
+	sv.Exited(pid+1, syscall.WaitStatus(3<<8))
+	assert.Equal(t, running, sv.Sub(), "exit of other process")
+
+	sv.Exited(pid, syscall.WaitStatus(3<<8))
+	assert.Equal(t, failed, sv.Sub(), "exit of main process")
 }
 
 // Simple service type test
@@ -59,6 +116,77 @@ func TestStartOneshot(t *testing.T) {
 
 }
 
+func TestStartContext(t *testing.T) {
+	sv := Unit{}
+	sv.Definition.Service.Type = "oneshot"
+	sv.Definition.Service.ExecStartPre = "sleep 60"
+	sv.Cmd = exec.Command("echo", "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	started := time.Now()
+	assert.Equal(t, context.Canceled, sv.StartContext(ctx), "sv.StartContext")
+	assert.True(t, time.Since(started) < 10*time.Second, "ExecStartPre was not killed")
+	assert.Nil(t, sv.Cmd.Process, "ExecStart run after cancellation")
+}
+
+func TestStopControl(t *testing.T) {
+	sv := Unit{}
+	sv.Definition.Service.Type = "simple"
+	sv.Definition.Service.ExecStartPre = "sleep 60"
+	sv.Cmd = exec.Command("sleep", "60")
+
+	errch := make(chan error, 1)
+	go func() { errch <- sv.Start() }()
+
+	for timeout := time.After(5 * time.Second); sv.ControlPID() == 0; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("ExecStartPre was not started")
+		default:
+		}
+	}
+	assert.Equal(t, startPre, sv.Sub(), "sv.Sub")
+	assert.Equal(t, unit.Activating, sv.Active(), "sv.Active")
+	assert.Zero(t, sv.MainPID(), "sv.MainPID")
+
+	require.NoError(t, sv.Stop(), "sv.Stop")
+	select {
+	case err := <-errch:
+		assert.Error(t, err, "sv.Start")
+	case <-time.After(5 * time.Second):
+		t.Fatal("ExecStartPre was not killed")
+	}
+	assert.Zero(t, sv.ControlPID(), "sv.ControlPID")
+	assert.Nil(t, sv.Cmd.Process, "ExecStart run after ExecStartPre was killed")
+}
+
+func TestStartPost(t *testing.T) {
+	sv := Unit{}
+	sv.Definition.Service.Type = "simple"
+	sv.Definition.Service.ExecStartPost = "true"
+	sv.Cmd = exec.Command("sleep", "60")
+
+	require.NoError(t, sv.Start(), "sv.Start")
+	assert.Equal(t, running, sv.Sub(), "sv.Sub")
+	require.NoError(t, sv.Stop(), "sv.Stop")
+
+	failing := Unit{}
+	failing.Definition.Service.Type = "simple"
+	failing.Definition.Service.ExecStartPost = "false"
+	failing.Cmd = exec.Command("sleep", "60")
+
+	assert.Error(t, failing.Start(), "failing.Start with ExecStartPost failing")
+	for timeout := time.After(5 * time.Second); failing.Sub() == running; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("service process was not killed")
+		default:
+		}
+	}
+}
+
 func TestActive(t *testing.T) {
 	// Oneshot service
 	sv := Unit{}
@@ -92,6 +220,64 @@ func TestActive(t *testing.T) {
 
 }
 
+func TestStopKilled(t *testing.T) {
+	sv := Unit{}
+	sv.Definition.Service.Type = "simple"
+	sv.Cmd = exec.Command("sleep", "60")
+
+	require.NoError(t, sv.Start(), "sv.Start")
+	require.NoError(t, sv.Stop(), "sv.Stop")
+
+	for timeout := time.After(5 * time.Second); sv.Sub() == running; time.Sleep(10 * time.Millisecond) {
+		select {
+		case <-timeout:
+			t.Fatal("process was not killed")
+		default:
+		}
+	}
+	assert.Equal(t, unit.Inactive, sv.Active(), "service killed by Stop")
+}
+
+// Service process queried while it is started, waited for and stopped, meant to be run with -race
+func TestConcurrentSub(t *testing.T) {
+	sv := Unit{}
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/sleep 60`)), "sv.Define")
+
+	done := make(chan struct{})
+	queried := make(chan struct{})
+	go func() {
+		defer close(queried)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			sv.Active()
+			sv.MainPID()
+			sv.ExitCode()
+			sv.AutoRestart()
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sv.Start(), "sv.Start")
+		require.NoError(t, sv.Stop(), "sv.Stop")
+		for timeout := time.After(5 * time.Second); sv.Sub() == running; time.Sleep(10 * time.Millisecond) {
+			select {
+			case <-timeout:
+				t.Fatal("process was not killed")
+			default:
+			}
+		}
+	}
+	close(done)
+	<-queried
+
+	assert.Equal(t, unit.Inactive, sv.Active(), "service killed by Stop")
+}
+
 func TestSuported(t *testing.T) {
 	for typ, is := range supported {
 		assert.Equal(t, is, Supported(typ), typ)
@@ -99,3 +285,134 @@ func TestSuported(t *testing.T) {
 
 	assert.False(t, Supported("not-a-service"))
 }
+
+func TestDefineDBus(t *testing.T) {
+	sv := Unit{}
+	assert.Error(t, sv.Define(strings.NewReader(`[Service]
+Type=dbus
+ExecStart=/bin/sleep 1000`)), "BusName= not set")
+
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=dbus
+BusName=org.example.Foo
+ExecStart=/bin/sleep 1000`)), "sv.Define")
+	assert.Equal(t, "org.example.Foo", sv.BusName())
+	assert.Equal(t, DEFAULT_TIMEOUT_START_SEC, sv.TimeoutStartSec())
+
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=oneshot
+BusName=org.example.Foo
+ExecStart=/bin/true`)), "sv.Define")
+	assert.Empty(t, sv.BusName(), "only services of Type=dbus wait for the name")
+	assert.Zero(t, sv.TimeoutStartSec(), "oneshot services get unlimited time by default")
+}
+
+func TestDefineEnvironment(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/env
+Environment=DROPPED=1
+Environment=
+Environment="FOO=bar baz" QUX=1
+Environment='QUOTED=a "b"'`)), "sv.Define")
+
+	assert.Equal(t, []string{"FOO=bar baz", "QUX=1", `QUOTED=a "b"`}, sv.Definition.Service.Environment)
+	assert.Contains(t, sv.Cmd.Env, "FOO=bar baz", "sv.Cmd.Env")
+	assert.NotContains(t, sv.Cmd.Env, "DROPPED=1", "sv.Cmd.Env")
+}
+
+func TestSetProperty(t *testing.T) {
+	sv := Unit{}
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+MemoryMax=1G`)), "sv.Define")
+	assert.Equal(t, unit.Resources{MemoryMax: 1 << 30}, sv.Resources())
+
+	for name, value := range map[string]string{
+		"CPUQuota":  "20%",
+		"MemoryMax": "64M",
+		"TasksMax":  "infinity",
+		"Restart":   "on-failure",
+	} {
+		assert.NoError(t, sv.SetProperty(name, value), name)
+	}
+	assert.Equal(t, unit.Resources{CPUQuota: 0.2, MemoryMax: 64 << 20, TasksMax: unit.Unlimited}, sv.Resources())
+	assert.Equal(t, "on-failure", sv.Definition.Service.Restart)
+
+	assert.Error(t, sv.SetProperty("Restart", "sometimes"), "unknown policy")
+	assert.Error(t, sv.SetProperty("CPUQuota", "20"), "no percent sign")
+	assert.Error(t, sv.SetProperty("ExecStart", "/bin/false"), "not settable")
+	assert.Equal(t, "on-failure", sv.Definition.Service.Restart, "definition kept on error")
+}
+
+func TestAutoRestart(t *testing.T) {
+	for policy, expected := range map[string][]bool{
+		// exit 0, exit 1, SIGKILL
+		"no":          {false, false, false},
+		"always":      {true, true, true},
+		"on-success":  {true, false, false},
+		"on-failure":  {false, true, true},
+		"on-abnormal": {false, false, true},
+	} {
+		for i, status := range []syscall.WaitStatus{0, 1 << 8, syscall.WaitStatus(syscall.SIGKILL)} {
+			sv := Unit{}
+			require.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+Restart=`+policy)), "sv.Define")
+
+			sv.Cmd.Process = &os.Process{Pid: 42}
+			assert.False(t, sv.AutoRestart(), "%s running", policy)
+
+			sv.Exited(42, status)
+			assert.Equal(t, expected[i], sv.AutoRestart(), "%s status %d", policy, status)
+
+			sv.killed = true
+			assert.False(t, sv.AutoRestart(), "%s stopped", policy)
+		}
+	}
+}
+
+func TestStandardOutput(t *testing.T) {
+	path, err := ioutil.TempDir("", "output-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	out, errOut := filepath.Join(path, "out.log"), filepath.Join(path, "err.log")
+	require.NoError(t, ioutil.WriteFile(out, []byte("previous output\n"), 0644))
+
+	script := filepath.Join(path, "output.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte("#!/bin/sh\necho out\necho err >&2\n"), 0755))
+
+	sv := Unit{}
+	require.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=oneshot
+ExecStart=`+script+`
+StandardOutput=append:`+out+`
+StandardError=truncate:`+errOut)), "sv.Define")
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, sv.Start(), "sv.Start")
+	}
+
+	b, err := ioutil.ReadFile(out)
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, "previous output\nout\nout\n", string(b), "output appended on each start")
+
+	b, err = ioutil.ReadFile(errOut)
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, "err\n", string(b), "error truncated on each start")
+
+	// The file removed is created again on the next start
+	require.NoError(t, os.Remove(out))
+	require.NoError(t, sv.Start(), "sv.Start")
+	b, err = ioutil.ReadFile(out)
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Equal(t, "out\n", string(b))
+
+	for _, value := range []string{"file:relative.log", "syslog", "append"} {
+		sv = Unit{}
+		assert.Error(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+StandardOutput=`+value)), value)
+	}
+}
@@ -0,0 +1,359 @@
+// Package socket defines a socket unit type, which listens on sockets on behalf of the service activated by them
+package socket
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	dead      = "dead"
+	listening = "listening"
+	running   = "running"
+	failed    = "failed"
+)
+
+// Interval the sockets are polled for incoming connections or datagrams at, while the service is not running
+var POLL_INTERVAL = 100 * time.Millisecond
+
+// Socket unit
+type Unit struct {
+	Definition
+
+	// Name of the socket, used to derive the default service activated
+	name string
+
+	// activate starts the service name passing it the listening sockets files and waits for it to start,
+	// running reports whether the service name is running
+	activate func(name string, files []*os.File) error
+	running  func(name string) bool
+
+	// Listening sockets, retained while the service is not running, nil if the socket is stopped
+	files []*os.File
+
+	// Closed to stop watching the sockets
+	stop chan struct{}
+
+	// Whether the service could not be activated
+	failed bool
+
+	mutex sync.Mutex
+}
+
+// Socket unit definition
+type Definition struct {
+	unit.Definition
+	Socket struct {
+		// Addresses listened on, either paths of Unix sockets or TCP/UDP addresses, e.g. "8080" or "127.0.0.1:53"
+		ListenStream, ListenDatagram []string
+
+		// Service activated, the one named after the socket by default
+		Service string
+
+		// Only Accept=no is supported, i.e. the service is passed the listening sockets
+		Accept bool
+
+		// Whether SO_REUSEPORT is set on the sockets, so that several processes may listen on the same port
+		ReusePort bool
+	}
+}
+
+// New returns a new, undefined socket name, which starts the service it activates using activate and
+// queries whether it is running using running
+func New(name string, activate func(name string, files []*os.File) error, running func(name string) bool) *Unit {
+	return &Unit{name: name, activate: activate, running: running}
+}
+
+// Define attempts to fill the sock definition by parsing r
+func (sock *Unit) Define(r io.Reader) (err error) {
+	def := Definition{}
+	def.Unit.DefaultDependencies = true
+
+	var warnings error
+	if err = unit.ParseDefinition(r, &def); unit.IsWarning(err) {
+		warnings = err
+	} else if err != nil {
+		return
+	}
+
+	switch {
+	case len(def.Socket.ListenStream) == 0 && len(def.Socket.ListenDatagram) == 0:
+		return unit.ParseErr("ListenStream", unit.ErrNotSet)
+	case def.Socket.Accept:
+		return unit.ParseErr("Accept", unit.ParseErr("yes", unit.ErrNotSupported))
+	}
+
+	sock.mutex.Lock()
+	sock.Definition = def
+	sock.mutex.Unlock()
+
+	return warnings
+}
+
+// Triggers returns the name of the service activated by sock.
+// Unless specified by Service=, it is the service named after sock
+func (sock *Unit) Triggers() string {
+	if sock.Definition.Socket.Service != "" {
+		return sock.Definition.Socket.Service
+	}
+	return strings.TrimSuffix(sock.name, ".socket") + ".service"
+}
+
+// StartContext opens the sockets and starts watching them, the service is activated on the first connection or datagram
+func (sock *Unit) StartContext(ctx context.Context) (err error) {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.files != nil {
+		return nil
+	}
+
+	var files []*os.File
+	for _, addr := range sock.Definition.Socket.ListenStream {
+		f, err := listen(ctx, "stream", addr, sock.Definition.Socket.ReusePort)
+		if err != nil {
+			closeAll(files)
+			return err
+		}
+		files = append(files, f)
+	}
+	for _, addr := range sock.Definition.Socket.ListenDatagram {
+		f, err := listen(ctx, "datagram", addr, sock.Definition.Socket.ReusePort)
+		if err != nil {
+			closeAll(files)
+			return err
+		}
+		files = append(files, f)
+	}
+
+	sock.listen(files)
+	return nil
+}
+
+// Files returns the listening sockets of sock, nil if it is stopped
+func (sock *Unit) Files() []*os.File {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	return append([]*os.File(nil), sock.files...)
+}
+
+// Adopt makes sock listen on files opened already, e.g. the sockets passed across re-execution.
+// files are closed, if sock is started already
+func (sock *Unit) Adopt(files []*os.File) {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.files != nil {
+		closeAll(files)
+		return
+	}
+	sock.listen(files)
+}
+
+// listen makes sock listening on files and starts watching them, the mutex of sock must be locked
+func (sock *Unit) listen(files []*os.File) {
+	fds := make([]int, len(files))
+	for i, f := range files {
+		fds[i] = int(f.Fd())
+	}
+
+	sock.files, sock.stop, sock.failed = files, make(chan struct{}), false
+	go sock.watch(files, fds, sock.stop, sock.Triggers())
+}
+
+// StopContext stops watching the sockets and closes them, the service activated is left running
+func (sock *Unit) StopContext(ctx context.Context) error {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.stop != nil {
+		close(sock.stop)
+	}
+	closeAll(sock.files)
+	sock.files, sock.stop, sock.failed = nil, nil, false
+	return nil
+}
+
+// watch activates the service, once any of the files, which descriptors are fds, becomes readable while it is not running,
+// until stop is closed. Once the service exits, e.g. having been idle, the sockets get watched again
+func (sock *Unit) watch(files []*os.File, fds []int, stop <-chan struct{}, service string) {
+	p, err := newPoller(fds)
+	if err != nil {
+		sock.fail(stop, err)
+		return
+	}
+	defer p.close()
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if sock.running != nil && sock.running(service) {
+			// The service accepts the connections itself
+			time.Sleep(POLL_INTERVAL)
+			continue
+		}
+
+		ready, err := p.wait(POLL_INTERVAL)
+		switch {
+		case err != nil:
+			sock.fail(stop, err)
+			return
+		case !ready:
+			continue
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		log.WithField("service", service).Debug("sock.activate")
+		if sock.activate == nil {
+			return
+		}
+		if err = sock.activate(service, files); err != nil {
+			sock.fail(stop, err)
+			return
+		}
+	}
+}
+
+// fail makes sock failed due to err, unless it was stopped, i.e. stop is closed
+func (sock *Unit) fail(stop <-chan struct{}, err error) {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.stop != stop {
+		return
+	}
+	log.WithField("err", err).Errorf("Error activating the service of %s", sock.name)
+	sock.failed = true
+}
+
+// listen opens a listening socket of kind("stream" or "datagram") on addr and returns its file.
+// addr is either an absolute path of a Unix socket, which is replaced, if it exists, a port or a host and port
+func listen(ctx context.Context, kind, addr string, reusePort bool) (f *os.File, err error) {
+	network := "tcp"
+	if kind == "datagram" {
+		network = "udp"
+	}
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+		if kind == "datagram" {
+			network = "unixgram"
+		}
+		if err = os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if !strings.Contains(addr, ":") {
+		// Port only
+		addr = ":" + addr
+	}
+
+	lc := net.ListenConfig{}
+	if reusePort {
+		lc.Control = func(network, address string, c syscall.RawConn) (err error) {
+			if cerr := c.Control(func(fd uintptr) {
+				err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, SO_REUSEPORT, 1)
+			}); cerr != nil {
+				return cerr
+			}
+			return
+		}
+	}
+
+	var l filer
+	switch network {
+	case "tcp", "unix":
+		var ln net.Listener
+		if ln, err = lc.Listen(ctx, network, addr); err != nil {
+			return
+		}
+		if ul, ok := ln.(*net.UnixListener); ok {
+			// The socket file is kept, once the listener is closed
+			ul.SetUnlinkOnClose(false)
+		}
+		l = ln.(filer)
+	default:
+		var c net.PacketConn
+		if c, err = lc.ListenPacket(ctx, network, addr); err != nil {
+			return
+		}
+		l = c.(filer)
+	}
+
+	// The file is a duplicate, which stays open, once the listener is closed
+	defer l.Close()
+	return l.File()
+}
+
+// filer is a listener or connection, which file can be obtained
+type filer interface {
+	File() (*os.File, error)
+	Close() error
+}
+
+// closeAll closes files
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// Active returns activation status of the unit
+func (sock *Unit) Active() unit.Activation {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	switch {
+	case sock.failed:
+		return unit.Failed
+	case sock.files != nil:
+		return unit.Active
+	}
+	return unit.Inactive
+}
+
+// Sub reports the sub status of a socket
+func (sock *Unit) Sub() string {
+	sock.mutex.Lock()
+	isFailed, started := sock.failed, sock.files != nil
+	sock.mutex.Unlock()
+
+	switch {
+	case isFailed:
+		return failed
+	case !started:
+		return dead
+	case sock.running != nil && sock.running(sock.Triggers()):
+		return running
+	}
+	return listening
+}
+
+// ResetFailed makes a failed socket inactive, the sockets are closed
+func (sock *Unit) ResetFailed() {
+	sock.mutex.Lock()
+	failed := sock.failed
+	sock.mutex.Unlock()
+
+	if failed {
+		sock.StopContext(context.Background())
+	}
+}
@@ -0,0 +1,46 @@
+package socket
+
+import (
+	"syscall"
+	"time"
+)
+
+// SO_REUSEPORT socket option of Linux, which package syscall does not define
+const SO_REUSEPORT = 0xf
+
+// poller waits for any of the sockets watched to become readable
+type poller struct {
+	epfd int
+}
+
+// newPoller returns a poller watching the sockets, which file descriptors are fds
+func newPoller(fds []int) (p *poller, err error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fd := range fds {
+		ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err = syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			syscall.Close(epfd)
+			return nil, err
+		}
+	}
+	return &poller{epfd}, nil
+}
+
+// wait reports whether any of the sockets is readable, waiting for one no longer than timeout
+func (p *poller) wait(timeout time.Duration) (ready bool, err error) {
+	events := make([]syscall.EpollEvent, 1)
+	n, err := syscall.EpollWait(p.epfd, events, int(timeout/time.Millisecond))
+	if err == syscall.EINTR {
+		return false, nil
+	}
+	return n > 0, err
+}
+
+// close stops watching the sockets
+func (p *poller) close() {
+	syscall.Close(p.epfd)
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package socket
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+)
+
+// SO_REUSEPORT socket option
+const SO_REUSEPORT = syscall.SO_REUSEPORT
+
+// poller is not implemented, the sockets are only watched on Linux
+type poller struct{}
+
+// newPoller returns unit.ErrNotSupported, the sockets are only watched on Linux
+func newPoller(fds []int) (*poller, error) {
+	return nil, unit.ErrNotSupported
+}
+
+func (p *poller) wait(timeout time.Duration) (bool, error) {
+	return false, unit.ErrNotSupported
+}
+
+func (p *poller) close() {}
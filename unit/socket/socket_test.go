@@ -0,0 +1,128 @@
+package socket
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefine(t *testing.T) {
+	sock := New("a.socket", nil, nil)
+	if err := sock.Define(strings.NewReader(`[Socket]`)); assert.Error(t, err, "sock.Define without ListenStream=") {
+		if pe, ok := err.(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+			assert.Equal(t, "ListenStream", pe.Source)
+			assert.Equal(t, unit.ErrNotSet, pe.Err)
+		}
+	}
+	assert.Error(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=8080
+Accept=yes`)), "sock.Define with Accept=yes")
+
+	require.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=8080`)), "sock.Define")
+	assert.Equal(t, "a.service", sock.Triggers(), "default service activated")
+
+	require.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenDatagram=/run/b.sock
+Service=b.service`)), "sock.Define")
+	assert.Equal(t, "b.service", sock.Triggers(), "Service=")
+}
+
+func TestActivate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.sock")
+
+	var mutex sync.Mutex
+	active, activations := false, 0
+	activated := make(chan []*os.File, 10)
+
+	sock := New("a.socket", func(name string, files []*os.File) error {
+		mutex.Lock()
+		active, activations = true, activations+1
+		mutex.Unlock()
+		activated <- files
+		return nil
+	}, func(name string) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return active
+	})
+	require.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=`+path)), "sock.Define")
+
+	require.NoError(t, sock.StartContext(context.Background()), "sock.StartContext")
+	defer sock.StopContext(context.Background())
+	assert.Equal(t, unit.Active, sock.Active())
+	assert.Equal(t, listening, sock.Sub())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err, "net.Dial")
+	defer conn.Close()
+
+	select {
+	case files := <-activated:
+		assert.Len(t, files, 1, "listening sockets passed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("service was not activated")
+	}
+	assert.Equal(t, running, sock.Sub())
+
+	// The connection is not accepted while the service is running
+	time.Sleep(3 * POLL_INTERVAL)
+	mutex.Lock()
+	assert.Equal(t, 1, activations, "activated while running")
+
+	// Service exited idle, the connection still pending activates it again
+	active = false
+	mutex.Unlock()
+
+	select {
+	case <-activated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("service was not activated again")
+	}
+	assert.Equal(t, unit.Active, sock.Active(), "socket not failed")
+
+	require.NoError(t, sock.StopContext(context.Background()), "sock.StopContext")
+	assert.Equal(t, unit.Inactive, sock.Active())
+	assert.Equal(t, dead, sock.Sub())
+}
+
+func TestReusePort(t *testing.T) {
+	def := `[Socket]
+ListenStream=127.0.0.1:0
+ReusePort=yes`
+	a := New("a.socket", nil, nil)
+	require.NoError(t, a.Define(strings.NewReader(def)), "a.Define")
+	require.NoError(t, a.StartContext(context.Background()), "a.StartContext")
+	defer a.StopContext(context.Background())
+
+	l, err := net.FileListener(a.files[0])
+	require.NoError(t, err, "net.FileListener")
+	addr := l.Addr().String()
+	l.Close()
+
+	b := New("b.socket", nil, nil)
+	require.NoError(t, b.Define(strings.NewReader(`[Socket]
+ListenStream=`+addr+`
+ReusePort=yes`)), "b.Define")
+	require.NoError(t, b.StartContext(context.Background()), "b.StartContext on the same port")
+	b.StopContext(context.Background())
+
+	c := New("c.socket", nil, nil)
+	require.NoError(t, c.Define(strings.NewReader(`[Socket]
+ListenStream=`+addr)), "c.Define")
+	assert.Error(t, c.StartContext(context.Background()), "c.StartContext on the same port without ReusePort=")
+}
@@ -0,0 +1,148 @@
+package unit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/go-systemd/unit"
+)
+
+// Specifiers maps specifier characters to the values they expand to -- https://www.freedesktop.org/software/systemd/man/systemd.unit.html#Specifiers
+type Specifiers map[byte]string
+
+// NewSpecifiers returns specifiers of a unit named name, which definition is found at path.
+// The specifiers describing the host and the user are determined once per call, the ones, which can not be,
+// expand to empty strings
+func NewSpecifiers(name, path string) (spec Specifiers) {
+	prefix, instance, _ := splitInstance(name)
+
+	// Last component of the prefix
+	component := prefix
+	if i := strings.LastIndexByte(prefix, '-'); i >= 0 {
+		component = prefix[i+1:]
+	}
+
+	filename := prefix
+	if instance != "" {
+		filename = instance
+	}
+
+	spec = Specifiers{
+		'n': name,
+		'N': strings.TrimSuffix(name, filepath.Ext(name)),
+		'p': prefix,
+		'P': unit.UnitNameUnescape(prefix),
+		'i': instance,
+		'I': unit.UnitNameUnescape(instance),
+		'j': component,
+		'J': unit.UnitNameUnescape(component),
+		'f': unit.UnitNamePathUnescape(filename),
+
+		'y': path,
+		'Y': filepath.Dir(path),
+
+		't': RuntimeDir,
+		'S': StateDir,
+		'C': CacheDir,
+		'L': LogsDir,
+		'E': ConfigDir,
+		'T': TempDir,
+		'V': VarTempDir,
+
+		'a': Architecture(),
+		'v': KernelRelease(),
+		'm': MachineID(),
+		'b': BootID(),
+		'H': Hostname(),
+	}
+	spec['l'] = strings.SplitN(spec['H'], ".", 2)[0]
+
+	release := OSRelease()
+	for c, key := range map[byte]string{
+		'o': "ID",
+		'w': "VERSION_ID",
+		'B': "BUILD_ID",
+		'W': "VARIANT_ID",
+		'M': "IMAGE_ID",
+		'A': "IMAGE_VERSION",
+	} {
+		spec[c] = release[key]
+	}
+
+	if u, err := user.Current(); err == nil {
+		spec['u'] = u.Username
+		spec['U'] = u.Uid
+		spec['G'] = u.Gid
+		spec['h'] = u.HomeDir
+		if g, err := user.LookupGroupId(u.Gid); err == nil {
+			spec['g'] = g.Name
+		}
+	}
+	if spec['s'] = os.Getenv("SHELL"); spec['s'] == "" {
+		spec['s'] = "/bin/sh"
+	}
+
+	return
+}
+
+// Expand returns s with every specifier replaced by its value found in spec.
+// "%%" expands to a single "%". If an unknown specifier is encountered,
+// ErrUnknownSpecifier wrapped in a ParseError is returned
+func (spec Specifiers) Expand(s string) (string, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(s)))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i == len(s)-1 {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if s[i] == '%' {
+			buf.WriteByte('%')
+		} else if v, ok := spec[s[i]]; ok {
+			buf.WriteString(v)
+		} else {
+			return "", ParseErr("%"+string(s[i]), ErrUnknownSpecifier)
+		}
+	}
+	return buf.String(), nil
+}
+
+// ExpandDefinition reads a definition in Systemd unit-file format from r and returns a reader
+// of the same definition with specifiers expanded in every value.
+// The values, which can not be expanded, are reported in the MultiError returned, as by ExpandOptions
+func ExpandDefinition(r io.Reader, spec Specifiers) (io.Reader, error) {
+	opts, err := Deserialize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = spec.ExpandOptions(opts); err != nil {
+		return nil, err
+	}
+	return opts.Reader(), nil
+}
+
+// ExpandOptions replaces specifiers in values of opts in place.
+// Every value, which can not be expanded, is reported in the MultiError returned
+func (spec Specifiers) ExpandOptions(opts Options) error {
+	merr := MultiError{}
+	for _, opt := range opts {
+		value, err := spec.Expand(opt.Value)
+		if err != nil {
+			merr = append(merr, opt.Err(err))
+			continue
+		}
+		opt.Value = value
+	}
+
+	if len(merr) > 0 {
+		return merr
+	}
+	return nil
+}
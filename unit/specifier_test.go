@@ -0,0 +1,78 @@
+package unit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSpecifiers(t *testing.T) {
+	spec := unit.NewSpecifiers("systemd-fsck@dev-sda1.service", "/lib/systemd/system/systemd-fsck@.service")
+
+	for c, v := range map[byte]string{
+		'n': "systemd-fsck@dev-sda1.service",
+		'N': "systemd-fsck@dev-sda1",
+		'p': "systemd-fsck",
+		'P': "systemd/fsck",
+		'i': "dev-sda1",
+		'I': "dev/sda1",
+		'j': "fsck",
+		'f': "/dev/sda1",
+		'y': "/lib/systemd/system/systemd-fsck@.service",
+		'Y': "/lib/systemd/system",
+		't': unit.RuntimeDir,
+		'a': unit.Architecture(),
+	} {
+		assert.Equal(t, v, spec[c], "%%%c", c)
+	}
+
+	spec = unit.NewSpecifiers("foo-bar.service", "")
+	assert.Equal(t, "/foo/bar", spec['f'], "%f without instance")
+	assert.Equal(t, "", spec['i'], "%i without instance")
+}
+
+func TestExpand(t *testing.T) {
+	spec := unit.Specifiers{
+		'i': "tty1",
+		'H': "host",
+	}
+
+	for in, out := range map[string]string{
+		"/sbin/agetty %i":   "/sbin/agetty tty1",
+		"%H-%i":             "host-tty1",
+		"echo 100%%":        "echo 100%",
+		"trailing percent%": "trailing percent%",
+	} {
+		expanded, err := spec.Expand(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, out, expanded, in)
+	}
+
+	_, err := spec.Expand("%Q is unknown")
+	if pe, ok := err.(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+		assert.Equal(t, "%Q", pe.Source)
+		assert.Equal(t, unit.ErrUnknownSpecifier, pe.Err)
+	}
+}
+
+func TestExpandDefinition(t *testing.T) {
+	spec := unit.Specifiers{'n': "foo.service"}
+
+	_, err := unit.ExpandDefinition(strings.NewReader(`[Unit]
+Description=%n
+Documentation=%Q`), spec)
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") {
+		assert.Len(t, me, 1)
+	}
+
+	r, err := unit.ExpandDefinition(strings.NewReader(`[Unit]
+Description=%n`), spec)
+	require.NoError(t, err, "unit.ExpandDefinition")
+
+	def := unit.Definition{}
+	require.NoError(t, unit.ParseDefinition(r, &def), "unit.ParseDefinition")
+	assert.Equal(t, "foo.service", def.Description())
+}
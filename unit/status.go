@@ -1,22 +1,83 @@
 package unit
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+)
 
 type Status struct {
+	Description string `json:"Description,omitempty"`
+
 	Load       LoadStatus       `json:"Load"`
 	Activation ActivationStatus `json:"Activation"`
 
+	// Condition, which was not met on the last start attempt
+	Condition string `json:"Condition,omitempty"`
+
+	// Assertion, which failed on the last start attempt
+	Assert string `json:"Assert,omitempty"`
+
+	// Warnings emitted while parsing the definition
+	Warnings []string `json:"Warnings,omitempty"`
+
+	// Results of the jobs of dependencies on the last job of the unit
+	Dependencies []DependencyStatus `json:"Dependencies,omitempty"`
+
+	// Main process of the unit, 0 if none is running
+	MainPID int `json:"MainPID,omitempty"`
+
+	// Control process of the unit running, e.g. ExecStartPre= command, 0 if none is
+	ControlPID int `json:"ControlPID,omitempty"`
+
+	// Main and control processes of the unit and their descendants, parents before children
+	Processes []Process `json:"Processes,omitempty"`
+
+	// Resident memory(in bytes) and CPU time used by the processes
+	Memory uint64        `json:"Memory,omitempty"`
+	CPU    time.Duration `json:"CPU,omitempty"`
+
+	// Status text, error number and D-Bus error name last reported by the processes using sd_notify(3)
+	StatusText     string `json:"StatusText,omitempty"`
+	StatusErrno    int    `json:"StatusErrno,omitempty"`
+	StatusBusError string `json:"StatusBusError,omitempty"`
+
 	Log []byte `json:"Log,omitempty"`
 }
+
+// Process describes a process of a unit
+type Process struct {
+	PID  int `json:"PID"`
+	PPID int `json:"PPID"`
+
+	// Command line of the process, its name if not available
+	Command string `json:"Command"`
+}
+type DependencyStatus struct {
+	Name string `json:"Name"`
+
+	// Either "required" or "wanted"
+	Kind string `json:"Kind"`
+
+	Result string `json:"Result"`
+}
 type ActivationStatus struct {
 	State Activation `json:"State"`
 	Sub   string     `json:"Sub"`
+
+	// Time the unit entered the state, zero if not known
+	Since time.Time `json:"Since,omitempty"`
 }
 type LoadStatus struct {
-	Path   string `json:"Path"`
-	Loaded Load   `json:"Loaded"`
-	State  Enable `json:"Enabled"`
-	Vendor Enable `json:"Vendor"`
+	Path    string   `json:"Path"`
+	DropIns []string `json:"DropIns,omitempty"`
+	Loaded  Load     `json:"Loaded"`
+	State   Enable   `json:"Enabled"`
+	Vendor  Enable   `json:"Vendor"`
+
+	// Whether the definition changed on disk since it was loaded
+	NeedDaemonReload bool `json:"NeedDaemonReload,omitempty"`
 }
 
 func (s Status) String() (out string) {
@@ -25,9 +86,38 @@ func (s Status) String() (out string) {
 			out += fmt.Sprintf("\nLog:\n%s", s.Log)
 		}
 	}()
-	return fmt.Sprintf(
-		`Loaded: %s (%s; %s; vendor preset: %s)
-Active: %s (%s)`,
-		s.Load.Loaded, s.Load.Path, s.Load.State, s.Load.Vendor,
+
+	out = fmt.Sprintf("Loaded: %s (%s; %s; vendor preset: %s)",
+		s.Load.Loaded, s.Load.Path, s.Load.State, s.Load.Vendor)
+
+	if len(s.Load.DropIns) > 0 {
+		out += fmt.Sprintf("\nDrop-In: %s", strings.Join(s.Load.DropIns, ", "))
+	}
+
+	out += fmt.Sprintf("\nActive: %s (%s)",
 		s.Activation.State, s.Activation.Sub)
+
+	if s.StatusText != "" {
+		out += fmt.Sprintf("\nStatus: %q", s.StatusText)
+	}
+	if s.StatusErrno > 0 {
+		out += fmt.Sprintf("\nError: %d (%s)", s.StatusErrno, syscall.Errno(s.StatusErrno))
+	}
+	if s.StatusBusError != "" {
+		out += fmt.Sprintf("\nBus error: %s", s.StatusBusError)
+	}
+
+	if s.Condition != "" {
+		out += fmt.Sprintf("\nCondition: start condition failed: %s", s.Condition)
+	}
+	if s.Assert != "" {
+		out += fmt.Sprintf("\nAssert: start assertion failed: %s", s.Assert)
+	}
+	for _, w := range s.Warnings {
+		out += fmt.Sprintf("\nWarning: %s", w)
+	}
+	for _, dep := range s.Dependencies {
+		out += fmt.Sprintf("\nDependency: %s (%s): %s", dep.Name, dep.Kind, dep.Result)
+	}
+	return
 }
@@ -0,0 +1,184 @@
+// Package timer defines a timer unit type
+package timer
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	dead    = "dead"
+	waiting = "waiting"
+	elapsed = "elapsed"
+)
+
+// Timer unit
+type Unit struct {
+	Definition
+
+	// Name of the timer, used to derive the default unit triggered
+	name string
+
+	// trigger starts the unit triggered
+	trigger func(name string) error
+
+	calendar unit.Calendar
+
+	// Timer of the next elapse, nil if the timer is stopped or elapsed for the last time
+	timer *time.Timer
+	next  time.Time
+
+	started bool
+	mutex   sync.Mutex
+}
+
+// Timer unit definition
+type Definition struct {
+	unit.Definition
+	Timer struct {
+		OnCalendar string
+		Unit       string
+	}
+}
+
+// New returns a new, undefined timer name, which starts units using trigger
+func New(name string, trigger func(name string) error) *Unit {
+	return &Unit{name: name, trigger: trigger}
+}
+
+// Define attempts to fill the tm definition by parsing r
+func (tm *Unit) Define(r io.Reader) (err error) {
+	def := Definition{}
+	def.Unit.DefaultDependencies = true
+
+	var warnings error
+	if err = unit.ParseDefinition(r, &def); unit.IsWarning(err) {
+		warnings = err
+	} else if err != nil {
+		return
+	}
+
+	if def.Timer.OnCalendar == "" {
+		return unit.ParseErr("OnCalendar", unit.ErrNotSet)
+	}
+
+	calendar, err := unit.ParseCalendar(def.Timer.OnCalendar)
+	if err != nil {
+		return unit.ParseErr("OnCalendar", unit.ParseErr(def.Timer.OnCalendar, err))
+	}
+
+	tm.mutex.Lock()
+	tm.Definition, tm.calendar = def, calendar
+	tm.mutex.Unlock()
+
+	return warnings
+}
+
+// Triggers returns the name of the unit started, once tm elapses.
+// Unless specified by Unit=, it is the service named after tm
+func (tm *Unit) Triggers() string {
+	if tm.Definition.Timer.Unit != "" {
+		return tm.Definition.Timer.Unit
+	}
+	return strings.TrimSuffix(tm.name, ".timer") + ".service"
+}
+
+// Start schedules the next elapse of tm
+func (tm *Unit) Start() error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.started = true
+	tm.schedule(time.Now())
+	return nil
+}
+
+// Stop cancels the elapse of tm scheduled
+func (tm *Unit) Stop() error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.started, tm.next = false, time.Time{}
+	if tm.timer != nil {
+		tm.timer.Stop()
+		tm.timer = nil
+	}
+	return nil
+}
+
+// schedule schedules the first elapse of tm after now, tm.mutex must be locked
+func (tm *Unit) schedule(now time.Time) {
+	if tm.timer != nil {
+		tm.timer.Stop()
+		tm.timer = nil
+	}
+
+	if tm.next = tm.calendar.Next(now); tm.next.IsZero() {
+		return
+	}
+
+	next := tm.next
+	tm.timer = time.AfterFunc(next.Sub(now), func() {
+		tm.elapse(next)
+	})
+}
+
+// elapse starts the unit triggered by tm and schedules the next elapse
+func (tm *Unit) elapse(at time.Time) {
+	tm.mutex.Lock()
+	if !tm.started || !tm.next.Equal(at) {
+		// Stopped or rescheduled meanwhile
+		tm.mutex.Unlock()
+		return
+	}
+	tm.schedule(at)
+	name := tm.Triggers()
+	tm.mutex.Unlock()
+
+	log.WithField("unit", name).Debug("tm.elapse")
+	if tm.trigger == nil {
+		return
+	}
+	if err := tm.trigger(name); err != nil {
+		log.WithField("unit", name).Errorf("Error starting unit triggered: %s", err)
+	}
+}
+
+// NextElapse returns the time of the next elapse of tm or the zero time, if none is scheduled
+func (tm *Unit) NextElapse() time.Time {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	return tm.next
+}
+
+// Active returns activation status of the unit
+func (tm *Unit) Active() unit.Activation {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tm.started {
+		return unit.Active
+	}
+	return unit.Inactive
+}
+
+// Sub reports the sub status of a timer
+func (tm *Unit) Sub() string {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	switch {
+	case !tm.started:
+		return dead
+	case tm.timer == nil:
+		return elapsed
+	default:
+		return waiting
+	}
+}
@@ -0,0 +1,61 @@
+package timer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefine(t *testing.T) {
+	tm := New("a.timer", nil)
+	if err := tm.Define(strings.NewReader(`[Timer]`)); assert.Error(t, err, "tm.Define without OnCalendar=") {
+		if pe, ok := err.(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+			assert.Equal(t, "OnCalendar", pe.Source)
+			assert.Equal(t, unit.ErrNotSet, pe.Err)
+		}
+	}
+	assert.Error(t, tm.Define(strings.NewReader(`[Timer]
+OnCalendar=sometimes`)), "tm.Define with a bad expression")
+
+	require.NoError(t, tm.Define(strings.NewReader(`[Timer]
+OnCalendar=Mon..Fri *-*-* 09:00`)), "tm.Define")
+	assert.Equal(t, "a.service", tm.Triggers(), "default unit triggered")
+
+	require.NoError(t, tm.Define(strings.NewReader(`[Timer]
+OnCalendar=daily
+Unit=b.service`)), "tm.Define")
+	assert.Equal(t, "b.service", tm.Triggers())
+}
+
+func TestElapse(t *testing.T) {
+	triggered := make(chan string, 10)
+	tm := New("a.timer", func(name string) error {
+		triggered <- name
+		return nil
+	})
+	require.NoError(t, tm.Define(strings.NewReader(`[Timer]
+OnCalendar=*:*:*`)), "tm.Define")
+
+	assert.Equal(t, dead, tm.Sub())
+	require.NoError(t, tm.Start(), "tm.Start")
+	assert.Equal(t, unit.Active, tm.Active())
+	assert.Equal(t, waiting, tm.Sub())
+	assert.False(t, tm.NextElapse().IsZero(), "next elapse scheduled")
+
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-triggered:
+			assert.Equal(t, "a.service", name)
+		case <-time.After(3 * time.Second):
+			t.Fatal("timer did not elapse")
+		}
+	}
+
+	require.NoError(t, tm.Stop(), "tm.Stop")
+	assert.Equal(t, unit.Inactive, tm.Active())
+	assert.True(t, tm.NextElapse().IsZero(), "no elapse scheduled once stopped")
+}
@@ -0,0 +1,197 @@
+package unit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Infinity is the time span "infinity" parses to
+const Infinity = time.Duration(math.MaxInt64)
+
+// Unlimited is the size "infinity" parses to
+const Unlimited = uint64(math.MaxUint64)
+
+// ParseBool parses a boolean value as accepted by Systemd
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "yes", "y", "true", "t", "on":
+		return true, nil
+	case "0", "no", "n", "false", "f", "off":
+		return false, nil
+	}
+	return false, ErrBadBool
+}
+
+// timeUnits maps time span units to their durations -- https://www.freedesktop.org/software/systemd/man/systemd.time.html
+var timeUnits = map[string]time.Duration{
+	"us": time.Microsecond, "usec": time.Microsecond, "µs": time.Microsecond,
+	"ms": time.Millisecond, "msec": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"M": 2629800 * time.Second, "month": 2629800 * time.Second, "months": 2629800 * time.Second,
+	"y": 31557600 * time.Second, "year": 31557600 * time.Second, "years": 31557600 * time.Second,
+}
+
+// ParseTimespan parses a time span(e.g. "5min 20s", "1h" or "infinity") as accepted by Systemd.
+// Numbers without a unit are interpreted as seconds
+func ParseTimespan(s string) (d time.Duration, err error) {
+	s = strings.TrimSpace(s)
+	if s == "infinity" {
+		return Infinity, nil
+	}
+
+	if s == "" {
+		return 0, ErrBadTimespan
+	}
+
+	for s != "" {
+		var number, unit string
+		if number, s = splitNumber(s); number == "" {
+			return 0, ErrBadTimespan
+		}
+		unit, s = splitUnit(s)
+
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, ErrBadTimespan
+		}
+
+		mult := time.Second
+		if unit != "" {
+			var ok bool
+			if mult, ok = timeUnits[unit]; !ok {
+				return 0, ErrBadTimespan
+			}
+		}
+		d += time.Duration(f * float64(mult))
+	}
+	return
+}
+
+// sizeUnits maps size suffixes to their multipliers(base 1024)
+var sizeUnits = map[string]float64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+	"E": 1 << 60,
+}
+
+// ParseSize parses a size in bytes(e.g. "512M", "1.5G" or "infinity") as accepted by Systemd.
+// Suffixes are interpreted with the base of 1024
+func ParseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "infinity" {
+		return Unlimited, nil
+	}
+
+	number, suffix := splitNumber(s)
+	mult, ok := sizeUnits[strings.TrimSpace(suffix)]
+	if number == "" || !ok {
+		return 0, ErrBadSize
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 || f*mult >= math.MaxUint64 {
+		return 0, ErrBadSize
+	}
+	return uint64(f * mult), nil
+}
+
+// ParseCPUQuota parses a CPU quota(e.g. "20%" or "150%") as accepted by Systemd into the share of the time of a single CPU.
+// An empty quota parses to 0, i.e. no quota
+func ParseCPUQuota(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if !strings.HasSuffix(s, "%") {
+		return 0, ErrBadQuota
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || f <= 0 {
+		return 0, ErrBadQuota
+	}
+	return f / 100, nil
+}
+
+// splitNumber splits s into the leading decimal number and the rest
+func splitNumber(s string) (number, rest string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// splitUnit splits s into the leading unit name and the rest with whitespace removed
+func splitUnit(s string) (unit, rest string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+}
+
+// SplitQuoted splits s into words separated by whitespace. Single and double quotes
+// group words containing whitespace, backslash escapes the following character
+func SplitQuoted(s string) (words []string, err error) {
+	var word []rune
+	var quote rune
+	var inWord, escaped bool
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word = append(word, r)
+			escaped = false
+
+		case r == '\\':
+			inWord, escaped = true, true
+
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word = append(word, r)
+			}
+
+		case r == '"', r == '\'':
+			inWord, quote = true, r
+
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, string(word))
+				word, inWord = nil, false
+			}
+
+		default:
+			inWord = true
+			word = append(word, r)
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, ErrBadQuoting
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package unit_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plasma-umass/systemgo/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBool(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"yes": true, "On": true, "1": true, "true": true,
+		"no": false, "OFF": false, "0": false, "false": false,
+	} {
+		b, err := unit.ParseBool(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, b, s)
+		}
+	}
+
+	_, err := unit.ParseBool("maybe")
+	assert.Equal(t, unit.ErrBadBool, err, "maybe")
+}
+
+func TestParseTimespan(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"5":          5 * time.Second,
+		"5min 20s":   5*time.Minute + 20*time.Second,
+		"5min20s":    5*time.Minute + 20*time.Second,
+		"1h":         time.Hour,
+		"1.5 hours":  90 * time.Minute,
+		"2d 3h":      51 * time.Hour,
+		"300ms":      300 * time.Millisecond,
+		"10 usec":    10 * time.Microsecond,
+		"infinity":   unit.Infinity,
+		" 1w 1days ": 8 * 24 * time.Hour,
+	} {
+		d, err := unit.ParseTimespan(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, d, s)
+		}
+	}
+
+	for _, s := range []string{"", "min", "5 lightyears", "1..2s"} {
+		_, err := unit.ParseTimespan(s)
+		assert.Equal(t, unit.ErrBadTimespan, err, s)
+	}
+}
+
+func TestParseCPUQuota(t *testing.T) {
+	for s, expected := range map[string]float64{
+		"":     0,
+		"20%":  0.2,
+		"150%": 1.5,
+		" 5% ": 0.05,
+	} {
+		quota, err := unit.ParseCPUQuota(s)
+		if assert.NoError(t, err, s) {
+			assert.InDelta(t, expected, quota, 1e-9, s)
+		}
+	}
+
+	for _, s := range []string{"20", "0%", "-5%", "a%"} {
+		_, err := unit.ParseCPUQuota(s)
+		assert.Equal(t, unit.ErrBadQuota, err, s)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]uint64{
+		"512":      512,
+		"512M":     512 << 20,
+		"1.5K":     1536,
+		"2 G":      2 << 30,
+		"infinity": unit.Unlimited,
+	} {
+		size, err := unit.ParseSize(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, size, s)
+		}
+	}
+
+	for _, s := range []string{"", "M", "5X", "-1"} {
+		_, err := unit.ParseSize(s)
+		assert.Equal(t, unit.ErrBadSize, err, s)
+	}
+}
+
+func TestParseDefinitionValues(t *testing.T) {
+	def := &struct {
+		unit.Definition
+		Test struct {
+			Timeout time.Duration
+			Limit   uint64
+			Bool    bool
+		}
+	}{}
+
+	assert.NoError(t, unit.ParseDefinition(strings.NewReader(`[Test]
+Timeout=1min 30s
+Limit=1K
+Bool=on`), def), "unit.ParseDefinition")
+
+	assert.Equal(t, 90*time.Second, def.Test.Timeout, "time.Duration")
+	assert.Equal(t, uint64(1024), def.Test.Limit, "uint64")
+	assert.True(t, def.Test.Bool, "bool")
+
+	err := unit.ParseDefinition(strings.NewReader(`[Test]
+Timeout=soon`), def)
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") {
+		assert.EqualError(t, me[0], "line 2: Timeout: "+unit.ErrBadTimespan.Error())
+	}
+}
+
+func TestSplitQuoted(t *testing.T) {
+	for s, expected := range map[string][]string{
+		"a b  c":              {"a", "b", "c"},
+		`"FOO=bar baz" QUX=1`: {"FOO=bar baz", "QUX=1"},
+		`'a "b"' c\ d`:        {`a "b"`, "c d"},
+		`x""y ""`:             {"xy", ""},
+		"":                    nil,
+	} {
+		words, err := unit.SplitQuoted(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, words, s)
+		}
+	}
+
+	for _, s := range []string{`"foo`, `foo\`} {
+		_, err := unit.SplitQuoted(s)
+		assert.Equal(t, unit.ErrBadQuoting, err, s)
+	}
+}
@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Paths to files used to detect virtualization of the host
+var (
+	InitEnvironPath = "/proc/1/environ"
+	DMIVendorPaths  = []string{"/sys/class/dmi/id/sys_vendor", "/sys/class/dmi/id/product_name", "/sys/class/dmi/id/board_vendor"}
+	CPUInfoPath     = "/proc/cpuinfo"
+)
+
+// containerFiles maps files found only in containers to the container technology IDs as used by Systemd
+var containerFiles = map[string]string{
+	"/.dockerenv":        "docker",
+	"/run/.containerenv": "podman",
+}
+
+// vmVendors maps DMI vendor strings to the virtual machine IDs as used by Systemd
+var vmVendors = map[string]string{
+	"KVM":                   "kvm",
+	"QEMU":                  "qemu",
+	"VMware":                "vmware",
+	"VMW":                   "vmware",
+	"innotek GmbH":          "oracle",
+	"VirtualBox":            "oracle",
+	"Xen":                   "xen",
+	"Bochs":                 "bochs",
+	"Parallels":             "parallels",
+	"BHYVE":                 "bhyve",
+	"Amazon EC2":            "amazon",
+	"Microsoft Corporation": "microsoft",
+}
+
+// Virtualization returns the ID of the virtualization technology the host runs in(e.g. "docker" or "kvm")
+// as named by Systemd and whether it is a container technology.
+// If no virtualization is detected - an empty string is returned
+func Virtualization() (id string, container bool) {
+	if id = detectContainer(); id != "" {
+		return id, true
+	}
+	return detectVM(), false
+}
+
+func detectContainer() string {
+	if id := os.Getenv("container"); id != "" {
+		return id
+	}
+
+	if b, err := ioutil.ReadFile(InitEnvironPath); err == nil {
+		for _, env := range bytes.Split(b, []byte{0}) {
+			if bytes.HasPrefix(env, []byte("container=")) {
+				return string(env[len("container="):])
+			}
+		}
+	}
+
+	for path, id := range containerFiles {
+		if _, err := os.Stat(path); err == nil {
+			return id
+		}
+	}
+	return ""
+}
+
+func detectVM() string {
+	for _, path := range DMIVendorPaths {
+		vendor := readLine(path)
+		if vendor == "" {
+			continue
+		}
+
+		for prefix, id := range vmVendors {
+			if strings.HasPrefix(vendor, prefix) {
+				return id
+			}
+		}
+	}
+
+	if _, err := os.Stat("/proc/xen"); err == nil {
+		return "xen"
+	}
+
+	if b, err := ioutil.ReadFile(CPUInfoPath); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "flags") && strings.Contains(line, " hypervisor") {
+				return "vm-other"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package utmp
+
+import "syscall"
+
+// release returns the release of the running kernel, e.g. "5.10.0", empty if it is unknown
+func release() string {
+	uts := syscall.Utsname{}
+	if err := syscall.Uname(&uts); err != nil {
+		return ""
+	}
+
+	release := make([]byte, 0, len(uts.Release))
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	return string(release)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package utmp
+
+import "syscall"
+
+// release returns the release of the running kernel, e.g. "21.6.0", empty if it is unknown
+func release() string {
+	release, err := syscall.Sysctl("kern.osrelease")
+	if err != nil {
+		return ""
+	}
+	return release
+}
@@ -0,0 +1,250 @@
+// Package utmp writes the login records read by who(1), last(1) and pam_lastlog(8) to utmp(5) and wtmp files,
+// as systemd-update-utmp does
+package utmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Default paths to the records of the current logins and to the log of the past ones
+const (
+	DEFAULT_UTMP_PATH = "/run/utmp"
+	DEFAULT_WTMP_PATH = "/var/log/wtmp"
+)
+
+// Types of the records
+const (
+	Empty        = 0
+	RunLevel     = 1
+	BootTime     = 2
+	NewTime      = 3
+	OldTime      = 4
+	InitProcess  = 5
+	LoginProcess = 6
+	UserProcess  = 7
+	DeadProcess  = 8
+)
+
+// Size of a record as laid out by glibc on Linux, where the time is 32-bit even on 64-bit architectures
+const RECORD_SIZE = 384
+
+// Sizes of the string fields of a record
+const (
+	lineSize = 32
+	idSize   = 4
+	userSize = 32
+	hostSize = 256
+)
+
+var ErrShortRecord = errors.New("Record is too short")
+
+// order is the byte order of the host, which the records are written in
+var order binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 0 {
+		order = binary.BigEndian
+	}
+}
+
+// Record is a utmp record
+type Record struct {
+	Type int16
+	PID  int32
+
+	// Name of the terminal without "/dev/"(e.g. "tty1"), identifier of the record(e.g. "tty1" or "~~"),
+	// name of the user logged in and host logged in from or, for the records of the system, the kernel release
+	Line, ID, User, Host string
+
+	Time time.Time
+}
+
+// Boot returns the record of the system booted at t
+func Boot(t time.Time) Record {
+	r := systemRecord(t)
+	r.Type, r.User = BootTime, "reboot"
+	return r
+}
+
+// Runlevel returns the record of the change of the runlevel to level from previous(0 if unknown), e.g. '3' from '1'
+func Runlevel(level, previous byte) Record {
+	r := systemRecord(time.Now())
+	r.Type, r.User = RunLevel, "runlevel"
+	r.PID = int32(level) | int32(previous)<<8
+	return r
+}
+
+// Shutdown returns the record of the system shutting down
+func Shutdown() Record {
+	r := systemRecord(time.Now())
+	r.Type, r.User = RunLevel, "shutdown"
+	return r
+}
+
+// Init returns the record of the process pid, e.g. a getty, started on the terminal line with the identifier id
+func Init(id, line string, pid int) Record {
+	return Record{Type: InitProcess, PID: int32(pid), ID: id, Line: strings.TrimPrefix(line, "/dev/"), Time: time.Now()}
+}
+
+// Dead returns the record of the process pid started with the identifier id, which has exited
+func Dead(id, line string, pid int) Record {
+	r := Init(id, line, pid)
+	r.Type = DeadProcess
+	return r
+}
+
+// systemRecord returns a record of the system at t
+func systemRecord(t time.Time) Record {
+	r := Record{Line: "~", ID: "~~", Time: t}
+	r.Host = release()
+	return r
+}
+
+// Marshal returns the binary representation of r
+func (r Record) Marshal() []byte {
+	b := make([]byte, RECORD_SIZE)
+	order.PutUint16(b[0:], uint16(r.Type))
+	order.PutUint32(b[4:], uint32(r.PID))
+	off := 8
+	for _, f := range []struct {
+		s    string
+		size int
+	}{{r.Line, lineSize}, {r.ID, idSize}, {r.User, userSize}, {r.Host, hostSize}} {
+		copy(b[off:off+f.size], f.s)
+		off += f.size
+	}
+
+	// Exit status and session are left zero
+	off += 8
+	order.PutUint32(b[off:], uint32(r.Time.Unix()))
+	order.PutUint32(b[off+4:], uint32(r.Time.Nanosecond()/1000))
+	return b
+}
+
+// Unmarshal parses the binary representation of r from b
+func (r *Record) Unmarshal(b []byte) error {
+	if len(b) < RECORD_SIZE {
+		return ErrShortRecord
+	}
+	r.Type = int16(order.Uint16(b[0:]))
+	r.PID = int32(order.Uint32(b[4:]))
+	off := 8
+	for _, f := range []struct {
+		s    *string
+		size int
+	}{{&r.Line, lineSize}, {&r.ID, idSize}, {&r.User, userSize}, {&r.Host, hostSize}} {
+		field := b[off : off+f.size]
+		if i := bytes.IndexByte(field, 0); i >= 0 {
+			field = field[:i]
+		}
+		*f.s = string(field)
+		off += f.size
+	}
+
+	off += 8
+	r.Time = time.Unix(int64(int32(order.Uint32(b[off:]))), int64(int32(order.Uint32(b[off+4:])))*1000)
+	return nil
+}
+
+// Read returns the records found in the file at path
+func Read(path string) (records []Record, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for ; len(b) >= RECORD_SIZE; b = b[RECORD_SIZE:] {
+		r := Record{}
+		r.Unmarshal(b)
+		records = append(records, r)
+	}
+	return
+}
+
+// Put writes r to the utmp file at path replacing the record it supersedes, as pututline(3) does:
+// the one of the same type for the records of the system, the one with the same identifier for the ones of the processes
+func Put(path string, r Record) (err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0664)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if err = lock(f); err != nil {
+		return
+	}
+
+	b := make([]byte, RECORD_SIZE)
+	for off := int64(0); ; off += RECORD_SIZE {
+		if _, err = f.ReadAt(b, off); err == io.EOF {
+			// No record superseded, the file may end with a partial record
+			_, err = f.WriteAt(r.Marshal(), off)
+			return
+		} else if err != nil {
+			return
+		}
+
+		existing := Record{}
+		existing.Unmarshal(b)
+		if existing.supersededBy(r) {
+			_, err = f.WriteAt(r.Marshal(), off)
+			return
+		}
+	}
+}
+
+// Append appends r to the wtmp file at path
+func Append(path string, r Record) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0664)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if err = lock(f); err != nil {
+		return
+	}
+	_, err = f.Write(r.Marshal())
+	return
+}
+
+// Write puts r to the utmp file at utmpPath and appends it to the wtmp file at wtmpPath, an empty path is skipped
+func Write(utmpPath, wtmpPath string, r Record) error {
+	if utmpPath != "" {
+		if err := Put(utmpPath, r); err != nil {
+			return err
+		}
+	}
+	if wtmpPath != "" {
+		return Append(wtmpPath, r)
+	}
+	return nil
+}
+
+// supersededBy reports whether r gets replaced by other in a utmp file
+func (r Record) supersededBy(other Record) bool {
+	switch other.Type {
+	case RunLevel, BootTime, NewTime, OldTime:
+		return r.Type == other.Type
+	case InitProcess, LoginProcess, UserProcess, DeadProcess:
+		switch r.Type {
+		case InitProcess, LoginProcess, UserProcess, DeadProcess:
+			return r.ID == other.ID
+		}
+	}
+	return false
+}
+
+// lock locks f for writing as glibc does, the lock is released once f is closed
+func lock(f *os.File) error {
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &syscall.Flock_t{Type: syscall.F_WRLCK})
+}
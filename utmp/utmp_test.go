@@ -0,0 +1,65 @@
+package utmp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	r := Init("tty1", "/dev/tty1", 42)
+	r.Time = time.Unix(1500000000, 123000)
+
+	b := r.Marshal()
+	require.Len(t, b, RECORD_SIZE)
+
+	parsed := Record{}
+	require.NoError(t, parsed.Unmarshal(b))
+	assert.Equal(t, Record{Type: InitProcess, PID: 42, Line: "tty1", ID: "tty1", Time: r.Time}, parsed)
+
+	assert.Equal(t, ErrShortRecord, parsed.Unmarshal(b[:RECORD_SIZE-1]))
+}
+
+func TestWrite(t *testing.T) {
+	path, err := ioutil.TempDir("", "utmp-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(path)
+
+	utmpPath, wtmpPath := filepath.Join(path, "utmp"), filepath.Join(path, "wtmp")
+
+	boot := Boot(time.Unix(1500000000, 0))
+	for _, r := range []Record{
+		boot,
+		Runlevel('3', 0),
+		Init("tty1", "/dev/tty1", 42),
+		Init("tty2", "/dev/tty2", 43),
+		Dead("tty1", "/dev/tty1", 42),
+		Runlevel('6', '3'),
+	} {
+		require.NoError(t, Write(utmpPath, wtmpPath, r))
+	}
+
+	records, err := Read(utmpPath)
+	require.NoError(t, err)
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, "reboot", records[0].User)
+		assert.Equal(t, boot.Time, records[0].Time)
+		assert.Equal(t, "~~", records[0].ID)
+
+		assert.EqualValues(t, RunLevel, records[1].Type)
+		assert.EqualValues(t, '6'|'3'<<8, records[1].PID)
+
+		assert.EqualValues(t, DeadProcess, records[2].Type, "tty1 superseded")
+		assert.EqualValues(t, InitProcess, records[3].Type)
+		assert.Equal(t, "tty2", records[3].Line)
+	}
+
+	records, err = Read(wtmpPath)
+	require.NoError(t, err)
+	assert.Len(t, records, 6, "wtmp is appended")
+}